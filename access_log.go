@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// What of a member's personal data an admin viewed.
+const (
+	// AccessMember is a member's record, with their contact details.
+	AccessMember = "member"
+	// AccessMembers is the list of every member.
+	AccessMembers = "members"
+	AccessLoans   = "loans"
+	AccessFines   = "fines"
+)
+
+const (
+	defaultAccessLogLimit = 100
+	maxAccessLogLimit     = 1000
+)
+
+// MemberAccess is an admin viewing a member's personal data.
+type MemberAccess struct {
+	Id int `json:"id" xml:"id"`
+	// MemberId is the member whose data was viewed, or nil for the list
+	// of every member.
+	MemberId *int `json:"member_id,omitempty" xml:"member_id,omitempty"`
+	// Actor is the admin, as adminActor gives them.
+	Actor string `json:"actor" xml:"actor"`
+	// Data is what was viewed: AccessMember, AccessMembers, AccessLoans
+	// or AccessFines.
+	Data       string    `json:"data" xml:"data"`
+	AccessedAt time.Time `json:"accessed_at" xml:"accessed_at"`
+}
+
+// Accesses never change once logged.
+func (a MemberAccess) timestamps() (created, updated time.Time) {
+	return a.AccessedAt, a.AccessedAt
+}
+
+// MemberAccessQuery selects accesses for ListMemberAccess.
+type MemberAccessQuery struct {
+	// MemberId keeps only accesses to the member's data if it isn't 0.
+	// Views of the list of every member are kept too, as they showed it.
+	MemberId int
+	// Actor keeps only the accesses by the admin if it isn't empty.
+	Actor string
+	// Since and Until bound when the accesses were made if they aren't
+	// zero.
+	Since, Until time.Time
+	Limit        int
+	ChangedAfter
+}
+
+type MemberAccessResponse struct {
+	Status  string         `json:"status" xml:"status"`
+	Message string         `json:"message" xml:"message"`
+	Data    []MemberAccess `json:"data" xml:"data>access"`
+}
+
+// The actor of requests bearing the admin token.
+const operatorActor = "operator"
+
+// The actor of requests bearing an admin user's token.
+func userActor(user User) string {
+	return "user:" + strconv.Itoa(user.Id)
+}
+
+type adminContextKey struct{}
+
+// Make the admin r is made by, operatorActor or a userActor, known to
+// auditMemberAccess.
+func withAdmin(r *http.Request, actor string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), adminContextKey{}, actor))
+}
+
+// The admin ctx acts for, pseudonymized in privacy mode, and whether it
+// acts for one.
+func adminActor(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(adminContextKey{}).(string)
+	if !ok {
+		return "", false
+	}
+	return pseudonymize(actor), true
+}
+
+// Record that the admin r is made by, if it is, is viewing data of the
+// member with id, or of every member if id is nil. Data is only shown
+// once the view is on record, so the request fails if recording does;
+// members viewing their own data aren't recorded.
+func auditMemberAccess(w http.ResponseWriter, r *http.Request, id *int, data string) bool {
+	if err := recordMemberAccess(r.Context(), store, id, data); err != nil {
+		writeProblem(w, r, codeInternal, "Error recording access")
+		log.Printf("Access recording error: %v", err)
+		return false
+	}
+	return true
+}
+
+// Record in s the view auditMemberAccess does, for handlers that show
+// members' data as they change something, and so record it in the same
+// transaction.
+func recordMemberAccess(ctx context.Context, s BookStore, id *int, data string) error {
+	actor, ok := adminActor(ctx)
+	if !ok {
+		return nil
+	}
+	access := MemberAccess{
+		MemberId:   id,
+		Actor:      actor,
+		Data:       data,
+		AccessedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	return s.RecordMemberAccess(ctx, &access)
+}
+
+// List who viewed members' personal data, newest first: ?member_id= keeps
+// the views of one member's, ?actor= those by one admin, and ?since= and
+// ?until= bound when, as RFC 3339 times or dates.
+func listMemberAccessHandler(w http.ResponseWriter, r *http.Request) {
+	query := MemberAccessQuery{Actor: r.URL.Query().Get("actor")}
+	if v := r.URL.Query().Get("member_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 1 {
+			writeProblem(w, r, codeInvalidParameter, "Invalid member ID")
+			return
+		}
+		query.MemberId = id
+	}
+	var err error
+	if query.Since, err = timeParameter(r, "since"); err != nil {
+		writeProblem(w, r, codeInvalidParameter, err.Error())
+		return
+	}
+	if query.Until, err = timeParameter(r, "until"); err != nil {
+		writeProblem(w, r, codeInvalidParameter, err.Error())
+		return
+	}
+	var ok bool
+	if query.Limit, ok = intParameter(r, "limit", defaultAccessLogLimit, maxAccessLogLimit); !ok {
+		writeProblem(w, r, codeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(maxAccessLogLimit))
+		return
+	}
+	if query.ChangedAfter, ok = requestedChangedAfterFilter(w, r); !ok {
+		return
+	}
+
+	accesses, err := store.ListMemberAccess(r.Context(), query)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching access log")
+		log.Printf("Access log query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, MemberAccessResponse{
+		Status:  "success",
+		Message: "Access log retrieved successfully",
+		Data:    accesses,
+	})
+}
+
+// The time in the query parameter, zero if absent. It is an RFC 3339 time
+// or a date, meaning midnight UTC.
+func timeParameter(r *http.Request, name string) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.New(name + " must be a time like 2006-01-02T15:04:05Z or a date like 2006-01-02")
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// The admin panel: static pages under admin/ that manage books through the
+// JSON API, for people who'd rather not use curl.
+//
+//go:embed admin
+var adminFiles embed.FS
+
+// Serve the admin panel at prefix.
+func adminUIHandler(prefix string) http.Handler {
+	files, err := fs.Sub(adminFiles, "admin")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix(prefix, http.FileServerFS(files))
+}
@@ -0,0 +1,146 @@
+// The admin panel talks to the same JSON API as every other client.
+"use strict";
+
+const api = "/api/v1";
+const perPage = 20;
+let page = 1;
+let query = "";
+
+const $ = (id) => document.getElementById(id);
+const form = $("book");
+
+function setStatus(message) {
+  $("status").textContent = message;
+}
+
+// Fetch a JSON API URL, throwing the problem the API answered with if the
+// request failed.
+async function request(method, path, body) {
+  const options = { method, headers: { Accept: "application/json" } };
+  if (body !== undefined) {
+    options.headers["Content-Type"] = "application/json";
+    options.body = JSON.stringify(body);
+  }
+  const response = await fetch(api + path, options);
+  const data = await response.json().catch(() => ({}));
+  if (!response.ok) {
+    throw Object.assign(new Error(data.detail || response.statusText), { problem: data });
+  }
+  return data;
+}
+
+function cell(text) {
+  const td = document.createElement("td");
+  td.textContent = text;
+  return td;
+}
+
+function button(label, onClick) {
+  const b = document.createElement("button");
+  b.type = "button";
+  b.textContent = label;
+  b.addEventListener("click", onClick);
+  return b;
+}
+
+async function load() {
+  const params = new URLSearchParams({ page, per_page: perPage });
+  let path = "/books";
+  if (query) {
+    path = "/books/search";
+    params.set("q", query);
+  }
+
+  let result;
+  try {
+    result = await request("GET", path + "?" + params);
+  } catch (err) {
+    setStatus(err.message);
+    return;
+  }
+
+  const rows = $("books");
+  rows.replaceChildren();
+  for (const book of result.data || []) {
+    const tr = document.createElement("tr");
+    tr.append(cell(book.id), cell(book.title), cell(book.author), cell(book.price.toFixed(2)), cell(book.isbn || ""));
+    const actions = document.createElement("td");
+    actions.append(button("Edit", () => edit(book)), button("Delete", () => remove(book)));
+    tr.append(actions);
+    rows.append(tr);
+  }
+
+  const links = result.links || {};
+  $("prev").disabled = !links.prev;
+  $("next").disabled = !links.next;
+  $("page").textContent = "Page " + page;
+  setStatus(result.message);
+}
+
+function edit(book) {
+  $("form-title").textContent = "Edit book " + book.id;
+  for (const name of ["id", "title", "author", "price", "isbn"]) {
+    form.elements[name].value = book[name] ?? "";
+  }
+  $("errors").replaceChildren();
+  form.elements.title.focus();
+}
+
+function reset() {
+  $("form-title").textContent = "New book";
+  form.reset();
+  form.elements.id.value = "";
+  $("errors").replaceChildren();
+}
+
+async function remove(book) {
+  if (!confirm(`Delete "${book.title}" and its reviews?`)) {
+    return;
+  }
+  try {
+    const result = await request("DELETE", "/book/" + book.id);
+    setStatus(result.message);
+  } catch (err) {
+    setStatus(err.message);
+  }
+  load();
+}
+
+async function save(event) {
+  event.preventDefault();
+  const id = form.elements.id.value;
+  const book = {
+    title: form.elements.title.value,
+    author: form.elements.author.value,
+    price: Number(form.elements.price.value),
+    isbn: form.elements.isbn.value,
+  };
+
+  try {
+    const result = id ? await request("PUT", "/book/" + id, book) : await request("POST", "/book", book);
+    setStatus(result.message);
+    reset();
+    load();
+  } catch (err) {
+    // Validation problems list every field that broke a rule.
+    const errors = (err.problem && err.problem.errors) || [{ message: err.message }];
+    $("errors").replaceChildren(...errors.map((e) => {
+      const li = document.createElement("li");
+      li.textContent = e.message;
+      return li;
+    }));
+  }
+}
+
+$("search").addEventListener("submit", (event) => {
+  event.preventDefault();
+  query = event.target.elements.q.value.trim();
+  page = 1;
+  load();
+});
+$("prev").addEventListener("click", () => { page--; load(); });
+$("next").addEventListener("click", () => { page++; load(); });
+$("cancel").addEventListener("click", reset);
+form.addEventListener("submit", save);
+
+load();
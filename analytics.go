@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// SearchRecord is one search a client made.
+type SearchRecord struct {
+	Id int
+	// Term is the query as searchTerm normalizes it, so searches that
+	// differ only in case or spacing count as the same term.
+	Term string
+	// Visitor is who searched, as visitorId gives them, pseudonymized in
+	// privacy mode.
+	Visitor   string
+	Results   int
+	Latency   time.Duration
+	CreatedAt time.Time
+}
+
+// SearchAnalytics summarizes the searches made since Since.
+type SearchAnalytics struct {
+	Since              time.Time `json:"since" xml:"since"`
+	TotalSearches      int       `json:"total_searches" xml:"total_searches"`
+	ZeroResultSearches int       `json:"zero_result_searches" xml:"zero_result_searches"`
+	// Visitors counts the different visitors who searched. In privacy
+	// mode the same visitor counts again each time the salt rotates.
+	Visitors int `json:"visitors" xml:"visitors"`
+	// TopTerms are the terms searched most, most first.
+	TopTerms []SearchTermStats `json:"top_terms" xml:"top_terms>search"`
+	// ZeroResultTerms are the terms that found nothing searched most:
+	// what readers look for and the catalog lacks.
+	ZeroResultTerms []SearchTermStats `json:"zero_result_terms" xml:"zero_result_terms>search"`
+}
+
+type SearchTermStats struct {
+	Term           string  `json:"term" xml:"term"`
+	Searches       int     `json:"searches" xml:"searches"`
+	Visitors       int     `json:"visitors" xml:"visitors"`
+	AverageResults float64 `json:"average_results" xml:"average_results"`
+	// AverageLatencyMs is how long the store took to search, in
+	// milliseconds.
+	AverageLatencyMs float64 `json:"average_latency_ms" xml:"average_latency_ms"`
+}
+
+type SearchAnalyticsResponse struct {
+	Status  string          `json:"status" xml:"status"`
+	Message string          `json:"message" xml:"message"`
+	Data    SearchAnalytics `json:"data" xml:"data"`
+}
+
+const (
+	// The longest term recorded, in characters; the column holds 255.
+	maxSearchTermLength = 255
+
+	defaultAnalyticsDays  = 30
+	maxAnalyticsDays      = 365
+	defaultAnalyticsLimit = 20
+	maxAnalyticsLimit     = 100
+)
+
+// The form of query that searches are counted under.
+func searchTerm(query string) string {
+	term := strings.ToLower(normalizeText(query))
+	if utf8.RuneCountInString(term) > maxSearchTermLength {
+		term = string([]rune(term)[:maxSearchTermLength])
+	}
+	return term
+}
+
+// Record a search for the analytics, for the search_flush job to store.
+func recordSearch(ctx context.Context, query string, results int, started time.Time) {
+	searchLog.add(tenantId(ctx), SearchRecord{
+		Term:      searchTerm(query),
+		Visitor:   visitorId(ctx),
+		Results:   results,
+		Latency:   time.Since(started),
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	})
+}
+
+// The most searches searchBuffer holds between flushes. Past it, while
+// the store can't take them, searches go unrecorded.
+const maxBufferedSearches = 10000
+
+// searchBuffer keeps searches in memory until the search_flush job writes
+// them to the store together, so searching costs no insert.
+type searchBuffer struct {
+	mu sync.Mutex
+	// pending has the searches of each tenant, by its id.
+	pending map[int][]SearchRecord
+	size    int
+	dropped int
+}
+
+var searchLog = &searchBuffer{pending: make(map[int][]SearchRecord)}
+
+func (b *searchBuffer) add(tenant int, search SearchRecord) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.size >= maxBufferedSearches {
+		b.dropped++
+		return
+	}
+	b.pending[tenant] = append(b.pending[tenant], search)
+	b.size++
+}
+
+// Store the searches so far; the search_flush job. Searches the store
+// couldn't take are kept for the next flush.
+func (b *searchBuffer) flush(ctx context.Context) error {
+	b.mu.Lock()
+	pending, dropped := b.pending, b.dropped
+	b.pending, b.size, b.dropped = make(map[int][]SearchRecord), 0, 0
+	b.mu.Unlock()
+	if dropped > 0 {
+		log.Printf("Dropped %d searches while the search log was full", dropped)
+	}
+
+	var errs []error
+	for tenant, searches := range pending {
+		err := store.RecordSearches(withTenantId(ctx, tenant), searches)
+		if err == nil {
+			continue
+		}
+		errs = append(errs, err)
+		for _, search := range searches {
+			b.add(tenant, search)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Parse an optional positive integer query parameter no bigger than max.
+func intParameter(r *http.Request, name string, def, max int) (int, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > max {
+		return 0, false
+	}
+	return n, true
+}
+
+func searchAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	days, ok := intParameter(r, "days", defaultAnalyticsDays, maxAnalyticsDays)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "days must be between 1 and "+strconv.Itoa(maxAnalyticsDays))
+		return
+	}
+	limit, ok := intParameter(r, "limit", defaultAnalyticsLimit, maxAnalyticsLimit)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(maxAnalyticsLimit))
+		return
+	}
+
+	since := time.Now().UTC().AddDate(0, 0, -days).Truncate(time.Microsecond)
+	analytics, err := store.SearchAnalytics(r.Context(), since, limit)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error computing search analytics")
+		log.Printf("Search analytics query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, SearchAnalyticsResponse{
+		Status:  "success",
+		Message: "Search analytics computed successfully",
+		Data:    analytics,
+	})
+}
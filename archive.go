@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// archived reports whether book is archived, for slices.DeleteFunc.
+func archived(book Book) bool {
+	return book.ArchivedAt != nil
+}
+
+// Archive the books neither changed nor viewed, lent or sold for years,
+// and bring back archived books that have been since; the book_archival
+// job.
+func archiveStaleBooks(years int) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		archived, restored, err := store.ArchiveBooks(ctx, now.AddDate(-years, 0, 0), now)
+		if len(archived) > 0 || len(restored) > 0 {
+			log.Printf("Archived %d books and brought back %d", len(archived), len(restored))
+		}
+		return err
+	}
+}
+
+// The ?include_archived= parameter of a book list, false unless it is
+// true, and whether it is valid.
+func includeArchivedParameter(r *http.Request) (bool, bool) {
+	if !r.URL.Query().Has("include_archived") {
+		return false, true
+	}
+	include, err := strconv.ParseBool(r.URL.Query().Get("include_archived"))
+	return include, err == nil
+}
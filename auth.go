@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Allow only requests bearing the admin token, which administers every
+// tenant, or the token of an admin user of the request's tenant. With no
+// admin token configured only admin users get in.
+func requireAdmin(token string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given, ok := adminCredential(r)
+			if ok && isOperator(token, given) {
+				next.ServeHTTP(w, withAdmin(r, operatorActor))
+				return
+			}
+			if ok {
+				user, err := store.GetUserByToken(r.Context(), hashUserToken(given))
+				if err == nil && user.Admin {
+					next.ServeHTTP(w, withAdmin(r, userActor(user)))
+					return
+				} else if err != nil && !errors.Is(err, ErrNotFound) {
+					writeProblem(w, r, codeInternal, "Error fetching user")
+					log.Printf("User query error: %v", err)
+					return
+				}
+			}
+			refuseAdmin(w, r)
+		})
+	}
+}
+
+// Allow the requests requireAdmin allows and those bearing the token of
+// the user linked to the member the route is about, whose id is the {id}
+// in the path.
+func requireMemberOrAdmin(token string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given, ok := adminCredential(r)
+			if ok && isOperator(token, given) {
+				next.ServeHTTP(w, withAdmin(r, operatorActor))
+				return
+			}
+			if ok {
+				user, allowed, err := allowedMember(r, hashUserToken(given))
+				if allowed && user.Admin {
+					next.ServeHTTP(w, withAdmin(r, userActor(user)))
+					return
+				} else if allowed {
+					next.ServeHTTP(w, r)
+					return
+				} else if err != nil {
+					writeProblem(w, r, codeInternal, "Error fetching user")
+					log.Printf("User query error: %v", err)
+					return
+				}
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="bookshelf"`)
+			writeProblem(w, r, codeUnauthorized, "The member's or an admin's token required")
+		})
+	}
+}
+
+// Report whether the user whose token hashes to tokenHash is an admin or
+// linked to the member whose id is in r's path, with the user if there is
+// one.
+func allowedMember(r *http.Request, tokenHash string) (User, bool, error) {
+	user, err := store.GetUserByToken(r.Context(), tokenHash)
+	if errors.Is(err, ErrNotFound) {
+		return User{}, false, nil
+	} else if err != nil {
+		return User{}, false, err
+	}
+	if user.Admin {
+		return user, true, nil
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return user, false, nil
+	}
+	member, err := store.GetMember(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		return user, false, nil
+	} else if err != nil {
+		return user, false, err
+	}
+	return user, member.UserId != nil && *member.UserId == user.Id, nil
+}
+
+// Allow only requests bearing the admin token, for managing tenants. With
+// no token configured the routes are disabled.
+func requireOperator(token string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given, ok := adminCredential(r)
+			if !ok || !isOperator(token, given) {
+				refuseAdmin(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Report whether given is the admin token: the secrets manager's latest,
+// or else the configured token.
+func isOperator(token, given string) bool {
+	token = secrets.get("admin_token", token)
+	return token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+func refuseAdmin(w http.ResponseWriter, r *http.Request) {
+	// Browsers prompt for Basic credentials, which is how people log in
+	// to the admin panel.
+	w.Header().Add("WWW-Authenticate", `Bearer realm="bookshelf-admin"`)
+	w.Header().Add("WWW-Authenticate", `Basic realm="bookshelf-admin", charset="UTF-8"`)
+	writeProblem(w, r, codeUnauthorized, "Admin token required")
+}
+
+// The token r presents, as a bearer token or as the password of Basic
+// credentials with any user name.
+func adminCredential(r *http.Request) (string, bool) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token, true
+	}
+	_, password, ok := r.BasicAuth()
+	return password, ok
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backup is a dump of a tenant's catalog: every book and review. It is
+// independent of the database, so a backup of one backend can be restored
+// into another. Webhooks and other settings aren't included.
+type Backup struct {
+	// Version is the version of this format, backupVersion when written.
+	Version int `json:"version"`
+	// Schema is the newest migration of the server that wrote it.
+	Schema    string         `json:"schema"`
+	CreatedAt time.Time      `json:"created_at"`
+	Books     []Book         `json:"books"`
+	Reviews   []backupReview `json:"reviews"`
+}
+
+// backupReview is a review as backed up, with the account that posted it,
+// which the API doesn't show.
+type backupReview struct {
+	Review
+	UserId *int `json:"user_id,omitempty"`
+}
+
+// The reviews in a backup, with their accounts.
+func (b Backup) reviews() []Review {
+	reviews := make([]Review, len(b.Reviews))
+	for i, review := range b.Reviews {
+		reviews[i] = review.Review
+		reviews[i].UserId = review.UserId
+	}
+	return reviews
+}
+
+const backupVersion = 1
+
+// BackupInfo describes a stored backup.
+type BackupInfo struct {
+	Name      string    `json:"name" xml:"name"`
+	Size      int64     `json:"size" xml:"size"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+}
+
+// Backups never change once written.
+func (b BackupInfo) timestamps() (created, updated time.Time) {
+	return b.CreatedAt, b.CreatedAt
+}
+
+type BackupResponse struct {
+	Status  string     `json:"status" xml:"status"`
+	Message string     `json:"message" xml:"message"`
+	Data    BackupInfo `json:"data" xml:"data"`
+}
+
+type BackupsResponse struct {
+	Status  string       `json:"status" xml:"status"`
+	Message string       `json:"message" xml:"message"`
+	Data    []BackupInfo `json:"data" xml:"data>backup"`
+}
+
+// BackupStorage keeps backup files, which are gzipped JSON Backups named
+// by backupName.
+type BackupStorage interface {
+	Save(ctx context.Context, name string, data []byte) error
+	// List returns the stored backups, newest first.
+	List(ctx context.Context) ([]BackupInfo, error)
+	// Open returns the contents of a backup, or ErrNotFound.
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// Open the backup storage selected by the config, or return nil if none
+// is.
+func openBackupStorage(cfg Config) (BackupStorage, error) {
+	switch cfg.BackupStorage {
+	case "", "none":
+		return nil, nil
+	case "local":
+		return newLocalBackupStorage(cfg.BackupDir)
+	case "s3":
+		return newS3BackupStorage(cfg.BackupS3)
+	default:
+		return nil, fmt.Errorf("unknown backup storage %q", cfg.BackupStorage)
+	}
+}
+
+const (
+	backupPrefix     = "bookshelf-"
+	backupSuffix     = ".json.gz"
+	backupTimeLayout = "20060102T150405.000Z"
+)
+
+// Backups are named after the tenant ctx acts for, left out for the
+// default tenant so its names are those from before tenants, and the time
+// they were made, so a tenant's names sort in time order.
+func backupName(ctx context.Context, t time.Time) string {
+	prefix := backupPrefix
+	if tenant := currentTenant(ctx); tenant.Id != defaultTenant.Id {
+		prefix += tenant.Slug + "-"
+	}
+	return prefix + t.UTC().Format(backupTimeLayout) + backupSuffix
+}
+
+// The slug of the tenant a backup is of and the time it was made, from
+// its name; false if the name isn't one backupName makes.
+func parseBackupName(name string) (string, time.Time, bool) {
+	rest, ok := strings.CutPrefix(name, backupPrefix)
+	if !ok {
+		return "", time.Time{}, false
+	}
+	if rest, ok = strings.CutSuffix(rest, backupSuffix); !ok || len(rest) < len(backupTimeLayout) {
+		return "", time.Time{}, false
+	}
+	slug, stamp := defaultTenant.Slug, rest[len(rest)-len(backupTimeLayout):]
+	if rest != stamp {
+		if slug, ok = strings.CutSuffix(strings.TrimSuffix(rest, stamp), "-"); !ok || slug == defaultTenant.Slug {
+			return "", time.Time{}, false
+		}
+	}
+	t, err := time.Parse(backupTimeLayout, stamp)
+	return slug, t, err == nil
+}
+
+// Whether the backup with the name is of the tenant ctx acts for.
+func ownBackup(ctx context.Context, name string) bool {
+	slug, _, ok := parseBackupName(name)
+	return ok && slug == currentTenant(ctx).Slug
+}
+
+// The stored backups of the tenant ctx acts for, newest first.
+func (b *backupService) list(ctx context.Context) ([]BackupInfo, error) {
+	stored, err := b.storage.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	own := []BackupInfo{}
+	for _, info := range stored {
+		if ownBackup(ctx, info.Name) {
+			own = append(own, info)
+		}
+	}
+	return own, nil
+}
+
+// backupService makes backups and keeps the newest keep of them; keep 0
+// keeps all.
+type backupService struct {
+	storage BackupStorage
+	keep    int
+	// mu keeps backups from running at the same time.
+	mu sync.Mutex
+}
+
+// The backups, nil when no backup storage is configured.
+var backups *backupService
+
+// Dump the catalog of the tenant ctx acts for to the storage and delete
+// its backups beyond the newest keep.
+func (b *backupService) run(ctx context.Context) (BackupInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	backup, err := dumpCatalog(ctx, now)
+	if err != nil {
+		return BackupInfo{}, err
+	}
+
+	var data bytes.Buffer
+	zw := gzip.NewWriter(&data)
+	if err := json.NewEncoder(zw).Encode(backup); err != nil {
+		return BackupInfo{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return BackupInfo{}, err
+	}
+
+	info := BackupInfo{Name: backupName(ctx, now), Size: int64(data.Len()), CreatedAt: now}
+	if err := b.storage.Save(ctx, info.Name, data.Bytes()); err != nil {
+		return BackupInfo{}, err
+	}
+
+	if err := b.prune(ctx); err != nil {
+		log.Printf("Backup pruning error: %v", err)
+	}
+	return info, nil
+}
+
+// Delete the tenant's backups beyond the newest keep.
+func (b *backupService) prune(ctx context.Context) error {
+	if b.keep <= 0 {
+		return nil
+	}
+	stored, err := b.list(ctx)
+	if err != nil {
+		return err
+	}
+	for _, info := range stored[min(b.keep, len(stored)):] {
+		if err := b.storage.Delete(ctx, info.Name); err != nil {
+			return err
+		}
+		log.Printf("Deleted old backup %s", info.Name)
+	}
+	return nil
+}
+
+// How many books' reviews dumpCatalog asks for at once.
+const backupReviewBatch = 500
+
+// Read the whole catalog in one transaction, so the dump is consistent.
+func dumpCatalog(ctx context.Context, now time.Time) (Backup, error) {
+	backup := Backup{Version: backupVersion, Schema: schemaVersion(), CreatedAt: now}
+	err := store.WithTx(ctx, func(tx BookStore) error {
+		books, err := tx.ListBooks(ctx)
+		if err != nil {
+			return err
+		}
+		backup.Books = books
+
+		backup.Reviews = []backupReview{}
+		for start := 0; start < len(books); start += backupReviewBatch {
+			batch := books[start:min(start+backupReviewBatch, len(books))]
+			ids := make([]int, len(batch))
+			for i, book := range batch {
+				ids[i] = book.Id
+			}
+			reviews, err := tx.ListReviewsForBooks(ctx, ids, math.MaxInt32)
+			if err != nil {
+				return err
+			}
+			for _, review := range reviews {
+				backup.Reviews = append(backup.Reviews, backupReview{Review: review, UserId: review.UserId})
+			}
+		}
+		return nil
+	})
+	if backup.Books == nil {
+		backup.Books = []Book{}
+	}
+	return backup, err
+}
+
+// Set up the backup storage as configured. The backup job makes the
+// scheduled backups.
+func initBackups(cfg Config) error {
+	storage, err := openBackupStorage(cfg)
+	if err != nil || storage == nil {
+		return err
+	}
+	backups = &backupService{storage: storage, keep: cfg.BackupKeep}
+	return nil
+}
+
+// Back up a tenant's catalog; the backup job, run for each tenant.
+func runBackupJob(ctx context.Context) error {
+	info, err := backups.run(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("Backed up the catalog to %s", info.Name)
+	return nil
+}
+
+// Answer requests to the backup endpoints when backups aren't configured.
+func backupsDisabled(w http.ResponseWriter, r *http.Request) bool {
+	if backups == nil {
+		writeProblem(w, r, codeBackupsDisabled, "No backup storage is configured")
+		return true
+	}
+	return false
+}
+
+func createBackupHandler(w http.ResponseWriter, r *http.Request) {
+	if backupsDisabled(w, r) {
+		return
+	}
+
+	info, err := backups.run(r.Context())
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error backing up the catalog")
+		log.Printf("Backup error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, BackupResponse{
+		Status:  "success",
+		Message: "Backup created successfully",
+		Data:    info,
+	})
+}
+
+func listBackupsHandler(w http.ResponseWriter, r *http.Request) {
+	if backupsDisabled(w, r) {
+		return
+	}
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+
+	stored, err := backups.list(r.Context())
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error listing backups")
+		log.Printf("Backup listing error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, BackupsResponse{
+		Status:  "success",
+		Message: "Backups retrieved successfully",
+		Data:    keepChangedAfter(stored, after),
+	})
+}
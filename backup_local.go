@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// localBackupStorage keeps backups as files in a directory.
+type localBackupStorage struct {
+	dir string
+}
+
+func newLocalBackupStorage(dir string) (*localBackupStorage, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &localBackupStorage{dir: dir}, nil
+}
+
+func (s *localBackupStorage) Save(ctx context.Context, name string, data []byte) error {
+	// Write to a temporary file first so a crash never leaves a partial
+	// backup under a backup's name.
+	f, err := os.CreateTemp(s.dir, ".tmp-"+name)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(s.dir, name))
+}
+
+func (s *localBackupStorage) List(ctx context.Context) ([]BackupInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := []BackupInfo{}
+	for _, entry := range entries {
+		_, createdAt, ok := parseBackupName(entry.Name())
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		stored = append(stored, BackupInfo{Name: entry.Name(), Size: info.Size(), CreatedAt: createdAt})
+	}
+	slices.Reverse(stored)
+	return stored, nil
+}
+
+func (s *localBackupStorage) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(s.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *localBackupStorage) Delete(ctx context.Context, name string) error {
+	return os.Remove(filepath.Join(s.dir, name))
+}
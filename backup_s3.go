@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// s3BackupStorage keeps backups as objects in an S3 bucket.
+type s3BackupStorage struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+func newS3BackupStorage(cfg S3Config) (*s3BackupStorage, error) {
+	// Without keys, use the instance's IAM role.
+	creds := credentials.NewIAM("")
+	if cfg.AccessKey != "" {
+		creds = credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, "")
+	}
+
+	endpoint, secure := "s3.amazonaws.com", true
+	if cfg.Endpoint != "" {
+		u, err := url.Parse(cfg.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		endpoint, secure = u.Host, u.Scheme != "http"
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{Creds: creds, Secure: secure, Region: cfg.Region})
+	if err != nil {
+		return nil, err
+	}
+	return &s3BackupStorage{client: client, bucket: cfg.Bucket, prefix: cfg.Prefix}, nil
+}
+
+func (s *s3BackupStorage) Save(ctx context.Context, name string, data []byte) error {
+	_, err := s.client.PutObject(ctx, s.bucket, s.prefix+name, bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectOptions{ContentType: "application/gzip"})
+	return err
+}
+
+func (s *s3BackupStorage) List(ctx context.Context) ([]BackupInfo, error) {
+	stored := []BackupInfo{}
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: s.prefix}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		name := path.Base(strings.TrimPrefix(object.Key, s.prefix))
+		_, createdAt, ok := parseBackupName(name)
+		if !ok || s.prefix+name != object.Key {
+			continue
+		}
+		stored = append(stored, BackupInfo{Name: name, Size: object.Size, CreatedAt: createdAt})
+	}
+	// Keys are listed in lexical order, which is oldest first.
+	slices.Reverse(stored)
+	return stored, nil
+}
+
+func (s *s3BackupStorage) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, s.prefix+name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// GetObject doesn't fail for missing objects; Stat does.
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return object, nil
+}
+
+func (s *s3BackupStorage) Delete(ctx context.Context, name string) error {
+	return s.client.RemoveObject(ctx, s.bucket, s.prefix+name, minio.RemoveObjectOptions{})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BookMergeRequest merges books entered more than once into the one to
+// keep.
+type BookMergeRequest struct {
+	SurvivorId   int   `json:"survivor_id" xml:"survivor_id" validate:"required"`
+	DuplicateIds []int `json:"duplicate_ids" xml:"duplicate_ids>id" validate:"required,min=1,max=100"`
+}
+
+// Merge duplicate books into a survivor: their reviews, loans, copies and
+// holds, readers' shelf entries, notes, quotes and wishlists move to the
+// survivor, all in one transaction, and the duplicates are archived, their
+// ids redirecting to it.
+func mergeBooksHandler(w http.ResponseWriter, r *http.Request) {
+	var req BookMergeRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	errs := validateRequest(req)
+	if slices.Contains(req.DuplicateIds, req.SurvivorId) {
+		errs = append(errs, newFieldError("duplicate_ids", "excluded", "can't include the survivor"))
+	}
+	if len(slices.Compact(slices.Sorted(slices.Values(req.DuplicateIds)))) < len(req.DuplicateIds) {
+		errs = append(errs, newFieldError("duplicate_ids", "unique", "must not repeat an id"))
+	}
+	if errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	var survivor Book
+	mergedAt := time.Now().UTC().Truncate(time.Microsecond)
+	err := store.WithTx(r.Context(), func(tx BookStore) error {
+		var err error
+		if survivor, err = tx.GetBook(r.Context(), req.SurvivorId); err != nil {
+			return err
+		}
+		for _, id := range req.DuplicateIds {
+			if err := tx.MergeBook(r.Context(), id, req.SurvivorId, mergedAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error merging books")
+		log.Printf("Book merge error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, BookResponse{
+		Status:  "success",
+		Message: "Books merged successfully",
+		Data:    survivor,
+	})
+}
+
+// Redirect a request for a book merged into another to the survivor,
+// reporting whether the book was merged.
+func redirectMergedBook(w http.ResponseWriter, r *http.Request, id int) bool {
+	survivorId, err := store.MergedInto(r.Context(), id)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.Printf("Book merge query error: %v", err)
+		}
+		return false
+	}
+	location := *r.URL
+	location.Path = strings.TrimSuffix(r.URL.Path, strconv.Itoa(id)) + strconv.Itoa(survivorId)
+	http.Redirect(w, r, location.String(), http.StatusMovedPermanently)
+	return true
+}
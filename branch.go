@@ -0,0 +1,286 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultNearbyBranches = 10
+	maxNearbyBranches     = 50
+	// The mean radius of the Earth, which distances between branches are
+	// measured over as if it were a sphere.
+	earthRadiusKm = 6371.0
+)
+
+// Branch is one of the library's buildings, where copies are shelved and
+// lent from.
+type Branch struct {
+	Id      int    `json:"id" xml:"id"`
+	Name    string `json:"name" xml:"name" validate:"required,max=255"`
+	Address string `json:"address,omitempty" xml:"address,omitempty" validate:"max=500"`
+	// Latitude and Longitude are in degrees, north and east positive.
+	Latitude  *float64  `json:"latitude" xml:"latitude" validate:"required,gte=-90,lte=90"`
+	Longitude *float64  `json:"longitude" xml:"longitude" validate:"required,gte=-180,lte=180"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+}
+
+func (b Branch) timestamps() (created, updated time.Time) {
+	return b.CreatedAt, b.UpdatedAt
+}
+
+type BranchResponse struct {
+	Status  string `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
+	Data    Branch `json:"data" xml:"data"`
+}
+
+type BranchesResponse struct {
+	Status  string   `json:"status" xml:"status"`
+	Message string   `json:"message" xml:"message"`
+	Data    []Branch `json:"data" xml:"data>branch"`
+}
+
+// NearbyBranch is a branch with a copy of a book free to borrow, and how
+// far it is from the reader.
+type NearbyBranch struct {
+	Branch
+	// DistanceKm is as the crow flies, to a tenth of a kilometre.
+	DistanceKm float64 `json:"distance_km" xml:"distance_km"`
+	// Available counts the copies of the book free to borrow there.
+	Available int `json:"available" xml:"available"`
+}
+
+type NearbyBranchesResponse struct {
+	Status  string         `json:"status" xml:"status"`
+	Message string         `json:"message" xml:"message"`
+	Data    []NearbyBranch `json:"data" xml:"data>branch"`
+}
+
+var errNoSuchBranch = errors.New("no such branch")
+
+// Tidy the fields as typed.
+func (b *Branch) normalize() {
+	b.Name = normalizeText(b.Name)
+	b.Address = strings.TrimSpace(b.Address)
+}
+
+// List the branches by name.
+func listBranchesHandler(w http.ResponseWriter, r *http.Request) {
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+	branches, err := store.ListBranches(r.Context(), after)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching branches")
+		log.Printf("Branch query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, BranchesResponse{
+		Status:  "success",
+		Message: "Branches retrieved successfully",
+		Data:    branches,
+	})
+}
+
+func createBranchHandler(w http.ResponseWriter, r *http.Request) {
+	var branch Branch
+	if err := decodeRequest(r, &branch); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	branch.normalize()
+	if errs := validateRequest(branch); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	branch.Id = 0
+	branch.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	branch.UpdatedAt = branch.CreatedAt
+
+	if err := store.CreateBranch(r.Context(), &branch); err != nil {
+		writeProblem(w, r, codeInternal, "Error creating branch")
+		log.Printf("Branch creation error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, BranchResponse{
+		Status:  "success",
+		Message: "Branch created successfully",
+		Data:    branch,
+	})
+}
+
+// Replace a branch's name, address and location.
+func updateBranchHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid branch ID")
+		return
+	}
+	var req Branch
+	if err := decodeRequest(r, &req); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	req.normalize()
+	if errs := validateRequest(req); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	var branch Branch
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		var err error
+		if branch, err = tx.GetBranch(r.Context(), id); err != nil {
+			return err
+		}
+		branch.Name, branch.Address, branch.Latitude, branch.Longitude = req.Name, req.Address, req.Latitude, req.Longitude
+		branch.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		return tx.UpdateBranch(r.Context(), branch)
+	})
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBranchNotFound, "Branch not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error updating branch")
+		log.Printf("Branch update error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, BranchResponse{
+		Status:  "success",
+		Message: "Branch updated successfully",
+		Data:    branch,
+	})
+}
+
+// Delete a branch. Its copies stay in the catalog, at no branch.
+func deleteBranchHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid branch ID")
+		return
+	}
+
+	err = store.DeleteBranch(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBranchNotFound, "Branch not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error deleting branch")
+		log.Printf("Branch deletion error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Branch deleted successfully",
+	})
+}
+
+// Answer with the branches that have a copy of a book free to borrow,
+// nearest to the reader at lat and lng first.
+func nearbyBranchesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+	lat, errLat := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
+	lng, errLng := strconv.ParseFloat(r.URL.Query().Get("lng"), 64)
+	// NaN compares false with everything, so it is kept out by asking for
+	// the range rather than against it.
+	if errLat != nil || errLng != nil || !(lat >= -90 && lat <= 90) || !(lng >= -180 && lng <= 180) {
+		writeProblem(w, r, codeInvalidParameter, "lat and lng must be a latitude and longitude in degrees")
+		return
+	}
+	limit, ok := intParameter(r, "limit", defaultNearbyBranches, maxNearbyBranches)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(maxNearbyBranches))
+		return
+	}
+
+	copies, err := store.ListCopies(r.Context(), id, ChangedAfter{})
+	var holds []Hold
+	if err == nil {
+		holds, err = store.ListHolds(r.Context(), id, ChangedAfter{})
+	}
+	var branches []Branch
+	if err == nil {
+		branches, err = store.ListBranches(r.Context(), ChangedAfter{})
+	}
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching nearby branches")
+		log.Printf("Nearby branch query error: %v", err)
+		return
+	}
+
+	nearby := nearbyBranches(branches, copies, holds, lat, lng)
+	writeResponse(w, r, http.StatusOK, NearbyBranchesResponse{
+		Status:  "success",
+		Message: "Nearby branches retrieved successfully",
+		Data:    nearby[:min(limit, len(nearby))],
+	})
+}
+
+// The branches with a copy of a book free to borrow, nearest to lat and
+// lng first. Copies are kept for ready holds as bookAvailability keeps
+// them, so the branches add up to what it counts.
+func nearbyBranches(branches []Branch, copies []Copy, holds []Hold, lat, lng float64) []NearbyBranch {
+	var ready int
+	for _, hold := range holds {
+		if hold.ReadyAt != nil {
+			ready++
+		}
+	}
+	available := map[int]int{}
+	for _, copy := range copies {
+		switch {
+		case copy.Loan != nil:
+		case ready > 0:
+			ready--
+		case copy.BranchId != nil:
+			available[*copy.BranchId]++
+		}
+	}
+
+	nearby := []NearbyBranch{}
+	for _, branch := range branches {
+		if available[branch.Id] == 0 {
+			continue
+		}
+		nearby = append(nearby, NearbyBranch{
+			Branch:     branch,
+			DistanceKm: math.Round(distanceKm(lat, lng, *branch.Latitude, *branch.Longitude)*10) / 10,
+			Available:  available[branch.Id],
+		})
+	}
+	sort.SliceStable(nearby, func(i, j int) bool {
+		return nearby[i].DistanceKm < nearby[j].DistanceKm
+	})
+	return nearby
+}
+
+// The great-circle distance between two points, in kilometres, by the
+// haversine formula.
+func distanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	const radians = math.Pi / 180
+	sinLat := math.Sin((lat2 - lat1) * radians / 2)
+	sinLng := math.Sin((lng2 - lng1) * radians / 2)
+	h := sinLat*sinLat + math.Cos(lat1*radians)*math.Cos(lat2*radians)*sinLng*sinLng
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(min(h, 1)))
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Cache is a key/value store for serialized read results.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte)
+	Delete(ctx context.Context, keys ...string)
+	// Clear drops every entry written by this cache.
+	Clear(ctx context.Context)
+}
+
+// Open the cache backend named in the config, or nil if caching is off.
+func openCache(cfg Config) (Cache, error) {
+	switch cfg.Cache {
+	case "", "none":
+		return nil, nil
+	case "memory":
+		return newMemoryCache(cfg.CacheTTL.Duration), nil
+	case "redis":
+		return newRedisCache(cfg.RedisURL, cfg.CacheTTL.Duration)
+	default:
+		return nil, fmt.Errorf("unsupported cache %q", cfg.Cache)
+	}
+}
+
+// memoryCache keeps entries in a map until their TTL runs out.
+type memoryCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+func newMemoryCache(ttl time.Duration) *memoryCache {
+	return &memoryCache{
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+func (c *memoryCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (c *memoryCache) Set(ctx context.Context, key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = cacheEntry{value: value, expires: time.Now().Add(c.ttl)}
+}
+
+func (c *memoryCache) Delete(ctx context.Context, keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+}
+
+func (c *memoryCache) Clear(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]cacheEntry)
+}
+
+// cachedStore serves book reads from a Cache and invalidates the affected
+// entries on every write. Methods it doesn't override go straight to the
+// wrapped store, so any new method that writes books must be added here.
+type cachedStore struct {
+	BookStore
+	cache Cache
+	// pending collects invalidations inside WithTx; they are applied once
+	// the transaction has ended so readers can't re-cache uncommitted state.
+	pending *cacheInvalidation
+}
+
+type cacheInvalidation struct {
+	keys  []string
+	clear bool
+}
+
+// Keys name the tenant, as the memory store's book ids are only unique
+// within one.
+func booksCacheKey(ctx context.Context) string {
+	return fmt.Sprintf("tenant:%d:books", tenantId(ctx))
+}
+
+func bookCacheKey(ctx context.Context, id int) string {
+	return fmt.Sprintf("tenant:%d:book:%d", tenantId(ctx), id)
+}
+
+func newCachedStore(store BookStore, cache Cache) *cachedStore {
+	return &cachedStore{BookStore: store, cache: cache}
+}
+
+// Return the cached value for key, or load it and cache the result.
+func cached[T any](ctx context.Context, s *cachedStore, key string, load func() (T, error)) (T, error) {
+	// Reads inside a transaction must see (and lock) the database rows.
+	if s.pending != nil {
+		return load()
+	}
+
+	var value T
+	if data, ok := s.cache.Get(ctx, key); ok {
+		if err := json.Unmarshal(data, &value); err == nil {
+			return value, nil
+		}
+	}
+
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("Cache encoding error: %v", err)
+		return value, nil
+	}
+	s.cache.Set(ctx, key, data)
+	return value, nil
+}
+
+func (s *cachedStore) invalidate(ctx context.Context, keys ...string) {
+	if s.pending != nil {
+		s.pending.keys = append(s.pending.keys, keys...)
+		return
+	}
+	s.cache.Delete(ctx, keys...)
+}
+
+func (s *cachedStore) ListBooks(ctx context.Context) ([]Book, error) {
+	return cached(ctx, s, booksCacheKey(ctx), func() ([]Book, error) {
+		return s.BookStore.ListBooks(ctx)
+	})
+}
+
+func (s *cachedStore) GetBook(ctx context.Context, id int) (Book, error) {
+	return cached(ctx, s, bookCacheKey(ctx, id), func() (Book, error) {
+		return s.BookStore.GetBook(ctx, id)
+	})
+}
+
+func (s *cachedStore) CreateBook(ctx context.Context, book *Book) error {
+	if err := s.BookStore.CreateBook(ctx, book); err != nil {
+		return err
+	}
+	s.invalidate(ctx, booksCacheKey(ctx))
+	return nil
+}
+
+func (s *cachedStore) UpdateBook(ctx context.Context, id int, book Book) (Book, error) {
+	updated, err := s.BookStore.UpdateBook(ctx, id, book)
+	s.invalidate(ctx, booksCacheKey(ctx), bookCacheKey(ctx, id))
+	return updated, err
+}
+
+func (s *cachedStore) SetPrices(ctx context.Context, prices map[int]float64) ([]Book, error) {
+	books, err := s.BookStore.SetPrices(ctx, prices)
+	keys := []string{booksCacheKey(ctx)}
+	for id := range prices {
+		keys = append(keys, bookCacheKey(ctx, id))
+	}
+	s.invalidate(ctx, keys...)
+	return books, err
+}
+
+func (s *cachedStore) ArchiveBooks(ctx context.Context, before, now time.Time) ([]int, []int, error) {
+	archived, restored, err := s.BookStore.ArchiveBooks(ctx, before, now)
+	keys := []string{booksCacheKey(ctx)}
+	for _, ids := range [][]int{archived, restored} {
+		for _, id := range ids {
+			keys = append(keys, bookCacheKey(ctx, id))
+		}
+	}
+	s.invalidate(ctx, keys...)
+	return archived, restored, err
+}
+
+func (s *cachedStore) MergeBook(ctx context.Context, duplicateId, survivorId int, mergedAt time.Time) error {
+	err := s.BookStore.MergeBook(ctx, duplicateId, survivorId, mergedAt)
+	s.invalidate(ctx, booksCacheKey(ctx), bookCacheKey(ctx, duplicateId), bookCacheKey(ctx, survivorId))
+	return err
+}
+
+func (s *cachedStore) DeleteBook(ctx context.Context, id int) error {
+	err := s.BookStore.DeleteBook(ctx, id)
+	s.invalidate(ctx, booksCacheKey(ctx), bookCacheKey(ctx, id))
+	return err
+}
+
+func (s *cachedStore) DeleteAllBooks(ctx context.Context) (int64, error) {
+	n, err := s.BookStore.DeleteAllBooks(ctx)
+	if s.pending != nil {
+		s.pending.clear = true
+	} else {
+		s.cache.Clear(ctx)
+	}
+	return n, err
+}
+
+func (s *cachedStore) RestoreCatalog(ctx context.Context, books []Book, reviews []Review) error {
+	err := s.BookStore.RestoreCatalog(ctx, books, reviews)
+	if s.pending != nil {
+		s.pending.clear = true
+	} else {
+		s.cache.Clear(ctx)
+	}
+	return err
+}
+
+func (s *cachedStore) WithTx(ctx context.Context, fn func(tx BookStore) error) error {
+	if s.pending != nil {
+		return fn(s)
+	}
+
+	pending := &cacheInvalidation{}
+	err := s.BookStore.WithTx(ctx, func(tx BookStore) error {
+		return fn(&cachedStore{BookStore: tx, cache: s.cache, pending: pending})
+	})
+
+	if pending.clear {
+		s.cache.Clear(ctx)
+	} else if len(pending.keys) > 0 {
+		s.cache.Delete(ctx, pending.keys...)
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisCachePrefix namespaces our keys so Clear leaves other data alone.
+const redisCachePrefix = "bookshelf:"
+
+// redisCache shares cached reads between instances and keeps them across
+// restarts. Redis errors are logged and treated as cache misses so an
+// outage only costs us database load.
+type redisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func newRedisCache(url string, ttl time.Duration) (*redisCache, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &redisCache{client: client, ttl: ttl}, nil
+}
+
+func (c *redisCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	value, err := c.client.Get(ctx, redisCachePrefix+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Printf("Redis get error: %v", err)
+		}
+		return nil, false
+	}
+	return value, true
+}
+
+func (c *redisCache) Set(ctx context.Context, key string, value []byte) {
+	if err := c.client.Set(ctx, redisCachePrefix+key, value, c.ttl).Err(); err != nil {
+		log.Printf("Redis set error: %v", err)
+	}
+}
+
+func (c *redisCache) Delete(ctx context.Context, keys ...string) {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = redisCachePrefix + key
+	}
+	if err := c.client.Del(ctx, prefixed...).Err(); err != nil {
+		log.Printf("Redis delete error: %v", err)
+	}
+}
+
+func (c *redisCache) Clear(ctx context.Context) {
+	iter := c.client.Scan(ctx, 0, redisCachePrefix+"*", 500).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		log.Printf("Redis scan error: %v", err)
+	}
+	if len(keys) == 0 {
+		return
+	}
+	if err := c.client.Del(ctx, keys...).Err(); err != nil {
+		log.Printf("Redis delete error: %v", err)
+	}
+}
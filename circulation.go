@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CheckoutRequest is what the circulation desk scans to lend a copy: the
+// member's library card and the copy's barcode.
+type CheckoutRequest struct {
+	CardNumber string `json:"card_number" xml:"card_number" validate:"required"`
+	Barcode    string `json:"barcode" xml:"barcode" validate:"required"`
+}
+
+// CheckinRequest is the barcode of a copy brought back.
+type CheckinRequest struct {
+	Barcode string `json:"barcode" xml:"barcode" validate:"required"`
+}
+
+// Checkin is a copy brought back: the loan it ends and, if a member was
+// waiting for the book, their hold, now ready.
+type Checkin struct {
+	Loan Loan  `json:"loan" xml:"loan"`
+	Hold *Hold `json:"hold,omitempty" xml:"hold,omitempty"`
+}
+
+type CheckinResponse struct {
+	Status  string  `json:"status" xml:"status"`
+	Message string  `json:"message" xml:"message"`
+	Data    Checkin `json:"data" xml:"data"`
+}
+
+var (
+	errCardUnknown       = errors.New("no member has the card")
+	errMembershipExpired = errors.New("membership expired")
+	errLoanLimitReached  = errors.New("loan limit reached")
+	errBookOnHold        = errors.New("free copies kept for holds")
+	errCopyNotOnLoan     = errors.New("copy not on loan")
+)
+
+// Lend a copy to the member whose card was scanned, checking in one go
+// that their membership is current, that they may borrow another book and
+// that the copy isn't kept for a member with a hold on the book. A member
+// borrowing a book they held fulfils their hold.
+func checkoutHandler(w http.ResponseWriter, r *http.Request) {
+	var req CheckoutRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	req.CardNumber = strings.ToUpper(strings.TrimSpace(req.CardNumber))
+	req.Barcode = strings.TrimSpace(req.Barcode)
+	if errs := validateRequest(req); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	var loan Loan
+	err := store.WithTx(r.Context(), func(tx BookStore) error {
+		member, err := tx.GetMemberByCard(r.Context(), req.CardNumber)
+		if errors.Is(err, ErrNotFound) {
+			return errCardUnknown
+		} else if err != nil {
+			return err
+		}
+		if !member.activeAt(now) {
+			return errMembershipExpired
+		}
+		copy, err := tx.GetCopyByBarcode(r.Context(), req.Barcode)
+		if err != nil {
+			return err
+		}
+		if copy.Loan != nil {
+			return errCopyOnLoan
+		}
+
+		loans, err := tx.ListMemberLoans(r.Context(), member.Id, ChangedAfter{})
+		if err != nil {
+			return err
+		}
+		var onLoan int
+		for _, l := range loans {
+			if l.ReturnedAt == nil {
+				onLoan++
+			}
+		}
+		if onLoan >= tierLoanLimits[member.Tier] {
+			return errLoanLimitReached
+		}
+
+		if err := takeHeldCopy(r.Context(), tx, member.Id, copy.BookId); err != nil {
+			return err
+		}
+
+		book, err := tx.GetBook(r.Context(), copy.BookId)
+		if err != nil {
+			return err
+		}
+		loan = Loan{MemberId: member.Id, BookId: copy.BookId, CopyId: &copy.Id, BorrowedAt: now, DueAt: now.Add(loanPeriod), Book: &book, CreatedAt: now, UpdatedAt: now}
+		return tx.CreateLoan(r.Context(), &loan)
+	})
+	switch {
+	case errors.Is(err, errCardUnknown):
+		writeProblem(w, r, codeMemberNotFound, "No member has this card")
+		return
+	case errors.Is(err, errMembershipExpired):
+		writeProblem(w, r, codeMembershipExpired, "The membership has expired")
+		return
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeCopyNotFound, "No copy has this barcode")
+		return
+	case errors.Is(err, errCopyOnLoan):
+		writeProblem(w, r, codeCopyOnLoan, "The copy is on loan")
+		return
+	case errors.Is(err, errLoanLimitReached):
+		writeProblem(w, r, codeLoanLimitReached, "The member can't borrow more books")
+		return
+	case errors.Is(err, errBookOnHold):
+		writeProblem(w, r, codeBookOnHold, "The copy is kept for a member with a hold")
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error checking out copy")
+		log.Printf("Checkout error: %v", err)
+		return
+	}
+	activity.count(r.Context(), loan.BookId, ActivityLoan)
+
+	writeResponse(w, r, http.StatusCreated, LoanResponse{
+		Status:  "success",
+		Message: "Copy checked out successfully",
+		Data:    loan,
+	})
+}
+
+// Check that a member may take a copy of the book given its holds:
+// fulfilling their own hold, or when more copies are free than members
+// wait for.
+func takeHeldCopy(ctx context.Context, tx BookStore, memberId, bookId int) error {
+	holds, err := tx.ListHolds(ctx, bookId, ChangedAfter{})
+	if err != nil || len(holds) == 0 {
+		return err
+	}
+	for _, hold := range holds {
+		if hold.MemberId == memberId {
+			return tx.DeleteHold(ctx, hold.Id)
+		}
+	}
+
+	copies, err := tx.ListCopies(ctx, bookId, ChangedAfter{})
+	if err != nil {
+		return err
+	}
+	var free int
+	for _, copy := range copies {
+		if copy.Loan == nil {
+			free++
+		}
+	}
+	if free <= len(holds) {
+		return errBookOnHold
+	}
+	return nil
+}
+
+// End the loan of a copy brought back, fining the member finePerDay for
+// each day it is late. If members are waiting for the book, the copy is
+// set aside for the first of them, who is told by email that it is ready
+// to pick up.
+func checkinHandler(finePerDay float64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CheckinRequest
+		if err := decodeRequest(r, &req); err != nil {
+			writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+			return
+		}
+		req.Barcode = strings.TrimSpace(req.Barcode)
+		if errs := validateRequest(req); errs != nil {
+			writeValidationErrors(w, r, errs)
+			return
+		}
+
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		var checkin Checkin
+		err := store.WithTx(r.Context(), func(tx BookStore) error {
+			copy, err := tx.GetCopyByBarcode(r.Context(), req.Barcode)
+			if err != nil {
+				return err
+			}
+			if copy.Loan == nil {
+				return errCopyNotOnLoan
+			}
+			checkin.Loan = *copy.Loan
+			checkin.Loan.ReturnedAt, checkin.Loan.UpdatedAt = &now, now
+			if err := tx.ReturnLoan(r.Context(), checkin.Loan.Id, now); err != nil {
+				return err
+			}
+			book, err := tx.GetBook(r.Context(), copy.BookId)
+			if err != nil {
+				return err
+			}
+			checkin.Loan.Book = &book
+			if checkin.Loan.Fine, err = assessOverdueFine(r.Context(), tx, checkin.Loan, book.Title, finePerDay); err != nil {
+				return err
+			}
+
+			holds, err := tx.ListHolds(r.Context(), copy.BookId, ChangedAfter{})
+			if err != nil {
+				return err
+			}
+			for _, hold := range holds {
+				if hold.ReadyAt != nil {
+					continue
+				}
+				if err := tx.SetHoldReady(r.Context(), hold.Id, now); err != nil {
+					return err
+				}
+				member, err := tx.GetMember(r.Context(), hold.MemberId)
+				if err != nil {
+					return err
+				}
+				if err := recordMemberAccess(r.Context(), tx, &member.Id, AccessMember); err != nil {
+					return err
+				}
+				hold.ReadyAt, hold.UpdatedAt, hold.Member = &now, now, &member
+				checkin.Hold = &hold
+				break
+			}
+			return nil
+		})
+		switch {
+		case errors.Is(err, ErrNotFound):
+			writeProblem(w, r, codeCopyNotFound, "No copy has this barcode")
+			return
+		case errors.Is(err, errCopyNotOnLoan):
+			writeProblem(w, r, codeCopyNotOnLoan, "The copy isn't on loan")
+			return
+		case err != nil:
+			writeProblem(w, r, codeInternal, "Error checking in copy")
+			log.Printf("Checkin error: %v", err)
+			return
+		}
+		if hold := checkin.Hold; hold != nil && hold.Member.Email != "" {
+			book := checkin.Loan.Book
+			_, err := notify(r.Context(), "email", hold.Member.Email, NotificationHoldAvailable, map[string]string{
+				"title":     book.Title,
+				"author":    book.Author,
+				"pickup_by": now.Add(holdPickupPeriod).Format("January 2, 2006"),
+			})
+			// Without email configured the desk tells the member itself.
+			if err != nil && !errors.Is(err, errChannelUnavailable) {
+				log.Printf("Hold notification error: %v", err)
+			}
+		}
+
+		writeResponse(w, r, http.StatusOK, CheckinResponse{
+			Status:  "success",
+			Message: "Copy checked in successfully",
+			Data:    checkin,
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+func addCommand() *cobra.Command {
+	var b book
+	cmd := &cobra.Command{
+		Use:   "add",
+		Short: "Add a book",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			var created book
+			if _, err := c.do("POST", "/book", nil, b, &created); err != nil {
+				return err
+			}
+			return printBooks([]book{created})
+		},
+	}
+	cmd.Flags().StringVar(&b.Title, "title", "", "title (required)")
+	cmd.Flags().StringVar(&b.Author, "author", "", "author (required)")
+	cmd.Flags().Float64Var(&b.Price, "price", 0, "price")
+	cmd.Flags().StringVar(&b.ISBN, "isbn", "", "ISBN-10 or ISBN-13")
+	cmd.MarkFlagRequired("title")
+	cmd.MarkFlagRequired("author")
+	return cmd
+}
+
+// Flags for a page of books.
+type pageFlags struct {
+	page, perPage int
+}
+
+func (p *pageFlags) register(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&p.page, "page", 1, "page to show")
+	cmd.Flags().IntVar(&p.perPage, "per-page", 20, "books per page (at most 100)")
+}
+
+func (p *pageFlags) query() url.Values {
+	return url.Values{"page": {strconv.Itoa(p.page)}, "per_page": {strconv.Itoa(p.perPage)}}
+}
+
+// Print the page of books the API path answers with.
+func listBooks(path string, query url.Values) error {
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	var books []book
+	if _, err := c.do("GET", path, query, nil, &books); err != nil {
+		return err
+	}
+	if books == nil {
+		books = []book{}
+	}
+	return printBooks(books)
+}
+
+func listCommand() *cobra.Command {
+	var p pageFlags
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the books in the catalog",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listBooks("/books", p.query())
+		},
+	}
+	p.register(cmd)
+	return cmd
+}
+
+func searchCommand() *cobra.Command {
+	var p pageFlags
+	cmd := &cobra.Command{
+		Use:   "search QUERY",
+		Short: "Search books by title, author or ISBN",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := p.query()
+			query.Set("q", args[0])
+			return listBooks("/books/search", query)
+		},
+	}
+	p.register(cmd)
+	return cmd
+}
+
+func deleteCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete ID...",
+		Short: "Delete books and their reviews",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, arg := range args {
+				if _, err := strconv.Atoi(arg); err != nil {
+					return fmt.Errorf("invalid book id %q", arg)
+				}
+			}
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			for _, id := range args {
+				if _, err := c.do("DELETE", "/book/"+id, nil, nil, nil); err != nil {
+					return fmt.Errorf("book %s: %w", id, err)
+				}
+				fmt.Printf("Deleted book %s\n", id)
+			}
+			return nil
+		},
+	}
+}
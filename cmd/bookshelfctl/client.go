@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const apiPrefix = "/api/v1"
+
+// book is a book as the API sends and takes it.
+type book struct {
+	Id     int     `json:"id,omitempty"`
+	Title  string  `json:"title"`
+	Author string  `json:"author"`
+	Price  float64 `json:"price"`
+	ISBN   string  `json:"isbn,omitempty"`
+	Slug   string  `json:"slug,omitempty"`
+}
+
+// response is the envelope of every successful API response.
+type response struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// problem is an API error response.
+type problem struct {
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
+	Code   string `json:"code"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (p *problem) Error() string {
+	msg := p.Detail
+	if msg == "" || len(p.Errors) > 0 {
+		msg = p.Title
+	}
+	msg = fmt.Sprintf("%s (%s)", msg, p.Code)
+	// Validation problems list every broken rule.
+	for _, e := range p.Errors {
+		msg += "\n  " + e.Message
+	}
+	return msg
+}
+
+type client struct {
+	creds credentials
+	http  *http.Client
+}
+
+func newClient() (*client, error) {
+	creds, err := currentCredentials()
+	if err != nil {
+		return nil, err
+	}
+	return &client{creds: creds, http: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// Send a request to the API path and decode the response's data into
+// data, if not nil. Error responses are returned as *problem.
+func (c *client) do(method, path string, query url.Values, body, data any) (response, error) {
+	var resp response
+	u := strings.TrimRight(c.creds.URL, "/") + apiPrefix + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return resp, err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return resp, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.creds.Tenant != "" {
+		req.Header.Set("X-Tenant", c.creds.Tenant)
+	}
+	if c.creds.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.creds.Token)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return resp, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		p := &problem{Status: res.StatusCode, Title: res.Status}
+		if err := json.NewDecoder(res.Body).Decode(p); err != nil || p.Code == "" {
+			return resp, fmt.Errorf("%s %s: %s", method, u, res.Status)
+		}
+		return resp, p
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return resp, fmt.Errorf("%s %s: reading response: %w", method, u, err)
+	}
+	if data != nil && len(resp.Data) > 0 {
+		if err := json.Unmarshal(resp.Data, data); err != nil {
+			return resp, fmt.Errorf("%s %s: reading response: %w", method, u, err)
+		}
+	}
+	return resp, nil
+}
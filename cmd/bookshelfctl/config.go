@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const defaultURL = "http://localhost:8080"
+
+// credentials are what login saves: where the server is, the tenant to
+// work on and the admin token to send it.
+type credentials struct {
+	URL    string `json:"url"`
+	Tenant string `json:"tenant,omitempty"`
+	Token  string `json:"token,omitempty"`
+}
+
+// The file login saves credentials in, in the user's config directory.
+func credentialsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bookshelf", "credentials.json"), nil
+}
+
+// Read the saved credentials; none saved yet isn't an error.
+func loadCredentials() (credentials, error) {
+	var creds credentials
+	path, err := credentialsPath()
+	if err != nil {
+		return creds, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return creds, nil
+	} else if err != nil {
+		return creds, err
+	}
+	return creds, json.Unmarshal(data, &creds)
+}
+
+// Save credentials readable only by the user, since the token grants admin
+// access.
+func saveCredentials(creds credentials) (string, error) {
+	path, err := credentialsPath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// The credentials to use: each from its flag, else the environment, else
+// the saved ones.
+func currentCredentials() (credentials, error) {
+	creds, err := loadCredentials()
+	if err != nil {
+		return creds, err
+	}
+	if v := os.Getenv("BOOKSHELF_URL"); v != "" {
+		creds.URL = v
+	}
+	if v := os.Getenv("BOOKSHELF_TENANT"); v != "" {
+		creds.Tenant = v
+	}
+	if v := os.Getenv("BOOKSHELF_ADMIN_TOKEN"); v != "" {
+		creds.Token = v
+	}
+	if flagURL != "" {
+		creds.URL = flagURL
+	}
+	if flagTenant != "" {
+		creds.Tenant = flagTenant
+	}
+	if flagToken != "" {
+		creds.Token = flagToken
+	}
+	if creds.URL == "" {
+		creds.URL = defaultURL
+	}
+	return creds, nil
+}
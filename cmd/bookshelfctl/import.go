@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func importCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "import FILE",
+		Short: "Add the books in a CSV or JSON file",
+		Long: `Add the books in a CSV file, with a header row naming the columns as the
+API's CSV export does (title, author, price, isbn; id and slug are
+ignored), or in a JSON array of books. Books the server rejects are
+reported and skipped.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			books, err := readBooks(args[0])
+			if err != nil {
+				return err
+			}
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			failed := 0
+			for i, b := range books {
+				if _, err := c.do("POST", "/book", nil, b, nil); err != nil {
+					fmt.Fprintf(os.Stderr, "Book %d (%q): %v\n", i+1, b.Title, err)
+					failed++
+				}
+			}
+			fmt.Printf("Imported %d of %d books\n", len(books)-failed, len(books))
+			if failed > 0 {
+				return fmt.Errorf("%d books weren't imported", failed)
+			}
+			return nil
+		},
+	}
+}
+
+// Read books from a .csv or .json file.
+func readBooks(path string) ([]book, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return readBooksCSV(f)
+	case ".json":
+		var books []book
+		if err := json.NewDecoder(f).Decode(&books); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return books, nil
+	default:
+		return nil, fmt.Errorf("%s: only .csv and .json files can be imported", path)
+	}
+}
+
+func readBooksCSV(r io.Reader) ([]book, error) {
+	rows := csv.NewReader(r)
+	header, err := rows.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading the header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"title", "author"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("the header has no %s column", name)
+		}
+	}
+	field := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	var books []book
+	for {
+		row, err := rows.Read()
+		if errors.Is(err, io.EOF) {
+			return books, nil
+		} else if err != nil {
+			return nil, err
+		}
+		b := book{Title: field(row, "title"), Author: field(row, "author"), ISBN: field(row, "isbn")}
+		if price := field(row, "price"); price != "" {
+			line, _ := rows.FieldPos(0)
+			if b.Price, err = strconv.ParseFloat(price, 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid price %q", line, price)
+			}
+		}
+		books = append(books, b)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func loginCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "login",
+		Short: "Save the server URL, tenant and admin token for later commands",
+		Long: `Save the server URL (--url), tenant (--tenant) and admin token
+(--token, or read from standard input) for later commands, after checking
+them against the server. Without a token only the public API can be used.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flagToken == "" && os.Getenv("BOOKSHELF_ADMIN_TOKEN") == "" {
+				fmt.Fprint(os.Stderr, "Admin token (empty for none): ")
+				line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+				if err != nil && line == "" {
+					return fmt.Errorf("reading the token: %w", err)
+				}
+				flagToken = strings.TrimSpace(line)
+			}
+
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			// Listing webhooks needs the token, so it checks both.
+			path := "/stats"
+			if c.creds.Token != "" {
+				path = "/webhooks"
+			}
+			if _, err := c.do("GET", path, nil, nil, nil); err != nil {
+				return fmt.Errorf("checking the credentials: %w", err)
+			}
+
+			saved, err := saveCredentials(c.creds)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Logged in to %s; credentials saved in %s\n", c.creds.URL, saved)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,45 @@
+// Command bookshelfctl is a command line client for the bookshelf HTTP
+// API, for scripting and quick admin work from a terminal.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// Global flags; empty ones fall back to the environment, then to the
+// credentials saved by login.
+var (
+	flagURL    string
+	flagTenant string
+	flagToken  string
+	flagOutput string
+)
+
+func main() {
+	root := &cobra.Command{
+		Use:           "bookshelfctl",
+		Short:         "Manage a bookshelf catalog over its HTTP API",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if flagOutput != "table" && flagOutput != "json" {
+				return fmt.Errorf("--output must be table or json, not %q", flagOutput)
+			}
+			return nil
+		},
+	}
+	root.PersistentFlags().StringVar(&flagURL, "url", "", "server URL (default $BOOKSHELF_URL, the saved one or "+defaultURL+")")
+	root.PersistentFlags().StringVar(&flagTenant, "tenant", "", "tenant slug (default $BOOKSHELF_TENANT, the saved one or the server's default tenant)")
+	root.PersistentFlags().StringVar(&flagToken, "token", "", "admin token (default $BOOKSHELF_ADMIN_TOKEN or the saved one)")
+	root.PersistentFlags().StringVarP(&flagOutput, "output", "o", "table", "output format: table or json")
+
+	root.AddCommand(loginCommand(), addCommand(), listCommand(), searchCommand(), deleteCommand(), importCommand())
+
+	if err := root.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+// Print books as a table or, with -o json, as a JSON array.
+func printBooks(books []book) error {
+	if flagOutput == "json" {
+		return printJSON(books)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTITLE\tAUTHOR\tPRICE\tISBN")
+	for _, b := range books {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", b.Id, b.Title, b.Author, strconv.FormatFloat(b.Price, 'f', 2, 64), b.ISBN)
+	}
+	return w.Flush()
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// The articles a title can start with in each language, left out when
+// sorting by title in it so "The Hobbit" sorts under H.
+var leadingArticles = map[language.Base][]string{
+	mustBase("en"): {"the ", "a ", "an "},
+	mustBase("fr"): {"le ", "la ", "les ", "l'", "l’", "un ", "une "},
+	mustBase("de"): {"der ", "die ", "das ", "ein ", "eine "},
+	mustBase("es"): {"el ", "la ", "los ", "las ", "un ", "una "},
+	mustBase("it"): {"il ", "lo ", "la ", "i ", "gli ", "le ", "l'", "l’", "un ", "uno ", "una "},
+	mustBase("pt"): {"o ", "a ", "os ", "as ", "um ", "uma "},
+	mustBase("nl"): {"de ", "het ", "een "},
+}
+
+func mustBase(s string) language.Base {
+	return language.MustParseBase(s)
+}
+
+// bookSort orders books as ?sort= asks: by title as readers of a
+// language expect, accented letters where its alphabet puts them and
+// leading articles left out, or newest first by when they were added or
+// last changed.
+type bookSort struct {
+	// by is "title", RecentAdded or RecentUpdated.
+	by         string
+	collator   *collate.Collator
+	articles   []string
+	descending bool
+}
+
+// The sort asked for with ?sort=title, or -title for Z to A, recent for
+// the books added last first or updated for those changed last first, or
+// nil if none was. A title sort's language is ?locale=, or else the one
+// Accept-Language ranks highest, or English; any language can be asked
+// for, not only those messages are translated into.
+func requestedBookSort(r *http.Request) (*bookSort, error) {
+	var descending bool
+	switch r.URL.Query().Get("sort") {
+	case "":
+		return nil, nil
+	case "title":
+	case "-title":
+		descending = true
+	case "recent":
+		return &bookSort{by: RecentAdded}, nil
+	case "updated":
+		return &bookSort{by: RecentUpdated}, nil
+	default:
+		return nil, errors.New("sort must be title, -title, recent or updated")
+	}
+
+	tag := language.English
+	if locale := r.URL.Query().Get("locale"); locale != "" {
+		var err error
+		if tag, err = language.Parse(locale); err != nil {
+			return nil, errors.New("locale must be a language tag such as en or fr-CA")
+		}
+	} else if tags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language")); err == nil && len(tags) > 0 {
+		tag = tags[0]
+	}
+	base, _ := tag.Base()
+	return &bookSort{
+		by:         "title",
+		collator:   collate.New(tag),
+		articles:   leadingArticles[base],
+		descending: descending,
+	}, nil
+}
+
+// The fields to load for books about to be sorted and then cut down to
+// fields: they need what they are sorted by.
+func (s *bookSort) fields(fields []string) []string {
+	if s == nil || fields == nil {
+		return fields
+	}
+	need := "title"
+	switch s.by {
+	case RecentAdded:
+		need = "created_at"
+	case RecentUpdated:
+		need = "updated_at"
+	}
+	if slices.Contains(fields, need) {
+		return fields
+	}
+	return append(slices.Clone(fields), need)
+}
+
+// Sort books by title, those with the same title by id, or newest first.
+func (s *bookSort) sort(books []Book) {
+	if s == nil {
+		return
+	}
+	if s.by != "title" {
+		sortRecent(books, s.by)
+		return
+	}
+	var buf collate.Buffer
+	keys := make(map[int][]byte, len(books))
+	for _, book := range books {
+		keys[book.Id] = s.collator.KeyFromString(&buf, s.withoutArticle(book.Title))
+	}
+	sort.SliceStable(books, func(i, j int) bool {
+		a, b := books[i], books[j]
+		if c := bytes.Compare(keys[a.Id], keys[b.Id]); c != 0 {
+			return (c < 0) != s.descending
+		}
+		return a.Id < b.Id
+	})
+}
+
+// The title without the article it starts with, if it has more after it.
+func (s *bookSort) withoutArticle(title string) string {
+	for _, article := range s.articles {
+		if len(title) > len(article) && strings.EqualFold(title[:len(article)], article) {
+			if rest := strings.TrimSpace(title[len(article):]); rest != "" {
+				return rest
+			}
+		}
+	}
+	return title
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Collection is a named list of books a user keeps in their own order,
+// such as "Summer reading". Unlike the shelf it can be shared read-only
+// with anyone who has its link.
+type Collection struct {
+	Id     int    `json:"id" xml:"id"`
+	UserId int    `json:"-" xml:"-"`
+	Name   string `json:"name" xml:"name" validate:"required,max=100"`
+	// ShareToken makes the collection readable at ShareURL by anyone; it
+	// is empty while the collection isn't shared.
+	ShareToken string    `json:"-" xml:"-"`
+	ShareURL   string    `json:"share_url,omitempty" xml:"share_url,omitempty"`
+	CreatedAt  time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" xml:"updated_at"`
+	// Books are in the collection's order.
+	Books []Book `json:"books" xml:"books>book"`
+}
+
+func (c Collection) timestamps() (created, updated time.Time) {
+	return c.CreatedAt, c.UpdatedAt
+}
+
+// CollectionOrder is a new order for the books in a collection.
+type CollectionOrder struct {
+	BookIds []int `json:"book_ids" xml:"book_ids>id"`
+}
+
+type CollectionResponse struct {
+	Status  string     `json:"status" xml:"status"`
+	Message string     `json:"message" xml:"message"`
+	Data    Collection `json:"data" xml:"data"`
+}
+
+type CollectionsResponse struct {
+	Status  string       `json:"status" xml:"status"`
+	Message string       `json:"message" xml:"message"`
+	Data    []Collection `json:"data" xml:"data>collection"`
+}
+
+// The most books one collection holds.
+const maxCollectionBooks = 1000
+
+// Set the link a shared collection is read at, for r's host.
+func (c *Collection) setShareURL(r *http.Request) {
+	if c.ShareToken != "" {
+		c.ShareURL = requestBaseURL(r) + apiPrefix + "/collections/shared/" + c.ShareToken
+	}
+}
+
+func listCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+	collections, err := store.ListCollections(r.Context(), currentUser(r).Id, after)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching collections")
+		log.Printf("Collection query error: %v", err)
+		return
+	}
+	for i := range collections {
+		collections[i].setShareURL(r)
+	}
+
+	writeResponse(w, r, http.StatusOK, CollectionsResponse{
+		Status:  "success",
+		Message: "Collections retrieved successfully",
+		Data:    collections,
+	})
+}
+
+func createCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	var collection Collection
+	if err := decodeRequest(r, &collection); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	collection.Name = normalizeText(collection.Name)
+	if errs := validateRequest(collection); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	collection = Collection{
+		UserId:    currentUser(r).Id,
+		Name:      collection.Name,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		Books:     []Book{},
+	}
+	collection.UpdatedAt = collection.CreatedAt
+
+	if err := store.CreateCollection(r.Context(), &collection); err != nil {
+		writeProblem(w, r, codeInternal, "Error creating collection")
+		log.Printf("Collection creation error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, CollectionResponse{
+		Status:  "success",
+		Message: "Collection created successfully",
+		Data:    collection,
+	})
+}
+
+func getCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid collection ID")
+		return
+	}
+
+	collection, err := store.GetCollection(r.Context(), currentUser(r).Id, id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeCollectionNotFound, "Collection not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching collection")
+		log.Printf("Collection query error: %v", err)
+		return
+	}
+	collection.setShareURL(r)
+
+	writeResponse(w, r, http.StatusOK, CollectionResponse{
+		Status:  "success",
+		Message: "Collection retrieved successfully",
+		Data:    collection,
+	})
+}
+
+// Read a collection someone shared; no account is needed.
+func getSharedCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	collection, err := store.GetSharedCollection(r.Context(), mux.Vars(r)["token"])
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeCollectionNotFound, "Collection not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching collection")
+		log.Printf("Collection query error: %v", err)
+		return
+	}
+	collection.setShareURL(r)
+
+	writeResponse(w, r, http.StatusOK, CollectionResponse{
+		Status:  "success",
+		Message: "Collection retrieved successfully",
+		Data:    collection,
+	})
+}
+
+func deleteCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid collection ID")
+		return
+	}
+
+	err = store.DeleteCollection(r.Context(), currentUser(r).Id, id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeCollectionNotFound, "Collection not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error deleting collection")
+		log.Printf("Collection deletion error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Collection deleted successfully",
+	})
+}
+
+// Errors the changes made by changeCollection refuse a request with.
+var (
+	errCollectionBookMissing = errors.New("book not found")
+	errNotInCollection       = errors.New("book not in the collection")
+	errCollectionFull        = errors.New("collection full")
+	errCollectionOrder       = errors.New("order doesn't list the collection's books")
+)
+
+// Change the user's collection named in the URL in one transaction and
+// answer with it: change modifies the collection, which is then saved as
+// updated now, or returns one of the errors above.
+func changeCollection(w http.ResponseWriter, r *http.Request, message string, change func(tx BookStore, collection *Collection) error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid collection ID")
+		return
+	}
+
+	var collection Collection
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		var err error
+		if collection, err = tx.GetCollection(r.Context(), currentUser(r).Id, id); err != nil {
+			return err
+		}
+		if err := change(tx, &collection); err != nil {
+			return err
+		}
+		collection.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		return tx.UpdateCollection(r.Context(), collection)
+	})
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeCollectionNotFound, "Collection not found")
+		return
+	case errors.Is(err, errCollectionBookMissing):
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	case errors.Is(err, errNotInCollection):
+		writeProblem(w, r, codeBookNotFound, "Book isn't in the collection")
+		return
+	case errors.Is(err, errCollectionFull):
+		writeValidationErrors(w, r, []FieldError{newFieldError("books", "max", "must have at most %d books", maxCollectionBooks)})
+		return
+	case errors.Is(err, errCollectionOrder):
+		writeValidationErrors(w, r, []FieldError{newFieldError("book_ids", "order", "must list each book in the collection once")})
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error updating collection")
+		log.Printf("Collection update error: %v", err)
+		return
+	}
+	collection.setShareURL(r)
+
+	writeResponse(w, r, http.StatusOK, CollectionResponse{
+		Status:  "success",
+		Message: message,
+		Data:    collection,
+	})
+}
+
+// Rename a collection.
+func updateCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	var req Collection
+	if err := decodeRequest(r, &req); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	req.Name = normalizeText(req.Name)
+	if errs := validateRequest(req); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	changeCollection(w, r, "Collection updated successfully", func(tx BookStore, collection *Collection) error {
+		collection.Name = req.Name
+		return nil
+	})
+}
+
+// Add a book to the end of a collection; one already in it stays where
+// it is.
+func addCollectionBookHandler(w http.ResponseWriter, r *http.Request) {
+	bookId, err := strconv.Atoi(mux.Vars(r)["book_id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+
+	changeCollection(w, r, "Book added to collection successfully", func(tx BookStore, collection *Collection) error {
+		if slices.ContainsFunc(collection.Books, func(b Book) bool { return b.Id == bookId }) {
+			return nil
+		}
+		if len(collection.Books) >= maxCollectionBooks {
+			return errCollectionFull
+		}
+		book, err := tx.GetBook(r.Context(), bookId)
+		if errors.Is(err, ErrNotFound) {
+			return errCollectionBookMissing
+		} else if err != nil {
+			return err
+		}
+		collection.Books = append(collection.Books, book)
+		return nil
+	})
+}
+
+func removeCollectionBookHandler(w http.ResponseWriter, r *http.Request) {
+	bookId, err := strconv.Atoi(mux.Vars(r)["book_id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+
+	changeCollection(w, r, "Book removed from collection successfully", func(tx BookStore, collection *Collection) error {
+		i := slices.IndexFunc(collection.Books, func(b Book) bool { return b.Id == bookId })
+		if i < 0 {
+			return errNotInCollection
+		}
+		collection.Books = slices.Delete(collection.Books, i, i+1)
+		return nil
+	})
+}
+
+// Put the books of a collection in a new order, which lists each of them
+// once.
+func reorderCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	var order CollectionOrder
+	if err := decodeRequest(r, &order); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	changeCollection(w, r, "Collection reordered successfully", func(tx BookStore, collection *Collection) error {
+		if len(order.BookIds) != len(collection.Books) {
+			return errCollectionOrder
+		}
+		books := make(map[int]Book, len(collection.Books))
+		for _, book := range collection.Books {
+			books[book.Id] = book
+		}
+		reordered := make([]Book, 0, len(order.BookIds))
+		for _, id := range order.BookIds {
+			book, ok := books[id]
+			if !ok {
+				return errCollectionOrder
+			}
+			delete(books, id)
+			reordered = append(reordered, book)
+		}
+		collection.Books = reordered
+		return nil
+	})
+}
+
+// Share a collection read-only, giving it a link; a shared one keeps its
+// link.
+func shareCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	changeCollection(w, r, "Collection shared successfully", func(tx BookStore, collection *Collection) error {
+		if collection.ShareToken == "" {
+			secret := make([]byte, 16)
+			rand.Read(secret)
+			collection.ShareToken = hex.EncodeToString(secret)
+		}
+		return nil
+	})
+}
+
+// Stop sharing a collection; its link stops working, and sharing it again
+// makes a new one.
+func unshareCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	changeCollection(w, r, "Collection no longer shared", func(tx BookStore, collection *Collection) error {
+		collection.ShareToken = ""
+		return nil
+	})
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Compress responses with brotli or gzip, whichever the client prefers
+// in Accept-Encoding.
+func compressMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		// Upgraded connections (WebSocket) need the unwrapped writer to hijack.
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressResponse{ResponseWriter: w, encoding: encoding}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// Pick "br" or "gzip" from an Accept-Encoding header, or "" for identity.
+// Brotli wins a tie since it compresses JSON noticeably better. An
+// encoding with q=0 is refused, and "*" only stands for those the header
+// doesn't name.
+func negotiateEncoding(header string) string {
+	listed := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		listed[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, name := range []string{"br", "gzip"} {
+		q, ok := listed[name]
+		if !ok {
+			q, ok = listed["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// Report whether a response of this type is worth compressing.
+func compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "json") ||
+		strings.HasSuffix(mediaType, "xml") ||
+		mediaType == "application/javascript" ||
+		mediaType == "image/svg+xml"
+}
+
+// compressResponse decides at WriteHeader whether to compress, based on
+// the status and Content-Type the handler set.
+type compressResponse struct {
+	http.ResponseWriter
+	encoding    string
+	writer      io.WriteCloser
+	wroteHeader bool
+}
+
+func (c *compressResponse) WriteHeader(status int) {
+	if c.wroteHeader {
+		return
+	}
+	c.wroteHeader = true
+
+	h := c.Header()
+	compress := status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified &&
+		h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type"))
+
+	// The compressed bytes differ from the ones the tag was computed on,
+	// so it can only be a weak validator, also when revalidating.
+	if etag := h.Get("ETag"); (compress || status == http.StatusNotModified) && etag != "" && !strings.HasPrefix(etag, "W/") {
+		h.Set("ETag", "W/"+etag)
+	}
+
+	if compress {
+		h.Set("Content-Encoding", c.encoding)
+		h.Del("Content-Length")
+
+		if c.encoding == "br" {
+			c.writer = brotli.NewWriterLevel(c.ResponseWriter, 5)
+		} else {
+			c.writer = gzip.NewWriter(c.ResponseWriter)
+		}
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *compressResponse) Write(p []byte) (int, error) {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+	if c.writer != nil {
+		return c.writer.Write(p)
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+// Flush pushes buffered compressed data to the client, for streaming handlers.
+func (c *compressResponse) Flush() {
+	if f, ok := c.writer.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (c *compressResponse) Close() error {
+	if c.writer != nil {
+		return c.writer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header, want string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"BR", "br"},
+		{"*", "br"},
+		{"br;q=0", ""},
+		{"br;q=0, gzip;q=0", ""},
+		{"br;q=0, *", "gzip"},
+		{"gzip;q=0.8, *;q=0.5", "gzip"},
+		{"*;q=0", ""},
+		{"*;q=0, gzip", "gzip"},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Copy is one of the library's physical copies of a book, labelled with a
+// barcode of its own that the circulation desk scans.
+type Copy struct {
+	Id     int `json:"id" xml:"id"`
+	BookId int `json:"book_id" xml:"book_id"`
+	// Barcode is unique in the tenant.
+	Barcode string `json:"barcode" xml:"barcode" validate:"required,max=64"`
+	// BranchId is the branch the copy is shelved at, if it is known.
+	BranchId  *int      `json:"branch_id,omitempty" xml:"branch_id,omitempty"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	// UpdatedAt is when the copy last moved branch; its loans have times
+	// of their own.
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+	// Loan is the copy's loan while it is out.
+	Loan *Loan `json:"loan,omitempty" xml:"loan,omitempty"`
+}
+
+func (c Copy) timestamps() (created, updated time.Time) {
+	return c.CreatedAt, c.UpdatedAt
+}
+
+// CopyBranchRequest moves a copy to a branch, or to none if BranchId is
+// missing.
+type CopyBranchRequest struct {
+	BranchId *int `json:"branch_id" xml:"branch_id"`
+}
+
+type CopyResponse struct {
+	Status  string `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
+	Data    Copy   `json:"data" xml:"data"`
+}
+
+type CopiesResponse struct {
+	Status  string `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
+	Data    []Copy `json:"data" xml:"data>copy"`
+}
+
+// What a copy is doing, as patrons see it.
+const (
+	CopyAvailable = "available"
+	// CopyHeld copies are in but kept for members whose holds are ready.
+	CopyHeld    = "held"
+	CopyOnLoan  = "on_loan"
+	CopyOverdue = "overdue"
+)
+
+// CopyAvailability is a copy's status and, while it is out, when it is
+// due back.
+type CopyAvailability struct {
+	Id     int        `json:"id" xml:"id"`
+	Status string     `json:"status" xml:"status"`
+	DueAt  *time.Time `json:"due_at,omitempty" xml:"due_at,omitempty"`
+}
+
+// Availability is when a book's copies are expected back, for patrons
+// deciding whether to wait or place a hold.
+type Availability struct {
+	BookId int `json:"book_id" xml:"book_id"`
+	// Available counts the copies free to borrow now.
+	Available int `json:"available" xml:"available"`
+	// Holds is how many members are in the queue for the book, counting
+	// those whose copies are held for them.
+	Holds int `json:"holds" xml:"holds"`
+	// ExpectedBackAt is the soonest due date of the copies out, missing
+	// when none is.
+	ExpectedBackAt *time.Time `json:"expected_back_at,omitempty" xml:"expected_back_at,omitempty"`
+	// Copies are in order of when they are due back, those in first.
+	Copies []CopyAvailability `json:"copies" xml:"copies>copy"`
+}
+
+type AvailabilityResponse struct {
+	Status  string       `json:"status" xml:"status"`
+	Message string       `json:"message" xml:"message"`
+	Data    Availability `json:"data" xml:"data"`
+}
+
+var errCopyOnLoan = errors.New("copy on loan")
+
+// List a book's copies, with the loans of those that are out.
+func listCopiesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+
+	copies, err := store.ListCopies(r.Context(), id, after)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching copies")
+		log.Printf("Copy query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, CopiesResponse{
+		Status:  "success",
+		Message: "Copies retrieved successfully",
+		Data:    copies,
+	})
+}
+
+// Answer with the status of each copy of a book, when those out are due
+// back and how long the queue for it is. Copies in but kept for members
+// with ready holds aren't available to others.
+func bookAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+
+	copies, err := store.ListCopies(r.Context(), id, ChangedAfter{})
+	var holds []Hold
+	if err == nil {
+		holds, err = store.ListHolds(r.Context(), id, ChangedAfter{})
+	}
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching availability")
+		log.Printf("Availability query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, AvailabilityResponse{
+		Status:  "success",
+		Message: "Availability retrieved successfully",
+		Data:    bookAvailability(id, copies, holds, time.Now().UTC()),
+	})
+}
+
+// The availability of a book's copies as of now, given its holds.
+func bookAvailability(bookId int, copies []Copy, holds []Hold, now time.Time) Availability {
+	var ready int
+	for _, hold := range holds {
+		if hold.ReadyAt != nil {
+			ready++
+		}
+	}
+	a := Availability{BookId: bookId, Holds: len(holds), Copies: []CopyAvailability{}}
+	for _, copy := range copies {
+		c := CopyAvailability{Id: copy.Id, Status: CopyAvailable}
+		switch {
+		case copy.Loan != nil:
+			c.Status, c.DueAt = CopyOnLoan, &copy.Loan.DueAt
+			if now.After(copy.Loan.DueAt) {
+				c.Status = CopyOverdue
+			}
+			if a.ExpectedBackAt == nil || c.DueAt.Before(*a.ExpectedBackAt) {
+				a.ExpectedBackAt = c.DueAt
+			}
+		case ready > 0:
+			c.Status = CopyHeld
+			ready--
+		default:
+			a.Available++
+		}
+		a.Copies = append(a.Copies, c)
+	}
+	sort.SliceStable(a.Copies, func(i, j int) bool {
+		di, dj := a.Copies[i].DueAt, a.Copies[j].DueAt
+		if di == nil || dj == nil {
+			return di == nil && dj != nil
+		}
+		return di.Before(*dj)
+	})
+	return a
+}
+
+// Add a copy of a book, given the barcode it is labelled with and the
+// branch it is shelved at, if any.
+func createCopyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+	var copy Copy
+	if err := decodeRequest(r, &copy); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	copy.Barcode = strings.TrimSpace(copy.Barcode)
+	if errs := validateRequest(copy); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	copy = Copy{BookId: id, Barcode: copy.Barcode, BranchId: copy.BranchId, CreatedAt: time.Now().UTC().Truncate(time.Microsecond)}
+	copy.UpdatedAt = copy.CreatedAt
+
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		if _, err := tx.GetBookFields(r.Context(), id, []string{"id"}); err != nil {
+			return err
+		}
+		if err := checkBranch(r.Context(), tx, copy.BranchId); err != nil {
+			return err
+		}
+		return tx.CreateCopy(r.Context(), &copy)
+	})
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	case errors.Is(err, errNoSuchBranch):
+		writeProblem(w, r, codeBranchNotFound, "Branch not found")
+		return
+	case errors.Is(err, ErrDuplicateBarcode):
+		writeProblem(w, r, codeDuplicateBarcode, "Another copy has this barcode")
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error creating copy")
+		log.Printf("Copy creation error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, CopyResponse{
+		Status:  "success",
+		Message: "Copy created successfully",
+		Data:    copy,
+	})
+}
+
+// Delete a copy that isn't on loan. Its past loans stay in their members'
+// histories.
+func deleteCopyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid copy ID")
+		return
+	}
+
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		copy, err := tx.GetCopy(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		if copy.Loan != nil {
+			return errCopyOnLoan
+		}
+		return tx.DeleteCopy(r.Context(), id)
+	})
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeCopyNotFound, "Copy not found")
+		return
+	case errors.Is(err, errCopyOnLoan):
+		writeProblem(w, r, codeCopyOnLoan, "The copy is on loan")
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error deleting copy")
+		log.Printf("Copy deletion error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Copy deleted successfully",
+	})
+}
+
+// Move a copy to another branch, or to none.
+func setCopyBranchHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid copy ID")
+		return
+	}
+	var req CopyBranchRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	var copy Copy
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		if err := checkBranch(r.Context(), tx, req.BranchId); err != nil {
+			return err
+		}
+		if err := tx.SetCopyBranch(r.Context(), id, req.BranchId, time.Now().UTC().Truncate(time.Microsecond)); err != nil {
+			return err
+		}
+		var err error
+		copy, err = tx.GetCopy(r.Context(), id)
+		return err
+	})
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeCopyNotFound, "Copy not found")
+		return
+	case errors.Is(err, errNoSuchBranch):
+		writeProblem(w, r, codeBranchNotFound, "Branch not found")
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error moving copy")
+		log.Printf("Copy branch update error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, CopyResponse{
+		Status:  "success",
+		Message: "Copy moved successfully",
+		Data:    copy,
+	})
+}
+
+// Check the branch a copy is put at exists, returning errNoSuchBranch if
+// it doesn't; a nil branchId is no branch and always fine.
+func checkBranch(ctx context.Context, tx BookStore, branchId *int) error {
+	if branchId == nil {
+		return nil
+	}
+	_, err := tx.GetBranch(ctx, *branchId)
+	if errors.Is(err, ErrNotFound) {
+		return errNoSuchBranch
+	}
+	return err
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/csv"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const mediaTypeCSV = "text/csv"
+
+// Serve the request with csvHandler instead of next when the client asks
+// for CSV, with ?format=csv or by preferring text/csv in Accept. It goes
+// around next so its middleware, like withETag, can't buffer the stream.
+func withCSV(csvHandler, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if wantsCSV(r) {
+			csvHandler(w, r)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func wantsCSV(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "csv"
+	}
+	offers := append(slices.Clone(responseMediaTypes), mediaTypeCSV)
+	return negotiateMediaType(r.Header.Get("Accept"), offers) == mediaTypeCSV
+}
+
+func listBooksCSVHandler(w http.ResponseWriter, r *http.Request) {
+	writeBooksCSV(w, r, "")
+}
+
+func searchBooksCSVHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		writeProblem(w, r, codeInvalidParameter, "Search query is required")
+		return
+	}
+	writeBooksCSV(w, r, query)
+}
+
+// Write the books matching query as CSV rows while they are read from the
+// store, with a column per requested field. Pagination doesn't apply; the
+// export has every match. An error after the first row can only cut the
+// response short.
+func writeBooksCSV(w http.ResponseWriter, r *http.Request, query string) {
+	fields, err := requestedFields(r)
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, err.Error())
+		return
+	}
+	formats, ok := requestedFormats(r)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "book_format must be hardcover, paperback, ebook or audiobook")
+		return
+	}
+	filter, err := parseBookFilter(query)
+	if err != nil {
+		writeProblem(w, r, codeInvalidQuery, err.Error())
+		return
+	}
+	if filter != nil {
+		// Matched by the filter alone.
+		query = ""
+	}
+	createdAfter, updatedAfter, err := requestedChangedAfter(r)
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, err.Error())
+		return
+	}
+	includeArchived, ok := includeArchivedParameter(r)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "include_archived must be true or false")
+		return
+	}
+	columns := fields
+	if columns == nil {
+		columns = bookFields
+	}
+
+	out := csv.NewWriter(w)
+	started := false
+	start := func() {
+		w.Header().Add("Vary", "Accept")
+		w.Header().Set("Content-Type", mediaTypeCSV+"; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="books.csv"`)
+		w.WriteHeader(http.StatusOK)
+		out.Write(columns)
+		started = true
+	}
+
+	err = store.StreamBooks(r.Context(), BookQuery{Search: query, Fields: fields, Formats: formats, Filter: filter, CreatedAfter: createdAfter, UpdatedAfter: updatedAfter, ExcludeArchived: !includeArchived}, func(book Book) error {
+		if !started {
+			start()
+		}
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			switch value := book.field(column).(type) {
+			case *int:
+				row[i] = strconv.Itoa(*value)
+			case *string:
+				row[i] = *value
+			case *float64:
+				row[i] = strconv.FormatFloat(*value, 'f', -1, 64)
+			case **time.Time:
+				if *value != nil {
+					row[i] = (*value).Format(time.RFC3339Nano)
+				}
+			}
+		}
+		out.Write(row)
+		return out.Error()
+	})
+	if err != nil {
+		log.Printf("CSV export error: %v", err)
+		if !started {
+			writeProblem(w, r, codeInternal, "Error fetching books")
+		}
+		return
+	}
+
+	if !started {
+		start()
+	}
+	out.Flush()
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExchangeRates are the prices of currencies in Base: one Base buys
+// Rates[c] of currency c.
+type ExchangeRates struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// RateProvider fetches the current exchange rates.
+type RateProvider interface {
+	FetchRates(ctx context.Context) (ExchangeRates, error)
+}
+
+// Open the exchange rate provider selected by the config, or return nil
+// if none is.
+func openRateProvider(cfg Config) (RateProvider, error) {
+	switch cfg.ExchangeRates {
+	case "", "none":
+		return nil, nil
+	case "ecb":
+		return ecbRates{}, nil
+	case "json":
+		if cfg.ExchangeRatesURL == "" {
+			return nil, errors.New("exchange_rates json needs exchange_rates_url")
+		}
+		return jsonRates{url: cfg.ExchangeRatesURL}, nil
+	default:
+		return nil, fmt.Errorf("unknown exchange rate provider %q", cfg.ExchangeRates)
+	}
+}
+
+var ratesClient = &http.Client{Timeout: 10 * time.Second}
+
+func getRates(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := ratesClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("%s: %s", url, res.Status)
+	}
+	return res, nil
+}
+
+// The European Central Bank's reference rates, published each working
+// day, with the euro as base.
+type ecbRates struct{}
+
+const ecbRatesURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+
+func (ecbRates) FetchRates(ctx context.Context) (ExchangeRates, error) {
+	res, err := getRates(ctx, ecbRatesURL)
+	if err != nil {
+		return ExchangeRates{}, err
+	}
+	defer res.Body.Close()
+
+	var doc struct {
+		Cubes []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube>Cube>Cube"`
+	}
+	if err := xml.NewDecoder(res.Body).Decode(&doc); err != nil {
+		return ExchangeRates{}, fmt.Errorf("ecb rates: %w", err)
+	}
+	rates := ExchangeRates{Base: "EUR", Rates: map[string]float64{}}
+	for _, cube := range doc.Cubes {
+		rate, err := strconv.ParseFloat(cube.Rate, 64)
+		if err != nil {
+			return ExchangeRates{}, fmt.Errorf("ecb rates: %s: %w", cube.Currency, err)
+		}
+		rates.Rates[cube.Currency] = rate
+	}
+	return rates, nil
+}
+
+// Rates from a URL answering with {"base": "USD", "rates": {"EUR": 0.92,
+// ...}}, the format of Frankfurter, Open Exchange Rates and others.
+type jsonRates struct {
+	url string
+}
+
+func (p jsonRates) FetchRates(ctx context.Context) (ExchangeRates, error) {
+	res, err := getRates(ctx, p.url)
+	if err != nil {
+		return ExchangeRates{}, err
+	}
+	defer res.Body.Close()
+
+	var rates ExchangeRates
+	if err := json.NewDecoder(res.Body).Decode(&rates); err != nil {
+		return ExchangeRates{}, fmt.Errorf("exchange rates: %w", err)
+	}
+	if rates.Base == "" || len(rates.Rates) == 0 {
+		return ExchangeRates{}, errors.New("exchange rates: no base or rates in the response")
+	}
+	return rates, nil
+}
+
+// currencyConverter converts catalog prices, which are in base, at the
+// rates last fetched from its provider, if it has one. It keeps the last
+// rates it got when the provider is down.
+type currencyConverter struct {
+	base     string
+	provider RateProvider
+
+	mu    sync.RWMutex
+	rates ExchangeRates
+}
+
+var currencies *currencyConverter
+
+var (
+	errRatesUnavailable = errors.New("exchange rates unavailable")
+	errUnknownCurrency  = errors.New("unknown currency")
+)
+
+// Fetch the rates now, keeping the last ones if that fails; the
+// exchange_rates job.
+func (c *currencyConverter) refresh(ctx context.Context) error {
+	rates, err := c.provider.FetchRates(ctx)
+	if err != nil {
+		return err
+	}
+	rates.Rates[rates.Base] = 1
+	if _, ok := rates.Rates[c.base]; !ok {
+		return fmt.Errorf("exchange rates: no rate for the catalog currency %s", c.base)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rates = rates
+	return nil
+}
+
+// The factor that converts a catalog price to currency.
+func (c *currencyConverter) rate(currency string) (float64, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.provider == nil || c.rates.Rates == nil {
+		return 0, errRatesUnavailable
+	}
+	to, ok := c.rates.Rates[currency]
+	if !ok {
+		return 0, errUnknownCurrency
+	}
+	return to / c.rates.Rates[c.base], nil
+}
+
+// Set up currency conversion as configured and fetch the rates. The fetch
+// failing isn't fatal: conversions are unavailable until the
+// exchange_rates job gets them.
+func initCurrencies(cfg Config) error {
+	provider, err := openRateProvider(cfg)
+	if err != nil {
+		return err
+	}
+	currencies = &currencyConverter{base: cfg.Currency, provider: provider}
+	if provider == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := currencies.refresh(ctx); err != nil {
+		log.Printf("Exchange rate fetch error: %v", err)
+	}
+	return nil
+}
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Convert the prices of books to the currency asked for with ?currency=,
+// rounded to the cent, and return the currency prices are in. Without
+// the parameter prices stay in the catalog currency.
+func convertPrices(r *http.Request, books []Book) (string, error) {
+	currency := strings.ToUpper(r.URL.Query().Get("currency"))
+	if currency == "" || currency == currencies.base {
+		return currencies.base, nil
+	}
+	if !currencyCode.MatchString(currency) {
+		return "", errUnknownCurrency
+	}
+	rate, err := currencies.rate(currency)
+	if err != nil {
+		return "", err
+	}
+	for i := range books {
+		books[i].Price = math.Round(books[i].Price*rate*100) / 100
+	}
+	return currency, nil
+}
+
+// Answer a request whose prices couldn't be converted.
+func writeConversionError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errUnknownCurrency) {
+		writeProblem(w, r, codeInvalidParameter, "currency must be a known ISO 4217 currency code")
+		return
+	}
+	writeProblem(w, r, codeExchangeRatesUnavailable, "Exchange rates aren't available")
+}
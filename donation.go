@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// The conditions donated books come in.
+const (
+	ConditionNew     = "new"
+	ConditionLikeNew = "like_new"
+	ConditionGood    = "good"
+	ConditionFair    = "fair"
+	ConditionPoor    = "poor"
+)
+
+var bookConditions = []string{ConditionNew, ConditionLikeNew, ConditionGood, ConditionFair, ConditionPoor}
+
+// Where a donation is in triage.
+const (
+	DonationPending  = "pending"
+	DonationAccepted = "accepted"
+	DonationRejected = "rejected"
+)
+
+var donationStatuses = []string{DonationPending, DonationAccepted, DonationRejected}
+
+// Donation is a book given to the library. It waits in the triage queue
+// until it is accepted, entering the catalog, or rejected.
+type Donation struct {
+	Id        int    `json:"id" xml:"id"`
+	DonorName string `json:"donor_name" xml:"donor_name" validate:"required,max=255"`
+	// DonorEmail and DonorAddress are for the tax letter, if the donor
+	// wants one.
+	DonorEmail   string `json:"donor_email,omitempty" xml:"donor_email,omitempty" validate:"omitempty,email,max=255"`
+	DonorAddress string `json:"donor_address,omitempty" xml:"donor_address,omitempty" validate:"max=1000"`
+	Title        string `json:"title" xml:"title" validate:"required,max=255"`
+	Author       string `json:"author" xml:"author" validate:"required,max=255"`
+	ISBN         string `json:"isbn,omitempty" xml:"isbn,omitempty" validate:"omitempty,isbn,max=17"`
+	Condition    string `json:"condition" xml:"condition" validate:"required,book_condition"`
+	// AppraisedValue is what the book is worth, in the catalog's currency.
+	AppraisedValue float64 `json:"appraised_value" xml:"appraised_value" validate:"gte=0,lte=99999999.99"`
+	Status         string  `json:"status" xml:"status"`
+	// Note is why the donation was accepted or rejected.
+	Note string `json:"note,omitempty" xml:"note,omitempty"`
+	// BookId is the catalog entry of an accepted donation.
+	BookId     *int       `json:"book_id,omitempty" xml:"book_id,omitempty"`
+	ReceivedAt time.Time  `json:"received_at" xml:"received_at"`
+	DecidedAt  *time.Time `json:"decided_at,omitempty" xml:"decided_at,omitempty"`
+	// CreatedAt is ReceivedAt, and UpdatedAt DecidedAt once decided.
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+}
+
+func (d Donation) timestamps() (created, updated time.Time) {
+	return d.CreatedAt, d.UpdatedAt
+}
+
+// DonationQuery selects donations for ListDonations.
+type DonationQuery struct {
+	// Status keeps only donations with it; empty keeps them all.
+	Status string
+	// ReceivedFrom and ReceivedBefore bound when the donations were
+	// received if they aren't zero.
+	ReceivedFrom, ReceivedBefore time.Time
+	ChangedAfter
+}
+
+// DonationDecision is the triage decision on a donation.
+type DonationDecision struct {
+	Note string `json:"note" xml:"note" validate:"max=1000"`
+	// Barcode, when accepting, adds the donated book as a copy with it.
+	Barcode string `json:"barcode" xml:"barcode" validate:"max=64"`
+}
+
+// DonorDonations is what one donor gave in a year, for their tax letter.
+type DonorDonations struct {
+	DonorName    string     `json:"donor_name" xml:"donor_name"`
+	DonorEmail   string     `json:"donor_email,omitempty" xml:"donor_email,omitempty"`
+	DonorAddress string     `json:"donor_address,omitempty" xml:"donor_address,omitempty"`
+	TotalValue   float64    `json:"total_value" xml:"total_value"`
+	Donations    []Donation `json:"donations" xml:"donations>donation"`
+}
+
+// DonationReport is the accepted donations received in a year, by donor.
+type DonationReport struct {
+	Year       int              `json:"year" xml:"year"`
+	Currency   string           `json:"currency" xml:"currency"`
+	TotalValue float64          `json:"total_value" xml:"total_value"`
+	Donors     []DonorDonations `json:"donors" xml:"donors>donor"`
+}
+
+type DonationResponse struct {
+	Status  string   `json:"status" xml:"status"`
+	Message string   `json:"message" xml:"message"`
+	Data    Donation `json:"data" xml:"data"`
+}
+
+type DonationsResponse struct {
+	Status  string     `json:"status" xml:"status"`
+	Message string     `json:"message" xml:"message"`
+	Data    []Donation `json:"data" xml:"data>donation"`
+}
+
+type DonationReportResponse struct {
+	Status  string         `json:"status" xml:"status"`
+	Message string         `json:"message" xml:"message"`
+	Data    DonationReport `json:"data" xml:"data"`
+}
+
+var errDonationDecided = errors.New("donation already decided")
+
+// Tidy the fields as typed.
+func (d *Donation) normalize() {
+	d.DonorName = normalizeText(d.DonorName)
+	d.DonorEmail = strings.TrimSpace(d.DonorEmail)
+	d.DonorAddress = normalizeText(d.DonorAddress)
+	d.Title = normalizeText(d.Title)
+	d.Author = normalizeText(d.Author)
+	d.ISBN = strings.TrimSpace(d.ISBN)
+	d.Condition = strings.ToLower(strings.TrimSpace(d.Condition))
+}
+
+// List donations, oldest first: those with the status in ?status=, or the
+// triage queue of pending ones without it. ?status=all lists every one.
+func listDonationsHandler(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch {
+	case status == "":
+		status = DonationPending
+	case status == "all":
+		status = ""
+	case !slices.Contains(donationStatuses, status):
+		writeProblem(w, r, codeInvalidParameter, "status must be pending, accepted, rejected or all")
+		return
+	}
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+
+	donations, err := store.ListDonations(r.Context(), DonationQuery{Status: status, ChangedAfter: after})
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching donations")
+		log.Printf("Donation query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, DonationsResponse{
+		Status:  "success",
+		Message: "Donations retrieved successfully",
+		Data:    donations,
+	})
+}
+
+// Record a donated book, pending triage.
+func createDonationHandler(w http.ResponseWriter, r *http.Request) {
+	var donation Donation
+	if err := decodeRequest(r, &donation); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	donation.normalize()
+	if errs := validateRequest(donation); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	donation.Id, donation.BookId, donation.DecidedAt, donation.Note = 0, nil, nil, ""
+	donation.Status = DonationPending
+	donation.AppraisedValue = roundCents(donation.AppraisedValue)
+	donation.ReceivedAt = time.Now().UTC().Truncate(time.Microsecond)
+	donation.CreatedAt, donation.UpdatedAt = donation.ReceivedAt, donation.ReceivedAt
+
+	if err := store.CreateDonation(r.Context(), &donation); err != nil {
+		writeProblem(w, r, codeInternal, "Error creating donation")
+		log.Printf("Donation creation error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, DonationResponse{
+		Status:  "success",
+		Message: "Donation created successfully",
+		Data:    donation,
+	})
+}
+
+func getDonationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid donation ID")
+		return
+	}
+
+	donation, err := store.GetDonation(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeDonationNotFound, "Donation not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching donation")
+		log.Printf("Donation query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, DonationResponse{
+		Status:  "success",
+		Message: "Donation retrieved successfully",
+		Data:    donation,
+	})
+}
+
+// Accept a pending donation, entering it in the catalog: as the book with
+// its ISBN if there is one, else as a new book. With a barcode the book
+// also gets the donated copy.
+func acceptDonationHandler(w http.ResponseWriter, r *http.Request) {
+	decideDonation(w, r, DonationAccepted, "Donation accepted successfully")
+}
+
+func rejectDonationHandler(w http.ResponseWriter, r *http.Request) {
+	decideDonation(w, r, DonationRejected, "Donation rejected successfully")
+}
+
+// Record the triage decision on the donation with the id in the path.
+func decideDonation(w http.ResponseWriter, r *http.Request, status, message string) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid donation ID")
+		return
+	}
+	var decision DonationDecision
+	if err := decodeRequest(r, &decision); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	decision.Note = normalizeText(decision.Note)
+	decision.Barcode = strings.TrimSpace(decision.Barcode)
+	if errs := validateRequest(decision); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	var donation Donation
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		var err error
+		if donation, err = tx.GetDonation(r.Context(), id); err != nil {
+			return err
+		}
+		if donation.Status != DonationPending {
+			return errDonationDecided
+		}
+		decidedAt := time.Now().UTC().Truncate(time.Microsecond)
+		donation.Status, donation.Note, donation.DecidedAt, donation.UpdatedAt = status, decision.Note, &decidedAt, decidedAt
+		if status == DonationAccepted {
+			if err := catalogDonation(r.Context(), tx, &donation, decision.Barcode); err != nil {
+				return err
+			}
+		}
+		return tx.DecideDonation(r.Context(), donation)
+	})
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeDonationNotFound, "Donation not found")
+		return
+	case errors.Is(err, errDonationDecided):
+		writeProblem(w, r, codeDonationDecided, "The donation was already accepted or rejected")
+		return
+	case errors.Is(err, ErrDuplicateBarcode):
+		writeProblem(w, r, codeDuplicateBarcode, "Another copy has this barcode")
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error deciding donation")
+		log.Printf("Donation decision error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, DonationResponse{
+		Status:  "success",
+		Message: message,
+		Data:    donation,
+	})
+}
+
+// Enter an accepted donation in the catalog and set its BookId.
+func catalogDonation(ctx context.Context, tx BookStore, donation *Donation, barcode string) error {
+	bookId := 0
+	isbn, ok := normalizeISBN(donation.ISBN)
+	if ok {
+		id, err := tx.GetBookIdByISBN(ctx, isbn)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		bookId = id
+	}
+	if bookId == 0 {
+		book := Book{Title: donation.Title, Author: donation.Author, ISBN: isbn}
+		if err := createBook(ctx, tx, &book); err != nil {
+			return err
+		}
+		bookId = book.Id
+	}
+	donation.BookId = &bookId
+
+	if barcode == "" {
+		return nil
+	}
+	copy := Copy{BookId: bookId, Barcode: barcode, CreatedAt: *donation.DecidedAt, UpdatedAt: *donation.DecidedAt}
+	return tx.CreateCopy(ctx, &copy)
+}
+
+// Report the donations accepted that were received in ?year=, this year
+// by default, by donor, for their tax letters.
+func donationReportHandler(w http.ResponseWriter, r *http.Request) {
+	year := time.Now().UTC().Year()
+	if v := r.URL.Query().Get("year"); v != "" {
+		var err error
+		if year, err = strconv.Atoi(v); err != nil || year < 1 || year > 9999 {
+			writeProblem(w, r, codeInvalidParameter, "Invalid year")
+			return
+		}
+	}
+
+	donations, err := store.ListDonations(r.Context(), DonationQuery{
+		Status:         DonationAccepted,
+		ReceivedFrom:   time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC),
+		ReceivedBefore: time.Date(year+1, time.January, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching donations")
+		log.Printf("Donation query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, DonationReportResponse{
+		Status:  "success",
+		Message: "Donation report retrieved successfully",
+		Data:    donationReport(year, donations),
+	})
+}
+
+// Sum up the donations by donor, who is known by their email address, or
+// their name if they gave none.
+func donationReport(year int, donations []Donation) DonationReport {
+	report := DonationReport{Year: year, Currency: currencies.base, Donors: []DonorDonations{}}
+	byDonor := map[string]int{}
+	for _, donation := range donations {
+		key := strings.ToLower(donation.DonorEmail)
+		if key == "" {
+			key = "name:" + donation.DonorName
+		}
+		i, ok := byDonor[key]
+		if !ok {
+			i = len(report.Donors)
+			byDonor[key] = i
+			report.Donors = append(report.Donors, DonorDonations{DonorName: donation.DonorName, DonorEmail: donation.DonorEmail})
+		}
+		donor := &report.Donors[i]
+		// The latest donation has the donor's current address.
+		if donation.DonorAddress != "" {
+			donor.DonorAddress = donation.DonorAddress
+		}
+		donor.Donations = append(donor.Donations, donation)
+		donor.TotalValue = roundCents(donor.TotalValue + donation.AppraisedValue)
+		report.TotalValue = roundCents(report.TotalValue + donation.AppraisedValue)
+	}
+	sort.Slice(report.Donors, func(i, j int) bool {
+		return report.Donors[i].DonorName < report.Donors[j].DonorName
+	})
+	return report
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Fill in the ISBNs, covers and descriptions books lack with what the
+// metadata provider knows of them; the metadata_enrichment job, which
+// runs when an admin starts it. Books without an ISBN are searched for by
+// title and author. Fields a book has are never changed.
+func enrichBooks(ctx context.Context) error {
+	var lacking []Book
+	err := store.StreamBooks(ctx, BookQuery{}, func(book Book) error {
+		if book.ISBN == "" || book.CoverURL == "" || book.Description == "" {
+			lacking = append(lacking, book)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	reportProgress(ctx, func(progress *JobProgress) { progress.Total += len(lacking) })
+
+	failed := 0
+	for _, book := range lacking {
+		updated, err := enrichBook(ctx, book)
+		if err != nil {
+			failed++
+			log.Printf("Metadata enrichment error for book %d: %v", book.Id, err)
+		}
+		reportProgress(ctx, func(progress *JobProgress) {
+			progress.Done++
+			if updated {
+				progress.Updated++
+			}
+			if err != nil {
+				progress.Failed++
+			}
+		})
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d books couldn't be enriched", failed, len(lacking))
+	}
+	return nil
+}
+
+// Look the book up with the metadata provider and fill in what it lacks,
+// reporting whether it changed.
+func enrichBook(ctx context.Context, book Book) (bool, error) {
+	var found Book
+	var err error
+	if book.ISBN == "" {
+		found, err = bookMetadata.FindBook(ctx, book.Title, book.Author)
+	} else if isbn, ok := normalizeISBN(book.ISBN); ok {
+		found, err = bookMetadata.LookupISBN(ctx, isbn)
+	} else {
+		return false, nil
+	}
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	var update Book
+	if book.ISBN == "" {
+		update.ISBN = found.ISBN
+	}
+	if book.CoverURL == "" {
+		update.CoverURL = found.CoverURL
+	}
+	if book.Description == "" {
+		update.Description = found.Description
+	}
+	update.normalize()
+	if len(update.updatedFields()) == 0 {
+		return false, nil
+	}
+	if errs := validateFields(update, update.updatedFields()...); errs != nil {
+		return false, fmt.Errorf("invalid metadata: %s", validationMessage(errs))
+	}
+
+	_, err = updateExistingBook(ctx, store, book.Id, update)
+	if errors.Is(err, ErrDuplicateISBN) {
+		// Another book has the ISBN, likely the same book entered twice
+		// for an admin to merge; the rest can still be filled in.
+		update.ISBN = ""
+		if len(update.updatedFields()) == 0 {
+			return false, nil
+		}
+		_, err = updateExistingBook(ctx, store, book.Id, update)
+	}
+	if errors.Is(err, ErrNotFound) {
+		// Deleted since the job started.
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Error codes identify what went wrong in every error response, next to
+// the message meant for people. Clients should branch on them rather than
+// on messages, which may be reworded; codes never change once released.
+const (
+	codeInvalidRequest           = "INVALID_REQUEST"
+	codeInvalidParameter         = "INVALID_PARAMETER"
+	codeValidationFailed         = "VALIDATION_FAILED"
+	codeNoFieldsToUpdate         = "NO_FIELDS_TO_UPDATE"
+	codeInvalidISBN              = "INVALID_ISBN"
+	codeInvalidQuery             = "INVALID_QUERY"
+	codeTooManyIncluded          = "TOO_MANY_INCLUDED"
+	codeQueryTooComplex          = "QUERY_TOO_COMPLEX"
+	codeUnauthorized             = "UNAUTHORIZED"
+	codeBookNotFound             = "BOOK_NOT_FOUND"
+	codeWebhookNotFound          = "WEBHOOK_NOT_FOUND"
+	codeBackupNotFound           = "BACKUP_NOT_FOUND"
+	codePushSubscriptionNotFound = "PUSH_SUBSCRIPTION_NOT_FOUND"
+	codeTenantNotFound           = "TENANT_NOT_FOUND"
+	codeCollectionNotFound       = "COLLECTION_NOT_FOUND"
+	codeSeriesNotFound           = "SERIES_NOT_FOUND"
+	codeWorkNotFound             = "WORK_NOT_FOUND"
+	codeGoalNotFound             = "GOAL_NOT_FOUND"
+	codeNoteNotFound             = "NOTE_NOT_FOUND"
+	codeQuoteNotFound            = "QUOTE_NOT_FOUND"
+	codeSavedSearchNotFound      = "SAVED_SEARCH_NOT_FOUND"
+	codeMemberNotFound           = "MEMBER_NOT_FOUND"
+	codeLoanNotFound             = "LOAN_NOT_FOUND"
+	codeCopyNotFound             = "COPY_NOT_FOUND"
+	codeBranchNotFound           = "BRANCH_NOT_FOUND"
+	codeHoldNotFound             = "HOLD_NOT_FOUND"
+	codeFineNotFound             = "FINE_NOT_FOUND"
+	codePaymentNotFound          = "PAYMENT_NOT_FOUND"
+	codeDonationNotFound         = "DONATION_NOT_FOUND"
+	codeSupplierNotFound         = "SUPPLIER_NOT_FOUND"
+	codePurchaseOrderNotFound    = "PURCHASE_ORDER_NOT_FOUND"
+	codeSupplierPriceNotFound    = "SUPPLIER_PRICE_NOT_FOUND"
+	codeSaleNotFound             = "SALE_NOT_FOUND"
+	codeGiftCardNotFound         = "GIFT_CARD_NOT_FOUND"
+	codeJobNotFound              = "JOB_NOT_FOUND"
+	codeUserNotFound             = "USER_NOT_FOUND"
+	codeRouteNotFound            = "ROUTE_NOT_FOUND"
+	codeMethodNotAllowed         = "METHOD_NOT_ALLOWED"
+	codeDuplicateISBN            = "DUPLICATE_ISBN"
+	codeBookHasNoISBN            = "BOOK_HAS_NO_ISBN"
+	codeDuplicateEmail           = "DUPLICATE_EMAIL"
+	codeDuplicateTenant          = "DUPLICATE_TENANT"
+	codeDuplicateCardNumber      = "DUPLICATE_CARD_NUMBER"
+	codeMemberInUse              = "MEMBER_IN_USE"
+	codeLoanReturned             = "LOAN_ALREADY_RETURNED"
+	codeDuplicateBarcode         = "DUPLICATE_BARCODE"
+	codeDuplicateHold            = "DUPLICATE_HOLD"
+	codeCopyOnLoan               = "COPY_ON_LOAN"
+	codeCopyNotOnLoan            = "COPY_NOT_ON_LOAN"
+	codeBookOnHold               = "BOOK_ON_HOLD"
+	codeMembershipExpired        = "MEMBERSHIP_EXPIRED"
+	codeLoanLimitReached         = "LOAN_LIMIT_REACHED"
+	codeSavedSearchLimit         = "SAVED_SEARCH_LIMIT_REACHED"
+	codeFineSettled              = "FINE_ALREADY_SETTLED"
+	codeDonationDecided          = "DONATION_ALREADY_DECIDED"
+	codePurchaseOrderClosed      = "PURCHASE_ORDER_CLOSED"
+	codeSupplierHasOrders        = "SUPPLIER_HAS_ORDERS"
+	codeOutOfStock               = "OUT_OF_STOCK"
+	codeSaleShipped              = "SALE_ALREADY_SHIPPED"
+	codeSaleNotShipped           = "SALE_NOT_SHIPPED"
+	codeGiftCardVoided           = "GIFT_CARD_VOIDED"
+	codeGiftCardEmpty            = "GIFT_CARD_EMPTY"
+	codeNotEnoughPoints          = "NOT_ENOUGH_POINTS"
+	codeJobRunning               = "JOB_RUNNING"
+	codeJobDisabled              = "JOB_DISABLED"
+	codeIdempotencyKeyInProgress = "IDEMPOTENCY_KEY_IN_PROGRESS"
+	codeIdempotencyKeyReused     = "IDEMPOTENCY_KEY_REUSED"
+	codeRequestTooLarge          = "REQUEST_TOO_LARGE"
+	codeBackupIncompatible       = "BACKUP_INCOMPATIBLE"
+	codeBooksInUse               = "BOOKS_IN_USE"
+	codeBackupsDisabled          = "BACKUPS_DISABLED"
+	codeExchangeRatesUnavailable = "EXCHANGE_RATES_UNAVAILABLE"
+	codeMetadataUnavailable      = "METADATA_UNAVAILABLE"
+	codeNotificationsUnavailable = "NOTIFICATIONS_UNAVAILABLE"
+	codeInternal                 = "INTERNAL_ERROR"
+)
+
+// problemType is the status and title every error with a code shares.
+type problemType struct {
+	status int
+	title  string
+}
+
+var problemTypes = map[string]problemType{
+	codeInvalidRequest:           {http.StatusBadRequest, "Invalid request"},
+	codeInvalidParameter:         {http.StatusBadRequest, "Invalid parameter"},
+	codeValidationFailed:         {http.StatusBadRequest, "Validation failed"},
+	codeNoFieldsToUpdate:         {http.StatusBadRequest, "No fields to update"},
+	codeInvalidISBN:              {http.StatusBadRequest, "Invalid ISBN"},
+	codeInvalidQuery:             {http.StatusBadRequest, "Invalid search query"},
+	codeTooManyIncluded:          {http.StatusBadRequest, "Too many related resources"},
+	codeQueryTooComplex:          {http.StatusBadRequest, "Query too complex"},
+	codeUnauthorized:             {http.StatusUnauthorized, "Unauthorized"},
+	codeBookNotFound:             {http.StatusNotFound, "Book not found"},
+	codeWebhookNotFound:          {http.StatusNotFound, "Webhook not found"},
+	codeBackupNotFound:           {http.StatusNotFound, "Backup not found"},
+	codePushSubscriptionNotFound: {http.StatusNotFound, "Push subscription not found"},
+	codeTenantNotFound:           {http.StatusNotFound, "Tenant not found"},
+	codeCollectionNotFound:       {http.StatusNotFound, "Collection not found"},
+	codeSeriesNotFound:           {http.StatusNotFound, "Series not found"},
+	codeWorkNotFound:             {http.StatusNotFound, "Work not found"},
+	codeGoalNotFound:             {http.StatusNotFound, "Goal not found"},
+	codeNoteNotFound:             {http.StatusNotFound, "Note not found"},
+	codeQuoteNotFound:            {http.StatusNotFound, "Quote not found"},
+	codeSavedSearchNotFound:      {http.StatusNotFound, "Saved search not found"},
+	codeMemberNotFound:           {http.StatusNotFound, "Member not found"},
+	codeLoanNotFound:             {http.StatusNotFound, "Loan not found"},
+	codeCopyNotFound:             {http.StatusNotFound, "Copy not found"},
+	codeBranchNotFound:           {http.StatusNotFound, "Branch not found"},
+	codeHoldNotFound:             {http.StatusNotFound, "Hold not found"},
+	codeFineNotFound:             {http.StatusNotFound, "Fine not found"},
+	codePaymentNotFound:          {http.StatusNotFound, "Payment not found"},
+	codeDonationNotFound:         {http.StatusNotFound, "Donation not found"},
+	codeSupplierNotFound:         {http.StatusNotFound, "Supplier not found"},
+	codePurchaseOrderNotFound:    {http.StatusNotFound, "Purchase order not found"},
+	codeSupplierPriceNotFound:    {http.StatusNotFound, "Supplier price not found"},
+	codeSaleNotFound:             {http.StatusNotFound, "Sale not found"},
+	codeGiftCardNotFound:         {http.StatusNotFound, "Gift card not found"},
+	codeJobNotFound:              {http.StatusNotFound, "Job not found"},
+	codeUserNotFound:             {http.StatusNotFound, "User not found"},
+	codeRouteNotFound:            {http.StatusNotFound, "Not found"},
+	codeMethodNotAllowed:         {http.StatusMethodNotAllowed, "Method not allowed"},
+	codeDuplicateISBN:            {http.StatusConflict, "Duplicate ISBN"},
+	codeBookHasNoISBN:            {http.StatusUnprocessableEntity, "Book has no ISBN"},
+	codeDuplicateEmail:           {http.StatusConflict, "Duplicate email address"},
+	codeDuplicateTenant:          {http.StatusConflict, "Duplicate tenant slug"},
+	codeDuplicateCardNumber:      {http.StatusConflict, "Duplicate card number"},
+	codeMemberInUse:              {http.StatusConflict, "Member in use"},
+	codeLoanReturned:             {http.StatusConflict, "Loan already returned"},
+	codeDuplicateBarcode:         {http.StatusConflict, "Duplicate barcode"},
+	codeDuplicateHold:            {http.StatusConflict, "Duplicate hold"},
+	codeCopyOnLoan:               {http.StatusConflict, "Copy on loan"},
+	codeCopyNotOnLoan:            {http.StatusConflict, "Copy not on loan"},
+	codeBookOnHold:               {http.StatusConflict, "Book on hold"},
+	codeMembershipExpired:        {http.StatusUnprocessableEntity, "Membership expired"},
+	codeLoanLimitReached:         {http.StatusUnprocessableEntity, "Loan limit reached"},
+	codeSavedSearchLimit:         {http.StatusUnprocessableEntity, "Saved search limit reached"},
+	codeFineSettled:              {http.StatusConflict, "Fine already settled"},
+	codeDonationDecided:          {http.StatusConflict, "Donation already decided"},
+	codePurchaseOrderClosed:      {http.StatusConflict, "Purchase order closed"},
+	codeSupplierHasOrders:        {http.StatusConflict, "Supplier has orders"},
+	codeOutOfStock:               {http.StatusConflict, "Out of stock"},
+	codeSaleShipped:              {http.StatusConflict, "Sale already shipped"},
+	codeSaleNotShipped:           {http.StatusConflict, "Sale not shipped"},
+	codeGiftCardVoided:           {http.StatusConflict, "Gift card voided"},
+	codeGiftCardEmpty:            {http.StatusConflict, "Gift card empty"},
+	codeNotEnoughPoints:          {http.StatusConflict, "Not enough points"},
+	codeJobRunning:               {http.StatusConflict, "Job running"},
+	codeJobDisabled:              {http.StatusConflict, "Job disabled"},
+	codeIdempotencyKeyInProgress: {http.StatusConflict, "Request in progress"},
+	codeIdempotencyKeyReused:     {http.StatusUnprocessableEntity, "Idempotency-Key reused"},
+	codeRequestTooLarge:          {http.StatusRequestEntityTooLarge, "Request too large"},
+	codeBackupIncompatible:       {http.StatusUnprocessableEntity, "Incompatible backup"},
+	codeBooksInUse:               {http.StatusConflict, "Books in use"},
+	codeBackupsDisabled:          {http.StatusServiceUnavailable, "Backups disabled"},
+	codeExchangeRatesUnavailable: {http.StatusServiceUnavailable, "Exchange rates unavailable"},
+	codeMetadataUnavailable:      {http.StatusServiceUnavailable, "Metadata unavailable"},
+	codeNotificationsUnavailable: {http.StatusServiceUnavailable, "Notifications unavailable"},
+	codeInternal:                 {http.StatusInternalServerError, "Internal server error"},
+}
+
+// Problem is the body of every error response, an RFC 7807 problem
+// details object with the error code as an extension member. As XML it is
+// a <problem> element in the RFC's namespace.
+type Problem struct {
+	// Type identifies the kind of problem; it is the same for every
+	// problem with the same Code.
+	Type     string `json:"type" xml:"type"`
+	Title    string `json:"title" xml:"title"`
+	Status   int    `json:"status" xml:"status"`
+	Detail   string `json:"detail,omitempty" xml:"detail,omitempty"`
+	Instance string `json:"instance,omitempty" xml:"instance,omitempty"`
+	Code     string `json:"code" xml:"code"`
+	// Errors lists the violations of a VALIDATION_FAILED problem.
+	Errors []FieldError `json:"errors,omitempty" xml:"errors>error,omitempty"`
+}
+
+// The problem with the given code in answer to r, with detail saying what
+// went wrong this time.
+func newProblem(r *http.Request, code, detail string) Problem {
+	t, ok := problemTypes[code]
+	if !ok {
+		code, t = codeInternal, problemTypes[codeInternal]
+	}
+	return Problem{
+		Type:     problemTypeURI(code),
+		Title:    t.title,
+		Status:   t.status,
+		Detail:   detail,
+		Instance: r.URL.RequestURI(),
+		Code:     code,
+	}
+}
+
+// Problem types are documented at /problems/<code in kebab case>.
+func problemTypeURI(code string) string {
+	return "/problems/" + strings.ToLower(strings.ReplaceAll(code, "_", "-"))
+}
+
+// Answer r with the problem with the given code. Every error response of
+// the REST API goes through here.
+func writeProblem(w http.ResponseWriter, r *http.Request, code, detail string) {
+	problem := newProblem(r, code, detail)
+	writeResponse(w, r, problem.Status, problem)
+}
+
+// Describe the problem type a Problem's Type points at.
+func problemTypeHandler(w http.ResponseWriter, r *http.Request) {
+	for code, t := range problemTypes {
+		if problemTypeURI(code) == r.URL.Path {
+			writeResponse(w, r, http.StatusOK, Problem{
+				Type:   problemTypeURI(code),
+				Title:  t.title,
+				Status: t.status,
+				Code:   code,
+			})
+			return
+		}
+	}
+	writeProblem(w, r, codeRouteNotFound, "Unknown problem type")
+}
+
+// Answer requests for unknown routes, or with methods a route doesn't
+// allow, with problems like any other error.
+func setProblemHandlers(r *mux.Router) {
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, codeRouteNotFound, "No route matches this URL")
+	})
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, codeMethodNotAllowed, "The route doesn't allow this method")
+	})
+}
+
+// codedError is an error for the GraphQL API that reports its code in
+// the error's extensions.
+type codedError struct {
+	code    string
+	message string
+}
+
+func (e codedError) Error() string {
+	return e.message
+}
+
+func (e codedError) Extensions() map[string]any {
+	return map[string]any{"code": e.code}
+}
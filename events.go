@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Catalog change event types.
+const (
+	EventBookCreated = "book.created"
+	EventBookUpdated = "book.updated"
+	EventBookDeleted = "book.deleted"
+)
+
+// Event is a change to the catalog, pushed to subscribers after the write
+// has been committed.
+type Event struct {
+	// Id is the event's outbox id. Ids increase, though not always by one,
+	// and stay the same when an event is delivered again.
+	Id int64 `json:"id"`
+	// TenantId is the id of the tenant whose catalog changed; only its
+	// subscribers and webhooks get the event.
+	TenantId int       `json:"tenant_id"`
+	Type     string    `json:"type"`
+	Book     Book      `json:"book"`
+	Time     time.Time `json:"time"`
+}
+
+// Global live change feed for /ws and /events, published to by the
+// outbox relay.
+var events = newEventBus()
+
+// eventBus fans events out to subscribers. Publishing never blocks: a
+// subscriber that falls too far behind is dropped and has its channel
+// closed, so it can reconnect and resume or refetch.
+type eventBus struct {
+	mu sync.Mutex
+	// subscribers maps each subscriber to the id of its tenant.
+	subscribers map[chan Event]int
+	lastId      int64
+	// history holds the most recent events, oldest first, for resuming.
+	history []Event
+}
+
+const (
+	// How many events a subscriber may have queued before it is dropped.
+	subscriberBuffer = 64
+	// How many past events are kept for clients resuming a stream.
+	eventHistorySize = 1000
+)
+
+func newEventBus() *eventBus {
+	return &eventBus{subscribers: make(map[chan Event]int)}
+}
+
+// Subscribe returns a channel receiving every event published for the
+// tenant and a function that ends the subscription.
+func (b *eventBus) Subscribe(tenantId int) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.subscribe(tenantId)
+}
+
+// SubscribeSince is Subscribe for a client that has seen the events up to
+// lastId. It also returns the events it missed, or ok false if some of
+// them are no longer kept (or lastId is from before a restart), in which
+// case the client must refetch.
+func (b *eventBus) SubscribeSince(tenantId int, lastId int64) (missed []Event, ok bool, ch <-chan Event, unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ok = lastId <= b.lastId
+	if len(b.history) > 0 && lastId < b.history[0].Id-1 {
+		ok = false
+	}
+	if ok {
+		for _, event := range b.history {
+			if event.Id > lastId && event.TenantId == tenantId {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	ch, unsubscribe = b.subscribe(tenantId)
+	return missed, ok, ch, unsubscribe
+}
+
+// Add a subscriber; b.mu must be held.
+func (b *eventBus) subscribe(tenantId int) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	b.subscribers[ch] = tenantId
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (b *eventBus) Publish(events ...Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, event := range events {
+		b.lastId = max(b.lastId, event.Id)
+		if len(b.history) == eventHistorySize {
+			b.history = append(b.history[:0], b.history[1:]...)
+		}
+		b.history = append(b.history, event)
+
+		for ch, tenantId := range b.subscribers {
+			if tenantId != event.TenantId {
+				continue
+			}
+			select {
+			case ch <- event:
+			default:
+				delete(b.subscribers, ch)
+				close(ch)
+			}
+		}
+	}
+}
+
+// eventStore records an Event in the outbox for every book written
+// through it, in the same transaction as the write, for relayOutbox to
+// publish. Like cachedStore, any new method that writes books must be
+// added here.
+type eventStore struct {
+	BookStore
+	// recorded is set inside WithTx once an event has been written, to
+	// wake the relay after the commit.
+	recorded *bool
+}
+
+func newEventStore(store BookStore) *eventStore {
+	return &eventStore{BookStore: store}
+}
+
+// Run fn on the wrapped store in a transaction, joining the open one if
+// there is one, and wake the relay if it committed events.
+func (s *eventStore) inTx(ctx context.Context, fn func(inner BookStore, recorded *bool) error) error {
+	if s.recorded != nil {
+		return fn(s.BookStore, s.recorded)
+	}
+
+	recorded := false
+	err := s.BookStore.WithTx(ctx, func(tx BookStore) error {
+		return fn(tx, &recorded)
+	})
+	if err == nil && recorded {
+		wake(outboxWake)
+	}
+	return err
+}
+
+// Write an event of eventType for each book to the outbox.
+func record(ctx context.Context, inner BookStore, recorded *bool, eventType string, books ...Book) error {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	for _, book := range books {
+		if err := inner.AddOutboxEvent(ctx, &Event{Type: eventType, Book: book, Time: now}); err != nil {
+			return err
+		}
+		*recorded = true
+	}
+	return nil
+}
+
+func (s *eventStore) CreateBook(ctx context.Context, book *Book) error {
+	return s.inTx(ctx, func(inner BookStore, recorded *bool) error {
+		if err := inner.CreateBook(ctx, book); err != nil {
+			return err
+		}
+		return record(ctx, inner, recorded, EventBookCreated, *book)
+	})
+}
+
+func (s *eventStore) UpdateBook(ctx context.Context, id int, book Book) (Book, error) {
+	var updated Book
+	err := s.inTx(ctx, func(inner BookStore, recorded *bool) error {
+		var err error
+		if updated, err = inner.UpdateBook(ctx, id, book); err != nil {
+			return err
+		}
+		return record(ctx, inner, recorded, EventBookUpdated, updated)
+	})
+	return updated, err
+}
+
+func (s *eventStore) SetPrices(ctx context.Context, prices map[int]float64) ([]Book, error) {
+	var updated []Book
+	err := s.inTx(ctx, func(inner BookStore, recorded *bool) error {
+		var err error
+		if updated, err = inner.SetPrices(ctx, prices); err != nil {
+			return err
+		}
+		return record(ctx, inner, recorded, EventBookUpdated, updated...)
+	})
+	return updated, err
+}
+
+func (s *eventStore) DeleteBook(ctx context.Context, id int) error {
+	return s.inTx(ctx, func(inner BookStore, recorded *bool) error {
+		book, err := inner.GetBook(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := inner.DeleteBook(ctx, id); err != nil {
+			return err
+		}
+		return record(ctx, inner, recorded, EventBookDeleted, book)
+	})
+}
+
+// MergeBook records a book.deleted event for the duplicate, which leaves
+// the catalog.
+func (s *eventStore) MergeBook(ctx context.Context, duplicateId, survivorId int, mergedAt time.Time) error {
+	return s.inTx(ctx, func(inner BookStore, recorded *bool) error {
+		book, err := inner.GetBook(ctx, duplicateId)
+		if err != nil {
+			return err
+		}
+		if err := inner.MergeBook(ctx, duplicateId, survivorId, mergedAt); err != nil {
+			return err
+		}
+		return record(ctx, inner, recorded, EventBookDeleted, book)
+	})
+}
+
+// DeleteAllBooks records a book.deleted event for each book, so it reads
+// the books in the same transaction as the delete.
+func (s *eventStore) DeleteAllBooks(ctx context.Context) (int64, error) {
+	var n int64
+	err := s.inTx(ctx, func(inner BookStore, recorded *bool) error {
+		books, err := inner.ListBooks(ctx)
+		if err != nil {
+			return err
+		}
+		if n, err = inner.DeleteAllBooks(ctx); err != nil {
+			return err
+		}
+		return record(ctx, inner, recorded, EventBookDeleted, books...)
+	})
+	return n, err
+}
+
+// RestoreCatalog records a book.deleted event for each book it deletes, a
+// book.updated event for each book it restores in place and a
+// book.created event for each book it adds back, so consumers can follow
+// along as they would for the same changes made one by one.
+func (s *eventStore) RestoreCatalog(ctx context.Context, books []Book, reviews []Review) error {
+	return s.inTx(ctx, func(inner BookStore, recorded *bool) error {
+		current, err := inner.ListBooks(ctx)
+		if err != nil {
+			return err
+		}
+		if err := inner.RestoreCatalog(ctx, books, reviews); err != nil {
+			return err
+		}
+
+		kept := make(map[int]bool, len(current))
+		for _, book := range current {
+			kept[book.Id] = true
+		}
+		var created, updated []Book
+		for _, book := range books {
+			if kept[book.Id] {
+				updated = append(updated, book)
+				delete(kept, book.Id)
+			} else {
+				created = append(created, book)
+			}
+		}
+		deleted := slices.DeleteFunc(current, func(book Book) bool { return !kept[book.Id] })
+		if err := record(ctx, inner, recorded, EventBookDeleted, deleted...); err != nil {
+			return err
+		}
+		if err := record(ctx, inner, recorded, EventBookUpdated, updated...); err != nil {
+			return err
+		}
+		return record(ctx, inner, recorded, EventBookCreated, created...)
+	})
+}
+
+func (s *eventStore) WithTx(ctx context.Context, fn func(tx BookStore) error) error {
+	return s.inTx(ctx, func(inner BookStore, recorded *bool) error {
+		return fn(&eventStore{BookStore: inner, recorded: recorded})
+	})
+}
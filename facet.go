@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"sort"
+)
+
+// How many authors and categories a facet lists, the commonest first.
+const maxFacetValues = 20
+
+// The lower bounds of the price buckets of a search's facets; the last
+// bucket has no upper bound.
+var priceBucketBounds = []float64{0, 10, 20, 50, 100}
+
+// SearchFacets counts the books a search found by author, category,
+// format and price, for filter sidebars, over every book found rather
+// than the page returned.
+type SearchFacets struct {
+	Authors    []FacetValue  `json:"authors" xml:"authors>facet"`
+	Categories []FacetValue  `json:"categories" xml:"categories>facet"`
+	Formats    []FacetValue  `json:"formats" xml:"formats>facet"`
+	Prices     []PriceBucket `json:"prices" xml:"prices>bucket"`
+}
+
+type FacetValue struct {
+	Value string `json:"value" xml:"value"`
+	Count int    `json:"count" xml:"count"`
+}
+
+// PriceBucket counts the books priced from Min up to, but not including,
+// Max, in the currency of the response. The last bucket has no Max.
+type PriceBucket struct {
+	Min   float64  `json:"min" xml:"min"`
+	Max   *float64 `json:"max,omitempty" xml:"max,omitempty"`
+	Count int      `json:"count" xml:"count"`
+}
+
+// Count the books by author, category, format and price bucket. Every
+// bucket is listed, empty or not.
+func searchFacets(ctx context.Context, books []Book) (*SearchFacets, error) {
+	categories := map[int][]string{}
+	if len(books) > 0 {
+		var err error
+		if categories, err = store.BookCategories(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	authors, inCategory, formats := map[string]int{}, map[string]int{}, map[string]int{}
+	prices := make([]PriceBucket, len(priceBucketBounds))
+	for i, bound := range priceBucketBounds {
+		prices[i].Min = bound
+		if i+1 < len(priceBucketBounds) {
+			prices[i].Max = &priceBucketBounds[i+1]
+		}
+	}
+	for _, book := range books {
+		if book.Author != "" {
+			authors[book.Author]++
+		}
+		for _, category := range categories[book.Id] {
+			inCategory[category]++
+		}
+		if book.Format != "" {
+			formats[book.Format]++
+		}
+		bucket := sort.Search(len(priceBucketBounds), func(i int) bool {
+			return priceBucketBounds[i] > book.Price
+		})
+		prices[max(bucket-1, 0)].Count++
+	}
+
+	return &SearchFacets{
+		Authors:    facetValues(authors, maxFacetValues),
+		Categories: facetValues(inCategory, maxFacetValues),
+		Formats:    facetValues(formats, len(formats)),
+		Prices:     prices,
+	}, nil
+}
+
+// Up to limit of the counted values, the commonest first, then
+// alphabetically.
+func facetValues(counts map[string]int, limit int) []FacetValue {
+	values := make([]FacetValue, 0, len(counts))
+	for value, count := range counts {
+		values = append(values, FacetValue{Value: value, Count: count})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return values[i].Value < values[j].Value
+	})
+	return values[:min(limit, len(values))]
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const mediaTypeAtom = "application/atom+xml"
+
+const (
+	defaultFeedEntries = 20
+	maxFeedEntries     = 100
+)
+
+// atomFeed and the types below are the parts of an Atom document (RFC
+// 4287) the new arrivals feed uses.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	Id      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	Title     string     `xml:"title"`
+	Id        string     `xml:"id"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Author    atomPerson `xml:"author"`
+	Links     []atomLink `xml:"link"`
+	Content   atomText   `xml:"content"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// feedHandler serves the books added last as an Atom feed, newest first,
+// for feed readers and "new arrivals" widgets. Entries link to the book
+// in the API and to the book's cover, if it has one or coverURL is set
+// and it has an ISBN, which their HTML content shows too.
+func feedHandler(coverURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, ok := intParameter(r, "limit", defaultFeedEntries, maxFeedEntries)
+		if !ok {
+			writeProblem(w, r, codeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(maxFeedEntries))
+			return
+		}
+
+		books, err := store.RecentBooks(r.Context(), RecentAdded, limit)
+		if err != nil {
+			writeProblem(w, r, codeInternal, "Error fetching books from database")
+			log.Printf("Database query error: %v", err)
+			return
+		}
+
+		// Atom wants absolute links, and feed readers fetch from
+		// elsewhere.
+		base := requestBaseURL(r)
+		feed := atomFeed{
+			Title:   "New arrivals",
+			Id:      base + "/feed",
+			Updated: time.Now().UTC().Format(time.RFC3339),
+			Links:   []atomLink{{Rel: "self", Type: mediaTypeAtom, Href: base + r.URL.RequestURI()}},
+		}
+		if len(books) > 0 {
+			feed.Updated = books[0].CreatedAt.Format(time.RFC3339)
+		}
+		for _, book := range books {
+			href := base + apiPrefix + "/book/" + strconv.Itoa(book.Id)
+			added := book.CreatedAt.Format(time.RFC3339)
+			updated := added
+			if book.UpdatedAt != nil {
+				updated = book.UpdatedAt.Format(time.RFC3339)
+			}
+			entry := atomEntry{
+				Title:     book.Title,
+				Id:        href,
+				Published: added,
+				Updated:   updated,
+				Author:    atomPerson{Name: book.Author},
+				Links:     []atomLink{{Rel: "alternate", Type: mediaTypeJSON, Href: href}},
+			}
+
+			var content strings.Builder
+			if cover := bookCoverURL(coverURL, book); cover != "" {
+				entry.Links = append(entry.Links, atomLink{Rel: "enclosure", Type: "image/jpeg", Href: cover})
+				fmt.Fprintf(&content, `<p><img src="%s" alt="%s"></p>`, html.EscapeString(cover), html.EscapeString(book.Title))
+			}
+			fmt.Fprintf(&content, "<p>%s by %s, %.2f %s</p>", html.EscapeString(book.Title), html.EscapeString(book.Author), book.Price, currencies.base)
+			entry.Content = atomText{Type: "html", Body: content.String()}
+			feed.Entries = append(feed.Entries, entry)
+		}
+
+		var body bytes.Buffer
+		body.WriteString(xml.Header)
+		if err := xml.NewEncoder(&body).Encode(feed); err != nil {
+			writeProblem(w, r, codeInternal, "Error writing feed")
+			log.Printf("Feed error: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", mediaTypeAtom+"; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body.Bytes())
+	}
+}
+
+// The URL of the book's cover: its own, or else one made from coverURL,
+// or "" without either.
+func bookCoverURL(coverURL string, book Book) string {
+	if book.CoverURL != "" {
+		return book.CoverURL
+	}
+	isbn, ok := normalizeISBN(book.ISBN)
+	if coverURL == "" || !ok {
+		return ""
+	}
+	return strings.ReplaceAll(coverURL, "{isbn}", isbn)
+}
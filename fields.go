@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// The Book fields clients can select with ?fields=, in column order.
+var bookFields = []string{"id", "title", "author", "price", "isbn", "slug", "publisher", "format", "description", "cover_url", "created_at", "updated_at", "archived_at"}
+
+// Parse the ?fields= parameter into the fields to load, in column order.
+// The id is always included so responses can link to the book. Returns
+// nil if the parameter is absent.
+func requestedFields(r *http.Request) ([]string, error) {
+	if !r.URL.Query().Has("fields") {
+		return nil, nil
+	}
+
+	selected := map[string]bool{"id": true}
+	for _, field := range strings.Split(r.URL.Query().Get("fields"), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !slices.Contains(bookFields, field) {
+			return nil, errors.New("unknown field " + strconv.Quote(field))
+		}
+		selected[field] = true
+	}
+
+	var fields []string
+	for _, field := range bookFields {
+		if selected[field] {
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// Report whether every field in fields is one of bookFields, so they can
+// go into a column list.
+func validBookFields(fields []string) error {
+	for _, field := range fields {
+		if !slices.Contains(bookFields, field) {
+			return fmt.Errorf("unknown book field %q", field)
+		}
+	}
+	return nil
+}
+
+func (b *Book) field(name string) any {
+	switch name {
+	case "id":
+		return &b.Id
+	case "title":
+		return &b.Title
+	case "author":
+		return &b.Author
+	case "price":
+		return &b.Price
+	case "isbn":
+		return &b.ISBN
+	case "slug":
+		return &b.Slug
+	case "publisher":
+		return &b.Publisher
+	case "format":
+		return &b.Format
+	case "description":
+		return &b.Description
+	case "cover_url":
+		return &b.CoverURL
+	case "created_at":
+		return &b.CreatedAt
+	case "updated_at":
+		return &b.UpdatedAt
+	case "archived_at":
+		return &b.ArchivedAt
+	}
+	return nil
+}
+
+// Pointers to all of b's fields, in the order of bookFields, to scan
+// bookColumns into.
+func (b *Book) fieldPointers() []any {
+	dest := make([]any, len(bookFields))
+	for i, field := range bookFields {
+		dest[i] = b.field(field)
+	}
+	return dest
+}
+
+// A copy of b holding only fields, as a store that loaded just those
+// would return it.
+func (b Book) only(fields []string) Book {
+	if fields == nil {
+		return b
+	}
+	partial := Book{fields: fields}
+	for _, field := range fields {
+		switch field {
+		case "id":
+			partial.Id = b.Id
+		case "title":
+			partial.Title = b.Title
+		case "author":
+			partial.Author = b.Author
+		case "price":
+			partial.Price = b.Price
+		case "isbn":
+			partial.ISBN = b.ISBN
+		case "slug":
+			partial.Slug = b.Slug
+		case "publisher":
+			partial.Publisher = b.Publisher
+		case "format":
+			partial.Format = b.Format
+		case "description":
+			partial.Description = b.Description
+		case "cover_url":
+			partial.CoverURL = b.CoverURL
+		case "created_at":
+			partial.CreatedAt = b.CreatedAt
+		case "updated_at":
+			partial.UpdatedAt = b.UpdatedAt
+		case "archived_at":
+			partial.ArchivedAt = b.ArchivedAt
+		}
+	}
+	return partial
+}
+
+// wholeBook encodes with the default struct encodings.
+type wholeBook Book
+
+// A partial book encodes only the fields it was loaded with, in every
+// format that names its fields.
+func (b Book) MarshalJSON() ([]byte, error) {
+	if b.fields == nil {
+		return json.Marshal(wholeBook(b))
+	}
+
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for i, field := range b.fields {
+		value, err := json.Marshal(b.field(field))
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		out.WriteString(strconv.Quote(field) + ":")
+		out.Write(value)
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+func (b Book) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if b.fields == nil {
+		return e.EncodeElement(wholeBook(b), start)
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, field := range b.fields {
+		if err := e.EncodeElement(b.field(field), xml.StartElement{Name: xml.Name{Local: field}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func (b Book) EncodeMsgpack(enc *msgpack.Encoder) error {
+	if b.fields == nil {
+		return enc.Encode(wholeBook(b))
+	}
+
+	if err := enc.EncodeMapLen(len(b.fields)); err != nil {
+		return err
+	}
+	for _, field := range b.fields {
+		if err := enc.EncodeString(field); err != nil {
+			return err
+		}
+		if err := enc.Encode(b.field(field)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
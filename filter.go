@@ -0,0 +1,434 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// How many terms an advanced search can have, and how deep its
+	// parentheses can go, so the SQL made of it stays small.
+	maxFilterTerms = 20
+	maxFilterDepth = 10
+)
+
+// The fields terms of an advanced search can be about.
+var filterFields = []string{"title", "author", "publisher", "isbn", "format", "category", "tag", "price"}
+
+// BookFilter is an advanced search, parsed by parseBookFilter: terms
+// combined with AND, OR and NOT. It is only ever turned into SQL with its
+// values as parameters.
+type BookFilter struct {
+	// op is "and", "or" or "not" of operands, or empty for a term.
+	op       string
+	operands []*BookFilter
+
+	// field is what a term is about, empty for the title or author, and
+	// value what it has to contain, or be for the isbn, format, category
+	// and tag, lower-cased. Price terms compare the price with price,
+	// using compare, one of <, <=, >, >= and =, or keep it from price to
+	// upTo with "..".
+	field   string
+	value   string
+	compare string
+	price   float64
+	upTo    float64
+}
+
+// filterToken is a parenthesis, an operator or a term of an advanced
+// search.
+type filterToken struct {
+	// text is "(", ")", "AND", "OR" or "NOT", or empty for a term.
+	text    string
+	negated bool
+	field   string
+	value   string
+}
+
+// Parse search as an advanced search, such as
+//
+//	author:"Le Guin" price:<15 -tag:damaged (format:ebook OR format:audiobook)
+//
+// Terms are words or "quoted phrases", about a field if they start with
+// one and a colon, and a leading - leaves out the books they match. Terms
+// side by side must all match, unless OR is between them; AND and NOT
+// (the same as -) can be spelt out, and parentheses group. A search with
+// none of that, or with fields it doesn't know, such as "Re:Zero", isn't
+// advanced: the filter is nil and the search is matched as usual.
+func parseBookFilter(search string) (*BookFilter, error) {
+	tokens, advanced, err := tokenizeFilter(search)
+	if err != nil || !advanced {
+		return nil, err
+	}
+	p := filterParser{tokens: tokens}
+	filter, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		// Only an unmatched closing parenthesis stops a whole search.
+		return nil, errors.New(`unexpected ")"`)
+	}
+	return filter, nil
+}
+
+// Split search into tokens, and report whether any of them makes it an
+// advanced search.
+func tokenizeFilter(search string) ([]filterToken, bool, error) {
+	var tokens []filterToken
+	advanced := false
+	for i := 0; i < len(search); {
+		c := search[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '(' || c == ')':
+			tokens = append(tokens, filterToken{text: string(c)})
+			advanced = true
+			i++
+			continue
+		}
+
+		var token filterToken
+		if c == '-' && i+1 < len(search) && !strings.ContainsRune(" \t\n\r()", rune(search[i+1])) {
+			token.negated = true
+			advanced = true
+			i++
+		}
+		if search[i] == '"' {
+			value, n, err := quotedFilterValue(search[i:])
+			if err != nil {
+				return nil, false, err
+			}
+			token.value = value
+			advanced = true
+			i += n
+			tokens = append(tokens, token)
+			continue
+		}
+
+		end := i
+		for end < len(search) && !strings.ContainsRune(" \t\n\r()\"", rune(search[end])) {
+			end++
+		}
+		word := search[i:end]
+		i = end
+		field, value, ok := strings.Cut(word, ":")
+		if ok && slices.Contains(filterFields, strings.ToLower(field)) {
+			token.field = strings.ToLower(field)
+			if value == "" && i < len(search) && search[i] == '"' {
+				var n int
+				var err error
+				if value, n, err = quotedFilterValue(search[i:]); err != nil {
+					return nil, false, err
+				}
+				i += n
+			}
+			token.value = value
+			advanced = true
+		} else if !token.negated && (word == "AND" || word == "OR" || word == "NOT") {
+			token.text = word
+			advanced = true
+		} else {
+			token.value = word
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, advanced, nil
+}
+
+// The phrase quoted at the start of s, and how long it is with its
+// quotes.
+func quotedFilterValue(s string) (string, int, error) {
+	end := strings.IndexByte(s[1:], '"')
+	if end < 0 {
+		return "", 0, errors.New("unclosed quote")
+	}
+	return s[1 : end+1], end + 2, nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+	terms  int
+	depth  int
+}
+
+// The next token's text, "" for a term and "end" past the last one.
+func (p *filterParser) peek() string {
+	if p.pos == len(p.tokens) {
+		return "end"
+	}
+	return p.tokens[p.pos].text
+}
+
+// Terms joined by OR.
+func (p *filterParser) or() (*BookFilter, error) {
+	filter, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	operands := []*BookFilter{filter}
+	for p.peek() == "OR" {
+		p.pos++
+		if filter, err = p.and(); err != nil {
+			return nil, err
+		}
+		operands = append(operands, filter)
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return &BookFilter{op: "or", operands: operands}, nil
+}
+
+// Terms side by side or joined by AND.
+func (p *filterParser) and() (*BookFilter, error) {
+	filter, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	operands := []*BookFilter{filter}
+	for next := p.peek(); next != "end" && next != ")" && next != "OR"; next = p.peek() {
+		if next == "AND" {
+			p.pos++
+		}
+		if filter, err = p.not(); err != nil {
+			return nil, err
+		}
+		operands = append(operands, filter)
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return &BookFilter{op: "and", operands: operands}, nil
+}
+
+// A term or group, after NOT if any.
+func (p *filterParser) not() (*BookFilter, error) {
+	if p.peek() == "NOT" {
+		p.pos++
+		filter, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &BookFilter{op: "not", operands: []*BookFilter{filter}}, nil
+	}
+	return p.term()
+}
+
+// A term, or a group in parentheses.
+func (p *filterParser) term() (*BookFilter, error) {
+	next := p.peek()
+	if next == "(" {
+		if p.depth++; p.depth > maxFilterDepth {
+			return nil, fmt.Errorf("parentheses can be nested at most %d deep", maxFilterDepth)
+		}
+		p.pos++
+		filter, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, errors.New(`missing ")"`)
+		}
+		p.pos++
+		p.depth--
+		return filter, nil
+	}
+	if next == "end" {
+		return nil, errors.New("search ends without a term")
+	} else if next != "" {
+		return nil, fmt.Errorf("expected a term before %q", next)
+	}
+
+	token := p.tokens[p.pos]
+	p.pos++
+	if p.terms++; p.terms > maxFilterTerms {
+		return nil, fmt.Errorf("a search can have at most %d terms", maxFilterTerms)
+	}
+	filter, err := newFilterTerm(token.field, token.value)
+	if err != nil {
+		return nil, err
+	}
+	if token.negated {
+		return &BookFilter{op: "not", operands: []*BookFilter{filter}}, nil
+	}
+	return filter, nil
+}
+
+// The term matching value in field, checking the value suits the field.
+func newFilterTerm(field, value string) (*BookFilter, error) {
+	value = strings.ToLower(normalizeText(value))
+	if value == "" {
+		if field == "" {
+			return nil, errors.New("empty quotes")
+		}
+		return nil, fmt.Errorf("%s: needs a value", field)
+	}
+	filter := &BookFilter{field: field, value: value}
+	switch field {
+	case "isbn":
+		isbn, err := parseISBN(strings.ToUpper(value))
+		if err != nil {
+			return nil, err
+		}
+		filter.value = isbn
+	case "format":
+		if !slices.Contains(bookFormats, value) {
+			return nil, errors.New("format: must be hardcover, paperback, ebook or audiobook")
+		}
+	case "price":
+		errPrice := errors.New("price: must be a number after <, <=, >, >= or =, or a range such as 10..20")
+		if from, to, ok := strings.Cut(value, ".."); ok {
+			var err1, err2 error
+			filter.compare = ".."
+			filter.price, err1 = strconv.ParseFloat(from, 64)
+			filter.upTo, err2 = strconv.ParseFloat(to, 64)
+			if err1 != nil || err2 != nil {
+				return nil, errPrice
+			}
+			return filter, nil
+		}
+		filter.compare = "="
+		for _, compare := range []string{"<=", ">=", "<", ">", "="} {
+			if rest, ok := strings.CutPrefix(value, compare); ok {
+				filter.compare, value = compare, rest
+				break
+			}
+		}
+		var err error
+		if filter.price, err = strconv.ParseFloat(value, 64); err != nil {
+			return nil, errPrice
+		}
+	}
+	return filter, nil
+}
+
+// The filter as an SQL condition on the books table, and its parameters.
+func (f *BookFilter) sql() (string, []any) {
+	switch f.op {
+	case "and", "or":
+		conditions := make([]string, len(f.operands))
+		var args []any
+		for i, operand := range f.operands {
+			condition, operandArgs := operand.sql()
+			conditions[i] = condition
+			args = append(args, operandArgs...)
+		}
+		return "(" + strings.Join(conditions, " "+strings.ToUpper(f.op)+" ") + ")", args
+	case "not":
+		condition, args := f.operands[0].sql()
+		return "NOT " + condition, args
+	}
+
+	pattern := "%" + escapeLike(f.value) + "%"
+	switch f.field {
+	case "":
+		return "(LOWER(title) LIKE ? ESCAPE '!' OR LOWER(author) LIKE ? ESCAPE '!')", []any{pattern, pattern}
+	case "title", "author":
+		return "LOWER(" + f.field + ") LIKE ? ESCAPE '!'", []any{pattern}
+	case "publisher":
+		return "LOWER(COALESCE(publisher, '')) LIKE ? ESCAPE '!'", []any{pattern}
+	case "isbn", "format":
+		return "COALESCE(" + f.field + ", '') = ?", []any{f.value}
+	case "category", "tag":
+		table := "book_categories"
+		if f.field == "tag" {
+			table = "book_tags"
+		}
+		return "EXISTS (SELECT 1 FROM " + table + " WHERE " + table + ".tenant_id = books.tenant_id AND " + table + ".book_id = books.id AND LOWER(" + table + ".name) = ?)", []any{f.value}
+	}
+	if f.compare == ".." {
+		return "(price >= ? AND price <= ?)", []any{f.price, f.upTo}
+	}
+	return "price " + f.compare + " ?", []any{f.price}
+}
+
+// Whether the filter matches book, with its subjects.
+func (f *BookFilter) matches(book Book, subjects BookSubjects) bool {
+	switch f.op {
+	case "and":
+		for _, operand := range f.operands {
+			if !operand.matches(book, subjects) {
+				return false
+			}
+		}
+		return true
+	case "or":
+		for _, operand := range f.operands {
+			if operand.matches(book, subjects) {
+				return true
+			}
+		}
+		return false
+	case "not":
+		return !f.operands[0].matches(book, subjects)
+	}
+
+	contains := func(s string) bool {
+		return strings.Contains(strings.ToLower(s), f.value)
+	}
+	named := func(names []string) bool {
+		return slices.ContainsFunc(names, func(name string) bool { return strings.ToLower(name) == f.value })
+	}
+	switch f.field {
+	case "":
+		return contains(book.Title) || contains(book.Author)
+	case "title":
+		return contains(book.Title)
+	case "author":
+		return contains(book.Author)
+	case "publisher":
+		return contains(book.Publisher)
+	case "isbn":
+		return book.ISBN == f.value
+	case "format":
+		return book.Format == f.value
+	case "category":
+		return named(subjects.Categories)
+	case "tag":
+		return named(subjects.Tags)
+	}
+	switch f.compare {
+	case "<":
+		return book.Price < f.price
+	case "<=":
+		return book.Price <= f.price
+	case ">":
+		return book.Price > f.price
+	case ">=":
+		return book.Price >= f.price
+	case "..":
+		return book.Price >= f.price && book.Price <= f.upTo
+	}
+	return book.Price == f.price
+}
+
+// The values the title and the author have to contain for the filter to
+// match, to highlight.
+func (f *BookFilter) highlightTerms() (title, author []string) {
+	switch f.op {
+	case "and", "or":
+		for _, operand := range f.operands {
+			t, a := operand.highlightTerms()
+			title, author = append(title, t...), append(author, a...)
+		}
+		return title, author
+	case "not":
+		return nil, nil
+	}
+	switch f.field {
+	case "":
+		return []string{f.value}, []string{f.value}
+	case "title":
+		return []string{f.value}, nil
+	case "author":
+		return nil, []string{f.value}
+	}
+	return nil, nil
+}
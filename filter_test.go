@@ -0,0 +1,133 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBookFilterNotAdvanced(t *testing.T) {
+	for _, search := range []string{"", "dune", "Frank Herbert", "Re:Zero", "sci-fi", "- dune", "and or not"} {
+		filter, err := parseBookFilter(search)
+		if err != nil || filter != nil {
+			t.Errorf("parseBookFilter(%q) = %v, %v; want nil, nil", search, filter, err)
+		}
+	}
+}
+
+func TestParseBookFilterErrors(t *testing.T) {
+	tests := []struct {
+		search, err string
+	}{
+		{`"dune`, "unclosed quote"},
+		{`author:"Le Guin`, "unclosed quote"},
+		{`(dune`, `missing ")"`},
+		{`dune)`, `unexpected ")"`},
+		{`dune OR`, "search ends without a term"},
+		{`AND dune`, `expected a term before "AND"`},
+		{`""`, "empty quotes"},
+		{`author:`, "author: needs a value"},
+		{`format:scroll`, "format: must be"},
+		{`price:cheap`, "price: must be"},
+		{`price:10..`, "price: must be"},
+		{`isbn:123`, ""},
+		{strings.Repeat("(", maxFilterDepth+1) + "dune" + strings.Repeat(")", maxFilterDepth+1), "nested at most"},
+		{"title:a" + strings.Repeat(" title:a", maxFilterTerms), "at most 20 terms"},
+	}
+	for _, tt := range tests {
+		_, err := parseBookFilter(tt.search)
+		if err == nil {
+			t.Errorf("parseBookFilter(%q) succeeded, want an error", tt.search)
+		} else if !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("parseBookFilter(%q) = %q, want an error with %q", tt.search, err, tt.err)
+		}
+	}
+}
+
+func TestBookFilterSQL(t *testing.T) {
+	tests := []struct {
+		search string
+		sql    string
+		args   []any
+	}{
+		{
+			`title:dune`,
+			"LOWER(title) LIKE ? ESCAPE '!'",
+			[]any{"%dune%"},
+		},
+		{
+			`"Le Guin" -tag:damaged`,
+			"((LOWER(title) LIKE ? ESCAPE '!' OR LOWER(author) LIKE ? ESCAPE '!') AND NOT EXISTS (SELECT 1 FROM book_tags WHERE book_tags.tenant_id = books.tenant_id AND book_tags.book_id = books.id AND LOWER(book_tags.name) = ?))",
+			[]any{"%le guin%", "%le guin%", "damaged"},
+		},
+		{
+			`author:"Le Guin" price:<15 (format:ebook OR format:audiobook)`,
+			"(LOWER(author) LIKE ? ESCAPE '!' AND price < ? AND (COALESCE(format, '') = ? OR COALESCE(format, '') = ?))",
+			[]any{"%le guin%", 15.0, "ebook", "audiobook"},
+		},
+		{
+			`price:10..20 NOT category:Horror`,
+			"((price >= ? AND price <= ?) AND NOT EXISTS (SELECT 1 FROM book_categories WHERE book_categories.tenant_id = books.tenant_id AND book_categories.book_id = books.id AND LOWER(book_categories.name) = ?))",
+			[]any{10.0, 20.0, "horror"},
+		},
+		{
+			`publisher:"100%_sure!"`,
+			"LOWER(COALESCE(publisher, '')) LIKE ? ESCAPE '!'",
+			[]any{"%100!%!_sure!!%"},
+		},
+		{
+			`isbn:978-0-441-01359-3`,
+			"COALESCE(isbn, '') = ?",
+			[]any{"9780441013593"},
+		},
+		{
+			// Quotes and operators in values stay in the parameters.
+			`title:"x' OR 1=1 --"`,
+			"LOWER(title) LIKE ? ESCAPE '!'",
+			[]any{"%x' or 1=1 --%"},
+		},
+	}
+	for _, tt := range tests {
+		filter, err := parseBookFilter(tt.search)
+		if err != nil || filter == nil {
+			t.Fatalf("parseBookFilter(%q) = %v, %v", tt.search, filter, err)
+		}
+		sql, args := filter.sql()
+		if sql != tt.sql {
+			t.Errorf("parseBookFilter(%q).sql() = %s\nwant %s", tt.search, sql, tt.sql)
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("parseBookFilter(%q).sql() args = %#v, want %#v", tt.search, args, tt.args)
+		}
+	}
+}
+
+func TestBookFilterMatches(t *testing.T) {
+	dune := Book{Title: "Dune", Author: "Frank Herbert", Price: 9.99, Format: "paperback", ISBN: "9780441013593"}
+	subjects := BookSubjects{Categories: []string{"Science Fiction"}, Tags: []string{"classic"}}
+	tests := []struct {
+		search string
+		want   bool
+	}{
+		{`author:herbert`, true},
+		{`author:"le guin"`, false},
+		{`title:dune price:<10`, true},
+		{`title:dune price:>=10`, false},
+		{`price:9..10`, true},
+		{`price:=9.99`, true},
+		{`format:ebook OR format:paperback`, true},
+		{`category:"science fiction" -tag:damaged`, true},
+		{`NOT tag:classic`, false},
+		{`isbn:0441013597`, true},
+		{`(title:dune OR title:emma) AND NOT (author:austen)`, true},
+	}
+	for _, tt := range tests {
+		filter, err := parseBookFilter(tt.search)
+		if err != nil || filter == nil {
+			t.Fatalf("parseBookFilter(%q) = %v, %v", tt.search, filter, err)
+		}
+		if got := filter.matches(dune, subjects); got != tt.want {
+			t.Errorf("parseBookFilter(%q).matches(Dune) = %v, want %v", tt.search, got, tt.want)
+		}
+	}
+}
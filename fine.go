@@ -0,0 +1,461 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// How fines are paid.
+const (
+	PaymentCash = "cash"
+	PaymentCard = "card"
+)
+
+var paymentMethods = []string{PaymentCash, PaymentCard}
+
+// Fine is money a member owes the library, in the catalog's currency: for
+// a book returned late, or anything else the library charges for. It is
+// settled once paid in full or waived.
+type Fine struct {
+	Id       int `json:"id" xml:"id"`
+	MemberId int `json:"member_id" xml:"member_id"`
+	// LoanId is the loan returned late, for overdue fines.
+	LoanId *int    `json:"loan_id,omitempty" xml:"loan_id,omitempty"`
+	Reason string  `json:"reason" xml:"reason" validate:"required,max=255"`
+	Amount float64 `json:"amount" xml:"amount" validate:"gt=0,lte=99999999.99"`
+	// Paid is what was paid towards the fine so far.
+	Paid      float64    `json:"paid" xml:"paid"`
+	SettledAt *time.Time `json:"settled_at,omitempty" xml:"settled_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" xml:"created_at"`
+	// UpdatedAt is when the fine was last paid towards or settled.
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+}
+
+func (f Fine) timestamps() (created, updated time.Time) {
+	return f.CreatedAt, f.UpdatedAt
+}
+
+// FinePayment is money paid towards a fine, in cash or by card, with the
+// card terminal's reference for card payments.
+type FinePayment struct {
+	Id        int       `json:"id" xml:"id"`
+	FineId    int       `json:"fine_id" xml:"fine_id"`
+	Amount    float64   `json:"amount" xml:"amount" validate:"gt=0,lte=99999999.99"`
+	Method    string    `json:"method" xml:"method" validate:"required,payment_method"`
+	Reference string    `json:"reference,omitempty" xml:"reference,omitempty" validate:"required_if=Method card,max=255"`
+	PaidAt    time.Time `json:"paid_at" xml:"paid_at"`
+}
+
+// Receipt is what the desk prints for a payment.
+type Receipt struct {
+	// Number is the payment's, as printed.
+	Number     string      `json:"number" xml:"number"`
+	Currency   string      `json:"currency" xml:"currency"`
+	MemberName string      `json:"member_name" xml:"member_name"`
+	CardNumber string      `json:"card_number" xml:"card_number"`
+	Fine       Fine        `json:"fine" xml:"fine"`
+	Payment    FinePayment `json:"payment" xml:"payment"`
+	// Balance is what is left to pay on the fine after the payment.
+	Balance float64 `json:"balance" xml:"balance"`
+}
+
+// OutstandingBalance is what a member owes over their unsettled fines.
+type OutstandingBalance struct {
+	Member  Member  `json:"member" xml:"member"`
+	Fines   int     `json:"fines" xml:"fines"`
+	Balance float64 `json:"balance" xml:"balance"`
+}
+
+// OutstandingReport is every member who owes the library, most owed
+// first.
+type OutstandingReport struct {
+	Currency string               `json:"currency" xml:"currency"`
+	Total    float64              `json:"total" xml:"total"`
+	Balances []OutstandingBalance `json:"balances" xml:"balances>balance"`
+}
+
+type FineResponse struct {
+	Status  string `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
+	Data    Fine   `json:"data" xml:"data"`
+}
+
+type FinesResponse struct {
+	Status  string `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
+	Data    []Fine `json:"data" xml:"data>fine"`
+}
+
+type ReceiptResponse struct {
+	Status  string  `json:"status" xml:"status"`
+	Message string  `json:"message" xml:"message"`
+	Data    Receipt `json:"data" xml:"data"`
+}
+
+type OutstandingReportResponse struct {
+	Status  string            `json:"status" xml:"status"`
+	Message string            `json:"message" xml:"message"`
+	Data    OutstandingReport `json:"data" xml:"data"`
+}
+
+var (
+	errFineSettled        = errors.New("fine already settled")
+	errPaymentOverBalance = errors.New("payment exceeds balance")
+)
+
+// Round an amount of money to cents.
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// What is left to pay on the fine.
+func (f Fine) balance() float64 {
+	return roundCents(f.Amount - f.Paid)
+}
+
+// Fine the member for a loan returned late, at perDay for each day or
+// part of one past its due date. It returns nil for loans returned on
+// time or when overdue books aren't fined.
+func assessOverdueFine(ctx context.Context, tx BookStore, loan Loan, title string, perDay float64) (*Fine, error) {
+	late := loan.ReturnedAt.Sub(loan.DueAt)
+	if perDay <= 0 || late <= 0 {
+		return nil, nil
+	}
+	days := int(math.Ceil(late.Hours() / 24))
+	reason := fmt.Sprintf("%s returned %d days late", title, days)
+	if days == 1 {
+		reason = title + " returned a day late"
+	}
+	fine := Fine{
+		MemberId:  loan.MemberId,
+		LoanId:    &loan.Id,
+		Reason:    reason,
+		Amount:    roundCents(float64(days) * perDay),
+		CreatedAt: *loan.ReturnedAt,
+		UpdatedAt: *loan.ReturnedAt,
+	}
+	if err := tx.CreateFine(ctx, &fine); err != nil {
+		return nil, err
+	}
+	return &fine, nil
+}
+
+// List a member's fines, newest first. Members linked to a user can see
+// their own; admins can see anyone's.
+func listMemberFinesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid member ID")
+		return
+	}
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+
+	fines, err := store.ListMemberFines(r.Context(), id, after)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeMemberNotFound, "Member not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching fines")
+		log.Printf("Fine query error: %v", err)
+		return
+	}
+	if !auditMemberAccess(w, r, &id, AccessFines) {
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, FinesResponse{
+		Status:  "success",
+		Message: "Fines retrieved successfully",
+		Data:    fines,
+	})
+}
+
+// Charge a member a fine other than for a late return, such as for a lost
+// or damaged book.
+func createFineHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid member ID")
+		return
+	}
+	var fine Fine
+	if err := decodeRequest(r, &fine); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	fine.Reason = normalizeText(fine.Reason)
+	if errs := validateRequest(fine); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	fine = Fine{
+		MemberId:  id,
+		Reason:    fine.Reason,
+		Amount:    roundCents(fine.Amount),
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	fine.UpdatedAt = fine.CreatedAt
+
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		if _, err := tx.GetMember(r.Context(), id); err != nil {
+			return err
+		}
+		return tx.CreateFine(r.Context(), &fine)
+	})
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeMemberNotFound, "Member not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error creating fine")
+		log.Printf("Fine creation error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, FineResponse{
+		Status:  "success",
+		Message: "Fine created successfully",
+		Data:    fine,
+	})
+}
+
+// Record a payment towards a fine, settling it once it is paid in full,
+// and answer with the receipt to print.
+func payFineHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid fine ID")
+		return
+	}
+	var payment FinePayment
+	if err := decodeRequest(r, &payment); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	payment.Reference = strings.TrimSpace(payment.Reference)
+	if errs := validateRequest(payment); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	payment = FinePayment{
+		FineId:    id,
+		Amount:    roundCents(payment.Amount),
+		Method:    payment.Method,
+		Reference: payment.Reference,
+		PaidAt:    time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	var receipt Receipt
+	var balance float64
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		fine, err := tx.GetFine(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		if fine.SettledAt != nil {
+			return errFineSettled
+		}
+		if balance = fine.balance(); payment.Amount > balance {
+			return errPaymentOverBalance
+		}
+		if err := tx.CreateFinePayment(r.Context(), &payment); err != nil {
+			return err
+		}
+		fine.Paid, fine.UpdatedAt = roundCents(fine.Paid+payment.Amount), payment.PaidAt
+		if fine.balance() == 0 {
+			fine.SettledAt = &payment.PaidAt
+			if err := tx.SettleFine(r.Context(), id, payment.PaidAt); err != nil {
+				return err
+			}
+		}
+		if err := recordMemberAccess(r.Context(), tx, &fine.MemberId, AccessFines); err != nil {
+			return err
+		}
+		receipt, err = newReceipt(r.Context(), tx, fine, payment)
+		return err
+	})
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeFineNotFound, "Fine not found")
+		return
+	case errors.Is(err, errFineSettled):
+		writeProblem(w, r, codeFineSettled, "The fine is already settled")
+		return
+	case errors.Is(err, errPaymentOverBalance):
+		writeValidationErrors(w, r, []FieldError{newFieldError("amount", "lte", "must be at most %s", strconv.FormatFloat(balance, 'f', 2, 64))})
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error recording payment")
+		log.Printf("Fine payment error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, ReceiptResponse{
+		Status:  "success",
+		Message: "Payment recorded successfully",
+		Data:    receipt,
+	})
+}
+
+// Mark a fine settled without it being paid in full, waiving the rest.
+func settleFineHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid fine ID")
+		return
+	}
+
+	var fine Fine
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		var err error
+		if fine, err = tx.GetFine(r.Context(), id); err != nil {
+			return err
+		}
+		if fine.SettledAt != nil {
+			return errFineSettled
+		}
+		settledAt := time.Now().UTC().Truncate(time.Microsecond)
+		fine.SettledAt, fine.UpdatedAt = &settledAt, settledAt
+		return tx.SettleFine(r.Context(), id, settledAt)
+	})
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeFineNotFound, "Fine not found")
+		return
+	case errors.Is(err, errFineSettled):
+		writeProblem(w, r, codeFineSettled, "The fine is already settled")
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error settling fine")
+		log.Printf("Fine settlement error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, FineResponse{
+		Status:  "success",
+		Message: "Fine settled successfully",
+		Data:    fine,
+	})
+}
+
+// Answer with the receipt for a payment again, to reprint it. Its balance
+// is the fine's as of now.
+func getReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid payment ID")
+		return
+	}
+
+	var receipt Receipt
+	payment, err := store.GetFinePayment(r.Context(), id)
+	if err == nil {
+		var fine Fine
+		if fine, err = store.GetFine(r.Context(), payment.FineId); err == nil {
+			receipt, err = newReceipt(r.Context(), store, fine, payment)
+		}
+	}
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codePaymentNotFound, "Payment not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching receipt")
+		log.Printf("Receipt error: %v", err)
+		return
+	}
+	if !auditMemberAccess(w, r, &receipt.Fine.MemberId, AccessFines) {
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, ReceiptResponse{
+		Status:  "success",
+		Message: "Receipt retrieved successfully",
+		Data:    receipt,
+	})
+}
+
+// The receipt for a payment towards the fine.
+func newReceipt(ctx context.Context, s BookStore, fine Fine, payment FinePayment) (Receipt, error) {
+	member, err := s.GetMember(ctx, fine.MemberId)
+	if errors.Is(err, ErrNotFound) {
+		// The member was deleted since, their details erased.
+		member.Name = erasedName
+	} else if err != nil {
+		return Receipt{}, err
+	}
+	return Receipt{
+		Number:     fmt.Sprintf("R%08d", payment.Id),
+		Currency:   currencies.base,
+		MemberName: member.Name,
+		CardNumber: member.CardNumber,
+		Fine:       fine,
+		Payment:    payment,
+		Balance:    fine.balance(),
+	}, nil
+}
+
+// Report what every member with unsettled fines owes, most first.
+func outstandingFinesHandler(w http.ResponseWriter, r *http.Request) {
+	report, err := outstandingReport(r.Context())
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching outstanding balances")
+		log.Printf("Outstanding balance query error: %v", err)
+		return
+	}
+	for _, balance := range report.Balances {
+		if !auditMemberAccess(w, r, &balance.Member.Id, AccessFines) {
+			return
+		}
+	}
+
+	writeResponse(w, r, http.StatusOK, OutstandingReportResponse{
+		Status:  "success",
+		Message: "Outstanding balances retrieved successfully",
+		Data:    report,
+	})
+}
+
+// Sum up the unsettled fines by member.
+func outstandingReport(ctx context.Context) (OutstandingReport, error) {
+	fines, err := store.ListUnsettledFines(ctx)
+	if err != nil {
+		return OutstandingReport{}, err
+	}
+	report := OutstandingReport{Currency: currencies.base, Balances: []OutstandingBalance{}}
+	byMember := map[int]int{}
+	for _, fine := range fines {
+		i, ok := byMember[fine.MemberId]
+		if !ok {
+			member, err := store.GetMember(ctx, fine.MemberId)
+			if err != nil {
+				return OutstandingReport{}, err
+			}
+			i = len(report.Balances)
+			byMember[fine.MemberId] = i
+			report.Balances = append(report.Balances, OutstandingBalance{Member: member})
+		}
+		report.Balances[i].Fines++
+		report.Balances[i].Balance = roundCents(report.Balances[i].Balance + fine.balance())
+		report.Total = roundCents(report.Total + fine.balance())
+	}
+	sort.Slice(report.Balances, func(i, j int) bool {
+		a, b := report.Balances[i], report.Balances[j]
+		if a.Balance != b.Balance {
+			return a.Balance > b.Balance
+		}
+		return a.Member.Name < b.Member.Name
+	})
+	return report, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// The formats a book can be published in, which its Format is one of.
+const (
+	FormatHardcover = "hardcover"
+	FormatPaperback = "paperback"
+	FormatEbook     = "ebook"
+	FormatAudiobook = "audiobook"
+)
+
+var bookFormats = []string{FormatHardcover, FormatPaperback, FormatEbook, FormatAudiobook}
+
+// Parse the ?book_format= parameter, a comma-separated list of formats
+// books must be in one of; ?format= picks the response format. Returns nil
+// if the parameter is absent or empty, and false if it names an unknown
+// format.
+func requestedFormats(r *http.Request) ([]string, bool) {
+	var formats []string
+	for _, format := range strings.Split(r.URL.Query().Get("book_format"), ",") {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format == "" || slices.Contains(formats, format) {
+			continue
+		}
+		if !slices.Contains(bookFormats, format) {
+			return nil, false
+		}
+		formats = append(formats, format)
+	}
+	return formats, true
+}
+
+// Report whether the book is in one of formats, or formats is empty.
+func inFormats(book Book, formats []string) bool {
+	return len(formats) == 0 || slices.Contains(formats, book.Format)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// GiftCard is credit in the catalog's currency to spend on sales, known by
+// its code. Sales redeem what they can of its balance, so it can pay for
+// several of them in part.
+type GiftCard struct {
+	Id   int    `json:"id" xml:"id"`
+	Code string `json:"code" xml:"code"`
+	// Amount is what the card was issued for and Balance what is left of
+	// it.
+	Amount   float64    `json:"amount" xml:"amount" validate:"gt=0,lte=10000"`
+	Balance  float64    `json:"balance" xml:"balance"`
+	Note     string     `json:"note,omitempty" xml:"note,omitempty" validate:"max=1000"`
+	IssuedAt time.Time  `json:"issued_at" xml:"issued_at"`
+	VoidedAt *time.Time `json:"voided_at,omitempty" xml:"voided_at,omitempty"`
+	// CreatedAt is IssuedAt, and UpdatedAt when the card last paid for a
+	// sale or was voided.
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+	// Redemptions are the sales the card paid towards, oldest first.
+	Redemptions []GiftCardRedemption `json:"redemptions" xml:"redemptions>redemption"`
+}
+
+// GiftCardRedemption is what a gift card paid towards a sale.
+type GiftCardRedemption struct {
+	GiftCardId int       `json:"-" xml:"-"`
+	SaleId     int       `json:"sale_id" xml:"sale_id"`
+	Amount     float64   `json:"amount" xml:"amount"`
+	RedeemedAt time.Time `json:"redeemed_at" xml:"redeemed_at"`
+}
+
+type GiftCardResponse struct {
+	Status  string   `json:"status" xml:"status"`
+	Message string   `json:"message" xml:"message"`
+	Data    GiftCard `json:"data" xml:"data"`
+}
+
+// Gift card codes are four groups of four characters, like
+// 7KQM-2XWD-R9FA-LP3C, from an alphabet without the letters and digits
+// that are easily mixed up, giving 80 random bits.
+const (
+	giftCardAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	giftCardGroups   = 4
+	giftCardGroupLen = 4
+)
+
+var (
+	errGiftCardNotFound = errors.New("no gift card has this code")
+	errGiftCardVoided   = errors.New("gift card voided")
+	errGiftCardEmpty    = errors.New("gift card has no balance left")
+)
+
+// A new random gift card code.
+func newGiftCardCode() string {
+	random := make([]byte, giftCardGroups*giftCardGroupLen)
+	rand.Read(random)
+	for i, b := range random {
+		random[i] = giftCardAlphabet[int(b)%len(giftCardAlphabet)]
+	}
+	return formatGiftCardCode(string(random))
+}
+
+// Split the characters of a code into its groups.
+func formatGiftCardCode(chars string) string {
+	groups := make([]string, 0, giftCardGroups)
+	for i := 0; i < len(chars); i += giftCardGroupLen {
+		groups = append(groups, chars[i:i+giftCardGroupLen])
+	}
+	return strings.Join(groups, "-")
+}
+
+// The code as stored, from one typed in any case, with or without its
+// hyphens and spaces, or false if it can't be a gift card code.
+func normalizeGiftCardCode(code string) (string, bool) {
+	chars := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(code))
+	if len(chars) != giftCardGroups*giftCardGroupLen || strings.Trim(chars, giftCardAlphabet) != "" {
+		return "", false
+	}
+	return formatGiftCardCode(chars), true
+}
+
+// The gift card with code, locked until the transaction ends, if it can
+// still be spent. It fails with errGiftCardNotFound, errGiftCardVoided or
+// errGiftCardEmpty.
+func spendableGiftCard(ctx context.Context, tx BookStore, code string) (GiftCard, error) {
+	code, ok := normalizeGiftCardCode(code)
+	if !ok {
+		return GiftCard{}, errGiftCardNotFound
+	}
+	card, err := tx.GetGiftCard(ctx, code)
+	if errors.Is(err, ErrNotFound) {
+		return GiftCard{}, errGiftCardNotFound
+	} else if err != nil {
+		return GiftCard{}, err
+	}
+	if card.VoidedAt != nil {
+		return GiftCard{}, errGiftCardVoided
+	}
+	if card.Balance <= 0 {
+		return GiftCard{}, errGiftCardEmpty
+	}
+	return card, nil
+}
+
+// Issue a gift card for an amount, with a new code.
+func createGiftCardHandler(w http.ResponseWriter, r *http.Request) {
+	var card GiftCard
+	if err := decodeRequest(r, &card); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	card.Note = normalizeText(card.Note)
+	if errs := validateRequest(card); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	card.Id, card.Code, card.VoidedAt = 0, newGiftCardCode(), nil
+	card.Amount = roundCents(card.Amount)
+	card.Balance, card.Redemptions = card.Amount, []GiftCardRedemption{}
+	card.IssuedAt = time.Now().UTC().Truncate(time.Microsecond)
+	card.CreatedAt, card.UpdatedAt = card.IssuedAt, card.IssuedAt
+
+	if err := store.CreateGiftCard(r.Context(), &card); err != nil {
+		writeProblem(w, r, codeInternal, "Error issuing gift card")
+		log.Printf("Gift card creation error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, GiftCardResponse{
+		Status:  "success",
+		Message: "Gift card issued successfully",
+		Data:    card,
+	})
+}
+
+// The gift card with the code in the path, with its balance and what it
+// paid for.
+func getGiftCardHandler(w http.ResponseWriter, r *http.Request) {
+	code, ok := normalizeGiftCardCode(mux.Vars(r)["code"])
+	if !ok {
+		writeProblem(w, r, codeGiftCardNotFound, "Gift card not found")
+		return
+	}
+
+	card, err := store.GetGiftCard(r.Context(), code)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeGiftCardNotFound, "Gift card not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching gift card")
+		log.Printf("Gift card query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, GiftCardResponse{
+		Status:  "success",
+		Message: "Gift card retrieved successfully",
+		Data:    card,
+	})
+}
+
+// Void a gift card, so what is left of its balance can't be spent, as
+// when it was lost or sold by mistake.
+func voidGiftCardHandler(w http.ResponseWriter, r *http.Request) {
+	code, ok := normalizeGiftCardCode(mux.Vars(r)["code"])
+	if !ok {
+		writeProblem(w, r, codeGiftCardNotFound, "Gift card not found")
+		return
+	}
+
+	var card GiftCard
+	err := store.WithTx(r.Context(), func(tx BookStore) error {
+		var err error
+		if card, err = tx.GetGiftCard(r.Context(), code); err != nil {
+			return err
+		}
+		if card.VoidedAt != nil {
+			return errGiftCardVoided
+		}
+		voidedAt := time.Now().UTC().Truncate(time.Microsecond)
+		card.VoidedAt, card.UpdatedAt = &voidedAt, voidedAt
+		return tx.VoidGiftCard(r.Context(), card.Id, voidedAt)
+	})
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeGiftCardNotFound, "Gift card not found")
+		return
+	case errors.Is(err, errGiftCardVoided):
+		writeProblem(w, r, codeGiftCardVoided, "The gift card was already voided")
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error voiding gift card")
+		log.Printf("Gift card void error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, GiftCardResponse{
+		Status:  "success",
+		Message: "Gift card voided successfully",
+		Data:    card,
+	})
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// The units reading goals count in.
+const (
+	GoalBooks = "books"
+	GoalPages = "pages"
+)
+
+var goalUnits = []string{GoalBooks, GoalPages}
+
+// ReadingGoal is how many books, or pages, a reader means to finish in a
+// year.
+type ReadingGoal struct {
+	UserId int    `json:"-" xml:"-"`
+	Year   int    `json:"year" xml:"year"`
+	Unit   string `json:"unit" xml:"unit" validate:"required,goal_unit"`
+	Target int    `json:"target" xml:"target" validate:"required,min=1,max=1000000"`
+	// Progress is worked out from the books finished in the year when
+	// the goal is read.
+	Progress *GoalProgress `json:"progress,omitempty" xml:"progress,omitempty"`
+	// CreatedAt is when the goal for the year was first set, and absent
+	// for goals from before it was kept, as is UpdatedAt until the goal
+	// is set again.
+	CreatedAt *time.Time `json:"created_at,omitempty" xml:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty" xml:"updated_at,omitempty"`
+}
+
+func (g ReadingGoal) timestamps() (created, updated time.Time) {
+	return timeOrZero(g.CreatedAt), timeOrZero(g.UpdatedAt)
+}
+
+// GoalProgress is what a reader finished in a goal's year: the books on
+// their shelf marked finished that year, and the pages of those whose page
+// count is known from their reading progress.
+type GoalProgress struct {
+	Books int `json:"books" xml:"books"`
+	Pages int `json:"pages" xml:"pages"`
+	// BooksWithoutPages are the finished books of unknown length, which
+	// add nothing to Pages.
+	BooksWithoutPages int `json:"books_without_pages" xml:"books_without_pages"`
+	// Percent is how much of the target is done, up to 100.
+	Percent float64 `json:"percent" xml:"percent"`
+	// Expected is how much should be done by today to meet the target
+	// reading evenly through the year; it is only set in the year itself.
+	Expected *int         `json:"expected,omitempty" xml:"expected,omitempty"`
+	Months   []MonthStats `json:"months" xml:"months>month"`
+	// Genres are the categories of the finished books, most read first.
+	// A book counts in each of its categories.
+	Genres []GenreStats `json:"genres" xml:"genres>genre"`
+}
+
+type MonthStats struct {
+	Month int `json:"month" xml:"month"`
+	Books int `json:"books" xml:"books"`
+	Pages int `json:"pages" xml:"pages"`
+}
+
+type GenreStats struct {
+	Genre string `json:"genre" xml:"genre"`
+	Books int    `json:"books" xml:"books"`
+	Pages int    `json:"pages" xml:"pages"`
+}
+
+type GoalResponse struct {
+	Status  string      `json:"status" xml:"status"`
+	Message string      `json:"message" xml:"message"`
+	Data    ReadingGoal `json:"data" xml:"data"`
+}
+
+type GoalsResponse struct {
+	Status  string        `json:"status" xml:"status"`
+	Message string        `json:"message" xml:"message"`
+	Data    []ReadingGoal `json:"data" xml:"data>goal"`
+}
+
+// A book a reader finished, when, and its page count, 0 if unknown.
+type finishedBook struct {
+	bookId     int
+	finishedAt time.Time
+	pages      int
+	genres     []string
+}
+
+func listGoalsHandler(w http.ResponseWriter, r *http.Request) {
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+	userId := currentUser(r).Id
+	goals, err := store.ListGoals(r.Context(), userId, after)
+	if err == nil {
+		err = addGoalProgress(r.Context(), userId, goals)
+	}
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching goals")
+		log.Printf("Goal query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, GoalsResponse{
+		Status:  "success",
+		Message: "Goals retrieved successfully",
+		Data:    goals,
+	})
+}
+
+func getGoalHandler(w http.ResponseWriter, r *http.Request) {
+	year, ok := goalYear(r)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "Invalid year")
+		return
+	}
+
+	userId := currentUser(r).Id
+	goal, err := store.GetGoal(r.Context(), userId, year)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeGoalNotFound, "Goal not found")
+		return
+	}
+	if err == nil {
+		goals := []ReadingGoal{goal}
+		err = addGoalProgress(r.Context(), userId, goals)
+		goal = goals[0]
+	}
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching goal")
+		log.Printf("Goal query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, GoalResponse{
+		Status:  "success",
+		Message: "Goal retrieved successfully",
+		Data:    goal,
+	})
+}
+
+// Set the user's goal for a year, replacing any they had.
+func setGoalHandler(w http.ResponseWriter, r *http.Request) {
+	year, ok := goalYear(r)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "Invalid year")
+		return
+	}
+	var goal ReadingGoal
+	if err := decodeRequest(r, &goal); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	if errs := validateRequest(goal); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	userId := currentUser(r).Id
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	goal = ReadingGoal{UserId: userId, Year: year, Unit: goal.Unit, Target: goal.Target, CreatedAt: &now, UpdatedAt: &now}
+
+	err := store.SetGoal(r.Context(), &goal)
+	if err == nil {
+		goals := []ReadingGoal{goal}
+		err = addGoalProgress(r.Context(), userId, goals)
+		goal = goals[0]
+	}
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error saving goal")
+		log.Printf("Goal update error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, GoalResponse{
+		Status:  "success",
+		Message: "Goal saved successfully",
+		Data:    goal,
+	})
+}
+
+func deleteGoalHandler(w http.ResponseWriter, r *http.Request) {
+	year, ok := goalYear(r)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "Invalid year")
+		return
+	}
+
+	err := store.DeleteGoal(r.Context(), currentUser(r).Id, year)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeGoalNotFound, "Goal not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error deleting goal")
+		log.Printf("Goal deletion error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Goal deleted successfully",
+	})
+}
+
+// The year in the URL, a four-digit one.
+func goalYear(r *http.Request) (int, bool) {
+	year, err := strconv.Atoi(mux.Vars(r)["year"])
+	return year, err == nil && year >= 1000 && year <= 9999
+}
+
+// Work out the progress of each of the user's goals from the books they
+// finished.
+func addGoalProgress(ctx context.Context, userId int, goals []ReadingGoal) error {
+	if len(goals) == 0 {
+		return nil
+	}
+	books, err := finishedBooks(ctx, userId)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for i := range goals {
+		goals[i].Progress = goalProgress(goals[i], books, now)
+	}
+	return nil
+}
+
+// The books on the user's shelf marked finished, each at the last time it
+// was, with their page counts and categories.
+func finishedBooks(ctx context.Context, userId int) ([]finishedBook, error) {
+	items, err := store.ListShelf(ctx, userId, ReadingFinished, ChangedAfter{})
+	if err != nil {
+		return nil, err
+	}
+
+	books := make([]finishedBook, 0, len(items))
+	for _, item := range items {
+		book := finishedBook{bookId: item.BookId, finishedAt: item.AddedAt}
+		for _, change := range item.StatusChanges {
+			if change.Status == ReadingFinished {
+				book.finishedAt = change.ChangedAt
+			}
+		}
+		if item.Progress != nil && item.Progress.Pages != nil {
+			book.pages = *item.Progress.Pages
+		}
+		subjects, err := store.GetBookSubjects(ctx, item.BookId)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		book.genres = subjects.Categories
+		books = append(books, book)
+	}
+	return books, nil
+}
+
+// What the books finished in the goal's year add up to, by month and by
+// genre, as of now.
+func goalProgress(goal ReadingGoal, books []finishedBook, now time.Time) *GoalProgress {
+	progress := &GoalProgress{Months: make([]MonthStats, 12), Genres: []GenreStats{}}
+	for i := range progress.Months {
+		progress.Months[i].Month = i + 1
+	}
+	genres := map[string]*GenreStats{}
+	for _, book := range books {
+		if book.finishedAt.Year() != goal.Year {
+			continue
+		}
+		progress.Books++
+		progress.Pages += book.pages
+		if book.pages == 0 {
+			progress.BooksWithoutPages++
+		}
+		month := &progress.Months[book.finishedAt.Month()-1]
+		month.Books++
+		month.Pages += book.pages
+		for _, name := range book.genres {
+			genre, ok := genres[name]
+			if !ok {
+				genre = &GenreStats{Genre: name}
+				genres[name] = genre
+			}
+			genre.Books++
+			genre.Pages += book.pages
+		}
+	}
+	for _, genre := range genres {
+		progress.Genres = append(progress.Genres, *genre)
+	}
+	sort.Slice(progress.Genres, func(i, j int) bool {
+		a, b := progress.Genres[i], progress.Genres[j]
+		if a.Books != b.Books {
+			return a.Books > b.Books
+		}
+		return a.Genre < b.Genre
+	})
+
+	done := progress.Books
+	if goal.Unit == GoalPages {
+		done = progress.Pages
+	}
+	progress.Percent = min(math.Round(float64(done)/float64(goal.Target)*1000)/10, 100)
+	if now.Year() == goal.Year {
+		start := time.Date(goal.Year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		end := start.AddDate(1, 0, 0)
+		expected := int(float64(goal.Target) * float64(now.Sub(start)) / float64(end.Sub(start)))
+		progress.Expected = &expected
+	}
+	return progress
+}
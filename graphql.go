@@ -0,0 +1,654 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// Author is derived from the author names on books; there is no authors
+// table.
+type Author struct {
+	Name string `json:"name" xml:"name"`
+}
+
+// graphqlRequest is the standard GraphQL-over-HTTP request body.
+type graphqlRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+var graphqlSchema = mustBuildGraphQLSchema()
+
+// Limits on what one GraphQL request may ask for, as the API is open and
+// nested lists multiply: books { reviews { book { reviews ... } } }.
+const (
+	// maxGraphQLBodySize bounds POST bodies, variables included.
+	maxGraphQLBodySize = 1 << 20
+	// maxGraphQLQuerySize bounds the query document.
+	maxGraphQLQuerySize = 16 << 10
+	// maxGraphQLDepth bounds how deeply fields nest.
+	maxGraphQLDepth = 8
+	// maxGraphQLCost bounds the fields resolved, counting one per field
+	// and what is under a list field graphqlListCost times over.
+	maxGraphQLCost = 1000
+	// graphqlListCost is the items a list is reckoned to have.
+	graphqlListCost = 10
+)
+
+// Serve GraphQL queries, as a JSON POST body or, for queries only, as GET
+// parameters.
+func graphqlHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req graphqlRequest
+	if r.Method == http.MethodGet {
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if v := r.URL.Query().Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeGraphQLError(w, http.StatusBadRequest, codedError{codeInvalidParameter, "Invalid variables"})
+				return
+			}
+		}
+	} else if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLBodySize)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeGraphQLError(w, http.StatusRequestEntityTooLarge, codedError{codeRequestTooLarge, "The request body is larger than 1 MiB"})
+			return
+		}
+		writeGraphQLError(w, http.StatusBadRequest, codedError{codeInvalidRequest, "Invalid request body"})
+		return
+	}
+
+	if req.Query == "" {
+		writeGraphQLError(w, http.StatusBadRequest, codedError{codeInvalidRequest, "Query is required"})
+		return
+	}
+	if len(req.Query) > maxGraphQLQuerySize {
+		writeGraphQLError(w, http.StatusRequestEntityTooLarge, codedError{codeRequestTooLarge, "The query is larger than 16 KiB"})
+		return
+	}
+
+	doc, op := parseGraphQLOperation(req.Query, req.OperationName)
+	// Mutations over GET would be reachable from a plain link.
+	if r.Method == http.MethodGet && op != nil && op.Operation == ast.OperationTypeMutation {
+		writeGraphQLError(w, http.StatusMethodNotAllowed, codedError{codeInvalidRequest, "Mutations require POST"})
+		return
+	}
+	if op != nil {
+		if err := checkGraphQLCost(doc, op); err != nil {
+			writeGraphQLError(w, http.StatusBadRequest, *err)
+			return
+		}
+	}
+
+	// Reviews posted with a user's token are theirs. Other tokens, such
+	// as the admin's, don't make a user.
+	ctx := r.Context()
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		user, err := store.GetUserByToken(ctx, hashUserToken(token))
+		if err == nil {
+			ctx = context.WithValue(ctx, userContextKey{}, user)
+		} else if !errors.Is(err, ErrNotFound) {
+			writeGraphQLError(w, http.StatusInternalServerError, codedError{codeInternal, "Error fetching user"})
+			log.Printf("User query error: %v", err)
+			return
+		}
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         graphqlSchema,
+		RequestString:  req.Query,
+		OperationName:  req.OperationName,
+		VariableValues: req.Variables,
+		Context:        ctx,
+	})
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func mustBuildGraphQLSchema() graphql.Schema {
+	authorType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Author",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	reviewType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Review",
+		Fields: graphql.Fields{
+			"id":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"reviewer": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"rating":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"body":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"createdAt": &graphql.Field{
+				Type: graphql.DateTime,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return timestamp(p.Source.(Review).CreatedAt), nil
+				},
+			},
+		},
+	})
+
+	bookType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Book",
+		Fields: graphql.Fields{
+			"id":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"title": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"price": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"isbn": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if isbn := p.Source.(Book).ISBN; isbn != "" {
+						return isbn, nil
+					}
+					return nil, nil
+				},
+			},
+			"slug": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"publisher": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if publisher := p.Source.(Book).Publisher; publisher != "" {
+						return publisher, nil
+					}
+					return nil, nil
+				},
+			},
+			"format": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if format := p.Source.(Book).Format; format != "" {
+						return format, nil
+					}
+					return nil, nil
+				},
+			},
+			"description": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if description := p.Source.(Book).Description; description != "" {
+						return description, nil
+					}
+					return nil, nil
+				},
+			},
+			"coverUrl": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if coverURL := p.Source.(Book).CoverURL; coverURL != "" {
+						return coverURL, nil
+					}
+					return nil, nil
+				},
+			},
+			"createdAt": &graphql.Field{
+				Type: graphql.DateTime,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return timestamp(p.Source.(Book).CreatedAt), nil
+				},
+			},
+			"updatedAt": &graphql.Field{
+				Type: graphql.DateTime,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return timestamp(p.Source.(Book).UpdatedAt), nil
+				},
+			},
+			"archivedAt": &graphql.Field{
+				Type: graphql.DateTime,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return timestamp(p.Source.(Book).ArchivedAt), nil
+				},
+			},
+		},
+	})
+
+	// The types refer to each other, so the nested fields are added once
+	// all three exist.
+	bookType.AddFieldConfig("author", &graphql.Field{
+		Type: graphql.NewNonNull(authorType),
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			return Author{Name: p.Source.(Book).Author}, nil
+		},
+	})
+	bookType.AddFieldConfig("reviews", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(reviewType))),
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			reviews, err := store.ListReviews(p.Context, p.Source.(Book).Id)
+			return reviews, graphqlError(err, "error fetching reviews")
+		},
+	})
+	authorType.AddFieldConfig("books", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(bookType))),
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			books, err := booksByAuthor(p.Context, p.Source.(Author).Name)
+			return books, graphqlError(err, "error fetching books")
+		},
+	})
+	reviewType.AddFieldConfig("book", &graphql.Field{
+		Type: bookType,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			book, err := store.GetBook(p.Context, p.Source.(Review).BookId)
+			if errors.Is(err, ErrNotFound) {
+				return nil, nil
+			}
+			return book, graphqlError(err, "error fetching book")
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"books": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(bookType))),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					books, err := store.ListBooks(p.Context)
+					return books, graphqlError(err, "error fetching books")
+				},
+			},
+			"book": &graphql.Field{
+				Type: bookType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					book, err := store.GetBook(p.Context, p.Args["id"].(int))
+					if errors.Is(err, ErrNotFound) {
+						return nil, nil
+					}
+					return book, graphqlError(err, "error fetching book")
+				},
+			},
+			"bookBySlug": &graphql.Field{
+				Type: bookType,
+				Args: graphql.FieldConfigArgument{
+					"slug": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					id, err := store.GetBookIdBySlug(p.Context, p.Args["slug"].(string))
+					if err == nil {
+						var book Book
+						if book, err = store.GetBook(p.Context, id); err == nil {
+							return book, nil
+						}
+					}
+					if errors.Is(err, ErrNotFound) {
+						return nil, nil
+					}
+					return nil, graphqlError(err, "error fetching book")
+				},
+			},
+			"searchBooks": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(bookType))),
+				Args: graphql.FieldConfigArgument{
+					"query": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"fuzzy": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: true},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					q := p.Args["query"].(string)
+					if q == "" {
+						return nil, codedError{codeInvalidParameter, "query is required"}
+					}
+					filter, err := parseBookFilter(q)
+					if err != nil {
+						return nil, codedError{codeInvalidQuery, err.Error()}
+					}
+					started := time.Now()
+					books, err := searchBooks(p.Context, BookQuery{Search: q, Filter: filter}, p.Args["fuzzy"].(bool))
+					if err != nil {
+						return nil, graphqlError(err, "error searching books")
+					}
+					recordSearch(p.Context, q, len(books), started)
+					return books, nil
+				},
+			},
+			"authors": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(authorType))),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					books, err := store.ListBooks(p.Context)
+					if err != nil {
+						return nil, graphqlError(err, "error fetching authors")
+					}
+					return authorsOf(books), nil
+				},
+			},
+			"author": &graphql.Field{
+				Type: authorType,
+				Args: graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					name := p.Args["name"].(string)
+					books, err := booksByAuthor(p.Context, name)
+					if err != nil || len(books) == 0 {
+						return nil, graphqlError(err, "error fetching author")
+					}
+					return Author{Name: name}, nil
+				},
+			},
+			"reviews": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(reviewType))),
+				Args: graphql.FieldConfigArgument{
+					"bookId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					reviews, err := store.ListReviews(p.Context, p.Args["bookId"].(int))
+					return reviews, graphqlError(err, "error fetching reviews")
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createBook": &graphql.Field{
+				Type: graphql.NewNonNull(bookType),
+				Args: graphql.FieldConfigArgument{
+					"title":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"author":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"price":       &graphql.ArgumentConfig{Type: graphql.Float, DefaultValue: 0.0},
+					"isbn":        &graphql.ArgumentConfig{Type: graphql.String},
+					"publisher":   &graphql.ArgumentConfig{Type: graphql.String},
+					"format":      &graphql.ArgumentConfig{Type: graphql.String},
+					"description": &graphql.ArgumentConfig{Type: graphql.String},
+					"coverUrl":    &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					book := Book{
+						Title:  p.Args["title"].(string),
+						Author: p.Args["author"].(string),
+						Price:  p.Args["price"].(float64),
+					}
+					book.ISBN, _ = p.Args["isbn"].(string)
+					book.Publisher, _ = p.Args["publisher"].(string)
+					book.Format, _ = p.Args["format"].(string)
+					book.Description, _ = p.Args["description"].(string)
+					book.CoverURL, _ = p.Args["coverUrl"].(string)
+					book.normalize()
+					if err := book.checkISBN(); err != nil {
+						return nil, codedError{codeInvalidISBN, err.Error()}
+					}
+					if errs := validateRequest(book); errs != nil {
+						return nil, codedError{codeValidationFailed, validationMessage(errs)}
+					}
+					if err := createBook(p.Context, store, &book); err != nil {
+						return nil, graphqlError(err, "error creating book")
+					}
+					return book, nil
+				},
+			},
+			"updateBook": &graphql.Field{
+				Type: graphql.NewNonNull(bookType),
+				Args: graphql.FieldConfigArgument{
+					"id":          &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"title":       &graphql.ArgumentConfig{Type: graphql.String},
+					"author":      &graphql.ArgumentConfig{Type: graphql.String},
+					"price":       &graphql.ArgumentConfig{Type: graphql.Float},
+					"isbn":        &graphql.ArgumentConfig{Type: graphql.String},
+					"publisher":   &graphql.ArgumentConfig{Type: graphql.String},
+					"format":      &graphql.ArgumentConfig{Type: graphql.String},
+					"description": &graphql.ArgumentConfig{Type: graphql.String},
+					"coverUrl":    &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					// Like PUT /book/{id}, only the given non-empty fields change.
+					var book Book
+					book.Title, _ = p.Args["title"].(string)
+					book.Author, _ = p.Args["author"].(string)
+					book.Price, _ = p.Args["price"].(float64)
+					book.ISBN, _ = p.Args["isbn"].(string)
+					book.Publisher, _ = p.Args["publisher"].(string)
+					book.Format, _ = p.Args["format"].(string)
+					book.Description, _ = p.Args["description"].(string)
+					book.CoverURL, _ = p.Args["coverUrl"].(string)
+					book.normalize()
+					if len(book.updatedFields()) == 0 {
+						return nil, codedError{codeNoFieldsToUpdate, "no fields to update"}
+					}
+					if err := book.checkISBN(); err != nil {
+						return nil, codedError{codeInvalidISBN, err.Error()}
+					}
+					if errs := validateFields(book, book.updatedFields()...); errs != nil {
+						return nil, codedError{codeValidationFailed, validationMessage(errs)}
+					}
+
+					updated, err := updateExistingBook(p.Context, store, p.Args["id"].(int), book)
+					if err != nil {
+						return nil, graphqlError(err, "error updating book")
+					}
+					return updated, nil
+				},
+			},
+			"createReview": &graphql.Field{
+				Type: graphql.NewNonNull(reviewType),
+				Args: graphql.FieldConfigArgument{
+					"bookId":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"reviewer": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"rating":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"body":     &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: ""},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					review := Review{
+						BookId:   p.Args["bookId"].(int),
+						Reviewer: p.Args["reviewer"].(string),
+						Rating:   p.Args["rating"].(int),
+						Body:     p.Args["body"].(string),
+					}
+					if user, ok := p.Context.Value(userContextKey{}).(User); ok {
+						review.UserId = &user.Id
+					}
+					review.normalize()
+					if errs := validateRequest(review); errs != nil {
+						return nil, codedError{codeValidationFailed, validationMessage(errs)}
+					}
+					if err := store.CreateReview(p.Context, &review); err != nil {
+						return nil, graphqlError(err, "error creating review")
+					}
+					return review, nil
+				},
+			},
+		},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+	if err != nil {
+		log.Fatalf("GraphQL schema: %v", err)
+	}
+	return schema
+}
+
+// The query parsed and the operation of it a request would run, or nil if
+// it has none. Queries that don't parse are left for graphql.Do to report.
+func parseGraphQLOperation(query, operationName string) (*ast.Document, *ast.OperationDefinition) {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return nil, nil
+	}
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || (op.Name != nil && op.Name.Value == operationName) {
+			return doc, op
+		}
+	}
+	return doc, nil
+}
+
+// Check the operation against maxGraphQLDepth and maxGraphQLCost before
+// any of it runs. Introspection fields aren't counted, as the schema
+// bounds them.
+func checkGraphQLCost(doc *ast.Document, op *ast.OperationDefinition) *codedError {
+	c := graphqlCost{fragments: map[string]*ast.FragmentDefinition{}, spreading: map[string]bool{}}
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok {
+			c.fragments[fragment.Name.Value] = fragment
+		}
+	}
+	root := graphqlSchema.QueryType()
+	if op.Operation == ast.OperationTypeMutation {
+		root = graphqlSchema.MutationType()
+	}
+	cost := c.selections(op.SelectionSet, root, 1)
+	switch {
+	case c.tooDeep:
+		return &codedError{codeQueryTooComplex, fmt.Sprintf("The query nests fields more than %d deep", maxGraphQLDepth)}
+	case cost > maxGraphQLCost:
+		return &codedError{codeQueryTooComplex, fmt.Sprintf("The query would resolve more than %d fields", maxGraphQLCost)}
+	}
+	return nil
+}
+
+// graphqlCost adds up what a query's selections cost.
+type graphqlCost struct {
+	fragments map[string]*ast.FragmentDefinition
+	// spreading has the fragments being walked, which a spread of one of
+	// them inside itself would never leave; graphql.Do reports those.
+	spreading map[string]bool
+	tooDeep   bool
+}
+
+// The cost of the selections of set on parent, nil for fields outside the
+// schema's objects, depth fields deep. It stops adding up once over
+// maxGraphQLCost, so fragments spread many times over can't make it slow.
+func (c *graphqlCost) selections(set *ast.SelectionSet, parent *graphql.Object, depth int) int {
+	if set == nil {
+		return 0
+	}
+	cost := 0
+	for _, selection := range set.Selections {
+		if cost > maxGraphQLCost || c.tooDeep {
+			return cost
+		}
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name.Value, "__") {
+				continue
+			}
+			if depth > maxGraphQLDepth {
+				c.tooDeep = true
+				return cost
+			}
+			var fieldType graphql.Type
+			if parent != nil {
+				if field, ok := parent.Fields()[s.Name.Value]; ok {
+					fieldType = field.Type
+				}
+			}
+			object, list := graphqlObjectOf(fieldType)
+			under := c.selections(s.SelectionSet, object, depth+1)
+			if list {
+				under *= graphqlListCost
+			}
+			cost += 1 + under
+		case *ast.InlineFragment:
+			cost += c.selections(s.SelectionSet, parent, depth)
+		case *ast.FragmentSpread:
+			fragment, ok := c.fragments[s.Name.Value]
+			if !ok || c.spreading[s.Name.Value] {
+				continue
+			}
+			c.spreading[s.Name.Value] = true
+			cost += c.selections(fragment.SelectionSet, parent, depth)
+			delete(c.spreading, s.Name.Value)
+		}
+	}
+	return cost
+}
+
+// The object a field of type t resolves to, if any, and whether it is a
+// list of them.
+func graphqlObjectOf(t graphql.Type) (*graphql.Object, bool) {
+	list := false
+	for {
+		switch u := t.(type) {
+		case *graphql.NonNull:
+			t = u.OfType
+		case *graphql.List:
+			list, t = true, u.OfType
+		case *graphql.Object:
+			return u, list
+		default:
+			return nil, list
+		}
+	}
+}
+
+// Books by an exact author name.
+func booksByAuthor(ctx context.Context, name string) ([]Book, error) {
+	books, err := store.ListBooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []Book{}
+	for _, book := range books {
+		if book.Author == name {
+			matches = append(matches, book)
+		}
+	}
+	return matches, nil
+}
+
+// The distinct authors of books, sorted by name.
+func authorsOf(books []Book) []Author {
+	seen := map[string]bool{}
+	authors := []Author{}
+	for _, book := range books {
+		if !seen[book.Author] {
+			seen[book.Author] = true
+			authors = append(authors, Author{Name: book.Author})
+		}
+	}
+	sort.Slice(authors, func(i, j int) bool { return authors[i].Name < authors[j].Name })
+	return authors
+}
+
+// Answer a request that couldn't be run at all, in the shape of a GraphQL
+// response.
+func writeGraphQLError(w http.ResponseWriter, status int, err codedError) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"errors": []map[string]any{{"message": err.message, "extensions": err.Extensions()}},
+	})
+}
+
+// Map a store error to one safe to show clients, with its error code,
+// logging unexpected ones.
+func graphqlError(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, ErrNotFound) {
+		return codedError{codeBookNotFound, "book not found"}
+	}
+	if errors.Is(err, ErrDuplicateISBN) {
+		return codedError{codeDuplicateISBN, "another book has this isbn"}
+	}
+	log.Printf("%s: %v", message, err)
+	return codedError{codeInternal, message}
+}
+
+// A timestamp for a DateTime field, null if it wasn't recorded.
+func timestamp(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
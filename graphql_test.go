@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckGraphQLCost(t *testing.T) {
+	tests := []struct {
+		name, query, err string
+	}{
+		{"plain", `{ books { id title author { name } reviews { rating body } } }`, ""},
+		{"one book", `query($id: Int!) { book(id: $id) { title reviews { book { title } } } }`, ""},
+		{"introspection", `{ __schema { types { name fields { name type { name ofType { name ofType { name ofType { name ofType { name } } } } } } } } }`, ""},
+		{"nested lists", `{ books { reviews { book { reviews { book { title } } } } } }`, "more than 1000 fields"},
+		{"aliases", "{" + strings.Repeat(" b: books { title }", 100) + " }", "more than 1000 fields"},
+		{"deep", `query($id: Int!) { book(id: $id) { author { books { author { books { author { books { author { books { title } } } } } } } } } }`, "more than 8 deep"},
+		{"fragments", `{ books { ...r } } fragment r on Book { reviews { book { ...s } } } fragment s on Book { reviews { book { title } } }`, "more than 1000 fields"},
+		{"fragment cycle", `{ books { ...a } } fragment a on Book { author { books { ...a } } }`, ""},
+	}
+	for _, tt := range tests {
+		doc, op := parseGraphQLOperation(tt.query, "")
+		if op == nil {
+			t.Fatalf("%s: the query doesn't parse", tt.name)
+		}
+		err := checkGraphQLCost(doc, op)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: checkGraphQLCost = %q, want nil", tt.name, err.message)
+		case tt.err != "" && err == nil:
+			t.Errorf("%s: checkGraphQLCost = nil, want an error with %q", tt.name, tt.err)
+		case tt.err != "" && !strings.Contains(err.message, tt.err):
+			t.Errorf("%s: checkGraphQLCost = %q, want an error with %q", tt.name, err.message, tt.err)
+		}
+	}
+}
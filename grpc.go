@@ -0,0 +1,227 @@
+package main
+
+//go:generate protoc -I proto --go_out=proto --go_opt=paths=source_relative --go-grpc_out=proto --go-grpc_opt=paths=source_relative bookshelf/v1/bookshelf.proto
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	bookshelfv1 "github.com/amroexe/proto/bookshelf/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// bookServiceServer implements the gRPC BookService on the same store as
+// the REST handlers.
+type bookServiceServer struct {
+	bookshelfv1.UnimplementedBookServiceServer
+}
+
+// Serve gRPC on addr until the listener fails, with the calls that change
+// the catalog for admins only, as with token for the REST API.
+func serveGRPC(addr, token string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(grpcTenant, grpcAdmin(token)))
+	bookshelfv1.RegisterBookServiceServer(s, bookServiceServer{})
+	// Lets tools like grpcurl discover the service without the .proto file.
+	reflection.Register(s)
+
+	log.Printf("gRPC server starting on %s", addr)
+	return s.Serve(lis)
+}
+
+// Make each call act for the tenant named in its x-tenant metadata, the
+// gRPC counterpart of the X-Tenant header, or the default tenant.
+func grpcTenant(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	slugs := md.Get("x-tenant")
+	if len(slugs) == 0 || slugs[0] == "" {
+		return handler(ctx, req)
+	}
+
+	tenant, err := lookupTenant(ctx, strings.ToLower(slugs[0]))
+	if errors.Is(err, ErrNotFound) {
+		return nil, grpcStatus(codes.NotFound, codeTenantNotFound, "tenant not found")
+	} else if err != nil {
+		log.Printf("error fetching tenant: %v", err)
+		return nil, grpcStatus(codes.Internal, codeInternal, "error fetching tenant")
+	}
+	return handler(withTenant(ctx, tenant), req)
+}
+
+// The calls that change the catalog.
+var grpcWrites = map[string]bool{
+	bookshelfv1.BookService_CreateBook_FullMethodName:     true,
+	bookshelfv1.BookService_UpdateBook_FullMethodName:     true,
+	bookshelfv1.BookService_DeleteAllBooks_FullMethodName: true,
+}
+
+// Allow the calls that change the catalog only with the admin token or an
+// admin user's as "Bearer <token>" in their authorization metadata, the
+// gRPC counterpart of requireAdmin. With no token configured only admin
+// users can make them.
+func grpcAdmin(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !grpcWrites[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		var given string
+		if values := md.Get("authorization"); len(values) > 0 {
+			given, _ = strings.CutPrefix(values[0], "Bearer ")
+		}
+		if given != "" && isOperator(token, given) {
+			return handler(ctx, req)
+		}
+		if given != "" {
+			user, err := store.GetUserByToken(ctx, hashUserToken(given))
+			if err == nil && user.Admin {
+				return handler(ctx, req)
+			} else if err != nil && !errors.Is(err, ErrNotFound) {
+				log.Printf("User query error: %v", err)
+				return nil, grpcStatus(codes.Internal, codeInternal, "error fetching user")
+			}
+		}
+		return nil, grpcStatus(codes.Unauthenticated, codeUnauthorized, "admin token required")
+	}
+}
+
+func bookToProto(book Book) *bookshelfv1.Book {
+	return &bookshelfv1.Book{
+		Id:     int64(book.Id),
+		Title:  book.Title,
+		Author: book.Author,
+		Price:  book.Price,
+		Isbn:   book.ISBN,
+		Slug:   book.Slug,
+	}
+}
+
+func booksToProto(books []Book) []*bookshelfv1.Book {
+	out := make([]*bookshelfv1.Book, len(books))
+	for i, book := range books {
+		out[i] = bookToProto(book)
+	}
+	return out
+}
+
+func bookFromProto(book *bookshelfv1.Book) Book {
+	return Book{
+		Title:  book.GetTitle(),
+		Author: book.GetAuthor(),
+		Price:  book.GetPrice(),
+		ISBN:   book.GetIsbn(),
+	}
+}
+
+// Map a store error to a gRPC status, logging unexpected ones.
+func grpcError(err error, message string) error {
+	if errors.Is(err, ErrNotFound) {
+		return grpcStatus(codes.NotFound, codeBookNotFound, "book not found")
+	}
+	if errors.Is(err, ErrDuplicateISBN) {
+		return grpcStatus(codes.AlreadyExists, codeDuplicateISBN, "another book has this isbn")
+	}
+	log.Printf("%s: %v", message, err)
+	return grpcStatus(codes.Internal, codeInternal, message)
+}
+
+// A gRPC status error carrying the error code as the reason of an
+// ErrorInfo detail.
+func grpcStatus(c codes.Code, code, message string) error {
+	st, err := status.New(c, message).WithDetails(&errdetails.ErrorInfo{Reason: code, Domain: "bookshelf"})
+	if err != nil {
+		return status.Error(c, message)
+	}
+	return st.Err()
+}
+
+func (bookServiceServer) CreateBook(ctx context.Context, req *bookshelfv1.CreateBookRequest) (*bookshelfv1.Book, error) {
+	book := bookFromProto(req.GetBook())
+	book.normalize()
+	if err := book.checkISBN(); err != nil {
+		return nil, grpcStatus(codes.InvalidArgument, codeInvalidISBN, err.Error())
+	}
+	if errs := validateRequest(book); errs != nil {
+		return nil, grpcStatus(codes.InvalidArgument, codeValidationFailed, validationMessage(errs))
+	}
+
+	if err := createBook(ctx, store, &book); err != nil {
+		return nil, grpcError(err, "error creating book")
+	}
+	return bookToProto(book), nil
+}
+
+func (bookServiceServer) GetBook(ctx context.Context, req *bookshelfv1.GetBookRequest) (*bookshelfv1.Book, error) {
+	book, err := store.GetBook(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, grpcError(err, "error fetching book")
+	}
+	return bookToProto(book), nil
+}
+
+func (bookServiceServer) UpdateBook(ctx context.Context, req *bookshelfv1.UpdateBookRequest) (*bookshelfv1.Book, error) {
+	book := bookFromProto(req.GetBook())
+	book.normalize()
+	if len(book.updatedFields()) == 0 {
+		return nil, grpcStatus(codes.InvalidArgument, codeNoFieldsToUpdate, "no fields to update")
+	}
+	if err := book.checkISBN(); err != nil {
+		return nil, grpcStatus(codes.InvalidArgument, codeInvalidISBN, err.Error())
+	}
+	if errs := validateFields(book, book.updatedFields()...); errs != nil {
+		return nil, grpcStatus(codes.InvalidArgument, codeValidationFailed, validationMessage(errs))
+	}
+
+	updated, err := updateExistingBook(ctx, store, int(req.GetId()), book)
+	if err != nil {
+		return nil, grpcError(err, "error updating book")
+	}
+	return bookToProto(updated), nil
+}
+
+func (bookServiceServer) ListBooks(ctx context.Context, req *bookshelfv1.ListBooksRequest) (*bookshelfv1.ListBooksResponse, error) {
+	books, err := store.ListBooks(ctx)
+	if err != nil {
+		return nil, grpcError(err, "error fetching books")
+	}
+	return &bookshelfv1.ListBooksResponse{Books: booksToProto(books)}, nil
+}
+
+func (bookServiceServer) SearchBooks(ctx context.Context, req *bookshelfv1.SearchBooksRequest) (*bookshelfv1.SearchBooksResponse, error) {
+	if req.GetQuery() == "" {
+		return nil, grpcStatus(codes.InvalidArgument, codeInvalidParameter, "query is required")
+	}
+	filter, err := parseBookFilter(req.GetQuery())
+	if err != nil {
+		return nil, grpcStatus(codes.InvalidArgument, codeInvalidQuery, err.Error())
+	}
+
+	started := time.Now()
+	books, err := searchBooks(ctx, BookQuery{Search: req.GetQuery(), Filter: filter}, true)
+	if err != nil {
+		return nil, grpcError(err, "error searching books")
+	}
+	recordSearch(ctx, req.GetQuery(), len(books), started)
+	return &bookshelfv1.SearchBooksResponse{Books: booksToProto(books)}, nil
+}
+
+func (bookServiceServer) DeleteAllBooks(ctx context.Context, req *bookshelfv1.DeleteAllBooksRequest) (*bookshelfv1.DeleteAllBooksResponse, error) {
+	deleted, err := store.DeleteAllBooks(ctx)
+	if err != nil {
+		return nil, grpcError(err, "error deleting books")
+	}
+	return &bookshelfv1.DeleteAllBooksResponse{Deleted: deleted}, nil
+}
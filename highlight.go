@@ -0,0 +1,144 @@
+package main
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// SearchHighlight shows why a search found a book: its title and author,
+// escaped as HTML, with what matched in <em> tags. A field without a
+// match is left out.
+type SearchHighlight struct {
+	BookId int    `json:"book_id" xml:"book_id"`
+	Title  string `json:"title,omitempty" xml:"title,omitempty"`
+	Author string `json:"author,omitempty" xml:"author,omitempty"`
+}
+
+// Highlight what search found in book, as searchBooks matches it or as
+// filter does if it is an advanced search, and report whether anything
+// was.
+func highlightBook(book Book, search string, filter *BookFilter) (SearchHighlight, bool) {
+	highlight := SearchHighlight{BookId: book.Id}
+	if filter != nil {
+		titleTerms, authorTerms := filter.highlightTerms()
+		highlight.Title = highlightTerms(book.Title, titleTerms)
+		highlight.Author = highlightTerms(book.Author, authorTerms)
+	} else {
+		search = strings.ToLower(search)
+		highlight.Title = highlightSearch(book.Title, search)
+		highlight.Author = highlightSearch(book.Author, search)
+	}
+	return highlight, highlight.Title != "" || highlight.Author != ""
+}
+
+// text with each place containing search, lower-cased, highlighted, or
+// failing that each word within a typo or two of one of its words; empty
+// if nothing matched.
+func highlightSearch(text, search string) string {
+	h := newHighlighter(text)
+	if !h.markContaining(search) {
+		h.markWordsNear(wordsOf(search))
+	}
+	return h.String()
+}
+
+// text with each place containing one of terms, lower-cased,
+// highlighted; empty if nothing matched.
+func highlightTerms(text string, terms []string) string {
+	h := newHighlighter(text)
+	for _, term := range terms {
+		h.markContaining(term)
+	}
+	return h.String()
+}
+
+// highlighter marks the characters of a text to highlight.
+type highlighter struct {
+	runes  []rune
+	lower  []rune
+	marked []bool
+	found  bool
+}
+
+func newHighlighter(text string) *highlighter {
+	h := &highlighter{runes: []rune(text)}
+	h.lower = make([]rune, len(h.runes))
+	h.marked = make([]bool, len(h.runes))
+	for i, r := range h.runes {
+		h.lower[i] = unicode.ToLower(r)
+	}
+	return h
+}
+
+func (h *highlighter) mark(from, to int) {
+	for i := from; i < to; i++ {
+		h.marked[i] = true
+	}
+	h.found = true
+}
+
+// Mark each place containing query, lower-cased, and report whether there
+// were any.
+func (h *highlighter) markContaining(query string) bool {
+	q := []rune(query)
+	found := false
+	for i := 0; len(q) > 0 && i+len(q) <= len(h.lower); i++ {
+		if string(h.lower[i:i+len(q)]) == query {
+			h.mark(i, i+len(q))
+			found = true
+			i += len(q) - 1
+		}
+	}
+	return found
+}
+
+// Mark each word within a typo or two of one of words.
+func (h *highlighter) markWordsNear(words []string) {
+	for start := 0; start < len(h.lower); {
+		if !isWordRune(h.lower[start]) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(h.lower) && isWordRune(h.lower[end]) {
+			end++
+		}
+		word := string(h.lower[start:end])
+		for _, searched := range words {
+			allowed := allowedTypos(searched)
+			if editDistance(searched, word, allowed) <= allowed {
+				h.mark(start, end)
+				break
+			}
+		}
+		start = end
+	}
+}
+
+// The text, escaped, with the marked runs in <em> tags; empty if nothing
+// is marked.
+func (h *highlighter) String() string {
+	if !h.found {
+		return ""
+	}
+	var b strings.Builder
+	for i := 0; i < len(h.runes); {
+		j := i
+		for j < len(h.runes) && h.marked[j] == h.marked[i] {
+			j++
+		}
+		if h.marked[i] {
+			b.WriteString("<em>" + html.EscapeString(string(h.runes[i:j])) + "</em>")
+		} else {
+			b.WriteString(html.EscapeString(string(h.runes[i:j])))
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// Whether r is part of a word, as wordsOf splits them.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
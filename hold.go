@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Hold is a member's place in the queue for a book none of whose copies
+// is free. When a copy comes back, the first member waiting gets it: the
+// hold is ready and the copy is kept for them until they borrow it, which
+// fulfils the hold.
+type Hold struct {
+	Id       int       `json:"id" xml:"id"`
+	MemberId int       `json:"member_id" xml:"member_id" validate:"required"`
+	BookId   int       `json:"book_id" xml:"book_id" validate:"required"`
+	PlacedAt time.Time `json:"placed_at" xml:"placed_at"`
+	// ReadyAt is when a copy was set aside for the member.
+	ReadyAt *time.Time `json:"ready_at,omitempty" xml:"ready_at,omitempty"`
+	// Member is loaded by the circulation desk's check-in.
+	Member *Member `json:"member,omitempty" xml:"member,omitempty"`
+	// CreatedAt is PlacedAt, and UpdatedAt ReadyAt once the hold is ready.
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+}
+
+func (h Hold) timestamps() (created, updated time.Time) {
+	return h.CreatedAt, h.UpdatedAt
+}
+
+type HoldResponse struct {
+	Status  string `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
+	Data    Hold   `json:"data" xml:"data"`
+}
+
+type HoldsResponse struct {
+	Status  string `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
+	Data    []Hold `json:"data" xml:"data>hold"`
+}
+
+// How long a copy set aside for a hold waits to be picked up.
+const holdPickupPeriod = 7 * 24 * time.Hour
+
+var errDuplicateHold = errors.New("member already holds the book")
+
+// List the holds on a book in the order they are served.
+func listHoldsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+
+	holds, err := store.ListHolds(r.Context(), id, after)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching holds")
+		log.Printf("Hold query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, HoldsResponse{
+		Status:  "success",
+		Message: "Holds retrieved successfully",
+		Data:    holds,
+	})
+}
+
+// Put a member at the end of the queue for a book.
+func createHoldHandler(w http.ResponseWriter, r *http.Request) {
+	var hold Hold
+	if err := decodeRequest(r, &hold); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	if errs := validateRequest(hold); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	hold = Hold{MemberId: hold.MemberId, BookId: hold.BookId, PlacedAt: time.Now().UTC().Truncate(time.Microsecond)}
+	hold.CreatedAt, hold.UpdatedAt = hold.PlacedAt, hold.PlacedAt
+
+	err := store.WithTx(r.Context(), func(tx BookStore) error {
+		if _, err := tx.GetMember(r.Context(), hold.MemberId); errors.Is(err, ErrNotFound) {
+			return errLoanMemberMissing
+		} else if err != nil {
+			return err
+		}
+		holds, err := tx.ListHolds(r.Context(), hold.BookId, ChangedAfter{})
+		if err != nil {
+			return err
+		}
+		for _, h := range holds {
+			if h.MemberId == hold.MemberId {
+				return errDuplicateHold
+			}
+		}
+		return tx.CreateHold(r.Context(), &hold)
+	})
+	switch {
+	case errors.Is(err, errLoanMemberMissing):
+		writeProblem(w, r, codeMemberNotFound, "Member not found")
+		return
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	case errors.Is(err, errDuplicateHold):
+		writeProblem(w, r, codeDuplicateHold, "The member already has a hold on this book")
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error creating hold")
+		log.Printf("Hold creation error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, HoldResponse{
+		Status:  "success",
+		Message: "Hold created successfully",
+		Data:    hold,
+	})
+}
+
+// Cancel a hold, freeing any copy set aside for it.
+func deleteHoldHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid hold ID")
+		return
+	}
+
+	err = store.DeleteHold(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeHoldNotFound, "Hold not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error deleting hold")
+		log.Printf("Hold deletion error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Hold deleted successfully",
+	})
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// IdempotencyRecord is the response to a request made with an
+// Idempotency-Key, replayed when the request is retried.
+type IdempotencyRecord struct {
+	// Caller is who made the request, as idempotencyCaller gives them;
+	// keys are only theirs.
+	Caller string
+	Key    string
+	// Fingerprint hashes the method, path and body, so a key reused for a
+	// different request is caught.
+	Fingerprint string
+	// Status is 0 while the first request is still being handled.
+	Status      int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+const (
+	// How long responses are kept for replay.
+	idempotencyKeyTTL = 24 * time.Hour
+	// How long a key stays reserved for a request being handled. A request
+	// that takes longer, or whose server died, can be retried after that.
+	idempotencyKeyLease = time.Minute
+	// Longest Idempotency-Key accepted.
+	maxIdempotencyKeyLength = 255
+	// Largest body of a request with an Idempotency-Key, which is read
+	// whole to fingerprint it.
+	maxIdempotentBodySize = 1 << 20
+)
+
+// Make POST handlers safe to retry: the first request with a given
+// Idempotency-Key header runs next and its response is stored, and later
+// ones with the same key get that response back instead of running it
+// again. Server errors aren't stored, so those requests can be retried.
+func withIdempotency(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			writeProblem(w, r, codeInvalidRequest, "Idempotency-Key is too long")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeProblem(w, r, codeRequestTooLarge, "The request body is larger than 1 MiB")
+			return
+		} else if err != nil {
+			writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		caller := idempotencyCaller(r)
+		record, reserved, err := store.ReserveIdempotencyKey(r.Context(), IdempotencyRecord{
+			Caller:      caller,
+			Key:         key,
+			Fingerprint: requestFingerprint(r, body),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(idempotencyKeyLease),
+		}, now)
+		if err != nil {
+			writeProblem(w, r, codeInternal, "Error checking Idempotency-Key")
+			log.Printf("Idempotency key error: %v", err)
+			return
+		}
+
+		if !reserved {
+			replayIdempotent(w, r, record, requestFingerprint(r, body))
+			return
+		}
+
+		rec := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+
+		// A detached context, so a client hanging up doesn't leave the key
+		// reserved.
+		ctx := context.WithoutCancel(r.Context())
+		if rec.status >= 500 {
+			err = store.DeleteIdempotencyKey(ctx, caller, key)
+		} else {
+			record.Status = rec.status
+			record.ContentType = w.Header().Get("Content-Type")
+			record.Body = rec.body.Bytes()
+			record.ExpiresAt = time.Now().UTC().Truncate(time.Microsecond).Add(idempotencyKeyTTL)
+			err = store.CompleteIdempotencyKey(ctx, record)
+		}
+		if err != nil {
+			log.Printf("Idempotency key error: %v", err)
+		}
+
+		w.WriteHeader(rec.status)
+		w.Write(rec.body.Bytes())
+	}
+}
+
+// Answer a retry with the stored response, or explain why there is none.
+func replayIdempotent(w http.ResponseWriter, r *http.Request, record IdempotencyRecord, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		writeProblem(w, r, codeIdempotencyKeyReused, "Idempotency-Key was already used for a different request")
+		return
+	}
+	if record.Status == 0 {
+		w.Header().Set("Retry-After", "1")
+		writeProblem(w, r, codeIdempotencyKeyInProgress, "A request with this Idempotency-Key is still in progress")
+		return
+	}
+
+	w.Header().Set("Content-Type", record.ContentType)
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.Status)
+	w.Write(record.Body)
+}
+
+// Who made r, whose keys are theirs alone, so a client reusing another's
+// key doesn't get its response: the admin, by their actor, or the user
+// signed in, or on open routes the credential presented, hashed, or else
+// the address the request came from.
+func idempotencyCaller(r *http.Request) string {
+	if actor, ok := r.Context().Value(adminContextKey{}).(string); ok {
+		return actor
+	}
+	if user, ok := r.Context().Value(userContextKey{}).(User); ok {
+		return "user:" + strconv.Itoa(user.Id)
+	}
+	if credential, ok := adminCredential(r); ok && credential != "" {
+		return "credential:" + hashUserToken(credential)
+	}
+	return "client:" + hostOf(r.RemoteAddr)
+}
+
+// Hash what makes two requests the same. Legacy paths count as their
+// versioned equivalents.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+apiPath(r.URL.Path)+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Remove expired idempotency keys; the idempotency_prune job.
+func pruneIdempotencyKeys(ctx context.Context) error {
+	n, err := store.DeleteExpiredIdempotencyKeys(ctx, time.Now().UTC())
+	if n > 0 {
+		log.Printf("Pruned %d expired idempotency keys", n)
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Relationships of books that ?include= can add to a response.
+var bookIncludes = []string{"author", "reviews"}
+
+const (
+	// How many relationships an include path may follow. Nested paths
+	// such as reviews.book would only repeat what the response has.
+	maxIncludeDepth = 1
+	// How many related resources one response may include, and so how
+	// many books; bigger responses need pagination.
+	maxIncludedResources = 500
+)
+
+// Included holds the related resources of the books in a response.
+// Reviews name their book in book_id and books name their author, so
+// clients can match them up.
+type Included struct {
+	Authors []Author `json:"authors,omitempty" xml:"authors>author,omitempty"`
+	Reviews []Review `json:"reviews,omitempty" xml:"reviews>review,omitempty"`
+}
+
+var errTooManyIncluded = errors.New("too many related resources to include; request fewer books with page and per_page")
+
+// Parse the ?include= parameter into the relationships to include.
+// Returns nil if there are none.
+func requestedIncludes(r *http.Request) ([]string, error) {
+	var includes []string
+	for _, path := range strings.Split(r.URL.Query().Get("include"), ",") {
+		path = strings.TrimSpace(path)
+		if path == "" || slices.Contains(includes, path) {
+			continue
+		}
+		if strings.Count(path, ".")+1 > maxIncludeDepth {
+			return nil, errors.New("include paths can be at most " + strconv.Itoa(maxIncludeDepth) + " deep")
+		}
+		if !slices.Contains(bookIncludes, path) {
+			return nil, errors.New("unsupported include " + strconv.Quote(path))
+		}
+		includes = append(includes, path)
+	}
+	return includes, nil
+}
+
+// Load the related resources of books. Returns errTooManyIncluded rather
+// than more than maxIncludedResources of them.
+func loadIncluded(ctx context.Context, includes []string, books []Book) (*Included, error) {
+	if len(includes) == 0 {
+		return nil, nil
+	}
+	if len(books) > maxIncludedResources {
+		return nil, errTooManyIncluded
+	}
+
+	included := &Included{}
+	if slices.Contains(includes, "author") {
+		for _, author := range authorsOf(books) {
+			// Partial books may not have their author loaded.
+			if author.Name != "" {
+				included.Authors = append(included.Authors, author)
+			}
+		}
+	}
+
+	if slices.Contains(includes, "reviews") {
+		ids := make([]int, len(books))
+		for i, book := range books {
+			ids[i] = book.Id
+		}
+		limit := maxIncludedResources - len(included.Authors)
+		reviews, err := store.ListReviewsForBooks(ctx, ids, limit+1)
+		if err != nil {
+			return nil, err
+		}
+		if len(reviews) > limit {
+			return nil, errTooManyIncluded
+		}
+		included.Reviews = reviews
+	}
+	return included, nil
+}
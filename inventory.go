@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// BookStock is how many copies of a book the store has in stock, counting
+// those received against purchase orders.
+type BookStock struct {
+	BookId   int `json:"book_id" xml:"book_id"`
+	Quantity int `json:"quantity" xml:"quantity"`
+}
+
+type BookStockResponse struct {
+	Status  string    `json:"status" xml:"status"`
+	Message string    `json:"message" xml:"message"`
+	Data    BookStock `json:"data" xml:"data"`
+}
+
+// ReorderSuggestion is a book running low: how many copies to order to
+// bring it back up to the reorder level, from its cheapest supplier, and
+// what they cost.
+type ReorderSuggestion struct {
+	BookId int    `json:"book_id" xml:"book_id"`
+	Title  string `json:"title" xml:"title"`
+	Stock  int    `json:"stock" xml:"stock"`
+	// OnOrder counts the copies still to come on open purchase orders.
+	OnOrder  int           `json:"on_order" xml:"on_order"`
+	Quantity int           `json:"quantity" xml:"quantity"`
+	Supplier SupplierPrice `json:"supplier" xml:"supplier"`
+	Cost     float64       `json:"cost" xml:"cost"`
+}
+
+// ReorderReport is what to order to bring every book with a supplier back
+// up to the reorder level.
+type ReorderReport struct {
+	Currency    string              `json:"currency" xml:"currency"`
+	Level       int                 `json:"level" xml:"level"`
+	TotalCost   float64             `json:"total_cost" xml:"total_cost"`
+	Suggestions []ReorderSuggestion `json:"suggestions" xml:"suggestions>suggestion"`
+}
+
+type ReorderReportResponse struct {
+	Status  string        `json:"status" xml:"status"`
+	Message string        `json:"message" xml:"message"`
+	Data    ReorderReport `json:"data" xml:"data"`
+}
+
+func getBookStockHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+
+	quantity, err := store.GetBookStock(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching stock")
+		log.Printf("Stock query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, BookStockResponse{
+		Status:  "success",
+		Message: "Stock retrieved successfully",
+		Data:    BookStock{BookId: id, Quantity: quantity},
+	})
+}
+
+// Suggest what to reorder: the books some supplier has a price for with
+// fewer copies in stock and on order than the reorder level, in ?level= or
+// level by default, each from its cheapest supplier.
+func reorderSuggestionsHandler(level int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		level := level
+		if v := r.URL.Query().Get("level"); v != "" {
+			var err error
+			if level, err = strconv.Atoi(v); err != nil || level < 1 || level > 10000 {
+				writeProblem(w, r, codeInvalidParameter, "level must be a number from 1 to 10000")
+				return
+			}
+		}
+
+		prices, err := store.ListSupplierPrices(r.Context(), SupplierPriceQuery{})
+		cheapest := cheapestSuppliers(prices)
+		bookIds := make([]int, 0, len(cheapest))
+		for id := range cheapest {
+			bookIds = append(bookIds, id)
+		}
+		var stock map[int]int
+		var orders []PurchaseOrder
+		if err == nil {
+			stock, err = store.ListBookStock(r.Context(), bookIds)
+		}
+		if err == nil {
+			orders, err = store.ListPurchaseOrders(r.Context(), PurchaseOrderQuery{Status: OrderOpen})
+		}
+		if err != nil {
+			writeProblem(w, r, codeInternal, "Error fetching reorder suggestions")
+			log.Printf("Reorder query error: %v", err)
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, ReorderReportResponse{
+			Status:  "success",
+			Message: "Reorder suggestions retrieved successfully",
+			Data:    reorderReport(level, cheapest, stock, orders),
+		})
+	}
+}
+
+func reorderReport(level int, cheapest map[int]SupplierPrice, stock map[int]int, orders []PurchaseOrder) ReorderReport {
+	onOrder := map[int]int{}
+	for _, order := range orders {
+		for _, line := range order.Lines {
+			onOrder[line.BookId] += line.outstanding()
+		}
+	}
+
+	report := ReorderReport{Currency: currencies.base, Level: level, Suggestions: []ReorderSuggestion{}}
+	for bookId, price := range cheapest {
+		quantity, ok := stock[bookId]
+		if !ok {
+			continue
+		}
+		s := ReorderSuggestion{BookId: bookId, Title: price.Title, Stock: quantity, OnOrder: onOrder[bookId], Supplier: price}
+		if s.Quantity = level - s.Stock - s.OnOrder; s.Quantity <= 0 {
+			continue
+		}
+		s.Cost = roundCents(float64(s.Quantity) * price.UnitCost)
+		report.TotalCost = roundCents(report.TotalCost + s.Cost)
+		report.Suggestions = append(report.Suggestions, s)
+	}
+	sort.Slice(report.Suggestions, func(i, j int) bool {
+		a, b := report.Suggestions[i], report.Suggestions[j]
+		if a.Title != b.Title {
+			return a.Title < b.Title
+		}
+		return a.BookId < b.BookId
+	})
+	return report
+}
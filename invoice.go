@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/text/language"
+)
+
+// Where the columns of an invoice's lines are, in points from the left:
+// the titles start at the margin and the figures end at their column.
+const (
+	invoiceMargin   = 50
+	invoiceQuantity = 330
+	invoiceUnit     = 400
+	invoiceTaxRate  = 460
+	invoiceAmount   = pdfPageWidth - invoiceMargin
+	// Titles longer than this many characters are cut short to fit.
+	invoiceTitleLength = 44
+)
+
+// Answer with the invoice for a sale as a PDF, from seller, with its
+// labels in the language of Accept-Language.
+func saleInvoiceHandler(seller SellerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			writeProblem(w, r, codeInvalidParameter, "Invalid sale ID")
+			return
+		}
+
+		sale, err := store.GetSale(r.Context(), id)
+		if errors.Is(err, ErrNotFound) {
+			writeProblem(w, r, codeSaleNotFound, "Sale not found")
+			return
+		} else if err != nil {
+			writeProblem(w, r, codeInternal, "Error fetching sale")
+			log.Printf("Sale query error: %v", err)
+			return
+		}
+		sale.totalTaxes()
+
+		number := invoiceNumber(sale.InvoiceNumber)
+		body := renderInvoice(requestLanguage(r), seller, sale, currencies.base)
+		w.Header().Add("Vary", "Accept-Language")
+		w.Header().Set("Content-Type", mediaTypePDF)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="invoice-%s.pdf"`, number))
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
+
+// The invoice number as printed.
+func invoiceNumber(n int) string {
+	return fmt.Sprintf("%06d", n)
+}
+
+// Lay out the invoice for a sale: the seller and the invoice's number and
+// date, who it is made out to, a row for each line, running onto more
+// pages as needed, and the totals with the tax itemized by rule and what
+// points and a gift card paid.
+func renderInvoice(lang language.Tag, seller SellerConfig, sale Sale, currency string) []byte {
+	t := func(message string) string { return translate(lang, message) }
+	money := func(amount float64) string { return strconv.FormatFloat(amount, 'f', 2, 64) }
+	var doc pdfDocument
+	y := float64(pdfPageHeight - invoiceMargin)
+
+	doc.text(pdfBold, 14, invoiceMargin, y, seller.Name)
+	sellerY := y - 16
+	for _, line := range strings.Split(seller.Address, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			doc.text(pdfRegular, 10, invoiceMargin, sellerY, line)
+			sellerY -= 13
+		}
+	}
+	if seller.Email != "" {
+		doc.text(pdfRegular, 10, invoiceMargin, sellerY, seller.Email)
+		sellerY -= 13
+	}
+	if seller.TaxId != "" {
+		doc.text(pdfRegular, 10, invoiceMargin, sellerY, t("Tax ID")+": "+seller.TaxId)
+		sellerY -= 13
+	}
+
+	doc.text(pdfBold, 20, 380, y, t("Invoice"))
+	doc.text(pdfRegular, 10, 380, y-22, t("Invoice number"))
+	doc.text(pdfMono, 10, 470, y-22, invoiceNumber(sale.InvoiceNumber))
+	doc.text(pdfRegular, 10, 380, y-36, t("Date"))
+	doc.text(pdfMono, 10, 470, y-36, sale.SoldAt.Format(time.DateOnly))
+	y = min(sellerY, y-36) - 24
+
+	if sale.CustomerName != "" || sale.CustomerAddress != "" {
+		doc.text(pdfBold, 10, invoiceMargin, y, t("Bill to"))
+		y -= 13
+		for _, line := range append([]string{sale.CustomerName}, wrapText(sale.CustomerAddress, 70)...) {
+			if line != "" {
+				doc.text(pdfRegular, 10, invoiceMargin, y, line)
+				y -= 13
+			}
+		}
+		y -= 11
+	}
+
+	header := func() {
+		doc.text(pdfBold, 10, invoiceMargin, y, t("Title"))
+		doc.textRight(10, invoiceQuantity, y, t("Qty"))
+		doc.textRight(10, invoiceUnit, y, t("Unit price"))
+		doc.textRight(10, invoiceTaxRate, y, t("Tax"))
+		doc.textRight(10, invoiceAmount, y, t("Amount"))
+		doc.line(invoiceMargin, y-4, invoiceAmount, y-4)
+		y -= 18
+	}
+	header()
+	for _, line := range sale.Lines {
+		if y < 2*invoiceMargin {
+			doc.addPage()
+			y = pdfPageHeight - invoiceMargin
+			header()
+		}
+		title := line.Title
+		if runes := []rune(title); len(runes) > invoiceTitleLength {
+			title = string(runes[:invoiceTitleLength-1]) + "…"
+		}
+		doc.text(pdfRegular, 10, invoiceMargin, y, title)
+		doc.textRight(10, invoiceQuantity, y, strconv.Itoa(line.Quantity))
+		doc.textRight(10, invoiceUnit, y, money(line.UnitPrice))
+		doc.textRight(10, invoiceTaxRate, y, percent(line.TaxRate))
+		doc.textRight(10, invoiceAmount, y, money(line.Amount))
+		y -= 14
+	}
+
+	// The totals stay together, on a page of their own if need be.
+	totalLines := len(sale.Taxes) + 3
+	if sale.PointsDiscount > 0 {
+		totalLines++
+	}
+	if sale.GiftCardAmount > 0 {
+		totalLines++
+	}
+	if sale.AmountDue != sale.Total {
+		totalLines++
+	}
+	if y < 2*invoiceMargin+float64(totalLines)*14 {
+		doc.addPage()
+		y = pdfPageHeight - invoiceMargin
+	}
+	doc.line(invoiceMargin, y+6, invoiceAmount, y+6)
+	y -= 8
+	doc.text(pdfRegular, 10, 300, y, t("Subtotal"))
+	doc.textRight(10, invoiceAmount, y, money(sale.Subtotal))
+	for _, tax := range sale.Taxes {
+		y -= 14
+		doc.text(pdfRegular, 10, 300, y, fmt.Sprintf("%s %s (%s %s)", tax.Name, percent(tax.Rate), t("on"), money(tax.Taxable)))
+		doc.textRight(10, invoiceAmount, y, money(tax.Amount))
+	}
+	y -= 18
+	doc.text(pdfBold, 11, 300, y, t("Total")+" ("+currency+")")
+	doc.textRight(11, invoiceAmount, y, money(sale.Total))
+	if sale.PointsDiscount > 0 {
+		y -= 16
+		doc.text(pdfRegular, 10, 300, y, fmt.Sprintf("%s (%d)", t("Paid with points"), sale.PointsRedeemed))
+		doc.textRight(10, invoiceAmount, y, money(-sale.PointsDiscount))
+	}
+	if sale.GiftCardAmount > 0 {
+		y -= 16
+		doc.text(pdfRegular, 10, 300, y, t("Paid by gift card"))
+		doc.textRight(10, invoiceAmount, y, money(-sale.GiftCardAmount))
+	}
+	if sale.AmountDue != sale.Total {
+		y -= 16
+		doc.text(pdfBold, 11, 300, y, t("Amount due"))
+		doc.textRight(11, invoiceAmount, y, money(sale.AmountDue))
+	}
+	if sale.TaxIncluded {
+		y -= 24
+		doc.text(pdfRegular, 9, invoiceMargin, y, t("Prices include tax."))
+	}
+
+	return doc.bytes(t("Invoice") + " " + invoiceNumber(sale.InvoiceNumber))
+}
+
+// A rate as a percentage, like 7% or 8.875%.
+func percent(rate float64) string {
+	return strconv.FormatFloat(math.Round(rate*100000)/1000, 'f', -1, 64) + "%"
+}
+
+// Break s into lines of at most width characters at spaces, cutting
+// words longer than that.
+func wrapText(s string, width int) []string {
+	var lines []string
+	var line []rune
+	for _, word := range strings.Fields(s) {
+		w := []rune(word)
+		if len(line) > 0 && len(line)+1+len(w) > width {
+			lines = append(lines, string(line))
+			line = nil
+		}
+		for len(w) > width {
+			lines = append(lines, string(w[:width]))
+			w = w[width:]
+		}
+		if len(line) > 0 {
+			line = append(line, ' ')
+		}
+		line = append(line, w...)
+	}
+	if len(line) > 0 {
+		lines = append(lines, string(line))
+	}
+	return lines
+}
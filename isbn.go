@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// The ways an ISBN can be wrong, as problem details.
+var (
+	errISBNCharacters = errors.New("isbn must be digits, with hyphens or spaces between them and an X as the check digit of an ISBN-10")
+	errISBNLength     = errors.New("isbn must have 10 or 13 digits")
+	errISBNPrefix     = errors.New("isbn must start with 978 or 979 if it has 13 digits")
+	errISBNCheckDigit = errors.New("isbn has the wrong check digit")
+)
+
+// The ISBN-13 of isbn, an ISBN-10 or ISBN-13 that may have hyphens or
+// spaces, and whether its check digit is right. ISBN-13s are the EAN-13s
+// barcodes on books encode.
+func normalizeISBN(isbn string) (string, bool) {
+	isbn13, err := parseISBN(isbn)
+	return isbn13, err == nil
+}
+
+// The ISBN-13 of isbn, as normalizeISBN, or which way it is wrong.
+func parseISBN(isbn string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(isbn)))
+
+	switch len(digits) {
+	case 10:
+		sum := 0
+		for i, d := range digits {
+			switch {
+			case d >= '0' && d <= '9':
+				sum += int(d-'0') * (10 - i)
+			case d == 'X' && i == 9:
+				sum += 10
+			default:
+				return "", errISBNCharacters
+			}
+		}
+		if sum%11 != 0 {
+			return "", errISBNCheckDigit
+		}
+		// An ISBN-10 is the ISBN-13 without the 978 prefix, with a check
+		// digit of its own.
+		body := "978" + digits[:9]
+		return body + ean13CheckDigit(body), nil
+	case 13:
+		if strings.Trim(digits, "0123456789") != "" {
+			return "", errISBNCharacters
+		}
+		if !strings.HasPrefix(digits, "978") && !strings.HasPrefix(digits, "979") {
+			return "", errISBNPrefix
+		}
+		if ean13CheckDigit(digits[:12]) != digits[12:] {
+			return "", errISBNCheckDigit
+		}
+		return digits, nil
+	}
+	if strings.Trim(digits, "0123456789X") != "" {
+		return "", errISBNCharacters
+	}
+	return "", errISBNLength
+}
+
+// Report how the ISBN of a book a client sent is wrong, if it is; an
+// empty one, which leaves it out or unchanged, is fine. Books are stored
+// with their ISBN-13 as normalize leaves it, so only the ISBNs it
+// couldn't normalize fail.
+func (b Book) checkISBN() error {
+	if b.ISBN == "" {
+		return nil
+	}
+	_, err := parseISBN(b.ISBN)
+	return err
+}
+
+// The ISBN-10 of isbn13, a normalized ISBN-13, or "" if it has none: only
+// 978 ISBNs were ever ISBN-10s.
+func isbn10(isbn13 string) string {
+	if !strings.HasPrefix(isbn13, "978") {
+		return ""
+	}
+	body := isbn13[3:12]
+	sum := 0
+	for i, d := range body {
+		sum += int(d-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return body + "X"
+	}
+	return body + strconv.Itoa(check)
+}
+
+// The check digit of the first 12 digits of an EAN-13.
+func ean13CheckDigit(digits string) string {
+	sum := 0
+	for i, d := range digits {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(d-'0') * weight
+	}
+	return strconv.Itoa((10 - sum%10) % 10)
+}
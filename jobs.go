@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/robfig/cron/v3"
+)
+
+// job is background work run on a cron schedule, in UTC.
+type job struct {
+	name string
+	// schedule is the default cron expression; job_schedules overrides
+	// it. A job without one only runs when an admin starts it.
+	schedule string
+	// enabled is false for jobs the configuration has no use for, such
+	// as backups without a backup storage.
+	enabled bool
+	run     func(ctx context.Context) error
+}
+
+// The jobs, all registered here. Each takes what it needs from cfg and
+// the services main set up before the scheduler starts.
+func registeredJobs(cfg Config) []job {
+	return []job{
+		{
+			name:     "backup",
+			schedule: cfg.BackupSchedule,
+			enabled:  backups != nil,
+			run:      perTenant(runBackupJob),
+		},
+		{
+			name:     "idempotency_prune",
+			schedule: "@hourly",
+			enabled:  true,
+			run:      pruneIdempotencyKeys,
+		},
+		{
+			name:     "exchange_rates",
+			schedule: "@every " + cfg.ExchangeRatesRefresh.String(),
+			enabled:  currencies.provider != nil,
+			run:      currencies.refresh,
+		},
+		{
+			name:     "secrets_refresh",
+			schedule: "@every " + cfg.Secrets.Refresh.String(),
+			enabled:  secrets.provider != nil,
+			run:      refreshSecrets,
+		},
+		{
+			name:     "recommendations",
+			schedule: "@hourly",
+			enabled:  true,
+			run:      perTenant(refreshRecommendations),
+		},
+		{
+			name:     "activity_flush",
+			schedule: "@every 1m",
+			enabled:  true,
+			run:      activity.flush,
+		},
+		{
+			name:     "search_flush",
+			schedule: "@every 10s",
+			enabled:  true,
+			run:      searchLog.flush,
+		},
+		{
+			name:     "loyalty_expiry",
+			schedule: "@hourly",
+			enabled:  cfg.LoyaltyPointsExpiry.Duration > 0,
+			run:      perTenant(expireLoyaltyPoints),
+		},
+		{
+			name:     "wishlist_price_alerts",
+			schedule: "@hourly",
+			enabled:  len(notificationChannels) > 0,
+			run:      perTenant(alertWishlistPrices),
+		},
+		{
+			name:     "saved_search_alerts",
+			schedule: "@hourly",
+			enabled:  len(notificationChannels) > 0,
+			run:      perTenant(alertSavedSearches),
+		},
+		{
+			name:    "metadata_enrichment",
+			enabled: bookMetadata != nil,
+			run:     perTenant(enrichBooks),
+		},
+		{
+			name:     "retention_purge",
+			schedule: "@daily",
+			enabled:  cfg.Retention.enabled(),
+			run:      purgeExpiredLogs(cfg.Retention),
+		},
+		{
+			name:     "book_archival",
+			schedule: "@daily",
+			enabled:  cfg.ArchiveAfterYears > 0,
+			run:      perTenant(archiveStaleBooks(cfg.ArchiveAfterYears)),
+		},
+		{
+			name:     "cache_warmup",
+			schedule: "@every " + cfg.CacheTTL.String(),
+			enabled:  cfg.Cache != "" && cfg.Cache != "none",
+			run:      perTenant(warmCache),
+		},
+	}
+}
+
+// Run fn for every tenant in turn, for jobs that work on one tenant's
+// data at a time.
+func perTenant(fn func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return forEachTenant(ctx, fn)
+	}
+}
+
+// Refill the read cache with the book list, the read most often, so the
+// first client after it expires doesn't wait on the database; the
+// cache_warmup job.
+func warmCache(ctx context.Context) error {
+	_, err := store.ListBooks(ctx)
+	return err
+}
+
+// JobRun is the outcome of one run of a job.
+type JobRun struct {
+	StartedAt  time.Time `json:"started_at" xml:"started_at"`
+	DurationMs int64     `json:"duration_ms" xml:"duration_ms"`
+	Succeeded  bool      `json:"succeeded" xml:"succeeded"`
+	Error      string    `json:"error,omitempty" xml:"error,omitempty"`
+	// Progress is how far the run got, for jobs that report it.
+	Progress *JobProgress `json:"progress,omitempty" xml:"progress,omitempty"`
+}
+
+// JobProgress counts the items a job run has to work through and has
+// worked through so far, for jobs working through many.
+type JobProgress struct {
+	Total int `json:"total" xml:"total"`
+	Done  int `json:"done" xml:"done"`
+	// Updated and Failed count the items done that the run changed and
+	// that it couldn't work on.
+	Updated int `json:"updated" xml:"updated"`
+	Failed  int `json:"failed" xml:"failed"`
+}
+
+// JobStatus describes a job and how its last run went.
+type JobStatus struct {
+	Name     string `json:"name" xml:"name"`
+	Schedule string `json:"schedule" xml:"schedule"`
+	Enabled  bool   `json:"enabled" xml:"enabled"`
+	Running  bool   `json:"running" xml:"running"`
+	// Progress is how far the running job has got, if it reports that.
+	Progress *JobProgress `json:"progress,omitempty" xml:"progress,omitempty"`
+	// LastRun is nil until the job has run since the server started.
+	LastRun *JobRun `json:"last_run,omitempty" xml:"last_run,omitempty"`
+	// NextRun is nil for jobs without a schedule.
+	NextRun *time.Time `json:"next_run,omitempty" xml:"next_run,omitempty"`
+}
+
+type JobsResponse struct {
+	Status  string      `json:"status" xml:"status"`
+	Message string      `json:"message" xml:"message"`
+	Data    []JobStatus `json:"data" xml:"data>job"`
+}
+
+type JobResponse struct {
+	Status  string    `json:"status" xml:"status"`
+	Message string    `json:"message" xml:"message"`
+	Data    JobStatus `json:"data" xml:"data"`
+}
+
+// scheduledJob is a job and the state of its runs.
+type scheduledJob struct {
+	job
+	entry cron.EntryID
+
+	mu       sync.Mutex
+	running  bool
+	progress *JobProgress
+	lastRun  *JobRun
+}
+
+// Run the job unless its previous run is still going.
+func (j *scheduledJob) Run() {
+	if !j.begin() {
+		log.Printf("Job %s skipped, its last run hasn't finished", j.name)
+		return
+	}
+	j.execute()
+}
+
+// Mark the job running, or report false if it already is.
+func (j *scheduledJob) begin() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.running {
+		return false
+	}
+	j.running, j.progress = true, nil
+	return true
+}
+
+// Run the job begin marked running and record how it went.
+func (j *scheduledJob) execute() {
+	started := time.Now()
+	err := j.run(context.WithValue(context.Background(), runningJobKey{}, j))
+	run := &JobRun{
+		StartedAt:  started.UTC().Truncate(time.Millisecond),
+		DurationMs: time.Since(started).Milliseconds(),
+		Succeeded:  err == nil,
+	}
+	if err != nil {
+		run.Error = err.Error()
+		log.Printf("Job %s error: %v", j.name, err)
+	}
+
+	j.mu.Lock()
+	run.Progress = j.progress
+	j.running, j.progress, j.lastRun = false, nil, run
+	j.mu.Unlock()
+}
+
+type runningJobKey struct{}
+
+// Update the progress of the job run ctx belongs to, if any, with
+// update; jobs call it as they work through their items.
+func reportProgress(ctx context.Context, update func(progress *JobProgress)) {
+	j, ok := ctx.Value(runningJobKey{}).(*scheduledJob)
+	if !ok {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.progress == nil {
+		j.progress = &JobProgress{}
+	}
+	update(j.progress)
+}
+
+// scheduler runs the enabled jobs.
+type scheduler struct {
+	cron *cron.Cron
+	jobs []*scheduledJob
+}
+
+var jobScheduler *scheduler
+
+// Schedule the registered jobs as configured and start running them.
+// Unknown job names and invalid schedules in the config are errors.
+func startJobs(cfg Config) error {
+	s := &scheduler{cron: cron.New(cron.WithLocation(time.UTC))}
+	var names []string
+	for _, j := range registeredJobs(cfg) {
+		names = append(names, j.name)
+		if schedule, ok := cfg.JobSchedules[j.name]; ok {
+			j.schedule = schedule
+		}
+		if slices.Contains(cfg.JobsDisabled, j.name) {
+			j.enabled = false
+		}
+
+		sj := &scheduledJob{job: j}
+		if j.enabled && j.schedule != "" {
+			var err error
+			if sj.entry, err = s.cron.AddJob(j.schedule, sj); err != nil {
+				return fmt.Errorf("job %s: schedule %q: %w", j.name, j.schedule, err)
+			}
+		}
+		s.jobs = append(s.jobs, sj)
+	}
+
+	for name := range cfg.JobSchedules {
+		if !slices.Contains(names, name) {
+			return fmt.Errorf("job_schedules: unknown job %q", name)
+		}
+	}
+	for _, name := range cfg.JobsDisabled {
+		if !slices.Contains(names, name) {
+			return fmt.Errorf("jobs_disabled: unknown job %q", name)
+		}
+	}
+
+	s.cron.Start()
+	jobScheduler = s
+	return nil
+}
+
+// The status of every registered job, in registration order.
+func (s *scheduler) status() []JobStatus {
+	statuses := make([]JobStatus, len(s.jobs))
+	for i, j := range s.jobs {
+		statuses[i] = s.jobStatus(j)
+	}
+	return statuses
+}
+
+func (s *scheduler) jobStatus(j *scheduledJob) JobStatus {
+	j.mu.Lock()
+	status := JobStatus{
+		Name:     j.name,
+		Schedule: j.schedule,
+		Enabled:  j.enabled,
+		Running:  j.running,
+		LastRun:  j.lastRun,
+	}
+	if j.progress != nil {
+		progress := *j.progress
+		status.Progress = &progress
+	}
+	j.mu.Unlock()
+
+	if j.entry != 0 {
+		next := s.cron.Entry(j.entry).Next
+		status.NextRun = &next
+	}
+	return status
+}
+
+// The registered job named name, or nil.
+func (s *scheduler) job(name string) *scheduledJob {
+	for _, j := range s.jobs {
+		if j.name == name {
+			return j
+		}
+	}
+	return nil
+}
+
+func listJobsHandler(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, r, http.StatusOK, JobsResponse{
+		Status:  "success",
+		Message: "Jobs retrieved successfully",
+		Data:    jobScheduler.status(),
+	})
+}
+
+func getJobHandler(w http.ResponseWriter, r *http.Request) {
+	j := jobScheduler.job(mux.Vars(r)["name"])
+	if j == nil {
+		writeProblem(w, r, codeJobNotFound, "Job not found")
+		return
+	}
+	writeResponse(w, r, http.StatusOK, JobResponse{
+		Status:  "success",
+		Message: "Job retrieved successfully",
+		Data:    jobScheduler.jobStatus(j),
+	})
+}
+
+// Start a run of an enabled job now, in the background, whether or not
+// it has a schedule; its status shows how it goes.
+func runJobHandler(w http.ResponseWriter, r *http.Request) {
+	j := jobScheduler.job(mux.Vars(r)["name"])
+	if j == nil {
+		writeProblem(w, r, codeJobNotFound, "Job not found")
+		return
+	}
+	if !j.enabled {
+		writeProblem(w, r, codeJobDisabled, "The job is disabled")
+		return
+	}
+	if !j.begin() {
+		writeProblem(w, r, codeJobRunning, "The job is already running")
+		return
+	}
+	go j.execute()
+
+	writeResponse(w, r, http.StatusAccepted, JobResponse{
+		Status:  "success",
+		Message: "Job started successfully",
+		Data:    jobScheduler.jobStatus(j),
+	})
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// jsonAPIResource is implemented by the types served as JSON:API
+// resources. Their JSON fields other than id become the attributes.
+type jsonAPIResource interface {
+	jsonAPIType() string
+}
+
+// jsonAPIRelated is implemented by resources that refer to others. Each
+// relationship replaces the attribute named after it with an _id suffix.
+type jsonAPIRelated interface {
+	jsonAPIRelationships() map[string]jsonAPIIdentifier
+}
+
+func (Book) jsonAPIType() string            { return "books" }
+func (Review) jsonAPIType() string          { return "reviews" }
+func (Webhook) jsonAPIType() string         { return "webhooks" }
+func (WebhookDelivery) jsonAPIType() string { return "webhook-deliveries" }
+func (Author) jsonAPIType() string          { return "authors" }
+
+// Authors have no numeric id; their name identifies them.
+func (a Author) jsonAPIId() string { return a.Name }
+
+func (r Review) jsonAPIRelationships() map[string]jsonAPIIdentifier {
+	return map[string]jsonAPIIdentifier{"book": {Type: "books", Id: strconv.Itoa(r.BookId)}}
+}
+
+func (d WebhookDelivery) jsonAPIRelationships() map[string]jsonAPIIdentifier {
+	return map[string]jsonAPIIdentifier{"webhook": {Type: "webhooks", Id: strconv.Itoa(d.WebhookId)}}
+}
+
+type jsonAPIIdentifier struct {
+	Type string `json:"type"`
+	Id   string `json:"id"`
+}
+
+type jsonAPIResourceObject struct {
+	Type          string         `json:"type"`
+	Id            string         `json:"id,omitempty"`
+	Attributes    map[string]any `json:"attributes,omitempty"`
+	Relationships map[string]any `json:"relationships,omitempty"`
+}
+
+type jsonAPIDoc struct {
+	Data     any                     `json:"data,omitempty"`
+	Included []jsonAPIResourceObject `json:"included,omitempty"`
+	Meta     map[string]any          `json:"meta,omitempty"`
+	Links    map[string]jsonAPILink  `json:"links,omitempty"`
+	JSONAPI  map[string]string       `json:"jsonapi"`
+}
+
+// jsonAPILink is a link object; the method, where there is one, goes in
+// its meta.
+type jsonAPILink struct {
+	Href string            `json:"href"`
+	Meta map[string]string `json:"meta,omitempty"`
+}
+
+type jsonAPIError struct {
+	Status string         `json:"status"`
+	Code   string         `json:"code,omitempty"`
+	Title  string         `json:"title"`
+	Detail string         `json:"detail,omitempty"`
+	Source *jsonAPISource `json:"source,omitempty"`
+	Meta   map[string]any `json:"meta,omitempty"`
+}
+
+type jsonAPISource struct {
+	Pointer string `json:"pointer"`
+}
+
+type jsonAPIErrorDocument struct {
+	Errors  []jsonAPIError    `json:"errors"`
+	JSONAPI map[string]string `json:"jsonapi"`
+	status  int
+}
+
+var jsonAPIVersion = map[string]string{"version": "1.1"}
+
+// The error objects for a problem: one per violation for validation
+// problems, pointing at the attribute that broke the rule, with the rule
+// under meta, and one for the problem otherwise.
+func jsonAPIProblem(problem Problem) jsonAPIErrorDocument {
+	doc := jsonAPIErrorDocument{JSONAPI: jsonAPIVersion, status: problem.Status}
+	status := strconv.Itoa(problem.Status)
+	for _, err := range problem.Errors {
+		doc.Errors = append(doc.Errors, jsonAPIError{
+			Status: status,
+			Code:   problem.Code,
+			Title:  problem.Title,
+			Detail: err.Message,
+			Source: &jsonAPISource{Pointer: "/data/attributes/" + err.Field},
+			Meta:   map[string]any{"rule": err.Rule},
+		})
+	}
+	if len(doc.Errors) == 0 {
+		doc.Errors = []jsonAPIError{{Status: status, Code: problem.Code, Title: problem.Title, Detail: problem.Detail}}
+	}
+	return doc
+}
+
+// Build the JSON:API document for v, one of the *Response types: its Data
+// becomes the primary data and its Message the meta. Its Included
+// resources become the included ones and the books' relationships to
+// them. Problems become error documents.
+func jsonAPIDocument(status int, v any) (any, error) {
+	if problem, ok := v.(Problem); ok {
+		return jsonAPIProblem(problem), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	message := rv.FieldByName("Message").String()
+
+	doc := jsonAPIDoc{JSONAPI: jsonAPIVersion}
+	if message != "" {
+		doc.Meta = map[string]any{"message": message}
+	}
+	if field := rv.FieldByName("Links"); field.IsValid() && field.Len() > 0 {
+		links := field.Interface().(Links)
+		doc.Links = map[string]jsonAPILink{}
+		for name, link := range links {
+			object := jsonAPILink{Href: link.Href}
+			if link.Method != "" {
+				object.Meta = map[string]string{"method": link.Method}
+			}
+			doc.Links[name] = object
+		}
+	}
+
+	data := rv.FieldByName("Data")
+	if !data.IsValid() {
+		return doc, nil
+	}
+
+	if data.Kind() == reflect.Slice {
+		objects := []jsonAPIResourceObject{}
+		for i := 0; i < data.Len(); i++ {
+			object, err := jsonAPIObject(data.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, object)
+		}
+		doc.Data = objects
+	} else {
+		object, err := jsonAPIObject(data.Interface())
+		if err != nil {
+			return nil, err
+		}
+		doc.Data = object
+	}
+
+	if field := rv.FieldByName("Included"); field.IsValid() && !field.IsNil() {
+		if err := includeRelated(&doc, field.Interface().(*Included)); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// Turn a resource into its resource object.
+func jsonAPIObject(v any) (jsonAPIResourceObject, error) {
+	resource, ok := v.(jsonAPIResource)
+	if !ok {
+		return jsonAPIResourceObject{}, fmt.Errorf("%T is not a JSON:API resource", v)
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return jsonAPIResourceObject{}, err
+	}
+	var attributes map[string]any
+	if err := json.Unmarshal(raw, &attributes); err != nil {
+		return jsonAPIResourceObject{}, err
+	}
+
+	object := jsonAPIResourceObject{Type: resource.jsonAPIType(), Attributes: attributes}
+	if identified, ok := v.(interface{ jsonAPIId() string }); ok {
+		object.Id = identified.jsonAPIId()
+	} else if id, ok := attributes["id"].(float64); ok {
+		object.Id = strconv.FormatInt(int64(id), 10)
+	}
+	delete(attributes, "id")
+
+	if related, ok := v.(jsonAPIRelated); ok {
+		object.Relationships = map[string]any{}
+		for name, identifier := range related.jsonAPIRelationships() {
+			object.Relationships[name] = map[string]any{"data": identifier}
+			delete(attributes, name+"_id")
+		}
+	}
+	return object, nil
+}
+
+// Add the included resources to doc, and the book resources' relationships
+// to them.
+func includeRelated(doc *jsonAPIDoc, included *Included) error {
+	var books []*jsonAPIResourceObject
+	switch data := doc.Data.(type) {
+	case jsonAPIResourceObject:
+		books = append(books, &data)
+		defer func() { doc.Data = data }()
+	case []jsonAPIResourceObject:
+		for i := range data {
+			books = append(books, &data[i])
+		}
+	}
+
+	reviews := map[string][]jsonAPIIdentifier{}
+	for _, review := range included.Reviews {
+		object, err := jsonAPIObject(review)
+		if err != nil {
+			return err
+		}
+		bookId := strconv.Itoa(review.BookId)
+		reviews[bookId] = append(reviews[bookId], jsonAPIIdentifier{Type: object.Type, Id: object.Id})
+		doc.Included = append(doc.Included, object)
+	}
+	for _, author := range included.Authors {
+		object, err := jsonAPIObject(author)
+		if err != nil {
+			return err
+		}
+		doc.Included = append(doc.Included, object)
+	}
+
+	for _, book := range books {
+		if book.Type != "books" || book.Id == "" {
+			continue
+		}
+		if book.Relationships == nil {
+			book.Relationships = map[string]any{}
+		}
+		if included.Reviews != nil {
+			identifiers := reviews[book.Id]
+			if identifiers == nil {
+				identifiers = []jsonAPIIdentifier{}
+			}
+			book.Relationships["reviews"] = map[string]any{"data": identifiers}
+		}
+		if name, ok := book.Attributes["author"].(string); ok && included.Authors != nil {
+			book.Relationships["author"] = map[string]any{"data": jsonAPIIdentifier{Type: "authors", Id: name}}
+		}
+	}
+	return nil
+}
+
+// Decode a JSON:API document with a single resource object into v, from
+// the object's attributes.
+func decodeJSONAPI(body io.Reader, v any) error {
+	var doc struct {
+		Data *struct {
+			Type       string          `json:"type"`
+			Attributes json.RawMessage `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(body).Decode(&doc); err != nil {
+		return err
+	}
+	if doc.Data == nil || doc.Data.Type == "" {
+		return errors.New("a resource object with a type is required")
+	}
+	if resource, ok := v.(jsonAPIResource); ok && resource.jsonAPIType() != doc.Data.Type {
+		return fmt.Errorf("resource type %q is not %q", doc.Data.Type, resource.jsonAPIType())
+	}
+	if len(doc.Data.Attributes) == 0 {
+		return nil
+	}
+	return json.Unmarshal(doc.Data.Attributes, v)
+}
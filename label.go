@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/png"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/ean"
+	"github.com/boombuler/barcode/qr"
+	"github.com/gorilla/mux"
+)
+
+const (
+	mediaTypePNG = "image/png"
+	mediaTypeSVG = "image/svg+xml"
+)
+
+// Label sizes in pixels: the width of barcodes, whose height is a third of
+// it, and the side of QR codes.
+const (
+	defaultLabelSize = 300
+	maxLabelSize     = 2000
+)
+
+// labelHandler serves a shelf label for a book, drawn by encode from the
+// value it asks for: the book's ISBN (the default) or its id. ?format
+// picks PNG or SVG, otherwise chosen by Accept, and ?size the label's size.
+func labelHandler(encode func(value string, isbn bool, size int) (barcode.Barcode, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+			return
+		}
+		query := r.URL.Query()
+		format := query.Get("format")
+		switch format {
+		case "":
+			format = negotiateMediaType(r.Header.Get("Accept"), []string{mediaTypePNG, mediaTypeSVG})
+		case "png":
+			format = mediaTypePNG
+		case "svg":
+			format = mediaTypeSVG
+		default:
+			writeProblem(w, r, codeInvalidParameter, "format must be png or svg")
+			return
+		}
+		which := query.Get("value")
+		if which != "" && which != "isbn" && which != "id" {
+			writeProblem(w, r, codeInvalidParameter, "value must be isbn or id")
+			return
+		}
+		size, ok := intParameter(r, "size", defaultLabelSize, maxLabelSize)
+		if !ok {
+			writeProblem(w, r, codeInvalidParameter, "size must be between 1 and "+strconv.Itoa(maxLabelSize))
+			return
+		}
+
+		book, err := store.GetBook(r.Context(), id)
+		if errors.Is(err, ErrNotFound) {
+			writeProblem(w, r, codeBookNotFound, "Book not found")
+			return
+		} else if err != nil {
+			writeProblem(w, r, codeInternal, "Error fetching book from database")
+			log.Printf("Database query error: %v", err)
+			return
+		}
+
+		value, isbn := strconv.Itoa(book.Id), which != "id"
+		if isbn {
+			if book.ISBN == "" {
+				writeProblem(w, r, codeBookHasNoISBN, "Book has no ISBN")
+				return
+			}
+			value, _ = normalizeISBN(book.ISBN)
+		}
+		code, err := encode(value, isbn, size)
+		if err != nil {
+			writeProblem(w, r, codeInvalidParameter, "size is too small for the label")
+			return
+		}
+
+		var body bytes.Buffer
+		if format == mediaTypeSVG {
+			writeSVG(&body, code)
+		} else if err := png.Encode(&body, code); err != nil {
+			writeProblem(w, r, codeInternal, "Error drawing label")
+			log.Printf("Label error: %v", err)
+			return
+		}
+		w.Header().Add("Vary", "Accept")
+		w.Header().Set("Content-Type", format)
+		w.WriteHeader(http.StatusOK)
+		w.Write(body.Bytes())
+	}
+}
+
+// A barcode of the book's ISBN as an EAN-13, or of its id as Code 128.
+func encodeBarcode(value string, isbn bool, size int) (barcode.Barcode, error) {
+	var code barcode.Barcode
+	var err error
+	if isbn {
+		code, err = ean.Encode(value)
+	} else {
+		code, err = code128.Encode(value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return barcode.Scale(code, size, max(size/3, 1))
+}
+
+// A QR code of the book's ISBN or id.
+func encodeQRCode(value string, isbn bool, size int) (barcode.Barcode, error) {
+	code, err := qr.Encode(value, qr.M, qr.Auto)
+	if err != nil {
+		return nil, err
+	}
+	return barcode.Scale(code, size, size)
+}
+
+// Write code as SVG: a white background with a black rectangle for each
+// run of dark pixels, as tall as the rows that repeat it, which keeps
+// the document small.
+func writeSVG(buf *bytes.Buffer, code barcode.Barcode) {
+	bounds := code.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %[1]d %[2]d" shape-rendering="crispEdges">`, width, height)
+	fmt.Fprintf(buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, width, height)
+	for y := 0; y < height; {
+		runs := darkRuns(code, y)
+		rows := 1
+		for y+rows < height && slices.Equal(darkRuns(code, y+rows), runs) {
+			rows++
+		}
+		for i := 0; i < len(runs); i += 2 {
+			fmt.Fprintf(buf, "M%d %dh%dv%dh-%[3]dz", runs[i], y, runs[i+1]-runs[i], rows)
+		}
+		y += rows
+	}
+	buf.WriteString(`"/></svg>`)
+}
+
+// The starts and ends of the runs of dark pixels in row y of code.
+func darkRuns(code barcode.Barcode, y int) []int {
+	bounds := code.Bounds()
+	var runs []int
+	inRun := false
+	for x := 0; x < bounds.Dx(); x++ {
+		r, g, b, _ := code.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+		if dark := r+g+b < 3*0x8000; dark != inRun {
+			runs = append(runs, x)
+			inRun = dark
+		}
+	}
+	if inRun {
+		runs = append(runs, bounds.Dx())
+	}
+	return runs
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Link points a client at a related request, so it can follow links
+// instead of building URLs.
+type Link struct {
+	Href   string `json:"href"`
+	Method string `json:"method,omitempty"`
+}
+
+// Links are keyed by relation, such as "self" or "next".
+type Links map[string]Link
+
+// In XML each link is a <link> element with the relation in its rel
+// attribute, in relation order.
+func (links Links) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type xmlLink struct {
+		Rel    string `xml:"rel,attr"`
+		Href   string `xml:"href,attr"`
+		Method string `xml:"method,attr,omitempty"`
+	}
+	var list struct {
+		Links []xmlLink `xml:"link"`
+	}
+	for _, rel := range slices.Sorted(maps.Keys(links)) {
+		list.Links = append(list.Links, xmlLink{Rel: rel, Href: links[rel].Href, Method: links[rel].Method})
+	}
+	return e.EncodeElement(list, start)
+}
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// The scheme and host r was made to, for the absolute URLs feeds and
+// sitemaps need. Behind a TLS-terminating proxy, X-Forwarded-Proto tells.
+func requestBaseURL(r *http.Request) string {
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		return "https://" + r.Host
+	}
+	return "http://" + r.Host
+}
+
+// Links for acting on a book and finding related books.
+func bookLinks(book Book) Links {
+	self := apiPrefix + "/book/" + strconv.Itoa(book.Id)
+	links := Links{
+		"self":       {Href: self, Method: http.MethodGet},
+		"update":     {Href: self, Method: http.MethodPut},
+		"delete":     {Href: self, Method: http.MethodDelete},
+		"collection": {Href: apiPrefix + "/books", Method: http.MethodGet},
+	}
+	// A partial book may not have its author loaded.
+	if book.Author != "" {
+		links["author"] = Link{Href: apiPrefix + "/books/search?q=" + url.QueryEscape(book.Author), Method: http.MethodGet}
+	}
+	return links
+}
+
+// Return the page of books asked for by the page and per_page parameters,
+// with its links. Without either parameter all books are one
+// page and only the self link is returned.
+func paginate(r *http.Request, books []Book) ([]Book, Links, error) {
+	query := r.URL.Query()
+	self := apiPath(r.URL.Path)
+	links := Links{"self": {Href: withQuery(self, query)}}
+
+	if !query.Has("page") && !query.Has("per_page") {
+		return books, links, nil
+	}
+
+	page, perPage := 1, defaultPerPage
+	var err error
+	if v := query.Get("page"); v != "" {
+		if page, err = strconv.Atoi(v); err != nil || page < 1 {
+			return nil, nil, errors.New("page must be a positive integer")
+		}
+	}
+	if v := query.Get("per_page"); v != "" {
+		if perPage, err = strconv.Atoi(v); err != nil || perPage < 1 || perPage > maxPerPage {
+			return nil, nil, errors.New("per_page must be between 1 and " + strconv.Itoa(maxPerPage))
+		}
+	}
+
+	lastPage := max(1, (len(books)+perPage-1)/perPage)
+	pageLink := func(n int) Link {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		q.Set("page", strconv.Itoa(n))
+		q.Set("per_page", strconv.Itoa(perPage))
+		return Link{Href: withQuery(self, q)}
+	}
+
+	links["first"] = pageLink(1)
+	links["last"] = pageLink(lastPage)
+	if page > 1 {
+		links["prev"] = pageLink(min(page-1, lastPage))
+	}
+	if page < lastPage {
+		links["next"] = pageLink(page + 1)
+	}
+
+	start := min((page-1)*perPage, len(books))
+	end := min(start+perPage, len(books))
+	return books[start:end], links, nil
+}
+
+// The path under the current API version, also for requests made to a
+// legacy unversioned path.
+func apiPath(path string) string {
+	if strings.HasPrefix(path, apiPrefix+"/") {
+		return path
+	}
+	return apiPrefix + path
+}
+
+func withQuery(path string, query url.Values) string {
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Loan is a book the library lent to one of its members. It is out until
+// it is returned.
+type Loan struct {
+	Id       int `json:"id" xml:"id"`
+	MemberId int `json:"member_id" xml:"member_id" validate:"required"`
+	BookId   int `json:"book_id" xml:"book_id" validate:"required"`
+	// CopyId is the copy lent, for loans made at the circulation desk.
+	CopyId *int `json:"copy_id,omitempty" xml:"copy_id,omitempty"`
+	// BorrowedAt is when the loan was recorded.
+	BorrowedAt time.Time `json:"borrowed_at" xml:"borrowed_at"`
+	// DueAt is loanPeriod after BorrowedAt unless given.
+	DueAt      time.Time  `json:"due_at" xml:"due_at"`
+	ReturnedAt *time.Time `json:"returned_at,omitempty" xml:"returned_at,omitempty"`
+	// Book is loaded by ListMemberLoans.
+	Book *Book `json:"book,omitempty" xml:"book,omitempty"`
+	// Fine is the fine charged when the loan was returned late, set on
+	// return.
+	Fine *Fine `json:"fine,omitempty" xml:"fine,omitempty"`
+	// CreatedAt is BorrowedAt, and UpdatedAt ReturnedAt once returned.
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+}
+
+func (l Loan) timestamps() (created, updated time.Time) {
+	return l.CreatedAt, l.UpdatedAt
+}
+
+// LoanStats sums up a member's loans.
+type LoanStats struct {
+	Borrows int `json:"borrows" xml:"borrows"`
+	// OnLoan are the loans not yet returned, and Overdue those of them
+	// past their due date.
+	OnLoan  int `json:"on_loan" xml:"on_loan"`
+	Overdue int `json:"overdue" xml:"overdue"`
+	// AverageLoanDays is how long the member kept the books they returned,
+	// in days; it is missing until they return one.
+	AverageLoanDays *float64 `json:"average_loan_days,omitempty" xml:"average_loan_days,omitempty"`
+	// FavoriteGenres are the categories of the books borrowed most, most
+	// first. A book counts in each of its categories.
+	FavoriteGenres []GenreCount `json:"favorite_genres" xml:"favorite_genres>genre"`
+}
+
+type GenreCount struct {
+	Genre   string `json:"genre" xml:"genre"`
+	Borrows int    `json:"borrows" xml:"borrows"`
+}
+
+// LoanHistory is every loan of a member, newest first, and their stats.
+type LoanHistory struct {
+	MemberId int       `json:"member_id" xml:"member_id"`
+	Stats    LoanStats `json:"stats" xml:"stats"`
+	Loans    []Loan    `json:"loans" xml:"loans>loan"`
+}
+
+type LoanResponse struct {
+	Status  string `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
+	Data    Loan   `json:"data" xml:"data"`
+}
+
+type LoanHistoryResponse struct {
+	Status  string      `json:"status" xml:"status"`
+	Message string      `json:"message" xml:"message"`
+	Data    LoanHistory `json:"data" xml:"data"`
+}
+
+// How long books are lent for unless a loan says otherwise.
+const loanPeriod = 21 * 24 * time.Hour
+
+// How many genres LoanStats.FavoriteGenres lists at most.
+const maxFavoriteGenres = 5
+
+var (
+	errLoanMemberMissing = errors.New("member not found")
+	errLoanReturned      = errors.New("loan already returned")
+)
+
+// Lend a book to a member, due back in three weeks unless the request
+// gives due_at.
+func createLoanHandler(w http.ResponseWriter, r *http.Request) {
+	var loan Loan
+	if err := decodeRequest(r, &loan); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	errs := validateRequest(loan)
+	if !loan.DueAt.IsZero() && !loan.DueAt.After(now) {
+		errs = append(errs, newFieldError("due_at", "gt", "must be in the future"))
+	}
+	if errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	dueAt := now.Add(loanPeriod)
+	if !loan.DueAt.IsZero() {
+		dueAt = loan.DueAt.UTC().Truncate(time.Microsecond)
+	}
+	loan = Loan{MemberId: loan.MemberId, BookId: loan.BookId, BorrowedAt: now, DueAt: dueAt, CreatedAt: now, UpdatedAt: now}
+
+	err := store.WithTx(r.Context(), func(tx BookStore) error {
+		if _, err := tx.GetMember(r.Context(), loan.MemberId); errors.Is(err, ErrNotFound) {
+			return errLoanMemberMissing
+		} else if err != nil {
+			return err
+		}
+		book, err := tx.GetBook(r.Context(), loan.BookId)
+		if err != nil {
+			return err
+		}
+		loan.Book = &book
+		return tx.CreateLoan(r.Context(), &loan)
+	})
+	switch {
+	case errors.Is(err, errLoanMemberMissing):
+		writeProblem(w, r, codeMemberNotFound, "Member not found")
+		return
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error creating loan")
+		log.Printf("Loan creation error: %v", err)
+		return
+	}
+	activity.count(r.Context(), loan.BookId, ActivityLoan)
+
+	writeResponse(w, r, http.StatusCreated, LoanResponse{
+		Status:  "success",
+		Message: "Loan created successfully",
+		Data:    loan,
+	})
+}
+
+// Record a lent book as returned, fining the member finePerDay for each
+// day it is late.
+func returnLoanHandler(finePerDay float64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			writeProblem(w, r, codeInvalidParameter, "Invalid loan ID")
+			return
+		}
+
+		var loan Loan
+		err = store.WithTx(r.Context(), func(tx BookStore) error {
+			var err error
+			if loan, err = tx.GetLoan(r.Context(), id); err != nil {
+				return err
+			}
+			if loan.ReturnedAt != nil {
+				return errLoanReturned
+			}
+			returnedAt := time.Now().UTC().Truncate(time.Microsecond)
+			loan.ReturnedAt, loan.UpdatedAt = &returnedAt, returnedAt
+			if err := tx.ReturnLoan(r.Context(), id, returnedAt); err != nil {
+				return err
+			}
+			book, err := tx.GetBookFields(r.Context(), loan.BookId, []string{"title"})
+			if err != nil {
+				return err
+			}
+			loan.Fine, err = assessOverdueFine(r.Context(), tx, loan, book.Title, finePerDay)
+			return err
+		})
+		switch {
+		case errors.Is(err, ErrNotFound):
+			writeProblem(w, r, codeLoanNotFound, "Loan not found")
+			return
+		case errors.Is(err, errLoanReturned):
+			writeProblem(w, r, codeLoanReturned, "The loan was already returned")
+			return
+		case err != nil:
+			writeProblem(w, r, codeInternal, "Error returning loan")
+			log.Printf("Loan return error: %v", err)
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, LoanResponse{
+			Status:  "success",
+			Message: "Loan returned successfully",
+			Data:    loan,
+		})
+	}
+}
+
+// List every loan of a member, returned or not, newest first, with their
+// stats. Members linked to a user can see their own; admins can see
+// anyone's.
+func loanHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid member ID")
+		return
+	}
+
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+
+	history := LoanHistory{MemberId: id}
+	_, err = store.GetMember(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeMemberNotFound, "Member not found")
+		return
+	}
+	if err == nil {
+		history.Loans, err = store.ListMemberLoans(r.Context(), id, ChangedAfter{})
+	}
+	if err == nil {
+		history.Stats, err = loanStats(r.Context(), history.Loans, time.Now().UTC())
+	}
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching loans")
+		log.Printf("Loan query error: %v", err)
+		return
+	}
+	if !auditMemberAccess(w, r, &id, AccessLoans) {
+		return
+	}
+	// The stats stay those of every loan.
+	history.Loans = keepChangedAfter(history.Loans, after)
+
+	writeResponse(w, r, http.StatusOK, LoanHistoryResponse{
+		Status:  "success",
+		Message: "Loans retrieved successfully",
+		Data:    history,
+	})
+}
+
+// Sum up a member's loans as of now, with their books' categories for
+// their favorite genres.
+func loanStats(ctx context.Context, loans []Loan, now time.Time) (LoanStats, error) {
+	stats := LoanStats{Borrows: len(loans), FavoriteGenres: []GenreCount{}}
+	var kept time.Duration
+	var returned int
+	genres := map[string]int{}
+	categories := map[int][]string{}
+	for _, loan := range loans {
+		if loan.ReturnedAt == nil {
+			stats.OnLoan++
+			if now.After(loan.DueAt) {
+				stats.Overdue++
+			}
+		} else {
+			kept += loan.ReturnedAt.Sub(loan.BorrowedAt)
+			returned++
+		}
+
+		names, ok := categories[loan.BookId]
+		if !ok {
+			subjects, err := store.GetBookSubjects(ctx, loan.BookId)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return LoanStats{}, err
+			}
+			names = subjects.Categories
+			categories[loan.BookId] = names
+		}
+		for _, name := range names {
+			genres[name]++
+		}
+	}
+
+	if returned > 0 {
+		days := math.Round(kept.Hours()/24/float64(returned)*10) / 10
+		stats.AverageLoanDays = &days
+	}
+	for name, borrows := range genres {
+		stats.FavoriteGenres = append(stats.FavoriteGenres, GenreCount{Genre: name, Borrows: borrows})
+	}
+	sort.Slice(stats.FavoriteGenres, func(i, j int) bool {
+		a, b := stats.FavoriteGenres[i], stats.FavoriteGenres[j]
+		if a.Borrows != b.Borrows {
+			return a.Borrows > b.Borrows
+		}
+		return a.Genre < b.Genre
+	})
+	if len(stats.FavoriteGenres) > maxFavoriteGenres {
+		stats.FavoriteGenres = stats.FavoriteGenres[:maxFavoriteGenres]
+	}
+	return stats, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// The message catalog: locales/<language>.json maps each English message
+// the API sends to its translation. Messages missing from a catalog, and
+// details that quote the request, are sent in English.
+//
+//go:embed locales
+var localeFiles embed.FS
+
+var translations, supportedLanguages = loadTranslations()
+
+var languageMatcher = language.NewMatcher(supportedLanguages)
+
+// Read the catalogs, English first as the fallback.
+func loadTranslations() (map[language.Tag]map[string]string, []language.Tag) {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+
+	catalogs := map[language.Tag]map[string]string{}
+	tags := []language.Tag{language.English}
+	for _, entry := range entries {
+		tag := language.MustParse(strings.TrimSuffix(entry.Name(), ".json"))
+		data, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var catalog map[string]string
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			panic(fmt.Sprintf("locales/%s: %v", entry.Name(), err))
+		}
+		catalogs[tag] = catalog
+		tags = append(tags, tag)
+	}
+	return catalogs, tags
+}
+
+// The supported language the Accept-Language header of r ranks highest,
+// or English.
+func requestLanguage(r *http.Request) language.Tag {
+	tags, _, _ := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	_, index, _ := languageMatcher.Match(tags...)
+	return supportedLanguages[index]
+}
+
+func translate(lang language.Tag, message string) string {
+	if translated, ok := translations[lang][message]; ok {
+		return translated
+	}
+	return message
+}
+
+// A copy of v, a Problem or one of the *Response types, with its messages
+// in lang.
+func localize(lang language.Tag, v any) any {
+	if lang == language.English {
+		return v
+	}
+
+	if problem, ok := v.(Problem); ok {
+		problem.Title = translate(lang, problem.Title)
+		problem.Detail = translate(lang, problem.Detail)
+		if problem.Errors != nil {
+			errs := make([]FieldError, len(problem.Errors))
+			for i, err := range problem.Errors {
+				err.Message = err.Field + " " + fmt.Sprintf(translate(lang, err.format), err.args...)
+				errs[i] = err
+			}
+			problem.Errors = errs
+			problem.Detail = validationMessage(errs)
+		}
+		return problem
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Struct {
+		return v
+	}
+	if message := rv.FieldByName("Message"); message.IsValid() && message.Kind() == reflect.String {
+		localized := reflect.New(rv.Type()).Elem()
+		localized.Set(rv)
+		localized.FieldByName("Message").SetString(translate(lang, message.String()))
+		return localized.Interface()
+	}
+	return v
+}
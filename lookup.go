@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// BookLookup is what a scanned barcode resolved to.
+type BookLookup struct {
+	// Source is "catalog" for a book in the catalog, or "metadata" for one
+	// only the metadata provider knows, which can be imported by creating
+	// it with the import link.
+	Source string `json:"source" xml:"source"`
+	Book   Book   `json:"book" xml:"book"`
+}
+
+type BookLookupResponse struct {
+	Status  string     `json:"status" xml:"status"`
+	Message string     `json:"message" xml:"message"`
+	Data    BookLookup `json:"data" xml:"data"`
+	Links   Links      `json:"links,omitempty" xml:"links,omitempty"`
+}
+
+// Resolve a scanned barcode: an ISBN, as EAN-13 or ISBN-10, or a book id
+// from one of our labels. ISBNs the catalog lacks are looked up with the
+// metadata provider and offered for import.
+func lookupBarcodeHandler(w http.ResponseWriter, r *http.Request) {
+	code := strings.TrimSpace(mux.Vars(r)["barcode"])
+	isbn, isISBN := normalizeISBN(code)
+	id, err := strconv.Atoi(code)
+	if !isISBN && (err != nil || id < 1 || len(code) > 9) {
+		writeProblem(w, r, codeInvalidParameter, "Barcode is neither an ISBN nor a book ID")
+		return
+	}
+
+	if isISBN {
+		id, err = store.GetBookIdByISBN(r.Context(), isbn)
+		if errors.Is(err, ErrNotFound) {
+			lookupMetadata(w, r, isbn)
+			return
+		} else if err != nil {
+			writeProblem(w, r, codeInternal, "Error fetching book from database")
+			log.Printf("Database query error: %v", err)
+			return
+		}
+	}
+	book, err := store.GetBook(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "No book has this barcode")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching book from database")
+		log.Printf("Database query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, BookLookupResponse{
+		Status:  "success",
+		Message: "Book found in the catalog",
+		Data:    BookLookup{Source: "catalog", Book: book},
+		Links:   bookLinks(book),
+	})
+}
+
+// Answer a lookup of an ISBN the catalog lacks with what the metadata
+// provider knows of it. The book has no price; clients set one before
+// importing it.
+func lookupMetadata(w http.ResponseWriter, r *http.Request, isbn string) {
+	if bookMetadata == nil {
+		writeProblem(w, r, codeBookNotFound, "No book has this barcode")
+		return
+	}
+	book, err := bookMetadata.LookupISBN(r.Context(), isbn)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "No book has this barcode")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeMetadataUnavailable, "Error looking up the ISBN")
+		log.Printf("Metadata lookup error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, BookLookupResponse{
+		Status:  "success",
+		Message: "Book found by the metadata provider",
+		Data:    BookLookup{Source: "metadata", Book: book},
+		Links:   Links{"import": {Href: apiPrefix + "/book", Method: http.MethodPost}},
+	})
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// The kinds of entry in a user's points history.
+const (
+	PointsEarned   = "earned"
+	PointsRedeemed = "redeemed"
+	PointsExpired  = "expired"
+)
+
+// LoyaltyEntry is points a user earned on a sale, redeemed on one or lost
+// when they expired; Points is negative for the last two.
+type LoyaltyEntry struct {
+	Id     int    `json:"id" xml:"id"`
+	UserId int    `json:"-" xml:"-"`
+	Kind   string `json:"kind" xml:"kind"`
+	Points int    `json:"points" xml:"points"`
+	// SaleId is the sale the points were earned or redeemed on.
+	SaleId int `json:"sale_id,omitempty" xml:"sale_id,omitempty"`
+	// ExpiresAt is when earned points expire, if they do.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" xml:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" xml:"created_at"`
+	// Remaining is how many of the earned points are left to redeem; the
+	// oldest are redeemed first.
+	Remaining int `json:"-" xml:"-"`
+}
+
+// Entries never change once added.
+func (e LoyaltyEntry) timestamps() (created, updated time.Time) {
+	return e.CreatedAt, e.CreatedAt
+}
+
+// LoyaltyPoints is a user's points balance, what it is worth at checkout,
+// and their history of points, newest first.
+type LoyaltyPoints struct {
+	Balance  int            `json:"balance" xml:"balance"`
+	Value    float64        `json:"value" xml:"value"`
+	Currency string         `json:"currency" xml:"currency"`
+	History  []LoyaltyEntry `json:"history" xml:"history>entry"`
+}
+
+type LoyaltyPointsResponse struct {
+	Status  string        `json:"status" xml:"status"`
+	Message string        `json:"message" xml:"message"`
+	Data    LoyaltyPoints `json:"data" xml:"data"`
+}
+
+// loyaltyPolicy is how sales earn and redeem points.
+type loyaltyPolicy struct {
+	// perUnit is the points earned for each unit of the currency paid.
+	perUnit float64
+	// value is what a point takes off a sale.
+	value float64
+	// expiry is how long points last after being earned; zero keeps them.
+	expiry time.Duration
+}
+
+var loyalty = &loyaltyPolicy{value: 0.01}
+
+const (
+	defaultPointsHistoryLimit = 50
+	maxPointsHistoryLimit     = 500
+)
+
+var errUserNotFound = errors.New("user not found")
+
+// Set up the loyalty program as configured.
+func initLoyalty(cfg Config) error {
+	if cfg.LoyaltyPointsPerUnit < 0 {
+		return errors.New("loyalty_points_per_unit must not be negative")
+	}
+	if cfg.LoyaltyPointValue <= 0 {
+		return errors.New("loyalty_point_value must be positive")
+	}
+	if cfg.LoyaltyPointsExpiry.Duration < 0 {
+		return errors.New("loyalty_points_expiry must not be negative")
+	}
+	loyalty = &loyaltyPolicy{perUnit: cfg.LoyaltyPointsPerUnit, value: cfg.LoyaltyPointValue, expiry: cfg.LoyaltyPointsExpiry.Duration}
+	return nil
+}
+
+// The whole points earned by paying amount.
+func (p *loyaltyPolicy) earned(amount float64) int {
+	// Rounded to a thousandth first, so float error can't cost a point.
+	return int(math.Floor(math.Round(amount*p.perUnit*1000) / 1000))
+}
+
+// Redeem up to points on a sale, no more than its total needs, setting
+// the sale's PointsRedeemed and PointsDiscount.
+func (p *loyaltyPolicy) redeem(sale *Sale, points int) {
+	points = min(points, int(math.Ceil(math.Round(sale.Total/p.value*1000)/1000)))
+	sale.PointsRedeemed = points
+	sale.PointsDiscount = min(roundCents(float64(points)*p.value), sale.Total)
+}
+
+// When points earned at a time expire, if they do.
+func (p *loyaltyPolicy) expiresAt(earnedAt time.Time) *time.Time {
+	if p.expiry == 0 {
+		return nil
+	}
+	expiresAt := earnedAt.Add(p.expiry)
+	return &expiresAt
+}
+
+// Take the points past their expiry date off their users' balances; the
+// loyalty_expiry job.
+func expireLoyaltyPoints(ctx context.Context) error {
+	n, err := store.ExpireLoyaltyPoints(ctx, time.Now().UTC())
+	if n > 0 {
+		log.Printf("Expired %d loyalty points", n)
+	}
+	return err
+}
+
+// The signed-in user's points balance and history.
+func myPointsHandler(w http.ResponseWriter, r *http.Request) {
+	writeLoyaltyPoints(w, r, currentUser(r).Id)
+}
+
+// A user's points balance and history, for support staff.
+func userPointsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid user ID")
+		return
+	}
+	if _, err := store.GetUser(r.Context(), id); errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeUserNotFound, "User not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching user")
+		log.Printf("User query error: %v", err)
+		return
+	}
+	writeLoyaltyPoints(w, r, id)
+}
+
+// Answer with the user's points balance and their latest ?limit= entries,
+// of those added after ?created_after= if it is given.
+func writeLoyaltyPoints(w http.ResponseWriter, r *http.Request, userId int) {
+	limit, ok := intParameter(r, "limit", defaultPointsHistoryLimit, maxPointsHistoryLimit)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(maxPointsHistoryLimit))
+		return
+	}
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+
+	balance, err := store.LoyaltyBalance(r.Context(), userId, time.Now().UTC())
+	var history []LoyaltyEntry
+	if err == nil {
+		history, err = store.ListLoyaltyEntries(r.Context(), userId, limit, after)
+	}
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching points")
+		log.Printf("Loyalty points query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, LoyaltyPointsResponse{
+		Status:  "success",
+		Message: "Points retrieved successfully",
+		Data: LoyaltyPoints{
+			Balance:  balance,
+			Value:    roundCents(float64(balance) * loyalty.value),
+			Currency: currencies.base,
+			History:  history,
+		},
+	})
+}
@@ -1,403 +1,691 @@
 package main
 
 import (
-	"database/sql"
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
-	"strings"
+	"slices"
+	"strconv"
+	"time"
 
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/gorilla/mux"
 )
 
 type Response struct {
-	Message string `json:"message"`
+	Message string `json:"message" xml:"message"`
 }
 
 type Book struct {
-	Id     int     `json:"id"`
-	Title  string  `json:"title"`
-	Author string  `json:"author"`
-	Price  float64 `json:"price"`
+	Id     int     `json:"id" xml:"id"`
+	Title  string  `json:"title" xml:"title" validate:"required,max=255"`
+	Author string  `json:"author" xml:"author" validate:"required,max=255"`
+	Price  float64 `json:"price" xml:"price" validate:"gte=0,lte=99999999.99"`
+	// ISBN is optional.
+	ISBN string `json:"isbn,omitempty" xml:"isbn,omitempty" validate:"omitempty,isbn,max=17"`
+	// Publisher is optional; editions of one work often differ in it.
+	Publisher string `json:"publisher,omitempty" xml:"publisher,omitempty" validate:"max=255"`
+	// Format is optional, one of bookFormats.
+	Format string `json:"format,omitempty" xml:"format,omitempty" validate:"omitempty,book_format"`
+	// Description and CoverURL are optional; the metadata_enrichment job
+	// fills them in from the metadata provider.
+	Description string `json:"description,omitempty" xml:"description,omitempty" validate:"max=5000"`
+	CoverURL    string `json:"cover_url,omitempty" xml:"cover_url,omitempty" validate:"omitempty,http_url,max=2048"`
+	// Slug is made from the title, see slugify; clients can't set it.
+	Slug string `json:"slug" xml:"slug"`
+	// CreatedAt is when the store added the book and UpdatedAt when it
+	// last changed it, in UTC; clients can't set them. Books added before
+	// they were recorded have neither.
+	CreatedAt *time.Time `json:"created_at,omitempty" xml:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty" xml:"updated_at,omitempty"`
+	// ArchivedAt is when the book_archival job archived the book for
+	// going untouched, leaving it out of lists unless asked for; nil for
+	// books in use.
+	ArchivedAt *time.Time `json:"archived_at,omitempty" xml:"archived_at,omitempty"`
+
+	// fields lists the fields a partial book was loaded with; nil means
+	// all of them.
+	fields []string
 }
 
 // For single Book response (create, get by Id, update).
 type BookResponse struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
-	Data    Book   `json:"data,omitempty"`
+	Status  string `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
+	Data    Book   `json:"data,omitempty" xml:"data"`
+	Links   Links  `json:"links,omitempty" xml:"links,omitempty"`
+	// Included holds related resources asked for with ?include=.
+	Included *Included `json:"included,omitempty" xml:"included,omitempty"`
+	// Currency is the currency of the prices, see convertPrices.
+	Currency string `json:"currency,omitempty" xml:"currency,omitempty"`
 }
 
 // For multiple books operations (GET all, Search).
+// Links holds self and, when paginated, first, last, prev and next.
 type BooksResponse struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
-	Data    []Book `json:"data,omitempty"`
+	Status   string    `json:"status" xml:"status"`
+	Message  string    `json:"message" xml:"message"`
+	Data     []Book    `json:"data,omitempty" xml:"data>book,omitempty"`
+	Links    Links     `json:"links,omitempty" xml:"links,omitempty"`
+	Included *Included `json:"included,omitempty" xml:"included,omitempty"`
+	Currency string    `json:"currency,omitempty" xml:"currency,omitempty"`
+	// Facets and Highlights are returned by GET /books/search; there is
+	// a highlight for each book on the page.
+	Facets     *SearchFacets     `json:"facets,omitempty" xml:"facets,omitempty"`
+	Highlights []SearchHighlight `json:"highlights,omitempty" xml:"highlights>highlight,omitempty"`
 }
 
-// Global DB handler.
-var db *sql.DB
+// Global storage handler.
+var store BookStore
 
 // Other endpoints.
 func createBookHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
 	var book Book
 	// Checks for invalid req.body.
-	err := json.NewDecoder(r.Body).Decode(&book)
+	err := decodeRequest(r, &book)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(BookResponse{
-			Status:  "error",
-			Message: "Invalid request body.",
-		})
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body.")
 		return
 	}
-
-	// Checks for empty input field.
-	if book.Title == "" || book.Author == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(BookResponse{
-			Status:  "error",
-			Message: "Title and Author field are required.",
-		})
+	book.normalize()
+	if err := book.checkISBN(); err != nil {
+		writeProblem(w, r, codeInvalidISBN, err.Error())
 		return
 	}
 
-	// Prepare SQL statement.
-	stmt, err := db.Prepare("INSERT INTO books (title, author, price) VALUES (?, ?, ?)")
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(BookResponse{
-			Status:  "error",
-			Message: "Database error.",
-		})
-		log.Printf("Statement preparation error: %v", err)
+	if errs := validateRequest(book); errs != nil {
+		writeValidationErrors(w, r, errs)
 		return
 	}
-	defer stmt.Close()
 
-	// Execute Statement.
-	result, err := stmt.Exec(book.Title, book.Author, book.Price)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(BookResponse{
-			Status:  "error",
-			Message: "Error creating book",
-		})
-		log.Printf("Statement execution error: %v", err)
+	// Insert the book, the store sets its Id.
+	err = createBook(r.Context(), store, &book)
+	if errors.Is(err, ErrDuplicateISBN) {
+		writeProblem(w, r, codeDuplicateISBN, "Another book has this ISBN")
 		return
-	}
-
-	// Get the lastly inseted bookId
-	lastId, err := result.LastInsertId()
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(BookResponse{
-			Status:  "error",
-			Message: "Error getting new book ID.",
-		})
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error creating book")
+		log.Printf("Book creation error: %v", err)
 		return
 	}
 
-	// Set the ID in our Book struct.
-	book.Id = int(lastId)
-
 	// Success Response.
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(BookResponse{
-		Status:  "sucess",
+	writeResponse(w, r, http.StatusCreated, BookResponse{
+		Status:  "success",
 		Message: "Book created Successfully",
 		Data:    book,
+		Links:   bookLinks(book),
 	})
 }
 
+// Load the books query selects. Whole books come through ListBooks and
+// SearchBooks, which may be cached; partial or filtered ones are only read
+// from the columns and rows they need.
+func listBooks(ctx context.Context, query BookQuery) ([]Book, error) {
+	if query.Fields == nil && len(query.Formats) == 0 && query.Filter == nil && query.CreatedAfter.IsZero() && query.UpdatedAfter.IsZero() {
+		var books []Book
+		var err error
+		if query.Search == "" {
+			books, err = store.ListBooks(ctx)
+		} else {
+			books, err = store.SearchBooks(ctx, query.Search)
+		}
+		if err != nil || !query.ExcludeArchived {
+			return books, err
+		}
+		// The cache may share the list, so it is cut down in a copy.
+		return slices.DeleteFunc(slices.Clone(books), archived), nil
+	}
+
+	books := []Book{}
+	err := store.StreamBooks(ctx, query, func(book Book) error {
+		books = append(books, book)
+		return nil
+	})
+	return books, err
+}
+
+// The ?created_after= and ?updated_after= parameters of a book list, zero
+// if absent. They are RFC 3339 times or dates, meaning midnight UTC.
+func requestedChangedAfter(r *http.Request) (createdAfter, updatedAfter time.Time, err error) {
+	if createdAfter, err = timeParameter(r, "created_after"); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	updatedAfter, err = timeParameter(r, "updated_after")
+	return createdAfter, updatedAfter, err
+}
+
 func getAllBooksHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+	fields, err := requestedFields(r)
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, err.Error())
+		return
+	}
+	includes, err := requestedIncludes(r)
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, err.Error())
+		return
+	}
+	formats, ok := requestedFormats(r)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "book_format must be hardcover, paperback, ebook or audiobook")
+		return
+	}
+	order, err := requestedBookSort(r)
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, err.Error())
+		return
+	}
+	createdAfter, updatedAfter, err := requestedChangedAfter(r)
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, err.Error())
+		return
+	}
+	includeArchived, ok := includeArchivedParameter(r)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "include_archived must be true or false")
+		return
+	}
 
 	// Query to get-All-Books
-	rows, err := db.Query("SELECT id, title, author, price FROM books")
+	books, err := listBooks(r.Context(), BookQuery{Fields: order.fields(fields), Formats: formats, CreatedAfter: createdAfter, UpdatedAfter: updatedAfter, ExcludeArchived: !includeArchived})
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(BooksResponse{
-			Status:  "error",
-			Message: "Error fetching books from database",
-		})
+		writeProblem(w, r, codeInternal, "Error fetching books from database")
 		log.Printf("Database query error: %v", err)
 		return
 	}
-	defer rows.Close()
-
-	// Slice to store all Book
-	var books []Book
+	order.sort(books)
+	for i := range books {
+		books[i] = books[i].only(fields)
+	}
 
-	for rows.Next() {
-		var book Book
-		err := rows.Scan(&book.Id, &book.Title, &book.Author, &book.Price)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(BooksResponse{
-				Status:  "error",
-				Message: "Error scanning database records",
-			})
-			log.Printf("Row scanning error: %v", err)
-			return
-		}
-		books = append(books, book)
+	page, links, err := paginate(r, books)
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, err.Error())
+		return
+	}
+	currency, err := convertPrices(r, page)
+	if err != nil {
+		writeConversionError(w, r, err)
+		return
 	}
 
-	// Check for errors from iterating over rows
-	if err = rows.Err(); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(BooksResponse{
-			Status:  "erro",
-			Message: "Error iterating through records",
-		})
-		log.Printf("Row iteration error: %v", err)
+	included, err := loadIncluded(r.Context(), includes, page)
+	if errors.Is(err, errTooManyIncluded) {
+		writeProblem(w, r, codeTooManyIncluded, err.Error())
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching related resources")
+		log.Printf("Database include error: %v", err)
 		return
 	}
 
 	// If not books found,
 	// return empty array with success status
 	if len(books) == 0 {
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(BooksResponse{
-			Status:  "success",
-			Message: "No books found",
-			Data:    []Book{},
+		writeResponse(w, r, http.StatusOK, BooksResponse{
+			Status:   "success",
+			Message:  "No books found",
+			Data:     []Book{},
+			Links:    links,
+			Currency: currency,
 		})
 		return
 	}
 
 	// Sucess response with books
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(BooksResponse{
-		Status:  "success",
-		Message: "Books retrived sucessfully",
-		Data:    books,
+	writeResponse(w, r, http.StatusOK, BooksResponse{
+		Status:   "success",
+		Message:  "Books retrived sucessfully",
+		Data:     page,
+		Links:    links,
+		Included: included,
+		Currency: currency,
 	})
 }
 
-func deleteAllBooks(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+func searchBooksHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		writeProblem(w, r, codeInvalidParameter, "Search query is required")
+		return
+	}
 
-	// Execute DELETE query
-	result, err := db.Exec("DELETE FROM books")
+	fields, err := requestedFields(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(Response{
-			Message: "Error deleting books from database",
-		})
-		log.Printf("Databse deletion error: %v", err)
+		writeProblem(w, r, codeInvalidParameter, err.Error())
+		return
+	}
+	includes, err := requestedIncludes(r)
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, err.Error())
+		return
+	}
+	formats, ok := requestedFormats(r)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "book_format must be hardcover, paperback, ebook or audiobook")
+		return
+	}
+	fuzzy, ok := fuzzyParameter(r)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "fuzzy must be true or false")
+		return
+	}
+	filter, err := parseBookFilter(query)
+	if err != nil {
+		writeProblem(w, r, codeInvalidQuery, err.Error())
+		return
+	}
+	order, err := requestedBookSort(r)
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, err.Error())
+		return
+	}
+	createdAfter, updatedAfter, err := requestedChangedAfter(r)
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, err.Error())
+		return
+	}
+	includeArchived, ok := includeArchivedParameter(r)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "include_archived must be true or false")
 		return
 	}
 
-	// Get the number of affected rows
-	rowsAffected, err := result.RowsAffected()
+	// Facets and highlights need whole books, so fields are picked once
+	// they're done.
+	started := time.Now()
+	books, err := searchBooks(r.Context(), BookQuery{Search: query, Formats: formats, Filter: filter, CreatedAfter: createdAfter, UpdatedAfter: updatedAfter, ExcludeArchived: !includeArchived}, fuzzy)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(Response{
-			Message: "Error getting affected rows count",
-		})
-		log.Printf("Error getting affected rows: %v", err)
+		writeProblem(w, r, codeInternal, "Error searching books")
+		log.Printf("Database search error: %v", err)
 		return
 	}
+	recordSearch(r.Context(), query, len(books), started)
+	order.sort(books)
 
-	// If no books were affected
-	if rowsAffected == 0 {
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(Response{
-			Message: "No books to delete",
-		})
+	currency, err := convertPrices(r, books)
+	if err != nil {
+		writeConversionError(w, r, err)
 		return
 	}
-	// Sucess response
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(Response{
-		Message: "All books deleted successfully",
-	})
-}
+	facets, err := searchFacets(r.Context(), books)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error searching books")
+		log.Printf("Database facet error: %v", err)
+		return
+	}
+	highlights := map[int]SearchHighlight{}
+	for i := range books {
+		if highlight, ok := highlightBook(books[i], query, filter); ok {
+			highlights[books[i].Id] = highlight
+		}
+		books[i] = books[i].only(fields)
+	}
 
-func updateBookHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+	page, links, err := paginate(r, books)
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, err.Error())
+		return
+	}
+	var pageHighlights []SearchHighlight
+	for _, book := range page {
+		if highlight, ok := highlights[book.Id]; ok {
+			pageHighlights = append(pageHighlights, highlight)
+		}
+	}
 
-	// GET id from URL parameters
-	vars := mux.Vars(r)
-	id, ok := vars["id"]
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(BookResponse{
-			Status:  "error",
-			Message: "Book ID is required",
+	included, err := loadIncluded(r.Context(), includes, page)
+	if errors.Is(err, errTooManyIncluded) {
+		writeProblem(w, r, codeTooManyIncluded, err.Error())
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching related resources")
+		log.Printf("Database include error: %v", err)
+		return
+	}
+
+	if len(books) == 0 {
+		writeResponse(w, r, http.StatusOK, BooksResponse{
+			Status:   "success",
+			Message:  "No books found",
+			Data:     []Book{},
+			Links:    links,
+			Currency: currency,
+			Facets:   facets,
 		})
 		return
 	}
 
-	// Parse request body
-	var book Book
-	err := json.NewDecoder(r.Body).Decode(&book)
+	writeResponse(w, r, http.StatusOK, BooksResponse{
+		Status:     "success",
+		Message:    "Books retrieved successfully",
+		Data:       page,
+		Links:      links,
+		Included:   included,
+		Currency:   currency,
+		Facets:     facets,
+		Highlights: pageHighlights,
+	})
+}
+
+func getBookHandler(w http.ResponseWriter, r *http.Request) {
+	// GET id from URL parameters
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(BookResponse{
-			Status:  "error",
-			Message: "Invalid request body",
-		})
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
 		return
 	}
+	writeBook(w, r, id)
+}
 
-	// Check if books exists
-	var existingBook Book
-	err = db.QueryRow("SELECT id, title, author, price FROM books WHERE id = ?", id).Scan(&existingBook.Id, &existingBook.Title, &existingBook.Author, &existingBook.Price)
-	if err == sql.ErrNoRows {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(BookResponse{
-			Status:  "error",
-			Message: "Book no found",
-		})
+func getBookBySlugHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := store.GetBookIdBySlug(r.Context(), mux.Vars(r)["slug"])
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book not found")
 		return
 	} else if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(BookResponse{
-			Status:  "error",
-			Message: "Database error while checking book existence",
-		})
+		writeProblem(w, r, codeInternal, "Error fetching book from database")
 		log.Printf("Database query error: %v", err)
 		return
 	}
+	writeBook(w, r, id)
+}
 
-	// Prepare update quer - only update not-empty fields
-	query := "UPDATE books SET"
-	var updates []interface{}
-	var setParts []string
+// Answer r with the book with id, with the fields and related resources
+// it asks for.
+func writeBook(w http.ResponseWriter, r *http.Request, id int) {
+	fields, err := requestedFields(r)
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, err.Error())
+		return
+	}
+	includes, err := requestedIncludes(r)
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, err.Error())
+		return
+	}
 
-	if book.Title != "" {
-		setParts = append(setParts, " title = ?")
-		updates = append(updates, book.Title)
+	var book Book
+	if fields == nil {
+		book, err = store.GetBook(r.Context(), id)
+	} else {
+		book, err = store.GetBookFields(r.Context(), id, fields)
 	}
-	if book.Author != "" {
-		setParts = append(setParts, " author = ?")
-		updates = append(updates, book.Author)
+	if errors.Is(err, ErrNotFound) {
+		if mux.Vars(r)["id"] != "" && redirectMergedBook(w, r, id) {
+			return
+		}
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching book from database")
+		log.Printf("Database query error: %v", err)
+		return
+	}
+	// Merged duplicates stay archived; fields may leave archived_at out.
+	if (book.ArchivedAt != nil || fields != nil) && mux.Vars(r)["id"] != "" && redirectMergedBook(w, r, id) {
+		return
 	}
-	if book.Price != 0 {
-		setParts = append(setParts, " price = ?")
-		updates = append(updates, book.Price)
+	converted := []Book{book}
+	currency, err := convertPrices(r, converted)
+	if err != nil {
+		writeConversionError(w, r, err)
+		return
 	}
+	book = converted[0]
+	activity.count(r.Context(), id, ActivityView)
 
-	// If no fields to update
-	if len(updates) == 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(BookResponse{
-			Status:  "error",
-			Message: "No fields to update",
-		})
+	included, err := loadIncluded(r.Context(), includes, []Book{book})
+	if errors.Is(err, errTooManyIncluded) {
+		writeProblem(w, r, codeTooManyIncluded, err.Error())
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching related resources")
+		log.Printf("Database include error: %v", err)
 		return
 	}
 
-	// Complete the query
-	query += strings.Join(setParts, ",") + "WHERE id = ?"
-	updates = append(updates, id)
+	writeResponse(w, r, http.StatusOK, BookResponse{
+		Status:   "success",
+		Message:  "Book retrieved successfully",
+		Data:     book,
+		Links:    bookLinks(book),
+		Included: included,
+		Currency: currency,
+	})
+}
 
-	// Execute update
-	result, err := db.Exec(query, updates...)
+func deleteBookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(BookResponse{
-			Status:  "error",
-			Message: "Error updating book",
-		})
-		log.Printf("Database update error: %v", err)
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
 		return
 	}
 
-	// Check the affected-rows
-	rowsAffected, err := result.RowsAffected()
+	err = store.DeleteBook(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error deleting book")
+		log.Printf("Database deletion error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Book deleted successfully",
+	})
+}
+
+func deleteAllBooks(w http.ResponseWriter, r *http.Request) {
+	// Delete every book and get the number of affected rows
+	rowsAffected, err := store.DeleteAllBooks(r.Context())
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(BookResponse{
-			Status:  "error",
-			Message: "Error checking update Status",
-		})
-		log.Printf("Error getting affected rows: %v", err)
+		writeProblem(w, r, codeInternal, "Error deleting books from database")
+		log.Printf("Databse deletion error: %v", err)
 		return
 	}
 
+	// If no books were affected
 	if rowsAffected == 0 {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(BookResponse{
-			Status:  "error",
-			Message: "Book not found or no changes made",
+		writeResponse(w, r, http.StatusOK, Response{
+			Message: "No books to delete",
 		})
 		return
 	}
+	// Sucess response
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "All books deleted successfully",
+	})
+}
+
+func updateBookHandler(w http.ResponseWriter, r *http.Request) {
+	// GET id from URL parameters
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Book ID is required")
+		return
+	}
 
-	// Fetch updated book
-	var updatedBook Book
-	err = db.QueryRow("SELECT id, title, author, price FROM books WHERE id = ?").Scan(&updatedBook.Title, &updatedBook.Author, &updatedBook.Price)
+	// Parse request body
+	var book Book
+	err = decodeRequest(r, &book)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(BookResponse{
-			Status:  "error",
-			Message: "Error fetching updated book",
-		})
-		log.Printf("Error fetchingg updated book: %v", err)
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	book.normalize()
+
+	// If no fields to update
+	if len(book.updatedFields()) == 0 {
+		writeProblem(w, r, codeNoFieldsToUpdate, "No fields to update")
+		return
+	}
+	if err := book.checkISBN(); err != nil {
+		writeProblem(w, r, codeInvalidISBN, err.Error())
+		return
+	}
+	if errs := validateFields(book, book.updatedFields()...); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	// Check the book exists and update it in one transaction,
+	// only not-empty fields are changed
+	updatedBook, err := updateExistingBook(r.Context(), store, id, book)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	} else if errors.Is(err, ErrDuplicateISBN) {
+		writeProblem(w, r, codeDuplicateISBN, "Another book has this ISBN")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error updating book")
+		log.Printf("Database update error: %v", err)
 		return
 	}
 
 	// Success response
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(BookResponse{
+	writeResponse(w, r, http.StatusOK, BookResponse{
 		Status:  "success",
 		Message: "Book updated successfully",
 		Data:    updatedBook,
+		Links:   bookLinks(updatedBook),
 	})
 }
 
-// Initialize Database connection.
-func initDB() {
+// Initialize the storage backend selected by the config.
+func initStore(cfg Config) {
 	var err error
 
-	db, err = sql.Open("mysql", "root:mysecret@tcp(localhost:3306)/bookstore")
+	store, err = openStore(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	err = db.Ping()
+	// Serve hot reads from the cache when one is configured.
+	cache, err := openCache(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if cache != nil {
+		store = newCachedStore(store, cache)
+	}
 
-	log.Println("Connected to Mysql container.")
+	// Record catalog changes in the outbox for relayOutbox.
+	store = newEventStore(store)
+
+	log.Println("Connected to database.")
 }
 
 // Heartbeat program to checkServer.
 func checkServer(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
 	response := Response{
 		Message: "Hello, there",
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	writeResponse(w, r, http.StatusOK, response)
 }
 
 func main() {
-	// Initialize DB connection.
-	initDB()
-	defer db.Close()
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	r := mux.NewRouter()
+	// Commands that don't need the database.
+	if flag.Arg(0) == "vapid-keys" {
+		public, private, err := generateVAPIDKeys()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("vapid_public_key:  %s\nvapid_private_key: %s\n", public, private)
+		return
+	}
 
-	r.HandleFunc("/check", checkServer).Methods("GET")
+	cfg, err = initSecrets(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := initPII(cfg); err != nil {
+		log.Fatal(err)
+	}
 
-	r.HandleFunc("/book", createBookHandler).Methods("POST")
-	r.HandleFunc("/book/{id}", updateBookHandler).Methods("PUT")
+	// Initialize storage.
+	initStore(cfg)
+	defer store.Close()
 
-	r.HandleFunc("/books", getAllBooksHandler).Methods("GET")
-	r.HandleFunc("/books", deleteAllBooks).Methods("DELETE")
+	switch command := flag.Arg(0); command {
+	case "":
+	case "seed":
+		if err := seed(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+		return
+	default:
+		log.Fatalf("unknown command %q", command)
+	}
+
+	frontend, err := openFrontend(cfg.FrontendDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	r := newRouter(cfg, frontend)
+	// Wrapping the router, rather than using it as its middleware, sets
+	// the headers on responses no route matched too.
+	withHeaders, err := securityHeaders(cfg.SecurityHeaders)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	publisher, err := openPublisher(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if publisher != nil {
+		defer publisher.Close()
+	}
+
+	go relayOutbox(events, publisher)
+	go dispatchWebhooks()
+
+	if err := initBackups(cfg); err != nil {
+		log.Fatal(err)
+	}
+	if err := initCurrencies(cfg); err != nil {
+		log.Fatal(err)
+	}
+	if err := initTaxes(cfg); err != nil {
+		log.Fatal(err)
+	}
+	if err := initLoyalty(cfg); err != nil {
+		log.Fatal(err)
+	}
+	if err := initPrivacy(cfg); err != nil {
+		log.Fatal(err)
+	}
+	if err := initMetadata(cfg); err != nil {
+		log.Fatal(err)
+	}
+	if err := initNotifications(cfg); err != nil {
+		log.Fatal(err)
+	}
+	go dispatchNotifications()
+	if err := startJobs(cfg); err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.GRPCAddr != "" {
+		go func() {
+			log.Fatal(serveGRPC(cfg.GRPCAddr, cfg.AdminToken))
+		}()
+	}
 
 	// Start server.
-	log.Printf("Server starting on port 8080:")
-	log.Fatal(http.ListenAndServe(":8080", r))
+	log.Printf("Server starting on %s:", cfg.Addr)
+	log.Fatal(http.ListenAndServe(cfg.Addr, withHeaders(r)))
 }
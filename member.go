@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// The membership tiers, which decide how much members can borrow.
+const (
+	TierBasic    = "basic"
+	TierStandard = "standard"
+	TierPremium  = "premium"
+)
+
+var memberTiers = []string{TierBasic, TierStandard, TierPremium}
+
+// How many books members of each tier can have on loan at once.
+var tierLoanLimits = map[string]int{TierBasic: 3, TierStandard: 5, TierPremium: 10}
+
+// Member is someone registered with the library, who borrows its books
+// with their library card. Members needn't have a user account; one linked
+// to theirs by UserId lets them see their own membership and loans.
+type Member struct {
+	Id int `json:"id" xml:"id"`
+	// CardNumber is the number on the member's library card, unique in
+	// the tenant: upper case letters and digits. A new member gets a
+	// random ten-digit number unless one is given.
+	CardNumber string `json:"card_number" xml:"card_number" validate:"omitempty,card_number"`
+	Name       string `json:"name" xml:"name" validate:"required,max=255"`
+	Email      string `json:"email,omitempty" xml:"email,omitempty" validate:"omitempty,email,max=255"`
+	// Phone is in E.164 format.
+	Phone   string `json:"phone,omitempty" xml:"phone,omitempty" validate:"omitempty,e164"`
+	Address string `json:"address,omitempty" xml:"address,omitempty" validate:"max=1000"`
+	// Tier is standard unless given.
+	Tier string `json:"tier" xml:"tier" validate:"omitempty,member_tier"`
+	// ExpiresAt is when the membership runs out, a year after joining
+	// unless given. Members from before memberships expired have none.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" xml:"expires_at,omitempty"`
+	UserId    *int       `json:"user_id,omitempty" xml:"user_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" xml:"updated_at"`
+}
+
+func (m Member) timestamps() (created, updated time.Time) {
+	return m.CreatedAt, m.UpdatedAt
+}
+
+type MemberResponse struct {
+	Status  string `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
+	Data    Member `json:"data" xml:"data"`
+}
+
+type MembersResponse struct {
+	Status  string   `json:"status" xml:"status"`
+	Message string   `json:"message" xml:"message"`
+	Data    []Member `json:"data" xml:"data>member"`
+}
+
+var cardNumberPattern = regexp.MustCompile(`^[A-Z0-9]{4,32}$`)
+
+// How long a new membership lasts unless it says otherwise.
+const membershipPeriod = 365 * 24 * time.Hour
+
+// How often createMemberHandler picks another card number when the one it
+// picked is taken.
+const maxCardNumberAttempts = 3
+
+var errMemberUserMissing = errors.New("user not found")
+
+// Tidy the fields as typed, upper casing the card number the way cards
+// are printed, and default the tier.
+func (m *Member) normalize() {
+	m.CardNumber = strings.ToUpper(strings.TrimSpace(m.CardNumber))
+	m.Name = normalizeText(m.Name)
+	m.Email = strings.TrimSpace(m.Email)
+	m.Phone = strings.TrimSpace(m.Phone)
+	m.Address = normalizeText(m.Address)
+	if m.Tier == "" {
+		m.Tier = TierStandard
+	}
+	if m.ExpiresAt != nil {
+		expiresAt := m.ExpiresAt.UTC().Truncate(time.Microsecond)
+		m.ExpiresAt = &expiresAt
+	}
+}
+
+// Whether the membership is current at t.
+func (m Member) activeAt(t time.Time) bool {
+	return m.ExpiresAt == nil || m.ExpiresAt.After(t)
+}
+
+// A random card number for a new member.
+func newCardNumber() string {
+	return fmt.Sprintf("%010d", rand.Int64N(10_000_000_000))
+}
+
+// List every member, by name.
+func listMembersHandler(w http.ResponseWriter, r *http.Request) {
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+	members, err := store.ListMembers(r.Context(), after)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching members")
+		log.Printf("Member query error: %v", err)
+		return
+	}
+	if !auditMemberAccess(w, r, nil, AccessMembers) {
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, MembersResponse{
+		Status:  "success",
+		Message: "Members retrieved successfully",
+		Data:    members,
+	})
+}
+
+func createMemberHandler(w http.ResponseWriter, r *http.Request) {
+	var member Member
+	if err := decodeRequest(r, &member); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	member.normalize()
+	if errs := validateRequest(member); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	member.Id = 0
+	member.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	member.UpdatedAt = member.CreatedAt
+	if member.ExpiresAt == nil {
+		expiresAt := member.CreatedAt.Add(membershipPeriod)
+		member.ExpiresAt = &expiresAt
+	}
+	pickCard := member.CardNumber == ""
+
+	var err error
+	for range maxCardNumberAttempts {
+		if pickCard {
+			member.CardNumber = newCardNumber()
+		}
+		err = store.WithTx(r.Context(), func(tx BookStore) error {
+			if err := checkMemberUser(r.Context(), tx, member); err != nil {
+				return err
+			}
+			return tx.CreateMember(r.Context(), &member)
+		})
+		if !pickCard || !errors.Is(err, ErrDuplicateCardNumber) {
+			break
+		}
+	}
+	if err != nil {
+		writeMemberError(w, r, err, "Error creating member")
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, MemberResponse{
+		Status:  "success",
+		Message: "Member created successfully",
+		Data:    member,
+	})
+}
+
+func getMemberHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid member ID")
+		return
+	}
+
+	member, err := store.GetMember(r.Context(), id)
+	if err != nil {
+		writeMemberError(w, r, err, "Error fetching member")
+		return
+	}
+	if !auditMemberAccess(w, r, &member.Id, AccessMember) {
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, MemberResponse{
+		Status:  "success",
+		Message: "Member retrieved successfully",
+		Data:    member,
+	})
+}
+
+// Find the member a library card belongs to, for the circulation desk to
+// look up scanned cards.
+func getMemberByCardHandler(w http.ResponseWriter, r *http.Request) {
+	number := strings.ToUpper(mux.Vars(r)["number"])
+
+	member, err := store.GetMemberByCard(r.Context(), number)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeMemberNotFound, "No member has this card")
+		return
+	} else if err != nil {
+		writeMemberError(w, r, err, "Error fetching member")
+		return
+	}
+	if !auditMemberAccess(w, r, &member.Id, AccessMember) {
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, MemberResponse{
+		Status:  "success",
+		Message: "Member retrieved successfully",
+		Data:    member,
+	})
+}
+
+// Replace a member's details. Without a card number or expiry date they
+// keep the ones they had.
+func updateMemberHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid member ID")
+		return
+	}
+	var req Member
+	if err := decodeRequest(r, &req); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	req.normalize()
+	if errs := validateRequest(req); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	var member Member
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		var err error
+		if member, err = tx.GetMember(r.Context(), id); err != nil {
+			return err
+		}
+		if req.CardNumber != "" {
+			member.CardNumber = req.CardNumber
+		}
+		if req.ExpiresAt != nil {
+			member.ExpiresAt = req.ExpiresAt
+		}
+		member.Name, member.Email, member.Phone, member.Address = req.Name, req.Email, req.Phone, req.Address
+		member.Tier, member.UserId = req.Tier, req.UserId
+		member.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		if err := checkMemberUser(r.Context(), tx, member); err != nil {
+			return err
+		}
+		return tx.UpdateMember(r.Context(), member)
+	})
+	if err != nil {
+		writeMemberError(w, r, err, "Error updating member")
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, MemberResponse{
+		Status:  "success",
+		Message: "Member updated successfully",
+		Data:    member,
+	})
+}
+
+// Delete a member, keeping their loan history, unless they have books on
+// loan or fines outstanding.
+func deleteMemberHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid member ID")
+		return
+	}
+
+	if err := store.DeleteMember(r.Context(), id); err != nil {
+		writeMemberError(w, r, err, "Error deleting member")
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Member deleted successfully",
+	})
+}
+
+// Check that the user a member is linked to exists.
+func checkMemberUser(ctx context.Context, tx BookStore, member Member) error {
+	if member.UserId == nil {
+		return nil
+	}
+	_, err := tx.GetUser(ctx, *member.UserId)
+	if errors.Is(err, ErrNotFound) {
+		return errMemberUserMissing
+	}
+	return err
+}
+
+// Answer a request the member handlers failed, with message if the error
+// isn't one of theirs.
+func writeMemberError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeMemberNotFound, "Member not found")
+	case errors.Is(err, ErrDuplicateCardNumber):
+		writeProblem(w, r, codeDuplicateCardNumber, "Another member has this card number")
+	case errors.Is(err, errMemberUserMissing):
+		writeValidationErrors(w, r, []FieldError{newFieldError("user_id", "exists", "must be the id of a user")})
+	case errors.Is(err, ErrMemberInUse):
+		writeProblem(w, r, codeMemberInUse, "The member has books on loan or fines outstanding")
+	default:
+		writeProblem(w, r, codeInternal, message)
+		log.Printf("Member error: %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MetadataProvider looks up books the catalog doesn't have, or details
+// of those it has, in a bibliographic database.
+type MetadataProvider interface {
+	// LookupISBN returns the title, author, ISBN and what else is known
+	// of the book with isbn, a normalized ISBN-13, or ErrNotFound.
+	LookupISBN(ctx context.Context, isbn string) (Book, error)
+	// FindBook returns the book best matching title and author, as
+	// LookupISBN does, or ErrNotFound.
+	FindBook(ctx context.Context, title, author string) (Book, error)
+}
+
+// The metadata provider selected by the config, or nil.
+var bookMetadata MetadataProvider
+
+// Open the metadata provider selected by the config, or return nil if
+// none is.
+func openMetadataProvider(cfg Config) (MetadataProvider, error) {
+	switch cfg.MetadataProvider {
+	case "", "none":
+		return nil, nil
+	case "openlibrary":
+		p := openLibrary{url: strings.TrimSuffix(cfg.MetadataURL, "/")}
+		if interval := cfg.MetadataInterval.Duration; interval > 0 {
+			p.ticks = time.NewTicker(interval).C
+		}
+		return p, nil
+	default:
+		return nil, fmt.Errorf("unknown metadata provider %q", cfg.MetadataProvider)
+	}
+}
+
+func initMetadata(cfg Config) error {
+	provider, err := openMetadataProvider(cfg)
+	if err != nil {
+		return err
+	}
+	bookMetadata = provider
+	return nil
+}
+
+var metadataClient = &http.Client{Timeout: 10 * time.Second}
+
+// Open Library's Books and Search APIs. The Books API answers for ISBNs
+// it doesn't know with an empty object.
+type openLibrary struct {
+	url string
+	// ticks spaces requests metadata_interval apart; nil doesn't.
+	ticks <-chan time.Time
+}
+
+// Get path with query from Open Library, once it is time for another
+// request, and decode the JSON answer into v.
+func (p openLibrary) get(ctx context.Context, path string, query url.Values, v any) error {
+	if p.ticks != nil {
+		select {
+		case <-p.ticks:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	res, err := metadataClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("open library: unexpected status %s", res.Status)
+	}
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return fmt.Errorf("open library: %w", err)
+	}
+	return nil
+}
+
+func (p openLibrary) LookupISBN(ctx context.Context, isbn string) (Book, error) {
+	key := "ISBN:" + isbn
+	var books map[string]struct {
+		Title    string `json:"title"`
+		Subtitle string `json:"subtitle"`
+		Authors  []struct {
+			Name string `json:"name"`
+		} `json:"authors"`
+		Publishers []struct {
+			Name string `json:"name"`
+		} `json:"publishers"`
+		// Notes is a string or a {"type", "value"} object.
+		Notes json.RawMessage `json:"notes"`
+		Cover struct {
+			Medium string `json:"medium"`
+		} `json:"cover"`
+	}
+	query := url.Values{"bibkeys": {key}, "format": {"json"}, "jscmd": {"data"}}
+	if err := p.get(ctx, "/api/books", query, &books); err != nil {
+		return Book{}, err
+	}
+	found, ok := books[key]
+	if !ok || found.Title == "" {
+		return Book{}, ErrNotFound
+	}
+
+	book := Book{Title: found.Title, ISBN: isbn, CoverURL: found.Cover.Medium}
+	if found.Subtitle != "" {
+		book.Title += ": " + found.Subtitle
+	}
+	authors := make([]string, len(found.Authors))
+	for i, author := range found.Authors {
+		authors[i] = author.Name
+	}
+	book.Author = strings.Join(authors, ", ")
+	if len(found.Publishers) > 0 {
+		book.Publisher = found.Publishers[0].Name
+	}
+	var notes struct {
+		Value string `json:"value"`
+	}
+	if json.Unmarshal(found.Notes, &book.Description) != nil && json.Unmarshal(found.Notes, &notes) == nil {
+		book.Description = notes.Value
+	}
+	return book, nil
+}
+
+// Search for the title and author, then look up the first edition found
+// with a valid ISBN.
+func (p openLibrary) FindBook(ctx context.Context, title, author string) (Book, error) {
+	var results struct {
+		Docs []struct {
+			ISBN []string `json:"isbn"`
+		} `json:"docs"`
+	}
+	query := url.Values{"title": {title}, "author": {author}, "fields": {"isbn"}, "limit": {"5"}}
+	if err := p.get(ctx, "/search.json", query, &results); err != nil {
+		return Book{}, err
+	}
+	for _, doc := range results.Docs {
+		for _, isbn := range doc.ISBN {
+			if isbn, ok := normalizeISBN(isbn); ok {
+				return p.LookupISBN(ctx, isbn)
+			}
+		}
+	}
+	return Book{}, ErrNotFound
+}
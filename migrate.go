@@ -0,0 +1,112 @@
+package main
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"strings"
+)
+
+// Migration files live in migrations/<dialect>/ and run in file-name order.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// Apply the dialect's migrations that have not been recorded yet.
+func migrate(db *sql.DB, d dialect) error {
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version VARCHAR(255) PRIMARY KEY)")
+	if err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	dir := path.Join("migrations", d.name)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		version, ok := strings.CutSuffix(entry.Name(), ".sql")
+		if !ok {
+			continue
+		}
+
+		var applied int
+		err := db.QueryRow(d.rebind("SELECT COUNT(*) FROM schema_migrations WHERE version = ?"), version).Scan(&applied)
+		if err != nil {
+			return err
+		}
+		if applied > 0 {
+			continue
+		}
+
+		data, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if err := applyMigration(db, d, version, string(data)); err != nil {
+			return fmt.Errorf("migration %s: %w", version, err)
+		}
+		log.Printf("Applied migration %s", version)
+	}
+	return nil
+}
+
+// The newest migration this build has, which names the schema it uses.
+// Every dialect has the same migrations.
+func schemaVersion() string {
+	entries, err := fs.ReadDir(migrationFiles, "migrations/sqlite")
+	if err != nil || len(entries) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(entries[len(entries)-1].Name(), ".sql")
+}
+
+func applyMigration(db *sql.DB, d dialect, version, script string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range splitStatements(script) {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(d.rebind("INSERT INTO schema_migrations (version) VALUES (?)"), version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Split a script into statements on semicolons that end a line,
+// dropping blank lines and -- comments.
+func splitStatements(script string) []string {
+	var stmts []string
+	var current strings.Builder
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
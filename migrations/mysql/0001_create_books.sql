@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS books (
+    id     INT AUTO_INCREMENT PRIMARY KEY,
+    title  VARCHAR(255) NOT NULL,
+    author VARCHAR(255) NOT NULL,
+    price  DECIMAL(10, 2) NOT NULL DEFAULT 0
+);
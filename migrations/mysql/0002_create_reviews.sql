@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS reviews (
+    id       INT AUTO_INCREMENT PRIMARY KEY,
+    book_id  INT NOT NULL,
+    reviewer VARCHAR(255) NOT NULL,
+    rating   TINYINT NOT NULL,
+    body     TEXT NOT NULL,
+    CONSTRAINT fk_reviews_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    url        VARCHAR(2048) NOT NULL,
+    secret     VARCHAR(255) NOT NULL,
+    events     VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME(6) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id          INT AUTO_INCREMENT PRIMARY KEY,
+    webhook_id  INT NOT NULL,
+    event_id    BIGINT NOT NULL,
+    event_type  VARCHAR(64) NOT NULL,
+    attempt     INT NOT NULL,
+    status_code INT NOT NULL DEFAULT 0,
+    error       TEXT NOT NULL,
+    created_at  DATETIME(6) NOT NULL,
+    CONSTRAINT fk_webhook_deliveries_webhook FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE
+);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id         BIGINT AUTO_INCREMENT PRIMARY KEY,
+    event_type VARCHAR(64) NOT NULL,
+    payload    TEXT NOT NULL,
+    created_at DATETIME(6) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_jobs (
+    id              INT AUTO_INCREMENT PRIMARY KEY,
+    webhook_id      INT NOT NULL,
+    event           TEXT NOT NULL,
+    attempt         INT NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME(6) NOT NULL,
+    INDEX webhook_jobs_next_attempt_at_idx (next_attempt_at),
+    CONSTRAINT fk_webhook_jobs_webhook FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    idempotency_key VARCHAR(255) PRIMARY KEY,
+    fingerprint     CHAR(64) NOT NULL,
+    status          INT NOT NULL DEFAULT 0,
+    content_type    VARCHAR(255) NOT NULL DEFAULT '',
+    body            MEDIUMBLOB,
+    created_at      DATETIME(6) NOT NULL,
+    expires_at      DATETIME(6) NOT NULL,
+    INDEX idempotency_keys_expires_at_idx (expires_at)
+);
@@ -0,0 +1 @@
+CREATE UNIQUE INDEX books_isbn_idx ON books (isbn);
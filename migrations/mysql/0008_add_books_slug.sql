@@ -0,0 +1,3 @@
+ALTER TABLE books ADD COLUMN slug VARCHAR(255) NULL;
+
+CREATE UNIQUE INDEX books_slug_idx ON books (slug);
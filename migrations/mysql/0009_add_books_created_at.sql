@@ -0,0 +1,3 @@
+ALTER TABLE books ADD COLUMN created_at DATETIME(6) NULL;
+
+CREATE INDEX books_created_at_idx ON books (created_at);
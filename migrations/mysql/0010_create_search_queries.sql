@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS search_queries (
+    id         BIGINT AUTO_INCREMENT PRIMARY KEY,
+    term       VARCHAR(255) NOT NULL,
+    results    INT NOT NULL,
+    latency_us BIGINT NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+    INDEX search_queries_created_at_idx (created_at)
+);
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS notifications (
+    id              INT AUTO_INCREMENT PRIMARY KEY,
+    channel         VARCHAR(32) NOT NULL,
+    recipient       VARCHAR(255) NOT NULL,
+    kind            VARCHAR(64) NOT NULL,
+    subject         TEXT NOT NULL,
+    body            TEXT NOT NULL,
+    attempt         INT NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME(6) NOT NULL,
+    created_at      DATETIME(6) NOT NULL,
+    INDEX notifications_next_attempt_at_idx (next_attempt_at)
+);
+
+CREATE TABLE IF NOT EXISTS notification_failures (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    channel    VARCHAR(32) NOT NULL,
+    recipient  VARCHAR(255) NOT NULL,
+    kind       VARCHAR(64) NOT NULL,
+    subject    TEXT NOT NULL,
+    body       TEXT NOT NULL,
+    attempts   INT NOT NULL,
+    error      TEXT NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+    failed_at  DATETIME(6) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    recipient VARCHAR(255) NOT NULL,
+    kind      VARCHAR(64) NOT NULL,
+    enabled   BOOLEAN NOT NULL,
+    PRIMARY KEY (recipient, kind)
+);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS push_subscriptions (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    endpoint   VARCHAR(2048) NOT NULL,
+    p256dh     VARCHAR(255) NOT NULL,
+    auth       VARCHAR(64) NOT NULL,
+    recipient  VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME(6) NOT NULL,
+    INDEX push_subscriptions_endpoint_idx (endpoint(255)),
+    INDEX push_subscriptions_recipient_idx (recipient)
+);
+
+CREATE TABLE IF NOT EXISTS push_watches (
+    subscription_id INT NOT NULL,
+    book_id         INT NOT NULL,
+    price           DECIMAL(10, 2) NOT NULL,
+    PRIMARY KEY (subscription_id, book_id),
+    INDEX push_watches_book_id_idx (book_id)
+);
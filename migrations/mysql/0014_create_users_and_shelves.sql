@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS users (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    email      VARCHAR(255) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+    UNIQUE INDEX users_email_idx (email),
+    UNIQUE INDEX users_token_hash_idx (token_hash)
+);
+
+CREATE TABLE IF NOT EXISTS shelf_items (
+    user_id  INT NOT NULL,
+    book_id  INT NOT NULL,
+    rating   TINYINT,
+    added_at DATETIME(6) NOT NULL,
+    PRIMARY KEY (user_id, book_id),
+    INDEX shelf_items_book_id_idx (book_id),
+    CONSTRAINT fk_shelf_items_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_shelf_items_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS recommendations (
+    user_id         INT NOT NULL,
+    book_id         INT NOT NULL,
+    score           DOUBLE NOT NULL,
+    because_book_id INT NOT NULL,
+    PRIMARY KEY (user_id, book_id),
+    CONSTRAINT fk_recommendations_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_recommendations_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS book_categories (
+    book_id INT NOT NULL,
+    name    VARCHAR(100) NOT NULL,
+    PRIMARY KEY (book_id, name),
+    INDEX book_categories_name_idx (name),
+    CONSTRAINT fk_book_categories_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS book_tags (
+    book_id INT NOT NULL,
+    name    VARCHAR(100) NOT NULL,
+    PRIMARY KEY (book_id, name),
+    INDEX book_tags_name_idx (name),
+    CONSTRAINT fk_book_tags_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS book_activity (
+    book_id INT NOT NULL,
+    day     DATE NOT NULL,
+    views   INT NOT NULL DEFAULT 0,
+    loans   INT NOT NULL DEFAULT 0,
+    sales   INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (book_id, day),
+    INDEX book_activity_day_idx (day),
+    CONSTRAINT fk_book_activity_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
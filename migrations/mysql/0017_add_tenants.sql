@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS tenants (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    slug       VARCHAR(63) NOT NULL,
+    name       VARCHAR(255) NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+    UNIQUE INDEX tenants_slug_idx (slug)
+);
+
+-- Everything from before tenants belongs to the default tenant.
+INSERT INTO tenants (id, slug, name, created_at) VALUES (1, 'default', 'Default', UTC_TIMESTAMP(6));
+
+ALTER TABLE books ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+ALTER TABLE reviews ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+ALTER TABLE webhooks ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+ALTER TABLE webhook_deliveries ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+ALTER TABLE webhook_jobs ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+ALTER TABLE outbox ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+ALTER TABLE search_queries ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+ALTER TABLE notifications ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+ALTER TABLE notification_failures ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+ALTER TABLE push_subscriptions ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+ALTER TABLE push_watches ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+ALTER TABLE shelf_items ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+ALTER TABLE recommendations ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+ALTER TABLE book_categories ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+ALTER TABLE book_tags ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+ALTER TABLE book_activity ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+ALTER TABLE idempotency_keys ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+ALTER TABLE notification_preferences ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+ALTER TABLE notification_contacts ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+
+ALTER TABLE users ADD COLUMN admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- ISBNs, slugs, email addresses and the keys below only have to be
+-- unique within a tenant.
+ALTER TABLE books DROP INDEX books_isbn_idx, ADD UNIQUE INDEX books_isbn_idx (tenant_id, isbn);
+ALTER TABLE books DROP INDEX books_slug_idx, ADD UNIQUE INDEX books_slug_idx (tenant_id, slug);
+ALTER TABLE users DROP INDEX users_email_idx, ADD UNIQUE INDEX users_email_idx (tenant_id, email);
+ALTER TABLE idempotency_keys DROP PRIMARY KEY, ADD PRIMARY KEY (tenant_id, idempotency_key);
+ALTER TABLE notification_preferences DROP PRIMARY KEY, ADD PRIMARY KEY (tenant_id, recipient, kind);
+ALTER TABLE notification_contacts DROP PRIMARY KEY, ADD PRIMARY KEY (tenant_id, recipient);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS collections (
+    id          INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id   INT NOT NULL DEFAULT 1,
+    user_id     INT NOT NULL,
+    name        VARCHAR(100) NOT NULL,
+    share_token CHAR(32),
+    created_at  DATETIME(6) NOT NULL,
+    INDEX collections_user_id_idx (user_id),
+    UNIQUE INDEX collections_share_token_idx (share_token),
+    CONSTRAINT fk_collections_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS collection_items (
+    tenant_id     INT NOT NULL DEFAULT 1,
+    collection_id INT NOT NULL,
+    book_id       INT NOT NULL,
+    position      INT NOT NULL,
+    PRIMARY KEY (collection_id, book_id),
+    INDEX collection_items_book_id_idx (book_id),
+    CONSTRAINT fk_collection_items_collection FOREIGN KEY (collection_id) REFERENCES collections (id) ON DELETE CASCADE,
+    CONSTRAINT fk_collection_items_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS series (
+    id          INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id   INT NOT NULL DEFAULT 1,
+    name        VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL,
+    created_at  DATETIME(6) NOT NULL
+);
+
+-- A book is in at most one series.
+CREATE TABLE IF NOT EXISTS series_books (
+    tenant_id INT NOT NULL DEFAULT 1,
+    book_id   INT PRIMARY KEY,
+    series_id INT NOT NULL,
+    position  DOUBLE NOT NULL,
+    INDEX series_books_series_id_idx (series_id, position),
+    CONSTRAINT fk_series_books_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE,
+    CONSTRAINT fk_series_books_series FOREIGN KEY (series_id) REFERENCES series (id) ON DELETE CASCADE
+);
@@ -0,0 +1,18 @@
+ALTER TABLE books ADD COLUMN publisher VARCHAR(255);
+
+CREATE TABLE IF NOT EXISTS works (
+    id        INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id INT NOT NULL DEFAULT 1,
+    title     VARCHAR(255) NOT NULL,
+    author    VARCHAR(255) NOT NULL
+);
+
+-- A book is an edition of at most one work.
+CREATE TABLE IF NOT EXISTS work_editions (
+    tenant_id INT NOT NULL DEFAULT 1,
+    book_id   INT PRIMARY KEY,
+    work_id   INT NOT NULL,
+    INDEX work_editions_work_id_idx (work_id),
+    CONSTRAINT fk_work_editions_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE,
+    CONSTRAINT fk_work_editions_work FOREIGN KEY (work_id) REFERENCES works (id) ON DELETE CASCADE
+);
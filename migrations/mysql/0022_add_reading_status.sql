@@ -0,0 +1,18 @@
+ALTER TABLE shelf_items ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'to-read';
+
+-- Every status a shelved book has had, starting with the one it was
+-- shelved with.
+CREATE TABLE IF NOT EXISTS reading_status_changes (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id  INT NOT NULL DEFAULT 1,
+    user_id    INT NOT NULL,
+    book_id    INT NOT NULL,
+    status     VARCHAR(16) NOT NULL,
+    changed_at DATETIME(6) NOT NULL,
+    INDEX reading_status_changes_user_id_idx (user_id, book_id),
+    CONSTRAINT fk_reading_status_changes_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_reading_status_changes_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
+
+INSERT INTO reading_status_changes (tenant_id, user_id, book_id, status, changed_at)
+SELECT tenant_id, user_id, book_id, status, added_at FROM shelf_items ORDER BY added_at;
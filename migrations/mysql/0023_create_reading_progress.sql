@@ -0,0 +1,14 @@
+-- Every update of how far readers have got in the books on their shelves.
+CREATE TABLE IF NOT EXISTS reading_progress (
+    id          INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id   INT NOT NULL DEFAULT 1,
+    user_id     INT NOT NULL,
+    book_id     INT NOT NULL,
+    page        INT,
+    pages       INT,
+    percent     DOUBLE,
+    recorded_at DATETIME(6) NOT NULL,
+    INDEX reading_progress_user_id_idx (user_id, book_id),
+    CONSTRAINT fk_reading_progress_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_reading_progress_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS reading_goals (
+    tenant_id INT NOT NULL DEFAULT 1,
+    user_id   INT NOT NULL,
+    year      INT NOT NULL,
+    unit      VARCHAR(16) NOT NULL,
+    target    INT NOT NULL,
+    PRIMARY KEY (user_id, year),
+    CONSTRAINT fk_reading_goals_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS notes (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id  INT NOT NULL DEFAULT 1,
+    user_id    INT NOT NULL,
+    book_id    INT NOT NULL,
+    kind       VARCHAR(16) NOT NULL,
+    body       TEXT NOT NULL,
+    page       INT,
+    created_at DATETIME(6) NOT NULL,
+    updated_at DATETIME(6) NOT NULL,
+    INDEX notes_user_id_idx (user_id, created_at),
+    CONSTRAINT fk_notes_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_notes_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS quotes (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id  INT NOT NULL DEFAULT 1,
+    user_id    INT NOT NULL,
+    book_id    INT NOT NULL,
+    body       TEXT NOT NULL,
+    author     VARCHAR(255) NOT NULL,
+    public     BOOLEAN NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+    INDEX quotes_user_id_idx (user_id),
+    INDEX quotes_public_idx (tenant_id, public),
+    CONSTRAINT fk_quotes_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_quotes_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
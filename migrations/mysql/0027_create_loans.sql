@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS loans (
+    id          INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id   INT NOT NULL DEFAULT 1,
+    member_id   INT NOT NULL,
+    book_id     INT NOT NULL,
+    borrowed_at DATETIME(6) NOT NULL,
+    due_at      DATETIME(6) NOT NULL,
+    returned_at DATETIME(6),
+    INDEX loans_member_id_idx (member_id, borrowed_at),
+    CONSTRAINT fk_loans_member FOREIGN KEY (member_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_loans_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
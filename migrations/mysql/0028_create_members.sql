@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS members (
+    id          INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id   INT NOT NULL DEFAULT 1,
+    card_number VARCHAR(32) NOT NULL,
+    name        VARCHAR(255) NOT NULL,
+    email       VARCHAR(255) NOT NULL,
+    phone       VARCHAR(16) NOT NULL,
+    address     TEXT NOT NULL,
+    tier        VARCHAR(16) NOT NULL,
+    expires_at  DATETIME(6),
+    user_id     INT,
+    created_at  DATETIME(6) NOT NULL,
+    UNIQUE INDEX members_card_number_idx (tenant_id, card_number),
+    INDEX members_name_idx (tenant_id, name),
+    CONSTRAINT fk_members_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL
+);
+
+-- Loans were made to users; each user who borrowed becomes a member,
+-- linked to their account, with their id as card number.
+INSERT INTO members (tenant_id, card_number, name, email, phone, address, tier, expires_at, user_id, created_at)
+SELECT tenant_id, LPAD(id, 10, '0'), name, email, '', '', 'standard', NULL, id, created_at
+FROM users WHERE id IN (SELECT member_id FROM loans);
+
+ALTER TABLE loans DROP FOREIGN KEY fk_loans_member;
+UPDATE loans JOIN members ON members.user_id = loans.member_id SET loans.member_id = members.id;
+ALTER TABLE loans ADD CONSTRAINT fk_loans_member FOREIGN KEY (member_id) REFERENCES members (id) ON DELETE CASCADE;
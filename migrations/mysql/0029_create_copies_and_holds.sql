@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS copies (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id  INT NOT NULL DEFAULT 1,
+    book_id    INT NOT NULL,
+    barcode    VARCHAR(64) NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+    UNIQUE INDEX copies_barcode_idx (tenant_id, barcode),
+    CONSTRAINT fk_copies_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
+
+ALTER TABLE loans ADD COLUMN copy_id INT,
+    ADD CONSTRAINT fk_loans_copy FOREIGN KEY (copy_id) REFERENCES copies (id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS holds (
+    id        INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id INT NOT NULL DEFAULT 1,
+    member_id INT NOT NULL,
+    book_id   INT NOT NULL,
+    placed_at DATETIME(6) NOT NULL,
+    ready_at  DATETIME(6),
+    INDEX holds_book_id_idx (book_id, placed_at),
+    CONSTRAINT fk_holds_member FOREIGN KEY (member_id) REFERENCES members (id) ON DELETE CASCADE,
+    CONSTRAINT fk_holds_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS fines (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id  INT NOT NULL DEFAULT 1,
+    member_id  INT NOT NULL,
+    loan_id    INT,
+    reason     VARCHAR(255) NOT NULL,
+    amount     DECIMAL(10, 2) NOT NULL,
+    settled_at DATETIME(6),
+    created_at DATETIME(6) NOT NULL,
+    INDEX fines_member_id_idx (member_id, created_at),
+    CONSTRAINT fk_fines_member FOREIGN KEY (member_id) REFERENCES members (id) ON DELETE CASCADE,
+    CONSTRAINT fk_fines_loan FOREIGN KEY (loan_id) REFERENCES loans (id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS fine_payments (
+    id        INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id INT NOT NULL DEFAULT 1,
+    fine_id   INT NOT NULL,
+    amount    DECIMAL(10, 2) NOT NULL,
+    method    VARCHAR(16) NOT NULL,
+    reference VARCHAR(255) NOT NULL,
+    paid_at   DATETIME(6) NOT NULL,
+    CONSTRAINT fk_fine_payments_fine FOREIGN KEY (fine_id) REFERENCES fines (id) ON DELETE CASCADE
+);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS donations (
+    id              INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id       INT NOT NULL DEFAULT 1,
+    donor_name      VARCHAR(255) NOT NULL,
+    donor_email     VARCHAR(255) NOT NULL,
+    donor_address   TEXT NOT NULL,
+    title           VARCHAR(255) NOT NULL,
+    author          VARCHAR(255) NOT NULL,
+    isbn            VARCHAR(17) NOT NULL,
+    book_condition  VARCHAR(16) NOT NULL,
+    appraised_value DECIMAL(10, 2) NOT NULL,
+    status          VARCHAR(16) NOT NULL,
+    note            TEXT NOT NULL,
+    book_id         INT,
+    received_at     DATETIME(6) NOT NULL,
+    decided_at      DATETIME(6),
+    INDEX donations_status_idx (status, received_at),
+    CONSTRAINT fk_donations_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE SET NULL
+);
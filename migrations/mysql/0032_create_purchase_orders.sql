@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS suppliers (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id  INT NOT NULL DEFAULT 1,
+    name       VARCHAR(255) NOT NULL,
+    email      VARCHAR(255) NOT NULL,
+    phone      VARCHAR(16) NOT NULL,
+    created_at DATETIME(6) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS purchase_orders (
+    id          INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id   INT NOT NULL DEFAULT 1,
+    supplier_id INT NOT NULL,
+    reference   VARCHAR(64) NOT NULL,
+    status      VARCHAR(16) NOT NULL,
+    ordered_at  DATETIME(6) NOT NULL,
+    closed_at   DATETIME(6),
+    INDEX purchase_orders_status_idx (status, ordered_at),
+    CONSTRAINT fk_purchase_orders_supplier FOREIGN KEY (supplier_id) REFERENCES suppliers (id)
+);
+
+CREATE TABLE IF NOT EXISTS purchase_order_lines (
+    id        INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id INT NOT NULL DEFAULT 1,
+    order_id  INT NOT NULL,
+    book_id   INT NOT NULL,
+    quantity  INT NOT NULL,
+    unit_cost DECIMAL(10, 2) NOT NULL,
+    received  INT NOT NULL DEFAULT 0,
+    CONSTRAINT fk_purchase_order_lines_order FOREIGN KEY (order_id) REFERENCES purchase_orders (id) ON DELETE CASCADE,
+    CONSTRAINT fk_purchase_order_lines_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
+
+ALTER TABLE books ADD COLUMN stock INT NOT NULL DEFAULT 0;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS supplier_prices (
+    tenant_id      INT NOT NULL DEFAULT 1,
+    supplier_id    INT NOT NULL,
+    book_id        INT NOT NULL,
+    unit_cost      DECIMAL(10, 2) NOT NULL,
+    lead_time_days INT NOT NULL,
+    updated_at     DATETIME(6) NOT NULL,
+    PRIMARY KEY (supplier_id, book_id),
+    INDEX supplier_prices_book_id_idx (book_id, unit_cost),
+    CONSTRAINT fk_supplier_prices_supplier FOREIGN KEY (supplier_id) REFERENCES suppliers (id) ON DELETE CASCADE,
+    CONSTRAINT fk_supplier_prices_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS sales (
+    id        INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id INT NOT NULL DEFAULT 1,
+    total     DECIMAL(16, 2) NOT NULL,
+    sold_at   DATETIME(6) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS sale_lines (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id  INT NOT NULL DEFAULT 1,
+    sale_id    INT NOT NULL,
+    book_id    INT,
+    title      VARCHAR(255) NOT NULL,
+    quantity   INT NOT NULL,
+    unit_price DECIMAL(10, 2) NOT NULL,
+    amount     DECIMAL(14, 2) NOT NULL,
+    day        DATE NOT NULL,
+    INDEX sale_lines_day_idx (tenant_id, day),
+    CONSTRAINT fk_sale_lines_sale FOREIGN KEY (sale_id) REFERENCES sales (id) ON DELETE CASCADE,
+    CONSTRAINT fk_sale_lines_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE SET NULL
+);
@@ -0,0 +1,5 @@
+ALTER TABLE sales ADD COLUMN region VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE sales ADD COLUMN tax_included BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE sale_lines ADD COLUMN tax_name VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE sale_lines ADD COLUMN tax_rate DECIMAL(6, 5) NOT NULL DEFAULT 0;
+ALTER TABLE sale_lines ADD COLUMN tax DECIMAL(14, 2) NOT NULL DEFAULT 0;
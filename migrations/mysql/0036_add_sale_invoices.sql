@@ -0,0 +1,15 @@
+ALTER TABLE sales ADD COLUMN customer_name VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE sales ADD COLUMN customer_address TEXT NOT NULL;
+ALTER TABLE sales ADD COLUMN invoice_number INT NOT NULL DEFAULT 0;
+
+UPDATE sales JOIN (SELECT later.id, COUNT(*) AS n FROM sales later JOIN sales earlier ON earlier.tenant_id = later.tenant_id AND earlier.id <= later.id GROUP BY later.id) numbered ON numbered.id = sales.id
+SET sales.invoice_number = numbered.n;
+
+CREATE UNIQUE INDEX sales_invoice_number_idx ON sales (tenant_id, invoice_number);
+
+CREATE TABLE IF NOT EXISTS invoice_numbers (
+    tenant_id   INT PRIMARY KEY,
+    last_number INT NOT NULL
+);
+
+INSERT INTO invoice_numbers (tenant_id, last_number) SELECT tenant_id, MAX(invoice_number) FROM sales GROUP BY tenant_id;
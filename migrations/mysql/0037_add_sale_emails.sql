@@ -0,0 +1,6 @@
+ALTER TABLE notifications ADD COLUMN html TEXT NOT NULL;
+
+ALTER TABLE sales ADD COLUMN customer_email VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE sales ADD COLUMN shipped_at DATETIME(6) NULL;
+ALTER TABLE sales ADD COLUMN carrier VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE sales ADD COLUMN tracking_number VARCHAR(64) NOT NULL DEFAULT '';
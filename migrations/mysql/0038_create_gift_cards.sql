@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS gift_cards (
+    id        INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id INT NOT NULL DEFAULT 1,
+    code      VARCHAR(19) NOT NULL,
+    amount    DECIMAL(10, 2) NOT NULL,
+    balance   DECIMAL(10, 2) NOT NULL,
+    note      TEXT NOT NULL,
+    issued_at DATETIME(6) NOT NULL,
+    voided_at DATETIME(6) NULL,
+    UNIQUE INDEX gift_cards_code_idx (tenant_id, code)
+);
+
+CREATE TABLE IF NOT EXISTS gift_card_redemptions (
+    id           INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id    INT NOT NULL DEFAULT 1,
+    gift_card_id INT NOT NULL,
+    sale_id      INT NOT NULL,
+    amount       DECIMAL(10, 2) NOT NULL,
+    redeemed_at  DATETIME(6) NOT NULL,
+    CONSTRAINT fk_gift_card_redemptions_card FOREIGN KEY (gift_card_id) REFERENCES gift_cards (id) ON DELETE CASCADE,
+    CONSTRAINT fk_gift_card_redemptions_sale FOREIGN KEY (sale_id) REFERENCES sales (id) ON DELETE CASCADE
+);
+
+ALTER TABLE sales ADD COLUMN gift_card_amount DECIMAL(16, 2) NOT NULL DEFAULT 0;
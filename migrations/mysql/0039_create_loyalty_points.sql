@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS loyalty_points (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id  INT NOT NULL DEFAULT 1,
+    user_id    INT NOT NULL,
+    kind       VARCHAR(16) NOT NULL,
+    points     INT NOT NULL,
+    remaining  INT NOT NULL DEFAULT 0,
+    sale_id    INT NULL,
+    expires_at DATETIME(6) NULL,
+    created_at DATETIME(6) NOT NULL,
+    INDEX loyalty_points_user_id_idx (user_id),
+    CONSTRAINT fk_loyalty_points_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_loyalty_points_sale FOREIGN KEY (sale_id) REFERENCES sales (id) ON DELETE SET NULL
+);
+
+ALTER TABLE sales ADD COLUMN user_id INT NULL,
+    ADD CONSTRAINT fk_sales_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL;
+
+ALTER TABLE sales ADD COLUMN points_redeemed INT NOT NULL DEFAULT 0;
+
+ALTER TABLE sales ADD COLUMN points_discount DECIMAL(16, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE sales ADD COLUMN points_earned INT NOT NULL DEFAULT 0;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS wishlist_items (
+    tenant_id     INT NOT NULL DEFAULT 1,
+    user_id       INT NOT NULL,
+    book_id       INT NOT NULL,
+    target_price  DECIMAL(10, 2) NULL,
+    added_at      DATETIME(6) NOT NULL,
+    alerted_price DECIMAL(10, 2) NULL,
+    alerted_at    DATETIME(6) NULL,
+    PRIMARY KEY (user_id, book_id),
+    INDEX wishlist_items_book_id_idx (book_id),
+    CONSTRAINT fk_wishlist_items_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_wishlist_items_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS book_prices (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id  INT NOT NULL DEFAULT 1,
+    book_id    INT NOT NULL,
+    price      DECIMAL(10, 2) NOT NULL,
+    changed_at DATETIME(6) NOT NULL,
+    INDEX book_prices_book_id_idx (book_id),
+    CONSTRAINT fk_book_prices_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE CASCADE
+);
+
+INSERT INTO book_prices (tenant_id, book_id, price, changed_at)
+SELECT tenant_id, id, price, COALESCE(created_at, CURRENT_TIMESTAMP(6)) FROM books;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS book_merges (
+    tenant_id   INT NOT NULL DEFAULT 1,
+    book_id     INT NOT NULL,
+    survivor_id INT NOT NULL,
+    merged_at   DATETIME(6) NOT NULL,
+    PRIMARY KEY (tenant_id, book_id),
+    INDEX book_merges_survivor_id_idx (survivor_id),
+    CONSTRAINT fk_book_merges_survivor FOREIGN KEY (survivor_id) REFERENCES books (id) ON DELETE CASCADE
+);
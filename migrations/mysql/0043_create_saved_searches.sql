@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id           INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id    INT NOT NULL DEFAULT 1,
+    user_id      INT NOT NULL,
+    name         VARCHAR(100) NOT NULL,
+    search_query VARCHAR(200) NOT NULL,
+    category     VARCHAR(100) NOT NULL,
+    format       VARCHAR(16) NOT NULL,
+    max_price    DECIMAL(10, 2),
+    seen_book_id INT NOT NULL,
+    created_at   DATETIME(6) NOT NULL,
+    notified_at  DATETIME(6),
+    INDEX saved_searches_user_id_idx (user_id),
+    CONSTRAINT fk_saved_searches_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS branches (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id  INT NOT NULL DEFAULT 1,
+    name       VARCHAR(255) NOT NULL,
+    address    VARCHAR(500) NOT NULL DEFAULT '',
+    latitude   DOUBLE NOT NULL,
+    longitude  DOUBLE NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+    INDEX branches_tenant_id_idx (tenant_id, name)
+);
+
+ALTER TABLE copies ADD COLUMN branch_id INT,
+    ADD CONSTRAINT fk_copies_branch FOREIGN KEY (branch_id) REFERENCES branches (id) ON DELETE SET NULL;
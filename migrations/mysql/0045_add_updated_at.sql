@@ -0,0 +1,7 @@
+ALTER TABLE books ADD COLUMN updated_at DATETIME(6) NULL;
+
+UPDATE books SET updated_at = created_at;
+
+CREATE INDEX books_updated_at_idx ON books (updated_at);
+
+ALTER TABLE reviews ADD COLUMN created_at DATETIME(6) NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE books DROP INDEX books_created_at_idx, ADD INDEX books_created_at_idx (tenant_id, created_at, id);
+ALTER TABLE books DROP INDEX books_updated_at_idx, ADD INDEX books_updated_at_idx (tenant_id, updated_at, id);
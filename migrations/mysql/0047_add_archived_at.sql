@@ -0,0 +1,3 @@
+ALTER TABLE books ADD COLUMN archived_at DATETIME(6) NULL;
+
+CREATE INDEX books_archived_at_idx ON books (tenant_id, archived_at);
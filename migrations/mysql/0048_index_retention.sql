@@ -0,0 +1,2 @@
+CREATE INDEX webhook_deliveries_created_at_idx ON webhook_deliveries (created_at);
+CREATE INDEX notification_failures_failed_at_idx ON notification_failures (failed_at);
@@ -0,0 +1 @@
+ALTER TABLE search_queries ADD COLUMN visitor VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS member_access_log (
+    id          INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id   INT NOT NULL DEFAULT 1,
+    member_id   INT NULL,
+    actor       VARCHAR(64) NOT NULL,
+    data        VARCHAR(32) NOT NULL,
+    accessed_at DATETIME(6) NOT NULL,
+    INDEX member_access_log_accessed_at_idx (tenant_id, accessed_at),
+    INDEX member_access_log_member_id_idx (tenant_id, member_id, accessed_at)
+);
@@ -0,0 +1,2 @@
+-- Encrypted contact details are longer than the plain ones.
+ALTER TABLE members MODIFY email TEXT NOT NULL, MODIFY phone TEXT NOT NULL;
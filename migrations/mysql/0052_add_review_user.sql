@@ -0,0 +1,4 @@
+-- The account a review was posted with. Reviews from before have none:
+-- they were only signed with a name, which doesn't tell whose they are.
+ALTER TABLE reviews ADD COLUMN user_id INT,
+    ADD CONSTRAINT fk_reviews_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL;
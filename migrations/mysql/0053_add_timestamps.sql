@@ -0,0 +1,85 @@
+-- Every record the API shows has the times the store added it and last
+-- changed it. Records with no earlier time to go by have none until they
+-- change.
+
+ALTER TABLE branches ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE branches SET updated_at = created_at;
+
+ALTER TABLE series ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE series SET updated_at = created_at;
+
+ALTER TABLE works ADD COLUMN created_at DATETIME(6) NULL;
+ALTER TABLE works ADD COLUMN updated_at DATETIME(6) NULL;
+
+ALTER TABLE quotes ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE quotes SET updated_at = created_at;
+
+ALTER TABLE shelf_items ADD COLUMN created_at DATETIME(6) NULL;
+ALTER TABLE shelf_items ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE shelf_items SET created_at = added_at, updated_at = added_at;
+
+ALTER TABLE wishlist_items ADD COLUMN created_at DATETIME(6) NULL;
+ALTER TABLE wishlist_items ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE wishlist_items SET created_at = added_at, updated_at = COALESCE(alerted_at, added_at);
+
+ALTER TABLE saved_searches ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE saved_searches SET updated_at = COALESCE(notified_at, created_at);
+
+ALTER TABLE reading_goals ADD COLUMN created_at DATETIME(6) NULL;
+ALTER TABLE reading_goals ADD COLUMN updated_at DATETIME(6) NULL;
+
+ALTER TABLE collections ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE collections SET updated_at = created_at;
+
+ALTER TABLE tenants ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE tenants SET updated_at = created_at;
+
+ALTER TABLE users ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE users SET updated_at = created_at;
+
+ALTER TABLE members ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE members SET updated_at = created_at;
+
+ALTER TABLE loans ADD COLUMN created_at DATETIME(6) NULL;
+ALTER TABLE loans ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE loans SET created_at = borrowed_at, updated_at = COALESCE(returned_at, borrowed_at);
+
+ALTER TABLE copies ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE copies SET updated_at = created_at;
+
+ALTER TABLE holds ADD COLUMN created_at DATETIME(6) NULL;
+ALTER TABLE holds ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE holds SET created_at = placed_at, updated_at = COALESCE(ready_at, placed_at);
+
+ALTER TABLE fines ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE fines SET updated_at = COALESCE(settled_at,
+    (SELECT MAX(paid_at) FROM fine_payments WHERE fine_payments.fine_id = fines.id), created_at);
+
+ALTER TABLE donations ADD COLUMN created_at DATETIME(6) NULL;
+ALTER TABLE donations ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE donations SET created_at = received_at, updated_at = COALESCE(decided_at, received_at);
+
+ALTER TABLE suppliers ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE suppliers SET updated_at = created_at;
+
+ALTER TABLE supplier_prices ADD COLUMN created_at DATETIME(6) NULL;
+UPDATE supplier_prices SET created_at = updated_at;
+
+ALTER TABLE purchase_orders ADD COLUMN created_at DATETIME(6) NULL;
+ALTER TABLE purchase_orders ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE purchase_orders SET created_at = ordered_at, updated_at = COALESCE(closed_at, ordered_at);
+
+ALTER TABLE sales ADD COLUMN created_at DATETIME(6) NULL;
+ALTER TABLE sales ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE sales SET created_at = sold_at, updated_at = COALESCE(shipped_at, sold_at);
+
+ALTER TABLE gift_cards ADD COLUMN created_at DATETIME(6) NULL;
+ALTER TABLE gift_cards ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE gift_cards SET created_at = issued_at, updated_at = COALESCE(voided_at,
+    (SELECT MAX(redeemed_at) FROM gift_card_redemptions WHERE gift_card_redemptions.gift_card_id = gift_cards.id), issued_at);
+
+ALTER TABLE webhooks ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE webhooks SET updated_at = created_at;
+
+ALTER TABLE push_subscriptions ADD COLUMN updated_at DATETIME(6) NULL;
+UPDATE push_subscriptions SET updated_at = created_at;
@@ -0,0 +1,10 @@
+-- Deleting a member erases their details and keeps their row, so their
+-- loans, fines and payments stay on record.
+ALTER TABLE members ADD COLUMN deleted_at DATETIME(6);
+
+ALTER TABLE loans DROP FOREIGN KEY fk_loans_member;
+ALTER TABLE loans ADD CONSTRAINT fk_loans_member FOREIGN KEY (member_id) REFERENCES members (id) ON DELETE RESTRICT;
+ALTER TABLE fines DROP FOREIGN KEY fk_fines_member;
+ALTER TABLE fines ADD CONSTRAINT fk_fines_member FOREIGN KEY (member_id) REFERENCES members (id) ON DELETE RESTRICT;
+ALTER TABLE fine_payments DROP FOREIGN KEY fk_fine_payments_fine;
+ALTER TABLE fine_payments ADD CONSTRAINT fk_fine_payments_fine FOREIGN KEY (fine_id) REFERENCES fines (id) ON DELETE RESTRICT;
@@ -0,0 +1,7 @@
+-- Order lines outlive their books, as sale lines do, keeping the title
+-- the book had.
+ALTER TABLE purchase_order_lines ADD COLUMN title VARCHAR(255) NOT NULL DEFAULT '';
+UPDATE purchase_order_lines JOIN books ON books.id = purchase_order_lines.book_id SET purchase_order_lines.title = books.title;
+ALTER TABLE purchase_order_lines DROP FOREIGN KEY fk_purchase_order_lines_book;
+ALTER TABLE purchase_order_lines MODIFY book_id INT NULL;
+ALTER TABLE purchase_order_lines ADD CONSTRAINT fk_purchase_order_lines_book FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE SET NULL;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS books (
+    id     SERIAL PRIMARY KEY,
+    title  VARCHAR(255) NOT NULL,
+    author VARCHAR(255) NOT NULL,
+    price  NUMERIC(10, 2) NOT NULL DEFAULT 0
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS reviews (
+    id       SERIAL PRIMARY KEY,
+    book_id  INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    reviewer VARCHAR(255) NOT NULL,
+    rating   SMALLINT NOT NULL,
+    body     TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS reviews_book_id_idx ON reviews (book_id);
@@ -0,0 +1 @@
+ALTER TABLE books ADD COLUMN IF NOT EXISTS isbn VARCHAR(17);
@@ -0,0 +1 @@
+CREATE UNIQUE INDEX IF NOT EXISTS books_isbn_idx ON books (isbn);
@@ -0,0 +1,3 @@
+ALTER TABLE books ADD COLUMN IF NOT EXISTS slug VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS books_slug_idx ON books (slug);
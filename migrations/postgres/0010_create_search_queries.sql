@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS search_queries (
+    id         BIGSERIAL PRIMARY KEY,
+    term       VARCHAR(255) NOT NULL,
+    results    INTEGER NOT NULL,
+    latency_us BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS search_queries_created_at_idx ON search_queries (created_at);
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS notifications (
+    id              SERIAL PRIMARY KEY,
+    channel         VARCHAR(32) NOT NULL,
+    recipient       VARCHAR(255) NOT NULL,
+    kind            VARCHAR(64) NOT NULL,
+    subject         TEXT NOT NULL,
+    body            TEXT NOT NULL,
+    attempt         INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS notifications_next_attempt_at_idx ON notifications (next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS notification_failures (
+    id         SERIAL PRIMARY KEY,
+    channel    VARCHAR(32) NOT NULL,
+    recipient  VARCHAR(255) NOT NULL,
+    kind       VARCHAR(64) NOT NULL,
+    subject    TEXT NOT NULL,
+    body       TEXT NOT NULL,
+    attempts   INTEGER NOT NULL,
+    error      TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    failed_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    recipient VARCHAR(255) NOT NULL,
+    kind      VARCHAR(64) NOT NULL,
+    enabled   BOOLEAN NOT NULL,
+    PRIMARY KEY (recipient, kind)
+);
@@ -0,0 +1,6 @@
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS channel VARCHAR(32) NOT NULL DEFAULT 'email';
+
+CREATE TABLE IF NOT EXISTS notification_contacts (
+    recipient VARCHAR(255) PRIMARY KEY,
+    phone     VARCHAR(16) NOT NULL
+);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS push_subscriptions (
+    id         SERIAL PRIMARY KEY,
+    endpoint   VARCHAR(2048) NOT NULL,
+    p256dh     VARCHAR(255) NOT NULL,
+    auth       VARCHAR(64) NOT NULL,
+    recipient  VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS push_subscriptions_endpoint_idx ON push_subscriptions (endpoint);
+CREATE INDEX IF NOT EXISTS push_subscriptions_recipient_idx ON push_subscriptions (recipient);
+
+CREATE TABLE IF NOT EXISTS push_watches (
+    subscription_id INTEGER NOT NULL,
+    book_id         INTEGER NOT NULL,
+    price           NUMERIC(10, 2) NOT NULL,
+    PRIMARY KEY (subscription_id, book_id)
+);
+
+CREATE INDEX IF NOT EXISTS push_watches_book_id_idx ON push_watches (book_id);
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS users (
+    id         SERIAL PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    email      VARCHAR(255) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_idx ON users (email);
+CREATE UNIQUE INDEX IF NOT EXISTS users_token_hash_idx ON users (token_hash);
+
+CREATE TABLE IF NOT EXISTS shelf_items (
+    user_id  INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    book_id  INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    rating   SMALLINT,
+    added_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, book_id)
+);
+
+CREATE INDEX IF NOT EXISTS shelf_items_book_id_idx ON shelf_items (book_id);
+
+CREATE TABLE IF NOT EXISTS recommendations (
+    user_id         INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    book_id         INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    score           DOUBLE PRECISION NOT NULL,
+    because_book_id INTEGER NOT NULL,
+    PRIMARY KEY (user_id, book_id)
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS book_categories (
+    book_id INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    name    VARCHAR(100) NOT NULL,
+    PRIMARY KEY (book_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS book_categories_name_idx ON book_categories (name);
+
+CREATE TABLE IF NOT EXISTS book_tags (
+    book_id INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    name    VARCHAR(100) NOT NULL,
+    PRIMARY KEY (book_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS book_tags_name_idx ON book_tags (name);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS book_activity (
+    book_id INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    day     DATE NOT NULL,
+    views   INTEGER NOT NULL DEFAULT 0,
+    loans   INTEGER NOT NULL DEFAULT 0,
+    sales   INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (book_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS book_activity_day_idx ON book_activity (day);
@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS tenants (
+    id         SERIAL PRIMARY KEY,
+    slug       VARCHAR(63) NOT NULL,
+    name       VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS tenants_slug_idx ON tenants (slug);
+
+-- Everything from before tenants belongs to the default tenant.
+INSERT INTO tenants (id, slug, name, created_at) VALUES (1, 'default', 'Default', NOW());
+SELECT setval(pg_get_serial_sequence('tenants', 'id'), 2, false);
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE webhook_jobs ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE search_queries ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE notification_failures ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE push_subscriptions ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE push_watches ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE shelf_items ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE recommendations ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE book_categories ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE book_tags ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE book_activity ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE notification_contacts ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- ISBNs, slugs, email addresses and the keys below only have to be
+-- unique within a tenant.
+DROP INDEX IF EXISTS books_isbn_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS books_isbn_idx ON books (tenant_id, isbn);
+DROP INDEX IF EXISTS books_slug_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS books_slug_idx ON books (tenant_id, slug);
+DROP INDEX IF EXISTS users_email_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_idx ON users (tenant_id, email);
+ALTER TABLE idempotency_keys DROP CONSTRAINT idempotency_keys_pkey, ADD PRIMARY KEY (tenant_id, idempotency_key);
+ALTER TABLE notification_preferences DROP CONSTRAINT notification_preferences_pkey, ADD PRIMARY KEY (tenant_id, recipient, kind);
+ALTER TABLE notification_contacts DROP CONSTRAINT notification_contacts_pkey, ADD PRIMARY KEY (tenant_id, recipient);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS collections (
+    id          SERIAL PRIMARY KEY,
+    tenant_id   INTEGER NOT NULL DEFAULT 1,
+    user_id     INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name        VARCHAR(100) NOT NULL,
+    share_token CHAR(32),
+    created_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS collections_user_id_idx ON collections (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS collections_share_token_idx ON collections (share_token);
+
+CREATE TABLE IF NOT EXISTS collection_items (
+    tenant_id     INTEGER NOT NULL DEFAULT 1,
+    collection_id INTEGER NOT NULL REFERENCES collections (id) ON DELETE CASCADE,
+    book_id       INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    position      INTEGER NOT NULL,
+    PRIMARY KEY (collection_id, book_id)
+);
+
+CREATE INDEX IF NOT EXISTS collection_items_book_id_idx ON collection_items (book_id);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS series (
+    id          SERIAL PRIMARY KEY,
+    tenant_id   INTEGER NOT NULL DEFAULT 1,
+    name        VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL
+);
+
+-- A book is in at most one series.
+CREATE TABLE IF NOT EXISTS series_books (
+    tenant_id INTEGER NOT NULL DEFAULT 1,
+    book_id   INTEGER PRIMARY KEY REFERENCES books (id) ON DELETE CASCADE,
+    series_id INTEGER NOT NULL REFERENCES series (id) ON DELETE CASCADE,
+    position  DOUBLE PRECISION NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS series_books_series_id_idx ON series_books (series_id, position);
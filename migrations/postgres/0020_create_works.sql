@@ -0,0 +1,17 @@
+ALTER TABLE books ADD COLUMN IF NOT EXISTS publisher VARCHAR(255);
+
+CREATE TABLE IF NOT EXISTS works (
+    id        SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL DEFAULT 1,
+    title     VARCHAR(255) NOT NULL,
+    author    VARCHAR(255) NOT NULL
+);
+
+-- A book is an edition of at most one work.
+CREATE TABLE IF NOT EXISTS work_editions (
+    tenant_id INTEGER NOT NULL DEFAULT 1,
+    book_id   INTEGER PRIMARY KEY REFERENCES books (id) ON DELETE CASCADE,
+    work_id   INTEGER NOT NULL REFERENCES works (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS work_editions_work_id_idx ON work_editions (work_id);
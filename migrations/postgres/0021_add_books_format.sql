@@ -0,0 +1,4 @@
+-- hardcover, paperback, ebook or audiobook; NULL when not known.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS format VARCHAR(16);
+
+CREATE INDEX IF NOT EXISTS books_format_idx ON books (format);
@@ -0,0 +1,13 @@
+-- Every update of how far readers have got in the books on their shelves.
+CREATE TABLE IF NOT EXISTS reading_progress (
+    id          SERIAL PRIMARY KEY,
+    tenant_id   INTEGER NOT NULL DEFAULT 1,
+    user_id     INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    book_id     INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    page        INTEGER,
+    pages       INTEGER,
+    percent     DOUBLE PRECISION,
+    recorded_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS reading_progress_user_id_idx ON reading_progress (user_id, book_id);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS reading_goals (
+    tenant_id INTEGER NOT NULL DEFAULT 1,
+    user_id   INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    year      INTEGER NOT NULL,
+    unit      VARCHAR(16) NOT NULL,
+    target    INTEGER NOT NULL,
+    PRIMARY KEY (user_id, year)
+);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS notes (
+    id         SERIAL PRIMARY KEY,
+    tenant_id  INTEGER NOT NULL DEFAULT 1,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    book_id    INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    kind       VARCHAR(16) NOT NULL,
+    body       TEXT NOT NULL,
+    page       INTEGER,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS notes_user_id_idx ON notes (user_id, created_at);
+CREATE INDEX IF NOT EXISTS notes_book_id_idx ON notes (book_id);
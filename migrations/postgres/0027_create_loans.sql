@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS loans (
+    id          SERIAL PRIMARY KEY,
+    tenant_id   INTEGER NOT NULL DEFAULT 1,
+    member_id   INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    book_id     INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    borrowed_at TIMESTAMPTZ NOT NULL,
+    due_at      TIMESTAMPTZ NOT NULL,
+    returned_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS loans_member_id_idx ON loans (member_id, borrowed_at);
+CREATE INDEX IF NOT EXISTS loans_book_id_idx ON loans (book_id);
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS members (
+    id          SERIAL PRIMARY KEY,
+    tenant_id   INTEGER NOT NULL DEFAULT 1,
+    card_number VARCHAR(32) NOT NULL,
+    name        VARCHAR(255) NOT NULL,
+    email       VARCHAR(255) NOT NULL,
+    phone       VARCHAR(16) NOT NULL,
+    address     TEXT NOT NULL,
+    tier        VARCHAR(16) NOT NULL,
+    expires_at  TIMESTAMPTZ,
+    user_id     INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    created_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS members_card_number_idx ON members (tenant_id, card_number);
+CREATE INDEX IF NOT EXISTS members_name_idx ON members (tenant_id, name);
+CREATE INDEX IF NOT EXISTS members_user_id_idx ON members (user_id);
+
+-- Loans were made to users; each user who borrowed becomes a member,
+-- linked to their account, with their id as card number.
+INSERT INTO members (tenant_id, card_number, name, email, phone, address, tier, expires_at, user_id, created_at)
+SELECT tenant_id, LPAD(id::text, 10, '0'), name, email, '', '', 'standard', NULL, id, created_at
+FROM users WHERE id IN (SELECT member_id FROM loans);
+
+ALTER TABLE loans DROP CONSTRAINT IF EXISTS loans_member_id_fkey;
+UPDATE loans SET member_id = members.id FROM members WHERE members.user_id = loans.member_id;
+ALTER TABLE loans ADD CONSTRAINT loans_member_id_fkey FOREIGN KEY (member_id) REFERENCES members (id) ON DELETE CASCADE;
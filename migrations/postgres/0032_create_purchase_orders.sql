@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS suppliers (
+    id         SERIAL PRIMARY KEY,
+    tenant_id  INTEGER NOT NULL DEFAULT 1,
+    name       TEXT NOT NULL,
+    email      TEXT NOT NULL,
+    phone      TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS purchase_orders (
+    id          SERIAL PRIMARY KEY,
+    tenant_id   INTEGER NOT NULL DEFAULT 1,
+    supplier_id INTEGER NOT NULL REFERENCES suppliers (id),
+    reference   TEXT NOT NULL,
+    status      TEXT NOT NULL,
+    ordered_at  TIMESTAMPTZ NOT NULL,
+    closed_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS purchase_orders_status_idx ON purchase_orders (status, ordered_at);
+CREATE INDEX IF NOT EXISTS purchase_orders_supplier_id_idx ON purchase_orders (supplier_id);
+
+CREATE TABLE IF NOT EXISTS purchase_order_lines (
+    id        SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL DEFAULT 1,
+    order_id  INTEGER NOT NULL REFERENCES purchase_orders (id) ON DELETE CASCADE,
+    book_id   INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    quantity  INTEGER NOT NULL,
+    unit_cost NUMERIC(10, 2) NOT NULL,
+    received  INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS purchase_order_lines_order_id_idx ON purchase_order_lines (order_id);
+CREATE INDEX IF NOT EXISTS purchase_order_lines_book_id_idx ON purchase_order_lines (book_id);
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS stock INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS supplier_prices (
+    tenant_id      INTEGER NOT NULL DEFAULT 1,
+    supplier_id    INTEGER NOT NULL REFERENCES suppliers (id) ON DELETE CASCADE,
+    book_id        INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    unit_cost      NUMERIC(10, 2) NOT NULL,
+    lead_time_days INTEGER NOT NULL,
+    updated_at     TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (supplier_id, book_id)
+);
+
+CREATE INDEX IF NOT EXISTS supplier_prices_book_id_idx ON supplier_prices (book_id, unit_cost);
@@ -0,0 +1,5 @@
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS region TEXT NOT NULL DEFAULT '';
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS tax_included BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE sale_lines ADD COLUMN IF NOT EXISTS tax_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE sale_lines ADD COLUMN IF NOT EXISTS tax_rate NUMERIC(6, 5) NOT NULL DEFAULT 0;
+ALTER TABLE sale_lines ADD COLUMN IF NOT EXISTS tax NUMERIC(14, 2) NOT NULL DEFAULT 0;
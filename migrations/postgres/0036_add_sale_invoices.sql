@@ -0,0 +1,14 @@
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS customer_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS customer_address TEXT NOT NULL DEFAULT '';
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS invoice_number INTEGER NOT NULL DEFAULT 0;
+
+UPDATE sales SET invoice_number = (SELECT COUNT(*) FROM sales earlier WHERE earlier.tenant_id = sales.tenant_id AND earlier.id <= sales.id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS sales_invoice_number_idx ON sales (tenant_id, invoice_number);
+
+CREATE TABLE IF NOT EXISTS invoice_numbers (
+    tenant_id   INTEGER PRIMARY KEY,
+    last_number INTEGER NOT NULL
+);
+
+INSERT INTO invoice_numbers (tenant_id, last_number) SELECT tenant_id, MAX(invoice_number) FROM sales GROUP BY tenant_id;
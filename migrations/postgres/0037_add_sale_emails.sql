@@ -0,0 +1,6 @@
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS html TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS customer_email TEXT NOT NULL DEFAULT '';
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS shipped_at TIMESTAMPTZ;
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS carrier TEXT NOT NULL DEFAULT '';
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS tracking_number TEXT NOT NULL DEFAULT '';
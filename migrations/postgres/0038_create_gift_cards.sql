@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS gift_cards (
+    id        SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL DEFAULT 1,
+    code      TEXT NOT NULL,
+    amount    NUMERIC(10, 2) NOT NULL,
+    balance   NUMERIC(10, 2) NOT NULL,
+    note      TEXT NOT NULL DEFAULT '',
+    issued_at TIMESTAMPTZ NOT NULL,
+    voided_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS gift_cards_code_idx ON gift_cards (tenant_id, code);
+
+CREATE TABLE IF NOT EXISTS gift_card_redemptions (
+    id           SERIAL PRIMARY KEY,
+    tenant_id    INTEGER NOT NULL DEFAULT 1,
+    gift_card_id INTEGER NOT NULL REFERENCES gift_cards (id) ON DELETE CASCADE,
+    sale_id      INTEGER NOT NULL REFERENCES sales (id) ON DELETE CASCADE,
+    amount       NUMERIC(10, 2) NOT NULL,
+    redeemed_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS gift_card_redemptions_gift_card_id_idx ON gift_card_redemptions (gift_card_id);
+
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS gift_card_amount NUMERIC(16, 2) NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS book_merges (
+    tenant_id   INTEGER NOT NULL DEFAULT 1,
+    book_id     INTEGER NOT NULL,
+    survivor_id INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    merged_at   TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (tenant_id, book_id)
+);
+
+CREATE INDEX IF NOT EXISTS book_merges_survivor_id_idx ON book_merges (survivor_id);
@@ -0,0 +1,2 @@
+ALTER TABLE books ADD COLUMN IF NOT EXISTS description TEXT;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS cover_url VARCHAR(2048);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id           SERIAL PRIMARY KEY,
+    tenant_id    INTEGER NOT NULL DEFAULT 1,
+    user_id      INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name         VARCHAR(100) NOT NULL,
+    search_query VARCHAR(200) NOT NULL,
+    category     VARCHAR(100) NOT NULL,
+    format       VARCHAR(16) NOT NULL,
+    max_price    NUMERIC(10, 2),
+    seen_book_id INTEGER NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL,
+    notified_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS saved_searches_user_id_idx ON saved_searches (user_id);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS branches (
+    id         SERIAL PRIMARY KEY,
+    tenant_id  INTEGER NOT NULL DEFAULT 1,
+    name       VARCHAR(255) NOT NULL,
+    address    VARCHAR(500) NOT NULL DEFAULT '',
+    latitude   DOUBLE PRECISION NOT NULL,
+    longitude  DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS branches_tenant_id_idx ON branches (tenant_id, name);
+
+ALTER TABLE copies ADD COLUMN IF NOT EXISTS branch_id INTEGER REFERENCES branches (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS copies_branch_id_idx ON copies (branch_id);
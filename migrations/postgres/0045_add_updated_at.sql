@@ -0,0 +1,7 @@
+ALTER TABLE books ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+
+UPDATE books SET updated_at = created_at WHERE updated_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS books_updated_at_idx ON books (updated_at);
+
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS books_created_at_idx;
+CREATE INDEX IF NOT EXISTS books_created_at_idx ON books (tenant_id, created_at, id);
+DROP INDEX IF EXISTS books_updated_at_idx;
+CREATE INDEX IF NOT EXISTS books_updated_at_idx ON books (tenant_id, updated_at, id);
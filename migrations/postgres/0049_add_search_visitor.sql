@@ -0,0 +1 @@
+ALTER TABLE search_queries ADD COLUMN IF NOT EXISTS visitor VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Encrypted contact details are longer than the plain ones.
+ALTER TABLE members ALTER COLUMN email TYPE TEXT, ALTER COLUMN phone TYPE TEXT;
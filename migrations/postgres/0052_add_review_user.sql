@@ -0,0 +1,5 @@
+-- The account a review was posted with. Reviews from before have none:
+-- they were only signed with a name, which doesn't tell whose they are.
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS user_id INTEGER REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS reviews_user_id_idx ON reviews (user_id);
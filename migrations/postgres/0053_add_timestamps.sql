@@ -0,0 +1,85 @@
+-- Every record the API shows has the times the store added it and last
+-- changed it. Records with no earlier time to go by have none until they
+-- change.
+
+ALTER TABLE branches ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE branches SET updated_at = created_at;
+
+ALTER TABLE series ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE series SET updated_at = created_at;
+
+ALTER TABLE works ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+ALTER TABLE works ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE quotes SET updated_at = created_at;
+
+ALTER TABLE shelf_items ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+ALTER TABLE shelf_items ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE shelf_items SET created_at = added_at, updated_at = added_at;
+
+ALTER TABLE wishlist_items ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+ALTER TABLE wishlist_items ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE wishlist_items SET created_at = added_at, updated_at = COALESCE(alerted_at, added_at);
+
+ALTER TABLE saved_searches ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE saved_searches SET updated_at = COALESCE(notified_at, created_at);
+
+ALTER TABLE reading_goals ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+ALTER TABLE reading_goals ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+
+ALTER TABLE collections ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE collections SET updated_at = created_at;
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE tenants SET updated_at = created_at;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE users SET updated_at = created_at;
+
+ALTER TABLE members ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE members SET updated_at = created_at;
+
+ALTER TABLE loans ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+ALTER TABLE loans ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE loans SET created_at = borrowed_at, updated_at = COALESCE(returned_at, borrowed_at);
+
+ALTER TABLE copies ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE copies SET updated_at = created_at;
+
+ALTER TABLE holds ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+ALTER TABLE holds ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE holds SET created_at = placed_at, updated_at = COALESCE(ready_at, placed_at);
+
+ALTER TABLE fines ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE fines SET updated_at = COALESCE(settled_at,
+    (SELECT MAX(paid_at) FROM fine_payments WHERE fine_payments.fine_id = fines.id), created_at);
+
+ALTER TABLE donations ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+ALTER TABLE donations ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE donations SET created_at = received_at, updated_at = COALESCE(decided_at, received_at);
+
+ALTER TABLE suppliers ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE suppliers SET updated_at = created_at;
+
+ALTER TABLE supplier_prices ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+UPDATE supplier_prices SET created_at = updated_at;
+
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE purchase_orders SET created_at = ordered_at, updated_at = COALESCE(closed_at, ordered_at);
+
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE sales SET created_at = sold_at, updated_at = COALESCE(shipped_at, sold_at);
+
+ALTER TABLE gift_cards ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+ALTER TABLE gift_cards ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE gift_cards SET created_at = issued_at, updated_at = COALESCE(voided_at,
+    (SELECT MAX(redeemed_at) FROM gift_card_redemptions WHERE gift_card_redemptions.gift_card_id = gift_cards.id), issued_at);
+
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE webhooks SET updated_at = created_at;
+
+ALTER TABLE push_subscriptions ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE push_subscriptions SET updated_at = created_at;
@@ -0,0 +1,4 @@
+-- Keys are the caller's: the same key from another client is another
+-- request. Keys from before have no caller and simply expire.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS caller VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE idempotency_keys DROP CONSTRAINT idempotency_keys_pkey, ADD PRIMARY KEY (tenant_id, caller, idempotency_key);
@@ -0,0 +1,10 @@
+-- Deleting a member erases their details and keeps their row, so their
+-- loans, fines and payments stay on record.
+ALTER TABLE members ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+ALTER TABLE loans DROP CONSTRAINT IF EXISTS loans_member_id_fkey;
+ALTER TABLE loans ADD CONSTRAINT loans_member_id_fkey FOREIGN KEY (member_id) REFERENCES members (id) ON DELETE RESTRICT;
+ALTER TABLE fines DROP CONSTRAINT IF EXISTS fines_member_id_fkey;
+ALTER TABLE fines ADD CONSTRAINT fines_member_id_fkey FOREIGN KEY (member_id) REFERENCES members (id) ON DELETE RESTRICT;
+ALTER TABLE fine_payments DROP CONSTRAINT IF EXISTS fine_payments_fine_id_fkey;
+ALTER TABLE fine_payments ADD CONSTRAINT fine_payments_fine_id_fkey FOREIGN KEY (fine_id) REFERENCES fines (id) ON DELETE RESTRICT;
@@ -0,0 +1,7 @@
+-- Order lines outlive their books, as sale lines do, keeping the title
+-- the book had.
+ALTER TABLE purchase_order_lines ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '';
+UPDATE purchase_order_lines SET title = books.title FROM books WHERE books.id = purchase_order_lines.book_id;
+ALTER TABLE purchase_order_lines ALTER COLUMN book_id DROP NOT NULL;
+ALTER TABLE purchase_order_lines DROP CONSTRAINT IF EXISTS purchase_order_lines_book_id_fkey;
+ALTER TABLE purchase_order_lines ADD CONSTRAINT purchase_order_lines_book_id_fkey FOREIGN KEY (book_id) REFERENCES books (id) ON DELETE SET NULL;
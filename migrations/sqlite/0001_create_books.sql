@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS books (
+    id     INTEGER PRIMARY KEY AUTOINCREMENT,
+    title  TEXT NOT NULL,
+    author TEXT NOT NULL,
+    price  REAL NOT NULL DEFAULT 0
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS reviews (
+    id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    book_id  INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    reviewer TEXT NOT NULL,
+    rating   INTEGER NOT NULL,
+    body     TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS reviews_book_id_idx ON reviews (book_id);
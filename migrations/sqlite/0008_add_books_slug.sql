@@ -0,0 +1,3 @@
+ALTER TABLE books ADD COLUMN slug TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS books_slug_idx ON books (slug);
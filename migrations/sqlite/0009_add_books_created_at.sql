@@ -0,0 +1,3 @@
+ALTER TABLE books ADD COLUMN created_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS books_created_at_idx ON books (created_at);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS search_queries (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    term       TEXT NOT NULL,
+    results    INTEGER NOT NULL,
+    latency_us INTEGER NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS search_queries_created_at_idx ON search_queries (created_at);
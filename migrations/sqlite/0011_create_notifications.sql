@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS notifications (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    channel         TEXT NOT NULL,
+    recipient       TEXT NOT NULL,
+    kind            TEXT NOT NULL,
+    subject         TEXT NOT NULL,
+    body            TEXT NOT NULL,
+    attempt         INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    created_at      DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS notifications_next_attempt_at_idx ON notifications (next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS notification_failures (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    channel    TEXT NOT NULL,
+    recipient  TEXT NOT NULL,
+    kind       TEXT NOT NULL,
+    subject    TEXT NOT NULL,
+    body       TEXT NOT NULL,
+    attempts   INTEGER NOT NULL,
+    error      TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    failed_at  DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    recipient TEXT NOT NULL,
+    kind      TEXT NOT NULL,
+    enabled   BOOLEAN NOT NULL,
+    PRIMARY KEY (recipient, kind)
+);
@@ -0,0 +1,6 @@
+ALTER TABLE notification_preferences ADD COLUMN channel TEXT NOT NULL DEFAULT 'email';
+
+CREATE TABLE IF NOT EXISTS notification_contacts (
+    recipient TEXT PRIMARY KEY,
+    phone     TEXT NOT NULL
+);
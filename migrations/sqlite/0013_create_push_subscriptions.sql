@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS push_subscriptions (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    endpoint   TEXT NOT NULL,
+    p256dh     TEXT NOT NULL,
+    auth       TEXT NOT NULL,
+    recipient  TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS push_subscriptions_endpoint_idx ON push_subscriptions (endpoint);
+CREATE INDEX IF NOT EXISTS push_subscriptions_recipient_idx ON push_subscriptions (recipient);
+
+CREATE TABLE IF NOT EXISTS push_watches (
+    subscription_id INTEGER NOT NULL,
+    book_id         INTEGER NOT NULL,
+    price           REAL NOT NULL,
+    PRIMARY KEY (subscription_id, book_id)
+);
+
+CREATE INDEX IF NOT EXISTS push_watches_book_id_idx ON push_watches (book_id);
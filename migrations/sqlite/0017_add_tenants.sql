@@ -0,0 +1,81 @@
+CREATE TABLE IF NOT EXISTS tenants (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    slug       TEXT NOT NULL,
+    name       TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS tenants_slug_idx ON tenants (slug);
+
+-- Everything from before tenants belongs to the default tenant.
+INSERT INTO tenants (id, slug, name, created_at) VALUES (1, 'default', 'Default', CURRENT_TIMESTAMP);
+
+ALTER TABLE books ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE reviews ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE webhooks ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE webhook_deliveries ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE webhook_jobs ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE outbox ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE search_queries ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE notifications ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE notification_failures ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE push_subscriptions ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE push_watches ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE shelf_items ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE recommendations ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE book_categories ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE book_tags ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE book_activity ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE users ADD COLUMN admin BOOLEAN NOT NULL DEFAULT 0;
+
+-- ISBNs, slugs and email addresses only have to be unique within a tenant.
+DROP INDEX IF EXISTS books_isbn_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS books_isbn_idx ON books (tenant_id, isbn);
+DROP INDEX IF EXISTS books_slug_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS books_slug_idx ON books (tenant_id, slug);
+DROP INDEX IF EXISTS users_email_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_idx ON users (tenant_id, email);
+
+-- As are the keys of these tables; SQLite can't change a primary key, so
+-- they are copied into new tables.
+CREATE TABLE idempotency_keys_new (
+    tenant_id       INTEGER NOT NULL DEFAULT 1,
+    idempotency_key TEXT NOT NULL,
+    fingerprint     TEXT NOT NULL,
+    status          INTEGER NOT NULL DEFAULT 0,
+    content_type    TEXT NOT NULL DEFAULT '',
+    body            BLOB,
+    created_at      DATETIME NOT NULL,
+    expires_at      DATETIME NOT NULL,
+    PRIMARY KEY (tenant_id, idempotency_key)
+);
+INSERT INTO idempotency_keys_new (idempotency_key, fingerprint, status, content_type, body, created_at, expires_at)
+    SELECT idempotency_key, fingerprint, status, content_type, body, created_at, expires_at FROM idempotency_keys;
+DROP TABLE idempotency_keys;
+ALTER TABLE idempotency_keys_new RENAME TO idempotency_keys;
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
+
+CREATE TABLE notification_preferences_new (
+    tenant_id INTEGER NOT NULL DEFAULT 1,
+    recipient TEXT NOT NULL,
+    kind      TEXT NOT NULL,
+    enabled   BOOLEAN NOT NULL,
+    channel   TEXT NOT NULL DEFAULT 'email',
+    PRIMARY KEY (tenant_id, recipient, kind)
+);
+INSERT INTO notification_preferences_new (recipient, kind, enabled, channel)
+    SELECT recipient, kind, enabled, channel FROM notification_preferences;
+DROP TABLE notification_preferences;
+ALTER TABLE notification_preferences_new RENAME TO notification_preferences;
+
+CREATE TABLE notification_contacts_new (
+    tenant_id INTEGER NOT NULL DEFAULT 1,
+    recipient TEXT NOT NULL,
+    phone     TEXT NOT NULL,
+    PRIMARY KEY (tenant_id, recipient)
+);
+INSERT INTO notification_contacts_new (recipient, phone) SELECT recipient, phone FROM notification_contacts;
+DROP TABLE notification_contacts;
+ALTER TABLE notification_contacts_new RENAME TO notification_contacts;
@@ -0,0 +1,4 @@
+-- hardcover, paperback, ebook or audiobook; NULL when not known.
+ALTER TABLE books ADD COLUMN format TEXT;
+
+CREATE INDEX IF NOT EXISTS books_format_idx ON books (format);
@@ -0,0 +1,17 @@
+ALTER TABLE shelf_items ADD COLUMN status TEXT NOT NULL DEFAULT 'to-read';
+
+-- Every status a shelved book has had, starting with the one it was
+-- shelved with.
+CREATE TABLE IF NOT EXISTS reading_status_changes (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id  INTEGER NOT NULL DEFAULT 1,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    book_id    INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    status     TEXT NOT NULL,
+    changed_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS reading_status_changes_user_id_idx ON reading_status_changes (user_id, book_id);
+
+INSERT INTO reading_status_changes (tenant_id, user_id, book_id, status, changed_at)
+SELECT tenant_id, user_id, book_id, status, added_at FROM shelf_items ORDER BY added_at;
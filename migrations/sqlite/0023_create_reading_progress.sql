@@ -0,0 +1,13 @@
+-- Every update of how far readers have got in the books on their shelves.
+CREATE TABLE IF NOT EXISTS reading_progress (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id   INTEGER NOT NULL DEFAULT 1,
+    user_id     INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    book_id     INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    page        INTEGER,
+    pages       INTEGER,
+    percent     REAL,
+    recorded_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS reading_progress_user_id_idx ON reading_progress (user_id, book_id);
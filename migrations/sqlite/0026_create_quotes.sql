@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS quotes (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id  INTEGER NOT NULL DEFAULT 1,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    book_id    INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    body       TEXT NOT NULL,
+    author     TEXT NOT NULL,
+    public     BOOLEAN NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS quotes_user_id_idx ON quotes (user_id);
+CREATE INDEX IF NOT EXISTS quotes_book_id_idx ON quotes (book_id);
+CREATE INDEX IF NOT EXISTS quotes_public_idx ON quotes (tenant_id, public);
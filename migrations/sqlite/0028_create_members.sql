@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS members (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id   INTEGER NOT NULL DEFAULT 1,
+    card_number TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    email       TEXT NOT NULL,
+    phone       TEXT NOT NULL,
+    address     TEXT NOT NULL,
+    tier        TEXT NOT NULL,
+    expires_at  DATETIME,
+    user_id     INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    created_at  DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS members_card_number_idx ON members (tenant_id, card_number);
+CREATE INDEX IF NOT EXISTS members_name_idx ON members (tenant_id, name);
+CREATE INDEX IF NOT EXISTS members_user_id_idx ON members (user_id);
+
+-- Loans were made to users; each user who borrowed becomes a member,
+-- linked to their account, with their id as card number.
+INSERT INTO members (tenant_id, card_number, name, email, phone, address, tier, expires_at, user_id, created_at)
+SELECT tenant_id, printf('%010d', id), name, email, '', '', 'standard', NULL, id, created_at
+FROM users WHERE id IN (SELECT member_id FROM loans);
+
+CREATE TABLE loans_by_member (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id   INTEGER NOT NULL DEFAULT 1,
+    member_id   INTEGER NOT NULL REFERENCES members (id) ON DELETE CASCADE,
+    book_id     INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    borrowed_at DATETIME NOT NULL,
+    due_at      DATETIME NOT NULL,
+    returned_at DATETIME
+);
+
+INSERT INTO loans_by_member (id, tenant_id, member_id, book_id, borrowed_at, due_at, returned_at)
+SELECT loans.id, loans.tenant_id, members.id, loans.book_id, loans.borrowed_at, loans.due_at, loans.returned_at
+FROM loans JOIN members ON members.user_id = loans.member_id;
+
+DROP TABLE loans;
+ALTER TABLE loans_by_member RENAME TO loans;
+
+CREATE INDEX IF NOT EXISTS loans_member_id_idx ON loans (member_id, borrowed_at);
+CREATE INDEX IF NOT EXISTS loans_book_id_idx ON loans (book_id);
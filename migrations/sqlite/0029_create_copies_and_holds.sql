@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS copies (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id  INTEGER NOT NULL DEFAULT 1,
+    book_id    INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    barcode    TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS copies_barcode_idx ON copies (tenant_id, barcode);
+CREATE INDEX IF NOT EXISTS copies_book_id_idx ON copies (book_id);
+
+ALTER TABLE loans ADD COLUMN copy_id INTEGER REFERENCES copies (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS loans_copy_id_idx ON loans (copy_id);
+
+CREATE TABLE IF NOT EXISTS holds (
+    id        INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id INTEGER NOT NULL DEFAULT 1,
+    member_id INTEGER NOT NULL REFERENCES members (id) ON DELETE CASCADE,
+    book_id   INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    placed_at DATETIME NOT NULL,
+    ready_at  DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS holds_book_id_idx ON holds (book_id, placed_at);
+CREATE INDEX IF NOT EXISTS holds_member_id_idx ON holds (member_id);
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS fines (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id  INTEGER NOT NULL DEFAULT 1,
+    member_id  INTEGER NOT NULL REFERENCES members (id) ON DELETE CASCADE,
+    loan_id    INTEGER REFERENCES loans (id) ON DELETE SET NULL,
+    reason     TEXT NOT NULL,
+    amount     REAL NOT NULL,
+    settled_at DATETIME,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS fines_member_id_idx ON fines (member_id, created_at);
+CREATE INDEX IF NOT EXISTS fines_loan_id_idx ON fines (loan_id);
+
+CREATE TABLE IF NOT EXISTS fine_payments (
+    id        INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id INTEGER NOT NULL DEFAULT 1,
+    fine_id   INTEGER NOT NULL REFERENCES fines (id) ON DELETE CASCADE,
+    amount    REAL NOT NULL,
+    method    TEXT NOT NULL,
+    reference TEXT NOT NULL,
+    paid_at   DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS fine_payments_fine_id_idx ON fine_payments (fine_id);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS donations (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id       INTEGER NOT NULL DEFAULT 1,
+    donor_name      TEXT NOT NULL,
+    donor_email     TEXT NOT NULL,
+    donor_address   TEXT NOT NULL,
+    title           TEXT NOT NULL,
+    author          TEXT NOT NULL,
+    isbn            TEXT NOT NULL,
+    book_condition  TEXT NOT NULL,
+    appraised_value REAL NOT NULL,
+    status          TEXT NOT NULL,
+    note            TEXT NOT NULL,
+    book_id         INTEGER REFERENCES books (id) ON DELETE SET NULL,
+    received_at     DATETIME NOT NULL,
+    decided_at      DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS donations_status_idx ON donations (status, received_at);
+CREATE INDEX IF NOT EXISTS donations_book_id_idx ON donations (book_id);
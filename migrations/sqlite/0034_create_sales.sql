@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS sales (
+    id        INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id INTEGER NOT NULL DEFAULT 1,
+    total     REAL NOT NULL,
+    sold_at   DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS sale_lines (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id  INTEGER NOT NULL DEFAULT 1,
+    sale_id    INTEGER NOT NULL REFERENCES sales (id) ON DELETE CASCADE,
+    book_id    INTEGER REFERENCES books (id) ON DELETE SET NULL,
+    title      TEXT NOT NULL,
+    quantity   INTEGER NOT NULL,
+    unit_price REAL NOT NULL,
+    amount     REAL NOT NULL,
+    day        DATE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sale_lines_sale_id_idx ON sale_lines (sale_id);
+CREATE INDEX IF NOT EXISTS sale_lines_day_idx ON sale_lines (tenant_id, day);
+CREATE INDEX IF NOT EXISTS sale_lines_book_id_idx ON sale_lines (book_id);
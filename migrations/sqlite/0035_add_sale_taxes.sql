@@ -0,0 +1,5 @@
+ALTER TABLE sales ADD COLUMN region TEXT NOT NULL DEFAULT '';
+ALTER TABLE sales ADD COLUMN tax_included BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE sale_lines ADD COLUMN tax_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE sale_lines ADD COLUMN tax_rate REAL NOT NULL DEFAULT 0;
+ALTER TABLE sale_lines ADD COLUMN tax REAL NOT NULL DEFAULT 0;
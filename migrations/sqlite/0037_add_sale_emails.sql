@@ -0,0 +1,6 @@
+ALTER TABLE notifications ADD COLUMN html TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE sales ADD COLUMN customer_email TEXT NOT NULL DEFAULT '';
+ALTER TABLE sales ADD COLUMN shipped_at DATETIME;
+ALTER TABLE sales ADD COLUMN carrier TEXT NOT NULL DEFAULT '';
+ALTER TABLE sales ADD COLUMN tracking_number TEXT NOT NULL DEFAULT '';
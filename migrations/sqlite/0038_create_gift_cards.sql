@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS gift_cards (
+    id        INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id INTEGER NOT NULL DEFAULT 1,
+    code      TEXT NOT NULL,
+    amount    REAL NOT NULL,
+    balance   REAL NOT NULL,
+    note      TEXT NOT NULL DEFAULT '',
+    issued_at DATETIME NOT NULL,
+    voided_at DATETIME
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS gift_cards_code_idx ON gift_cards (tenant_id, code);
+
+CREATE TABLE IF NOT EXISTS gift_card_redemptions (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id    INTEGER NOT NULL DEFAULT 1,
+    gift_card_id INTEGER NOT NULL REFERENCES gift_cards (id) ON DELETE CASCADE,
+    sale_id      INTEGER NOT NULL REFERENCES sales (id) ON DELETE CASCADE,
+    amount       REAL NOT NULL,
+    redeemed_at  DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS gift_card_redemptions_gift_card_id_idx ON gift_card_redemptions (gift_card_id);
+
+ALTER TABLE sales ADD COLUMN gift_card_amount REAL NOT NULL DEFAULT 0;
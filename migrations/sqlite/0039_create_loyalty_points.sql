@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS loyalty_points (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id  INTEGER NOT NULL DEFAULT 1,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    kind       TEXT NOT NULL,
+    points     INTEGER NOT NULL,
+    remaining  INTEGER NOT NULL DEFAULT 0,
+    sale_id    INTEGER REFERENCES sales (id) ON DELETE SET NULL,
+    expires_at DATETIME,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS loyalty_points_user_id_idx ON loyalty_points (user_id);
+
+ALTER TABLE sales ADD COLUMN user_id INTEGER REFERENCES users (id) ON DELETE SET NULL;
+
+ALTER TABLE sales ADD COLUMN points_redeemed INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE sales ADD COLUMN points_discount REAL NOT NULL DEFAULT 0;
+
+ALTER TABLE sales ADD COLUMN points_earned INTEGER NOT NULL DEFAULT 0;
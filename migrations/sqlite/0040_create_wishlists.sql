@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS wishlist_items (
+    tenant_id     INTEGER NOT NULL DEFAULT 1,
+    user_id       INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    book_id       INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    target_price  REAL,
+    added_at      DATETIME NOT NULL,
+    alerted_price REAL,
+    alerted_at    DATETIME,
+    PRIMARY KEY (user_id, book_id)
+);
+
+CREATE INDEX IF NOT EXISTS wishlist_items_book_id_idx ON wishlist_items (book_id);
+
+CREATE TABLE IF NOT EXISTS book_prices (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id  INTEGER NOT NULL DEFAULT 1,
+    book_id    INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    price      REAL NOT NULL,
+    changed_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS book_prices_book_id_idx ON book_prices (book_id);
+
+INSERT INTO book_prices (tenant_id, book_id, price, changed_at)
+SELECT tenant_id, id, price, COALESCE(created_at, CURRENT_TIMESTAMP) FROM books;
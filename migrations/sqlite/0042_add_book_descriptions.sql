@@ -0,0 +1,2 @@
+ALTER TABLE books ADD COLUMN description TEXT;
+ALTER TABLE books ADD COLUMN cover_url TEXT;
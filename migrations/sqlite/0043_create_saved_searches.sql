@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id    INTEGER NOT NULL DEFAULT 1,
+    user_id      INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name         TEXT NOT NULL,
+    search_query TEXT NOT NULL,
+    category     TEXT NOT NULL,
+    format       TEXT NOT NULL,
+    max_price    REAL,
+    seen_book_id INTEGER NOT NULL,
+    created_at   DATETIME NOT NULL,
+    notified_at  DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS saved_searches_user_id_idx ON saved_searches (user_id);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS branches (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id  INTEGER NOT NULL DEFAULT 1,
+    name       TEXT NOT NULL,
+    address    TEXT NOT NULL DEFAULT '',
+    latitude   REAL NOT NULL,
+    longitude  REAL NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS branches_tenant_id_idx ON branches (tenant_id, name);
+
+ALTER TABLE copies ADD COLUMN branch_id INTEGER REFERENCES branches (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS copies_branch_id_idx ON copies (branch_id);
@@ -0,0 +1 @@
+ALTER TABLE search_queries ADD COLUMN visitor TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS member_access_log (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id   INTEGER NOT NULL DEFAULT 1,
+    member_id   INTEGER,
+    actor       TEXT NOT NULL,
+    data        TEXT NOT NULL,
+    accessed_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS member_access_log_accessed_at_idx ON member_access_log (tenant_id, accessed_at);
+CREATE INDEX IF NOT EXISTS member_access_log_member_id_idx ON member_access_log (tenant_id, member_id, accessed_at);
@@ -0,0 +1,19 @@
+-- Keys are the caller's: the same key from another client is another
+-- request. Keys from before have no caller and simply expire.
+CREATE TABLE idempotency_keys_new (
+    tenant_id       INTEGER NOT NULL DEFAULT 1,
+    caller          TEXT NOT NULL DEFAULT '',
+    idempotency_key TEXT NOT NULL,
+    fingerprint     TEXT NOT NULL,
+    status          INTEGER NOT NULL DEFAULT 0,
+    content_type    TEXT NOT NULL DEFAULT '',
+    body            BLOB,
+    created_at      DATETIME NOT NULL,
+    expires_at      DATETIME NOT NULL,
+    PRIMARY KEY (tenant_id, caller, idempotency_key)
+);
+INSERT INTO idempotency_keys_new (tenant_id, idempotency_key, fingerprint, status, content_type, body, created_at, expires_at)
+    SELECT tenant_id, idempotency_key, fingerprint, status, content_type, body, created_at, expires_at FROM idempotency_keys;
+DROP TABLE idempotency_keys;
+ALTER TABLE idempotency_keys_new RENAME TO idempotency_keys;
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
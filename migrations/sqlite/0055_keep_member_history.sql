@@ -0,0 +1,14 @@
+-- Deleting a member erases their details and keeps their row, so their
+-- loans, fines and payments stay on record.
+ALTER TABLE members ADD COLUMN deleted_at DATETIME;
+
+-- SQLite can't change a foreign key without rebuilding the tables, whose
+-- drops would set off the very cascades this stops, so triggers refuse
+-- the deletes instead.
+CREATE TRIGGER IF NOT EXISTS members_restrict_delete BEFORE DELETE ON members
+WHEN EXISTS (SELECT 1 FROM loans WHERE member_id = OLD.id) OR EXISTS (SELECT 1 FROM fines WHERE member_id = OLD.id)
+BEGIN SELECT RAISE(ABORT, 'FOREIGN KEY constraint failed'); END;
+
+CREATE TRIGGER IF NOT EXISTS fines_restrict_delete BEFORE DELETE ON fines
+WHEN EXISTS (SELECT 1 FROM fine_payments WHERE fine_id = OLD.id)
+BEGIN SELECT RAISE(ABORT, 'FOREIGN KEY constraint failed'); END;
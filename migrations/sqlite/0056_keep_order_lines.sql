@@ -0,0 +1,19 @@
+-- Order lines outlive their books, as sale lines do, keeping the title
+-- the book had.
+CREATE TABLE purchase_order_lines_new (
+    id        INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id INTEGER NOT NULL DEFAULT 1,
+    order_id  INTEGER NOT NULL REFERENCES purchase_orders (id) ON DELETE CASCADE,
+    book_id   INTEGER REFERENCES books (id) ON DELETE SET NULL,
+    title     TEXT NOT NULL DEFAULT '',
+    quantity  INTEGER NOT NULL,
+    unit_cost REAL NOT NULL,
+    received  INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO purchase_order_lines_new (id, tenant_id, order_id, book_id, title, quantity, unit_cost, received)
+    SELECT l.id, l.tenant_id, l.order_id, l.book_id, COALESCE(b.title, ''), l.quantity, l.unit_cost, l.received
+    FROM purchase_order_lines l LEFT JOIN books b ON b.id = l.book_id;
+DROP TABLE purchase_order_lines;
+ALTER TABLE purchase_order_lines_new RENAME TO purchase_order_lines;
+CREATE INDEX IF NOT EXISTS purchase_order_lines_order_id_idx ON purchase_order_lines (order_id);
+CREATE INDEX IF NOT EXISTS purchase_order_lines_book_id_idx ON purchase_order_lines (book_id);
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/text/unicode/norm"
+)
+
+// The kinds of notes: a reader's own thoughts, or a passage of the book
+// they marked.
+const (
+	NoteText      = "note"
+	NoteHighlight = "highlight"
+)
+
+var noteKinds = []string{NoteText, NoteHighlight}
+
+// Note is a reader's private note on a book, or a passage they
+// highlighted in it. Only its reader sees it.
+type Note struct {
+	Id     int `json:"id" xml:"id"`
+	UserId int `json:"-" xml:"-"`
+	BookId int `json:"book_id" xml:"book_id"`
+	// Kind is a note unless given.
+	Kind string `json:"kind" xml:"kind" validate:"omitempty,note_kind"`
+	Text string `json:"text" xml:"text" validate:"required,max=10000"`
+	// Page is where in the book the note is about, if the reader says.
+	Page      *int      `json:"page,omitempty" xml:"page,omitempty" validate:"omitempty,min=0,max=100000"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+	// Book is loaded by ListNotes.
+	Book *Book `json:"book,omitempty" xml:"book,omitempty"`
+}
+
+func (n Note) timestamps() (created, updated time.Time) {
+	return n.CreatedAt, n.UpdatedAt
+}
+
+// NoteQuery selects a reader's notes for ListNotes.
+type NoteQuery struct {
+	// BookId keeps the notes on one book; 0 keeps all.
+	BookId int
+	// Search keeps the notes containing each of its words, ignoring
+	// case; empty keeps all.
+	Search string
+	ChangedAfter
+}
+
+type NoteResponse struct {
+	Status  string `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
+	Data    Note   `json:"data" xml:"data"`
+}
+
+type NotesResponse struct {
+	Status  string `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
+	Data    []Note `json:"data" xml:"data>note"`
+}
+
+// Tidy the text as typed, keeping its line breaks and leaving out any
+// HTML, and default the kind.
+func (n *Note) normalize() {
+	n.Text = strings.TrimSpace(norm.NFC.String(sanitizeText(n.Text)))
+	if n.Kind == "" {
+		n.Kind = NoteText
+	}
+}
+
+// The words a search must find, lower cased.
+func searchWords(query string) []string {
+	return strings.Fields(strings.ToLower(norm.NFC.String(query)))
+}
+
+// List the user's notes, newest first, optionally only those matching
+// ?q=, across all their books.
+func listNotesHandler(w http.ResponseWriter, r *http.Request) {
+	listNotes(w, r, NoteQuery{Search: r.URL.Query().Get("q")})
+}
+
+// List the user's notes on one book, newest first.
+func listBookNotesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+	listNotes(w, r, NoteQuery{BookId: id, Search: r.URL.Query().Get("q")})
+}
+
+func listNotes(w http.ResponseWriter, r *http.Request, query NoteQuery) {
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+	query.ChangedAfter = after
+	notes, err := store.ListNotes(r.Context(), currentUser(r).Id, query)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching notes")
+		log.Printf("Note query error: %v", err)
+		return
+	}
+
+	message := "Notes retrieved successfully"
+	if len(notes) == 0 {
+		message = "No notes found"
+	}
+	writeResponse(w, r, http.StatusOK, NotesResponse{
+		Status:  "success",
+		Message: message,
+		Data:    notes,
+	})
+}
+
+func createNoteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+	var note Note
+	if err := decodeRequest(r, &note); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	note.normalize()
+	if errs := validateRequest(note); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	note = Note{UserId: currentUser(r).Id, BookId: id, Kind: note.Kind, Text: note.Text, Page: note.Page, CreatedAt: now, UpdatedAt: now}
+
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		if _, err := tx.GetBookFields(r.Context(), id, []string{"id"}); err != nil {
+			return err
+		}
+		return tx.CreateNote(r.Context(), &note)
+	})
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error creating note")
+		log.Printf("Note creation error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, NoteResponse{
+		Status:  "success",
+		Message: "Note created successfully",
+		Data:    note,
+	})
+}
+
+func getNoteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid note ID")
+		return
+	}
+
+	note, err := store.GetNote(r.Context(), currentUser(r).Id, id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeNoteNotFound, "Note not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching note")
+		log.Printf("Note query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, NoteResponse{
+		Status:  "success",
+		Message: "Note retrieved successfully",
+		Data:    note,
+	})
+}
+
+// Replace the kind, text and page of a note.
+func updateNoteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid note ID")
+		return
+	}
+	var req Note
+	if err := decodeRequest(r, &req); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	req.normalize()
+	if errs := validateRequest(req); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	userId := currentUser(r).Id
+	var note Note
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		var err error
+		if note, err = tx.GetNote(r.Context(), userId, id); err != nil {
+			return err
+		}
+		note.Kind, note.Text, note.Page = req.Kind, req.Text, req.Page
+		note.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		return tx.UpdateNote(r.Context(), note)
+	})
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeNoteNotFound, "Note not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error updating note")
+		log.Printf("Note update error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, NoteResponse{
+		Status:  "success",
+		Message: "Note updated successfully",
+		Data:    note,
+	})
+}
+
+func deleteNoteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid note ID")
+		return
+	}
+
+	err = store.DeleteNote(r.Context(), currentUser(r).Id, id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeNoteNotFound, "Note not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error deleting note")
+		log.Printf("Note deletion error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Note deleted successfully",
+	})
+}
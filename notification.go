@@ -0,0 +1,554 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// The kinds of notification. Each has a template in
+// templates/<channel>/<kind>.tmpl for every channel that can send it,
+// defining its "body" and, for email, its "subject". An email can have an
+// HTML version too, in templates/email/<kind>.html.tmpl.
+const (
+	NotificationHoldAvailable     = "hold_available"
+	NotificationLoanDueSoon       = "loan_due_soon"
+	NotificationLoanOverdue       = "loan_overdue"
+	NotificationOrderConfirmation = "order_confirmation"
+	NotificationOrderShipped      = "order_shipped"
+	NotificationWishlistPriceDrop = "wishlist_price_drop"
+)
+
+var notificationKinds = []string{NotificationHoldAvailable, NotificationLoanDueSoon, NotificationLoanOverdue, NotificationOrderConfirmation, NotificationOrderShipped, NotificationWishlistPriceDrop, NotificationSavedSearchMatch}
+
+// Notification is a message waiting to be sent to a recipient.
+type Notification struct {
+	Id int
+	// TenantId is the id of the tenant the notification is sent for, set
+	// by the store.
+	TenantId  int
+	Channel   string
+	Recipient string
+	Kind      string
+	Subject   string
+	Body      string
+	// HTML is the body as HTML, for emails that have it.
+	HTML string
+	// Attempt is the number of attempts made so far.
+	Attempt       int
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+}
+
+// NotificationFailure is a notification given up on after its last
+// attempt failed, kept in the dead-letter log.
+type NotificationFailure struct {
+	Id        int    `json:"id" xml:"id"`
+	Channel   string `json:"channel" xml:"channel"`
+	Recipient string `json:"recipient" xml:"recipient"`
+	Kind      string `json:"kind" xml:"kind"`
+	Subject   string `json:"subject" xml:"subject"`
+	Body      string `json:"body" xml:"body"`
+	Attempts  int    `json:"attempts" xml:"attempts"`
+	// Error is why the last attempt failed.
+	Error     string    `json:"error" xml:"error"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	FailedAt  time.Time `json:"failed_at" xml:"failed_at"`
+}
+
+// Failures never change once given up on.
+func (f NotificationFailure) timestamps() (created, updated time.Time) {
+	return f.CreatedAt, f.FailedAt
+}
+
+// notificationChannel delivers notifications one way, such as by email.
+type notificationChannel interface {
+	send(ctx context.Context, n Notification) error
+}
+
+// The channels the config set up, by name.
+var notificationChannels = map[string]notificationChannel{}
+
+var (
+	errChannelUnavailable = errors.New("notification channel not configured")
+	// errNoTemplate means a kind of notification can't be sent over a
+	// channel, for want of a template.
+	errNoTemplate = errors.New("no template for the notification channel")
+	// errNoPhone means an SMS was asked for a recipient without a phone
+	// number.
+	errNoPhone = errors.New("recipient has no phone number")
+	// errNoPushSubscription means a push was asked for a recipient
+	// without a subscribed browser.
+	errNoPushSubscription = errors.New("recipient has no push subscription")
+)
+
+// Set up the notification channels the config has settings for.
+func initNotifications(cfg Config) error {
+	if cfg.SMTPAddr != "" {
+		email, err := newSMTPChannel(cfg)
+		if err != nil {
+			return err
+		}
+		notificationChannels["email"] = email
+	}
+	sms, err := openSMSChannel(cfg)
+	if err != nil {
+		return err
+	}
+	if sms != nil {
+		notificationChannels["sms"] = sms
+	}
+	push, err := openWebPushChannel(cfg)
+	if err != nil {
+		return err
+	}
+	if push != nil {
+		notificationChannels["push"] = push
+	}
+	return nil
+}
+
+//go:embed templates
+var notificationTemplateFiles embed.FS
+
+// The parsed templates by "<channel>/<kind>", and likewise the HTML ones,
+// which escape what they are filled in with.
+var notificationTemplates, notificationHTMLTemplates = parseNotificationTemplates()
+
+func parseNotificationTemplates() (map[string]*template.Template, map[string]*htmltemplate.Template) {
+	templates := map[string]*template.Template{}
+	htmlTemplates := map[string]*htmltemplate.Template{}
+	paths, _ := fs.Glob(notificationTemplateFiles, "templates/*/*.tmpl")
+	for _, p := range paths {
+		name := strings.TrimSuffix(strings.TrimPrefix(p, "templates/"), ".tmpl")
+		if base, ok := strings.CutSuffix(name, ".html"); ok {
+			htmlTemplates[base] = htmltemplate.Must(htmltemplate.New(path.Base(p)).Option("missingkey=error").ParseFS(notificationTemplateFiles, p))
+			continue
+		}
+		templates[name] = template.Must(template.New(path.Base(p)).Option("missingkey=error").ParseFS(notificationTemplateFiles, p))
+	}
+	return templates, htmlTemplates
+}
+
+// Whether notifications of kind can be sent over channel.
+func hasNotificationTemplate(channel, kind string) bool {
+	_, ok := notificationTemplates[channel+"/"+kind]
+	return ok
+}
+
+// errTemplateData means the data for a notification lacks something its
+// template uses.
+var errTemplateData = errors.New("notification template data")
+
+// Render the subject, body and HTML body of a notification of kind for
+// channel. Text messages have no subject, so their templates only define
+// a body, and only emails can have HTML.
+func renderNotification(channel, kind string, data any) (string, string, string, error) {
+	t, ok := notificationTemplates[channel+"/"+kind]
+	if !ok {
+		return "", "", "", errNoTemplate
+	}
+
+	var subject, body, html bytes.Buffer
+	if t.Lookup("subject") != nil {
+		if err := t.ExecuteTemplate(&subject, "subject", data); err != nil {
+			return "", "", "", fmt.Errorf("%w: %v", errTemplateData, err)
+		}
+	}
+	if err := t.ExecuteTemplate(&body, "body", data); err != nil {
+		return "", "", "", fmt.Errorf("%w: %v", errTemplateData, err)
+	}
+	if h, ok := notificationHTMLTemplates[channel+"/"+kind]; ok {
+		if err := h.ExecuteTemplate(&html, "body", data); err != nil {
+			return "", "", "", fmt.Errorf("%w: %v", errTemplateData, err)
+		}
+	}
+	return strings.TrimSpace(subject.String()), strings.TrimLeft(body.String(), "\n"), strings.TrimSpace(html.String()), nil
+}
+
+// The recipient's preference for kind: stored, or on by email.
+func (p NotificationPreferences) preference(kind string) NotificationPreference {
+	for _, preference := range p.Preferences {
+		if preference.Kind == kind {
+			return preference
+		}
+	}
+	return NotificationPreference{Kind: kind, Enabled: true, Channel: "email"}
+}
+
+// Where notifications over channel go for recipient, who is known by
+// email address: texts go to their phone and pushes to each browser they
+// subscribed.
+func notificationAddresses(ctx context.Context, channel, recipient string, preferences NotificationPreferences) ([]string, error) {
+	switch channel {
+	case "sms":
+		if preferences.Phone == nil {
+			return nil, errNoPhone
+		}
+		return []string{*preferences.Phone}, nil
+	case "push":
+		subs, err := store.ListPushSubscriptions(ctx, recipient)
+		if err != nil {
+			return nil, err
+		}
+		if len(subs) == 0 {
+			return nil, errNoPushSubscription
+		}
+		addresses := make([]string, len(subs))
+		for i, sub := range subs {
+			addresses[i] = sub.Endpoint
+		}
+		return addresses, nil
+	}
+	return []string{recipient}, nil
+}
+
+// Queue a notification of kind to recipient, rendered from data, unless
+// the recipient has turned that kind off. It goes over channel, or if that
+// is empty over the channel the recipient chose for the kind. It reports
+// whether the notification was queued.
+func notify(ctx context.Context, channel, recipient, kind string, data any) (bool, error) {
+	preferences, err := store.NotificationPreferences(ctx, recipient)
+	if err != nil {
+		return false, err
+	}
+	preference := preferences.preference(kind)
+	if channel == "" {
+		channel = preference.Channel
+	}
+
+	if _, ok := notificationChannels[channel]; !ok {
+		return false, errChannelUnavailable
+	}
+	subject, body, html, err := renderNotification(channel, kind, data)
+	if err != nil {
+		return false, err
+	}
+	if !preference.Enabled {
+		return false, nil
+	}
+
+	addresses, err := notificationAddresses(ctx, channel, recipient, preferences)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	err = store.WithTx(ctx, func(tx BookStore) error {
+		for _, address := range addresses {
+			n := Notification{
+				Channel:       channel,
+				Recipient:     address,
+				Kind:          kind,
+				Subject:       subject,
+				Body:          body,
+				HTML:          html,
+				NextAttemptAt: now,
+				CreatedAt:     now,
+			}
+			if err := tx.EnqueueNotification(ctx, &n); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	wake(notificationWake)
+	return true, nil
+}
+
+// The channels notifications can go out on, whether configured or not.
+var notificationChannelNames = []string{"email", "sms", "push"}
+
+// NotificationRequest asks for a notification to be sent, for the systems
+// that track the holds, loans and orders it is about.
+type NotificationRequest struct {
+	// Channel overrides the one the recipient chose for the kind.
+	Channel string `json:"channel" xml:"channel" validate:"omitempty,notification_channel"`
+	// Recipient is the email address the recipient is known by, whichever
+	// channel the notification goes over.
+	Recipient string `json:"recipient" xml:"recipient" validate:"required,email,max=255"`
+	Kind      string `json:"kind" xml:"kind" validate:"required,notification_kind"`
+	// Data fills in the kind's template. It can only be sent as JSON or
+	// MessagePack.
+	Data map[string]any `json:"data" xml:"-"`
+}
+
+type NotificationResult struct {
+	// Queued is false when the recipient has turned the kind off.
+	Queued bool `json:"queued" xml:"queued"`
+}
+
+type NotificationResponse struct {
+	Status  string             `json:"status" xml:"status"`
+	Message string             `json:"message" xml:"message"`
+	Data    NotificationResult `json:"data" xml:"data"`
+}
+
+// NotificationPreference turns one kind of notification on or off and
+// picks the channel it comes by.
+type NotificationPreference struct {
+	Kind    string `json:"kind" xml:"kind" validate:"notification_kind"`
+	Enabled bool   `json:"enabled" xml:"enabled"`
+	// Channel defaults to email.
+	Channel string `json:"channel" xml:"channel" validate:"omitempty,notification_channel"`
+}
+
+// NotificationPreferences are the kinds of notification a recipient wants
+// and how to reach them.
+type NotificationPreferences struct {
+	Recipient string `json:"recipient" xml:"recipient"`
+	// Phone is the number texts go to, in E.164 format. Updates leave it
+	// as it is when it's missing and remove it when it's empty.
+	Phone       *string                  `json:"phone,omitempty" xml:"phone,omitempty"`
+	Preferences []NotificationPreference `json:"preferences" xml:"preferences>preference" validate:"dive"`
+}
+
+type NotificationPreferencesResponse struct {
+	Status  string                  `json:"status" xml:"status"`
+	Message string                  `json:"message" xml:"message"`
+	Data    NotificationPreferences `json:"data" xml:"data"`
+}
+
+type NotificationFailuresResponse struct {
+	Status  string                `json:"status" xml:"status"`
+	Message string                `json:"message" xml:"message"`
+	Data    []NotificationFailure `json:"data" xml:"data>failure"`
+}
+
+const (
+	defaultNotificationFailuresLimit = 100
+	maxNotificationFailuresLimit     = 1000
+)
+
+func sendNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	var req NotificationRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	if errs := validateRequest(req); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	queued, err := notify(r.Context(), req.Channel, req.Recipient, req.Kind, req.Data)
+	switch {
+	case errors.Is(err, errChannelUnavailable):
+		writeProblem(w, r, codeNotificationsUnavailable, "The notification's channel isn't configured")
+		return
+	case errors.Is(err, errNoTemplate):
+		writeValidationErrors(w, r, []FieldError{newFieldError("kind", "channel", "can't be sent over the chosen channel")})
+		return
+	case errors.Is(err, errNoPhone):
+		writeValidationErrors(w, r, []FieldError{newFieldError("recipient", "phone", "has no phone number for texts")})
+		return
+	case errors.Is(err, errNoPushSubscription):
+		writeValidationErrors(w, r, []FieldError{newFieldError("recipient", "push_subscription", "has no browser subscribed to pushes")})
+		return
+	case errors.Is(err, errTemplateData):
+		writeValidationErrors(w, r, []FieldError{newFieldError("data", "template", "lacks a value the %s template uses", req.Kind)})
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error queuing notification")
+		log.Printf("Notification queuing error: %v", err)
+		return
+	}
+
+	message := "Notification queued"
+	if !queued {
+		message = "The recipient has turned this kind of notification off"
+	}
+	writeResponse(w, r, http.StatusAccepted, NotificationResponse{
+		Status:  "success",
+		Message: message,
+		Data:    NotificationResult{Queued: queued},
+	})
+}
+
+// The recipient named in the path, or false after answering with a
+// problem if it isn't one.
+func recipientParameter(w http.ResponseWriter, r *http.Request) (string, bool) {
+	recipient := mux.Vars(r)["recipient"]
+	if len(recipient) > 255 {
+		writeProblem(w, r, codeInvalidParameter, "Invalid recipient")
+		return "", false
+	}
+	return recipient, true
+}
+
+// The recipient's phone and every kind of notification, whether they get
+// it and by which channel.
+func notificationPreferences(ctx context.Context, recipient string) (NotificationPreferences, error) {
+	stored, err := store.NotificationPreferences(ctx, recipient)
+	if err != nil {
+		return NotificationPreferences{}, err
+	}
+
+	preferences := NotificationPreferences{Recipient: recipient, Phone: stored.Phone}
+	for _, kind := range notificationKinds {
+		preferences.Preferences = append(preferences.Preferences, stored.preference(kind))
+	}
+	return preferences, nil
+}
+
+// Check an update of the current preferences against the channels'
+// templates and the phone number texts need.
+func (req NotificationPreferences) channelErrors(current NotificationPreferences) []FieldError {
+	phone := current.Phone
+	if req.Phone != nil {
+		phone = req.Phone
+	}
+	hasPhone := phone != nil && *phone != ""
+
+	var errs []FieldError
+	updated := map[string]bool{}
+	for i, p := range req.Preferences {
+		updated[p.Kind] = true
+		field := fmt.Sprintf("preferences[%d].channel", i)
+		if !hasNotificationTemplate(p.Channel, p.Kind) {
+			errs = append(errs, newFieldError(field, "template", "can't send %s notifications", p.Kind))
+		} else if p.Channel == "sms" && !hasPhone {
+			errs = append(errs, newFieldError(field, "required_with", "needs a phone number"))
+		}
+	}
+	for _, p := range current.Preferences {
+		if p.Channel == "sms" && !updated[p.Kind] && !hasPhone {
+			errs = append(errs, newFieldError("phone", "required", "is needed for the %s texts", p.Kind))
+		}
+	}
+	return errs
+}
+
+func getNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	recipient, ok := recipientParameter(w, r)
+	if !ok {
+		return
+	}
+
+	preferences, err := notificationPreferences(r.Context(), recipient)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching notification preferences")
+		log.Printf("Notification preference query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, NotificationPreferencesResponse{
+		Status:  "success",
+		Message: "Notification preferences retrieved successfully",
+		Data:    preferences,
+	})
+}
+
+// Set the kinds listed in the body on or off, leaving the others as they
+// are.
+func updateNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	recipient, ok := recipientParameter(w, r)
+	if !ok {
+		return
+	}
+
+	var req NotificationPreferences
+	if err := decodeRequest(r, &req); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	for i := range req.Preferences {
+		if req.Preferences[i].Channel == "" {
+			req.Preferences[i].Channel = "email"
+		}
+	}
+	errs := validateRequest(req)
+	// An empty phone is allowed, to remove it, which the e164 tag can't
+	// express on a pointer.
+	if req.Phone != nil && *req.Phone != "" && validate.Var(*req.Phone, "e164") != nil {
+		errs = append(errs, newFieldError("phone", "e164", "must be a phone number in E.164 format, such as +14155550100"))
+	}
+	if errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	var preferences NotificationPreferences
+	err := store.WithTx(r.Context(), func(tx BookStore) error {
+		current, err := tx.NotificationPreferences(r.Context(), recipient)
+		if err != nil {
+			return err
+		}
+		if errs = req.channelErrors(current); errs != nil {
+			return nil
+		}
+
+		if req.Phone != nil {
+			if err := tx.SetNotificationPhone(r.Context(), recipient, *req.Phone); err != nil {
+				return err
+			}
+		}
+		for _, p := range req.Preferences {
+			if err := tx.SetNotificationPreference(r.Context(), recipient, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	if err == nil {
+		preferences, err = notificationPreferences(r.Context(), recipient)
+	}
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error updating notification preferences")
+		log.Printf("Notification preference update error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, NotificationPreferencesResponse{
+		Status:  "success",
+		Message: "Notification preferences updated successfully",
+		Data:    preferences,
+	})
+}
+
+// List the dead-letter log, newest first.
+func listNotificationFailuresHandler(w http.ResponseWriter, r *http.Request) {
+	limit, ok := intParameter(r, "limit", defaultNotificationFailuresLimit, maxNotificationFailuresLimit)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(maxNotificationFailuresLimit))
+		return
+	}
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+
+	failures, err := store.ListNotificationFailures(r.Context(), limit, after)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching failed notifications")
+		log.Printf("Notification failure query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, NotificationFailuresResponse{
+		Status:  "success",
+		Message: "Failed notifications retrieved successfully",
+		Data:    failures,
+	})
+}
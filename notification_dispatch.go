@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Waits before each retry of a failed send; one more attempt than there
+// are delays is made in total, then the notification goes to the
+// dead-letter log.
+var notificationRetryDelays = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour}
+
+var notificationWake = make(chan struct{}, 1)
+
+const (
+	// How long a claimed notification is reserved for the worker sending
+	// it. Must be well above smtpTimeout.
+	notificationLease        = 2 * time.Minute
+	notificationPollInterval = time.Second
+	notificationClaimBatch   = 20
+)
+
+// Send queued notifications until the process exits. Like webhook jobs,
+// they live in the store, so retries survive restarts.
+func dispatchNotifications() {
+	ticker := time.NewTicker(notificationPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			notifications, err := store.ClaimNotifications(context.Background(), time.Now().UTC(), notificationLease, notificationClaimBatch)
+			if err != nil {
+				log.Printf("Notification query error: %v", err)
+				break
+			}
+			for _, n := range notifications {
+				go sendNotification(n)
+			}
+			if len(notifications) < notificationClaimBatch {
+				break
+			}
+		}
+
+		select {
+		case <-notificationWake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Make one attempt to send n, then finish it, schedule its retry, or move
+// it to the dead-letter log.
+func sendNotification(n Notification) {
+	ctx := withTenantId(context.Background(), n.TenantId)
+
+	n.Attempt++
+	last := n.Attempt > len(notificationRetryDelays)
+	var sendErr error
+	if channel, ok := notificationChannels[n.Channel]; ok {
+		sendErr = channel.send(ctx, n)
+	} else {
+		// Queued before a restart that dropped the channel; retrying
+		// won't help.
+		sendErr, last = errChannelUnavailable, true
+	}
+
+	var err error
+	switch {
+	case sendErr == nil:
+		err = store.DeleteNotification(ctx, n.Id)
+	case last:
+		log.Printf("Notification %d to %s failed for good: %v", n.Id, n.Recipient, sendErr)
+		err = store.WithTx(ctx, func(tx BookStore) error {
+			failure := NotificationFailure{
+				Channel:   n.Channel,
+				Recipient: n.Recipient,
+				Kind:      n.Kind,
+				Subject:   n.Subject,
+				Body:      n.Body,
+				Attempts:  n.Attempt,
+				Error:     sendErr.Error(),
+				CreatedAt: n.CreatedAt,
+				FailedAt:  time.Now().UTC().Truncate(time.Microsecond),
+			}
+			if err := tx.AddNotificationFailure(ctx, &failure); err != nil {
+				return err
+			}
+			return tx.DeleteNotification(ctx, n.Id)
+		})
+	default:
+		n.NextAttemptAt = time.Now().UTC().Add(notificationRetryDelays[n.Attempt-1])
+		err = store.UpdateNotification(ctx, n)
+	}
+	if err != nil {
+		// The lease runs out and the attempt is repeated.
+		log.Printf("Notification update error: %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// webPushChannel sends notifications to browsers with the Web Push
+// protocol: the payload is encrypted for the subscription (RFC 8291) and
+// the request signed with the server's VAPID key (RFC 8292). Notifications
+// are addressed to subscription endpoints.
+type webPushChannel struct {
+	// privateKey is the configured VAPID key, which the secrets manager's
+	// replaces.
+	privateKey string
+	// subject is a mailto: or https: URL push services can reach the
+	// operator at.
+	subject string
+}
+
+// Open the Web Push channel if the config has a VAPID key pair, or return
+// nil.
+func openWebPushChannel(cfg Config) (*webPushChannel, error) {
+	if cfg.VAPIDPrivateKey == "" {
+		return nil, nil
+	}
+	if cfg.VAPIDSubject == "" {
+		return nil, errors.New("vapid_private_key needs vapid_subject")
+	}
+	if _, _, err := parseVAPIDKey(cfg.VAPIDPrivateKey); err != nil {
+		return nil, err
+	}
+	return &webPushChannel{privateKey: cfg.VAPIDPrivateKey, subject: cfg.VAPIDSubject}, nil
+}
+
+// The latest VAPID key, to sign with, and its public key, the
+// uncompressed P-256 point browsers subscribe with.
+func (c *webPushChannel) keys() (*ecdsa.PrivateKey, []byte, error) {
+	return parseVAPIDKey(secrets.get("vapid_private_key", c.privateKey))
+}
+
+// The VAPID key encoded as the config takes it, and its public key.
+func parseVAPIDKey(encoded string) (*ecdsa.PrivateKey, []byte, error) {
+	d, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil, fmt.Errorf("vapid_private_key: %w", err)
+	}
+	raw, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, nil, fmt.Errorf("vapid_private_key: %w", err)
+	}
+	// The uncompressed point is 0x04, then X and Y.
+	public := raw.PublicKey().Bytes()
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(public[1:33]),
+			Y:     new(big.Int).SetBytes(public[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}
+	return key, public, nil
+}
+
+// Generate a VAPID key pair, base64url encoded as browsers and the
+// config take them.
+func generateVAPIDKeys() (public, private string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()), base64.RawURLEncoding.EncodeToString(key.Bytes()), nil
+}
+
+// pushPayload is what the service worker receives, to show with
+// showNotification.
+type pushPayload struct {
+	Kind  string `json:"kind"`
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+var pushClient = &http.Client{Timeout: 10 * time.Second}
+
+// How long push services keep a message for a browser that is offline.
+const pushTTL = 24 * time.Hour
+
+func (c *webPushChannel) send(ctx context.Context, n Notification) error {
+	sub, err := store.GetPushSubscription(ctx, n.Recipient)
+	if errors.Is(err, ErrNotFound) {
+		// Unsubscribed since the notification was queued.
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(pushPayload{Kind: n.Kind, Title: n.Subject, Body: n.Body})
+	if err != nil {
+		return err
+	}
+	body, err := encryptPushPayload(sub.Keys, payload)
+	if err != nil {
+		return err
+	}
+	authorization, err := c.authorization(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", fmt.Sprint(int(pushTTL.Seconds())))
+
+	res, err := pushClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	switch {
+	case res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone:
+		// The browser unsubscribed or the subscription expired.
+		if err := store.DeletePushSubscription(ctx, sub.Endpoint); err != nil && !errors.Is(err, ErrNotFound) {
+			log.Printf("Push subscription deletion error: %v", err)
+		}
+		return nil
+	case res.StatusCode < 200 || res.StatusCode > 299:
+		return fmt.Errorf("push: unexpected status %s", res.Status)
+	}
+	return nil
+}
+
+// The VAPID Authorization header for a push to endpoint: a JWT for the
+// push service's origin, signed with ES256, and the public key.
+func (c *webPushChannel) authorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	privateKey, publicKey, err := c.keys()
+	if err != nil {
+		return "", err
+	}
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]any{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": c.subject,
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest[:])
+	if err != nil {
+		return "", err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	token := signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+	return "vapid t=" + token + ", k=" + base64.RawURLEncoding.EncodeToString(publicKey), nil
+}
+
+// The record size written in the aes128gcm header; the payload always
+// fits in the one record.
+const pushRecordSize = 4096
+
+// Encrypt payload for the subscription as a single aes128gcm record, as
+// RFC 8291 describes.
+func encryptPushPayload(keys PushKeys, payload []byte) ([]byte, error) {
+	// Browsers send the keys base64url encoded, some with padding.
+	uaPublic, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(keys.P256dh, "="))
+	if err != nil {
+		return nil, fmt.Errorf("p256dh: %w", err)
+	}
+	authSecret, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(keys.Auth, "="))
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	uaKey, err := ecdh.P256().NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, fmt.Errorf("p256dh: %w", err)
+	}
+
+	// A key pair of our own for this message only.
+	asKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asKey.PublicKey().Bytes()
+	shared, err := asKey.ECDH(uaKey)
+	if err != nil {
+		return nil, err
+	}
+
+	prkKey := hkdfExtract(shared, authSecret)
+	keyInfo := "WebPush: info\x00" + string(uaPublic) + string(asPublic)
+	ikm := hkdfExpand(prkKey, keyInfo, 32)
+
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	prk := hkdfExtract(ikm, salt)
+	cek := hkdfExpand(prk, "Content-Encoding: aes128gcm\x00", 16)
+	nonce := hkdfExpand(prk, "Content-Encoding: nonce\x00", 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// 0x02 marks the last record, with no padding after it.
+	plaintext := append(append([]byte{}, payload...), 0x02)
+	if len(plaintext)+gcm.Overhead() > pushRecordSize-86 {
+		return nil, errors.New("push: payload too large")
+	}
+
+	header := make([]byte, 0, 86)
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, pushRecordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+// The pseudorandom key HKDF-SHA256 (RFC 5869) extracts from secret.
+func hkdfExtract(secret, salt []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(secret)
+	return mac.Sum(nil)
+}
+
+// The first length bytes HKDF-SHA256 expands prk to with info; the
+// lengths Web Push needs are within the first block.
+func hkdfExpand(prk []byte, info string, length int) []byte {
+	mac := hmac.New(sha256.New, prk)
+	mac.Write([]byte(info))
+	mac.Write([]byte{1})
+	return mac.Sum(nil)[:length]
+}
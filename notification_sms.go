@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SMSProvider sends text messages to phone numbers in E.164 format.
+type SMSProvider interface {
+	SendSMS(ctx context.Context, to, body string) error
+}
+
+// Open the SMS channel for the provider selected by the config, or return
+// nil if none is.
+func openSMSChannel(cfg Config) (notificationChannel, error) {
+	switch cfg.SMSProvider {
+	case "", "none":
+		return nil, nil
+	case "twilio":
+		if cfg.SMSAccountSID == "" || cfg.SMSAuthToken == "" || cfg.SMSFrom == "" {
+			return nil, errors.New("sms_provider twilio needs sms_account_sid, sms_auth_token and sms_from")
+		}
+		return smsChannel{provider: twilioSMS{
+			url:        strings.TrimRight(cfg.SMSURL, "/"),
+			accountSID: cfg.SMSAccountSID,
+			authToken:  cfg.SMSAuthToken,
+			from:       cfg.SMSFrom,
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unknown SMS provider %q", cfg.SMSProvider)
+	}
+}
+
+// smsChannel sends notifications as texts through a provider. Texts have
+// no subject; the body is the whole message.
+type smsChannel struct {
+	provider SMSProvider
+}
+
+func (c smsChannel) send(ctx context.Context, n Notification) error {
+	return c.provider.SendSMS(ctx, n.Recipient, n.Body)
+}
+
+var smsClient = &http.Client{Timeout: 10 * time.Second}
+
+// twilioSMS sends texts with Twilio's Messages API, which other providers
+// copy; url points it at one of them instead of Twilio.
+type twilioSMS struct {
+	url        string
+	accountSID string
+	// authToken is the configured one, which the secrets manager's
+	// replaces.
+	authToken string
+	from      string
+}
+
+func (p twilioSMS) SendSMS(ctx context.Context, to, body string) error {
+	form := url.Values{"To": {to}, "From": {p.from}, "Body": {body}}
+	endpoint := p.url + "/2010-04-01/Accounts/" + url.PathEscape(p.accountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(p.accountSID, secrets.get("sms_auth_token", p.authToken))
+
+	res, err := smsClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		// Errors carry Twilio's code and a message saying what was wrong.
+		var problem struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+		if json.NewDecoder(io.LimitReader(res.Body, 64<<10)).Decode(&problem) == nil && problem.Message != "" {
+			return fmt.Errorf("sms: %s: %s (%d)", res.Status, problem.Message, problem.Code)
+		}
+		return fmt.Errorf("sms: unexpected status %s", res.Status)
+	}
+	return nil
+}
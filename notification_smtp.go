@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"time"
+)
+
+// smtpChannel sends notifications as plain text or HTML emails through
+// an SMTP server, upgrading to TLS when the server offers it.
+type smtpChannel struct {
+	addr     string
+	host     string
+	from     string
+	username string
+	// password is the configured one, which the secrets manager's replaces.
+	password string
+}
+
+// How long connecting to the mail server and sending one message may take.
+const smtpTimeout = 30 * time.Second
+
+func newSMTPChannel(cfg Config) (*smtpChannel, error) {
+	host, _, err := net.SplitHostPort(cfg.SMTPAddr)
+	if err != nil {
+		return nil, fmt.Errorf("smtp_addr: %w", err)
+	}
+	if _, err := mail.ParseAddress(cfg.EmailFrom); err != nil {
+		return nil, fmt.Errorf("email_from: %w", err)
+	}
+
+	return &smtpChannel{addr: cfg.SMTPAddr, host: host, from: cfg.EmailFrom, username: cfg.SMTPUsername, password: cfg.SMTPPassword}, nil
+}
+
+func (c *smtpChannel) validRecipient(recipient string) bool {
+	addr, err := mail.ParseAddress(recipient)
+	return err == nil && addr.Address == recipient
+}
+
+func (c *smtpChannel) send(ctx context.Context, n Notification) error {
+	dialer := net.Dialer{Timeout: smtpTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, c.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: c.host}); err != nil {
+			return err
+		}
+	}
+	if c.username != "" {
+		auth := smtp.PlainAuth("", c.username, secrets.get("smtp_password", c.password), c.host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	from, _ := mail.ParseAddress(c.from)
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(n.Recipient); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(c.message(n)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// The email for n: a UTF-8 text/plain message, quoted-printable encoded,
+// or if n has HTML, that and the text as alternatives.
+func (c *smtpChannel) message(n Notification) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", c.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", n.Recipient)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", n.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <notification-%d-%d@%s>\r\n", n.Id, n.CreatedAt.Unix(), c.host)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	if n.HTML == "" {
+		writeQuotedPrintable(&msg, "text/plain", n.Body)
+		return msg.Bytes()
+	}
+
+	// Readers show the last alternative they can, so the HTML goes last.
+	parts := multipart.NewWriter(&msg)
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+	for _, part := range []struct{ mediaType, body string }{{"text/plain", n.Body}, {"text/html", n.HTML}} {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.mediaType+"; charset=utf-8")
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		w, _ := parts.CreatePart(header)
+		qp := quotedprintable.NewWriter(w)
+		qp.Write([]byte(part.body))
+		qp.Close()
+	}
+	parts.Close()
+	return msg.Bytes()
+}
+
+// Write the headers and quoted-printable body of a single-part message.
+func writeQuotedPrintable(msg *bytes.Buffer, mediaType, body string) {
+	fmt.Fprintf(msg, "Content-Type: %s; charset=utf-8\r\n", mediaType)
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(msg)
+	qp.Write([]byte(body))
+	qp.Close()
+}
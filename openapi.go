@@ -0,0 +1,999 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// apiDoc describes one operation for the OpenAPI document. Request and
+// response bodies are given as zero values of the Go types we encode, so
+// the schemas follow the structs.
+type apiDoc struct {
+	Summary   string
+	Query     []string
+	Request   any
+	Responses map[int]any
+}
+
+// Documentation for the versioned routes, keyed by method and path
+// without the /api/vN prefix. Routes missing here are still listed.
+var apiDocs = map[string]apiDoc{
+	"POST /book": {
+		Summary:   "Create a book",
+		Request:   Book{},
+		Responses: map[int]any{201: BookResponse{}, 400: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"GET /book/{id}": {
+		Summary:   "Get a book",
+		Query:     []string{"fields", "include", "currency"},
+		Responses: map[int]any{200: BookResponse{}, 301: nil, 304: nil, 400: Problem{}, 404: Problem{}, 500: Problem{}, 503: Problem{}},
+	},
+	"GET /book/slug/{slug}": {
+		Summary:   "Get a book by its slug",
+		Query:     []string{"fields", "include", "currency"},
+		Responses: map[int]any{200: BookResponse{}, 304: nil, 400: Problem{}, 404: Problem{}, 500: Problem{}, 503: Problem{}},
+	},
+	"PUT /book/{id}": {
+		Summary:   "Update the non-empty fields of a book",
+		Request:   Book{},
+		Responses: map[int]any{200: BookResponse{}, 400: Problem{}, 404: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"DELETE /book/{id}": {
+		Summary:   "Delete a book and its reviews",
+		Responses: map[int]any{200: Response{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /book/{id}/barcode": {
+		Summary:   "A PNG or SVG barcode label of the book's ISBN (EAN-13) or id (Code 128)",
+		Query:     []string{"value", "format", "size"},
+		Responses: map[int]any{200: nil, 304: nil, 400: Problem{}, 404: Problem{}, 422: Problem{}, 500: Problem{}},
+	},
+	"GET /book/{id}/qrcode": {
+		Summary:   "A PNG or SVG QR code label of the book's ISBN or id",
+		Query:     []string{"value", "format", "size"},
+		Responses: map[int]any{200: nil, 304: nil, 400: Problem{}, 404: Problem{}, 422: Problem{}, 500: Problem{}},
+	},
+	"GET /book/{id}/subjects": {
+		Summary:   "The categories and tags of a book",
+		Responses: map[int]any{200: BookSubjectsResponse{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"PUT /book/{id}/subjects": {
+		Summary:   "Replace the categories and tags of a book",
+		Request:   BookSubjects{},
+		Responses: map[int]any{200: BookSubjectsResponse{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /book/{id}/similar": {
+		Summary:   "Books sharing the book's author, categories or tags, best first",
+		Query:     []string{"limit"},
+		Responses: map[int]any{200: SimilarBooksResponse{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /lookup/{barcode}": {
+		Summary:   "Find the book with a scanned ISBN or label in the catalog, or else with the metadata provider for import",
+		Responses: map[int]any{200: BookLookupResponse{}, 400: Problem{}, 404: Problem{}, 500: Problem{}, 503: Problem{}},
+	},
+	"GET /series": {
+		Summary:   "List the series, by name",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: SeriesListResponse{}, 400: Problem{}, 500: Problem{}},
+	},
+	"POST /series": {
+		Summary:   "Create a series",
+		Request:   Series{},
+		Responses: map[int]any{201: SeriesResponse{}, 400: Problem{}, 500: Problem{}},
+	},
+	"GET /series/{id}": {
+		Summary:   "One series",
+		Responses: map[int]any{200: SeriesResponse{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"PUT /series/{id}": {
+		Summary:   "Replace a series' name and description",
+		Request:   Series{},
+		Responses: map[int]any{200: SeriesResponse{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"DELETE /series/{id}": {
+		Summary:   "Delete a series; its books stay in the catalog",
+		Responses: map[int]any{200: Response{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /series/{id}/books": {
+		Summary:   "The volumes of a series in reading order",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: SeriesBooksResponse{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"PUT /series/{id}/books/{book_id}": {
+		Summary:   "Make a book a volume of a series, at a position or after the last one, moving it from any other series",
+		Request:   SeriesPosition{},
+		Responses: map[int]any{200: SeriesBooksResponse{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"DELETE /series/{id}/books/{book_id}": {
+		Summary:   "Take a book out of a series",
+		Responses: map[int]any{200: Response{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /works": {
+		Summary:   "List the works with their editions, by title",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: WorksResponse{}, 400: Problem{}, 500: Problem{}},
+	},
+	"POST /works": {
+		Summary:   "Create a work, without editions",
+		Request:   Work{},
+		Responses: map[int]any{201: WorkResponse{}, 400: Problem{}, 500: Problem{}},
+	},
+	"GET /works/search": {
+		Summary:   "Search books by title or author, one result per work with all its editions",
+		Query:     []string{"q"},
+		Responses: map[int]any{200: WorksResponse{}, 400: Problem{}, 500: Problem{}},
+	},
+	"GET /works/{id}": {
+		Summary:   "One work with its editions",
+		Responses: map[int]any{200: WorkResponse{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"PUT /works/{id}": {
+		Summary:   "Replace a work's title and author",
+		Request:   Work{},
+		Responses: map[int]any{200: WorkResponse{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"DELETE /works/{id}": {
+		Summary:   "Delete a work; its editions stay in the catalog, ungrouped",
+		Responses: map[int]any{200: Response{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"PUT /works/{id}/editions/{book_id}": {
+		Summary:   "Make a book an edition of a work, moving it from any other work",
+		Responses: map[int]any{200: WorkResponse{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"DELETE /works/{id}/editions/{book_id}": {
+		Summary:   "Ungroup a book from a work",
+		Responses: map[int]any{200: WorkResponse{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /books": {
+		Summary:   "List all books, or one page of them, by id or by title in the collation of a locale",
+		Query:     []string{"page", "per_page", "fields", "include", "book_format", "created_after", "updated_after", "include_archived", "sort", "locale", "format", "currency"},
+		Responses: map[int]any{200: BooksResponse{}, 304: nil, 400: Problem{}, 500: Problem{}, 503: Problem{}},
+	},
+	"GET /books/search": {
+		Summary:   "Search books by title or author, ranked by relevance and forgiving typos unless fuzzy is false, or with advanced query syntax, with facet counts and highlighted matches",
+		Query:     []string{"q", "fuzzy", "page", "per_page", "fields", "include", "book_format", "created_after", "updated_after", "include_archived", "sort", "locale", "format", "currency"},
+		Responses: map[int]any{200: BooksResponse{}, 400: Problem{}, 500: Problem{}, 503: Problem{}},
+	},
+	"GET /books/suggest": {
+		Summary:   "Titles and authors completing what a reader is typing, for search boxes",
+		Query:     []string{"q", "limit"},
+		Responses: map[int]any{200: SuggestionsResponse{}, 400: Problem{}, 500: Problem{}},
+	},
+	"DELETE /books": {
+		Summary:   "Delete all books",
+		Responses: map[int]any{200: Response{}, 500: Problem{}},
+	},
+	"GET /books/trending": {
+		Summary:   "The books viewed, lent or sold most over the last day, week or month",
+		Query:     []string{"window", "by", "limit"},
+		Responses: map[int]any{200: TrendingBooksResponse{}, 400: Problem{}, 500: Problem{}},
+	},
+	"GET /books/recent": {
+		Summary:   "The books added, or changed, last, newest first",
+		Query:     []string{"by", "limit", "fields", "format", "currency"},
+		Responses: map[int]any{200: BooksResponse{}, 304: nil, 400: Problem{}, 500: Problem{}},
+	},
+	"GET /stats": {
+		Summary:   "Catalog statistics: totals, prices, recent additions and top authors",
+		Responses: map[int]any{200: StatsResponse{}, 304: nil, 500: Problem{}},
+	},
+	"POST /webhooks": {
+		Summary:   "Register a webhook (admin)",
+		Request:   Webhook{},
+		Responses: map[int]any{201: WebhookResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"GET /webhooks": {
+		Summary:   "List webhooks (admin)",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: WebhooksResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"DELETE /webhooks/{id}": {
+		Summary:   "Delete a webhook (admin)",
+		Responses: map[int]any{200: Response{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /webhooks/{id}/deliveries": {
+		Summary:   "Recent delivery attempts of a webhook, newest first (admin)",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: WebhookDeliveriesResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"POST /admin/books/reprice": {
+		Summary:   "Change the prices of the books matching a filter, or preview the change with dry_run (admin)",
+		Request:   RepriceRequest{},
+		Responses: map[int]any{200: RepriceResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"POST /admin/books/merge": {
+		Summary:   "Merge duplicate books into a survivor, moving their reviews, loans, copies, holds and readers' entries to it and deleting them (admin)",
+		Request:   BookMergeRequest{},
+		Responses: map[int]any{200: BookResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /admin/analytics/searches": {
+		Summary:   "Most searched terms and searches that found nothing (admin)",
+		Query:     []string{"days", "limit"},
+		Responses: map[int]any{200: SearchAnalyticsResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"POST /admin/backup": {
+		Summary:   "Back up the catalog now (admin)",
+		Responses: map[int]any{201: BackupResponse{}, 401: Problem{}, 500: Problem{}, 503: Problem{}},
+	},
+	"GET /admin/backups": {
+		Summary:   "List the stored backups, newest first (admin)",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: BackupsResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}, 503: Problem{}},
+	},
+	"GET /admin/jobs": {
+		Summary:   "The background jobs, their schedules and how their last runs went (admin)",
+		Responses: map[int]any{200: JobsResponse{}, 401: Problem{}},
+	},
+	"GET /admin/jobs/{name}": {
+		Summary:   "A background job, how far its run has got and how its last run went (admin)",
+		Responses: map[int]any{200: JobResponse{}, 401: Problem{}, 404: Problem{}},
+	},
+	"POST /admin/jobs/{name}/run": {
+		Summary:   "Start a run of a background job now, scheduled or not (admin)",
+		Responses: map[int]any{202: JobResponse{}, 401: Problem{}, 404: Problem{}, 409: Problem{}},
+	},
+	"POST /admin/restore": {
+		Summary:   "Bring the catalog back to a backup; only describes the restore without confirm (admin)",
+		Request:   RestoreRequest{},
+		Responses: map[int]any{200: RestoreResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 422: Problem{}, 500: Problem{}, 503: Problem{}},
+	},
+	"POST /admin/notifications": {
+		Summary:   "Queue a notification rendered from its kind's template, unless the recipient turned the kind off (admin)",
+		Request:   NotificationRequest{},
+		Responses: map[int]any{202: NotificationResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}, 503: Problem{}},
+	},
+	"GET /admin/notifications/failures": {
+		Summary:   "Notifications given up on after their last attempt failed, newest first (admin)",
+		Query:     []string{"limit", "created_after", "updated_after"},
+		Responses: map[int]any{200: NotificationFailuresResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"GET /admin/notifications/preferences/{recipient}": {
+		Summary:   "The kinds of notification a recipient gets (admin)",
+		Responses: map[int]any{200: NotificationPreferencesResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"PUT /admin/notifications/preferences/{recipient}": {
+		Summary:   "Turn kinds of notification on or off for a recipient (admin)",
+		Request:   NotificationPreferences{},
+		Responses: map[int]any{200: NotificationPreferencesResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"POST /admin/push/subscriptions": {
+		Summary:   "Save a browser's Web Push subscription for a recipient's notifications (admin)",
+		Request:   PushSubscription{},
+		Responses: map[int]any{201: PushSubscriptionResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}, 503: Problem{}},
+	},
+	"POST /admin/users": {
+		Summary:   "Create an admin user of the tenant, getting their token (admin)",
+		Request:   User{},
+		Responses: map[int]any{201: UserTokenResponse{}, 400: Problem{}, 401: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"POST /admin/tenants": {
+		Summary:   "Create a tenant and its first admin user (operator)",
+		Request:   CreateTenantRequest{},
+		Responses: map[int]any{201: TenantAdminResponse{}, 400: Problem{}, 401: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"GET /admin/tenants": {
+		Summary:   "List the tenants (operator)",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: TenantsResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"POST /users": {
+		Summary:   "Sign up, getting the token to send to the /me routes",
+		Request:   User{},
+		Responses: map[int]any{201: UserTokenResponse{}, 400: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"GET /me": {
+		Summary:   "The signed-in user",
+		Responses: map[int]any{200: UserResponse{}, 401: Problem{}, 500: Problem{}},
+	},
+	"GET /me/export": {
+		Summary:   "Everything kept about the signed-in user, to take away",
+		Responses: map[int]any{200: UserExportResponse{}, 401: Problem{}, 500: Problem{}},
+	},
+	"POST /me/delete": {
+		Summary:   "Delete the signed-in user's account and personal data, anonymizing their reviews, memberships and orders",
+		Responses: map[int]any{200: Response{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /me/shelf": {
+		Summary:   "The books on the user's shelf, in the order they were added",
+		Query:     []string{"status", "created_after", "updated_after"},
+		Responses: map[int]any{200: ShelfResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"GET /me/books": {
+		Summary:   "The books on the user's shelf with a reading status, as GET /me/shelf",
+		Query:     []string{"status", "created_after", "updated_after"},
+		Responses: map[int]any{200: ShelfResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"GET /me/books/{id}/notes": {
+		Summary:   "The user's notes on a book, newest first",
+		Query:     []string{"q", "created_after", "updated_after"},
+		Responses: map[int]any{200: NotesResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"POST /me/books/{id}/notes": {
+		Summary:   "Add a private note or highlight to a book",
+		Request:   Note{},
+		Responses: map[int]any{201: NoteResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /me/notes": {
+		Summary:   "The user's notes on all books, newest first, or those containing every word in q",
+		Query:     []string{"q", "created_after", "updated_after"},
+		Responses: map[int]any{200: NotesResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"GET /me/notes/{id}": {
+		Summary:   "One of the user's notes",
+		Responses: map[int]any{200: NoteResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"PUT /me/notes/{id}": {
+		Summary:   "Replace the kind, text and page of a note",
+		Request:   Note{},
+		Responses: map[int]any{200: NoteResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"DELETE /me/notes/{id}": {
+		Summary:   "Delete a note",
+		Responses: map[int]any{200: Response{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /me/searches": {
+		Summary:   "The user's saved searches, oldest first",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: SavedSearchesResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"POST /me/searches": {
+		Summary:   "Save a search to be notified of new books matching it",
+		Request:   SavedSearch{},
+		Responses: map[int]any{201: SavedSearchResponse{}, 400: Problem{}, 401: Problem{}, 422: Problem{}, 500: Problem{}},
+	},
+	"DELETE /me/searches/{id}": {
+		Summary:   "Delete a saved search",
+		Responses: map[int]any{200: Response{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /members": {
+		Summary:   "Every library member, by name (admin)",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: MembersResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"POST /members": {
+		Summary:   "Register a library member, with a random card number unless one is given (admin)",
+		Request:   Member{},
+		Responses: map[int]any{201: MemberResponse{}, 400: Problem{}, 401: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"GET /members/{id}": {
+		Summary:   "A library member (the member or admin)",
+		Responses: map[int]any{200: MemberResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /members/card/{number}": {
+		Summary:   "The member a library card belongs to (admin)",
+		Responses: map[int]any{200: MemberResponse{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"PUT /members/{id}": {
+		Summary:   "Replace a member's details, keeping the card number and expiry date unless given (admin)",
+		Request:   Member{},
+		Responses: map[int]any{200: MemberResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"DELETE /members/{id}": {
+		Summary:   "Delete a member without books on loan, and their loan history (admin)",
+		Responses: map[int]any{200: Response{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"POST /loans": {
+		Summary:   "Lend a book to a member, due in three weeks unless due_at is given (admin)",
+		Request:   Loan{},
+		Responses: map[int]any{201: LoanResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"POST /loans/{id}/return": {
+		Summary:   "Record a loan as returned (admin)",
+		Responses: map[int]any{200: LoanResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"GET /members/{id}/loan-history": {
+		Summary:   "Every loan of a member, newest first, with their stats (the member or admin)",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: LoanHistoryResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /book/{id}/availability": {
+		Summary:   "Each copy of a book's status and due date, and the length of its hold queue",
+		Responses: map[int]any{200: AvailabilityResponse{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /book/{id}/nearby": {
+		Summary:   "The branches with a copy of a book free to borrow, nearest to ?lat= and ?lng= first",
+		Query:     []string{"lat", "lng", "limit"},
+		Responses: map[int]any{200: NearbyBranchesResponse{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /book/{id}/copies": {
+		Summary:   "A book's copies, with the loans of those that are out (admin)",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: CopiesResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"POST /book/{id}/copies": {
+		Summary:   "Add a copy of a book, labelled with its barcode, at a branch if given (admin)",
+		Request:   Copy{},
+		Responses: map[int]any{201: CopyResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"DELETE /copies/{id}": {
+		Summary:   "Delete a copy that isn't on loan (admin)",
+		Responses: map[int]any{200: Response{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"PUT /copies/{id}/branch": {
+		Summary:   "Move a copy to a branch, or to none (admin)",
+		Request:   CopyBranchRequest{},
+		Responses: map[int]any{200: CopyResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /branches": {
+		Summary:   "The library's branches and where they are, by name",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: BranchesResponse{}, 400: Problem{}, 500: Problem{}},
+	},
+	"POST /branches": {
+		Summary:   "Add a branch at a latitude and longitude (admin)",
+		Request:   Branch{},
+		Responses: map[int]any{201: BranchResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"PUT /branches/{id}": {
+		Summary:   "Replace a branch's name, address and location (admin)",
+		Request:   Branch{},
+		Responses: map[int]any{200: BranchResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"DELETE /branches/{id}": {
+		Summary:   "Delete a branch, leaving its copies at none (admin)",
+		Responses: map[int]any{200: Response{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /book/{id}/holds": {
+		Summary:   "The holds on a book, in the order they are served (admin)",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: HoldsResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"POST /holds": {
+		Summary:   "Put a member in the queue for a book (admin)",
+		Request:   Hold{},
+		Responses: map[int]any{201: HoldResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"DELETE /holds/{id}": {
+		Summary:   "Cancel a hold (admin)",
+		Responses: map[int]any{200: Response{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"POST /circulation/checkout": {
+		Summary:   "Lend the scanned copy to the member whose card was scanned, checking their membership, loan limit and the book's holds (admin)",
+		Request:   CheckoutRequest{},
+		Responses: map[int]any{201: LoanResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 422: Problem{}, 500: Problem{}},
+	},
+	"POST /circulation/checkin": {
+		Summary:   "Take back the scanned copy, setting it aside for the first member with a hold on the book (admin)",
+		Request:   CheckinRequest{},
+		Responses: map[int]any{200: CheckinResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"GET /members/{id}/fines": {
+		Summary:   "A member's fines, newest first, with what was paid (the member or admin)",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: FinesResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"POST /members/{id}/fines": {
+		Summary:   "Fine a member, for a lost or damaged book or the like (admin)",
+		Request:   Fine{},
+		Responses: map[int]any{201: FineResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"POST /fines/{id}/payments": {
+		Summary:   "Record a cash or card payment towards a fine, answering with its receipt (admin)",
+		Request:   FinePayment{},
+		Responses: map[int]any{201: ReceiptResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"POST /fines/{id}/settle": {
+		Summary:   "Mark a fine settled, waiving what is left to pay (admin)",
+		Responses: map[int]any{200: FineResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"GET /payments/{id}/receipt": {
+		Summary:   "The receipt for a fine payment, to reprint it (admin)",
+		Responses: map[int]any{200: ReceiptResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /admin/reports/outstanding-fines": {
+		Summary:   "What every member with unsettled fines owes, most first (admin)",
+		Responses: map[int]any{200: OutstandingReportResponse{}, 401: Problem{}, 500: Problem{}},
+	},
+	"GET /admin/access-log": {
+		Summary:   "Who viewed members' personal data, newest first (admin)",
+		Query:     []string{"member_id", "actor", "since", "until", "limit", "created_after", "updated_after"},
+		Responses: map[int]any{200: MemberAccessResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"GET /donations": {
+		Summary:   "Donations with a status, oldest first: the triage queue of pending ones by default (admin)",
+		Query:     []string{"status", "created_after", "updated_after"},
+		Responses: map[int]any{200: DonationsResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"POST /donations": {
+		Summary:   "Record a donated book, pending triage (admin)",
+		Request:   Donation{},
+		Responses: map[int]any{201: DonationResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"GET /donations/{id}": {
+		Summary:   "A donation (admin)",
+		Responses: map[int]any{200: DonationResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"POST /donations/{id}/accept": {
+		Summary:   "Accept a donation, entering it in the catalog with an optional copy (admin)",
+		Request:   DonationDecision{},
+		Responses: map[int]any{200: DonationResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"POST /donations/{id}/reject": {
+		Summary:   "Reject a donation (admin)",
+		Request:   DonationDecision{},
+		Responses: map[int]any{200: DonationResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"GET /admin/reports/donations": {
+		Summary:   "A year's accepted donations by donor with their appraised values, for tax letters (admin)",
+		Query:     []string{"year"},
+		Responses: map[int]any{200: DonationReportResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"GET /suppliers": {
+		Summary:   "The suppliers books are ordered from, by name (admin)",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: SuppliersResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"POST /suppliers": {
+		Summary:   "Add a supplier (admin)",
+		Request:   Supplier{},
+		Responses: map[int]any{201: SupplierResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"GET /suppliers/{id}": {
+		Summary:   "A supplier (admin)",
+		Responses: map[int]any{200: SupplierResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"PUT /suppliers/{id}": {
+		Summary:   "Replace a supplier's details (admin)",
+		Request:   Supplier{},
+		Responses: map[int]any{200: SupplierResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"DELETE /suppliers/{id}": {
+		Summary:   "Delete a supplier and its prices, unless books were ordered from it (admin)",
+		Responses: map[int]any{200: Response{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"GET /suppliers/{id}/prices": {
+		Summary:   "What a supplier charges for books, cheapest first (admin)",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: SupplierPricesResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"PUT /suppliers/{id}/prices/{book_id}": {
+		Summary:   "Set what a supplier charges for a book and its lead time (admin)",
+		Request:   SupplierPrice{},
+		Responses: map[int]any{200: SupplierPriceResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"DELETE /suppliers/{id}/prices/{book_id}": {
+		Summary:   "Remove what a supplier charges for a book (admin)",
+		Responses: map[int]any{200: Response{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /book/{id}/suppliers": {
+		Summary:   "What each supplier charges for a book, cheapest and then quickest first (admin)",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: SupplierPricesResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /purchase-orders": {
+		Summary:   "Purchase orders with their lines, oldest first, optionally with a status (admin)",
+		Query:     []string{"status", "supplier_id", "created_after", "updated_after"},
+		Responses: map[int]any{200: PurchaseOrdersResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"POST /purchase-orders": {
+		Summary:   "Place an order with a supplier for copies of books (admin)",
+		Request:   PurchaseOrder{},
+		Responses: map[int]any{201: PurchaseOrderResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /purchase-orders/{id}": {
+		Summary:   "A purchase order with its lines (admin)",
+		Responses: map[int]any{200: PurchaseOrderResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"POST /purchase-orders/{id}/receive": {
+		Summary:   "Receive a delivery against an open purchase order, adding it to stock (admin)",
+		Request:   OrderDelivery{},
+		Responses: map[int]any{200: PurchaseOrderResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"POST /purchase-orders/{id}/cancel": {
+		Summary:   "Cancel an open purchase order (admin)",
+		Responses: map[int]any{200: PurchaseOrderResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"GET /book/{id}/stock": {
+		Summary:   "How many copies of a book are in stock (admin)",
+		Responses: map[int]any{200: BookStockResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /admin/reports/open-orders": {
+		Summary:   "The open purchase orders with what is still to come and its value (admin)",
+		Responses: map[int]any{200: OpenOrdersReportResponse{}, 401: Problem{}, 500: Problem{}},
+	},
+	"GET /admin/reports/reorder": {
+		Summary:   "Books with fewer copies in stock and on order than the reorder level, with their cheapest suppliers (admin)",
+		Query:     []string{"level"},
+		Responses: map[int]any{200: ReorderReportResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"POST /sales": {
+		Summary:   "Sell copies of books from stock at their catalog prices (admin)",
+		Request:   Sale{},
+		Responses: map[int]any{201: SaleResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"GET /sales/{id}": {
+		Summary:   "A sale with its lines (admin)",
+		Responses: map[int]any{200: SaleResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /sales/{id}/invoice.pdf": {
+		Summary:   "The invoice for a sale as a PDF, labelled in the language of Accept-Language (admin)",
+		Responses: map[int]any{200: nil, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"POST /sales/{id}/ship": {
+		Summary:   "Record a sale as shipped and email its customer (admin)",
+		Request:   SaleShipment{},
+		Responses: map[int]any{200: SaleResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"POST /sales/{id}/emails": {
+		Summary:   "Send a sale's order confirmation or shipping email again (admin)",
+		Request:   SaleEmailRequest{},
+		Responses: map[int]any{202: NotificationResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 500: Problem{}, 503: Problem{}},
+	},
+	"GET /admin/reports/sales": {
+		Summary:   "Revenue and copies sold by day, week, month or category, with the best selling titles; ?format=csv exports the groups (admin)",
+		Query:     []string{"from", "to", "group_by", "limit", "format"},
+		Responses: map[int]any{200: SalesReportResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"POST /gift-cards": {
+		Summary:   "Issue a gift card for an amount, with a new code (admin)",
+		Request:   GiftCard{},
+		Responses: map[int]any{201: GiftCardResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"GET /gift-cards/{code}": {
+		Summary:   "A gift card's balance and the sales it paid towards (admin)",
+		Responses: map[int]any{200: GiftCardResponse{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"POST /gift-cards/{code}/void": {
+		Summary:   "Void a gift card so its balance can't be spent (admin)",
+		Responses: map[int]any{200: GiftCardResponse{}, 401: Problem{}, 404: Problem{}, 409: Problem{}, 500: Problem{}},
+	},
+	"GET /admin/users/{id}/points": {
+		Summary:   "A user's loyalty points balance and history, newest first (admin)",
+		Query:     []string{"limit", "created_after", "updated_after"},
+		Responses: map[int]any{200: LoyaltyPointsResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /me/points": {
+		Summary:   "The user's loyalty points balance, what it is worth at checkout and its history, newest first",
+		Query:     []string{"limit", "created_after", "updated_after"},
+		Responses: map[int]any{200: LoyaltyPointsResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"GET /me/quotes": {
+		Summary:   "The user's quotes, public or not, newest first",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: QuotesResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"POST /me/books/{id}/quotes": {
+		Summary:   "Save a quote from a book, attributed to its author unless given",
+		Request:   Quote{},
+		Responses: map[int]any{201: QuoteResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"PUT /me/quotes/{id}": {
+		Summary:   "Replace the text, author and visibility of a quote",
+		Request:   Quote{},
+		Responses: map[int]any{200: QuoteResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"DELETE /me/quotes/{id}": {
+		Summary:   "Delete a quote",
+		Responses: map[int]any{200: Response{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /book/{id}/quotes": {
+		Summary:   "The public quotes from a book, newest first",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: QuotesResponse{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /book/{id}/prices": {
+		Summary:   "A book's price history, oldest first",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: BookPricesResponse{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /quotes/random": {
+		Summary:   "One public quote, picked at random",
+		Responses: map[int]any{200: QuoteResponse{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /me/goals": {
+		Summary:   "The user's reading goals, by year, with their progress",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: GoalsResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"GET /me/goals/{year}": {
+		Summary:   "The user's reading goal for a year, with its progress by month and genre",
+		Responses: map[int]any{200: GoalResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"PUT /me/goals/{year}": {
+		Summary:   "Set the user's reading goal for a year, in books or pages",
+		Request:   ReadingGoal{},
+		Responses: map[int]any{200: GoalResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"DELETE /me/goals/{year}": {
+		Summary:   "Delete the user's reading goal for a year",
+		Responses: map[int]any{200: Response{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"PATCH /me/books/{id}/progress": {
+		Summary:   "Record the page or percent the user is at in a book on their shelf, with when they'll finish it",
+		Request:   ReadingProgress{},
+		Responses: map[int]any{200: ProgressResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"PUT /me/shelf/{id}": {
+		Summary:   "Put a book on the user's shelf, with an optional rating and reading status",
+		Request:   ShelfItem{},
+		Responses: map[int]any{200: ShelfItemResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"DELETE /me/shelf/{id}": {
+		Summary:   "Take a book off the user's shelf",
+		Responses: map[int]any{200: Response{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /me/wishlist": {
+		Summary:   "The books on the user's wishlist with their target prices, in the order they were added",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: WishlistResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"PUT /me/wishlist/{id}": {
+		Summary:   "Put a book on the user's wishlist, or change the target price they are alerted at",
+		Request:   WishlistItem{},
+		Responses: map[int]any{200: WishlistItemResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"DELETE /me/wishlist/{id}": {
+		Summary:   "Take a book off the user's wishlist",
+		Responses: map[int]any{200: Response{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /me/recommendations": {
+		Summary:   "Books readers of the user's shelf also shelved, best first; recomputed hourly",
+		Query:     []string{"limit"},
+		Responses: map[int]any{200: RecommendationsResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"GET /me/collections": {
+		Summary:   "The user's collections with their books",
+		Query:     []string{"created_after", "updated_after"},
+		Responses: map[int]any{200: CollectionsResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"POST /me/collections": {
+		Summary:   "Create an empty collection",
+		Request:   Collection{},
+		Responses: map[int]any{201: CollectionResponse{}, 400: Problem{}, 401: Problem{}, 500: Problem{}},
+	},
+	"GET /me/collections/{id}": {
+		Summary:   "One of the user's collections, its books in order",
+		Responses: map[int]any{200: CollectionResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"PUT /me/collections/{id}": {
+		Summary:   "Rename a collection",
+		Request:   Collection{},
+		Responses: map[int]any{200: CollectionResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"DELETE /me/collections/{id}": {
+		Summary:   "Delete a collection; its books stay in the catalog",
+		Responses: map[int]any{200: Response{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"PUT /me/collections/{id}/books/{book_id}": {
+		Summary:   "Add a book to the end of a collection",
+		Responses: map[int]any{200: CollectionResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"DELETE /me/collections/{id}/books/{book_id}": {
+		Summary:   "Take a book out of a collection",
+		Responses: map[int]any{200: CollectionResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"PUT /me/collections/{id}/order": {
+		Summary:   "Reorder a collection's books, listing each once",
+		Request:   CollectionOrder{},
+		Responses: map[int]any{200: CollectionResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"PUT /me/collections/{id}/share": {
+		Summary:   "Share a collection read-only at its share_url",
+		Responses: map[int]any{200: CollectionResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"DELETE /me/collections/{id}/share": {
+		Summary:   "Stop sharing a collection, retiring its share_url",
+		Responses: map[int]any{200: CollectionResponse{}, 400: Problem{}, 401: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /collections/shared/{token}": {
+		Summary:   "A collection someone shared",
+		Responses: map[int]any{200: CollectionResponse{}, 304: nil, 404: Problem{}, 500: Problem{}},
+	},
+	"GET /push/key": {
+		Summary:   "The VAPID public key browsers subscribe to pushes with",
+		Responses: map[int]any{200: PushKeyResponse{}, 503: Problem{}},
+	},
+	"POST /push/subscriptions": {
+		Summary:   "Save a browser's Web Push subscription and the books whose price drops it is pushed",
+		Request:   PushSubscription{},
+		Responses: map[int]any{201: PushSubscriptionResponse{}, 400: Problem{}, 404: Problem{}, 500: Problem{}, 503: Problem{}},
+	},
+	"DELETE /push/subscriptions": {
+		Summary:   "Delete a Web Push subscription",
+		Request:   UnsubscribeRequest{},
+		Responses: map[int]any{200: Response{}, 400: Problem{}, 404: Problem{}, 500: Problem{}},
+	},
+}
+
+// Serve the OpenAPI 3 document for the routes registered on r. It is built
+// on first use, once every route exists.
+func openAPIHandler(r *mux.Router) http.HandlerFunc {
+	var once sync.Once
+	var spec []byte
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		once.Do(func() {
+			spec, _ = json.MarshalIndent(buildOpenAPISpec(r), "", "  ")
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(spec)
+	}
+}
+
+func buildOpenAPISpec(r *mux.Router) map[string]any {
+	schemas := map[string]any{}
+	paths := map[string]map[string]any{}
+
+	r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(path, "/api/") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		// Strip /api/vN to find the documentation.
+		rest := strings.TrimPrefix(path, "/api/")
+		_, rest, _ = strings.Cut(rest, "/")
+
+		for _, method := range methods {
+			doc := apiDocs[method+" /"+rest]
+			if paths[path] == nil {
+				paths[path] = map[string]any{}
+			}
+			paths[path][strings.ToLower(method)] = openAPIOperation(path, doc, schemas)
+		}
+		return nil
+	})
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Bookshelf API",
+			"version": "1",
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas},
+	}
+}
+
+func openAPIOperation(path string, doc apiDoc, schemas map[string]any) map[string]any {
+	op := map[string]any{}
+	if doc.Summary != "" {
+		op["summary"] = doc.Summary
+	}
+
+	var params []any
+	for _, segment := range strings.Split(path, "/") {
+		if name, ok := strings.CutPrefix(segment, "{"); ok {
+			name, _, _ = strings.Cut(strings.TrimSuffix(name, "}"), ":")
+			params = append(params, map[string]any{
+				"name": name, "in": "path", "required": true,
+				"schema": map[string]any{"type": "string"},
+			})
+		}
+	}
+	for _, name := range doc.Query {
+		params = append(params, map[string]any{
+			"name": name, "in": "query",
+			"schema": map[string]any{"type": "string"},
+		})
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	if doc.Request != nil {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": schemaFor(reflect.TypeOf(doc.Request), schemas)},
+			},
+		}
+	}
+
+	responses := map[string]any{}
+	statuses := make([]int, 0, len(doc.Responses))
+	for status := range doc.Responses {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	for _, status := range statuses {
+		response := map[string]any{"description": http.StatusText(status)}
+		if body := doc.Responses[status]; body != nil {
+			contentType := mediaTypeJSON
+			if _, ok := body.(Problem); ok {
+				contentType = mediaTypeProblemJSON
+			}
+			response["content"] = map[string]any{
+				contentType: map[string]any{"schema": schemaFor(reflect.TypeOf(body), schemas)},
+			}
+		}
+		responses[strconv.Itoa(status)] = response
+	}
+	if len(responses) == 0 {
+		responses["default"] = map[string]any{"description": "Response"}
+	}
+	op["responses"] = responses
+	return op
+}
+
+// Describe a Go type as a JSON schema, adding named structs to schemas
+// and referring to them by $ref.
+func schemaFor(t reflect.Type, schemas map[string]any) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem(), schemas)}
+	case reflect.Struct:
+		ref := map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+		if _, ok := schemas[t.Name()]; ok {
+			return ref
+		}
+		// Placeholder first so self-referencing types terminate.
+		schemas[t.Name()] = map[string]any{}
+
+		properties := map[string]any{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = schemaFor(field.Type, schemas)
+		}
+		schemas[t.Name()] = map[string]any{"type": "object", "properties": properties}
+		return ref
+	default:
+		return map[string]any{}
+	}
+}
+
+// Swagger UI for the document at /openapi.json.
+func docsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(swaggerUIPage))
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Bookshelf API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+)
+
+const (
+	// How often the relay looks for events without being woken, which
+	// picks up events left by a crash or written by other instances.
+	outboxPollInterval = time.Second
+	outboxBatchSize    = 100
+	// How long the message bus gets to accept a batch.
+	outboxPublishTimeout = 10 * time.Second
+)
+
+// Woken after a transaction commits outbox events, and after the relay
+// queues webhook jobs.
+var (
+	outboxWake  = make(chan struct{}, 1)
+	webhookWake = make(chan struct{}, 1)
+)
+
+// Signal ch without blocking; one pending signal is enough.
+func wake(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// Hand outbox events on until the process exits: each batch is turned into
+// webhook jobs and removed from the outbox in one transaction, so an event
+// is queued for every webhook exactly once even across crashes, and then
+// published to bus for live subscribers. With a publisher the batch is
+// also sent to the message bus before the transaction commits; a failure
+// rolls it back to be retried, so the bus may see an event more than once
+// but never misses one.
+func relayOutbox(bus *eventBus, publisher EventPublisher) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := relayOutboxBatch(context.Background(), bus, publisher)
+			if err != nil {
+				log.Printf("Outbox relay error: %v", err)
+				break
+			}
+			if n < outboxBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-outboxWake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Relay up to one batch of events and report how many there were.
+func relayOutboxBatch(ctx context.Context, bus *eventBus, publisher EventPublisher) (int, error) {
+	var batch []Event
+	queued, notified := false, false
+	err := store.WithTx(ctx, func(tx BookStore) error {
+		var err error
+		batch, err = tx.PendingOutboxEvents(ctx, outboxBatchSize)
+		if err != nil || len(batch) == 0 {
+			return err
+		}
+
+		// The batch mixes tenants; each event goes to its own tenant's
+		// webhooks.
+		hooks := map[int][]Webhook{}
+		ids := make([]int64, len(batch))
+		now := time.Now().UTC()
+		for i, event := range batch {
+			ids[i] = event.Id
+			ctx := withTenantId(ctx, event.TenantId)
+			if _, ok := hooks[event.TenantId]; !ok {
+				if hooks[event.TenantId], err = tx.ListWebhooks(ctx, ChangedAfter{}); err != nil {
+					return err
+				}
+			}
+			if err := recordBookPrice(ctx, tx, event); err != nil {
+				return err
+			}
+			dropped, err := queuePriceDrops(ctx, tx, event)
+			if err != nil {
+				return err
+			}
+			notified = notified || dropped
+			for _, hook := range hooks[event.TenantId] {
+				if len(hook.Events) > 0 && !slices.Contains(hook.Events, event.Type) {
+					continue
+				}
+				if err := tx.EnqueueWebhookJob(ctx, &WebhookJob{WebhookId: hook.Id, Event: event, NextAttemptAt: now}); err != nil {
+					return err
+				}
+				queued = true
+			}
+		}
+
+		if err := tx.DeleteOutboxEvents(ctx, ids); err != nil {
+			return err
+		}
+		if publisher != nil {
+			ctx, cancel := context.WithTimeout(ctx, outboxPublishTimeout)
+			defer cancel()
+			return publisher.Publish(ctx, batch)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if queued {
+		wake(webhookWake)
+	}
+	if notified {
+		wake(notificationWake)
+	}
+	bus.Publish(batch...)
+	suggestions.relayed(batch)
+	return len(batch), nil
+}
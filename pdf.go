@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const mediaTypePDF = "application/pdf"
+
+// A4 in points, the unit of PDF coordinates, which start at the bottom
+// left of the page.
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+)
+
+// The standard fonts every PDF reader has, so none is embedded. Courier
+// is monospaced, so columns of figures can be aligned by counting
+// characters.
+const (
+	pdfRegular = "F1"
+	pdfBold    = "F2"
+	pdfMono    = "F3"
+)
+
+var pdfFonts = []struct{ name, base string }{
+	{pdfRegular, "Helvetica"},
+	{pdfBold, "Helvetica-Bold"},
+	{pdfMono, "Courier"},
+}
+
+// pdfDocument draws text and lines on pages, enough for invoices, and
+// writes them out as a PDF.
+type pdfDocument struct {
+	pages []*bytes.Buffer
+}
+
+// Start a new page and return its content stream.
+func (d *pdfDocument) addPage() *bytes.Buffer {
+	page := &bytes.Buffer{}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+func (d *pdfDocument) page() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		return d.addPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// Write s on the current page in font at size, starting at x, y.
+// Characters outside Windows-1252 show as question marks.
+func (d *pdfDocument) text(font string, size, x, y float64, s string) {
+	fmt.Fprintf(d.page(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfString(s))
+}
+
+// Write s in Courier at size so that it ends at x.
+func (d *pdfDocument) textRight(size, x, y float64, s string) {
+	width := float64(len([]rune(s))) * size * 0.6
+	d.text(pdfMono, size, x-width, y, s)
+}
+
+// Draw a thin line on the current page.
+func (d *pdfDocument) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.page(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+// Encode s as the body of a PDF string in Windows-1252, the encoding the
+// fonts are declared with.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		c, ok := charmap.Windows1252.EncodeRune(r)
+		if !ok {
+			c = '?'
+		}
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			if c < 0x20 || c > 0x7e {
+				fmt.Fprintf(&b, "\\%03o", c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	return b.String()
+}
+
+// The document as a PDF file: the catalog, the page tree, the fonts and
+// each page with its content stream, then the cross-reference table
+// giving where each object starts.
+func (d *pdfDocument) bytes(title string) []byte {
+	if len(d.pages) == 0 {
+		d.addPage()
+	}
+	var out bytes.Buffer
+	var offsets []int
+	object := func(format string, args ...any) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n", len(offsets))
+		fmt.Fprintf(&out, format, args...)
+		out.WriteString("\nendobj\n")
+	}
+
+	// Objects 1 and 2 are the catalog and the page tree, 3 the document
+	// information, then the fonts and two objects for each page.
+	firstPage := 4 + len(pdfFonts)
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	object("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages))
+	object("<< /Title (%s) /Producer (bookshelf) >>", pdfString(title))
+	var fonts []string
+	for i, font := range pdfFonts {
+		object("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", font.base)
+		fonts = append(fonts, fmt.Sprintf("/%s %d 0 R", font.name, 4+i))
+	}
+	for i, page := range d.pages {
+		object("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << %s >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, strings.Join(fonts, " "), firstPage+2*i+1)
+		object("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.Bytes())
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
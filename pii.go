@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The start of values piiCipher encrypted, followed by the id of the key
+// that did, a colon and the base64 of the nonce and sealed value.
+const piiPrefix = "enc:v1:"
+
+// piiCipher encrypts the personal data the database keeps with AES-256-GCM,
+// so a dump of it doesn't give the data away. It decrypts with the key
+// that encrypted, the current one or an earlier one.
+type piiCipher struct {
+	mu sync.RWMutex
+	// keyId is the current key's, which encrypts.
+	keyId string
+	keys  map[string]cipher.AEAD
+}
+
+// The cipher members' contact details are encrypted with, or nil to keep
+// them as they are.
+var pii *piiCipher
+
+var errPIIKeyMissing = errors.New("personal data is encrypted and no pii.key is configured")
+
+// Set up encryption of personal data as configured, decrypting the key
+// with KMS if it is given that way.
+func initPII(cfg Config) error {
+	pii = nil
+	key := cfg.PII.Key
+	if cfg.PII.KMSKey != "" {
+		if key != "" {
+			return errors.New("pii.key and pii.kms_key are exclusive")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		plaintext, err := kmsDecrypt(ctx, cfg.Secrets, cfg.PII.KMSEndpoint, cfg.PII.KMSKey)
+		if err != nil {
+			return fmt.Errorf("pii.kms_key: %w", err)
+		}
+		key = base64.StdEncoding.EncodeToString(plaintext)
+	}
+	if key == "" {
+		return nil
+	}
+
+	c := &piiCipher{keys: map[string]cipher.AEAD{}}
+	for i, k := range append([]string{key}, cfg.PII.PreviousKeys...) {
+		id, aead, err := piiKey(k)
+		if err != nil && i == 0 {
+			return fmt.Errorf("pii.key: %w", err)
+		} else if err != nil {
+			return fmt.Errorf("pii.previous_keys[%d]: %w", i-1, err)
+		}
+		if i == 0 {
+			c.keyId = id
+		}
+		c.keys[id] = aead
+	}
+	pii = c
+	return nil
+}
+
+// The id and cipher of the base64 AES-256 key. The id is a hash of the
+// key, which tells which key encrypted a value without giving it away.
+func piiKey(key string) (string, cipher.AEAD, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(raw) != 32 {
+		return "", nil, errors.New("must be 32 bytes")
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return "", nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", nil, err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:4]), aead, nil
+}
+
+// The start of values encrypted with the current key.
+func (c *piiCipher) prefix() string {
+	prefix, _ := c.current()
+	return prefix
+}
+
+// The start of values encrypted with the current key, and its cipher.
+func (c *piiCipher) current() (string, cipher.AEAD) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return piiPrefix + c.keyId + ":", c.keys[c.keyId]
+}
+
+// The cipher of the key with the id, if the cipher has it.
+func (c *piiCipher) key(id string) (cipher.AEAD, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	aead, ok := c.keys[id]
+	return aead, ok
+}
+
+// Encrypt with the key with the id from now on, keeping the others to
+// decrypt what they encrypted, and report whether it wasn't the current
+// key already.
+func (c *piiCipher) use(id string, aead cipher.AEAD) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if id == c.keyId {
+		return false
+	}
+	c.keyId = id
+	c.keys[id] = aead
+	return true
+}
+
+// Move encryption to the secrets manager's pii_key if it was rotated,
+// and encrypt members' contact details again with it, so the earlier key
+// isn't needed after a restart. A pii_key only turns encryption on at
+// startup.
+func rotatePIIKey(ctx context.Context) error {
+	key := secrets.get("pii_key", "")
+	if pii == nil || key == "" {
+		return nil
+	}
+	id, aead, err := piiKey(key)
+	if err != nil {
+		return fmt.Errorf("pii_key: %w", err)
+	}
+	if !pii.use(id, aead) {
+		return nil
+	}
+	log.Printf("Encrypting personal data with the rotated pii_key %s", id)
+	return store.EncryptMembers(ctx)
+}
+
+// s encrypted for column, which it can only be decrypted for, so values
+// can't be swapped between columns. Empty values stay empty, and with no
+// cipher s is kept as it is.
+func encryptPII(column, s string) (string, error) {
+	if pii == nil || s == "" {
+		return s, nil
+	}
+	prefix, aead := pii.current()
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(s), []byte(column))
+	return prefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// s, which may have been encrypted for column, decrypted. Values from
+// before encryption was set up are kept as they are.
+func decryptPII(column, s string) (string, error) {
+	rest, ok := strings.CutPrefix(s, piiPrefix)
+	if !ok {
+		return s, nil
+	}
+	if pii == nil {
+		return "", errPIIKeyMissing
+	}
+	id, encoded, _ := strings.Cut(rest, ":")
+	aead, ok := pii.key(id)
+	if !ok {
+		return "", fmt.Errorf("personal data is encrypted with key %s, which pii.key and pii.previous_keys don't have", id)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("%s: malformed encrypted value", column)
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(column))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", column, err)
+	}
+	return string(plaintext), nil
+}
+
+// The data key encrypted in blob, the base64 of a CiphertextBlob from AWS
+// KMS, decrypted by KMS with the region and credentials in aws, at
+// endpoint if it isn't empty.
+func kmsDecrypt(ctx context.Context, aws SecretsConfig, endpoint, blob string) ([]byte, error) {
+	if aws.AWSRegion == "" || aws.AWSAccessKey == "" || aws.AWSSecretKey == "" {
+		return nil, errors.New("needs secrets.aws_region, secrets.aws_access_key and secrets.aws_secret_key")
+	}
+	if endpoint == "" {
+		endpoint = "https://kms." + aws.AWSRegion + ".amazonaws.com"
+	}
+	body, err := json.Marshal(map[string]string{"CiphertextBlob": blob})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService.Decrypt")
+	if aws.AWSSessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", aws.AWSSessionToken)
+	}
+	signAWSRequest(req, body, "kms", aws.AWSRegion, aws.AWSAccessKey, aws.AWSSecretKey, time.Now())
+
+	res, err := secretsClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("kms: %s", res.Status)
+	}
+	var decrypted struct {
+		Plaintext []byte
+	}
+	if err := json.NewDecoder(res.Body).Decode(&decrypted); err != nil {
+		return nil, fmt.Errorf("kms: %w", err)
+	}
+	return decrypted.Plaintext, nil
+}
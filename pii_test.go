@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// A random base64 AES-256 key.
+func newPIIKey(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// Set up encryption with key and the previous keys, as for the server,
+// until the test ends.
+func usePIIKeys(t *testing.T, key string, previous ...string) {
+	t.Helper()
+	saved := pii
+	t.Cleanup(func() { pii = saved })
+	if err := initPII(Config{PII: PIIConfig{Key: key, PreviousKeys: previous}}); err != nil {
+		t.Fatalf("initPII: %v", err)
+	}
+}
+
+func TestPIIRoundTrip(t *testing.T) {
+	usePIIKeys(t, newPIIKey(t))
+
+	for _, s := range []string{"ann@example.org", "+14155550100", "1 Main St\nSpringfield", "Zoë Ünal"} {
+		encrypted, err := encryptPII("members.email", s)
+		if err != nil {
+			t.Fatalf("encryptPII(%q): %v", s, err)
+		}
+		if !strings.HasPrefix(encrypted, pii.prefix()) || strings.Contains(encrypted, s) {
+			t.Errorf("encryptPII(%q) = %q, which isn't encrypted with the current key", s, encrypted)
+		}
+		decrypted, err := decryptPII("members.email", encrypted)
+		if err != nil {
+			t.Fatalf("decryptPII(%q): %v", encrypted, err)
+		}
+		if decrypted != s {
+			t.Errorf("decryptPII(encryptPII(%q)) = %q", s, decrypted)
+		}
+	}
+}
+
+func TestPIIFreshNonces(t *testing.T) {
+	usePIIKeys(t, newPIIKey(t))
+
+	a, err := encryptPII("members.phone", "+14155550100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := encryptPII("members.phone", "+14155550100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Errorf("the same value encrypted twice gave %q both times", a)
+	}
+}
+
+func TestPIIEmptyAndPlain(t *testing.T) {
+	usePIIKeys(t, newPIIKey(t))
+
+	if encrypted, err := encryptPII("members.email", ""); err != nil || encrypted != "" {
+		t.Errorf(`encryptPII("") = %q, %v; want "", nil`, encrypted, err)
+	}
+	// Values from before encryption was set up are read as they are.
+	if decrypted, err := decryptPII("members.email", "ann@example.org"); err != nil || decrypted != "ann@example.org" {
+		t.Errorf("decryptPII of a plain value = %q, %v", decrypted, err)
+	}
+}
+
+func TestPIIWithoutKey(t *testing.T) {
+	usePIIKeys(t, newPIIKey(t))
+	encrypted, err := encryptPII("members.email", "ann@example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	usePIIKeys(t, "")
+	if kept, err := encryptPII("members.email", "ann@example.org"); err != nil || kept != "ann@example.org" {
+		t.Errorf("encryptPII without a key = %q, %v; want the value as it is", kept, err)
+	}
+	if _, err := decryptPII("members.email", encrypted); !errors.Is(err, errPIIKeyMissing) {
+		t.Errorf("decryptPII without a key: got %v, want errPIIKeyMissing", err)
+	}
+}
+
+func TestPIIColumnBinding(t *testing.T) {
+	usePIIKeys(t, newPIIKey(t))
+
+	encrypted, err := encryptPII("members.email", "ann@example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted, err := decryptPII("members.address", encrypted); err == nil {
+		t.Errorf("an email decrypted as an address, to %q", decrypted)
+	}
+}
+
+func TestPIITampering(t *testing.T) {
+	usePIIKeys(t, newPIIKey(t))
+
+	encrypted, err := encryptPII("members.email", "ann@example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(encrypted, pii.prefix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	for name, value := range map[string]string{
+		"flipped bit": pii.prefix() + base64.RawStdEncoding.EncodeToString(sealed),
+		"truncated":   pii.prefix() + "AAAA",
+		"not base64":  pii.prefix() + "!!!",
+	} {
+		if decrypted, err := decryptPII("members.email", value); err == nil {
+			t.Errorf("%s: decrypted to %q", name, decrypted)
+		}
+	}
+}
+
+func TestPIIKeyRotation(t *testing.T) {
+	oldKey, newKey := newPIIKey(t), newPIIKey(t)
+	usePIIKeys(t, oldKey)
+	oldPrefix := pii.prefix()
+	encrypted, err := encryptPII("members.phone", "+14155550100")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With the old key among the previous ones, what it encrypted still
+	// decrypts, and new values are encrypted with the new key.
+	usePIIKeys(t, newKey, oldKey)
+	if pii.prefix() == oldPrefix {
+		t.Fatalf("the new key has the old key's id %q", oldPrefix)
+	}
+	if decrypted, err := decryptPII("members.phone", encrypted); err != nil || decrypted != "+14155550100" {
+		t.Errorf("decryptPII after rotation = %q, %v", decrypted, err)
+	}
+	reencrypted, err := encryptPII("members.phone", "+14155550100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(reencrypted, pii.prefix()) {
+		t.Errorf("encryptPII after rotation = %q, not with the new key", reencrypted)
+	}
+
+	// Once the old key is dropped, its values no longer decrypt.
+	usePIIKeys(t, newKey)
+	if _, err := decryptPII("members.phone", encrypted); err == nil {
+		t.Error("a value of a dropped key decrypted")
+	}
+	if decrypted, err := decryptPII("members.phone", reencrypted); err != nil || decrypted != "+14155550100" {
+		t.Errorf("decryptPII with the new key = %q, %v", decrypted, err)
+	}
+}
+
+func TestInitPIIRejectsBadKeys(t *testing.T) {
+	saved := pii
+	t.Cleanup(func() { pii = saved })
+
+	short := base64.StdEncoding.EncodeToString(make([]byte, 16))
+	for name, cfg := range map[string]PIIConfig{
+		"not base64":        {Key: "not a key!"},
+		"short key":         {Key: short},
+		"bad previous key":  {Key: newPIIKey(t), PreviousKeys: []string{short}},
+		"key and KMS key":   {Key: newPIIKey(t), KMSKey: "blob"},
+		"KMS without creds": {KMSKey: "blob"},
+	} {
+		if err := initPII(Config{PII: cfg}); err == nil {
+			t.Errorf("%s: initPII succeeded", name)
+		}
+	}
+}
+
+func TestPIIKeyRotatedBySecretsManager(t *testing.T) {
+	oldKey, newKey := newPIIKey(t), newPIIKey(t)
+	usePIIKeys(t, oldKey)
+	oldPrefix := pii.prefix()
+	savedSecrets, savedStore := secrets, store
+	t.Cleanup(func() { secrets, store = savedSecrets, savedStore })
+	secrets = &secretManager{secrets: map[string]string{"pii_key": oldKey}}
+	store = newMemoryStore()
+
+	encrypted, err := encryptPII("members.email", "ann@example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rotatePIIKey(context.Background()); err != nil || pii.prefix() != oldPrefix {
+		t.Fatalf("rotatePIIKey with the same key = %v, prefix %q; want nil, %q", err, pii.prefix(), oldPrefix)
+	}
+
+	// A rotated key encrypts from the refresh on, and the old one still
+	// decrypts what it encrypted.
+	secrets.secrets = map[string]string{"pii_key": newKey}
+	if err := rotatePIIKey(context.Background()); err != nil {
+		t.Fatalf("rotatePIIKey: %v", err)
+	}
+	if pii.prefix() == oldPrefix {
+		t.Fatal("the rotated key isn't the current one")
+	}
+	if decrypted, err := decryptPII("members.email", encrypted); err != nil || decrypted != "ann@example.org" {
+		t.Errorf("decryptPII after rotation = %q, %v", decrypted, err)
+	}
+	if reencrypted, err := encryptPII("members.email", "ann@example.org"); err != nil || !strings.HasPrefix(reencrypted, pii.prefix()) {
+		t.Errorf("encryptPII after rotation = %q, %v; want it with the rotated key", reencrypted, err)
+	}
+}
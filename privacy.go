@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"time"
+)
+
+// The name left on what an erased user's name was on, such as their
+// reviews and memberships, so it still counts without saying who.
+const erasedName = "Deleted user"
+
+// UserExport is everything kept about a user, for them to take away.
+type UserExport struct {
+	User            User              `json:"user" xml:"user"`
+	Shelf           []ShelfItem       `json:"shelf" xml:"shelf>item"`
+	ReadingProgress []ReadingProgress `json:"reading_progress" xml:"reading_progress>progress"`
+	Goals           []ReadingGoal     `json:"goals" xml:"goals>goal"`
+	Notes           []Note            `json:"notes" xml:"notes>note"`
+	Quotes          []Quote           `json:"quotes" xml:"quotes>quote"`
+	Wishlist        []WishlistItem    `json:"wishlist" xml:"wishlist>item"`
+	Collections     []Collection      `json:"collections" xml:"collections>collection"`
+	SavedSearches   []SavedSearch     `json:"saved_searches" xml:"saved_searches>search"`
+	LoyaltyPoints   []LoyaltyEntry    `json:"loyalty_points" xml:"loyalty_points>entry"`
+	// Reviews are those signed with the user's name, as reviews aren't
+	// tied to accounts.
+	Reviews     []Review           `json:"reviews" xml:"reviews>review"`
+	Memberships []MembershipExport `json:"memberships" xml:"memberships>membership"`
+	// Orders are the sales made to the user or to their email.
+	Orders                  []Sale                  `json:"orders" xml:"orders>order"`
+	NotificationPreferences NotificationPreferences `json:"notification_preferences" xml:"notification_preferences"`
+	PushSubscriptions       []PushSubscription      `json:"push_subscriptions" xml:"push_subscriptions>subscription"`
+	ExportedAt              time.Time               `json:"exported_at" xml:"exported_at"`
+}
+
+// MembershipExport is a library membership linked to the user, with its
+// loans and fines.
+type MembershipExport struct {
+	Member Member `json:"member" xml:"member"`
+	Loans  []Loan `json:"loans" xml:"loans>loan"`
+	Fines  []Fine `json:"fines" xml:"fines>fine"`
+}
+
+type UserExportResponse struct {
+	Status  string     `json:"status" xml:"status"`
+	Message string     `json:"message" xml:"message"`
+	Data    UserExport `json:"data" xml:"data"`
+}
+
+// Answer with everything kept about the user, in JSON or XML as asked
+// for, so they can take it elsewhere.
+func exportMeHandler(w http.ResponseWriter, r *http.Request) {
+	export, err := exportUser(r, currentUser(r))
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error exporting data")
+		log.Printf("Data export error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, UserExportResponse{
+		Status:  "success",
+		Message: "Data exported successfully",
+		Data:    export,
+	})
+}
+
+func exportUser(r *http.Request, user User) (UserExport, error) {
+	ctx := r.Context()
+	export := UserExport{User: user, ExportedAt: time.Now().UTC()}
+	var err error
+	if export.Shelf, err = store.ListShelf(ctx, user.Id, "", ChangedAfter{}); err != nil {
+		return UserExport{}, err
+	}
+	if export.ReadingProgress, err = store.ListProgress(ctx, user.Id); err != nil {
+		return UserExport{}, err
+	}
+	if export.Goals, err = store.ListGoals(ctx, user.Id, ChangedAfter{}); err != nil {
+		return UserExport{}, err
+	}
+	if export.Notes, err = store.ListNotes(ctx, user.Id, NoteQuery{}); err != nil {
+		return UserExport{}, err
+	}
+	if export.Quotes, err = store.ListQuotes(ctx, user.Id, ChangedAfter{}); err != nil {
+		return UserExport{}, err
+	}
+	if export.Wishlist, err = store.ListWishlist(ctx, user.Id, ChangedAfter{}); err != nil {
+		return UserExport{}, err
+	}
+	if export.Collections, err = store.ListCollections(ctx, user.Id, ChangedAfter{}); err != nil {
+		return UserExport{}, err
+	}
+	if export.SavedSearches, err = store.ListSavedSearches(ctx, user.Id, ChangedAfter{}); err != nil {
+		return UserExport{}, err
+	}
+	if export.LoyaltyPoints, err = store.ListLoyaltyEntries(ctx, user.Id, math.MaxInt32, ChangedAfter{}); err != nil {
+		return UserExport{}, err
+	}
+	if export.Reviews, err = store.ListUserReviews(ctx, user.Id); err != nil {
+		return UserExport{}, err
+	}
+	if export.Orders, err = store.ListUserSales(ctx, user.Id, user.Email); err != nil {
+		return UserExport{}, err
+	}
+	if export.NotificationPreferences, err = store.NotificationPreferences(ctx, user.Email); err != nil {
+		return UserExport{}, err
+	}
+	if export.PushSubscriptions, err = store.ListPushSubscriptions(ctx, user.Email); err != nil {
+		return UserExport{}, err
+	}
+
+	members, err := store.ListMembers(ctx, ChangedAfter{})
+	if err != nil {
+		return UserExport{}, err
+	}
+	export.Memberships = []MembershipExport{}
+	for _, member := range members {
+		if member.UserId == nil || *member.UserId != user.Id {
+			continue
+		}
+		membership := MembershipExport{Member: member}
+		if membership.Loans, err = store.ListMemberLoans(ctx, member.Id, ChangedAfter{}); err != nil {
+			return UserExport{}, err
+		}
+		if membership.Fines, err = store.ListMemberFines(ctx, member.Id, ChangedAfter{}); err != nil {
+			return UserExport{}, err
+		}
+		export.Memberships = append(export.Memberships, membership)
+	}
+	return export, nil
+}
+
+// Delete the user's account and what is theirs alone, and anonymize what
+// they share with the library: their reviews, memberships and orders stay
+// without their name or contact details, so loans, sales and ratings
+// still add up.
+func deleteMeHandler(w http.ResponseWriter, r *http.Request) {
+	err := store.EraseUser(r.Context(), currentUser(r).Id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeUserNotFound, "User not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error deleting account")
+		log.Printf("Account deletion error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Account deleted successfully",
+	})
+}
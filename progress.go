@@ -0,0 +1,235 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ReadingProgress is how far a reader has got through a book on their
+// shelf, as of RecordedAt. Readers give the page they're on or how much
+// they've read in percent; once the book's page count is known, each
+// works out the other.
+type ReadingProgress struct {
+	BookId int  `json:"book_id" xml:"book_id"`
+	Page   *int `json:"page,omitempty" xml:"page,omitempty" validate:"omitempty,min=0,max=100000"`
+	// Pages is the book's page count, kept from earlier updates when an
+	// update doesn't give it.
+	Pages      *int      `json:"pages,omitempty" xml:"pages,omitempty" validate:"omitempty,min=1,max=100000"`
+	Percent    *float64  `json:"percent,omitempty" xml:"percent,omitempty" validate:"omitempty,min=0,max=100"`
+	RecordedAt time.Time `json:"recorded_at" xml:"recorded_at"`
+}
+
+// ProgressEstimate is when a reader will finish a book at their usual
+// pace, worked out from the progress they've recorded in all their books:
+// in pages when the book's page count is known, otherwise in percent.
+type ProgressEstimate struct {
+	PagesPerDay   *float64  `json:"pages_per_day,omitempty" xml:"pages_per_day,omitempty"`
+	PercentPerDay *float64  `json:"percent_per_day,omitempty" xml:"percent_per_day,omitempty"`
+	DaysLeft      float64   `json:"days_left" xml:"days_left"`
+	FinishesAt    time.Time `json:"finishes_at" xml:"finishes_at"`
+}
+
+// ProgressReport is a book's progress just recorded, its reading status
+// after it, and the estimate if there is one.
+type ProgressReport struct {
+	Progress ReadingProgress   `json:"progress" xml:"progress"`
+	Status   string            `json:"status" xml:"status"`
+	Estimate *ProgressEstimate `json:"estimate,omitempty" xml:"estimate,omitempty"`
+}
+
+type ProgressResponse struct {
+	Status  string         `json:"status" xml:"status"`
+	Message string         `json:"message" xml:"message"`
+	Data    ProgressReport `json:"data" xml:"data"`
+}
+
+var errPastLastPage = errors.New("page past the last page")
+
+// Paces need this much reading behind them, so a couple of updates made
+// minutes apart don't promise a book finished by tonight.
+const minPaceSpan = 24 * time.Hour
+
+// Record how far the user has got in a book on their shelf. Recording
+// progress marks the book as being read, or finished at the end.
+func updateProgressHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+	var progress ReadingProgress
+	if err := decodeRequest(r, &progress); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	errs := validateRequest(progress)
+	switch {
+	case progress.Page == nil && progress.Percent == nil:
+		errs = append(errs, newFieldError("page", "required", "is required"))
+	case progress.Page != nil && progress.Percent != nil:
+		errs = append(errs, newFieldError("percent", "excluded_with", "can't be given with %s", "page"))
+	}
+	if errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	progress.BookId = id
+	progress.RecordedAt = time.Now().UTC().Truncate(time.Microsecond)
+
+	userId := currentUser(r).Id
+	var report ProgressReport
+	var history []ReadingProgress
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		items, err := tx.ListShelf(r.Context(), userId, "", ChangedAfter{})
+		if err != nil {
+			return err
+		}
+		var item *ShelfItem
+		for i := range items {
+			if items[i].BookId == id {
+				item = &items[i]
+			}
+		}
+		if item == nil {
+			return ErrNotFound
+		}
+		if progress.Pages == nil && item.Progress != nil {
+			progress.Pages = item.Progress.Pages
+		}
+		if !completeProgress(&progress) {
+			return errPastLastPage
+		}
+		if err := tx.RecordProgress(r.Context(), userId, &progress); err != nil {
+			return err
+		}
+
+		status := ReadingReading
+		if progress.Percent != nil && *progress.Percent >= 100 {
+			status = ReadingFinished
+		}
+		if status != item.Status {
+			item.Status = status
+			item.AddedAt = progress.RecordedAt
+			if err := tx.ShelveBook(r.Context(), userId, item); err != nil {
+				return err
+			}
+		}
+		report = ProgressReport{Progress: progress, Status: status}
+
+		history, err = tx.ListProgress(r.Context(), userId)
+		return err
+	})
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeBookNotFound, "Book isn't on the shelf")
+		return
+	case errors.Is(err, errPastLastPage):
+		writeValidationErrors(w, r, []FieldError{newFieldError("page", "max", "must be at most %s", strconv.Itoa(*progress.Pages))})
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error recording progress")
+		log.Printf("Reading progress error: %v", err)
+		return
+	}
+	if report.Status != ReadingFinished {
+		report.Estimate = estimateFinish(progress, history)
+	}
+
+	writeResponse(w, r, http.StatusOK, ProgressResponse{
+		Status:  "success",
+		Message: "Progress recorded successfully",
+		Data:    report,
+	})
+}
+
+// Work out the page from the percent or the other way round, when the
+// book's page count is known. Reports false for a page past the end.
+func completeProgress(progress *ReadingProgress) bool {
+	if progress.Pages == nil {
+		return true
+	}
+	pages := *progress.Pages
+	if progress.Page != nil {
+		if *progress.Page > pages {
+			return false
+		}
+		percent := math.Round(float64(*progress.Page)/float64(pages)*1000) / 10
+		progress.Percent = &percent
+	} else {
+		page := int(math.Round(*progress.Percent / 100 * float64(pages)))
+		progress.Page = &page
+	}
+	return true
+}
+
+// Estimate when the book progress is in will be finished, at the pace the
+// reader has kept over history, their progress in every book in the order
+// it was recorded. Without enough history there is no estimate.
+func estimateFinish(progress ReadingProgress, history []ReadingProgress) *ProgressEstimate {
+	var left, pace float64
+	estimate := &ProgressEstimate{}
+	if pagesPerDay, ok := readingPace(history, func(p ReadingProgress) *float64 {
+		if p.Page == nil {
+			return nil
+		}
+		page := float64(*p.Page)
+		return &page
+	}); ok && progress.Pages != nil {
+		left, pace = float64(*progress.Pages-*progress.Page), pagesPerDay
+		rounded := math.Round(pace*10) / 10
+		estimate.PagesPerDay = &rounded
+	} else if percentPerDay, ok := readingPace(history, func(p ReadingProgress) *float64 {
+		return p.Percent
+	}); ok && progress.Percent != nil {
+		left, pace = 100-*progress.Percent, percentPerDay
+		rounded := math.Round(pace*10) / 10
+		estimate.PercentPerDay = &rounded
+	} else {
+		return nil
+	}
+
+	estimate.DaysLeft = math.Ceil(left/pace*10) / 10
+	estimate.FinishesAt = progress.RecordedAt.Add(time.Duration(left / pace * float64(24*time.Hour))).Truncate(time.Second)
+	return estimate
+}
+
+// A reader's pace in what measure reads from their progress, per day: how
+// far they got in each book from the first update to the last, over the
+// time between them, across all their books.
+func readingPace(history []ReadingProgress, measure func(ReadingProgress) *float64) (float64, bool) {
+	type span struct {
+		from, to     float64
+		start, until time.Time
+	}
+	spans := map[int]*span{}
+	for _, p := range history {
+		value := measure(p)
+		if value == nil {
+			continue
+		}
+		if s, ok := spans[p.BookId]; ok {
+			s.to, s.until = *value, p.RecordedAt
+		} else {
+			spans[p.BookId] = &span{*value, *value, p.RecordedAt, p.RecordedAt}
+		}
+	}
+
+	var read float64
+	var took time.Duration
+	for _, s := range spans {
+		if s.to > s.from {
+			read += s.to - s.from
+			took += s.until.Sub(s.start)
+		}
+	}
+	if read == 0 || took < minPaceSpan {
+		return 0, false
+	}
+	return read / took.Hours() * 24, true
+}
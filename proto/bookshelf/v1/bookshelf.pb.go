@@ -0,0 +1,844 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: bookshelf/v1/bookshelf.proto
+
+package bookshelfv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Book struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Id     int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title  string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Author string                 `protobuf:"bytes,3,opt,name=author,proto3" json:"author,omitempty"`
+	Price  float64                `protobuf:"fixed64,4,opt,name=price,proto3" json:"price,omitempty"`
+	// Empty if the book has no ISBN.
+	Isbn string `protobuf:"bytes,5,opt,name=isbn,proto3" json:"isbn,omitempty"`
+	// Made from the title by the server; ignored in requests.
+	Slug          string `protobuf:"bytes,6,opt,name=slug,proto3" json:"slug,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Book) Reset() {
+	*x = Book{}
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Book) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Book) ProtoMessage() {}
+
+func (x *Book) ProtoReflect() protoreflect.Message {
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Book.ProtoReflect.Descriptor instead.
+func (*Book) Descriptor() ([]byte, []int) {
+	return file_bookshelf_v1_bookshelf_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Book) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Book) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Book) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *Book) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Book) GetIsbn() string {
+	if x != nil {
+		return x.Isbn
+	}
+	return ""
+}
+
+func (x *Book) GetSlug() string {
+	if x != nil {
+		return x.Slug
+	}
+	return ""
+}
+
+type CreateBookRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Book          *Book                  `protobuf:"bytes,1,opt,name=book,proto3" json:"book,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateBookRequest) Reset() {
+	*x = CreateBookRequest{}
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateBookRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateBookRequest) ProtoMessage() {}
+
+func (x *CreateBookRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateBookRequest.ProtoReflect.Descriptor instead.
+func (*CreateBookRequest) Descriptor() ([]byte, []int) {
+	return file_bookshelf_v1_bookshelf_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CreateBookRequest) GetBook() *Book {
+	if x != nil {
+		return x.Book
+	}
+	return nil
+}
+
+type GetBookRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBookRequest) Reset() {
+	*x = GetBookRequest{}
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBookRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBookRequest) ProtoMessage() {}
+
+func (x *GetBookRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBookRequest.ProtoReflect.Descriptor instead.
+func (*GetBookRequest) Descriptor() ([]byte, []int) {
+	return file_bookshelf_v1_bookshelf_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetBookRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type UpdateBookRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Book          *Book                  `protobuf:"bytes,2,opt,name=book,proto3" json:"book,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateBookRequest) Reset() {
+	*x = UpdateBookRequest{}
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateBookRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateBookRequest) ProtoMessage() {}
+
+func (x *UpdateBookRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateBookRequest.ProtoReflect.Descriptor instead.
+func (*UpdateBookRequest) Descriptor() ([]byte, []int) {
+	return file_bookshelf_v1_bookshelf_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *UpdateBookRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdateBookRequest) GetBook() *Book {
+	if x != nil {
+		return x.Book
+	}
+	return nil
+}
+
+type ListBooksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBooksRequest) Reset() {
+	*x = ListBooksRequest{}
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBooksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBooksRequest) ProtoMessage() {}
+
+func (x *ListBooksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBooksRequest.ProtoReflect.Descriptor instead.
+func (*ListBooksRequest) Descriptor() ([]byte, []int) {
+	return file_bookshelf_v1_bookshelf_proto_rawDescGZIP(), []int{4}
+}
+
+type ListBooksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Books         []*Book                `protobuf:"bytes,1,rep,name=books,proto3" json:"books,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBooksResponse) Reset() {
+	*x = ListBooksResponse{}
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBooksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBooksResponse) ProtoMessage() {}
+
+func (x *ListBooksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBooksResponse.ProtoReflect.Descriptor instead.
+func (*ListBooksResponse) Descriptor() ([]byte, []int) {
+	return file_bookshelf_v1_bookshelf_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListBooksResponse) GetBooks() []*Book {
+	if x != nil {
+		return x.Books
+	}
+	return nil
+}
+
+type SearchBooksRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Matched case-insensitively against title and author.
+	Query         string `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchBooksRequest) Reset() {
+	*x = SearchBooksRequest{}
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchBooksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchBooksRequest) ProtoMessage() {}
+
+func (x *SearchBooksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchBooksRequest.ProtoReflect.Descriptor instead.
+func (*SearchBooksRequest) Descriptor() ([]byte, []int) {
+	return file_bookshelf_v1_bookshelf_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SearchBooksRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+type SearchBooksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Books         []*Book                `protobuf:"bytes,1,rep,name=books,proto3" json:"books,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchBooksResponse) Reset() {
+	*x = SearchBooksResponse{}
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchBooksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchBooksResponse) ProtoMessage() {}
+
+func (x *SearchBooksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchBooksResponse.ProtoReflect.Descriptor instead.
+func (*SearchBooksResponse) Descriptor() ([]byte, []int) {
+	return file_bookshelf_v1_bookshelf_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SearchBooksResponse) GetBooks() []*Book {
+	if x != nil {
+		return x.Books
+	}
+	return nil
+}
+
+type DeleteAllBooksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteAllBooksRequest) Reset() {
+	*x = DeleteAllBooksRequest{}
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteAllBooksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteAllBooksRequest) ProtoMessage() {}
+
+func (x *DeleteAllBooksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteAllBooksRequest.ProtoReflect.Descriptor instead.
+func (*DeleteAllBooksRequest) Descriptor() ([]byte, []int) {
+	return file_bookshelf_v1_bookshelf_proto_rawDescGZIP(), []int{8}
+}
+
+type DeleteAllBooksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deleted       int64                  `protobuf:"varint,1,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteAllBooksResponse) Reset() {
+	*x = DeleteAllBooksResponse{}
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteAllBooksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteAllBooksResponse) ProtoMessage() {}
+
+func (x *DeleteAllBooksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteAllBooksResponse.ProtoReflect.Descriptor instead.
+func (*DeleteAllBooksResponse) Descriptor() ([]byte, []int) {
+	return file_bookshelf_v1_bookshelf_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DeleteAllBooksResponse) GetDeleted() int64 {
+	if x != nil {
+		return x.Deleted
+	}
+	return 0
+}
+
+// ApiResponse is the protobuf encoding of the REST API's responses, served
+// for Accept: application/x-protobuf.
+type ApiResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Status  string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Message string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	// Types that are valid to be assigned to Data:
+	//
+	//	*ApiResponse_Book
+	//	*ApiResponse_Books
+	Data isApiResponse_Data `protobuf_oneof:"data"`
+	// Keyed by relation, such as "self" or "next".
+	Links         map[string]*Link `protobuf:"bytes,5,rep,name=links,proto3" json:"links,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApiResponse) Reset() {
+	*x = ApiResponse{}
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApiResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApiResponse) ProtoMessage() {}
+
+func (x *ApiResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApiResponse.ProtoReflect.Descriptor instead.
+func (*ApiResponse) Descriptor() ([]byte, []int) {
+	return file_bookshelf_v1_bookshelf_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ApiResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ApiResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ApiResponse) GetData() isApiResponse_Data {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *ApiResponse) GetBook() *Book {
+	if x != nil {
+		if x, ok := x.Data.(*ApiResponse_Book); ok {
+			return x.Book
+		}
+	}
+	return nil
+}
+
+func (x *ApiResponse) GetBooks() *BookList {
+	if x != nil {
+		if x, ok := x.Data.(*ApiResponse_Books); ok {
+			return x.Books
+		}
+	}
+	return nil
+}
+
+func (x *ApiResponse) GetLinks() map[string]*Link {
+	if x != nil {
+		return x.Links
+	}
+	return nil
+}
+
+type isApiResponse_Data interface {
+	isApiResponse_Data()
+}
+
+type ApiResponse_Book struct {
+	Book *Book `protobuf:"bytes,3,opt,name=book,proto3,oneof"`
+}
+
+type ApiResponse_Books struct {
+	Books *BookList `protobuf:"bytes,4,opt,name=books,proto3,oneof"`
+}
+
+func (*ApiResponse_Book) isApiResponse_Data() {}
+
+func (*ApiResponse_Books) isApiResponse_Data() {}
+
+type BookList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Books         []*Book                `protobuf:"bytes,1,rep,name=books,proto3" json:"books,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BookList) Reset() {
+	*x = BookList{}
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BookList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BookList) ProtoMessage() {}
+
+func (x *BookList) ProtoReflect() protoreflect.Message {
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BookList.ProtoReflect.Descriptor instead.
+func (*BookList) Descriptor() ([]byte, []int) {
+	return file_bookshelf_v1_bookshelf_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *BookList) GetBooks() []*Book {
+	if x != nil {
+		return x.Books
+	}
+	return nil
+}
+
+type Link struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Href          string                 `protobuf:"bytes,1,opt,name=href,proto3" json:"href,omitempty"`
+	Method        string                 `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Link) Reset() {
+	*x = Link{}
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Link) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Link) ProtoMessage() {}
+
+func (x *Link) ProtoReflect() protoreflect.Message {
+	mi := &file_bookshelf_v1_bookshelf_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Link.ProtoReflect.Descriptor instead.
+func (*Link) Descriptor() ([]byte, []int) {
+	return file_bookshelf_v1_bookshelf_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Link) GetHref() string {
+	if x != nil {
+		return x.Href
+	}
+	return ""
+}
+
+func (x *Link) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+var File_bookshelf_v1_bookshelf_proto protoreflect.FileDescriptor
+
+const file_bookshelf_v1_bookshelf_proto_rawDesc = "" +
+	"\n" +
+	"\x1cbookshelf/v1/bookshelf.proto\x12\fbookshelf.v1\"\x82\x01\n" +
+	"\x04Book\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12\x16\n" +
+	"\x06author\x18\x03 \x01(\tR\x06author\x12\x14\n" +
+	"\x05price\x18\x04 \x01(\x01R\x05price\x12\x12\n" +
+	"\x04isbn\x18\x05 \x01(\tR\x04isbn\x12\x12\n" +
+	"\x04slug\x18\x06 \x01(\tR\x04slug\";\n" +
+	"\x11CreateBookRequest\x12&\n" +
+	"\x04book\x18\x01 \x01(\v2\x12.bookshelf.v1.BookR\x04book\" \n" +
+	"\x0eGetBookRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"K\n" +
+	"\x11UpdateBookRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12&\n" +
+	"\x04book\x18\x02 \x01(\v2\x12.bookshelf.v1.BookR\x04book\"\x12\n" +
+	"\x10ListBooksRequest\"=\n" +
+	"\x11ListBooksResponse\x12(\n" +
+	"\x05books\x18\x01 \x03(\v2\x12.bookshelf.v1.BookR\x05books\"*\n" +
+	"\x12SearchBooksRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\"?\n" +
+	"\x13SearchBooksResponse\x12(\n" +
+	"\x05books\x18\x01 \x03(\v2\x12.bookshelf.v1.BookR\x05books\"\x17\n" +
+	"\x15DeleteAllBooksRequest\"2\n" +
+	"\x16DeleteAllBooksResponse\x12\x18\n" +
+	"\adeleted\x18\x01 \x01(\x03R\adeleted\"\xb7\x02\n" +
+	"\vApiResponse\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12(\n" +
+	"\x04book\x18\x03 \x01(\v2\x12.bookshelf.v1.BookH\x00R\x04book\x12.\n" +
+	"\x05books\x18\x04 \x01(\v2\x16.bookshelf.v1.BookListH\x00R\x05books\x12:\n" +
+	"\x05links\x18\x05 \x03(\v2$.bookshelf.v1.ApiResponse.LinksEntryR\x05links\x1aL\n" +
+	"\n" +
+	"LinksEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12(\n" +
+	"\x05value\x18\x02 \x01(\v2\x12.bookshelf.v1.LinkR\x05value:\x028\x01B\x06\n" +
+	"\x04dataJ\x04\b\x06\x10\aR\x04code\"4\n" +
+	"\bBookList\x12(\n" +
+	"\x05books\x18\x01 \x03(\v2\x12.bookshelf.v1.BookR\x05books\"2\n" +
+	"\x04Link\x12\x12\n" +
+	"\x04href\x18\x01 \x01(\tR\x04href\x12\x16\n" +
+	"\x06method\x18\x02 \x01(\tR\x06method2\xcf\x03\n" +
+	"\vBookService\x12A\n" +
+	"\n" +
+	"CreateBook\x12\x1f.bookshelf.v1.CreateBookRequest\x1a\x12.bookshelf.v1.Book\x12;\n" +
+	"\aGetBook\x12\x1c.bookshelf.v1.GetBookRequest\x1a\x12.bookshelf.v1.Book\x12A\n" +
+	"\n" +
+	"UpdateBook\x12\x1f.bookshelf.v1.UpdateBookRequest\x1a\x12.bookshelf.v1.Book\x12L\n" +
+	"\tListBooks\x12\x1e.bookshelf.v1.ListBooksRequest\x1a\x1f.bookshelf.v1.ListBooksResponse\x12R\n" +
+	"\vSearchBooks\x12 .bookshelf.v1.SearchBooksRequest\x1a!.bookshelf.v1.SearchBooksResponse\x12[\n" +
+	"\x0eDeleteAllBooks\x12#.bookshelf.v1.DeleteAllBooksRequest\x1a$.bookshelf.v1.DeleteAllBooksResponseBO\n" +
+	"\x18com.amroexe.bookshelf.v1P\x01Z1github.com/amroexe/proto/bookshelf/v1;bookshelfv1b\x06proto3"
+
+var (
+	file_bookshelf_v1_bookshelf_proto_rawDescOnce sync.Once
+	file_bookshelf_v1_bookshelf_proto_rawDescData []byte
+)
+
+func file_bookshelf_v1_bookshelf_proto_rawDescGZIP() []byte {
+	file_bookshelf_v1_bookshelf_proto_rawDescOnce.Do(func() {
+		file_bookshelf_v1_bookshelf_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_bookshelf_v1_bookshelf_proto_rawDesc), len(file_bookshelf_v1_bookshelf_proto_rawDesc)))
+	})
+	return file_bookshelf_v1_bookshelf_proto_rawDescData
+}
+
+var file_bookshelf_v1_bookshelf_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_bookshelf_v1_bookshelf_proto_goTypes = []any{
+	(*Book)(nil),                   // 0: bookshelf.v1.Book
+	(*CreateBookRequest)(nil),      // 1: bookshelf.v1.CreateBookRequest
+	(*GetBookRequest)(nil),         // 2: bookshelf.v1.GetBookRequest
+	(*UpdateBookRequest)(nil),      // 3: bookshelf.v1.UpdateBookRequest
+	(*ListBooksRequest)(nil),       // 4: bookshelf.v1.ListBooksRequest
+	(*ListBooksResponse)(nil),      // 5: bookshelf.v1.ListBooksResponse
+	(*SearchBooksRequest)(nil),     // 6: bookshelf.v1.SearchBooksRequest
+	(*SearchBooksResponse)(nil),    // 7: bookshelf.v1.SearchBooksResponse
+	(*DeleteAllBooksRequest)(nil),  // 8: bookshelf.v1.DeleteAllBooksRequest
+	(*DeleteAllBooksResponse)(nil), // 9: bookshelf.v1.DeleteAllBooksResponse
+	(*ApiResponse)(nil),            // 10: bookshelf.v1.ApiResponse
+	(*BookList)(nil),               // 11: bookshelf.v1.BookList
+	(*Link)(nil),                   // 12: bookshelf.v1.Link
+	nil,                            // 13: bookshelf.v1.ApiResponse.LinksEntry
+}
+var file_bookshelf_v1_bookshelf_proto_depIdxs = []int32{
+	0,  // 0: bookshelf.v1.CreateBookRequest.book:type_name -> bookshelf.v1.Book
+	0,  // 1: bookshelf.v1.UpdateBookRequest.book:type_name -> bookshelf.v1.Book
+	0,  // 2: bookshelf.v1.ListBooksResponse.books:type_name -> bookshelf.v1.Book
+	0,  // 3: bookshelf.v1.SearchBooksResponse.books:type_name -> bookshelf.v1.Book
+	0,  // 4: bookshelf.v1.ApiResponse.book:type_name -> bookshelf.v1.Book
+	11, // 5: bookshelf.v1.ApiResponse.books:type_name -> bookshelf.v1.BookList
+	13, // 6: bookshelf.v1.ApiResponse.links:type_name -> bookshelf.v1.ApiResponse.LinksEntry
+	0,  // 7: bookshelf.v1.BookList.books:type_name -> bookshelf.v1.Book
+	12, // 8: bookshelf.v1.ApiResponse.LinksEntry.value:type_name -> bookshelf.v1.Link
+	1,  // 9: bookshelf.v1.BookService.CreateBook:input_type -> bookshelf.v1.CreateBookRequest
+	2,  // 10: bookshelf.v1.BookService.GetBook:input_type -> bookshelf.v1.GetBookRequest
+	3,  // 11: bookshelf.v1.BookService.UpdateBook:input_type -> bookshelf.v1.UpdateBookRequest
+	4,  // 12: bookshelf.v1.BookService.ListBooks:input_type -> bookshelf.v1.ListBooksRequest
+	6,  // 13: bookshelf.v1.BookService.SearchBooks:input_type -> bookshelf.v1.SearchBooksRequest
+	8,  // 14: bookshelf.v1.BookService.DeleteAllBooks:input_type -> bookshelf.v1.DeleteAllBooksRequest
+	0,  // 15: bookshelf.v1.BookService.CreateBook:output_type -> bookshelf.v1.Book
+	0,  // 16: bookshelf.v1.BookService.GetBook:output_type -> bookshelf.v1.Book
+	0,  // 17: bookshelf.v1.BookService.UpdateBook:output_type -> bookshelf.v1.Book
+	5,  // 18: bookshelf.v1.BookService.ListBooks:output_type -> bookshelf.v1.ListBooksResponse
+	7,  // 19: bookshelf.v1.BookService.SearchBooks:output_type -> bookshelf.v1.SearchBooksResponse
+	9,  // 20: bookshelf.v1.BookService.DeleteAllBooks:output_type -> bookshelf.v1.DeleteAllBooksResponse
+	15, // [15:21] is the sub-list for method output_type
+	9,  // [9:15] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_bookshelf_v1_bookshelf_proto_init() }
+func file_bookshelf_v1_bookshelf_proto_init() {
+	if File_bookshelf_v1_bookshelf_proto != nil {
+		return
+	}
+	file_bookshelf_v1_bookshelf_proto_msgTypes[10].OneofWrappers = []any{
+		(*ApiResponse_Book)(nil),
+		(*ApiResponse_Books)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_bookshelf_v1_bookshelf_proto_rawDesc), len(file_bookshelf_v1_bookshelf_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   14,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_bookshelf_v1_bookshelf_proto_goTypes,
+		DependencyIndexes: file_bookshelf_v1_bookshelf_proto_depIdxs,
+		MessageInfos:      file_bookshelf_v1_bookshelf_proto_msgTypes,
+	}.Build()
+	File_bookshelf_v1_bookshelf_proto = out.File
+	file_bookshelf_v1_bookshelf_proto_goTypes = nil
+	file_bookshelf_v1_bookshelf_proto_depIdxs = nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/peer"
+)
+
+// pseudonymizer replaces identities with keyed hashes, the same for the
+// same identity until the salt keying them rotates. Salts are random and
+// only ever held in memory, so once one is replaced nobody can tell whose
+// hashes it made, or link them to the next salt's.
+type pseudonymizer struct {
+	rotation time.Duration
+
+	mu    sync.Mutex
+	salt  []byte
+	since time.Time
+}
+
+// The pseudonymizer analytics and the access log identify people
+// through, or nil to identify them as they are.
+var pseudonyms *pseudonymizer
+
+// Set up pseudonymization as configured.
+func initPrivacy(cfg Config) error {
+	if cfg.Privacy.SaltRotation.Duration <= 0 {
+		return errors.New("privacy.salt_rotation must be positive")
+	}
+	pseudonyms = nil
+	if cfg.Privacy.Pseudonymize {
+		pseudonyms = &pseudonymizer{rotation: cfg.Privacy.SaltRotation.Duration}
+	}
+	return nil
+}
+
+// The pseudonym of identity at now. Salts rotate on multiples of the
+// rotation since the zero time, so with a day's rotation at midnight UTC.
+func (p *pseudonymizer) pseudonym(identity string, now time.Time) string {
+	p.mu.Lock()
+	since := now.Truncate(p.rotation)
+	if p.salt == nil || !since.Equal(p.since) {
+		p.salt = make([]byte, 32)
+		rand.Read(p.salt)
+		p.since = since
+	}
+	mac := hmac.New(sha256.New, p.salt)
+	p.mu.Unlock()
+
+	mac.Write([]byte(identity))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+type clientContextKey struct{}
+
+// Remember the address each request comes from, for visitorId.
+func rememberClient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientContextKey{}, hostOf(r.RemoteAddr))))
+	})
+}
+
+// The host of addr, or addr if it has no port.
+func hostOf(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// identity, or its pseudonym if configured.
+func pseudonymize(identity string) string {
+	if identity == "" || pseudonyms == nil {
+		return identity
+	}
+	return pseudonyms.pseudonym(identity, time.Now())
+}
+
+// Who ctx acts for, as analytics record them: "user:" and the id of the
+// signed-in user, or else the address the request came from, over HTTP
+// or gRPC; empty if neither is known. Pseudonymized if configured.
+func visitorId(ctx context.Context) string {
+	var identity string
+	if user, ok := ctx.Value(userContextKey{}).(User); ok {
+		identity = "user:" + strconv.Itoa(user.Id)
+	} else if host, ok := ctx.Value(clientContextKey{}).(string); ok {
+		identity = host
+	} else if p, ok := peer.FromContext(ctx); ok {
+		identity = hostOf(p.Addr.String())
+	}
+	return pseudonymize(identity)
+}
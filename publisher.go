@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// EventPublisher sends catalog events to an external message bus for
+// downstream consumers such as analytics and search indexers.
+type EventPublisher interface {
+	// Publish sends the events in order and returns once the bus has
+	// accepted all of them.
+	Publish(ctx context.Context, events []Event) error
+	Close() error
+}
+
+// Open the message bus selected by the config, or return nil if none is.
+func openPublisher(cfg Config) (EventPublisher, error) {
+	switch cfg.EventBus {
+	case "", "none":
+		return nil, nil
+	case "kafka":
+		return newKafkaPublisher(cfg.KafkaBrokers, cfg.KafkaTopic)
+	case "nats":
+		return newNATSPublisher(cfg.NATSURL, cfg.NATSSubject)
+	default:
+		return nil, fmt.Errorf("unknown event bus %q", cfg.EventBus)
+	}
+}
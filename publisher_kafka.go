@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// kafkaPublisher writes each event as a message keyed by book id, so the
+// events of one book stay in order on one partition.
+type kafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+func newKafkaPublisher(brokers []string, topic string) (*kafkaPublisher, error) {
+	if len(brokers) == 0 {
+		return nil, errors.New("kafka event bus needs at least one broker")
+	}
+
+	return &kafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			// The relay already batches; don't wait for more messages.
+			BatchTimeout: 10 * time.Millisecond,
+		},
+	}, nil
+}
+
+func (p *kafkaPublisher) Publish(ctx context.Context, events []Event) error {
+	messages := make([]kafka.Message, len(events))
+	for i, event := range events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		messages[i] = kafka.Message{
+			Key:   []byte(strconv.Itoa(event.Book.Id)),
+			Value: value,
+			Headers: []kafka.Header{
+				{Key: "event-type", Value: []byte(event.Type)},
+				{Key: "event-id", Value: []byte(strconv.FormatInt(event.Id, 10))},
+			},
+		}
+	}
+	return p.writer.WriteMessages(ctx, messages...)
+}
+
+func (p *kafkaPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsPublisher publishes each event on <subject>.<event type>, e.g.
+// bookshelf.events.book.created, so subscribers can pick types with
+// wildcards.
+type natsPublisher struct {
+	conn    *nats.Conn
+	subject string
+}
+
+func newNATSPublisher(url, subject string) (*natsPublisher, error) {
+	conn, err := nats.Connect(url, nats.Name("bookshelf"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	return &natsPublisher{conn: conn, subject: subject}, nil
+}
+
+func (p *natsPublisher) Publish(ctx context.Context, events []Event) error {
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		msg := nats.NewMsg(p.subject + "." + event.Type)
+		msg.Data = data
+		// Lets a JetStream stream on the subject drop redeliveries.
+		msg.Header.Set(nats.MsgIdHdr, strconv.FormatInt(event.Id, 10))
+		if err := p.conn.PublishMsg(msg); err != nil {
+			return err
+		}
+	}
+	// Wait until the server has the messages.
+	return p.conn.FlushWithContext(ctx)
+}
+
+func (p *natsPublisher) Close() error {
+	return p.conn.Drain()
+}
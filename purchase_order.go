@@ -0,0 +1,434 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Where a purchase order is: open until every copy ordered is received,
+// unless it is cancelled first.
+const (
+	OrderOpen      = "open"
+	OrderReceived  = "received"
+	OrderCancelled = "cancelled"
+)
+
+var orderStatuses = []string{OrderOpen, OrderReceived, OrderCancelled}
+
+// PurchaseOrder is an order for books placed with a supplier.
+type PurchaseOrder struct {
+	Id         int `json:"id" xml:"id"`
+	SupplierId int `json:"supplier_id" xml:"supplier_id" validate:"required"`
+	// Reference is the supplier's number for the order, if they gave one.
+	Reference string      `json:"reference,omitempty" xml:"reference,omitempty" validate:"max=64"`
+	Status    string      `json:"status" xml:"status"`
+	Lines     []OrderLine `json:"lines" xml:"lines>line" validate:"max=200,dive"`
+	OrderedAt time.Time   `json:"ordered_at" xml:"ordered_at"`
+	// ClosedAt is when the order was received in full or cancelled.
+	ClosedAt *time.Time `json:"closed_at,omitempty" xml:"closed_at,omitempty"`
+	// CreatedAt is OrderedAt, and UpdatedAt when copies last came in or
+	// the order closed.
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+}
+
+func (o PurchaseOrder) timestamps() (created, updated time.Time) {
+	return o.CreatedAt, o.UpdatedAt
+}
+
+// OrderLine is how many copies of a book a purchase order is for, at what
+// cost each in the catalog's currency, and how many have come in.
+type OrderLine struct {
+	Id int `json:"id" xml:"id"`
+	// BookId is 0 once the book is deleted; Title is the one it had when
+	// ordered.
+	BookId   int     `json:"book_id,omitempty" xml:"book_id,omitempty" validate:"required"`
+	Title    string  `json:"title" xml:"title"`
+	Quantity int     `json:"quantity" xml:"quantity" validate:"gt=0,lte=10000"`
+	UnitCost float64 `json:"unit_cost" xml:"unit_cost" validate:"gte=0,lte=99999999.99"`
+	Received int     `json:"received" xml:"received"`
+}
+
+// PurchaseOrderQuery selects purchase orders for ListPurchaseOrders.
+type PurchaseOrderQuery struct {
+	// Status and SupplierId keep only the orders with them if they
+	// aren't zero.
+	Status     string
+	SupplierId int
+	ChangedAfter
+}
+
+// OrderDelivery is what came in of a purchase order. Without lines,
+// everything still to come is received.
+type OrderDelivery struct {
+	Lines []DeliveredLine `json:"lines" xml:"lines>line" validate:"max=200,dive"`
+}
+
+// DeliveredLine is how many copies came in for a line of an order.
+type DeliveredLine struct {
+	LineId   int `json:"line_id" xml:"line_id" validate:"required"`
+	Quantity int `json:"quantity" xml:"quantity" validate:"gt=0"`
+}
+
+// OpenOrder is a purchase order still open, with what is left to come.
+type OpenOrder struct {
+	PurchaseOrder
+	SupplierName string `json:"supplier_name" xml:"supplier_name"`
+	// Outstanding counts the copies ordered not yet received, worth
+	// OutstandingValue.
+	Outstanding      int     `json:"outstanding" xml:"outstanding"`
+	OutstandingValue float64 `json:"outstanding_value" xml:"outstanding_value"`
+}
+
+// OpenOrdersReport is every open purchase order, oldest first.
+type OpenOrdersReport struct {
+	Currency   string      `json:"currency" xml:"currency"`
+	TotalValue float64     `json:"total_value" xml:"total_value"`
+	Orders     []OpenOrder `json:"orders" xml:"orders>order"`
+}
+
+type PurchaseOrderResponse struct {
+	Status  string        `json:"status" xml:"status"`
+	Message string        `json:"message" xml:"message"`
+	Data    PurchaseOrder `json:"data" xml:"data"`
+}
+
+type PurchaseOrdersResponse struct {
+	Status  string          `json:"status" xml:"status"`
+	Message string          `json:"message" xml:"message"`
+	Data    []PurchaseOrder `json:"data" xml:"data>order"`
+}
+
+type OpenOrdersReportResponse struct {
+	Status  string           `json:"status" xml:"status"`
+	Message string           `json:"message" xml:"message"`
+	Data    OpenOrdersReport `json:"data" xml:"data"`
+}
+
+var (
+	errSupplierMissing = errors.New("supplier not found")
+	errOrderClosed     = errors.New("purchase order closed")
+	errDeliveryInvalid = errors.New("delivery doesn't match the order")
+)
+
+// How many copies of the line are still to come.
+func (l OrderLine) outstanding() int {
+	return l.Quantity - l.Received
+}
+
+// List purchase orders, oldest first, with the status in ?status= and from
+// the supplier in ?supplier_id= if they are given.
+func listPurchaseOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	query := PurchaseOrderQuery{Status: r.URL.Query().Get("status")}
+	if query.Status != "" && !slices.Contains(orderStatuses, query.Status) {
+		writeProblem(w, r, codeInvalidParameter, "status must be open, received or cancelled")
+		return
+	}
+	if v := r.URL.Query().Get("supplier_id"); v != "" {
+		var err error
+		if query.SupplierId, err = strconv.Atoi(v); err != nil {
+			writeProblem(w, r, codeInvalidParameter, "Invalid supplier ID")
+			return
+		}
+	}
+	var ok bool
+	if query.ChangedAfter, ok = requestedChangedAfterFilter(w, r); !ok {
+		return
+	}
+
+	orders, err := store.ListPurchaseOrders(r.Context(), query)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching purchase orders")
+		log.Printf("Purchase order query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, PurchaseOrdersResponse{
+		Status:  "success",
+		Message: "Purchase orders retrieved successfully",
+		Data:    orders,
+	})
+}
+
+// Place an order with a supplier for copies of books in the catalog.
+func createPurchaseOrderHandler(w http.ResponseWriter, r *http.Request) {
+	var order PurchaseOrder
+	if err := decodeRequest(r, &order); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	order.Reference = strings.TrimSpace(order.Reference)
+	errs := validateRequest(order)
+	if len(order.Lines) == 0 {
+		errs = append(errs, newFieldError("lines", "required", "is required"))
+	}
+	if errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	order.Id, order.Status, order.ClosedAt = 0, OrderOpen, nil
+	order.OrderedAt = time.Now().UTC().Truncate(time.Microsecond)
+	order.CreatedAt, order.UpdatedAt = order.OrderedAt, order.OrderedAt
+	for i := range order.Lines {
+		order.Lines[i].Id, order.Lines[i].Received = 0, 0
+		order.Lines[i].UnitCost = roundCents(order.Lines[i].UnitCost)
+	}
+
+	err := store.WithTx(r.Context(), func(tx BookStore) error {
+		if _, err := tx.GetSupplier(r.Context(), order.SupplierId); errors.Is(err, ErrNotFound) {
+			return errSupplierMissing
+		} else if err != nil {
+			return err
+		}
+		for i, line := range order.Lines {
+			book, err := tx.GetBookFields(r.Context(), line.BookId, []string{"id", "title"})
+			if err != nil {
+				return err
+			}
+			order.Lines[i].Title = book.Title
+		}
+		return tx.CreatePurchaseOrder(r.Context(), &order)
+	})
+	switch {
+	case errors.Is(err, errSupplierMissing):
+		writeProblem(w, r, codeSupplierNotFound, "Supplier not found")
+		return
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error creating purchase order")
+		log.Printf("Purchase order creation error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, PurchaseOrderResponse{
+		Status:  "success",
+		Message: "Purchase order created successfully",
+		Data:    order,
+	})
+}
+
+func getPurchaseOrderHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid purchase order ID")
+		return
+	}
+
+	order, err := store.GetPurchaseOrder(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codePurchaseOrderNotFound, "Purchase order not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching purchase order")
+		log.Printf("Purchase order query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, PurchaseOrderResponse{
+		Status:  "success",
+		Message: "Purchase order retrieved successfully",
+		Data:    order,
+	})
+}
+
+// Receive a delivery against an open purchase order, adding the copies
+// that came in to their books' stock. The order is received once every
+// copy ordered has come in.
+func receivePurchaseOrderHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid purchase order ID")
+		return
+	}
+	var delivery OrderDelivery
+	if err := decodeRequest(r, &delivery); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	if errs := validateRequest(delivery); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	var order PurchaseOrder
+	var errs []FieldError
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		var err error
+		if order, err = tx.GetPurchaseOrder(r.Context(), id); err != nil {
+			return err
+		}
+		if order.Status != OrderOpen {
+			return errOrderClosed
+		}
+		var received map[int]int
+		if received, errs = deliveredQuantities(order, delivery); errs != nil {
+			return errDeliveryInvalid
+		}
+
+		receivedAt := time.Now().UTC().Truncate(time.Microsecond)
+		done := true
+		for i, line := range order.Lines {
+			if n := received[line.Id]; n > 0 {
+				if err := tx.ReceiveOrderLine(r.Context(), line.Id, n, receivedAt); err != nil {
+					return err
+				}
+				// Copies of a book deleted since are in no stock.
+				if line.BookId != 0 {
+					if err := tx.AddBookStock(r.Context(), line.BookId, n); err != nil {
+						return err
+					}
+				}
+				order.Lines[i].Received += n
+				order.UpdatedAt = receivedAt
+			}
+			if order.Lines[i].outstanding() > 0 {
+				done = false
+			}
+		}
+		if !done {
+			return nil
+		}
+		order.Status, order.ClosedAt = OrderReceived, &receivedAt
+		return tx.ClosePurchaseOrder(r.Context(), order.Id, order.Status, receivedAt)
+	})
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codePurchaseOrderNotFound, "Purchase order not found")
+		return
+	case errors.Is(err, errOrderClosed):
+		writeProblem(w, r, codePurchaseOrderClosed, "The purchase order was already received or cancelled")
+		return
+	case errors.Is(err, errDeliveryInvalid):
+		writeValidationErrors(w, r, errs)
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error receiving purchase order")
+		log.Printf("Purchase order receipt error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, PurchaseOrderResponse{
+		Status:  "success",
+		Message: "Purchase order received successfully",
+		Data:    order,
+	})
+}
+
+// How many copies came in for each line of the order, by line id, or the
+// rules the delivery breaks: naming lines of other orders or more copies
+// than are still to come.
+func deliveredQuantities(order PurchaseOrder, delivery OrderDelivery) (map[int]int, []FieldError) {
+	received := map[int]int{}
+	if len(delivery.Lines) == 0 {
+		for _, line := range order.Lines {
+			received[line.Id] = line.outstanding()
+		}
+		return received, nil
+	}
+
+	var errs []FieldError
+	for i, delivered := range delivery.Lines {
+		field := fmt.Sprintf("lines[%d]", i)
+		j := slices.IndexFunc(order.Lines, func(l OrderLine) bool { return l.Id == delivered.LineId })
+		if j < 0 {
+			errs = append(errs, newFieldError(field+".line_id", "order_line", "isn't a line of the order"))
+			continue
+		}
+		received[delivered.LineId] += delivered.Quantity
+		if n := order.Lines[j].outstanding(); received[delivered.LineId] > n {
+			errs = append(errs, newFieldError(field+".quantity", "lte", "must be at most %s", strconv.Itoa(n)))
+		}
+	}
+	return received, errs
+}
+
+// Cancel an open purchase order. Copies already received stay in stock.
+func cancelPurchaseOrderHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid purchase order ID")
+		return
+	}
+
+	var order PurchaseOrder
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		var err error
+		if order, err = tx.GetPurchaseOrder(r.Context(), id); err != nil {
+			return err
+		}
+		if order.Status != OrderOpen {
+			return errOrderClosed
+		}
+		closedAt := time.Now().UTC().Truncate(time.Microsecond)
+		order.Status, order.ClosedAt, order.UpdatedAt = OrderCancelled, &closedAt, closedAt
+		return tx.ClosePurchaseOrder(r.Context(), order.Id, order.Status, closedAt)
+	})
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codePurchaseOrderNotFound, "Purchase order not found")
+		return
+	case errors.Is(err, errOrderClosed):
+		writeProblem(w, r, codePurchaseOrderClosed, "The purchase order was already received or cancelled")
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error cancelling purchase order")
+		log.Printf("Purchase order cancellation error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, PurchaseOrderResponse{
+		Status:  "success",
+		Message: "Purchase order cancelled successfully",
+		Data:    order,
+	})
+}
+
+// Report the open purchase orders with what is still to come of each and
+// what it is worth.
+func openOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	orders, err := store.ListPurchaseOrders(r.Context(), PurchaseOrderQuery{Status: OrderOpen})
+	var suppliers []Supplier
+	if err == nil {
+		suppliers, err = store.ListSuppliers(r.Context(), ChangedAfter{})
+	}
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching purchase orders")
+		log.Printf("Purchase order query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, OpenOrdersReportResponse{
+		Status:  "success",
+		Message: "Open orders retrieved successfully",
+		Data:    openOrdersReport(orders, suppliers),
+	})
+}
+
+func openOrdersReport(orders []PurchaseOrder, suppliers []Supplier) OpenOrdersReport {
+	names := make(map[int]string, len(suppliers))
+	for _, supplier := range suppliers {
+		names[supplier.Id] = supplier.Name
+	}
+	report := OpenOrdersReport{Currency: currencies.base, Orders: []OpenOrder{}}
+	for _, order := range orders {
+		open := OpenOrder{PurchaseOrder: order, SupplierName: names[order.SupplierId]}
+		for _, line := range order.Lines {
+			open.Outstanding += line.outstanding()
+			open.OutstandingValue += float64(line.outstanding()) * line.UnitCost
+		}
+		open.OutstandingValue = roundCents(open.OutstandingValue)
+		report.TotalValue = roundCents(report.TotalValue + open.OutstandingValue)
+		report.Orders = append(report.Orders, open)
+	}
+	return report
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdh"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NotificationPriceDrop is pushed to the browsers watching a book when its
+// price goes down. It isn't one of notificationKinds: watchers needn't be
+// known recipients, so preferences don't apply.
+const NotificationPriceDrop = "price_drop"
+
+// PushKeys are the keys a browser subscribed with, base64url encoded.
+type PushKeys struct {
+	// P256dh is the browser's P-256 public key.
+	P256dh string `json:"p256dh" xml:"p256dh" validate:"required,max=255"`
+	// Auth is the browser's authentication secret.
+	Auth string `json:"auth" xml:"auth" validate:"required,max=64"`
+}
+
+// PushSubscription is a browser's Web Push subscription, as
+// PushSubscription.toJSON() gives it, and what it is for.
+type PushSubscription struct {
+	Id       int      `json:"id" xml:"id"`
+	Endpoint string   `json:"endpoint" xml:"endpoint" validate:"required,https_url,max=2048"`
+	Keys     PushKeys `json:"keys" xml:"keys"`
+	// Recipient links the subscription to a patron, so it gets their
+	// notifications sent by push. Only admins can set it.
+	Recipient string `json:"recipient,omitempty" xml:"recipient,omitempty" validate:"omitempty,email,max=255"`
+	// Books are the ids of books whose price drops the browser is told
+	// of. They are only stored as watches, so they aren't loaded with the
+	// subscription.
+	Books     []int     `json:"books" xml:"books>book" validate:"max=100"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+}
+
+// PriceWatch is a subscription watching a book, and the price it last
+// saw.
+type PriceWatch struct {
+	Endpoint string
+	Price    float64
+}
+
+type PushSubscriptionResponse struct {
+	Status  string           `json:"status" xml:"status"`
+	Message string           `json:"message" xml:"message"`
+	Data    PushSubscription `json:"data" xml:"data"`
+}
+
+type PushKey struct {
+	// PublicKey is the applicationServerKey to subscribe with.
+	PublicKey string `json:"public_key" xml:"public_key"`
+}
+
+type PushKeyResponse struct {
+	Status  string  `json:"status" xml:"status"`
+	Message string  `json:"message" xml:"message"`
+	Data    PushKey `json:"data" xml:"data"`
+}
+
+// UnsubscribeRequest names the subscription to delete. Knowing the
+// endpoint, which only the browser and its push service do, is enough.
+type UnsubscribeRequest struct {
+	Endpoint string `json:"endpoint" xml:"endpoint" validate:"required,max=2048"`
+}
+
+// The Web Push channel, or nil without one.
+func webPush() *webPushChannel {
+	c, _ := notificationChannels["push"].(*webPushChannel)
+	return c
+}
+
+func pushKeyHandler(w http.ResponseWriter, r *http.Request) {
+	c := webPush()
+	if c == nil {
+		writeProblem(w, r, codeNotificationsUnavailable, "Web Push isn't configured")
+		return
+	}
+	_, publicKey, err := c.keys()
+	if err != nil {
+		log.Printf("VAPID key error: %v", err)
+		writeProblem(w, r, codeInternal, "Error reading push key")
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, PushKeyResponse{
+		Status:  "success",
+		Message: "Push key retrieved successfully",
+		Data:    PushKey{PublicKey: base64.RawURLEncoding.EncodeToString(publicKey)},
+	})
+}
+
+// Check that the keys are a P-256 public key and a 16 byte secret.
+func (keys PushKeys) errors() []FieldError {
+	var errs []FieldError
+	p256dh, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(keys.P256dh, "="))
+	if err == nil {
+		_, err = ecdh.P256().NewPublicKey(p256dh)
+	}
+	if err != nil {
+		errs = append(errs, newFieldError("keys.p256dh", "p256dh", "must be a base64url encoded P-256 public key"))
+	}
+	auth, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(keys.Auth, "="))
+	if err != nil || len(auth) != 16 {
+		errs = append(errs, newFieldError("keys.auth", "auth", "must be a base64url encoded 16 byte secret"))
+	}
+	return errs
+}
+
+// Save a browser's subscription, replacing the one it had. Anyone can
+// subscribe to price drops; linking a subscription to a recipient is for
+// admins, at the admin route.
+func subscribePushHandler(admin bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if webPush() == nil {
+			writeProblem(w, r, codeNotificationsUnavailable, "Web Push isn't configured")
+			return
+		}
+
+		var sub PushSubscription
+		if err := decodeRequest(r, &sub); err != nil {
+			writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+			return
+		}
+
+		errs := validateRequest(sub)
+		if errs == nil {
+			errs = sub.Keys.errors()
+		}
+		if sub.Recipient != "" && !admin {
+			errs = append(errs, newFieldError("recipient", "excluded", "can only be set by admins"))
+		}
+		if errs != nil {
+			writeValidationErrors(w, r, errs)
+			return
+		}
+		if sub.Books == nil {
+			sub.Books = []int{}
+		}
+		sub.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		sub.UpdatedAt = sub.CreatedAt
+
+		// Watch each book from its price now.
+		err := store.WithTx(r.Context(), func(tx BookStore) error {
+			prices := map[int]float64{}
+			for _, id := range sub.Books {
+				book, err := tx.GetBook(r.Context(), id)
+				if err != nil {
+					return err
+				}
+				prices[id] = book.Price
+			}
+			return tx.SavePushSubscription(r.Context(), &sub, prices)
+		})
+		if errors.Is(err, ErrNotFound) {
+			writeProblem(w, r, codeBookNotFound, "Book not found")
+			return
+		} else if err != nil {
+			writeProblem(w, r, codeInternal, "Error saving push subscription")
+			log.Printf("Push subscription error: %v", err)
+			return
+		}
+
+		writeResponse(w, r, http.StatusCreated, PushSubscriptionResponse{
+			Status:  "success",
+			Message: "Push subscription saved successfully",
+			Data:    sub,
+		})
+	}
+}
+
+func unsubscribePushHandler(w http.ResponseWriter, r *http.Request) {
+	var req UnsubscribeRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	if errs := validateRequest(req); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	err := store.DeletePushSubscription(r.Context(), req.Endpoint)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codePushSubscriptionNotFound, "Push subscription not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error deleting push subscription")
+		log.Printf("Push subscription deletion error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Push subscription deleted successfully",
+	})
+}
+
+// Queue a price_drop push for every subscription that watched the updated
+// book at a higher price, in the outbox relay's transaction, and report
+// whether there were any.
+func queuePriceDrops(ctx context.Context, tx BookStore, event Event) (bool, error) {
+	if event.Type != EventBookUpdated || webPush() == nil {
+		return false, nil
+	}
+	watches, err := tx.WatchPrice(ctx, event.Book.Id, event.Book.Price)
+	if err != nil || len(watches) == 0 {
+		return false, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	for _, watch := range watches {
+		subject, body, _, err := renderNotification("push", NotificationPriceDrop, map[string]any{
+			"title":     event.Book.Title,
+			"author":    event.Book.Author,
+			"price":     fmt.Sprintf("%.2f", event.Book.Price),
+			"old_price": fmt.Sprintf("%.2f", watch.Price),
+			"currency":  currencies.base,
+		})
+		if err != nil {
+			return false, err
+		}
+		err = tx.EnqueueNotification(ctx, &Notification{
+			Channel:       "push",
+			Recipient:     watch.Endpoint,
+			Kind:          NotificationPriceDrop,
+			Subject:       subject,
+			Body:          body,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
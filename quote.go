@@ -0,0 +1,249 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Quote is a passage a reader saved from a book, attributed to whoever
+// says it. Quotes are private to their reader unless made public, when
+// they show on the book and can come up as the random quote.
+type Quote struct {
+	Id     int    `json:"id" xml:"id"`
+	UserId int    `json:"-" xml:"-"`
+	BookId int    `json:"book_id" xml:"book_id"`
+	Text   string `json:"text" xml:"text" validate:"required,max=2000"`
+	// Author is who the quote is by: the book's author unless given, as
+	// for a line spoken by one of its characters.
+	Author    string    `json:"author" xml:"author" validate:"max=255"`
+	Public    bool      `json:"public" xml:"public"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+	// Book is loaded by ListQuotes and RandomQuote.
+	Book *Book `json:"book,omitempty" xml:"book,omitempty"`
+}
+
+func (q Quote) timestamps() (created, updated time.Time) {
+	return q.CreatedAt, q.UpdatedAt
+}
+
+type QuoteResponse struct {
+	Status  string `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
+	Data    Quote  `json:"data" xml:"data"`
+}
+
+type QuotesResponse struct {
+	Status  string  `json:"status" xml:"status"`
+	Message string  `json:"message" xml:"message"`
+	Data    []Quote `json:"data" xml:"data>quote"`
+}
+
+// Tidy the text as typed, keeping the line breaks of verse and leaving
+// out any HTML.
+func (q *Quote) normalize() {
+	q.Text = strings.TrimSpace(norm.NFC.String(sanitizeText(q.Text)))
+	q.Author = normalizeText(sanitizeText(q.Author))
+}
+
+// List the user's quotes, public or not, newest first.
+func listQuotesHandler(w http.ResponseWriter, r *http.Request) {
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+	quotes, err := store.ListQuotes(r.Context(), currentUser(r).Id, after)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching quotes")
+		log.Printf("Quote query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, QuotesResponse{
+		Status:  "success",
+		Message: "Quotes retrieved successfully",
+		Data:    quotes,
+	})
+}
+
+func createQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+	var quote Quote
+	if err := decodeRequest(r, &quote); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	quote.normalize()
+	if errs := validateRequest(quote); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	quote = Quote{
+		UserId:    currentUser(r).Id,
+		BookId:    id,
+		Text:      quote.Text,
+		Author:    quote.Author,
+		Public:    quote.Public,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	quote.UpdatedAt = quote.CreatedAt
+
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		book, err := tx.GetBook(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		if quote.Author == "" {
+			quote.Author = book.Author
+		}
+		quote.Book = &book
+		return tx.CreateQuote(r.Context(), &quote)
+	})
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error creating quote")
+		log.Printf("Quote creation error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, QuoteResponse{
+		Status:  "success",
+		Message: "Quote created successfully",
+		Data:    quote,
+	})
+}
+
+// Replace the text, author and visibility of a quote; without an author
+// it keeps the one it had.
+func updateQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid quote ID")
+		return
+	}
+	var req Quote
+	if err := decodeRequest(r, &req); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	req.normalize()
+	if errs := validateRequest(req); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	userId := currentUser(r).Id
+	var quote Quote
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		var err error
+		if quote, err = tx.GetQuote(r.Context(), userId, id); err != nil {
+			return err
+		}
+		quote.Text, quote.Public = req.Text, req.Public
+		quote.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		if req.Author != "" {
+			quote.Author = req.Author
+		}
+		return tx.UpdateQuote(r.Context(), quote)
+	})
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeQuoteNotFound, "Quote not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error updating quote")
+		log.Printf("Quote update error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, QuoteResponse{
+		Status:  "success",
+		Message: "Quote updated successfully",
+		Data:    quote,
+	})
+}
+
+func deleteQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid quote ID")
+		return
+	}
+
+	err = store.DeleteQuote(r.Context(), currentUser(r).Id, id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeQuoteNotFound, "Quote not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error deleting quote")
+		log.Printf("Quote deletion error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Quote deleted successfully",
+	})
+}
+
+// List the public quotes from a book, newest first.
+func listBookQuotesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+
+	quotes, err := store.ListBookQuotes(r.Context(), id, after)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching quotes")
+		log.Printf("Quote query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, QuotesResponse{
+		Status:  "success",
+		Message: "Quotes retrieved successfully",
+		Data:    quotes,
+	})
+}
+
+// Answer with one of the public quotes, picked at random, for a homepage
+// to show.
+func randomQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	quote, err := store.RandomQuote(r.Context())
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeQuoteNotFound, "No quotes are public yet")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching quote")
+		log.Printf("Quote query error: %v", err)
+		return
+	}
+
+	// Each request gets its own pick.
+	w.Header().Set("Cache-Control", "no-store")
+	writeResponse(w, r, http.StatusOK, QuoteResponse{
+		Status:  "success",
+		Message: "Quote retrieved successfully",
+		Data:    quote,
+	})
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRecentBooks = 20
+	maxRecentBooks     = 100
+)
+
+// What recent books can be newest by: when they were added, or when they
+// were last changed.
+const (
+	RecentAdded   = "added"
+	RecentUpdated = "updated"
+)
+
+// When book was added, or last changed if by is RecentUpdated; nil for
+// books from before that was recorded.
+func recentTime(book Book, by string) *time.Time {
+	if by == RecentUpdated {
+		return book.UpdatedAt
+	}
+	return book.CreatedAt
+}
+
+// Sort books newest first by when they were added or last changed, those
+// at the same time by id, newest first too. Books from before that was
+// recorded come last.
+func sortRecent(books []Book, by string) {
+	sort.SliceStable(books, func(i, j int) bool {
+		a, b := recentTime(books[i], by), recentTime(books[j], by)
+		switch {
+		case a != nil && b != nil && !a.Equal(*b):
+			return a.After(*b)
+		case (a == nil) != (b == nil):
+			return a != nil
+		}
+		return books[i].Id > books[j].Id
+	})
+}
+
+// List the books added last, or with ?by=updated those changed last,
+// newest first: what's new for dashboards, and for clients keeping a copy
+// of the catalog what to fetch again.
+func recentBooksHandler(w http.ResponseWriter, r *http.Request) {
+	by := r.URL.Query().Get("by")
+	switch by {
+	case "":
+		by = RecentAdded
+	case RecentAdded, RecentUpdated:
+	default:
+		writeProblem(w, r, codeInvalidParameter, "by must be added or updated")
+		return
+	}
+	limit, ok := intParameter(r, "limit", defaultRecentBooks, maxRecentBooks)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(maxRecentBooks))
+		return
+	}
+	fields, err := requestedFields(r)
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, err.Error())
+		return
+	}
+
+	books, err := store.RecentBooks(r.Context(), by, limit)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching books from database")
+		log.Printf("Database query error: %v", err)
+		return
+	}
+	for i := range books {
+		books[i] = books[i].only(fields)
+	}
+	currency, err := convertPrices(r, books)
+	if err != nil {
+		writeConversionError(w, r, err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, BooksResponse{
+		Status:   "success",
+		Message:  "Recent books retrieved successfully",
+		Data:     books,
+		Currency: currency,
+	})
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// Recommendation is a book suggested to a user because readers who
+// shelved books on the user's shelf also shelved it.
+type Recommendation struct {
+	UserId int     `json:"-" xml:"-"`
+	BookId int     `json:"book_id" xml:"book_id"`
+	Score  float64 `json:"score" xml:"score"`
+	// BecauseBookId is the book on the user's shelf that counted most
+	// toward the recommendation.
+	BecauseBookId int `json:"because_book_id" xml:"because_book_id"`
+	// Book is loaded by ListRecommendations.
+	Book *Book `json:"book,omitempty" xml:"book,omitempty"`
+}
+
+type RecommendationsResponse struct {
+	Status  string           `json:"status" xml:"status"`
+	Message string           `json:"message" xml:"message"`
+	Data    []Recommendation `json:"data" xml:"data>recommendation"`
+}
+
+const (
+	defaultRecommendations = 20
+	// maxRecommendations is also how many the recommendations job keeps
+	// for each user.
+	maxRecommendations = 50
+)
+
+func listRecommendationsHandler(w http.ResponseWriter, r *http.Request) {
+	limit, ok := intParameter(r, "limit", defaultRecommendations, maxRecommendations)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(maxRecommendations))
+		return
+	}
+
+	recommendations, err := store.ListRecommendations(r.Context(), currentUser(r).Id, limit)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching recommendations")
+		log.Printf("Recommendation query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, RecommendationsResponse{
+		Status:  "success",
+		Message: "Recommendations retrieved successfully",
+		Data:    recommendations,
+	})
+}
+
+// Recompute every user's recommendations from all the shelves; the
+// recommendations job.
+func refreshRecommendations(ctx context.Context) error {
+	items, err := store.AllShelfItems(ctx)
+	if err != nil {
+		return err
+	}
+	return store.ReplaceRecommendations(ctx, recommend(items))
+}
+
+// How much a book on a shelf says about what its reader likes. Shelving
+// an unrated book shows interest; ratings scale that, and books rated 1
+// count against the books shelved with them.
+func shelfWeight(item ShelfItem) float64 {
+	if item.Rating == nil {
+		return 1
+	}
+	return float64(*item.Rating-2) / 2
+}
+
+// Recommend each user the books shelved most often by readers of the
+// books on their shelf ("readers who shelved X also shelved Y"). How
+// strongly X suggests Y is the cosine similarity of their readers, the
+// readers of both over the geometric mean of the readers of each, so
+// books everyone shelves don't crowd out the rest.
+func recommend(items []ShelfItem) []Recommendation {
+	shelves := map[int][]ShelfItem{}
+	readers := map[int]int{}
+	for _, item := range items {
+		shelves[item.UserId] = append(shelves[item.UserId], item)
+		readers[item.BookId]++
+	}
+	together := map[[2]int]int{}
+	for _, shelf := range shelves {
+		for _, a := range shelf {
+			for _, b := range shelf {
+				if a.BookId != b.BookId {
+					together[[2]int{a.BookId, b.BookId}]++
+				}
+			}
+		}
+	}
+	alsoShelved := map[int][]int{}
+	for pair := range together {
+		alsoShelved[pair[0]] = append(alsoShelved[pair[0]], pair[1])
+	}
+
+	var recommendations []Recommendation
+	for userId, shelf := range shelves {
+		shelved := map[int]bool{}
+		for _, item := range shelf {
+			shelved[item.BookId] = true
+		}
+
+		scores := map[int]float64{}
+		because := map[int]int{}
+		best := map[int]float64{}
+		for _, item := range shelf {
+			weight := shelfWeight(item)
+			if weight == 0 {
+				continue
+			}
+			for _, other := range alsoShelved[item.BookId] {
+				if shelved[other] {
+					continue
+				}
+				similarity := float64(together[[2]int{item.BookId, other}]) /
+					math.Sqrt(float64(readers[item.BookId]*readers[other]))
+				scores[other] += weight * similarity
+				if weight*similarity > best[other] {
+					best[other], because[other] = weight*similarity, item.BookId
+				}
+			}
+		}
+
+		var mine []Recommendation
+		for bookId, score := range scores {
+			if score > 0 {
+				mine = append(mine, Recommendation{
+					UserId:        userId,
+					BookId:        bookId,
+					Score:         math.Round(score*10000) / 10000,
+					BecauseBookId: because[bookId],
+				})
+			}
+		}
+		sort.Slice(mine, func(i, j int) bool {
+			if mine[i].Score != mine[j].Score {
+				return mine[i].Score > mine[j].Score
+			}
+			return mine[i].BookId < mine[j].BookId
+		})
+		recommendations = append(recommendations, mine[:min(len(mine), maxRecommendations)]...)
+	}
+	return recommendations
+}
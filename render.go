@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Media types the API can respond with.
+const (
+	mediaTypeJSON     = "application/json"
+	mediaTypeJSONAPI  = "application/vnd.api+json"
+	mediaTypeXML      = "application/xml"
+	mediaTypeTextXML  = "text/xml"
+	mediaTypeMsgpack  = "application/msgpack"
+	mediaTypeProtobuf = "application/x-protobuf"
+
+	mediaTypeProblemJSON = "application/problem+json"
+	mediaTypeProblemXML  = "application/problem+xml"
+)
+
+// A responseFormat encodes the *Response types in one media type, and
+// decodes request bodies sent in it.
+type responseFormat struct {
+	mediaType   string
+	contentType string
+	// problemContentType is the Content-Type of problems, if the format
+	// has a problem details media type.
+	problemContentType string
+	// encode returns the body for v and the status to send it with, which
+	// is status unless the format reports errors its own way.
+	encode func(r *http.Request, status int, v any) (int, []byte, error)
+	decode func(body io.Reader, v any) error
+	// canEncode reports whether the format has an encoding for v; nil
+	// means it has one for every response.
+	canEncode func(v any) bool
+}
+
+// The formats in order of preference, the default first.
+var responseFormats = []responseFormat{
+	{mediaType: mediaTypeJSON, problemContentType: mediaTypeProblemJSON, encode: encodeJSON, decode: decodeJSON},
+	{mediaType: mediaTypeJSONAPI, encode: encodeJSONAPI, decode: decodeJSONAPI},
+	{mediaType: mediaTypeXML, contentType: mediaTypeXML + "; charset=utf-8", problemContentType: mediaTypeProblemXML + "; charset=utf-8", encode: encodeXML, decode: decodeXML},
+	{mediaType: mediaTypeTextXML, contentType: mediaTypeTextXML + "; charset=utf-8", problemContentType: mediaTypeProblemXML + "; charset=utf-8", encode: encodeXML, decode: decodeXML},
+	{mediaType: mediaTypeMsgpack, encode: encodeMsgpack, decode: decodeMsgpack},
+	{mediaType: mediaTypeProtobuf, encode: encodeProtobuf, decode: decodeProtobuf, canEncode: canEncodeProtobuf},
+}
+
+var responseMediaTypes = formatMediaTypes(responseFormats)
+
+func formatMediaTypes(formats []responseFormat) []string {
+	mediaTypes := make([]string, len(formats))
+	for i, format := range formats {
+		mediaTypes[i] = format.mediaType
+	}
+	return mediaTypes
+}
+
+// Write v, one of the *Response types or a Problem, with the given status
+// in the format the client prefers according to Accept, among those that
+// can encode v. Clients that accept none of them get JSON. Messages are
+// translated into the language the client prefers according to
+// Accept-Language.
+func writeResponse(w http.ResponseWriter, r *http.Request, status int, v any) {
+	w.Header().Add("Vary", "Accept")
+	w.Header().Add("Vary", "Accept-Language")
+	lang := requestLanguage(r)
+	w.Header().Set("Content-Language", lang.String())
+	v = localize(lang, v)
+
+	formats := slices.DeleteFunc(slices.Clone(responseFormats), func(format responseFormat) bool {
+		return format.canEncode != nil && !format.canEncode(v)
+	})
+	mediaType := negotiateMediaType(r.Header.Get("Accept"), formatMediaTypes(formats))
+	format := formats[slices.IndexFunc(formats, func(format responseFormat) bool { return format.mediaType == mediaType })]
+
+	status, body, err := format.encode(r, status, v)
+	if err != nil {
+		log.Printf("Response encoding error (%s): %v", format.mediaType, err)
+		v = localize(lang, newProblem(r, codeInternal, "Error encoding response"))
+		status, body, err = format.encode(r, http.StatusInternalServerError, v)
+		if err != nil {
+			http.Error(w, "Error encoding response", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	contentType := format.contentType
+	if _, ok := v.(Problem); ok && format.problemContentType != "" {
+		contentType = format.problemContentType
+	}
+	if contentType == "" {
+		contentType = format.mediaType
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// Decode the request body into v according to its Content-Type, as JSON
+// if it names none of the formats.
+func decodeRequest(r *http.Request, v any) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	for _, format := range responseFormats {
+		if format.mediaType == mediaType {
+			return format.decode(r.Body, v)
+		}
+	}
+	return decodeJSON(r.Body, v)
+}
+
+func encodeJSON(r *http.Request, status int, v any) (int, []byte, error) {
+	body, err := json.Marshal(v)
+	return status, append(body, '\n'), err
+}
+
+func decodeJSON(body io.Reader, v any) error {
+	return json.NewDecoder(body).Decode(v)
+}
+
+func encodeJSONAPI(r *http.Request, status int, v any) (int, []byte, error) {
+	doc, err := jsonAPIDocument(status, v)
+	if err != nil {
+		return status, nil, err
+	}
+	if errorDoc, ok := doc.(jsonAPIErrorDocument); ok {
+		status = errorDoc.status
+	}
+	body, err := json.Marshal(doc)
+	return status, append(body, '\n'), err
+}
+
+// Encode v as an XML document whose root <response> element holds the
+// fields of v, or a <problem> element for problems.
+func encodeXML(r *http.Request, status int, v any) (int, []byte, error) {
+	root := xml.Name{Local: "response"}
+	if _, ok := v.(Problem); ok {
+		root = xml.Name{Space: "urn:ietf:rfc:7807", Local: "problem"}
+	}
+
+	var body bytes.Buffer
+	body.WriteString(xml.Header)
+	if err := xml.NewEncoder(&body).EncodeElement(v, xml.StartElement{Name: root}); err != nil {
+		return status, nil, err
+	}
+	body.WriteByte('\n')
+	return status, body.Bytes(), nil
+}
+
+func decodeXML(body io.Reader, v any) error {
+	return xml.NewDecoder(body).Decode(v)
+}
+
+// Pick the offer the Accept header ranks highest, preferring earlier
+// offers on a tie. Returns the first offer if the header is empty or
+// accepts none of them.
+func negotiateMediaType(accept string, offers []string) string {
+	best, bestQ := offers[0], 0.0
+	if accept == "" {
+		return best
+	}
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		mediaRange = strings.ToLower(strings.TrimSpace(mediaRange))
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q <= bestQ {
+			continue
+		}
+
+		for _, offer := range offers {
+			if mediaRangeMatches(mediaRange, offer) {
+				best, bestQ = offer, q
+				break
+			}
+		}
+	}
+	return best
+}
+
+// Report whether a media range such as "application/*" covers mediaType.
+func mediaRangeMatches(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(mediaRange, "/*")
+	return ok && strings.HasPrefix(mediaType, prefix+"/")
+}
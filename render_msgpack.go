@@ -0,0 +1,26 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MessagePack responses have the same field names as the JSON ones.
+func encodeMsgpack(r *http.Request, status int, v any) (int, []byte, error) {
+	var body bytes.Buffer
+	enc := msgpack.NewEncoder(&body)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return status, nil, err
+	}
+	return status, body.Bytes(), nil
+}
+
+func decodeMsgpack(body io.Reader, v any) error {
+	dec := msgpack.NewDecoder(body)
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"google.golang.org/protobuf/proto"
+
+	bookshelfv1 "github.com/amroexe/proto/bookshelf/v1"
+)
+
+// Protobuf responses are bookshelf.v1.ApiResponse messages, which carry
+// books but no other resources, so they are only offered for book
+// responses without included resources and those without data. Problems
+// fall back to problem+json.
+func canEncodeProtobuf(v any) bool {
+	switch v := v.(type) {
+	case Response:
+		return true
+	case BookResponse:
+		return v.Included == nil
+	case BooksResponse:
+		return v.Included == nil
+	}
+	return false
+}
+
+func encodeProtobuf(r *http.Request, status int, v any) (int, []byte, error) {
+	var msg bookshelfv1.ApiResponse
+	switch v := v.(type) {
+	case Response:
+		msg.Message = v.Message
+	case BookResponse:
+		msg.Status, msg.Message, msg.Links = v.Status, v.Message, linksToProto(v.Links)
+		msg.Data = &bookshelfv1.ApiResponse_Book{Book: bookToProto(v.Data)}
+	case BooksResponse:
+		msg.Status, msg.Message, msg.Links = v.Status, v.Message, linksToProto(v.Links)
+		msg.Data = &bookshelfv1.ApiResponse_Books{Books: &bookshelfv1.BookList{Books: booksToProto(v.Data)}}
+	default:
+		return status, nil, fmt.Errorf("%T has no protobuf encoding", v)
+	}
+
+	body, err := proto.Marshal(&msg)
+	return status, body, err
+}
+
+// Request bodies are bookshelf.v1.Book messages.
+func decodeProtobuf(body io.Reader, v any) error {
+	book, ok := v.(*Book)
+	if !ok {
+		return fmt.Errorf("%T has no protobuf encoding", v)
+	}
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	var msg bookshelfv1.Book
+	if err := proto.Unmarshal(raw, &msg); err != nil {
+		return err
+	}
+	*book = bookFromProto(&msg)
+	return nil
+}
+
+func linksToProto(links Links) map[string]*bookshelfv1.Link {
+	if len(links) == 0 {
+		return nil
+	}
+	out := make(map[string]*bookshelfv1.Link, len(links))
+	for rel, link := range links {
+		out[rel] = &bookshelfv1.Link{Href: link.Href, Method: link.Method}
+	}
+	return out
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// RepriceRequest changes the price of the books that match every filter
+// given, by Percent or by Delta; exactly one of them must be set.
+type RepriceRequest struct {
+	// Author matches books by the author, ignoring case.
+	Author string `json:"author" xml:"author" validate:"max=255"`
+	// Format matches books in the format, so each format can be priced
+	// on its own.
+	Format string `json:"format" xml:"format" validate:"omitempty,book_format"`
+	// Category matches books in the category, ignoring case.
+	Category string `json:"category" xml:"category" validate:"max=100"`
+	// MinPrice and MaxPrice bound the current price, inclusive.
+	MinPrice *float64 `json:"min_price" xml:"min_price" validate:"omitempty,gte=0"`
+	MaxPrice *float64 `json:"max_price" xml:"max_price" validate:"omitempty,gte=0"`
+	// Percent changes prices by a percentage: 10 raises them by a tenth,
+	// -25 cuts them by a quarter.
+	Percent *float64 `json:"percent" xml:"percent" validate:"omitempty,gte=-100"`
+	// Delta adds a fixed amount, negative to lower prices.
+	Delta *float64 `json:"delta" xml:"delta"`
+	// DryRun reports the changes without making them.
+	DryRun bool `json:"dry_run" xml:"dry_run"`
+}
+
+// PriceChange is the repricing of one book.
+type PriceChange struct {
+	Id       int     `json:"id" xml:"id"`
+	Title    string  `json:"title" xml:"title"`
+	Author   string  `json:"author" xml:"author"`
+	OldPrice float64 `json:"old_price" xml:"old_price"`
+	NewPrice float64 `json:"new_price" xml:"new_price"`
+}
+
+type RepriceResult struct {
+	DryRun bool          `json:"dry_run" xml:"dry_run"`
+	Books  []PriceChange `json:"books" xml:"books>book"`
+}
+
+type RepriceResponse struct {
+	Status  string        `json:"status" xml:"status"`
+	Message string        `json:"message" xml:"message"`
+	Data    RepriceResult `json:"data" xml:"data"`
+}
+
+// The highest price a book can have, as Book's validate tag allows.
+const maxBookPrice = 99999999.99
+
+// Check the rules that involve more than one field of the request.
+func (req RepriceRequest) crossFieldErrors() []FieldError {
+	var errs []FieldError
+	if req.Percent == nil && req.Delta == nil {
+		errs = append(errs, newFieldError("percent", "required_without", "or delta is required"))
+	}
+	if req.Percent != nil && req.Delta != nil {
+		errs = append(errs, newFieldError("percent", "excluded_with", "can't be set with delta"))
+	}
+	if req.MinPrice != nil && req.MaxPrice != nil && *req.MaxPrice < *req.MinPrice {
+		errs = append(errs, newFieldError("max_price", "gtefield", "must be at least min_price"))
+	}
+	return errs
+}
+
+// Whether the book, in the given categories, matches the request.
+func (req RepriceRequest) matches(book Book, categories []string) bool {
+	if req.Author != "" && !strings.EqualFold(book.Author, req.Author) {
+		return false
+	}
+	if req.Category != "" && !slices.ContainsFunc(categories, func(c string) bool { return strings.EqualFold(c, req.Category) }) {
+		return false
+	}
+	if req.Format != "" && book.Format != req.Format {
+		return false
+	}
+	if req.MinPrice != nil && book.Price < *req.MinPrice {
+		return false
+	}
+	if req.MaxPrice != nil && book.Price > *req.MaxPrice {
+		return false
+	}
+	return true
+}
+
+// The adjusted price, rounded to the cent and kept from 0 to
+// maxBookPrice.
+func (req RepriceRequest) apply(price float64) float64 {
+	if req.Percent != nil {
+		price *= 1 + *req.Percent/100
+	} else {
+		price += *req.Delta
+	}
+	return min(max(math.Round(price*100)/100, 0), maxBookPrice)
+}
+
+func repriceBooksHandler(w http.ResponseWriter, r *http.Request) {
+	var req RepriceRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	req.Author = normalizeText(req.Author)
+	req.Category = normalizeText(req.Category)
+	req.Format = strings.ToLower(strings.TrimSpace(req.Format))
+
+	errs := append(validateRequest(req), req.crossFieldErrors()...)
+	if len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	// Read and write in one transaction, so the books repriced are the
+	// ones that matched and either all change or none do. The books stay
+	// locked in between, so no price set meanwhile is overwritten.
+	changes := []PriceChange{}
+	err := store.WithTx(r.Context(), func(tx BookStore) error {
+		books, err := tx.LockBooks(r.Context())
+		if err != nil {
+			return err
+		}
+		var categories map[int][]string
+		if req.Category != "" {
+			if categories, err = tx.BookCategories(r.Context()); err != nil {
+				return err
+			}
+		}
+		prices := map[int]float64{}
+		for _, book := range books {
+			if !req.matches(book, categories[book.Id]) {
+				continue
+			}
+			price := req.apply(book.Price)
+			if price == book.Price {
+				continue
+			}
+			prices[book.Id] = price
+			changes = append(changes, PriceChange{
+				Id:       book.Id,
+				Title:    book.Title,
+				Author:   book.Author,
+				OldPrice: book.Price,
+				NewPrice: price,
+			})
+		}
+		if req.DryRun || len(prices) == 0 {
+			return nil
+		}
+		_, err = tx.SetPrices(r.Context(), prices)
+		return err
+	})
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error repricing books")
+		log.Printf("Reprice error: %v", err)
+		return
+	}
+
+	message := "Prices updated successfully"
+	if req.DryRun {
+		message = "Dry run; no prices were changed"
+	}
+	writeResponse(w, r, http.StatusOK, RepriceResponse{
+		Status:  "success",
+		Message: message,
+		Data:    RepriceResult{DryRun: req.DryRun, Books: changes},
+	})
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// RestoreRequest names the backup to restore. Without Confirm nothing is
+// changed; the response describes what restoring would do.
+type RestoreRequest struct {
+	Backup  string `json:"backup" xml:"backup" validate:"required"`
+	Confirm bool   `json:"confirm" xml:"confirm"`
+}
+
+// RestoreSummary describes a restore, done or proposed.
+type RestoreSummary struct {
+	Backup    string    `json:"backup" xml:"backup"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	// Books and Reviews are how many the backup holds.
+	Books   int `json:"books" xml:"books"`
+	Reviews int `json:"reviews" xml:"reviews"`
+	// ReplacedBooks is how many books the catalog had before, and
+	// DeletedBooks how many of them the backup doesn't have.
+	ReplacedBooks int `json:"replaced_books" xml:"replaced_books"`
+	DeletedBooks  int `json:"deleted_books" xml:"deleted_books"`
+	// SafetyBackup is the backup of the catalog made just before the
+	// restore, to undo it with.
+	SafetyBackup string `json:"safety_backup,omitempty" xml:"safety_backup,omitempty"`
+	Restored     bool   `json:"restored" xml:"restored"`
+}
+
+type RestoreResponse struct {
+	Status  string         `json:"status" xml:"status"`
+	Message string         `json:"message" xml:"message"`
+	Data    RestoreSummary `json:"data" xml:"data"`
+}
+
+// errIncompatibleBackup is returned for backups this server can't read.
+var errIncompatibleBackup = errors.New("incompatible backup")
+
+// Read and decode a stored backup of the tenant ctx acts for, bringing it
+// up to the current format. Other tenants' backups are not found.
+func loadBackup(ctx context.Context, name string) (Backup, error) {
+	if !ownBackup(ctx, name) {
+		return Backup{}, ErrNotFound
+	}
+	f, err := backups.storage.Open(ctx, name)
+	if err != nil {
+		return Backup{}, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return Backup{}, err
+	}
+	var backup Backup
+	if err := json.NewDecoder(zr).Decode(&backup); err != nil {
+		return Backup{}, err
+	}
+	return backup, upgradeBackup(&backup)
+}
+
+// Bring a backup written by an older server up to date. The database
+// schema is already current, migrated at startup, so only the data can
+// differ: it may lack what later migrations added. Backups from a newer
+// server may have data this one would lose, so they are refused.
+func upgradeBackup(backup *Backup) error {
+	if backup.Version > backupVersion || backup.Schema > schemaVersion() {
+		return fmt.Errorf("%w: written by a newer server (schema %s)", errIncompatibleBackup, backup.Schema)
+	}
+
+	// Slugs came with migration 0008.
+	taken := map[string]bool{}
+	for _, book := range backup.Books {
+		taken[book.Slug] = true
+	}
+	for i, book := range backup.Books {
+		if book.Slug == "" {
+			backup.Books[i].Slug = firstFreeSlug(slugify(book.Title), taken)
+			taken[backup.Books[i].Slug] = true
+		}
+	}
+	return nil
+}
+
+func restoreHandler(w http.ResponseWriter, r *http.Request) {
+	if backupsDisabled(w, r) {
+		return
+	}
+
+	var req RestoreRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	if errs := validateRequest(req); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	backup, err := loadBackup(r.Context(), req.Backup)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBackupNotFound, "Backup not found")
+		return
+	} else if errors.Is(err, errIncompatibleBackup) {
+		writeProblem(w, r, codeBackupIncompatible, "The backup was written by a newer version of the server")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error reading the backup")
+		log.Printf("Backup read error: %v", err)
+		return
+	}
+
+	current, err := store.ListBooks(r.Context())
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error restoring the backup")
+		log.Printf("Restore error: %v", err)
+		return
+	}
+	summary := RestoreSummary{
+		Backup:        req.Backup,
+		CreatedAt:     backup.CreatedAt,
+		Books:         len(backup.Books),
+		Reviews:       len(backup.Reviews),
+		ReplacedBooks: len(current),
+	}
+	restored := make(map[int]bool, len(backup.Books))
+	for _, book := range backup.Books {
+		restored[book.Id] = true
+	}
+	for _, book := range current {
+		if !restored[book.Id] {
+			summary.DeletedBooks++
+		}
+	}
+
+	if !req.Confirm {
+		writeResponse(w, r, http.StatusOK, RestoreResponse{
+			Status:  "success",
+			Message: "Nothing was changed; send confirm: true to restore the backup",
+			Data:    summary,
+		})
+		return
+	}
+
+	safety, err := backups.run(r.Context())
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error backing up the catalog")
+		log.Printf("Backup error: %v", err)
+		return
+	}
+	summary.SafetyBackup = safety.Name
+
+	err = store.RestoreCatalog(r.Context(), backup.Books, backup.reviews())
+	if errors.Is(err, ErrBooksInUse) {
+		writeProblem(w, r, codeBooksInUse, "Books the backup doesn't have are still in use, so it can't be restored")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error restoring the backup")
+		log.Printf("Restore error: %v", err)
+		return
+	}
+	summary.Restored = true
+	log.Printf("Restored backup %s (%d books), saved the catalog before in %s", req.Backup, summary.Books, safety.Name)
+
+	writeResponse(w, r, http.StatusOK, RestoreResponse{
+		Status:  "success",
+		Message: "Backup restored successfully",
+		Data:    summary,
+	})
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Whether any log is kept for a limited time.
+func (c RetentionConfig) enabled() bool {
+	return c.SearchQueries.Duration > 0 || c.WebhookDeliveries.Duration > 0 || c.NotificationFailures.Duration > 0
+}
+
+// Delete what each log holds from longer ago than it is kept, of every
+// tenant; the retention_purge job.
+func purgeExpiredLogs(retention RetentionConfig) func(ctx context.Context) error {
+	logs := []struct {
+		name   string
+		keep   time.Duration
+		delete func(s BookStore, ctx context.Context, before time.Time) (int64, error)
+	}{
+		{"searches", retention.SearchQueries.Duration, BookStore.DeleteSearchesBefore},
+		{"webhook deliveries", retention.WebhookDeliveries.Duration, BookStore.DeleteWebhookDeliveriesBefore},
+		{"notification failures", retention.NotificationFailures.Duration, BookStore.DeleteNotificationFailuresBefore},
+	}
+	return func(ctx context.Context) error {
+		now := time.Now().UTC()
+		for _, l := range logs {
+			if l.keep <= 0 {
+				continue
+			}
+			n, err := l.delete(store, ctx, now.Add(-l.keep))
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				log.Printf("Purged %d %s", n, l.name)
+			}
+		}
+		return nil
+	}
+}
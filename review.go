@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Review is a reader's rating and opinion of a book.
+type Review struct {
+	Id       int    `json:"id" xml:"id"`
+	BookId   int    `json:"book_id" xml:"book_id"`
+	Reviewer string `json:"reviewer" xml:"reviewer" validate:"required,max=255"`
+	// Rating is from 1 to 5.
+	Rating int    `json:"rating" xml:"rating" validate:"min=1,max=5"`
+	Body   string `json:"body" xml:"body"`
+	// CreatedAt is when the store added the review, in UTC; reviews from
+	// before it was recorded have none. Reviews don't change, so they
+	// have no UpdatedAt.
+	CreatedAt *time.Time `json:"created_at,omitempty" xml:"created_at,omitempty"`
+	// UserId is the account of the reader who posted it, if they were
+	// signed in, which their export and erasure go by.
+	UserId *int `json:"-" xml:"-"`
+}
+
+// Tidy the text as typed, keeping the body's line breaks and leaving out
+// any HTML.
+func (r *Review) normalize() {
+	r.Reviewer = normalizeText(sanitizeText(r.Reviewer))
+	r.Body = strings.TrimSpace(norm.NFC.String(sanitizeText(r.Body)))
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"io/fs"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// The path prefix of the current API version.
+const apiPrefix = "/api/v1"
+
+// Each API version registers its routes on its own subrouter, so a later
+// version with different payloads can be mounted next to the ones before it.
+// The frontend, if not nil, answers the GET requests no route matches.
+func newRouter(cfg Config, frontend fs.FS) *mux.Router {
+	r := mux.NewRouter()
+	r.Use(compressMiddleware, resolveTenant(cfg.TenantDomain), rememberClient)
+	setProblemHandlers(r)
+	if frontend != nil {
+		// Middleware only wraps matched routes.
+		r.NotFoundHandler = compressMiddleware(spaHandler(frontend, r.NotFoundHandler))
+	}
+
+	r.HandleFunc("/check", checkServer).Methods("GET")
+	r.HandleFunc("/openapi.json", openAPIHandler(r)).Methods("GET")
+	r.HandleFunc("/docs", docsHandler).Methods("GET")
+	r.HandleFunc("/graphql", graphqlHandler).Methods("GET", "POST")
+	r.HandleFunc("/ws", wsHandler).Methods("GET")
+	r.HandleFunc("/events", sseHandler).Methods("GET")
+	r.HandleFunc("/feed", withETag(feedHandler(cfg.CoverURL))).Methods("GET")
+	r.HandleFunc("/sitemap.xml", withETag(sitemapHandler(cfg.BookPageURL))).Methods("GET")
+	r.HandleFunc("/sitemap-{page:[0-9]+}.xml", withETag(sitemapPageHandler(cfg.BookPageURL))).Methods("GET")
+	r.HandleFunc("/problems/{type}", problemTypeHandler).Methods("GET")
+
+	admin := r.PathPrefix("/admin").Subrouter()
+	admin.Use(requireAdmin(cfg.AdminToken))
+	admin.Handle("", http.RedirectHandler("/admin/", http.StatusMovedPermanently)).Methods("GET")
+	admin.PathPrefix("/").Handler(adminUIHandler("/admin/")).Methods("GET")
+
+	registerV1Routes(r.PathPrefix(apiPrefix).Subrouter(), cfg)
+
+	// Compatibility layer: the paths from before versioning keep serving
+	// the v1 book routes.
+	legacy := r.NewRoute().Subrouter()
+	legacy.Use(deprecatedPathMiddleware(apiPrefix))
+	registerBookRoutes(legacy)
+
+	return r
+}
+
+// Version 1 of the API.
+func registerV1Routes(r *mux.Router, cfg Config) {
+	registerBookRoutes(r)
+	r.HandleFunc("/stats", withETag(statsHandler)).Methods("GET")
+	r.HandleFunc("/books/trending", trendingBooksHandler).Methods("GET")
+	r.HandleFunc("/books/recent", withETag(recentBooksHandler)).Methods("GET")
+	r.HandleFunc("/book/{id}/barcode", withETag(labelHandler(encodeBarcode))).Methods("GET")
+	r.HandleFunc("/book/{id}/qrcode", withETag(labelHandler(encodeQRCode))).Methods("GET")
+	r.HandleFunc("/book/{id}/subjects", getBookSubjectsHandler).Methods("GET")
+	r.HandleFunc("/book/{id}/subjects", updateBookSubjectsHandler).Methods("PUT")
+	r.HandleFunc("/book/{id}/similar", similarBooksHandler).Methods("GET")
+	r.HandleFunc("/book/{id}/quotes", listBookQuotesHandler).Methods("GET")
+	r.HandleFunc("/book/{id}/prices", bookPricesHandler).Methods("GET")
+	r.HandleFunc("/book/{id}/availability", bookAvailabilityHandler).Methods("GET")
+	r.HandleFunc("/book/{id}/nearby", nearbyBranchesHandler).Methods("GET")
+	r.HandleFunc("/branches", listBranchesHandler).Methods("GET")
+	r.HandleFunc("/quotes/random", randomQuoteHandler).Methods("GET")
+	r.HandleFunc("/lookup/{barcode}", lookupBarcodeHandler).Methods("GET")
+	r.HandleFunc("/series", listSeriesHandler).Methods("GET")
+	r.HandleFunc("/series", createSeriesHandler).Methods("POST")
+	r.HandleFunc("/series/{id}", getSeriesHandler).Methods("GET")
+	r.HandleFunc("/series/{id}", updateSeriesHandler).Methods("PUT")
+	r.HandleFunc("/series/{id}", deleteSeriesHandler).Methods("DELETE")
+	r.HandleFunc("/series/{id}/books", listSeriesBooksHandler).Methods("GET")
+	r.HandleFunc("/series/{id}/books/{book_id}", setSeriesBookHandler).Methods("PUT")
+	r.HandleFunc("/series/{id}/books/{book_id}", removeSeriesBookHandler).Methods("DELETE")
+	r.HandleFunc("/works", listWorksHandler).Methods("GET")
+	r.HandleFunc("/works", createWorkHandler).Methods("POST")
+	r.HandleFunc("/works/search", searchWorksHandler).Methods("GET")
+	r.HandleFunc("/works/{id}", getWorkHandler).Methods("GET")
+	r.HandleFunc("/works/{id}", updateWorkHandler).Methods("PUT")
+	r.HandleFunc("/works/{id}", deleteWorkHandler).Methods("DELETE")
+	r.HandleFunc("/works/{id}/editions/{book_id}", addEditionHandler).Methods("PUT")
+	r.HandleFunc("/works/{id}/editions/{book_id}", removeEditionHandler).Methods("DELETE")
+	r.HandleFunc("/push/key", pushKeyHandler).Methods("GET")
+	r.HandleFunc("/push/subscriptions", subscribePushHandler(false)).Methods("POST")
+	r.HandleFunc("/push/subscriptions", unsubscribePushHandler).Methods("DELETE")
+	r.HandleFunc("/users", createUserHandler).Methods("POST")
+	r.HandleFunc("/collections/shared/{token}", withETag(getSharedCollectionHandler)).Methods("GET")
+
+	me := r.PathPrefix("/me").Subrouter()
+	me.Use(requireUser)
+	me.HandleFunc("", getMeHandler).Methods("GET")
+	me.HandleFunc("/export", exportMeHandler).Methods("GET")
+	me.HandleFunc("/delete", deleteMeHandler).Methods("POST")
+	me.HandleFunc("/shelf", listShelfHandler).Methods("GET")
+	me.HandleFunc("/books", listShelfHandler).Methods("GET")
+	me.HandleFunc("/books/{id}/progress", updateProgressHandler).Methods("PATCH")
+	me.HandleFunc("/books/{id}/notes", listBookNotesHandler).Methods("GET")
+	me.HandleFunc("/books/{id}/notes", createNoteHandler).Methods("POST")
+	me.HandleFunc("/books/{id}/quotes", createQuoteHandler).Methods("POST")
+	me.HandleFunc("/quotes", listQuotesHandler).Methods("GET")
+	me.HandleFunc("/quotes/{id}", updateQuoteHandler).Methods("PUT")
+	me.HandleFunc("/quotes/{id}", deleteQuoteHandler).Methods("DELETE")
+	me.HandleFunc("/notes", listNotesHandler).Methods("GET")
+	me.HandleFunc("/notes/{id}", getNoteHandler).Methods("GET")
+	me.HandleFunc("/notes/{id}", updateNoteHandler).Methods("PUT")
+	me.HandleFunc("/notes/{id}", deleteNoteHandler).Methods("DELETE")
+	me.HandleFunc("/shelf/{id}", shelveBookHandler).Methods("PUT")
+	me.HandleFunc("/shelf/{id}", unshelveBookHandler).Methods("DELETE")
+	me.HandleFunc("/wishlist", listWishlistHandler).Methods("GET")
+	me.HandleFunc("/wishlist/{id}", wishBookHandler).Methods("PUT")
+	me.HandleFunc("/wishlist/{id}", unwishBookHandler).Methods("DELETE")
+	me.HandleFunc("/searches", listSavedSearchesHandler).Methods("GET")
+	me.HandleFunc("/searches", createSavedSearchHandler).Methods("POST")
+	me.HandleFunc("/searches/{id}", deleteSavedSearchHandler).Methods("DELETE")
+	me.HandleFunc("/goals", listGoalsHandler).Methods("GET")
+	me.HandleFunc("/goals/{year}", getGoalHandler).Methods("GET")
+	me.HandleFunc("/goals/{year}", setGoalHandler).Methods("PUT")
+	me.HandleFunc("/goals/{year}", deleteGoalHandler).Methods("DELETE")
+	me.HandleFunc("/recommendations", listRecommendationsHandler).Methods("GET")
+	me.HandleFunc("/points", myPointsHandler).Methods("GET")
+	me.HandleFunc("/collections", listCollectionsHandler).Methods("GET")
+	me.HandleFunc("/collections", createCollectionHandler).Methods("POST")
+	me.HandleFunc("/collections/{id}", getCollectionHandler).Methods("GET")
+	me.HandleFunc("/collections/{id}", updateCollectionHandler).Methods("PUT")
+	me.HandleFunc("/collections/{id}", deleteCollectionHandler).Methods("DELETE")
+	me.HandleFunc("/collections/{id}/books/{book_id}", addCollectionBookHandler).Methods("PUT")
+	me.HandleFunc("/collections/{id}/books/{book_id}", removeCollectionBookHandler).Methods("DELETE")
+	me.HandleFunc("/collections/{id}/order", reorderCollectionHandler).Methods("PUT")
+	me.HandleFunc("/collections/{id}/share", shareCollectionHandler).Methods("PUT")
+	me.HandleFunc("/collections/{id}/share", unshareCollectionHandler).Methods("DELETE")
+
+	operator := r.NewRoute().Subrouter()
+	operator.Use(requireOperator(cfg.AdminToken))
+	operator.HandleFunc("/admin/tenants", createTenantHandler).Methods("POST")
+	operator.HandleFunc("/admin/tenants", listTenantsHandler).Methods("GET")
+
+	member := r.NewRoute().Subrouter()
+	member.Use(requireMemberOrAdmin(cfg.AdminToken))
+	member.HandleFunc("/members/{id}", getMemberHandler).Methods("GET")
+	member.HandleFunc("/members/{id}/loan-history", loanHistoryHandler).Methods("GET")
+	member.HandleFunc("/members/{id}/fines", listMemberFinesHandler).Methods("GET")
+
+	admin := r.NewRoute().Subrouter()
+	admin.Use(requireAdmin(cfg.AdminToken))
+	admin.HandleFunc("/members", listMembersHandler).Methods("GET")
+	admin.HandleFunc("/members", withIdempotency(createMemberHandler)).Methods("POST")
+	admin.HandleFunc("/members/card/{number}", getMemberByCardHandler).Methods("GET")
+	admin.HandleFunc("/members/{id}", updateMemberHandler).Methods("PUT")
+	admin.HandleFunc("/members/{id}", deleteMemberHandler).Methods("DELETE")
+	admin.HandleFunc("/loans", withIdempotency(createLoanHandler)).Methods("POST")
+	admin.HandleFunc("/loans/{id}/return", returnLoanHandler(cfg.OverdueFinePerDay)).Methods("POST")
+	admin.HandleFunc("/book/{id}/copies", listCopiesHandler).Methods("GET")
+	admin.HandleFunc("/book/{id}/copies", withIdempotency(createCopyHandler)).Methods("POST")
+	admin.HandleFunc("/copies/{id}", deleteCopyHandler).Methods("DELETE")
+	admin.HandleFunc("/copies/{id}/branch", setCopyBranchHandler).Methods("PUT")
+	admin.HandleFunc("/branches", withIdempotency(createBranchHandler)).Methods("POST")
+	admin.HandleFunc("/branches/{id}", updateBranchHandler).Methods("PUT")
+	admin.HandleFunc("/branches/{id}", deleteBranchHandler).Methods("DELETE")
+	admin.HandleFunc("/book/{id}/holds", listHoldsHandler).Methods("GET")
+	admin.HandleFunc("/holds", withIdempotency(createHoldHandler)).Methods("POST")
+	admin.HandleFunc("/holds/{id}", deleteHoldHandler).Methods("DELETE")
+	admin.HandleFunc("/circulation/checkout", withIdempotency(checkoutHandler)).Methods("POST")
+	admin.HandleFunc("/circulation/checkin", checkinHandler(cfg.OverdueFinePerDay)).Methods("POST")
+	admin.HandleFunc("/members/{id}/fines", withIdempotency(createFineHandler)).Methods("POST")
+	admin.HandleFunc("/fines/{id}/payments", withIdempotency(payFineHandler)).Methods("POST")
+	admin.HandleFunc("/fines/{id}/settle", settleFineHandler).Methods("POST")
+	admin.HandleFunc("/payments/{id}/receipt", getReceiptHandler).Methods("GET")
+	admin.HandleFunc("/admin/reports/outstanding-fines", outstandingFinesHandler).Methods("GET")
+	admin.HandleFunc("/admin/access-log", listMemberAccessHandler).Methods("GET")
+	admin.HandleFunc("/donations", listDonationsHandler).Methods("GET")
+	admin.HandleFunc("/donations", withIdempotency(createDonationHandler)).Methods("POST")
+	admin.HandleFunc("/donations/{id}", getDonationHandler).Methods("GET")
+	admin.HandleFunc("/donations/{id}/accept", acceptDonationHandler).Methods("POST")
+	admin.HandleFunc("/donations/{id}/reject", rejectDonationHandler).Methods("POST")
+	admin.HandleFunc("/admin/reports/donations", donationReportHandler).Methods("GET")
+	admin.HandleFunc("/suppliers", listSuppliersHandler).Methods("GET")
+	admin.HandleFunc("/suppliers", withIdempotency(createSupplierHandler)).Methods("POST")
+	admin.HandleFunc("/suppliers/{id}", getSupplierHandler).Methods("GET")
+	admin.HandleFunc("/suppliers/{id}", updateSupplierHandler).Methods("PUT")
+	admin.HandleFunc("/suppliers/{id}", deleteSupplierHandler).Methods("DELETE")
+	admin.HandleFunc("/suppliers/{id}/prices", listSupplierPricesHandler).Methods("GET")
+	admin.HandleFunc("/suppliers/{id}/prices/{book_id}", setSupplierPriceHandler).Methods("PUT")
+	admin.HandleFunc("/suppliers/{id}/prices/{book_id}", deleteSupplierPriceHandler).Methods("DELETE")
+	admin.HandleFunc("/book/{id}/suppliers", listBookSuppliersHandler).Methods("GET")
+	admin.HandleFunc("/purchase-orders", listPurchaseOrdersHandler).Methods("GET")
+	admin.HandleFunc("/purchase-orders", withIdempotency(createPurchaseOrderHandler)).Methods("POST")
+	admin.HandleFunc("/purchase-orders/{id}", getPurchaseOrderHandler).Methods("GET")
+	admin.HandleFunc("/purchase-orders/{id}/receive", withIdempotency(receivePurchaseOrderHandler)).Methods("POST")
+	admin.HandleFunc("/purchase-orders/{id}/cancel", cancelPurchaseOrderHandler).Methods("POST")
+	admin.HandleFunc("/book/{id}/stock", getBookStockHandler).Methods("GET")
+	admin.HandleFunc("/admin/reports/open-orders", openOrdersHandler).Methods("GET")
+	admin.HandleFunc("/admin/reports/reorder", reorderSuggestionsHandler(cfg.ReorderLevel)).Methods("GET")
+	admin.HandleFunc("/sales", withIdempotency(createSaleHandler)).Methods("POST")
+	admin.HandleFunc("/sales/{id}", getSaleHandler).Methods("GET")
+	admin.HandleFunc("/sales/{id}/invoice.pdf", saleInvoiceHandler(cfg.Seller)).Methods("GET")
+	admin.HandleFunc("/sales/{id}/ship", shipSaleHandler).Methods("POST")
+	admin.HandleFunc("/sales/{id}/emails", withIdempotency(resendSaleEmailHandler)).Methods("POST")
+	admin.HandleFunc("/admin/reports/sales", withCSV(salesReportCSVHandler, salesReportHandler)).Methods("GET")
+	admin.HandleFunc("/gift-cards", withIdempotency(createGiftCardHandler)).Methods("POST")
+	admin.HandleFunc("/gift-cards/{code}", getGiftCardHandler).Methods("GET")
+	admin.HandleFunc("/gift-cards/{code}/void", voidGiftCardHandler).Methods("POST")
+	admin.HandleFunc("/admin/users/{id}/points", userPointsHandler).Methods("GET")
+	admin.HandleFunc("/webhooks", withIdempotency(createWebhookHandler)).Methods("POST")
+	admin.HandleFunc("/webhooks", listWebhooksHandler).Methods("GET")
+	admin.HandleFunc("/webhooks/{id}", deleteWebhookHandler).Methods("DELETE")
+	admin.HandleFunc("/webhooks/{id}/deliveries", listWebhookDeliveriesHandler).Methods("GET")
+	admin.HandleFunc("/admin/books/reprice", repriceBooksHandler).Methods("POST")
+	admin.HandleFunc("/admin/books/merge", mergeBooksHandler).Methods("POST")
+	admin.HandleFunc("/admin/analytics/searches", searchAnalyticsHandler).Methods("GET")
+	admin.HandleFunc("/admin/backup", createBackupHandler).Methods("POST")
+	admin.HandleFunc("/admin/backups", listBackupsHandler).Methods("GET")
+	admin.HandleFunc("/admin/restore", restoreHandler).Methods("POST")
+	admin.HandleFunc("/admin/jobs", listJobsHandler).Methods("GET")
+	admin.HandleFunc("/admin/jobs/{name}", getJobHandler).Methods("GET")
+	admin.HandleFunc("/admin/jobs/{name}/run", runJobHandler).Methods("POST")
+	admin.HandleFunc("/admin/users", createAdminUserHandler).Methods("POST")
+	admin.HandleFunc("/admin/notifications", withIdempotency(sendNotificationHandler)).Methods("POST")
+	admin.HandleFunc("/admin/notifications/failures", listNotificationFailuresHandler).Methods("GET")
+	admin.HandleFunc("/admin/notifications/preferences/{recipient}", getNotificationPreferencesHandler).Methods("GET")
+	admin.HandleFunc("/admin/notifications/preferences/{recipient}", updateNotificationPreferencesHandler).Methods("PUT")
+	admin.HandleFunc("/admin/push/subscriptions", subscribePushHandler(true)).Methods("POST")
+}
+
+// The v1 book routes, which also answer at the legacy unversioned paths.
+func registerBookRoutes(r *mux.Router) {
+	r.HandleFunc("/book", withIdempotency(createBookHandler)).Methods("POST")
+	r.HandleFunc("/book/{id}", withETag(getBookHandler)).Methods("GET")
+	r.HandleFunc("/book/slug/{slug}", withETag(getBookBySlugHandler)).Methods("GET")
+	r.HandleFunc("/book/{id}", updateBookHandler).Methods("PUT")
+	r.HandleFunc("/book/{id}", deleteBookHandler).Methods("DELETE")
+
+	r.HandleFunc("/books", withCSV(listBooksCSVHandler, withETag(getAllBooksHandler))).Methods("GET")
+	r.HandleFunc("/books/search", withCSV(searchBooksCSVHandler, searchBooksHandler)).Methods("GET")
+	r.HandleFunc("/books/suggest", suggestBooksHandler).Methods("GET")
+	r.HandleFunc("/books", deleteAllBooks).Methods("DELETE")
+}
+
+// Mark responses from unversioned paths as deprecated and point clients
+// at the same path under prefix.
+func deprecatedPathMiddleware(prefix string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			successor := prefix + r.URL.Path
+			if r.URL.RawQuery != "" {
+				successor += "?" + r.URL.RawQuery
+			}
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
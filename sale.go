@@ -0,0 +1,582 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Sale is copies of books sold from stock, at the books' prices in the
+// catalog's currency, with the tax on them itemized.
+type Sale struct {
+	Id int `json:"id" xml:"id"`
+	// InvoiceNumber numbers the tenant's sales in order, without gaps,
+	// for their invoices.
+	InvoiceNumber int `json:"invoice_number" xml:"invoice_number"`
+	// CustomerName and CustomerAddress are who the invoice is made out
+	// to, if anyone.
+	CustomerName    string `json:"customer_name,omitempty" xml:"customer_name,omitempty" validate:"max=255"`
+	CustomerAddress string `json:"customer_address,omitempty" xml:"customer_address,omitempty" validate:"max=1000"`
+	// CustomerEmail is where the order confirmation and shipping emails
+	// go, if anywhere.
+	CustomerEmail string `json:"customer_email,omitempty" xml:"customer_email,omitempty" validate:"omitempty,email,max=255"`
+	// Region is where the sale is taxed, tax_region unless it is given.
+	Region string     `json:"region,omitempty" xml:"region,omitempty" validate:"max=64"`
+	Lines  []SaleLine `json:"lines" xml:"lines>line" validate:"max=200,dive"`
+	// TaxIncluded is whether the books' prices included tax, which
+	// otherwise was added to them.
+	TaxIncluded bool `json:"tax_included" xml:"tax_included"`
+	// Subtotal is what the lines came to before tax, and Total with it.
+	Subtotal float64   `json:"subtotal" xml:"subtotal"`
+	Tax      float64   `json:"tax" xml:"tax"`
+	Taxes    []SaleTax `json:"taxes" xml:"taxes>tax"`
+	Total    float64   `json:"total" xml:"total"`
+	// UserId is the user the sale earns loyalty points for, if any, and
+	// who redeems PointsRedeemed of theirs for the PointsDiscount.
+	UserId         int     `json:"user_id,omitempty" xml:"user_id,omitempty"`
+	PointsRedeemed int     `json:"points_redeemed" xml:"points_redeemed" validate:"gte=0"`
+	PointsDiscount float64 `json:"points_discount" xml:"points_discount"`
+	PointsEarned   int     `json:"points_earned" xml:"points_earned"`
+	// GiftCardCode is the code of a gift card to pay with when making the
+	// sale; GiftCardAmount is what it paid, as much of the total after
+	// the points discount as its balance allowed, and AmountDue what is
+	// left to pay.
+	GiftCardCode   string    `json:"gift_card_code,omitempty" xml:"gift_card_code,omitempty" validate:"max=32"`
+	GiftCardAmount float64   `json:"gift_card_amount" xml:"gift_card_amount"`
+	AmountDue      float64   `json:"amount_due" xml:"amount_due"`
+	SoldAt         time.Time `json:"sold_at" xml:"sold_at"`
+	// ShippedAt is when the sale was shipped, if it has been, by Carrier
+	// under TrackingNumber.
+	ShippedAt      *time.Time `json:"shipped_at,omitempty" xml:"shipped_at,omitempty"`
+	Carrier        string     `json:"carrier,omitempty" xml:"carrier,omitempty"`
+	TrackingNumber string     `json:"tracking_number,omitempty" xml:"tracking_number,omitempty"`
+	// CreatedAt is SoldAt, and UpdatedAt ShippedAt once shipped or when
+	// the customer's details were erased.
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+}
+
+// SaleLine is how many copies of a book a sale is for and what they came
+// to. The title is kept as it was when sold; BookId is zero once the book
+// is deleted.
+type SaleLine struct {
+	Id        int     `json:"id" xml:"id"`
+	BookId    int     `json:"book_id,omitempty" xml:"book_id,omitempty" validate:"required"`
+	Title     string  `json:"title" xml:"title"`
+	Quantity  int     `json:"quantity" xml:"quantity" validate:"gt=0,lte=1000"`
+	UnitPrice float64 `json:"unit_price" xml:"unit_price"`
+	// Amount is what the line came to before tax, and Tax the tax on it
+	// under the rule named TaxName, if one applied.
+	Amount  float64 `json:"amount" xml:"amount"`
+	TaxName string  `json:"tax_name,omitempty" xml:"tax_name,omitempty"`
+	TaxRate float64 `json:"tax_rate" xml:"tax_rate"`
+	Tax     float64 `json:"tax" xml:"tax"`
+}
+
+// SaleTax is the tax charged on a sale under one rule, on the Taxable
+// amount of the lines it applied to.
+type SaleTax struct {
+	Name    string  `json:"name" xml:"name"`
+	Rate    float64 `json:"rate" xml:"rate"`
+	Taxable float64 `json:"taxable" xml:"taxable"`
+	Amount  float64 `json:"amount" xml:"amount"`
+}
+
+// SalesTotal is the copies sold and the revenue they made over a day, or
+// for a category.
+type SalesTotal struct {
+	Day      time.Time
+	Category string
+	Units    int
+	Revenue  float64
+}
+
+// BookSales is how many copies of a book were sold and what they made.
+type BookSales struct {
+	BookId  int     `json:"book_id,omitempty" xml:"book_id,omitempty"`
+	Title   string  `json:"title" xml:"title"`
+	Units   int     `json:"units" xml:"units"`
+	Revenue float64 `json:"revenue" xml:"revenue"`
+}
+
+// SalesGroup is the sales of one period, named like 2024-03-05, 2024-W10
+// or 2024-03, or of one category.
+type SalesGroup struct {
+	Name    string  `json:"name" xml:"name"`
+	Units   int     `json:"units" xml:"units"`
+	Revenue float64 `json:"revenue" xml:"revenue"`
+}
+
+// SalesReport is the sales made from one day to another, grouped by
+// period or category, with the best selling titles.
+type SalesReport struct {
+	Currency string  `json:"currency" xml:"currency"`
+	From     string  `json:"from" xml:"from"`
+	To       string  `json:"to" xml:"to"`
+	GroupBy  string  `json:"group_by" xml:"group_by"`
+	Units    int     `json:"units" xml:"units"`
+	Revenue  float64 `json:"revenue" xml:"revenue"`
+	// Groups has every period in the range, sold in or not, or the
+	// categories with sales, most revenue first. A book in several
+	// categories counts in each.
+	Groups    []SalesGroup `json:"groups" xml:"groups>group"`
+	TopTitles []BookSales  `json:"top_titles" xml:"top_titles>title"`
+}
+
+// SaleShipment is how a sale was shipped, both optional.
+type SaleShipment struct {
+	Carrier        string `json:"carrier" xml:"carrier" validate:"max=64"`
+	TrackingNumber string `json:"tracking_number" xml:"tracking_number" validate:"max=64"`
+}
+
+type SaleResponse struct {
+	Status  string `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
+	Data    Sale   `json:"data" xml:"data"`
+}
+
+type SalesReportResponse struct {
+	Status  string      `json:"status" xml:"status"`
+	Message string      `json:"message" xml:"message"`
+	Data    SalesReport `json:"data" xml:"data"`
+}
+
+// What a sales report can be grouped by.
+const (
+	SalesByDay      = "day"
+	SalesByWeek     = "week"
+	SalesByMonth    = "month"
+	SalesByCategory = "category"
+)
+
+const (
+	defaultTopTitles = 10
+	maxTopTitles     = 100
+	// The longest range a sales report covers, so grouping by day stays
+	// a sensible size.
+	maxSalesReportDays = 3660
+)
+
+// The name sales of books in no category are grouped under.
+const uncategorized = "uncategorized"
+
+var (
+	errOutOfStock  = errors.New("not enough copies in stock")
+	errSaleShipped = errors.New("sale already shipped")
+)
+
+// Sell copies of books from stock at their catalog prices.
+func createSaleHandler(w http.ResponseWriter, r *http.Request) {
+	var sale Sale
+	if err := decodeRequest(r, &sale); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	sale.CustomerName = normalizeText(sale.CustomerName)
+	sale.CustomerAddress = normalizeText(sale.CustomerAddress)
+	errs := validateRequest(sale)
+	if len(sale.Lines) == 0 {
+		errs = append(errs, newFieldError("lines", "required", "is required"))
+	}
+	if sale.PointsRedeemed > 0 && sale.UserId == 0 {
+		errs = append(errs, newFieldError("user_id", "required", "is required to redeem points"))
+	}
+	if errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	sale.Id, sale.InvoiceNumber, sale.TaxIncluded = 0, 0, taxes.included
+	sale.ShippedAt, sale.Carrier, sale.TrackingNumber = nil, "", ""
+	giftCardCode, points := sale.GiftCardCode, sale.PointsRedeemed
+	sale.GiftCardCode, sale.GiftCardAmount = "", 0
+	sale.PointsRedeemed, sale.PointsDiscount, sale.PointsEarned = 0, 0, 0
+	if sale.Region = strings.TrimSpace(sale.Region); sale.Region == "" {
+		sale.Region = taxes.region
+	}
+	sale.SoldAt = time.Now().UTC().Truncate(time.Microsecond)
+	sale.CreatedAt, sale.UpdatedAt = sale.SoldAt, sale.SoldAt
+
+	err := store.WithTx(r.Context(), func(tx BookStore) error {
+		for i := range sale.Lines {
+			line := &sale.Lines[i]
+			book, err := tx.GetBookFields(r.Context(), line.BookId, []string{"id", "title", "price"})
+			if err != nil {
+				return err
+			}
+			subjects, err := tx.GetBookSubjects(r.Context(), line.BookId)
+			if err != nil {
+				return err
+			}
+			stock, err := tx.GetBookStock(r.Context(), line.BookId)
+			if err != nil {
+				return err
+			}
+			// Earlier lines of the sale for the same book have come out
+			// of stock already.
+			if stock < line.Quantity {
+				return fmt.Errorf("%w: book %d", errOutOfStock, line.BookId)
+			}
+			if err := tx.AddBookStock(r.Context(), line.BookId, -line.Quantity); err != nil {
+				return err
+			}
+			*line = SaleLine{BookId: line.BookId, Title: book.Title, Quantity: line.Quantity, UnitPrice: book.Price}
+			line.Amount = roundCents(float64(line.Quantity) * book.Price)
+			if rule, ok := taxes.rule(sale.Region, subjects.Categories); ok {
+				taxes.apply(line, rule)
+			}
+		}
+		sale.totalTaxes()
+		return checkout(r.Context(), tx, &sale, points, giftCardCode)
+	})
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	case errors.Is(err, errOutOfStock):
+		writeProblem(w, r, codeOutOfStock, "Not enough copies of a book are in stock")
+		return
+	case errors.Is(err, errUserNotFound):
+		writeProblem(w, r, codeUserNotFound, "User not found")
+		return
+	case errors.Is(err, ErrNotEnoughPoints):
+		writeProblem(w, r, codeNotEnoughPoints, "The user doesn't have that many points")
+		return
+	case errors.Is(err, errGiftCardNotFound):
+		writeProblem(w, r, codeGiftCardNotFound, "No gift card has this code")
+		return
+	case errors.Is(err, errGiftCardVoided):
+		writeProblem(w, r, codeGiftCardVoided, "The gift card was voided")
+		return
+	case errors.Is(err, errGiftCardEmpty):
+		writeProblem(w, r, codeGiftCardEmpty, "The gift card has no balance left")
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error creating sale")
+		log.Printf("Sale creation error: %v", err)
+		return
+	}
+	for _, line := range sale.Lines {
+		activity.add(r.Context(), line.BookId, ActivitySale, line.Quantity)
+	}
+	if sale.CustomerEmail != "" {
+		_, err := emailSale(r.Context(), sale, NotificationOrderConfirmation, sale.CustomerEmail)
+		// Without email configured the customer has the invoice instead.
+		if err != nil && !errors.Is(err, errChannelUnavailable) {
+			log.Printf("Order confirmation error: %v", err)
+		}
+	}
+
+	writeResponse(w, r, http.StatusCreated, SaleResponse{
+		Status:  "success",
+		Message: "Sale created successfully",
+		Data:    sale,
+	})
+}
+
+// Store a sale whose totals are worked out, taking points off them and
+// then the gift card with giftCardCode, if given, and record what the
+// sale earned and redeemed.
+func checkout(ctx context.Context, tx BookStore, sale *Sale, points int, giftCardCode string) error {
+	if sale.UserId != 0 {
+		if _, err := tx.GetUser(ctx, sale.UserId); errors.Is(err, ErrNotFound) {
+			return errUserNotFound
+		} else if err != nil {
+			return err
+		}
+	}
+	if points > 0 {
+		loyalty.redeem(sale, points)
+	}
+
+	var card GiftCard
+	if giftCardCode != "" {
+		var err error
+		if card, err = spendableGiftCard(ctx, tx, giftCardCode); err != nil {
+			return err
+		}
+		sale.GiftCardAmount = min(card.Balance, roundCents(sale.Total-sale.PointsDiscount))
+	}
+	sale.AmountDue = roundCents(sale.Total - sale.PointsDiscount - sale.GiftCardAmount)
+	if sale.UserId != 0 {
+		sale.PointsEarned = loyalty.earned(sale.Total - sale.PointsDiscount)
+	}
+
+	if err := tx.CreateSale(ctx, sale); err != nil {
+		return err
+	}
+	if sale.PointsRedeemed > 0 {
+		if err := tx.RedeemLoyaltyPoints(ctx, sale.UserId, sale.PointsRedeemed, sale.Id, sale.SoldAt); err != nil {
+			return err
+		}
+	}
+	if sale.PointsEarned > 0 {
+		entry := LoyaltyEntry{UserId: sale.UserId, Kind: PointsEarned, Points: sale.PointsEarned, Remaining: sale.PointsEarned, SaleId: sale.Id, ExpiresAt: loyalty.expiresAt(sale.SoldAt), CreatedAt: sale.SoldAt}
+		if err := tx.AddLoyaltyPoints(ctx, &entry); err != nil {
+			return err
+		}
+	}
+	if sale.GiftCardAmount > 0 {
+		return tx.RedeemGiftCard(ctx, GiftCardRedemption{GiftCardId: card.Id, SaleId: sale.Id, Amount: sale.GiftCardAmount, RedeemedAt: sale.SoldAt})
+	}
+	return nil
+}
+
+func getSaleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid sale ID")
+		return
+	}
+
+	sale, err := store.GetSale(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeSaleNotFound, "Sale not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching sale")
+		log.Printf("Sale query error: %v", err)
+		return
+	}
+	sale.totalTaxes()
+
+	writeResponse(w, r, http.StatusOK, SaleResponse{
+		Status:  "success",
+		Message: "Sale retrieved successfully",
+		Data:    sale,
+	})
+}
+
+// Record a sale as shipped and email its customer that it is on its way.
+func shipSaleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid sale ID")
+		return
+	}
+
+	var shipment SaleShipment
+	if err := decodeRequest(r, &shipment); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	shipment.Carrier = normalizeText(shipment.Carrier)
+	shipment.TrackingNumber = strings.TrimSpace(shipment.TrackingNumber)
+	if errs := validateRequest(shipment); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	var sale Sale
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		var err error
+		if sale, err = tx.GetSale(r.Context(), id); err != nil {
+			return err
+		}
+		if sale.ShippedAt != nil {
+			return errSaleShipped
+		}
+		shippedAt := time.Now().UTC().Truncate(time.Microsecond)
+		sale.ShippedAt, sale.Carrier, sale.TrackingNumber = &shippedAt, shipment.Carrier, shipment.TrackingNumber
+		sale.UpdatedAt = shippedAt
+		return tx.ShipSale(r.Context(), id, shipment.Carrier, shipment.TrackingNumber, shippedAt)
+	})
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeSaleNotFound, "Sale not found")
+		return
+	case errors.Is(err, errSaleShipped):
+		writeProblem(w, r, codeSaleShipped, "The sale was already shipped")
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error shipping sale")
+		log.Printf("Sale shipping error: %v", err)
+		return
+	}
+	sale.totalTaxes()
+	if sale.CustomerEmail != "" {
+		_, err := emailSale(r.Context(), sale, NotificationOrderShipped, sale.CustomerEmail)
+		if err != nil && !errors.Is(err, errChannelUnavailable) {
+			log.Printf("Shipping notification error: %v", err)
+		}
+	}
+
+	writeResponse(w, r, http.StatusOK, SaleResponse{
+		Status:  "success",
+		Message: "Sale shipped successfully",
+		Data:    sale,
+	})
+}
+
+// Report the sales made from ?from= to ?to=, both days included and the
+// last 30 days by default, grouped by ?group_by= day, week, month or
+// category, with the ?limit= best selling titles. ?format=csv exports the
+// groups instead.
+func salesReportHandler(w http.ResponseWriter, r *http.Request) {
+	report, ok := salesReport(w, r)
+	if !ok {
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, SalesReportResponse{
+		Status:  "success",
+		Message: "Sales report retrieved successfully",
+		Data:    report,
+	})
+}
+
+func salesReportCSVHandler(w http.ResponseWriter, r *http.Request) {
+	report, ok := salesReport(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Add("Vary", "Accept")
+	w.Header().Set("Content-Type", mediaTypeCSV+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="sales-%s-%s.csv"`, report.From, report.To))
+	w.WriteHeader(http.StatusOK)
+	out := csv.NewWriter(w)
+	out.Write([]string{report.GroupBy, "units", "revenue"})
+	for _, g := range report.Groups {
+		out.Write([]string{g.Name, strconv.Itoa(g.Units), strconv.FormatFloat(g.Revenue, 'f', 2, 64)})
+	}
+	out.Flush()
+}
+
+// Work out the sales report the request asks for, or write the problem
+// with it and return false.
+func salesReport(w http.ResponseWriter, r *http.Request) (SalesReport, bool) {
+	query := r.URL.Query()
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	if v := query.Get("to"); v != "" {
+		var err error
+		if to, err = time.Parse(time.DateOnly, v); err != nil {
+			writeProblem(w, r, codeInvalidParameter, "to must be a date like 2006-01-02")
+			return SalesReport{}, false
+		}
+	}
+	from := to.AddDate(0, 0, -29)
+	if v := query.Get("from"); v != "" {
+		var err error
+		if from, err = time.Parse(time.DateOnly, v); err != nil {
+			writeProblem(w, r, codeInvalidParameter, "from must be a date like 2006-01-02")
+			return SalesReport{}, false
+		}
+	}
+	if to.Before(from) || to.Sub(from) > maxSalesReportDays*24*time.Hour {
+		writeProblem(w, r, codeInvalidParameter, "to must not be before from or more than 3660 days after it")
+		return SalesReport{}, false
+	}
+	groupBy := query.Get("group_by")
+	switch groupBy {
+	case "":
+		groupBy = SalesByDay
+	case SalesByDay, SalesByWeek, SalesByMonth, SalesByCategory:
+	default:
+		writeProblem(w, r, codeInvalidParameter, "group_by must be day, week, month or category")
+		return SalesReport{}, false
+	}
+	limit, ok := intParameter(r, "limit", defaultTopTitles, maxTopTitles)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(maxTopTitles))
+		return SalesReport{}, false
+	}
+
+	// The store takes the day after the last, so every sale on it counts.
+	end := to.AddDate(0, 0, 1)
+	daily, err := store.SalesByDay(r.Context(), from, end)
+	var categories []SalesTotal
+	if err == nil && groupBy == SalesByCategory {
+		categories, err = store.SalesByCategory(r.Context(), from, end)
+	}
+	var top []BookSales
+	if err == nil {
+		top, err = store.TopSellingBooks(r.Context(), from, end, limit)
+	}
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching sales")
+		log.Printf("Sales query error: %v", err)
+		return SalesReport{}, false
+	}
+
+	report := SalesReport{
+		Currency:  currencies.base,
+		From:      from.Format(time.DateOnly),
+		To:        to.Format(time.DateOnly),
+		GroupBy:   groupBy,
+		TopTitles: top,
+	}
+	// The totals come from the days, as a book in several categories
+	// counts in each of them.
+	for _, t := range daily {
+		report.Units += t.Units
+		report.Revenue = roundCents(report.Revenue + t.Revenue)
+	}
+	if groupBy == SalesByCategory {
+		report.Groups = categorySales(categories)
+	} else {
+		report.Groups = periodSales(groupBy, from, to, daily)
+	}
+	return report, true
+}
+
+// The name of the period of a kind the day falls in: the day itself, its
+// ISO week or its month.
+func salesPeriod(groupBy string, day time.Time) string {
+	switch groupBy {
+	case SalesByWeek:
+		year, week := day.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week)
+	case SalesByMonth:
+		return day.Format("2006-01")
+	}
+	return day.Format(time.DateOnly)
+}
+
+// Roll the daily totals up into every period from one day to another, in
+// order, including those without sales.
+func periodSales(groupBy string, from, to time.Time, totals []SalesTotal) []SalesGroup {
+	groups := []SalesGroup{}
+	index := map[string]int{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		name := salesPeriod(groupBy, day)
+		if _, ok := index[name]; !ok {
+			index[name] = len(groups)
+			groups = append(groups, SalesGroup{Name: name})
+		}
+	}
+	for _, t := range totals {
+		if i, ok := index[salesPeriod(groupBy, t.Day)]; ok {
+			groups[i].Units += t.Units
+			groups[i].Revenue = roundCents(groups[i].Revenue + t.Revenue)
+		}
+	}
+	return groups
+}
+
+// The categories with sales, most revenue first.
+func categorySales(totals []SalesTotal) []SalesGroup {
+	groups := make([]SalesGroup, 0, len(totals))
+	for _, t := range totals {
+		name := t.Category
+		if name == "" {
+			name = uncategorized
+		}
+		groups = append(groups, SalesGroup{Name: name, Units: t.Units, Revenue: roundCents(t.Revenue)})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Revenue != groups[j].Revenue {
+			return groups[i].Revenue > groups[j].Revenue
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// The kinds of notification sent about sales.
+var saleEmailKinds = []string{NotificationOrderConfirmation, NotificationOrderShipped}
+
+// SaleEmailRequest asks for a sale's email to be sent again, to the
+// customer or, say, to an address they corrected.
+type SaleEmailRequest struct {
+	Kind string `json:"kind" xml:"kind" validate:"required,sale_email_kind"`
+	// Recipient defaults to the sale's customer_email.
+	Recipient string `json:"recipient" xml:"recipient" validate:"omitempty,email,max=255"`
+}
+
+// Queue the email of kind, order_confirmation or order_shipped, about the
+// sale to recipient. Like any notification it isn't sent if the
+// recipient turned its kind off, and it reports whether it was queued.
+func emailSale(ctx context.Context, sale Sale, kind, recipient string) (bool, error) {
+	data, err := saleEmailData(ctx, sale)
+	if err != nil {
+		return false, err
+	}
+	return notify(ctx, "email", recipient, kind, data)
+}
+
+// The template data for the emails about a sale: its invoice number as
+// the order_id, the items bought, with their authors if the books are
+// still in the catalog, the total and how it was shipped. Prices are
+// what the customer paid, with tax if it was included.
+func saleEmailData(ctx context.Context, sale Sale) (map[string]any, error) {
+	money := func(amount float64) string { return fmt.Sprintf("%.2f %s", amount, currencies.base) }
+
+	items := make([]map[string]any, len(sale.Lines))
+	for i, line := range sale.Lines {
+		var author string
+		if line.BookId != 0 {
+			book, err := store.GetBookFields(ctx, line.BookId, []string{"id", "author"})
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return nil, err
+			}
+			author = book.Author
+		}
+		title, price := line.Title, line.Amount
+		if line.Quantity > 1 {
+			title = fmt.Sprintf("%d × %s", line.Quantity, title)
+		}
+		if sale.TaxIncluded {
+			price = roundCents(price + line.Tax)
+		}
+		items[i] = map[string]any{"title": title, "author": author, "price": money(price)}
+	}
+
+	total := money(sale.Total)
+	if sale.Tax > 0 {
+		total += " (including " + money(sale.Tax) + " tax)"
+	}
+	return map[string]any{
+		"order_id":        invoiceNumber(sale.InvoiceNumber),
+		"items":           items,
+		"total":           total,
+		"carrier":         sale.Carrier,
+		"tracking_number": sale.TrackingNumber,
+	}, nil
+}
+
+// Send a sale's order confirmation or shipping email again, for support
+// staff to use when a customer didn't get it.
+func resendSaleEmailHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid sale ID")
+		return
+	}
+
+	var req SaleEmailRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	if errs := validateRequest(req); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	sale, err := store.GetSale(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeSaleNotFound, "Sale not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching sale")
+		log.Printf("Sale query error: %v", err)
+		return
+	}
+	sale.totalTaxes()
+	if req.Recipient == "" {
+		req.Recipient = sale.CustomerEmail
+	}
+	if req.Recipient == "" {
+		writeValidationErrors(w, r, []FieldError{newFieldError("recipient", "required", "is required, as the sale has no customer email")})
+		return
+	}
+	if req.Kind == NotificationOrderShipped && sale.ShippedAt == nil {
+		writeProblem(w, r, codeSaleNotShipped, "The sale hasn't been shipped yet")
+		return
+	}
+
+	queued, err := emailSale(r.Context(), sale, req.Kind, req.Recipient)
+	switch {
+	case errors.Is(err, errChannelUnavailable):
+		writeProblem(w, r, codeNotificationsUnavailable, "The notification's channel isn't configured")
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error queuing notification")
+		log.Printf("Sale email error: %v", err)
+		return
+	}
+
+	message := "Notification queued"
+	if !queued {
+		message = "The recipient has turned this kind of notification off"
+	}
+	writeResponse(w, r, http.StatusAccepted, NotificationResponse{
+		Status:  "success",
+		Message: message,
+		Data:    NotificationResult{Queued: queued},
+	})
+}
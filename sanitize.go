@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Elements whose content is code or markup rather than text, dropped
+// with them.
+var unsafeElements = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true,
+	"template": true, "noscript": true, "noembed": true, "noframes": true,
+	"textarea": true, "title": true, "xmp": true, "plaintext": true, "svg": true,
+	"math": true,
+}
+
+// Elements on lines of their own, kept as line breaks.
+var blockElements = map[string]bool{"p": true, "div": true, "li": true, "blockquote": true}
+
+// The plain text of s with any HTML left out, for text readers write:
+// tags and comments are removed, and so are scripts, styles and other
+// elements whose content isn't text. Line and paragraph breaks become
+// newlines and entities the characters they stand for, so "a &lt; b
+// <i>c</i>" becomes "a < b c" and "I <3 this" stays as typed. What's left
+// is text, not HTML: whatever renders it as HTML must escape it, as
+// "<<b>script>" comes out "<script>".
+func sanitizeText(s string) string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(s))
+	// The unsafe element being skipped, if any, and how deep inside
+	// others of its kind.
+	var skipping string
+	depth := 0
+	// Start a new line unless one was just started.
+	endLine := func() {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return b.String()
+		case html.TextToken:
+			if skipping == "" {
+				b.Write(z.Text())
+			}
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			switch {
+			case skipping == tag:
+				depth++
+			case skipping == "" && unsafeElements[tag]:
+				skipping, depth = tag, 1
+			case skipping == "" && tag == "br":
+				b.WriteByte('\n')
+			case skipping == "" && blockElements[tag]:
+				endLine()
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			switch {
+			case skipping == tag:
+				if depth--; depth == 0 {
+					skipping = ""
+				}
+			case skipping == "" && blockElements[tag]:
+				endLine()
+			}
+		case html.SelfClosingTagToken:
+			if name, _ := z.TagName(); skipping == "" && string(name) == "br" {
+				b.WriteByte('\n')
+			}
+		}
+	}
+}
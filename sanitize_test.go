@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestSanitizeText(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain text", "A fine read, 5 stars", "A fine read, 5 stars"},
+		{"heart", "I <3 this", "I <3 this"},
+		{"entities", "Tom &amp; Jerry &lt;3", "Tom & Jerry <3"},
+		{"tags", "<b>Bold</b> and <i>italic</i>", "Bold and italic"},
+		{"comment", "before<!-- hidden -->after", "beforeafter"},
+		{"line breaks", "one<br>two<br/>three", "one\ntwo\nthree"},
+		{"paragraphs", "<p>one</p><p>two</p>", "one\ntwo\n"},
+		{"script", "<script>alert(1)</script>hi", "hi"},
+		{"nested unsafe", "<svg><svg></svg><script>alert(1)</script></svg>after", "after"},
+		{"event handler", `<img src=x onerror="alert(1)">`, ""},
+		{"less than in text", "a < b <i>c</i>", "a < b c"},
+		{"escaped markup", "&lt;script&gt; <i>x</i>", "<script> x"},
+		{"tag stitched around another", "<<b>img src=x onerror=alert(1)>", "<img src=x onerror=alert(1)>"},
+		{"script stitched around tags", "<<b>script>alert(1)<</b>/script>", "<script>alert(1)</script>"},
+		{"plaintext", "ok<plaintext><script>alert(1)</script>", "ok"},
+		{"xmp", "<xmp><script>alert(1)</script></xmp>after", "after"},
+		{"noembed", "<noembed><script>alert(1)</script></noembed>after", "after"},
+		{"textarea", "<textarea><script>alert(1)</script></textarea>after", "after"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sanitizeText(tt.in)
+			if got != tt.want {
+				t.Errorf("sanitizeText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
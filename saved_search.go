@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// How many searches a reader can save.
+	maxSavedSearches = 25
+	// How many of the new books a saved search matched its notification
+	// lists; it says how many more there are.
+	maxSavedSearchMatches = 10
+)
+
+const NotificationSavedSearchMatch = "saved_search_match"
+
+var errSavedSearchLimit = errors.New("saved search limit reached")
+
+// SavedSearch is a search a reader saved to be told of new books matching
+// it, such as any new science fiction under 10: books whose title or
+// author match Query, as GET /books/search finds them, that are in
+// Category, in Format and cost at most MaxPrice. Criteria left out match
+// every book.
+type SavedSearch struct {
+	Id       int      `json:"id" xml:"id"`
+	UserId   int      `json:"-" xml:"-"`
+	Name     string   `json:"name" xml:"name" validate:"required,max=100"`
+	Query    string   `json:"query,omitempty" xml:"query,omitempty" validate:"max=200"`
+	Category string   `json:"category,omitempty" xml:"category,omitempty" validate:"max=100"`
+	Format   string   `json:"format,omitempty" xml:"format,omitempty" validate:"omitempty,book_format"`
+	MaxPrice *float64 `json:"max_price,omitempty" xml:"max_price,omitempty" validate:"omitempty,gte=0,lte=99999999.99"`
+	// SeenBookId is the newest book checked against the search; only
+	// books added after it are new.
+	SeenBookId int       `json:"-" xml:"-"`
+	CreatedAt  time.Time `json:"created_at" xml:"created_at"`
+	// NotifiedAt is when the reader was last told of new matches.
+	NotifiedAt *time.Time `json:"notified_at,omitempty" xml:"notified_at,omitempty"`
+	// UpdatedAt is NotifiedAt once the reader has been told of matches.
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+}
+
+func (s SavedSearch) timestamps() (created, updated time.Time) {
+	return s.CreatedAt, s.UpdatedAt
+}
+
+type SavedSearchResponse struct {
+	Status  string      `json:"status" xml:"status"`
+	Message string      `json:"message" xml:"message"`
+	Data    SavedSearch `json:"data" xml:"data"`
+}
+
+type SavedSearchesResponse struct {
+	Status  string        `json:"status" xml:"status"`
+	Message string        `json:"message" xml:"message"`
+	Data    []SavedSearch `json:"data" xml:"data>search"`
+}
+
+// Clean up the search as entered.
+func (s *SavedSearch) normalize() {
+	s.Name = normalizeText(s.Name)
+	s.Query = normalizeText(s.Query)
+	s.Category = normalizeText(s.Category)
+	if s.MaxPrice != nil {
+		*s.MaxPrice = roundCents(*s.MaxPrice)
+	}
+}
+
+// Whether book, in categories, matches the search.
+func (s SavedSearch) matches(book Book, categories []string) bool {
+	if s.Format != "" && book.Format != s.Format {
+		return false
+	}
+	if s.MaxPrice != nil && book.Price > *s.MaxPrice {
+		return false
+	}
+	if s.Category != "" {
+		inCategory := false
+		for _, category := range categories {
+			inCategory = inCategory || strings.EqualFold(category, s.Category)
+		}
+		if !inCategory {
+			return false
+		}
+	}
+	if s.Query != "" {
+		query := strings.ToLower(s.Query)
+		if _, ok := rankBook(book, query, wordsOf(query)); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// The id of the book added last, or 0 if there are none.
+func newestBookId(ctx context.Context, tx BookStore) (int, error) {
+	newest := 0
+	err := tx.StreamBooks(ctx, BookQuery{Fields: []string{"id"}}, func(book Book) error {
+		newest = max(newest, book.Id)
+		return nil
+	})
+	return newest, err
+}
+
+// Tell readers of the books added since the last run matching their saved
+// searches, one notification per search; the saved_search_alerts job.
+func alertSavedSearches(ctx context.Context) error {
+	searches, err := store.ListAllSavedSearches(ctx)
+	if err != nil || len(searches) == 0 {
+		return err
+	}
+	books, err := listBooks(ctx, BookQuery{})
+	if err != nil {
+		return err
+	}
+	categories, err := store.BookCategories(ctx)
+	if err != nil {
+		return err
+	}
+	newest := 0
+	for _, book := range books {
+		newest = max(newest, book.Id)
+	}
+
+	users := map[int]User{}
+	for _, search := range searches {
+		if search.SeenBookId >= newest {
+			continue
+		}
+		var matched []Book
+		for _, book := range books {
+			if book.Id > search.SeenBookId && search.matches(book, categories[book.Id]) {
+				matched = append(matched, book)
+			}
+		}
+		if len(matched) == 0 {
+			// A search deleted since the job started needn't be recorded.
+			if err := store.SetSavedSearchSeen(ctx, search.Id, newest, nil); err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+			continue
+		}
+
+		user, ok := users[search.UserId]
+		if !ok {
+			if user, err = store.GetUser(ctx, search.UserId); err != nil {
+				return err
+			}
+			users[search.UserId] = user
+		}
+		_, err = notify(ctx, "", user.Email, NotificationSavedSearchMatch, savedSearchMatchData(search, matched))
+		switch {
+		case errors.Is(err, errChannelUnavailable), errors.Is(err, errNoPhone), errors.Is(err, errNoPushSubscription):
+			// Not something another try would fix, so the books count as
+			// told of.
+			log.Printf("Saved search match for user %d not sent: %v", search.UserId, err)
+		case err != nil:
+			return err
+		}
+
+		notifiedAt := time.Now().UTC().Truncate(time.Microsecond)
+		if err := store.SetSavedSearchSeen(ctx, search.Id, newest, &notifiedAt); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// The template data for a saved search's new matches: the search's name,
+// how many books matched, the first of them and how many more there are.
+func savedSearchMatchData(search SavedSearch, matched []Book) map[string]any {
+	books := make([]map[string]any, 0, maxSavedSearchMatches)
+	for _, book := range matched[:min(len(matched), maxSavedSearchMatches)] {
+		books = append(books, map[string]any{
+			"title":  book.Title,
+			"author": book.Author,
+			"price":  fmt.Sprintf("%.2f", book.Price),
+		})
+	}
+	return map[string]any{
+		"name":     search.Name,
+		"count":    len(matched),
+		"books":    books,
+		"more":     len(matched) - len(books),
+		"currency": currencies.base,
+	}
+}
+
+// List the user's saved searches, oldest first.
+func listSavedSearchesHandler(w http.ResponseWriter, r *http.Request) {
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+	searches, err := store.ListSavedSearches(r.Context(), currentUser(r).Id, after)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching saved searches")
+		log.Printf("Saved search query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, SavedSearchesResponse{
+		Status:  "success",
+		Message: "Saved searches retrieved successfully",
+		Data:    searches,
+	})
+}
+
+// Save a search for the user. Only books added from now on are news to
+// it.
+func createSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	var search SavedSearch
+	if err := decodeRequest(r, &search); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	search.normalize()
+	if errs := validateRequest(search); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	search = SavedSearch{
+		UserId:    currentUser(r).Id,
+		Name:      search.Name,
+		Query:     search.Query,
+		Category:  search.Category,
+		Format:    search.Format,
+		MaxPrice:  search.MaxPrice,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	search.UpdatedAt = search.CreatedAt
+
+	err := store.WithTx(r.Context(), func(tx BookStore) error {
+		saved, err := tx.ListSavedSearches(r.Context(), search.UserId, ChangedAfter{})
+		if err != nil {
+			return err
+		}
+		if len(saved) >= maxSavedSearches {
+			return errSavedSearchLimit
+		}
+		if search.SeenBookId, err = newestBookId(r.Context(), tx); err != nil {
+			return err
+		}
+		return tx.CreateSavedSearch(r.Context(), &search)
+	})
+	if errors.Is(err, errSavedSearchLimit) {
+		writeProblem(w, r, codeSavedSearchLimit, "At most "+strconv.Itoa(maxSavedSearches)+" searches can be saved")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error saving search")
+		log.Printf("Saved search creation error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, SavedSearchResponse{
+		Status:  "success",
+		Message: "Search saved successfully",
+		Data:    search,
+	})
+}
+
+func deleteSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid saved search ID")
+		return
+	}
+
+	err = store.DeleteSavedSearch(r.Context(), currentUser(r).Id, id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeSavedSearchNotFound, "Saved search not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error deleting saved search")
+		log.Printf("Saved search deletion error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Saved search deleted successfully",
+	})
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Search the catalog for query.Search, for GET /books/search, GraphQL
+// searchBooks and gRPC SearchBooks. Without fuzzy it is listBooks: the
+// books whose title or author contains the search, by id. With fuzzy,
+// books whose every word of the search is within a typo or two of a word
+// of their title or author match too, so "Tolkein" finds Tolkien, and all
+// of them are ranked by relevance, those containing the search first.
+// Advanced searches, with query.Filter parsed from the search, find the
+// books the filter matches, by id, fuzzy or not.
+func searchBooks(ctx context.Context, query BookQuery, fuzzy bool) ([]Book, error) {
+	if query.Filter != nil {
+		query.Search = ""
+		return listBooks(ctx, query)
+	}
+	if !fuzzy {
+		return listBooks(ctx, query)
+	}
+
+	// Matching needs the title and author, so whole books are loaded,
+	// through the cache when no formats or times are picked.
+	candidates, err := listBooks(ctx, BookQuery{Formats: query.Formats, CreatedAfter: query.CreatedAfter, UpdatedAfter: query.UpdatedAfter, ExcludeArchived: query.ExcludeArchived})
+	if err != nil {
+		return nil, err
+	}
+
+	type hit struct {
+		book      Book
+		relevance searchRelevance
+	}
+	search := strings.ToLower(query.Search)
+	words := wordsOf(search)
+	var hits []hit
+	for _, book := range candidates {
+		if relevance, ok := rankBook(book, search, words); ok {
+			hits = append(hits, hit{book, relevance})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].relevance.before(hits[j].relevance)
+	})
+
+	books := make([]Book, len(hits))
+	for i, hit := range hits {
+		books[i] = hit.book.only(query.Fields)
+	}
+	return books, nil
+}
+
+// The ?fuzzy= parameter of a search, true unless it is false, and whether
+// it is valid.
+func fuzzyParameter(r *http.Request) (bool, bool) {
+	if !r.URL.Query().Has("fuzzy") {
+		return true, true
+	}
+	fuzzy, err := strconv.ParseBool(r.URL.Query().Get("fuzzy"))
+	return fuzzy, err == nil
+}
+
+// searchRelevance orders search hits: by tier, then for fuzzy hits by how
+// many typos they needed.
+type searchRelevance struct {
+	tier  int
+	typos int
+}
+
+// The tiers of search hits, best first.
+const (
+	tierTitleEquals = iota
+	tierTitlePrefix
+	tierTitleContains
+	tierAuthorContains
+	tierFuzzy
+)
+
+func (a searchRelevance) before(b searchRelevance) bool {
+	if a.tier != b.tier {
+		return a.tier < b.tier
+	}
+	return a.typos < b.typos
+}
+
+// How relevant book is to search, lower-cased, and its words, and whether
+// it matches at all.
+func rankBook(book Book, search string, words []string) (searchRelevance, bool) {
+	title := strings.ToLower(book.Title)
+	switch {
+	case title == search:
+		return searchRelevance{tier: tierTitleEquals}, true
+	case strings.HasPrefix(title, search):
+		return searchRelevance{tier: tierTitlePrefix}, true
+	case strings.Contains(title, search):
+		return searchRelevance{tier: tierTitleContains}, true
+	case strings.Contains(strings.ToLower(book.Author), search):
+		return searchRelevance{tier: tierAuthorContains}, true
+	}
+	if len(words) == 0 {
+		return searchRelevance{}, false
+	}
+
+	bookWords := wordsOf(title + " " + strings.ToLower(book.Author))
+	typos := 0
+	for _, word := range words {
+		allowed := allowedTypos(word)
+		best := allowed + 1
+		for _, bookWord := range bookWords {
+			if d := editDistance(word, bookWord, allowed); d < best {
+				best = d
+			}
+		}
+		if best > allowed {
+			return searchRelevance{}, false
+		}
+		typos += best
+	}
+	return searchRelevance{tier: tierFuzzy, typos: typos}, true
+}
+
+// The words of s, split at anything but letters and digits.
+func wordsOf(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// How many typos a search word may have and still match: none for short
+// words, which would otherwise match too much, one for most and two for
+// long ones.
+func allowedTypos(word string) int {
+	switch n := len([]rune(word)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// The edit distance between a and b: the fewest insertions, deletions,
+// substitutions and swaps of neighbouring letters turning one into the
+// other (the optimal string alignment distance), so a swap, the commonest
+// typo, counts once. Distances over limit are reported as limit+1.
+func editDistance(a, b string, limit int) int {
+	s, t := []rune(a), []rune(b)
+	if d := len(s) - len(t); d > limit || -d > limit {
+		return limit + 1
+	}
+
+	// Three rows of the table: two back, the previous and the current.
+	prev2 := make([]int, len(t)+1)
+	prev := make([]int, len(t)+1)
+	cur := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return min(prev[len(t)], limit+1)
+}
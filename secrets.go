@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SecretProvider fetches secrets by name from a secrets manager. The names
+// are those of the settings they stand in for: "database_password" (of
+// database_url and its replicas), "admin_token", "smtp_password",
+// "sms_auth_token", "vapid_private_key" and "pii_key" (of pii.key).
+type SecretProvider interface {
+	FetchSecrets(ctx context.Context) (map[string]string, error)
+}
+
+// Open the secrets manager selected by the config, or return nil if none
+// is.
+func openSecretProvider(cfg SecretsConfig) (SecretProvider, error) {
+	switch cfg.Provider {
+	case "", "none":
+		return nil, nil
+	case "vault":
+		if cfg.VaultAddr == "" || cfg.VaultToken == "" || cfg.VaultPath == "" {
+			return nil, errors.New("secrets provider vault needs secrets.vault_addr, secrets.vault_token and secrets.vault_path")
+		}
+		return vaultSecrets{addr: cfg.VaultAddr, token: cfg.VaultToken, path: cfg.VaultPath}, nil
+	case "aws":
+		if cfg.AWSSecretId == "" || cfg.AWSRegion == "" || cfg.AWSAccessKey == "" || cfg.AWSSecretKey == "" {
+			return nil, errors.New("secrets provider aws needs secrets.aws_secret_id, secrets.aws_region, secrets.aws_access_key and secrets.aws_secret_key")
+		}
+		endpoint := cfg.AWSEndpoint
+		if endpoint == "" {
+			endpoint = "https://secretsmanager." + cfg.AWSRegion + ".amazonaws.com"
+		}
+		return awsSecrets{
+			endpoint:     endpoint,
+			region:       cfg.AWSRegion,
+			secretId:     cfg.AWSSecretId,
+			accessKey:    cfg.AWSAccessKey,
+			secretKey:    cfg.AWSSecretKey,
+			sessionToken: cfg.AWSSessionToken,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown secrets provider %q", cfg.Provider)
+	}
+}
+
+var secretsClient = &http.Client{Timeout: 10 * time.Second}
+
+// secretManager keeps the secrets last fetched from its provider, if it
+// has one, for what reads them as it goes, so rotated secrets take effect
+// without a restart. It keeps the last secrets it got when the provider is
+// down.
+type secretManager struct {
+	provider SecretProvider
+
+	mu      sync.RWMutex
+	secrets map[string]string
+}
+
+var secrets *secretManager
+
+// Fetch the secrets now, keeping the last ones if that fails.
+func (m *secretManager) refresh(ctx context.Context) error {
+	fetched, err := m.provider.FetchSecrets(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.secrets = fetched
+	return nil
+}
+
+// The latest value of the secret name, or configured if the secrets
+// manager has none.
+func (m *secretManager) get(name, configured string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if value := m.secrets[name]; value != "" {
+		return value
+	}
+	return configured
+}
+
+// Fetch the secrets again and move to a rotated pii_key; the
+// secrets_refresh job.
+func refreshSecrets(ctx context.Context) error {
+	if err := secrets.refresh(ctx); err != nil {
+		return err
+	}
+	return rotatePIIKey(ctx)
+}
+
+// Set up the secrets manager as configured and fetch the secrets, which
+// the server can't start without. The config returned has them in place
+// of the configured ones, for what sets up with them at startup; what
+// uses them reads the latest with get.
+func initSecrets(cfg Config) (Config, error) {
+	provider, err := openSecretProvider(cfg.Secrets)
+	if err != nil {
+		return cfg, err
+	}
+	secrets = &secretManager{provider: provider}
+	if provider == nil {
+		return cfg, nil
+	}
+	if cfg.Secrets.Refresh.Duration <= 0 {
+		return cfg, errors.New("secrets.refresh must be positive")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := secrets.refresh(ctx); err != nil {
+		return cfg, fmt.Errorf("fetching secrets: %w", err)
+	}
+	cfg.AdminToken = secrets.get("admin_token", cfg.AdminToken)
+	cfg.SMTPPassword = secrets.get("smtp_password", cfg.SMTPPassword)
+	cfg.SMSAuthToken = secrets.get("sms_auth_token", cfg.SMSAuthToken)
+	cfg.VAPIDPrivateKey = secrets.get("vapid_private_key", cfg.VAPIDPrivateKey)
+	cfg.PII.Key = secrets.get("pii_key", cfg.PII.Key)
+	return cfg, nil
+}
+
+// The secrets in a JSON object of them, whose values must be strings.
+func secretStrings(object map[string]any) (map[string]string, error) {
+	values := make(map[string]string, len(object))
+	for name, value := range object {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("secret %q isn't a string", name)
+		}
+		values[name] = s
+	}
+	return values, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Secrets kept in HashiCorp Vault, as the fields of one secret of a KV
+// secrets engine, version 1 or 2, read with a token.
+type vaultSecrets struct {
+	addr  string
+	token string
+	// path is the secret's, after /v1/ in the HTTP API: with version 2
+	// engines it has data/ after the mount, as in "secret/data/bookshelf".
+	path string
+}
+
+func (v vaultSecrets) FetchSecrets(ctx context.Context) (map[string]string, error) {
+	url := strings.TrimRight(v.addr, "/") + "/v1/" + strings.Trim(v.path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	res, err := secretsClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault: %s: %s", v.path, res.Status)
+	}
+
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+	// Version 2 engines nest the fields in data.data, beside their
+	// metadata.
+	fields := body.Data
+	if inner, ok := fields["data"].(map[string]any); ok && fields["metadata"] != nil {
+		fields = inner
+	}
+	values, err := secretStrings(fields)
+	if err != nil {
+		return nil, fmt.Errorf("vault: %s: %w", v.path, err)
+	}
+	return values, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Set the configured security headers on every response, and the admin
+// UI's Content-Security-Policy on its own. They are set before the
+// handler runs, so handlers can still change them.
+func securityHeaders(cfg SecurityHeadersConfig) (mux.MiddlewareFunc, error) {
+	if cfg.HSTSMaxAge.Duration < 0 {
+		return nil, errors.New("security_headers.hsts_max_age must not be negative")
+	}
+	headers := map[string]string{
+		"X-Content-Type-Options": cfg.ContentTypeOptions,
+		"X-Frame-Options":        cfg.FrameOptions,
+		"Referrer-Policy":        cfg.ReferrerPolicy,
+	}
+	if seconds := int64(cfg.HSTSMaxAge.Seconds()); seconds > 0 {
+		hsts := "max-age=" + strconv.FormatInt(seconds, 10)
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		headers["Strict-Transport-Security"] = hsts
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for name, value := range headers {
+				if value != "" {
+					w.Header().Set(name, value)
+				}
+			}
+			if cfg.AdminCSP != "" && (r.URL.Path == "/admin" || strings.HasPrefix(r.URL.Path, "/admin/")) {
+				w.Header().Set("Content-Security-Policy", cfg.AdminCSP)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
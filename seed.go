@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// The sample catalog for development and demos: well-known books with
+// some reader reviews.
+//
+//go:embed seed/catalog.json
+var seedCatalog []byte
+
+type seedBook struct {
+	Book
+	Reviews []Review `json:"reviews"`
+}
+
+// Load the sample catalog into an empty store, in one transaction. A
+// catalog that already has books is left alone, so seeding can run on
+// every start of a demo environment.
+func seed(ctx context.Context) error {
+	var books []seedBook
+	if err := json.Unmarshal(seedCatalog, &books); err != nil {
+		return fmt.Errorf("parsing the sample catalog: %w", err)
+	}
+
+	stats, err := store.CatalogStats(ctx, time.Now().UTC(), 0)
+	if err != nil {
+		return err
+	}
+	if stats.TotalBooks > 0 {
+		log.Printf("The catalog already has %d books, not seeding it", stats.TotalBooks)
+		return nil
+	}
+
+	reviews := 0
+	err = store.WithTx(ctx, func(tx BookStore) error {
+		for _, b := range books {
+			book := b.Book
+			book.normalize()
+			if errs := validateRequest(book); errs != nil {
+				return fmt.Errorf("sample book %q: %s", book.Title, validationMessage(errs))
+			}
+			if err := createBook(ctx, tx, &book); err != nil {
+				return err
+			}
+
+			for _, review := range b.Reviews {
+				review.BookId = book.Id
+				if errs := validateRequest(review); errs != nil {
+					return fmt.Errorf("review of %q: %s", book.Title, validationMessage(errs))
+				}
+				if err := tx.CreateReview(ctx, &review); err != nil {
+					return err
+				}
+				reviews++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Seeded the catalog with %d books and %d reviews", len(books), reviews)
+	return nil
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Series is a run of books read in order, such as "Discworld". Each book
+// is a volume of at most one series, at a position in it.
+type Series struct {
+	Id          int       `json:"id" xml:"id"`
+	Name        string    `json:"name" xml:"name" validate:"required,max=255"`
+	Description string    `json:"description,omitempty" xml:"description,omitempty" validate:"max=2000"`
+	CreatedAt   time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" xml:"updated_at"`
+}
+
+func (s Series) timestamps() (created, updated time.Time) {
+	return s.CreatedAt, s.UpdatedAt
+}
+
+// SeriesBook is a volume of a series and where it comes. Positions need
+// not be whole, so a novella can come at 2.5, between 2 and 3.
+type SeriesBook struct {
+	Position float64 `json:"position" xml:"position"`
+	Book     Book    `json:"book" xml:"book"`
+}
+
+func (b SeriesBook) timestamps() (created, updated time.Time) {
+	return timeOrZero(b.Book.CreatedAt), timeOrZero(b.Book.UpdatedAt)
+}
+
+// SeriesPosition places a book in a series; without a position it goes
+// after the last volume.
+type SeriesPosition struct {
+	Position *float64 `json:"position" xml:"position" validate:"omitempty,min=0,max=100000"`
+}
+
+type SeriesResponse struct {
+	Status  string `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
+	Data    Series `json:"data" xml:"data"`
+}
+
+type SeriesListResponse struct {
+	Status  string   `json:"status" xml:"status"`
+	Message string   `json:"message" xml:"message"`
+	Data    []Series `json:"data" xml:"data>series"`
+}
+
+type SeriesBooksResponse struct {
+	Status  string       `json:"status" xml:"status"`
+	Message string       `json:"message" xml:"message"`
+	Data    []SeriesBook `json:"data" xml:"data>volume"`
+}
+
+func (s *Series) normalize() {
+	s.Name = normalizeText(s.Name)
+	s.Description = normalizeText(s.Description)
+}
+
+func listSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+	series, err := store.ListSeries(r.Context(), after)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching series")
+		log.Printf("Series query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, SeriesListResponse{
+		Status:  "success",
+		Message: "Series retrieved successfully",
+		Data:    series,
+	})
+}
+
+func createSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	var series Series
+	if err := decodeRequest(r, &series); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	series.normalize()
+	if errs := validateRequest(series); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	series.Id = 0
+	series.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	series.UpdatedAt = series.CreatedAt
+
+	if err := store.CreateSeries(r.Context(), &series); err != nil {
+		writeProblem(w, r, codeInternal, "Error creating series")
+		log.Printf("Series creation error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, SeriesResponse{
+		Status:  "success",
+		Message: "Series created successfully",
+		Data:    series,
+	})
+}
+
+func getSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid series ID")
+		return
+	}
+
+	series, err := store.GetSeries(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeSeriesNotFound, "Series not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching series")
+		log.Printf("Series query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, SeriesResponse{
+		Status:  "success",
+		Message: "Series retrieved successfully",
+		Data:    series,
+	})
+}
+
+// Replace the name and description of a series.
+func updateSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid series ID")
+		return
+	}
+	var req Series
+	if err := decodeRequest(r, &req); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	req.normalize()
+	if errs := validateRequest(req); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	var series Series
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		var err error
+		if series, err = tx.GetSeries(r.Context(), id); err != nil {
+			return err
+		}
+		series.Name, series.Description = req.Name, req.Description
+		series.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		return tx.UpdateSeries(r.Context(), series)
+	})
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeSeriesNotFound, "Series not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error updating series")
+		log.Printf("Series update error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, SeriesResponse{
+		Status:  "success",
+		Message: "Series updated successfully",
+		Data:    series,
+	})
+}
+
+// Delete a series; its books stay in the catalog, in no series.
+func deleteSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid series ID")
+		return
+	}
+
+	err = store.DeleteSeries(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeSeriesNotFound, "Series not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error deleting series")
+		log.Printf("Series deletion error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Series deleted successfully",
+	})
+}
+
+// List the volumes of a series in reading order.
+func listSeriesBooksHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid series ID")
+		return
+	}
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+
+	books, err := store.ListSeriesBooks(r.Context(), id, after)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeSeriesNotFound, "Series not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching series books")
+		log.Printf("Series books query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, SeriesBooksResponse{
+		Status:  "success",
+		Message: "Series books retrieved successfully",
+		Data:    books,
+	})
+}
+
+var errSeriesBookMissing = errors.New("book not found")
+
+// Make a book a volume of a series, at the position given or after the
+// last volume, moving it from any other series it was in.
+func setSeriesBookHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid series ID")
+		return
+	}
+	bookId, err := strconv.Atoi(vars["book_id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+	var req SeriesPosition
+	if r.ContentLength != 0 {
+		if err := decodeRequest(r, &req); err != nil {
+			writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+			return
+		}
+		if errs := validateRequest(req); errs != nil {
+			writeValidationErrors(w, r, errs)
+			return
+		}
+	}
+
+	var books []SeriesBook
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		if _, err := tx.GetSeries(r.Context(), id); err != nil {
+			return err
+		}
+		book, err := tx.GetBookFields(r.Context(), bookId, []string{"id"})
+		if errors.Is(err, ErrNotFound) {
+			return errSeriesBookMissing
+		} else if err != nil {
+			return err
+		}
+		if books, err = tx.ListSeriesBooks(r.Context(), id, ChangedAfter{}); err != nil {
+			return err
+		}
+
+		var position float64
+		if req.Position != nil {
+			position = *req.Position
+		} else {
+			position = 1
+			for _, volume := range books {
+				if volume.Book.Id != book.Id {
+					position = max(position, math.Floor(volume.Position)+1)
+				}
+			}
+		}
+		if err := tx.SetBookSeries(r.Context(), book.Id, id, position); err != nil {
+			return err
+		}
+		books, err = tx.ListSeriesBooks(r.Context(), id, ChangedAfter{})
+		return err
+	})
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeSeriesNotFound, "Series not found")
+		return
+	case errors.Is(err, errSeriesBookMissing):
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error updating series")
+		log.Printf("Series update error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, SeriesBooksResponse{
+		Status:  "success",
+		Message: "Book added to series successfully",
+		Data:    books,
+	})
+}
+
+func removeSeriesBookHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid series ID")
+		return
+	}
+	bookId, err := strconv.Atoi(vars["book_id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+
+	err = store.RemoveBookFromSeries(r.Context(), bookId, id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book isn't in the series")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error updating series")
+		log.Printf("Series update error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Book removed from series successfully",
+	})
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// The reading statuses of shelved books. Books are shelved to-read unless
+// the reader says otherwise.
+const (
+	ReadingToRead    = "to-read"
+	ReadingReading   = "reading"
+	ReadingFinished  = "finished"
+	ReadingAbandoned = "abandoned"
+)
+
+var readingStatuses = []string{ReadingToRead, ReadingReading, ReadingFinished, ReadingAbandoned}
+
+// ShelfItem is a book on a user's shelf.
+type ShelfItem struct {
+	UserId int `json:"-" xml:"-"`
+	BookId int `json:"book_id" xml:"book_id"`
+	// Rating is the user's, from 1 to 5, if they rated the book.
+	Rating *int `json:"rating,omitempty" xml:"rating,omitempty" validate:"omitempty,min=1,max=5"`
+	// Status is where the user is with the book; shelving it without one
+	// keeps the status it had.
+	Status  string    `json:"status" xml:"status" validate:"omitempty,reading_status"`
+	AddedAt time.Time `json:"added_at" xml:"added_at"`
+	// StatusChanges are the statuses the book has had, oldest first,
+	// starting with the one it was shelved with.
+	StatusChanges []StatusChange `json:"status_changes" xml:"status_changes>change"`
+	// Progress is the last progress recorded in the book, if any.
+	Progress *ReadingProgress `json:"progress,omitempty" xml:"progress,omitempty"`
+	// Book is loaded by ListShelf.
+	Book *Book `json:"book,omitempty" xml:"book,omitempty"`
+	// CreatedAt is when the book was first added, and UpdatedAt when it
+	// was last shelved or had progress recorded.
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+}
+
+func (i ShelfItem) timestamps() (created, updated time.Time) {
+	return i.CreatedAt, i.UpdatedAt
+}
+
+// StatusChange is a shelved book's move to a reading status.
+type StatusChange struct {
+	Status    string    `json:"status" xml:"status"`
+	ChangedAt time.Time `json:"changed_at" xml:"changed_at"`
+}
+
+type ShelfResponse struct {
+	Status  string      `json:"status" xml:"status"`
+	Message string      `json:"message" xml:"message"`
+	Data    []ShelfItem `json:"data" xml:"data>item"`
+}
+
+type ShelfItemResponse struct {
+	Status  string    `json:"status" xml:"status"`
+	Message string    `json:"message" xml:"message"`
+	Data    ShelfItem `json:"data" xml:"data"`
+}
+
+// List the books on the user's shelf, only those with the reading status
+// in ?status= if it is given.
+func listShelfHandler(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && !slices.Contains(readingStatuses, status) {
+		writeProblem(w, r, codeInvalidParameter, "status must be to-read, reading, finished or abandoned")
+		return
+	}
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := store.ListShelf(r.Context(), currentUser(r).Id, status, after)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching shelf")
+		log.Printf("Shelf query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, ShelfResponse{
+		Status:  "success",
+		Message: "Shelf retrieved successfully",
+		Data:    items,
+	})
+}
+
+// Put a book on the user's shelf, or change its rating or reading status.
+func shelveBookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+	var item ShelfItem
+	if err := decodeRequest(r, &item); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	if errs := validateRequest(item); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	item.BookId = id
+	item.AddedAt = time.Now().UTC().Truncate(time.Microsecond)
+	item.StatusChanges = nil
+
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		book, err := tx.GetBook(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		item.Book = &book
+		return tx.ShelveBook(r.Context(), currentUser(r).Id, &item)
+	})
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error shelving book")
+		log.Printf("Shelf update error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, ShelfItemResponse{
+		Status:  "success",
+		Message: "Book shelved successfully",
+		Data:    item,
+	})
+}
+
+func unshelveBookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+
+	err = store.UnshelveBook(r.Context(), currentUser(r).Id, id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book isn't on the shelf")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error removing book from shelf")
+		log.Printf("Shelf update error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Book removed from shelf successfully",
+	})
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// The most URLs one sitemap may list. Catalogs with more books get a
+// sitemap index at /sitemap.xml pointing at pages of this many.
+const sitemapSize = 50000
+
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc string `xml:"loc"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+// sitemapHandler serves /sitemap.xml, listing the public page of every
+// book, made from pageURL, or an index of the /sitemap-{page}.xml pages
+// that do once there are more than sitemapSize books.
+func sitemapHandler(pageURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pages, ok := sitemapPages(w, r)
+		if !ok {
+			return
+		}
+		if pages > 1 {
+			base := requestBaseURL(r)
+			index := sitemapIndex{Xmlns: sitemapNamespace}
+			for page := 1; page <= pages; page++ {
+				index.Sitemaps = append(index.Sitemaps, sitemapURL{Loc: base + "/sitemap-" + strconv.Itoa(page) + ".xml"})
+			}
+			writeSitemap(w, r, index)
+			return
+		}
+		writeSitemapPage(w, r, pageURL, 1)
+	}
+}
+
+// sitemapPageHandler serves one page of the sitemap index.
+func sitemapPageHandler(pageURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, err := strconv.Atoi(mux.Vars(r)["page"])
+		if err != nil {
+			writeProblem(w, r, codeInvalidParameter, "Invalid sitemap page")
+			return
+		}
+		pages, ok := sitemapPages(w, r)
+		if !ok {
+			return
+		}
+		if page < 1 || page > pages {
+			writeProblem(w, r, codeRouteNotFound, "Sitemap page not found")
+			return
+		}
+		writeSitemapPage(w, r, pageURL, page)
+	}
+}
+
+// How many sitemap pages the catalog needs, at least one. Answers r and
+// returns false if the catalog can't be counted.
+func sitemapPages(w http.ResponseWriter, r *http.Request) (int, bool) {
+	stats, err := store.CatalogStats(r.Context(), time.Now(), 0)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching books from database")
+		log.Printf("Database query error: %v", err)
+		return 0, false
+	}
+	return max(1, (stats.TotalBooks+sitemapSize-1)/sitemapSize), true
+}
+
+func writeSitemapPage(w http.ResponseWriter, r *http.Request, pageURL string, page int) {
+	base := requestBaseURL(r)
+	set := sitemapURLSet{Xmlns: sitemapNamespace, URLs: []sitemapURL{}}
+	query := BookQuery{Fields: []string{"id", "slug"}, Offset: (page - 1) * sitemapSize, Limit: sitemapSize}
+	err := store.StreamBooks(r.Context(), query, func(book Book) error {
+		loc := strings.NewReplacer("{id}", strconv.Itoa(book.Id), "{slug}", book.Slug).Replace(pageURL)
+		if strings.HasPrefix(loc, "/") {
+			loc = base + loc
+		}
+		set.URLs = append(set.URLs, sitemapURL{Loc: loc})
+		return nil
+	})
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching books from database")
+		log.Printf("Database query error: %v", err)
+		return
+	}
+	writeSitemap(w, r, set)
+}
+
+func writeSitemap(w http.ResponseWriter, r *http.Request, sitemap any) {
+	var body bytes.Buffer
+	body.WriteString(xml.Header)
+	if err := xml.NewEncoder(&body).Encode(sitemap); err != nil {
+		writeProblem(w, r, codeInternal, "Error writing sitemap")
+		log.Printf("Sitemap error: %v", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+}
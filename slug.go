@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Longest slug generated from a title, in characters, before a suffix
+// that makes it unique.
+const maxSlugLength = 80
+
+// Clean up a title or author name as entered: Unicode NFC, with runs of
+// whitespace collapsed to one space and none at the ends.
+func normalizeText(s string) string {
+	return strings.Join(strings.Fields(norm.NFC.String(s)), " ")
+}
+
+// Normalize the text fields of a book a client sent.
+func (b *Book) normalize() {
+	b.Title = normalizeText(b.Title)
+	b.Author = normalizeText(b.Author)
+	b.Publisher = normalizeText(b.Publisher)
+	b.ISBN = strings.TrimSpace(b.ISBN)
+	if isbn, ok := normalizeISBN(b.ISBN); ok {
+		b.ISBN = isbn
+	}
+	b.Format = strings.ToLower(strings.TrimSpace(b.Format))
+	b.Description = strings.TrimSpace(norm.NFC.String(b.Description))
+	b.CoverURL = strings.TrimSpace(b.CoverURL)
+}
+
+// Turn a title into a URL-safe slug: its letters and digits in lower case,
+// each run of anything else replaced by a hyphen. Accents are dropped from
+// Latin letters, so "Café Society" becomes "cafe-society"; letters of
+// other scripts are kept as they are. Titles with no letters or digits
+// get "book".
+func slugify(title string) string {
+	var b strings.Builder
+	var n int
+	separated, afterLatin := false, false
+	for _, r := range norm.NFKD.String(title) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// A combining mark belongs to the letter before it.
+			if !afterLatin && !separated && b.Len() > 0 {
+				b.WriteRune(r)
+			}
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			hyphen := separated && b.Len() > 0
+			if hyphen && n+2 > maxSlugLength || n+1 > maxSlugLength {
+				return finishSlug(b.String())
+			}
+			if hyphen {
+				b.WriteByte('-')
+				n++
+			}
+			separated, afterLatin = false, r < utf8.RuneSelf
+			b.WriteRune(unicode.ToLower(r))
+			n++
+		default:
+			separated = true
+		}
+	}
+	return finishSlug(b.String())
+}
+
+func finishSlug(slug string) string {
+	slug = strings.TrimRight(norm.NFC.String(slug), "-")
+	if slug == "" {
+		return "book"
+	}
+	return slug
+}
+
+// The first of base, base-2, base-3, ... that isn't taken.
+func firstFreeSlug(base string, taken map[string]bool) string {
+	slug := base
+	for n := 2; taken[slug]; n++ {
+		slug = base + "-" + strconv.Itoa(n)
+	}
+	return slug
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// The frontend built into the binary; see frontend/README.md.
+//
+//go:embed frontend
+var embeddedFrontend embed.FS
+
+// The frontend to serve: the files in dir if it is set, otherwise the
+// embedded ones. It is nil if there is no embedded frontend.
+func openFrontend(dir string) (fs.FS, error) {
+	if dir != "" {
+		files := os.DirFS(dir)
+		if _, err := fs.Stat(files, "index.html"); err != nil {
+			return nil, fmt.Errorf("frontend_dir: %w", err)
+		}
+		return files, nil
+	}
+
+	files, err := fs.Sub(embeddedFrontend, "frontend")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fs.Stat(files, "index.html"); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Serve a single-page app from files for GET requests no route matched.
+// Paths that aren't files get index.html, so the app's own router can
+// handle URLs it made with the history API. Requests under /api/, and
+// for missing files with an extension, go to notFound instead, so clients
+// get a problem rather than the page.
+func spaHandler(files fs.FS, notFound http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead || strings.HasPrefix(r.URL.Path, "/api/") {
+			notFound.ServeHTTP(w, r)
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+		if info, err := fs.Stat(files, name); err == nil && !info.IsDir() && name != "index.html" {
+			http.ServeFileFS(w, r, files, name)
+			return
+		}
+		if path.Ext(name) != "" && name != "index.html" {
+			notFound.ServeHTTP(w, r)
+			return
+		}
+
+		// The page must be revalidated so a deploy takes effect at once;
+		// assets usually have hashed names and can be cached.
+		w.Header().Set("Cache-Control", "no-cache")
+		index, err := fs.ReadFile(files, "index.html")
+		if err != nil {
+			notFound.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(index)
+	})
+}
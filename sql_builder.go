@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+)
+
+// sqlWhere composes a WHERE clause from conditions that must all hold,
+// keeping the arguments for their placeholders in order beside them, so
+// queries with optional filters don't have to splice SQL strings together.
+type sqlWhere struct {
+	conditions []string
+	args       []any
+}
+
+// A WHERE clause of condition, with an argument for each of its
+// placeholders.
+func where(condition string, args ...any) *sqlWhere {
+	return (&sqlWhere{}).and(condition, args...)
+}
+
+// Add condition, with an argument for each of its placeholders. An empty
+// condition adds nothing.
+func (w *sqlWhere) and(condition string, args ...any) *sqlWhere {
+	if condition != "" {
+		w.conditions = append(w.conditions, condition)
+		w.args = append(w.args, args...)
+	}
+	return w
+}
+
+// Add the conditions that rows were added after after.Created, going by
+// their created column, and changed after after.Updated, going by their
+// updated one, as far as those are given.
+func (w *sqlWhere) changedAfter(after ChangedAfter, created, updated string) *sqlWhere {
+	if !after.Created.IsZero() {
+		w.and(created+" > ?", after.Created)
+	}
+	if !after.Updated.IsZero() {
+		w.and(updated+" > ?", after.Updated)
+	}
+	return w
+}
+
+// The conditions joined with AND, and their arguments.
+func (w *sqlWhere) sql() (string, []any) {
+	return strings.Join(w.conditions, " AND "), w.args
+}
+
+// The clause, " WHERE " and the conditions or "" without any, and its
+// arguments, to follow a table.
+func (w *sqlWhere) clause() (string, []any) {
+	if len(w.conditions) == 0 {
+		return "", nil
+	}
+	condition, args := w.sql()
+	return " WHERE " + condition, args
+}
+
+// The condition that column is one of values, and its arguments. Without
+// values it never holds.
+func sqlIn[T any](column string, values []T) (string, []any) {
+	if len(values) == 0 {
+		return "1 = 0", nil
+	}
+	args := make([]any, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return column + " IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ") + ")", args
+}
+
+// sqlUpdate composes an UPDATE of the columns set on it, keeping their
+// values in order beside them.
+type sqlUpdate struct {
+	table       string
+	assignments []string
+	args        []any
+}
+
+// An UPDATE of table.
+func update(table string) *sqlUpdate {
+	return &sqlUpdate{table: table}
+}
+
+// Set column to value.
+func (u *sqlUpdate) set(column string, value any) *sqlUpdate {
+	u.assignments = append(u.assignments, column+" = ?")
+	u.args = append(u.args, value)
+	return u
+}
+
+// Whether no column is set.
+func (u *sqlUpdate) empty() bool {
+	return len(u.assignments) == 0
+}
+
+// The statement, updating the rows w holds for, and its arguments.
+func (u *sqlUpdate) where(w *sqlWhere) (string, []any) {
+	clause, whereArgs := w.clause()
+	args := append(append([]any{}, u.args...), whereArgs...)
+	return "UPDATE " + u.table + " SET " + strings.Join(u.assignments, ", ") + clause, args
+}
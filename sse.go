@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Comment lines sent this often keep proxies from closing an idle stream.
+const sseHeartbeat = 15 * time.Second
+
+// Stream catalog change events as Server-Sent Events, for clients that
+// can't use /ws. Each event carries its id, so a reconnecting EventSource
+// sends Last-Event-ID and resumes where it left off. If the missed events
+// are no longer available a "reset" event tells the client to refetch.
+func sseHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeProblem(w, r, codeInternal, "Streaming unsupported")
+		return
+	}
+
+	// The query parameter is for clients that can't set headers.
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = r.URL.Query().Get("last_event_id")
+	}
+
+	var missed []Event
+	var sub <-chan Event
+	var unsubscribe func()
+	resumed := true
+	if lastEventId != "" {
+		id, err := strconv.ParseInt(lastEventId, 10, 64)
+		if err != nil {
+			writeProblem(w, r, codeInvalidRequest, "Invalid Last-Event-ID")
+			return
+		}
+		missed, resumed, sub, unsubscribe = events.SubscribeSince(tenantId(r.Context()), id)
+	} else {
+		sub, unsubscribe = events.Subscribe(tenantId(r.Context()))
+	}
+	defer unsubscribe()
+
+	wanted := eventTypeFilter(r.URL.Query().Get("types"))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Stop nginx from buffering the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if !resumed {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range missed {
+		if wanted == nil || wanted[event.Type] {
+			writeSSEEvent(w, event)
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub:
+			if !ok {
+				// Dropped for falling behind; the client reconnects with Last-Event-ID.
+				return
+			}
+			if wanted != nil && !wanted[event.Type] {
+				continue
+			}
+			writeSSEEvent(w, event)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func writeSSEEvent(w http.ResponseWriter, event Event) {
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data)
+}
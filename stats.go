@@ -0,0 +1,67 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// CatalogStats summarizes the catalog for dashboards.
+type CatalogStats struct {
+	TotalBooks   int `json:"total_books" xml:"total_books"`
+	TotalAuthors int `json:"total_authors" xml:"total_authors"`
+	// Price is all zeros for an empty catalog.
+	Price PriceStats `json:"price" xml:"price"`
+	// RecentlyAdded doesn't count books added before the server recorded
+	// when books were added.
+	RecentlyAdded RecentlyAdded `json:"recently_added" xml:"recently_added"`
+	// Authors are the authors with the most books, most first.
+	Authors []AuthorCount `json:"authors" xml:"authors>author"`
+}
+
+type PriceStats struct {
+	Average float64 `json:"average" xml:"average"`
+	Min     float64 `json:"min" xml:"min"`
+	Max     float64 `json:"max" xml:"max"`
+}
+
+// RecentlyAdded counts the books added in the last day, week and 30 days.
+type RecentlyAdded struct {
+	LastDay   int `json:"last_day" xml:"last_day"`
+	LastWeek  int `json:"last_week" xml:"last_week"`
+	LastMonth int `json:"last_month" xml:"last_month"`
+}
+
+type AuthorCount struct {
+	Author string `json:"author" xml:"name"`
+	Books  int    `json:"books" xml:"books"`
+}
+
+type StatsResponse struct {
+	Status  string       `json:"status" xml:"status"`
+	Message string       `json:"message" xml:"message"`
+	Data    CatalogStats `json:"data" xml:"data"`
+}
+
+// How many authors GET /stats lists.
+const statsTopAuthors = 50
+
+// The start of each window RecentlyAdded counts in, from now.
+func recentWindows(now time.Time) (day, week, month time.Time) {
+	return now.AddDate(0, 0, -1), now.AddDate(0, 0, -7), now.AddDate(0, 0, -30)
+}
+
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := store.CatalogStats(r.Context(), time.Now().UTC(), statsTopAuthors)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error computing statistics")
+		log.Printf("Stats query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, StatsResponse{
+		Status:  "success",
+		Message: "Statistics computed successfully",
+		Data:    stats,
+	})
+}
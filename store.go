@@ -0,0 +1,1006 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrNotFound is returned by a store when the requested record does not exist.
+var ErrNotFound = errors.New("record not found")
+
+// ErrDuplicateISBN is returned by a store when a book would get the ISBN of
+// another book.
+var ErrDuplicateISBN = errors.New("isbn already in use")
+
+// errSlugTaken is returned by a store when another book took the slug it
+// picked before the book could be saved; trying again picks another.
+var errSlugTaken = errors.New("slug already in use")
+
+// ErrDuplicateEmail is returned by a store when a user would get the email
+// address of another user.
+var ErrDuplicateEmail = errors.New("email already in use")
+
+// ErrDuplicateCardNumber is returned by a store when a member would get
+// the card number of another member.
+var ErrDuplicateCardNumber = errors.New("card number already in use")
+
+// ErrDuplicateBarcode is returned by a store when a copy would get the
+// barcode of another copy.
+var ErrDuplicateBarcode = errors.New("barcode already in use")
+
+// ErrNotEnoughPoints is returned by a store when a user would redeem more
+// loyalty points than they have.
+var ErrNotEnoughPoints = errors.New("not enough loyalty points")
+
+// ErrDuplicateTenant is returned by a store when a tenant would get the
+// slug of another tenant.
+var ErrDuplicateTenant = errors.New("tenant slug already in use")
+
+// ErrMemberInUse is returned by DeleteMember when the member has books on
+// loan or fines outstanding.
+var ErrMemberInUse = errors.New("member has loans or fines outstanding")
+
+// ErrBooksInUse is returned by RestoreCatalog when something other than
+// the books' own history refers to books it would delete.
+var ErrBooksInUse = errors.New("books to delete are in use")
+
+// How often createBook and updateExistingBook try again after
+// errSlugTaken.
+const maxSlugAttempts = 3
+
+// BookStore is the storage layer behind the HTTP handlers. Everything
+// but tenants belongs to a tenant: methods read and write only the data of
+// the tenant their context acts for (see withTenant), except where they
+// say they work across tenants, for the background workers.
+type BookStore interface {
+	ListBooks(ctx context.Context) ([]Book, error)
+	// LockBooks is ListBooks that, inside a transaction, keeps the books
+	// from changing until it ends, for callers that write back what they
+	// read.
+	LockBooks(ctx context.Context) ([]Book, error)
+	// SearchBooks returns the books whose title or author contains query,
+	// ignoring case.
+	SearchBooks(ctx context.Context, query string) ([]Book, error)
+	// StreamBooks calls fn with each book the query selects as it is
+	// read, so the result never has to fit in memory. It stops at the
+	// first error from fn.
+	StreamBooks(ctx context.Context, query BookQuery, fn func(Book) error) error
+	// RecentBooks returns up to limit of the books added last, or changed
+	// last if by is RecentUpdated, newest first, with when they were.
+	// Books from before that was recorded are left out.
+	RecentBooks(ctx context.Context, by string, limit int) ([]Book, error)
+	GetBook(ctx context.Context, id int) (Book, error)
+	// GetBookFields is GetBook loading only the given fields, see
+	// bookFields.
+	GetBookFields(ctx context.Context, id int, fields []string) (Book, error)
+	// GetBookIdBySlug returns the id of the book with the slug, or
+	// ErrNotFound.
+	GetBookIdBySlug(ctx context.Context, slug string) (int, error)
+	// GetBookIdByISBN returns the id of the book whose ISBN is isbn, a
+	// normalized ISBN-13, as either an ISBN-13 or ISBN-10 and with or
+	// without hyphens, or ErrNotFound.
+	GetBookIdByISBN(ctx context.Context, isbn string) (int, error)
+	// CreateBook inserts the book and sets its Id and its Slug, made from
+	// the title. It returns ErrDuplicateISBN if another book has its ISBN,
+	// as does UpdateBook. Use createBook, which retries when a concurrent
+	// create took the slug.
+	CreateBook(ctx context.Context, book *Book) error
+	// UpdateBook changes the non-empty fields of book and returns the stored result.
+	// A new title gives the book a new slug.
+	UpdateBook(ctx context.Context, id int, book Book) (Book, error)
+	// SetPrices sets the price of each book in prices, by id, and returns
+	// the updated books ordered by id. It returns ErrNotFound if one of
+	// them doesn't exist.
+	SetPrices(ctx context.Context, prices map[int]float64) ([]Book, error)
+	// DeleteBook removes the book and its reviews, or returns ErrNotFound.
+	DeleteBook(ctx context.Context, id int) error
+	// DeleteAllBooks removes every book, along with its reviews, and
+	// reports how many books were deleted.
+	DeleteAllBooks(ctx context.Context) (int64, error)
+	// RestoreCatalog makes the books and reviews the given ones, keeping
+	// their ids and slugs: those the store has are updated in place,
+	// keeping what refers to them, the rest added, and those not given
+	// deleted. It returns ErrBooksInUse, changing nothing, if anything
+	// but their reviews, views, price history, recommendations,
+	// categories, tags and the sale, donation and order lines that keep
+	// their titles refers to books it would delete: loans,
+	// copies, holds, supplier prices, shelves, wishlists, notes, quotes,
+	// collections, series, works or merges. Ids are unique across
+	// tenants in the SQL stores, so a tenant is only restored from its own
+	// backups.
+	RestoreCatalog(ctx context.Context, books []Book, reviews []Review) error
+	// MergeBook moves everything that refers to the book with duplicateId
+	// to the one with survivorId, its stock included, and records the
+	// merge for MergedInto. The duplicate stays, archived and without its
+	// ISBN, with its views, price history and recommendations, but lists
+	// and searches leave it out and ArchiveBooks never restores it. Where
+	// the survivor has an entry of its own, for a reader, collection,
+	// supplier, subject, series or work, it keeps it. It returns
+	// ErrNotFound if either book doesn't exist or was merged itself.
+	MergeBook(ctx context.Context, duplicateId, survivorId int, mergedAt time.Time) error
+	// MergedInto returns the id of the book a book was merged into, or
+	// ErrNotFound.
+	MergedInto(ctx context.Context, id int) (int, error)
+	// CatalogStats aggregates the whole catalog, counting books added in
+	// the windows that end at now and listing the topAuthors authors with
+	// the most books.
+	CatalogStats(ctx context.Context, now time.Time, topAuthors int) (CatalogStats, error)
+
+	ReviewStore
+	WebhookStore
+	OutboxStore
+	IdempotencyStore
+	SearchLogStore
+	AccessLogStore
+	NotificationStore
+	PushStore
+	UserStore
+	ShelfStore
+	WishlistStore
+	GoalStore
+	NoteStore
+	QuoteStore
+	SavedSearchStore
+	MemberStore
+	LoanStore
+	CopyStore
+	BranchStore
+	HoldStore
+	FineStore
+	DonationStore
+	SupplierStore
+	PurchaseOrderStore
+	InventoryStore
+	SaleStore
+	GiftCardStore
+	LoyaltyStore
+	SubjectStore
+	ActivityStore
+	TenantStore
+	CollectionStore
+	SeriesStore
+	WorkStore
+
+	// WithTx runs fn against a store bound to one transaction, committing
+	// if fn returns nil and rolling back otherwise. Reads made through tx
+	// lock what they read until the transaction ends.
+	WithTx(ctx context.Context, fn func(tx BookStore) error) error
+	Close() error
+}
+
+// BookQuery selects books for StreamBooks.
+type BookQuery struct {
+	// Search matches books as SearchBooks does; empty matches every book.
+	Search string
+	// Fields limits the fields loaded, see bookFields; nil loads them all.
+	Fields []string
+	// Formats keeps only books in one of them; empty keeps every book.
+	Formats []string
+	// Filter keeps only books an advanced search matches, see
+	// parseBookFilter; nil keeps every book.
+	Filter *BookFilter
+	// CreatedAfter and UpdatedAfter keep only books added, and changed,
+	// after them if they aren't zero; books from before timestamps were
+	// recorded have neither.
+	CreatedAfter, UpdatedAfter time.Time
+	// ExcludeArchived leaves out archived books.
+	ExcludeArchived bool
+	// Offset skips that many books, in id order, and Limit stops after
+	// that many if it isn't 0.
+	Offset int
+	Limit  int
+}
+
+// TenantStore holds the tenants sharing the store.
+type TenantStore interface {
+	// CreateTenant stores the tenant and sets its Id. It returns
+	// ErrDuplicateTenant if another tenant has its slug.
+	CreateTenant(ctx context.Context, tenant *Tenant) error
+	// GetTenantBySlug returns the tenant with the slug, or ErrNotFound.
+	GetTenantBySlug(ctx context.Context, slug string) (Tenant, error)
+	// ListTenants returns the tenants after selects, ordered by id.
+	ListTenants(ctx context.Context, after ChangedAfter) ([]Tenant, error)
+}
+
+// ReviewStore holds reader reviews of books.
+type ReviewStore interface {
+	ListReviews(ctx context.Context, bookId int) ([]Review, error)
+	// ListReviewsForBooks returns up to limit reviews of the given books,
+	// ordered by book and then review.
+	ListReviewsForBooks(ctx context.Context, bookIds []int, limit int) ([]Review, error)
+	// ListUserReviews returns the reviews the user posted, in the order
+	// they were written.
+	ListUserReviews(ctx context.Context, userId int) ([]Review, error)
+	// CreateReview inserts the review and sets its Id. It returns
+	// ErrNotFound if the book doesn't exist.
+	CreateReview(ctx context.Context, review *Review) error
+}
+
+// WebhookStore holds the webhooks registered by admins and the log of
+// deliveries to them.
+type WebhookStore interface {
+	ListWebhooks(ctx context.Context, after ChangedAfter) ([]Webhook, error)
+	GetWebhook(ctx context.Context, id int) (Webhook, error)
+	// CreateWebhook inserts the webhook and sets its Id, CreatedAt and
+	// UpdatedAt.
+	CreateWebhook(ctx context.Context, hook *Webhook) error
+	// DeleteWebhook removes the webhook and its delivery log, or returns
+	// ErrNotFound.
+	DeleteWebhook(ctx context.Context, id int) error
+	// CreateWebhookDelivery records one delivery attempt and sets its Id.
+	CreateWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	// ListWebhookDeliveries returns up to limit of the attempts for the
+	// webhook after selects, newest first.
+	ListWebhookDeliveries(ctx context.Context, webhookId int, limit int, after ChangedAfter) ([]WebhookDelivery, error)
+	// DeleteWebhookDeliveriesBefore removes the delivery attempts of every
+	// tenant made before then and reports how many there were.
+	DeleteWebhookDeliveriesBefore(ctx context.Context, before time.Time) (int64, error)
+
+	// EnqueueWebhookJob queues an event for delivery and sets the job's Id.
+	EnqueueWebhookJob(ctx context.Context, job *WebhookJob) error
+	// ClaimWebhookJobs returns up to limit jobs due at now, of every
+	// tenant, and moves their next attempt to now+lease, so no other
+	// worker takes them meanwhile and they come back if this one dies.
+	ClaimWebhookJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]WebhookJob, error)
+	// UpdateWebhookJob saves the job's Attempt and NextAttemptAt.
+	UpdateWebhookJob(ctx context.Context, job WebhookJob) error
+	DeleteWebhookJob(ctx context.Context, id int) error
+}
+
+// OutboxStore holds change events written in the same transaction as the
+// change, until the relay has handed them on.
+type OutboxStore interface {
+	// AddOutboxEvent stores the event and sets its Id.
+	AddOutboxEvent(ctx context.Context, event *Event) error
+	// PendingOutboxEvents returns up to limit events of every tenant,
+	// oldest first. Inside a transaction they stay locked until it ends.
+	PendingOutboxEvents(ctx context.Context, limit int) ([]Event, error)
+	// DeleteOutboxEvents removes the events with the ids, of any tenant.
+	DeleteOutboxEvents(ctx context.Context, ids []int64) error
+}
+
+// IdempotencyStore remembers the responses to requests made with an
+// Idempotency-Key header, so retries get the same response.
+type IdempotencyStore interface {
+	// ReserveIdempotencyKey stores record, pending, and returns it with
+	// true, unless a record for its caller and key that expires after now
+	// exists; then it returns that one with false.
+	ReserveIdempotencyKey(ctx context.Context, record IdempotencyRecord, now time.Time) (IdempotencyRecord, bool, error)
+	// CompleteIdempotencyKey saves the response and expiry of a reserved
+	// key.
+	CompleteIdempotencyKey(ctx context.Context, record IdempotencyRecord) error
+	// DeleteIdempotencyKey frees the caller's reserved key for another
+	// attempt.
+	DeleteIdempotencyKey(ctx context.Context, caller, key string) error
+	// DeleteExpiredIdempotencyKeys removes the records of every tenant
+	// that expired before now and reports how many there were.
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error)
+}
+
+// SearchLogStore keeps the searches clients made, for analytics.
+type SearchLogStore interface {
+	// RecordSearches stores the searches, made in the tenant ctx acts
+	// for.
+	RecordSearches(ctx context.Context, searches []SearchRecord) error
+	// SearchAnalytics summarizes the searches made since then, listing up
+	// to limit terms of each kind.
+	SearchAnalytics(ctx context.Context, since time.Time, limit int) (SearchAnalytics, error)
+	// DeleteSearchesBefore removes the searches of every tenant made
+	// before then and reports how many there were.
+	DeleteSearchesBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// AccessLogStore holds the record of admins viewing members' personal
+// data.
+type AccessLogStore interface {
+	// RecordMemberAccess stores the access and sets its Id.
+	RecordMemberAccess(ctx context.Context, access *MemberAccess) error
+	// ListMemberAccess returns up to query.Limit of the accesses query
+	// selects, newest first.
+	ListMemberAccess(ctx context.Context, query MemberAccessQuery) ([]MemberAccess, error)
+}
+
+// NotificationStore holds the notifications waiting to be sent, the ones
+// that never could be, and which kinds each recipient wants.
+type NotificationStore interface {
+	// EnqueueNotification queues the notification and sets its Id.
+	EnqueueNotification(ctx context.Context, n *Notification) error
+	// ClaimNotifications returns up to limit notifications due at now, of
+	// every tenant, and moves their next attempt to now+lease, as
+	// ClaimWebhookJobs does.
+	ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Notification, error)
+	// UpdateNotification saves the notification's Attempt and
+	// NextAttemptAt.
+	UpdateNotification(ctx context.Context, n Notification) error
+	DeleteNotification(ctx context.Context, id int) error
+	// AddNotificationFailure records a notification given up on and sets
+	// its Id.
+	AddNotificationFailure(ctx context.Context, failure *NotificationFailure) error
+	// ListNotificationFailures returns up to limit of the failures after
+	// selects, newest first.
+	ListNotificationFailures(ctx context.Context, limit int, after ChangedAfter) ([]NotificationFailure, error)
+	// DeleteNotificationFailuresBefore removes the failures of every
+	// tenant given up on before then and reports how many there were.
+	DeleteNotificationFailuresBefore(ctx context.Context, before time.Time) (int64, error)
+	// NotificationPreferences returns the recipient's phone number and the
+	// preferences they have set, ordered by kind; kinds without one are on,
+	// by email.
+	NotificationPreferences(ctx context.Context, recipient string) (NotificationPreferences, error)
+	SetNotificationPreference(ctx context.Context, recipient string, preference NotificationPreference) error
+	// SetNotificationPhone sets the number texts to the recipient go to;
+	// an empty one removes it.
+	SetNotificationPhone(ctx context.Context, recipient, phone string) error
+}
+
+// PushStore holds the Web Push subscriptions of browsers and the book
+// prices they watch.
+type PushStore interface {
+	// SavePushSubscription stores the subscription, replacing any with its
+	// endpoint, and sets its Id. It watches the books in prices, by id,
+	// from the price given.
+	SavePushSubscription(ctx context.Context, sub *PushSubscription, prices map[int]float64) error
+	// GetPushSubscription returns the subscription with the endpoint, or
+	// ErrNotFound.
+	GetPushSubscription(ctx context.Context, endpoint string) (PushSubscription, error)
+	// ListPushSubscriptions returns the recipient's subscriptions.
+	ListPushSubscriptions(ctx context.Context, recipient string) ([]PushSubscription, error)
+	// DeletePushSubscription removes the subscription with the endpoint
+	// and its watches, or returns ErrNotFound.
+	DeletePushSubscription(ctx context.Context, endpoint string) error
+	// WatchPrice records price as the price of the book for every
+	// subscription watching it, and returns the ones that watched it at a
+	// higher price, with that price.
+	WatchPrice(ctx context.Context, bookId int, price float64) ([]PriceWatch, error)
+}
+
+// WishlistStore holds the books readers want, with the prices they wait
+// for, and the price history of books.
+type WishlistStore interface {
+	// ListWishlist returns the books on the user's wishlist after selects,
+	// in the order they were added.
+	ListWishlist(ctx context.Context, userId int, after ChangedAfter) ([]WishlistItem, error)
+	// SaveWishlistItem puts item.BookId on the user's wishlist at
+	// item.AddedAt with its target and alert, or replaces those and its
+	// UpdatedAt if it is there and sets item.AddedAt and item.CreatedAt to
+	// when it was first added.
+	SaveWishlistItem(ctx context.Context, userId int, item *WishlistItem) error
+	// DeleteWishlistItem takes the book off the user's wishlist, or
+	// returns ErrNotFound if it isn't on it.
+	DeleteWishlistItem(ctx context.Context, userId, bookId int) error
+	// ListPriceTargets returns every user's wishlist items with a target
+	// price, with their books, ordered by user.
+	ListPriceTargets(ctx context.Context) ([]WishlistItem, error)
+	// SetWishlistAlert records the price the user was told the book
+	// dropped to and when, or with nil forgets it, as updated at
+	// updatedAt.
+	SetWishlistAlert(ctx context.Context, userId, bookId int, price *float64, alertedAt *time.Time, updatedAt time.Time) error
+	// RecordBookPrice adds price to its book's price history, unless it is
+	// the price the book last had.
+	RecordBookPrice(ctx context.Context, price BookPrice) error
+	// ListBookPrices returns the book's prices after selects, oldest
+	// first, or ErrNotFound if there is no such book.
+	ListBookPrices(ctx context.Context, bookId int, after ChangedAfter) ([]BookPrice, error)
+}
+
+// UserStore holds the accounts of readers.
+type UserStore interface {
+	// CreateUser stores the user, who signs in with the token hashed to
+	// tokenHash, and sets its Id. It returns ErrDuplicateEmail if another
+	// user has its email address.
+	CreateUser(ctx context.Context, user *User, tokenHash string) error
+	// GetUserByToken returns the user whose token hashes to tokenHash, or
+	// ErrNotFound.
+	GetUserByToken(ctx context.Context, tokenHash string) (User, error)
+	// GetUser returns the user, or ErrNotFound.
+	GetUser(ctx context.Context, id int) (User, error)
+	// EraseUser deletes the user with everything only they have, such as
+	// their shelf, notes and points, and what notifications keep of them
+	// by email. What the library's figures need is kept without their
+	// name and contact details, which become erasedName or empty: their
+	// sales, the members linked to them with their loans and fines, and
+	// the reviews they posted. It returns ErrNotFound if there
+	// is no such user.
+	EraseUser(ctx context.Context, id int) error
+}
+
+// ShelfStore holds the books on readers' shelves and the recommendations
+// made from them.
+type ShelfStore interface {
+	// ListShelf returns the books on the user's shelf after selects with
+	// their status changes and last progress, in the order they were
+	// added; with a status, only those with it.
+	ListShelf(ctx context.Context, userId int, status string, after ChangedAfter) ([]ShelfItem, error)
+	// ShelveBook puts item.Book on the user's shelf at item.AddedAt, or
+	// updates its rating and status if it is there and sets item.AddedAt
+	// to when it was first added. An empty item.Status keeps the book's
+	// status, or is to-read for a new book; a new status is recorded as
+	// changed at the item.AddedAt given, which is also its UpdatedAt.
+	// item.StatusChanges is set to all the book's changes.
+	ShelveBook(ctx context.Context, userId int, item *ShelfItem) error
+	// UnshelveBook takes the book off the user's shelf, forgetting its
+	// status changes and progress, or returns ErrNotFound if it isn't on
+	// it.
+	UnshelveBook(ctx context.Context, userId, bookId int) error
+	// RecordProgress adds progress to the user's progress in
+	// progress.BookId, updating the shelf item at progress.RecordedAt, or
+	// returns ErrNotFound if the book isn't on their shelf.
+	RecordProgress(ctx context.Context, userId int, progress *ReadingProgress) error
+	// ListProgress returns all the progress the user has recorded in the
+	// books on their shelf, oldest first.
+	ListProgress(ctx context.Context, userId int) ([]ReadingProgress, error)
+	// AllShelfItems returns every user's shelf items, without their books,
+	// ordered by user.
+	AllShelfItems(ctx context.Context) ([]ShelfItem, error)
+	// ReplaceRecommendations replaces every user's recommendations with
+	// recommendations.
+	ReplaceRecommendations(ctx context.Context, recommendations []Recommendation) error
+	// ListRecommendations returns up to limit of the user's
+	// recommendations, best first.
+	ListRecommendations(ctx context.Context, userId, limit int) ([]Recommendation, error)
+}
+
+// CollectionStore holds the collections users group books into.
+type CollectionStore interface {
+	// ListCollections returns the user's collections after selects with
+	// their books, ordered by id.
+	ListCollections(ctx context.Context, userId int, after ChangedAfter) ([]Collection, error)
+	// GetCollection returns the user's collection with its books, or
+	// ErrNotFound. Inside a transaction it stays locked until it ends.
+	GetCollection(ctx context.Context, userId, id int) (Collection, error)
+	// GetSharedCollection returns the collection with the share token,
+	// with its books, or ErrNotFound.
+	GetSharedCollection(ctx context.Context, token string) (Collection, error)
+	// CreateCollection stores the collection, without books, and sets its
+	// Id.
+	CreateCollection(ctx context.Context, collection *Collection) error
+	// UpdateCollection saves the Name, ShareToken, UpdatedAt and Books, in
+	// order, of the user's collection, or returns ErrNotFound.
+	UpdateCollection(ctx context.Context, collection Collection) error
+	// DeleteCollection removes the user's collection, or returns
+	// ErrNotFound.
+	DeleteCollection(ctx context.Context, userId, id int) error
+}
+
+// SeriesStore holds the series books are volumes of. A book is in at most
+// one series, at a position in it.
+type SeriesStore interface {
+	// ListSeries returns the series after selects, ordered by name.
+	ListSeries(ctx context.Context, after ChangedAfter) ([]Series, error)
+	// GetSeries returns the series, or ErrNotFound. Inside a transaction
+	// it stays locked until it ends.
+	GetSeries(ctx context.Context, id int) (Series, error)
+	// CreateSeries stores the series and sets its Id.
+	CreateSeries(ctx context.Context, series *Series) error
+	// UpdateSeries saves the Name, Description and UpdatedAt of the
+	// series, or returns ErrNotFound.
+	UpdateSeries(ctx context.Context, series Series) error
+	// DeleteSeries removes the series, leaving its books in none, or
+	// returns ErrNotFound.
+	DeleteSeries(ctx context.Context, id int) error
+	// ListSeriesBooks returns the volumes of the series, the books after
+	// selects, by position, then by book id, or ErrNotFound if there is no
+	// such series.
+	ListSeriesBooks(ctx context.Context, seriesId int, after ChangedAfter) ([]SeriesBook, error)
+	// SetBookSeries puts the book in the series at position, taking it
+	// out of any other. Both must exist.
+	SetBookSeries(ctx context.Context, bookId, seriesId int, position float64) error
+	// RemoveBookFromSeries takes the book out of the series, or returns
+	// ErrNotFound if it isn't in it.
+	RemoveBookFromSeries(ctx context.Context, bookId, seriesId int) error
+}
+
+// WorkStore holds the works the books in the catalog are editions of. A
+// book is an edition of at most one work. Works are returned with their
+// editions, ordered by id.
+type WorkStore interface {
+	// ListWorks returns the works after selects, ordered by title.
+	ListWorks(ctx context.Context, after ChangedAfter) ([]Work, error)
+	// GetWork returns the work, or ErrNotFound. Inside a transaction it
+	// stays locked until it ends.
+	GetWork(ctx context.Context, id int) (Work, error)
+	// SearchWorks returns the works whose title or author, or an
+	// edition's title or author, contains query, ignoring case, ordered
+	// by id.
+	SearchWorks(ctx context.Context, query string) ([]Work, error)
+	// CreateWork stores the work, without editions, and sets its Id.
+	CreateWork(ctx context.Context, work *Work) error
+	// UpdateWork saves the Title, Author and UpdatedAt of the work, or
+	// returns ErrNotFound.
+	UpdateWork(ctx context.Context, work Work) error
+	// DeleteWork removes the work, leaving its editions ungrouped, or
+	// returns ErrNotFound.
+	DeleteWork(ctx context.Context, id int) error
+	// SetBookWork makes the book an edition of the work, taking it from
+	// any other. It returns ErrNotFound if either doesn't exist.
+	SetBookWork(ctx context.Context, bookId, workId int) error
+	// RemoveBookFromWork ungroups the book from the work, or returns
+	// ErrNotFound if it isn't an edition of it.
+	RemoveBookFromWork(ctx context.Context, bookId, workId int) error
+}
+
+// Create a book, trying again if a concurrent create takes its slug.
+func createBook(ctx context.Context, s BookStore, book *Book) error {
+	var err error
+	for range maxSlugAttempts {
+		if err = s.CreateBook(ctx, book); !errors.Is(err, errSlugTaken) {
+			break
+		}
+	}
+	return err
+}
+
+// Update a book after checking, in the same transaction, that it exists.
+// Only the non-empty fields of book are changed.
+func updateExistingBook(ctx context.Context, s BookStore, id int, book Book) (Book, error) {
+	var updated Book
+	var err error
+	for range maxSlugAttempts {
+		err = s.WithTx(ctx, func(tx BookStore) error {
+			if _, err := tx.GetBook(ctx, id); err != nil {
+				return err
+			}
+
+			var err error
+			updated, err = tx.UpdateBook(ctx, id, book)
+			return err
+		})
+		if !errors.Is(err, errSlugTaken) {
+			break
+		}
+	}
+	return updated, err
+}
+
+// Open the store selected by the scheme of the database URL.
+func openStore(cfg Config) (BookStore, error) {
+	if strings.HasPrefix(cfg.DatabaseURL, "memory://") {
+		return newMemoryStore(), nil
+	}
+
+	d, dsn, err := parseDatabaseURL(cfg.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var replicaDSNs []string
+	for _, url := range cfg.ReplicaURLs {
+		rd, rdsn, err := parseDatabaseURL(url)
+		if err != nil {
+			return nil, err
+		}
+		if d.name == "sqlite" {
+			return nil, errors.New("read replicas are not supported with sqlite")
+		}
+		if rd.name != d.name {
+			return nil, fmt.Errorf("replica %q must be a %s server like the primary", url, d.name)
+		}
+		replicaDSNs = append(replicaDSNs, rdsn)
+	}
+
+	s, err := openSQLStore(d, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if len(replicaDSNs) > 0 {
+		s.replicas = openReplicas(d, replicaDSNs, cfg.ReplicaCheckInterval.Duration)
+	}
+	return s, nil
+}
+
+// Split a SQL database URL into its dialect and driver DSN.
+// A URL without a scheme is treated as a plain MySQL DSN.
+func parseDatabaseURL(databaseURL string) (dialect, string, error) {
+	scheme, rest, found := strings.Cut(databaseURL, "://")
+	if !found {
+		dsn, err := mysqlDSN(databaseURL)
+		return mysqlDialect, dsn, err
+	}
+
+	switch scheme {
+	case "mysql":
+		dsn, err := mysqlDSN(rest)
+		return mysqlDialect, dsn, err
+	case "postgres", "postgresql":
+		return postgresDialect, databaseURL, nil
+	case "sqlite":
+		return sqliteDialect, sqliteDSN(rest), nil
+	default:
+		return dialect{}, "", fmt.Errorf("unsupported database scheme %q", scheme)
+	}
+}
+
+// Make the MySQL driver return DATETIME columns as time.Time, in UTC.
+func mysqlDSN(dsn string) (string, error) {
+	c, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	c.ParseTime = true
+	c.Loc = time.UTC
+	return c.FormatDSN(), nil
+}
+
+// Turn the path of a sqlite:// URL into a driver DSN. The database file is
+// created on first use; WAL mode and a busy timeout let concurrent requests
+// share it, and transactions take the write lock when they begin. Times are
+// written in SQLite's own format so they sort and work with its date
+// functions.
+func sqliteDSN(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + "_busy_timeout=5000&_journal_mode=WAL&_foreign_keys=1&_txlock=immediate"
+}
+
+// GoalStore holds readers' yearly reading goals, without their progress.
+type GoalStore interface {
+	// ListGoals returns the user's goals after selects, ordered by year.
+	ListGoals(ctx context.Context, userId int, after ChangedAfter) ([]ReadingGoal, error)
+	// GetGoal returns the user's goal for the year, or ErrNotFound.
+	GetGoal(ctx context.Context, userId, year int) (ReadingGoal, error)
+	// SetGoal saves goal as its user's goal for its year, replacing any
+	// they had and then setting goal.CreatedAt to when that was first set.
+	SetGoal(ctx context.Context, goal *ReadingGoal) error
+	// DeleteGoal removes the user's goal for the year, or returns
+	// ErrNotFound.
+	DeleteGoal(ctx context.Context, userId, year int) error
+}
+
+// SavedSearchStore holds the searches readers saved to be told of new
+// books matching them. Saved searches belong to one user; another's are as
+// good as missing.
+type SavedSearchStore interface {
+	// ListSavedSearches returns the user's saved searches after selects,
+	// oldest first.
+	ListSavedSearches(ctx context.Context, userId int, after ChangedAfter) ([]SavedSearch, error)
+	// ListAllSavedSearches returns every user's saved searches, by id.
+	ListAllSavedSearches(ctx context.Context) ([]SavedSearch, error)
+	// CreateSavedSearch stores the saved search and sets its Id.
+	CreateSavedSearch(ctx context.Context, search *SavedSearch) error
+	// DeleteSavedSearch deletes the user's saved search, or returns
+	// ErrNotFound.
+	DeleteSavedSearch(ctx context.Context, userId, id int) error
+	// SetSavedSearchSeen records the newest book checked against the
+	// saved search and, unless notifiedAt is nil, when its user was last
+	// told of matches, which updates the search. It returns ErrNotFound if
+	// there is no such search.
+	SetSavedSearchSeen(ctx context.Context, id, seenBookId int, notifiedAt *time.Time) error
+}
+
+// NoteStore holds readers' private notes and highlights. Notes belong to
+// one user; another's are as good as missing.
+type NoteStore interface {
+	// ListNotes returns the user's notes selected by query, with their
+	// books, newest first.
+	ListNotes(ctx context.Context, userId int, query NoteQuery) ([]Note, error)
+	// GetNote returns the user's note, or ErrNotFound. Inside a
+	// transaction it stays locked until it ends.
+	GetNote(ctx context.Context, userId, id int) (Note, error)
+	// CreateNote stores the note and sets its Id.
+	CreateNote(ctx context.Context, note *Note) error
+	// UpdateNote saves the Kind, Text, Page and UpdatedAt of the note, or
+	// returns ErrNotFound.
+	UpdateNote(ctx context.Context, note Note) error
+	// DeleteNote removes the user's note, or returns ErrNotFound.
+	DeleteNote(ctx context.Context, userId, id int) error
+}
+
+// QuoteStore holds the quotes readers save from books. Quotes belong to one
+// user, who alone can change them; public ones anyone can read.
+type QuoteStore interface {
+	// ListQuotes returns the user's quotes after selects, with their
+	// books, newest first.
+	ListQuotes(ctx context.Context, userId int, after ChangedAfter) ([]Quote, error)
+	// ListBookQuotes returns the public quotes from the book after
+	// selects, newest first, or ErrNotFound if there is no such book.
+	ListBookQuotes(ctx context.Context, bookId int, after ChangedAfter) ([]Quote, error)
+	// RandomQuote returns one of the public quotes, with its book, or
+	// ErrNotFound if none is public.
+	RandomQuote(ctx context.Context) (Quote, error)
+	// GetQuote returns the user's quote, or ErrNotFound. Inside a
+	// transaction it stays locked until it ends.
+	GetQuote(ctx context.Context, userId, id int) (Quote, error)
+	// CreateQuote stores the quote and sets its Id.
+	CreateQuote(ctx context.Context, quote *Quote) error
+	// UpdateQuote saves the Text, Author, Public and UpdatedAt of the
+	// quote, or returns ErrNotFound.
+	UpdateQuote(ctx context.Context, quote Quote) error
+	// DeleteQuote removes the user's quote, or returns ErrNotFound.
+	DeleteQuote(ctx context.Context, userId, id int) error
+}
+
+// MemberStore holds the library's members.
+type MemberStore interface {
+	// ListMembers returns the members after selects, ordered by name.
+	ListMembers(ctx context.Context, after ChangedAfter) ([]Member, error)
+	// GetMember returns the member, or ErrNotFound. Inside a transaction
+	// it stays locked until it ends.
+	GetMember(ctx context.Context, id int) (Member, error)
+	// GetMemberByCard returns the member with the card number, or
+	// ErrNotFound. Inside a transaction it stays locked until it ends.
+	GetMemberByCard(ctx context.Context, cardNumber string) (Member, error)
+	// CreateMember stores the member and sets its Id. It returns
+	// ErrDuplicateCardNumber if another member has its card number.
+	CreateMember(ctx context.Context, member *Member) error
+	// UpdateMember saves every field of the member but its CreatedAt, or
+	// returns ErrNotFound, or ErrDuplicateCardNumber if another member has
+	// its card number.
+	UpdateMember(ctx context.Context, member Member) error
+	// DeleteMember removes the member and their holds, or returns
+	// ErrNotFound, or ErrMemberInUse, changing nothing, if they have books
+	// on loan or fines outstanding. Their loans, fines and payments stay
+	// on record, the member's details erased as EraseUser does.
+	DeleteMember(ctx context.Context, id int) error
+	// EncryptMembers encrypts the contact details of every tenant's
+	// members with the current pii key where they aren't already.
+	EncryptMembers(ctx context.Context) error
+}
+
+// LoanStore holds the books lent to the library's members.
+type LoanStore interface {
+	// ListMemberLoans returns the loans of the member after selects,
+	// returned or not, with their books, newest first.
+	ListMemberLoans(ctx context.Context, memberId int, after ChangedAfter) ([]Loan, error)
+	// GetLoan returns the loan, or ErrNotFound. Inside a transaction it
+	// stays locked until it ends.
+	GetLoan(ctx context.Context, id int) (Loan, error)
+	// CreateLoan stores the loan and sets its Id.
+	CreateLoan(ctx context.Context, loan *Loan) error
+	// ReturnLoan records the loan as returned, and updated, at the time
+	// given, or returns ErrNotFound.
+	ReturnLoan(ctx context.Context, id int, returnedAt time.Time) error
+}
+
+// CopyStore holds the library's copies of its books.
+type CopyStore interface {
+	// ListCopies returns the book's copies after selects, each with its
+	// open loan if it is out, in the order they were added, or ErrNotFound
+	// if there is no such book.
+	ListCopies(ctx context.Context, bookId int, after ChangedAfter) ([]Copy, error)
+	// GetCopy returns the copy with its open loan, or ErrNotFound. Inside
+	// a transaction it stays locked until it ends.
+	GetCopy(ctx context.Context, id int) (Copy, error)
+	// GetCopyByBarcode returns the copy with the barcode and its open
+	// loan, or ErrNotFound. Inside a transaction it stays locked until it
+	// ends.
+	GetCopyByBarcode(ctx context.Context, barcode string) (Copy, error)
+	// CreateCopy stores the copy and sets its Id. It returns
+	// ErrDuplicateBarcode if another copy has its barcode.
+	CreateCopy(ctx context.Context, copy *Copy) error
+	// DeleteCopy removes the copy, keeping its loans, or returns
+	// ErrNotFound.
+	DeleteCopy(ctx context.Context, id int) error
+	// SetCopyBranch moves the copy to the branch, or to none if branchId
+	// is nil, as updated at updatedAt, or returns ErrNotFound.
+	SetCopyBranch(ctx context.Context, id int, branchId *int, updatedAt time.Time) error
+}
+
+// BranchStore holds the library's branches and where they are.
+type BranchStore interface {
+	// ListBranches returns the branches after selects, by name.
+	ListBranches(ctx context.Context, after ChangedAfter) ([]Branch, error)
+	// GetBranch returns the branch, or ErrNotFound.
+	GetBranch(ctx context.Context, id int) (Branch, error)
+	// CreateBranch stores the branch and sets its Id.
+	CreateBranch(ctx context.Context, branch *Branch) error
+	// UpdateBranch replaces the details of the branch with branch.Id, or
+	// returns ErrNotFound.
+	UpdateBranch(ctx context.Context, branch Branch) error
+	// DeleteBranch removes the branch, leaving its copies at none as
+	// updated now, or returns ErrNotFound.
+	DeleteBranch(ctx context.Context, id int) error
+}
+
+// HoldStore holds the members' places in the queues for books.
+type HoldStore interface {
+	// ListHolds returns the holds on the book after selects in the order
+	// they are served, oldest first, or ErrNotFound if there is no such
+	// book. Inside a transaction they stay locked until it ends.
+	ListHolds(ctx context.Context, bookId int, after ChangedAfter) ([]Hold, error)
+	// CreateHold stores the hold and sets its Id, or returns ErrNotFound
+	// if there is no such book.
+	CreateHold(ctx context.Context, hold *Hold) error
+	// SetHoldReady records that a copy was set aside for the hold, which
+	// updates it, at the time given, or returns ErrNotFound.
+	SetHoldReady(ctx context.Context, id int, readyAt time.Time) error
+	// DeleteHold removes the hold, or returns ErrNotFound.
+	DeleteHold(ctx context.Context, id int) error
+}
+
+// FineStore holds the fines members owe and their payments.
+type FineStore interface {
+	// ListMemberFines returns the member's fines after selects, settled or
+	// not, newest first, or ErrNotFound if there is no such member.
+	ListMemberFines(ctx context.Context, memberId int, after ChangedAfter) ([]Fine, error)
+	// ListUnsettledFines returns every fine not yet settled, oldest first.
+	ListUnsettledFines(ctx context.Context) ([]Fine, error)
+	// GetFine returns the fine, or ErrNotFound. Inside a transaction it
+	// stays locked until it ends.
+	GetFine(ctx context.Context, id int) (Fine, error)
+	// CreateFine stores the fine and sets its Id.
+	CreateFine(ctx context.Context, fine *Fine) error
+	// SettleFine records the fine as settled, and updated, at the time
+	// given, or returns ErrNotFound.
+	SettleFine(ctx context.Context, id int, settledAt time.Time) error
+	// GetFinePayment returns the payment, or ErrNotFound.
+	GetFinePayment(ctx context.Context, id int) (FinePayment, error)
+	// CreateFinePayment stores the payment, updating its fine at
+	// payment.PaidAt, and sets its Id.
+	CreateFinePayment(ctx context.Context, payment *FinePayment) error
+}
+
+// DonationStore holds the books given to the library.
+type DonationStore interface {
+	// ListDonations returns the donations query selects, oldest first.
+	ListDonations(ctx context.Context, query DonationQuery) ([]Donation, error)
+	// GetDonation returns the donation, or ErrNotFound. Inside a
+	// transaction it stays locked until it ends.
+	GetDonation(ctx context.Context, id int) (Donation, error)
+	// CreateDonation stores the donation and sets its Id.
+	CreateDonation(ctx context.Context, donation *Donation) error
+	// DecideDonation records the donation's Status, Note, BookId,
+	// DecidedAt and UpdatedAt, or returns ErrNotFound.
+	DecideDonation(ctx context.Context, donation Donation) error
+}
+
+// SupplierStore holds the suppliers books are ordered from.
+type SupplierStore interface {
+	// ListSuppliers returns the suppliers after selects, by name.
+	ListSuppliers(ctx context.Context, after ChangedAfter) ([]Supplier, error)
+	// GetSupplier returns the supplier, or ErrNotFound.
+	GetSupplier(ctx context.Context, id int) (Supplier, error)
+	// CreateSupplier stores the supplier and sets its Id.
+	CreateSupplier(ctx context.Context, supplier *Supplier) error
+	// UpdateSupplier replaces the details of the supplier with
+	// supplier.Id, or returns ErrNotFound.
+	UpdateSupplier(ctx context.Context, supplier Supplier) error
+	// DeleteSupplier removes the supplier and its prices, or returns
+	// ErrNotFound.
+	DeleteSupplier(ctx context.Context, id int) error
+	// ListSupplierPrices returns the prices query selects, cheapest first
+	// and then quickest to deliver.
+	ListSupplierPrices(ctx context.Context, query SupplierPriceQuery) ([]SupplierPrice, error)
+	// SetSupplierPrice stores what the supplier charges for the book,
+	// replacing any price it had and then setting price.CreatedAt to when
+	// it first had one.
+	SetSupplierPrice(ctx context.Context, price *SupplierPrice) error
+	// DeleteSupplierPrice removes what the supplier charges for the book,
+	// or returns ErrNotFound if it has no price for it.
+	DeleteSupplierPrice(ctx context.Context, supplierId, bookId int) error
+}
+
+// PurchaseOrderStore holds the orders placed with suppliers.
+type PurchaseOrderStore interface {
+	// ListPurchaseOrders returns the purchase orders query selects, with
+	// their lines, oldest first.
+	ListPurchaseOrders(ctx context.Context, query PurchaseOrderQuery) ([]PurchaseOrder, error)
+	// GetPurchaseOrder returns the purchase order with its lines, or
+	// ErrNotFound. Inside a transaction it stays locked until it ends.
+	GetPurchaseOrder(ctx context.Context, id int) (PurchaseOrder, error)
+	// CreatePurchaseOrder stores the order and its lines and sets their
+	// Ids.
+	CreatePurchaseOrder(ctx context.Context, order *PurchaseOrder) error
+	// ReceiveOrderLine adds quantity to the copies received for the line
+	// and records its order as updated at receivedAt, or returns
+	// ErrNotFound.
+	ReceiveOrderLine(ctx context.Context, lineId, quantity int, receivedAt time.Time) error
+	// ClosePurchaseOrder records the order as received or cancelled, and
+	// updated, at the time given, or returns ErrNotFound.
+	ClosePurchaseOrder(ctx context.Context, id int, status string, closedAt time.Time) error
+}
+
+// InventoryStore holds how many copies of each book are in stock.
+type InventoryStore interface {
+	// GetBookStock returns how many copies of the book are in stock, or
+	// ErrNotFound if there is no such book.
+	GetBookStock(ctx context.Context, bookId int) (int, error)
+	// ListBookStock returns how many copies of each of the books are in
+	// stock, by book id, leaving out those that don't exist.
+	ListBookStock(ctx context.Context, bookIds []int) (map[int]int, error)
+	// AddBookStock adds quantity, which may be negative, to the book's
+	// stock, or returns ErrNotFound if there is no such book.
+	AddBookStock(ctx context.Context, bookId, quantity int) error
+}
+
+// SaleStore holds the sales of books from stock.
+type SaleStore interface {
+	// GetSale returns the sale with its lines, or ErrNotFound. Inside a
+	// transaction it stays locked until it ends.
+	GetSale(ctx context.Context, id int) (Sale, error)
+	// ListUserSales returns the sales to the user, or to their email
+	// address, with their lines, oldest first.
+	ListUserSales(ctx context.Context, userId int, email string) ([]Sale, error)
+	// CreateSale stores the sale and its lines, setting their Ids and
+	// giving the sale the tenant's next invoice number.
+	CreateSale(ctx context.Context, sale *Sale) error
+	// ShipSale records the sale as shipped, and updated, at the time
+	// given, by carrier under trackingNumber, or returns ErrNotFound.
+	ShipSale(ctx context.Context, id int, carrier, trackingNumber string, shippedAt time.Time) error
+	// SalesByDay totals the sales made from one time to before another
+	// by the day they were made on, for the days with sales, in order.
+	SalesByDay(ctx context.Context, from, to time.Time) ([]SalesTotal, error)
+	// SalesByCategory totals the sales made from one time to before
+	// another by the categories of the books sold, with those of books
+	// in none under the empty category.
+	SalesByCategory(ctx context.Context, from, to time.Time) ([]SalesTotal, error)
+	// TopSellingBooks returns up to limit books with the most copies
+	// sold from one time to before another, most first.
+	TopSellingBooks(ctx context.Context, from, to time.Time, limit int) ([]BookSales, error)
+}
+
+// GiftCardStore holds the gift cards issued and what they paid for.
+type GiftCardStore interface {
+	// GetGiftCard returns the gift card with code, with its redemptions,
+	// or ErrNotFound. Inside a transaction it stays locked until it ends.
+	GetGiftCard(ctx context.Context, code string) (GiftCard, error)
+	// CreateGiftCard stores the gift card and sets its Id.
+	CreateGiftCard(ctx context.Context, card *GiftCard) error
+	// VoidGiftCard records the gift card as voided, and updated, at the
+	// time given, or returns ErrNotFound.
+	VoidGiftCard(ctx context.Context, id int, voidedAt time.Time) error
+	// RedeemGiftCard records what the gift card paid towards a sale and
+	// takes it off its balance, updating the card at RedeemedAt, or
+	// returns ErrNotFound.
+	RedeemGiftCard(ctx context.Context, redemption GiftCardRedemption) error
+}
+
+// LoyaltyStore holds the loyalty points users earn and redeem on sales.
+type LoyaltyStore interface {
+	// LoyaltyBalance returns the points the user has to redeem, those
+	// earned and not yet redeemed or expired at now.
+	LoyaltyBalance(ctx context.Context, userId int, now time.Time) (int, error)
+	// ListLoyaltyEntries returns up to limit of the user's entries after
+	// selects, newest first.
+	ListLoyaltyEntries(ctx context.Context, userId int, limit int, after ChangedAfter) ([]LoyaltyEntry, error)
+	// AddLoyaltyPoints stores points earned, with all of them remaining,
+	// and sets the entry's Id.
+	AddLoyaltyPoints(ctx context.Context, entry *LoyaltyEntry) error
+	// RedeemLoyaltyPoints takes points off the user's oldest unexpired
+	// points and records them as redeemed on the sale at now, or returns
+	// ErrNotEnoughPoints.
+	RedeemLoyaltyPoints(ctx context.Context, userId, points, saleId int, now time.Time) error
+	// ExpireLoyaltyPoints records the points remaining of those expired
+	// at now as expired and returns how many there were.
+	ExpireLoyaltyPoints(ctx context.Context, now time.Time) (int, error)
+}
+
+// SubjectStore holds the categories and tags of books.
+type SubjectStore interface {
+	// GetBookSubjects returns the book's categories and tags, sorted, or
+	// ErrNotFound if there is no such book.
+	GetBookSubjects(ctx context.Context, bookId int) (BookSubjects, error)
+	// SetBookSubjects replaces the book's categories and tags, or returns
+	// ErrNotFound if there is no such book.
+	SetBookSubjects(ctx context.Context, bookId int, subjects BookSubjects) error
+	// BookCategories returns the categories of every book that has some,
+	// by book id.
+	BookCategories(ctx context.Context) (map[int][]string, error)
+	// SimilarBooks returns up to limit books sharing the book's author
+	// (ignoring case) or some of its categories or tags, scored as
+	// SimilarBook describes, best first. It returns ErrNotFound if there
+	// is no such book.
+	SimilarBooks(ctx context.Context, bookId, limit int) ([]SimilarBook, error)
+}
+
+// ActivityStore holds daily counts of what happens to books.
+type ActivityStore interface {
+	// AddBookActivity adds the counts to the books' days, skipping books
+	// that no longer exist.
+	AddBookActivity(ctx context.Context, activities []BookActivity) error
+	// TrendingBooks returns up to limit books with the most activity of
+	// kind (ActivityView, ActivityLoan or ActivitySale) on days from since,
+	// most first.
+	TrendingBooks(ctx context.Context, kind string, since time.Time, limit int) ([]TrendingBook, error)
+	// ArchiveBooks archives, as of now, the books neither changed nor
+	// viewed, lent or sold since before, and brings back archived books
+	// that have been. Books from before changes were recorded are never
+	// archived. It returns the ids of the books archived and brought
+	// back.
+	ArchiveBooks(ctx context.Context, before, now time.Time) (archived, restored []int, err error)
+}
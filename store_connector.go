@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"net/url"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// secretConnector makes connections to the database at dsn with the
+// database password the secrets manager last gave, if it gave one, so a
+// rotated password is used from the next connection on. Connections
+// already made keep going.
+type secretConnector struct {
+	driver  driver.Driver
+	dialect dialect
+	dsn     string
+}
+
+// Open a pool of connections to the database at dsn through a
+// secretConnector.
+func openDB(d dialect, dsn string) (*sql.DB, error) {
+	// Opening doesn't connect; it only finds the driver.
+	db, err := sql.Open(d.driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return sql.OpenDB(secretConnector{driver: db.Driver(), dialect: d, dsn: dsn}), nil
+}
+
+func (c secretConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	dsn := c.dsn
+	if password := secrets.get("database_password", ""); password != "" {
+		var err error
+		if dsn, err = dsnWithPassword(c.dialect, dsn, password); err != nil {
+			return nil, err
+		}
+	}
+	if d, ok := c.driver.(driver.DriverContext); ok {
+		connector, err := d.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return connector.Connect(ctx)
+	}
+	return c.driver.Open(dsn)
+}
+
+func (c secretConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// dsn with its password replaced by password. SQLite has no passwords.
+func dsnWithPassword(d dialect, dsn, password string) (string, error) {
+	switch d.name {
+	case "mysql":
+		c, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return "", err
+		}
+		c.Passwd = password
+		return c.FormatDSN(), nil
+	case "postgres":
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", err
+		}
+		u.User = url.UserPassword(u.User.Username(), password)
+		return u.String(), nil
+	default:
+		return dsn, nil
+	}
+}
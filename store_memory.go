@@ -0,0 +1,898 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryStore implements BookStore with an in-process map. Nothing is
+// persisted, which makes it handy for demos, frontend work and tests.
+type memoryStore struct {
+	mu    *sync.RWMutex
+	state *memoryState
+	// inTx is set on the store handed to WithTx callbacks, which already
+	// hold the write lock.
+	inTx bool
+}
+
+// memoryState is the state guarded by memoryStore.mu: the tenants and
+// each one's data.
+type memoryState struct {
+	tenants      map[int]Tenant
+	nextTenantId int
+	data         map[int]*memoryData
+	// Outbox ids are shared by every tenant, as in the SQL stores, so
+	// the relay and resuming clients see one sequence.
+	nextOutboxId int64
+}
+
+// memoryData is the data of one tenant.
+type memoryData struct {
+	books  map[int]Book
+	nextId int
+
+	reviews      map[int]Review
+	nextReviewId int
+
+	webhooks              map[int]Webhook
+	nextWebhookId         int
+	webhookDeliveries     map[int]WebhookDelivery
+	nextWebhookDeliveryId int
+	webhookJobs           map[int]WebhookJob
+	nextWebhookJobId      int
+
+	outbox []Event
+
+	idempotencyKeys map[idempotencyKey]IdempotencyRecord
+
+	searches     []SearchRecord
+	nextSearchId int
+
+	memberAccess       []MemberAccess
+	nextMemberAccessId int
+
+	notifications             map[int]Notification
+	nextNotificationId        int
+	notificationFailures      []NotificationFailure
+	nextNotificationFailureId int
+	notificationPreferences   map[notificationPreference]NotificationPreference
+	notificationPhones        map[string]string
+
+	pushSubscriptions      map[int]PushSubscription
+	nextPushSubscriptionId int
+	pushWatches            map[pushWatch]float64
+
+	users               map[int]memoryUser
+	nextUserId          int
+	shelfItems          map[shelfKey]ShelfItem
+	wishlistItems       map[shelfKey]WishlistItem
+	readingProgress     map[shelfKey][]ReadingProgress
+	goals               map[goalKey]ReadingGoal
+	notes               map[int]Note
+	nextNoteId          int
+	quotes              map[int]Quote
+	nextQuoteId         int
+	savedSearches       map[int]SavedSearch
+	nextSavedSearchId   int
+	members             map[int]Member
+	nextMemberId        int
+	loans               map[int]Loan
+	nextLoanId          int
+	copies              map[int]Copy
+	nextCopyId          int
+	branches            map[int]Branch
+	nextBranchId        int
+	holds               map[int]Hold
+	nextHoldId          int
+	fines               map[int]Fine
+	nextFineId          int
+	finePayments        map[int]FinePayment
+	nextFinePaymentId   int
+	donations           map[int]Donation
+	nextDonationId      int
+	suppliers           map[int]Supplier
+	nextSupplierId      int
+	supplierPrices      map[supplierPriceKey]SupplierPrice
+	purchaseOrders      map[int]PurchaseOrder
+	nextPurchaseOrderId int
+	nextOrderLineId     int
+	bookStock           map[int]int
+	sales               map[int]Sale
+	nextSaleId          int
+	nextSaleLineId      int
+	lastInvoiceNumber   int
+	giftCards           map[int]GiftCard
+	nextGiftCardId      int
+	loyaltyEntries      map[int]LoyaltyEntry
+	nextLoyaltyEntryId  int
+	recommendations     []Recommendation
+
+	bookSubjects map[int]BookSubjects
+	bookPrices   map[int][]BookPrice
+	bookMerges   map[int]int
+	bookActivity map[activityKey]BookActivity
+
+	collections      map[int]memoryCollection
+	nextCollectionId int
+
+	series       map[int]Series
+	nextSeriesId int
+	seriesBooks  map[int]seriesMembership
+
+	works      map[int]Work
+	nextWorkId int
+	// editions maps the id of each book grouped under a work to the
+	// work's id.
+	editions map[int]int
+}
+
+// Copy a tenant's data, for memoryState.clone.
+func (d *memoryData) clone() *memoryData {
+	return &memoryData{
+		books:        maps.Clone(d.books),
+		nextId:       d.nextId,
+		reviews:      maps.Clone(d.reviews),
+		nextReviewId: d.nextReviewId,
+
+		webhooks:              maps.Clone(d.webhooks),
+		nextWebhookId:         d.nextWebhookId,
+		webhookDeliveries:     maps.Clone(d.webhookDeliveries),
+		nextWebhookDeliveryId: d.nextWebhookDeliveryId,
+		webhookJobs:           maps.Clone(d.webhookJobs),
+		nextWebhookJobId:      d.nextWebhookJobId,
+
+		outbox: slices.Clone(d.outbox),
+
+		idempotencyKeys: maps.Clone(d.idempotencyKeys),
+
+		searches:     slices.Clone(d.searches),
+		nextSearchId: d.nextSearchId,
+
+		memberAccess:       slices.Clone(d.memberAccess),
+		nextMemberAccessId: d.nextMemberAccessId,
+
+		notifications:             maps.Clone(d.notifications),
+		nextNotificationId:        d.nextNotificationId,
+		notificationFailures:      slices.Clone(d.notificationFailures),
+		nextNotificationFailureId: d.nextNotificationFailureId,
+		notificationPreferences:   maps.Clone(d.notificationPreferences),
+		notificationPhones:        maps.Clone(d.notificationPhones),
+
+		pushSubscriptions:      maps.Clone(d.pushSubscriptions),
+		nextPushSubscriptionId: d.nextPushSubscriptionId,
+		pushWatches:            maps.Clone(d.pushWatches),
+
+		users:               maps.Clone(d.users),
+		nextUserId:          d.nextUserId,
+		shelfItems:          maps.Clone(d.shelfItems),
+		wishlistItems:       maps.Clone(d.wishlistItems),
+		readingProgress:     maps.Clone(d.readingProgress),
+		goals:               maps.Clone(d.goals),
+		notes:               maps.Clone(d.notes),
+		nextNoteId:          d.nextNoteId,
+		quotes:              maps.Clone(d.quotes),
+		nextQuoteId:         d.nextQuoteId,
+		savedSearches:       maps.Clone(d.savedSearches),
+		nextSavedSearchId:   d.nextSavedSearchId,
+		members:             maps.Clone(d.members),
+		nextMemberId:        d.nextMemberId,
+		loans:               maps.Clone(d.loans),
+		nextLoanId:          d.nextLoanId,
+		copies:              maps.Clone(d.copies),
+		nextCopyId:          d.nextCopyId,
+		branches:            maps.Clone(d.branches),
+		nextBranchId:        d.nextBranchId,
+		holds:               maps.Clone(d.holds),
+		nextHoldId:          d.nextHoldId,
+		fines:               maps.Clone(d.fines),
+		nextFineId:          d.nextFineId,
+		finePayments:        maps.Clone(d.finePayments),
+		nextFinePaymentId:   d.nextFinePaymentId,
+		donations:           maps.Clone(d.donations),
+		nextDonationId:      d.nextDonationId,
+		suppliers:           maps.Clone(d.suppliers),
+		nextSupplierId:      d.nextSupplierId,
+		supplierPrices:      maps.Clone(d.supplierPrices),
+		purchaseOrders:      maps.Clone(d.purchaseOrders),
+		nextPurchaseOrderId: d.nextPurchaseOrderId,
+		nextOrderLineId:     d.nextOrderLineId,
+		bookStock:           maps.Clone(d.bookStock),
+		sales:               maps.Clone(d.sales),
+		nextSaleId:          d.nextSaleId,
+		nextSaleLineId:      d.nextSaleLineId,
+		giftCards:           maps.Clone(d.giftCards),
+		nextGiftCardId:      d.nextGiftCardId,
+		loyaltyEntries:      maps.Clone(d.loyaltyEntries),
+		nextLoyaltyEntryId:  d.nextLoyaltyEntryId,
+		lastInvoiceNumber:   d.lastInvoiceNumber,
+		recommendations:     slices.Clone(d.recommendations),
+
+		bookSubjects: maps.Clone(d.bookSubjects),
+		bookPrices:   maps.Clone(d.bookPrices),
+		bookMerges:   maps.Clone(d.bookMerges),
+		bookActivity: maps.Clone(d.bookActivity),
+
+		collections:      maps.Clone(d.collections),
+		nextCollectionId: d.nextCollectionId,
+
+		series:       maps.Clone(d.series),
+		nextSeriesId: d.nextSeriesId,
+		seriesBooks:  maps.Clone(d.seriesBooks),
+
+		works:      maps.Clone(d.works),
+		nextWorkId: d.nextWorkId,
+		editions:   maps.Clone(d.editions),
+	}
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		mu: &sync.RWMutex{},
+		state: &memoryState{
+			tenants:      map[int]Tenant{defaultTenant.Id: defaultTenant},
+			nextTenantId: defaultTenant.Id + 1,
+			data:         map[int]*memoryData{defaultTenant.Id: newMemoryData()},
+			nextOutboxId: 1,
+		},
+	}
+}
+
+func newMemoryData() *memoryData {
+	return &memoryData{
+		books:        make(map[int]Book),
+		nextId:       1,
+		reviews:      make(map[int]Review),
+		nextReviewId: 1,
+
+		webhooks:              make(map[int]Webhook),
+		nextWebhookId:         1,
+		webhookDeliveries:     make(map[int]WebhookDelivery),
+		nextWebhookDeliveryId: 1,
+		webhookJobs:           make(map[int]WebhookJob),
+		nextWebhookJobId:      1,
+
+		idempotencyKeys: make(map[idempotencyKey]IdempotencyRecord),
+
+		nextSearchId: 1,
+
+		nextMemberAccessId: 1,
+
+		notifications:             make(map[int]Notification),
+		nextNotificationId:        1,
+		nextNotificationFailureId: 1,
+		notificationPreferences:   make(map[notificationPreference]NotificationPreference),
+		notificationPhones:        make(map[string]string),
+
+		pushSubscriptions:      make(map[int]PushSubscription),
+		nextPushSubscriptionId: 1,
+		pushWatches:            make(map[pushWatch]float64),
+
+		users:               make(map[int]memoryUser),
+		nextUserId:          1,
+		shelfItems:          make(map[shelfKey]ShelfItem),
+		wishlistItems:       make(map[shelfKey]WishlistItem),
+		readingProgress:     make(map[shelfKey][]ReadingProgress),
+		goals:               make(map[goalKey]ReadingGoal),
+		notes:               make(map[int]Note),
+		nextNoteId:          1,
+		quotes:              make(map[int]Quote),
+		nextQuoteId:         1,
+		savedSearches:       make(map[int]SavedSearch),
+		nextSavedSearchId:   1,
+		members:             make(map[int]Member),
+		nextMemberId:        1,
+		loans:               make(map[int]Loan),
+		nextLoanId:          1,
+		copies:              make(map[int]Copy),
+		nextCopyId:          1,
+		branches:            make(map[int]Branch),
+		nextBranchId:        1,
+		holds:               make(map[int]Hold),
+		nextHoldId:          1,
+		fines:               make(map[int]Fine),
+		nextFineId:          1,
+		finePayments:        make(map[int]FinePayment),
+		nextFinePaymentId:   1,
+		donations:           make(map[int]Donation),
+		nextDonationId:      1,
+		suppliers:           make(map[int]Supplier),
+		nextSupplierId:      1,
+		supplierPrices:      make(map[supplierPriceKey]SupplierPrice),
+		purchaseOrders:      make(map[int]PurchaseOrder),
+		nextPurchaseOrderId: 1,
+		nextOrderLineId:     1,
+		bookStock:           make(map[int]int),
+		sales:               make(map[int]Sale),
+		giftCards:           make(map[int]GiftCard),
+		nextGiftCardId:      1,
+		loyaltyEntries:      make(map[int]LoyaltyEntry),
+		nextLoyaltyEntryId:  1,
+		nextSaleId:          1,
+		nextSaleLineId:      1,
+
+		bookSubjects: make(map[int]BookSubjects),
+		bookPrices:   make(map[int][]BookPrice),
+		bookMerges:   make(map[int]int),
+		bookActivity: make(map[activityKey]BookActivity),
+
+		collections:      make(map[int]memoryCollection),
+		nextCollectionId: 1,
+
+		series:       make(map[int]Series),
+		nextSeriesId: 1,
+		seriesBooks:  make(map[int]seriesMembership),
+
+		works:      make(map[int]Work),
+		nextWorkId: 1,
+		editions:   make(map[int]int),
+	}
+}
+
+// Copy the state so a failed transaction can be rolled back.
+func (st *memoryState) clone() *memoryState {
+	c := &memoryState{
+		tenants:      maps.Clone(st.tenants),
+		nextTenantId: st.nextTenantId,
+		data:         make(map[int]*memoryData, len(st.data)),
+		nextOutboxId: st.nextOutboxId,
+	}
+	for id, d := range st.data {
+		c.data[id] = d.clone()
+	}
+	return c
+}
+
+// The data of the tenant ctx acts for; the caller must hold the lock. A
+// tenant the store doesn't have has none, and writes to it are lost.
+func (s *memoryStore) data(ctx context.Context) *memoryData {
+	if d, ok := s.state.data[tenantId(ctx)]; ok {
+		return d
+	}
+	return newMemoryData()
+}
+
+// Take the read lock and return its release, unless a transaction holds the lock.
+func (s *memoryStore) rlock() func() {
+	if s.inTx {
+		return func() {}
+	}
+	s.mu.RLock()
+	return s.mu.RUnlock
+}
+
+// Take the write lock and return its release, unless a transaction holds the lock.
+func (s *memoryStore) lock() func() {
+	if s.inTx {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+func (s *memoryStore) WithTx(ctx context.Context, fn func(tx BookStore) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.state.clone()
+	if err := fn(&memoryStore{mu: s.mu, state: s.state, inTx: true}); err != nil {
+		*s.state = *snapshot
+		return err
+	}
+	return nil
+}
+
+func (s *memoryStore) ListBooks(ctx context.Context) ([]Book, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	books := make([]Book, 0, len(d.books))
+	for _, book := range d.books {
+		books = append(books, book)
+	}
+	sort.Slice(books, func(i, j int) bool { return books[i].Id < books[j].Id })
+	return books, nil
+}
+
+// Transactions hold the lock the whole time, so reading is enough.
+func (s *memoryStore) LockBooks(ctx context.Context) ([]Book, error) {
+	return s.ListBooks(ctx)
+}
+
+func (s *memoryStore) RecentBooks(ctx context.Context, by string, limit int) ([]Book, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	books := []Book{}
+	for _, book := range d.books {
+		if recentTime(book, by) != nil {
+			books = append(books, book)
+		}
+	}
+	sortRecent(books, by)
+	return books[:min(len(books), limit)], nil
+}
+
+func (s *memoryStore) SearchBooks(ctx context.Context, query string) ([]Book, error) {
+	books, _ := s.ListBooks(ctx)
+	unlock := s.rlock()
+	books = s.data(ctx).dropMerged(books)
+	unlock()
+	query = strings.ToLower(query)
+
+	matches := []Book{}
+	for _, book := range books {
+		if strings.Contains(strings.ToLower(book.Title), query) || strings.Contains(strings.ToLower(book.Author), query) {
+			matches = append(matches, book)
+		}
+	}
+	return matches, nil
+}
+
+func (s *memoryStore) StreamBooks(ctx context.Context, query BookQuery, fn func(Book) error) error {
+	if err := validBookFields(query.Fields); err != nil {
+		return err
+	}
+
+	// Call fn on a copy so a slow consumer doesn't hold the lock.
+	var books []Book
+	if query.Search == "" {
+		books, _ = s.ListBooks(ctx)
+		unlock := s.rlock()
+		books = s.data(ctx).dropMerged(books)
+		unlock()
+	} else {
+		books, _ = s.SearchBooks(ctx, query.Search)
+	}
+	if len(query.Formats) > 0 {
+		books = slices.DeleteFunc(books, func(book Book) bool { return !inFormats(book, query.Formats) })
+	}
+	if query.Filter != nil {
+		unlock := s.rlock()
+		subjects := maps.Clone(s.data(ctx).bookSubjects)
+		unlock()
+		books = slices.DeleteFunc(books, func(book Book) bool { return !query.Filter.matches(book, subjects[book.Id]) })
+	}
+	if !query.CreatedAfter.IsZero() {
+		books = slices.DeleteFunc(books, func(book Book) bool { return book.CreatedAt == nil || !book.CreatedAt.After(query.CreatedAfter) })
+	}
+	if !query.UpdatedAfter.IsZero() {
+		books = slices.DeleteFunc(books, func(book Book) bool { return book.UpdatedAt == nil || !book.UpdatedAt.After(query.UpdatedAfter) })
+	}
+	if query.ExcludeArchived {
+		books = slices.DeleteFunc(books, archived)
+	}
+	books = books[min(query.Offset, len(books)):]
+	if query.Limit > 0 {
+		books = books[:min(query.Limit, len(books))]
+	}
+	for _, book := range books {
+		if err := fn(book.only(query.Fields)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) GetBook(ctx context.Context, id int) (Book, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	book, ok := d.books[id]
+	if !ok {
+		return Book{}, ErrNotFound
+	}
+	return book, nil
+}
+
+func (s *memoryStore) GetBookFields(ctx context.Context, id int, fields []string) (Book, error) {
+	if err := validBookFields(fields); err != nil {
+		return Book{}, err
+	}
+	book, err := s.GetBook(ctx, id)
+	if err != nil {
+		return Book{}, err
+	}
+	return book.only(fields), nil
+}
+
+func (s *memoryStore) CreateBook(ctx context.Context, book *Book) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if d.isbnTaken(book.ISBN, 0) {
+		return ErrDuplicateISBN
+	}
+	book.Slug = d.freeSlug(slugify(book.Title), 0)
+	createdAt := time.Now().UTC().Truncate(time.Microsecond)
+	book.CreatedAt, book.UpdatedAt, book.ArchivedAt = &createdAt, &createdAt, nil
+	book.Id = d.nextId
+	d.nextId++
+	d.books[book.Id] = *book
+	return nil
+}
+
+func (s *memoryStore) UpdateBook(ctx context.Context, id int, book Book) (Book, error) {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	existing, ok := d.books[id]
+	if !ok {
+		return Book{}, ErrNotFound
+	}
+
+	// Only update non-empty fields.
+	if book.Title != "" {
+		existing.Title = book.Title
+		existing.Slug = d.freeSlug(slugify(book.Title), id)
+	}
+	if book.Author != "" {
+		existing.Author = book.Author
+	}
+	if book.Price != 0 {
+		existing.Price = book.Price
+	}
+	if book.ISBN != "" {
+		if d.isbnTaken(book.ISBN, id) {
+			return Book{}, ErrDuplicateISBN
+		}
+		existing.ISBN = book.ISBN
+	}
+	if book.Publisher != "" {
+		existing.Publisher = book.Publisher
+	}
+	if book.Format != "" {
+		existing.Format = book.Format
+	}
+	if book.Description != "" {
+		existing.Description = book.Description
+	}
+	if book.CoverURL != "" {
+		existing.CoverURL = book.CoverURL
+	}
+	// Like the SQL stores, count any field given as a change, even to the
+	// value it had.
+	if book.Title != "" || book.Author != "" || book.Price != 0 || book.ISBN != "" || book.Publisher != "" || book.Format != "" || book.Description != "" || book.CoverURL != "" {
+		updatedAt := time.Now().UTC().Truncate(time.Microsecond)
+		existing.UpdatedAt = &updatedAt
+	}
+
+	d.books[id] = existing
+	return existing, nil
+}
+
+// Report whether a book other than the one with id except has isbn, as
+// the unique index in the SQL stores would.
+func (d *memoryData) isbnTaken(isbn string, except int) bool {
+	if isbn == "" {
+		return false
+	}
+	for id, book := range d.books {
+		if id != except && book.ISBN == isbn {
+			return true
+		}
+	}
+	return false
+}
+
+// The first unused slug starting with base, counting the one the book
+// with id except has as unused.
+func (d *memoryData) freeSlug(base string, except int) string {
+	taken := map[string]bool{}
+	for id, book := range d.books {
+		if id != except {
+			taken[book.Slug] = true
+		}
+	}
+	return firstFreeSlug(base, taken)
+}
+
+func (s *memoryStore) GetBookIdBySlug(ctx context.Context, slug string) (int, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	for id, book := range d.books {
+		if book.Slug == slug {
+			return id, nil
+		}
+	}
+	return 0, ErrNotFound
+}
+
+func (s *memoryStore) GetBookIdByISBN(ctx context.Context, isbn string) (int, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	found := 0
+	for id, book := range d.books {
+		if normalized, ok := normalizeISBN(book.ISBN); ok && normalized == isbn && (found == 0 || id < found) {
+			found = id
+		}
+	}
+	if found == 0 {
+		return 0, ErrNotFound
+	}
+	return found, nil
+}
+
+func (s *memoryStore) SetPrices(ctx context.Context, prices map[int]float64) ([]Book, error) {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	for id := range prices {
+		if _, ok := d.books[id]; !ok {
+			return nil, ErrNotFound
+		}
+	}
+	var books []Book
+	updatedAt := time.Now().UTC().Truncate(time.Microsecond)
+	for _, id := range slices.Sorted(maps.Keys(prices)) {
+		book := d.books[id]
+		book.Price, book.UpdatedAt = prices[id], &updatedAt
+		d.books[id] = book
+		books = append(books, book)
+	}
+	return books, nil
+}
+
+func (s *memoryStore) DeleteBook(ctx context.Context, id int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.books[id]; !ok {
+		return ErrNotFound
+	}
+	delete(d.books, id)
+	for reviewId, review := range d.reviews {
+		if review.BookId == id {
+			delete(d.reviews, reviewId)
+		}
+	}
+	d.cascadeDeletedBooks()
+	return nil
+}
+
+func (s *memoryStore) DeleteAllBooks(ctx context.Context) (int64, error) {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	n := int64(len(d.books))
+	d.books = make(map[int]Book)
+	d.reviews = make(map[int]Review)
+	d.cascadeDeletedBooks()
+	return n, nil
+}
+
+func (s *memoryStore) RestoreCatalog(ctx context.Context, books []Book, reviews []Review) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	restored := make(map[int]Book, len(books))
+	for _, book := range books {
+		restored[book.Id] = book
+	}
+	for _, id := range d.referencedBooks() {
+		if _, ok := restored[id]; !ok {
+			return ErrBooksInUse
+		}
+	}
+
+	d.books = restored
+	d.reviews = make(map[int]Review, len(reviews))
+	for _, review := range reviews {
+		// Reviews stay tied to the accounts that still exist.
+		if review.UserId != nil {
+			if _, ok := d.users[*review.UserId]; !ok {
+				review.UserId = nil
+			}
+		}
+		d.reviews[review.Id] = review
+		d.nextReviewId = max(d.nextReviewId, review.Id+1)
+	}
+	for _, book := range books {
+		d.nextId = max(d.nextId, book.Id+1)
+	}
+	d.cascadeDeletedBooks()
+	return nil
+}
+
+// Drop what belonged to the books that no longer exist: their places on
+// shelves and wishlists, their subjects, their price history, the merges
+// into them and their activity, as the foreign keys do in the SQL stores.
+// List the ids of the books that something other than their own history
+// refers to, which RestoreCatalog won't delete. Ids may repeat.
+func (d *memoryData) referencedBooks() []int {
+	var ids []int
+	for _, loan := range d.loans {
+		ids = append(ids, loan.BookId)
+	}
+	for _, copy := range d.copies {
+		ids = append(ids, copy.BookId)
+	}
+	for _, hold := range d.holds {
+		ids = append(ids, hold.BookId)
+	}
+	for key := range d.supplierPrices {
+		ids = append(ids, key.bookId)
+	}
+	for key := range d.shelfItems {
+		ids = append(ids, key.bookId)
+	}
+	for key := range d.readingProgress {
+		ids = append(ids, key.bookId)
+	}
+	for key := range d.wishlistItems {
+		ids = append(ids, key.bookId)
+	}
+	for key := range d.pushWatches {
+		ids = append(ids, key.bookId)
+	}
+	for _, note := range d.notes {
+		ids = append(ids, note.BookId)
+	}
+	for _, quote := range d.quotes {
+		ids = append(ids, quote.BookId)
+	}
+	for _, c := range d.collections {
+		ids = append(ids, c.bookIds...)
+	}
+	for id := range d.seriesBooks {
+		ids = append(ids, id)
+	}
+	for id := range d.editions {
+		ids = append(ids, id)
+	}
+	for _, survivorId := range d.bookMerges {
+		ids = append(ids, survivorId)
+	}
+	return ids
+}
+
+func (d *memoryData) cascadeDeletedBooks() {
+	for key := range d.shelfItems {
+		if _, ok := d.books[key.bookId]; !ok {
+			delete(d.shelfItems, key)
+			delete(d.readingProgress, key)
+		}
+	}
+	for key := range d.wishlistItems {
+		if _, ok := d.books[key.bookId]; !ok {
+			delete(d.wishlistItems, key)
+		}
+	}
+	for id := range d.bookSubjects {
+		if _, ok := d.books[id]; !ok {
+			delete(d.bookSubjects, id)
+		}
+	}
+	for id := range d.bookPrices {
+		if _, ok := d.books[id]; !ok {
+			delete(d.bookPrices, id)
+		}
+	}
+	for id, survivorId := range d.bookMerges {
+		if _, ok := d.books[survivorId]; !ok {
+			delete(d.bookMerges, id)
+		}
+	}
+	for id, note := range d.notes {
+		if _, ok := d.books[note.BookId]; !ok {
+			delete(d.notes, id)
+		}
+	}
+	for id, quote := range d.quotes {
+		if _, ok := d.books[quote.BookId]; !ok {
+			delete(d.quotes, id)
+		}
+	}
+	for id, loan := range d.loans {
+		if _, ok := d.books[loan.BookId]; !ok {
+			delete(d.loans, id)
+		}
+	}
+	for id, fine := range d.fines {
+		if fine.LoanId != nil {
+			if _, ok := d.loans[*fine.LoanId]; !ok {
+				fine.LoanId = nil
+				d.fines[id] = fine
+			}
+		}
+	}
+	for id, donation := range d.donations {
+		if donation.BookId != nil {
+			if _, ok := d.books[*donation.BookId]; !ok {
+				donation.BookId = nil
+				d.donations[id] = donation
+			}
+		}
+	}
+	for id, order := range d.purchaseOrders {
+		var lines []OrderLine
+		for i, line := range order.Lines {
+			if _, ok := d.books[line.BookId]; ok || line.BookId == 0 {
+				continue
+			}
+			if lines == nil {
+				lines = slices.Clone(order.Lines)
+			}
+			lines[i].BookId = 0
+		}
+		if lines != nil {
+			order.Lines = lines
+			d.purchaseOrders[id] = order
+		}
+	}
+	for key := range d.supplierPrices {
+		if _, ok := d.books[key.bookId]; !ok {
+			delete(d.supplierPrices, key)
+		}
+	}
+	for id := range d.bookStock {
+		if _, ok := d.books[id]; !ok {
+			delete(d.bookStock, id)
+		}
+	}
+	for id, sale := range d.sales {
+		var lines []SaleLine
+		for i, line := range sale.Lines {
+			if _, ok := d.books[line.BookId]; ok || line.BookId == 0 {
+				continue
+			}
+			if lines == nil {
+				lines = slices.Clone(sale.Lines)
+			}
+			lines[i].BookId = 0
+		}
+		if lines != nil {
+			sale.Lines = lines
+			d.sales[id] = sale
+		}
+	}
+	for id, copy := range d.copies {
+		if _, ok := d.books[copy.BookId]; !ok {
+			delete(d.copies, id)
+		}
+	}
+	for id, hold := range d.holds {
+		if _, ok := d.books[hold.BookId]; !ok {
+			delete(d.holds, id)
+		}
+	}
+	for key := range d.bookActivity {
+		if _, ok := d.books[key.bookId]; !ok {
+			delete(d.bookActivity, key)
+		}
+	}
+	for id, c := range d.collections {
+		kept := make([]int, 0, len(c.bookIds))
+		for _, bookId := range c.bookIds {
+			if _, ok := d.books[bookId]; ok {
+				kept = append(kept, bookId)
+			}
+		}
+		c.bookIds = kept
+		d.collections[id] = c
+	}
+	for id := range d.seriesBooks {
+		if _, ok := d.books[id]; !ok {
+			delete(d.seriesBooks, id)
+		}
+	}
+	for id := range d.editions {
+		if _, ok := d.books[id]; !ok {
+			delete(d.editions, id)
+		}
+	}
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sort"
+)
+
+func (s *memoryStore) RecordMemberAccess(ctx context.Context, access *MemberAccess) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	access.Id = d.nextMemberAccessId
+	d.nextMemberAccessId++
+	d.memberAccess = append(d.memberAccess, *access)
+	return nil
+}
+
+func (s *memoryStore) ListMemberAccess(ctx context.Context, query MemberAccessQuery) ([]MemberAccess, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	accesses := []MemberAccess{}
+	for _, access := range slices.Backward(d.memberAccess) {
+		switch {
+		case query.MemberId != 0 && access.MemberId != nil && *access.MemberId != query.MemberId:
+		case query.Actor != "" && access.Actor != query.Actor:
+		case !query.Since.IsZero() && access.AccessedAt.Before(query.Since):
+		case !query.Until.IsZero() && !access.AccessedAt.Before(query.Until):
+		case !query.keeps(access):
+		default:
+			accesses = append(accesses, access)
+		}
+	}
+	sort.SliceStable(accesses, func(i, j int) bool {
+		return accesses[i].AccessedAt.After(accesses[j].AccessedAt)
+	})
+	return accesses[:min(query.Limit, len(accesses))], nil
+}
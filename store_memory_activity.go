@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+)
+
+func (s *memoryStore) AddBookActivity(ctx context.Context, activities []BookActivity) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	for _, a := range activities {
+		if _, ok := d.books[a.BookId]; !ok {
+			continue
+		}
+		key := activityKey{tenantId(ctx), a.BookId, a.Day}
+		b := d.bookActivity[key]
+		a.Views, a.Loans, a.Sales = a.Views+b.Views, a.Loans+b.Loans, a.Sales+b.Sales
+		d.bookActivity[key] = a
+	}
+	return nil
+}
+
+func (s *memoryStore) TrendingBooks(ctx context.Context, kind string, since time.Time, limit int) ([]TrendingBook, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	totals := map[int]*TrendingBook{}
+	for key, a := range d.bookActivity {
+		if _, merged := d.bookMerges[key.bookId]; merged || key.day.Before(since) {
+			continue
+		}
+		tb, ok := totals[key.bookId]
+		if !ok {
+			tb = &TrendingBook{Book: d.books[key.bookId]}
+			totals[key.bookId] = tb
+		}
+		tb.Views += a.Views
+		tb.Loans += a.Loans
+		tb.Sales += a.Sales
+	}
+
+	count := func(tb TrendingBook) int {
+		switch kind {
+		case ActivityLoan:
+			return tb.Loans
+		case ActivitySale:
+			return tb.Sales
+		}
+		return tb.Views
+	}
+	trending := []TrendingBook{}
+	for _, tb := range totals {
+		if count(*tb) > 0 {
+			trending = append(trending, *tb)
+		}
+	}
+	sort.Slice(trending, func(i, j int) bool {
+		if ci, cj := count(trending[i]), count(trending[j]); ci != cj {
+			return ci > cj
+		}
+		return trending[i].Book.Id < trending[j].Book.Id
+	})
+	return trending[:min(len(trending), limit)], nil
+}
+
+func (s *memoryStore) ArchiveBooks(ctx context.Context, before, now time.Time) (archived, restored []int, err error) {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	active := map[int]bool{}
+	for key := range d.bookActivity {
+		if !key.day.Before(before) {
+			active[key.bookId] = true
+		}
+	}
+	for id, book := range d.books {
+		changed := book.UpdatedAt
+		if changed == nil {
+			changed = book.CreatedAt
+		}
+		touched := active[id] || changed != nil && !changed.Before(before)
+		_, merged := d.bookMerges[id]
+		switch {
+		case book.ArchivedAt != nil && touched && !merged:
+			book.ArchivedAt = nil
+			restored = append(restored, id)
+		case book.ArchivedAt == nil && !touched && changed != nil:
+			archivedAt := now
+			book.ArchivedAt = &archivedAt
+			archived = append(archived, id)
+		default:
+			continue
+		}
+		d.books[id] = book
+	}
+	slices.Sort(archived)
+	slices.Sort(restored)
+	return archived, restored, nil
+}
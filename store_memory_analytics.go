@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+)
+
+// The most searches the memory store keeps of a tenant, dropping the
+// oldest, as it keeps them all in memory.
+const maxMemorySearches = 10000
+
+func (s *memoryStore) RecordSearches(ctx context.Context, searches []SearchRecord) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	for _, search := range searches {
+		search.Id = d.nextSearchId
+		d.nextSearchId++
+		d.searches = append(d.searches, search)
+	}
+	if over := len(d.searches) - maxMemorySearches; over > 0 {
+		d.searches = slices.Delete(d.searches, 0, over)
+	}
+	return nil
+}
+
+func (s *memoryStore) SearchAnalytics(ctx context.Context, since time.Time, limit int) (SearchAnalytics, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	analytics := SearchAnalytics{Since: since}
+	var all, zero []SearchRecord
+	for _, search := range d.searches {
+		if search.CreatedAt.Before(since) {
+			continue
+		}
+		all = append(all, search)
+		if search.Results == 0 {
+			zero = append(zero, search)
+		}
+	}
+	analytics.TotalSearches, analytics.ZeroResultSearches = len(all), len(zero)
+	analytics.Visitors = countVisitors(all)
+	analytics.TopTerms = searchTermStats(all, limit)
+	analytics.ZeroResultTerms = searchTermStats(zero, limit)
+	return analytics, nil
+}
+
+func (s *memoryStore) DeleteSearchesBefore(ctx context.Context, before time.Time) (int64, error) {
+	defer s.lock()()
+
+	var n int64
+	for _, d := range s.state.data {
+		kept := len(d.searches)
+		d.searches = slices.DeleteFunc(d.searches, func(search SearchRecord) bool { return search.CreatedAt.Before(before) })
+		n += int64(kept - len(d.searches))
+	}
+	return n, nil
+}
+
+// The number of different visitors who made searches, leaving out those
+// from before they were recorded.
+func countVisitors(searches []SearchRecord) int {
+	visitors := map[string]bool{}
+	for _, search := range searches {
+		if search.Visitor != "" {
+			visitors[search.Visitor] = true
+		}
+	}
+	return len(visitors)
+}
+
+// Group searches by term, most searched first, and keep the first limit.
+func searchTermStats(searches []SearchRecord, limit int) []SearchTermStats {
+	byTerm := map[string]*SearchTermStats{}
+	visitors := map[string][]SearchRecord{}
+	terms := []SearchTermStats{}
+	for _, search := range searches {
+		term := byTerm[search.Term]
+		if term == nil {
+			term = &SearchTermStats{Term: search.Term}
+			byTerm[search.Term] = term
+		}
+		term.Searches++
+		visitors[search.Term] = append(visitors[search.Term], search)
+		term.AverageResults += float64(search.Results)
+		term.AverageLatencyMs += float64(search.Latency.Microseconds()) / 1000
+	}
+	for _, term := range byTerm {
+		term.AverageResults /= float64(term.Searches)
+		term.AverageLatencyMs /= float64(term.Searches)
+		term.Visitors = countVisitors(visitors[term.Term])
+		terms = append(terms, *term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].Searches != terms[j].Searches {
+			return terms[i].Searches > terms[j].Searches
+		}
+		return terms[i].Term < terms[j].Term
+	})
+	return terms[:min(limit, len(terms))]
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+func (s *memoryStore) ListBranches(ctx context.Context, after ChangedAfter) ([]Branch, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	branches := make([]Branch, 0, len(d.branches))
+	for _, branch := range d.branches {
+		if after.keeps(branch) {
+			branches = append(branches, branch)
+		}
+	}
+	sort.Slice(branches, func(i, j int) bool {
+		if branches[i].Name != branches[j].Name {
+			return branches[i].Name < branches[j].Name
+		}
+		return branches[i].Id < branches[j].Id
+	})
+	return branches, nil
+}
+
+func (s *memoryStore) GetBranch(ctx context.Context, id int) (Branch, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	branch, ok := d.branches[id]
+	if !ok {
+		return Branch{}, ErrNotFound
+	}
+	return branch, nil
+}
+
+func (s *memoryStore) CreateBranch(ctx context.Context, branch *Branch) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	branch.Id = d.nextBranchId
+	d.nextBranchId++
+	d.branches[branch.Id] = *branch
+	return nil
+}
+
+func (s *memoryStore) UpdateBranch(ctx context.Context, branch Branch) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.branches[branch.Id]; !ok {
+		return ErrNotFound
+	}
+	d.branches[branch.Id] = branch
+	return nil
+}
+
+func (s *memoryStore) DeleteBranch(ctx context.Context, id int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.branches[id]; !ok {
+		return ErrNotFound
+	}
+	delete(d.branches, id)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	for copyId, copy := range d.copies {
+		if copy.BranchId != nil && *copy.BranchId == id {
+			copy.BranchId, copy.UpdatedAt = nil, now
+			d.copies[copyId] = copy
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"sort"
+)
+
+// memoryCollection is a collection as memoryData keeps it, with the ids
+// of its books in order. The slice is replaced, never changed, so clones
+// of the data can share it.
+type memoryCollection struct {
+	Collection
+	bookIds []int
+}
+
+// The collection with its books loaded; the caller must hold the lock.
+func (d *memoryData) loadCollection(c memoryCollection) Collection {
+	collection := c.Collection
+	collection.Books = make([]Book, 0, len(c.bookIds))
+	for _, id := range c.bookIds {
+		collection.Books = append(collection.Books, d.books[id])
+	}
+	return collection
+}
+
+func (s *memoryStore) ListCollections(ctx context.Context, userId int, after ChangedAfter) ([]Collection, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	collections := []Collection{}
+	for _, c := range d.collections {
+		if c.UserId == userId && after.keeps(c) {
+			collections = append(collections, d.loadCollection(c))
+		}
+	}
+	sort.Slice(collections, func(i, j int) bool { return collections[i].Id < collections[j].Id })
+	return collections, nil
+}
+
+func (s *memoryStore) GetCollection(ctx context.Context, userId, id int) (Collection, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	c, ok := d.collections[id]
+	if !ok || c.UserId != userId {
+		return Collection{}, ErrNotFound
+	}
+	return d.loadCollection(c), nil
+}
+
+func (s *memoryStore) GetSharedCollection(ctx context.Context, token string) (Collection, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	for _, c := range d.collections {
+		if token != "" && c.ShareToken == token {
+			return d.loadCollection(c), nil
+		}
+	}
+	return Collection{}, ErrNotFound
+}
+
+func (s *memoryStore) CreateCollection(ctx context.Context, collection *Collection) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	collection.Id = d.nextCollectionId
+	d.nextCollectionId++
+	stored := *collection
+	stored.Books = nil
+	d.collections[collection.Id] = memoryCollection{Collection: stored}
+	return nil
+}
+
+func (s *memoryStore) UpdateCollection(ctx context.Context, collection Collection) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	c, ok := d.collections[collection.Id]
+	if !ok || c.UserId != collection.UserId {
+		return ErrNotFound
+	}
+	c.Name, c.ShareToken, c.UpdatedAt = collection.Name, collection.ShareToken, collection.UpdatedAt
+	c.bookIds = make([]int, len(collection.Books))
+	for i, book := range collection.Books {
+		c.bookIds[i] = book.Id
+	}
+	d.collections[collection.Id] = c
+	return nil
+}
+
+func (s *memoryStore) DeleteCollection(ctx context.Context, userId, id int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if c, ok := d.collections[id]; !ok || c.UserId != userId {
+		return ErrNotFound
+	}
+	delete(d.collections, id)
+	return nil
+}
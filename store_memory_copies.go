@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+func (s *memoryStore) ListCopies(ctx context.Context, bookId int, after ChangedAfter) ([]Copy, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	if _, ok := d.books[bookId]; !ok {
+		return nil, ErrNotFound
+	}
+	copies := []Copy{}
+	for _, copy := range d.copies {
+		if copy.BookId == bookId && after.keeps(copy) {
+			copies = append(copies, d.withOpenLoan(copy))
+		}
+	}
+	sort.Slice(copies, func(i, j int) bool { return copies[i].Id < copies[j].Id })
+	return copies, nil
+}
+
+func (s *memoryStore) GetCopy(ctx context.Context, id int) (Copy, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	copy, ok := d.copies[id]
+	if !ok {
+		return Copy{}, ErrNotFound
+	}
+	return d.withOpenLoan(copy), nil
+}
+
+func (s *memoryStore) GetCopyByBarcode(ctx context.Context, barcode string) (Copy, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	for _, copy := range d.copies {
+		if copy.Barcode == barcode {
+			return d.withOpenLoan(copy), nil
+		}
+	}
+	return Copy{}, ErrNotFound
+}
+
+func (s *memoryStore) CreateCopy(ctx context.Context, copy *Copy) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	for _, other := range d.copies {
+		if other.Barcode == copy.Barcode {
+			return ErrDuplicateBarcode
+		}
+	}
+	copy.Id = d.nextCopyId
+	d.nextCopyId++
+	d.copies[copy.Id] = *copy
+	return nil
+}
+
+func (s *memoryStore) DeleteCopy(ctx context.Context, id int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.copies[id]; !ok {
+		return ErrNotFound
+	}
+	delete(d.copies, id)
+	for loanId, loan := range d.loans {
+		if loan.CopyId != nil && *loan.CopyId == id {
+			loan.CopyId = nil
+			d.loans[loanId] = loan
+		}
+	}
+	return nil
+}
+
+// The copy with its open loan, if it is out.
+func (d *memoryData) withOpenLoan(copy Copy) Copy {
+	for _, loan := range d.loans {
+		if loan.CopyId != nil && *loan.CopyId == copy.Id && loan.ReturnedAt == nil {
+			copy.Loan = &loan
+			break
+		}
+	}
+	return copy
+}
+
+func (s *memoryStore) SetCopyBranch(ctx context.Context, id int, branchId *int, updatedAt time.Time) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	copy, ok := d.copies[id]
+	if !ok {
+		return ErrNotFound
+	}
+	copy.BranchId, copy.UpdatedAt = branchId, updatedAt
+	d.copies[id] = copy
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"sort"
+)
+
+func (s *memoryStore) ListDonations(ctx context.Context, query DonationQuery) ([]Donation, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	donations := []Donation{}
+	for _, donation := range d.donations {
+		switch {
+		case query.Status != "" && donation.Status != query.Status:
+		case !query.ReceivedFrom.IsZero() && donation.ReceivedAt.Before(query.ReceivedFrom):
+		case !query.ReceivedBefore.IsZero() && !donation.ReceivedAt.Before(query.ReceivedBefore):
+		case !query.keeps(donation):
+		default:
+			donations = append(donations, donation)
+		}
+	}
+	sort.Slice(donations, func(i, j int) bool {
+		if !donations[i].ReceivedAt.Equal(donations[j].ReceivedAt) {
+			return donations[i].ReceivedAt.Before(donations[j].ReceivedAt)
+		}
+		return donations[i].Id < donations[j].Id
+	})
+	return donations, nil
+}
+
+func (s *memoryStore) GetDonation(ctx context.Context, id int) (Donation, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	donation, ok := d.donations[id]
+	if !ok {
+		return Donation{}, ErrNotFound
+	}
+	return donation, nil
+}
+
+func (s *memoryStore) CreateDonation(ctx context.Context, donation *Donation) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	donation.Id = d.nextDonationId
+	d.nextDonationId++
+	d.donations[donation.Id] = *donation
+	return nil
+}
+
+func (s *memoryStore) DecideDonation(ctx context.Context, donation Donation) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	stored, ok := d.donations[donation.Id]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Status, stored.Note, stored.BookId, stored.DecidedAt = donation.Status, donation.Note, donation.BookId, donation.DecidedAt
+	stored.UpdatedAt = donation.UpdatedAt
+	d.donations[donation.Id] = stored
+	return nil
+}
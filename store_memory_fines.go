@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+func (s *memoryStore) ListMemberFines(ctx context.Context, memberId int, after ChangedAfter) ([]Fine, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	if _, ok := d.members[memberId]; !ok {
+		return nil, ErrNotFound
+	}
+	fines := d.finesWhere(func(f Fine) bool { return f.MemberId == memberId && after.keeps(f) })
+	sort.Slice(fines, func(i, j int) bool {
+		if !fines[i].CreatedAt.Equal(fines[j].CreatedAt) {
+			return fines[i].CreatedAt.After(fines[j].CreatedAt)
+		}
+		return fines[i].Id > fines[j].Id
+	})
+	return fines, nil
+}
+
+func (s *memoryStore) ListUnsettledFines(ctx context.Context) ([]Fine, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	fines := d.finesWhere(func(f Fine) bool { return f.SettledAt == nil })
+	sort.Slice(fines, func(i, j int) bool {
+		if !fines[i].CreatedAt.Equal(fines[j].CreatedAt) {
+			return fines[i].CreatedAt.Before(fines[j].CreatedAt)
+		}
+		return fines[i].Id < fines[j].Id
+	})
+	return fines, nil
+}
+
+func (s *memoryStore) GetFine(ctx context.Context, id int) (Fine, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	fine, ok := d.fines[id]
+	if !ok {
+		return Fine{}, ErrNotFound
+	}
+	return d.withPaid(fine), nil
+}
+
+func (s *memoryStore) CreateFine(ctx context.Context, fine *Fine) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	fine.Id = d.nextFineId
+	d.nextFineId++
+	stored := *fine
+	stored.Paid = 0
+	d.fines[fine.Id] = stored
+	return nil
+}
+
+func (s *memoryStore) SettleFine(ctx context.Context, id int, settledAt time.Time) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	fine, ok := d.fines[id]
+	if !ok {
+		return ErrNotFound
+	}
+	fine.SettledAt, fine.UpdatedAt = &settledAt, settledAt
+	d.fines[id] = fine
+	return nil
+}
+
+func (s *memoryStore) GetFinePayment(ctx context.Context, id int) (FinePayment, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	payment, ok := d.finePayments[id]
+	if !ok {
+		return FinePayment{}, ErrNotFound
+	}
+	return payment, nil
+}
+
+func (s *memoryStore) CreateFinePayment(ctx context.Context, payment *FinePayment) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	payment.Id = d.nextFinePaymentId
+	d.nextFinePaymentId++
+	d.finePayments[payment.Id] = *payment
+	if fine, ok := d.fines[payment.FineId]; ok {
+		fine.UpdatedAt = payment.PaidAt
+		d.fines[payment.FineId] = fine
+	}
+	return nil
+}
+
+// The fines keep selects, with what was paid towards them.
+func (d *memoryData) finesWhere(keep func(Fine) bool) []Fine {
+	fines := []Fine{}
+	for _, fine := range d.fines {
+		if keep(fine) {
+			fines = append(fines, d.withPaid(fine))
+		}
+	}
+	return fines
+}
+
+// The fine with what was paid towards it.
+func (d *memoryData) withPaid(fine Fine) Fine {
+	for _, payment := range d.finePayments {
+		if payment.FineId == fine.Id {
+			fine.Paid += payment.Amount
+		}
+	}
+	fine.Paid = roundCents(fine.Paid)
+	return fine
+}
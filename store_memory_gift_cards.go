@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+func (s *memoryStore) GetGiftCard(ctx context.Context, code string) (GiftCard, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	for _, card := range d.giftCards {
+		if card.Code == code {
+			card.Redemptions = slices.Clone(card.Redemptions)
+			return card, nil
+		}
+	}
+	return GiftCard{}, ErrNotFound
+}
+
+func (s *memoryStore) CreateGiftCard(ctx context.Context, card *GiftCard) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	card.Id = d.nextGiftCardId
+	d.nextGiftCardId++
+	stored := *card
+	stored.Redemptions = []GiftCardRedemption{}
+	d.giftCards[card.Id] = stored
+	return nil
+}
+
+func (s *memoryStore) VoidGiftCard(ctx context.Context, id int, voidedAt time.Time) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	card, ok := d.giftCards[id]
+	if !ok {
+		return ErrNotFound
+	}
+	card.VoidedAt, card.UpdatedAt = &voidedAt, voidedAt
+	d.giftCards[id] = card
+	return nil
+}
+
+func (s *memoryStore) RedeemGiftCard(ctx context.Context, redemption GiftCardRedemption) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	card, ok := d.giftCards[redemption.GiftCardId]
+	if !ok {
+		return ErrNotFound
+	}
+	card.Balance, card.UpdatedAt = roundCents(card.Balance-redemption.Amount), redemption.RedeemedAt
+	card.Redemptions = append(slices.Clone(card.Redemptions), redemption)
+	d.giftCards[card.Id] = card
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"sort"
+)
+
+// goalKey keys memoryData.goals.
+type goalKey struct {
+	userId int
+	year   int
+}
+
+func (s *memoryStore) ListGoals(ctx context.Context, userId int, after ChangedAfter) ([]ReadingGoal, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	goals := []ReadingGoal{}
+	for key, goal := range d.goals {
+		if key.userId == userId && after.keeps(goal) {
+			goals = append(goals, goal)
+		}
+	}
+	sort.Slice(goals, func(i, j int) bool { return goals[i].Year < goals[j].Year })
+	return goals, nil
+}
+
+func (s *memoryStore) GetGoal(ctx context.Context, userId, year int) (ReadingGoal, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	goal, ok := d.goals[goalKey{userId, year}]
+	if !ok {
+		return ReadingGoal{}, ErrNotFound
+	}
+	return goal, nil
+}
+
+func (s *memoryStore) SetGoal(ctx context.Context, goal *ReadingGoal) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	key := goalKey{goal.UserId, goal.Year}
+	if existing, ok := d.goals[key]; ok {
+		goal.CreatedAt = existing.CreatedAt
+	}
+	stored := *goal
+	stored.Progress = nil
+	d.goals[key] = stored
+	return nil
+}
+
+func (s *memoryStore) DeleteGoal(ctx context.Context, userId, year int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	key := goalKey{userId, year}
+	if _, ok := d.goals[key]; !ok {
+		return ErrNotFound
+	}
+	delete(d.goals, key)
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+func (s *memoryStore) ListHolds(ctx context.Context, bookId int, after ChangedAfter) ([]Hold, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	if _, ok := d.books[bookId]; !ok {
+		return nil, ErrNotFound
+	}
+	holds := []Hold{}
+	for _, hold := range d.holds {
+		if hold.BookId == bookId && after.keeps(hold) {
+			holds = append(holds, hold)
+		}
+	}
+	sort.Slice(holds, func(i, j int) bool {
+		if !holds[i].PlacedAt.Equal(holds[j].PlacedAt) {
+			return holds[i].PlacedAt.Before(holds[j].PlacedAt)
+		}
+		return holds[i].Id < holds[j].Id
+	})
+	return holds, nil
+}
+
+func (s *memoryStore) CreateHold(ctx context.Context, hold *Hold) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.books[hold.BookId]; !ok {
+		return ErrNotFound
+	}
+	hold.Id = d.nextHoldId
+	d.nextHoldId++
+	stored := *hold
+	stored.Member = nil
+	d.holds[hold.Id] = stored
+	return nil
+}
+
+func (s *memoryStore) SetHoldReady(ctx context.Context, id int, readyAt time.Time) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	hold, ok := d.holds[id]
+	if !ok {
+		return ErrNotFound
+	}
+	hold.ReadyAt, hold.UpdatedAt = &readyAt, readyAt
+	d.holds[id] = hold
+	return nil
+}
+
+func (s *memoryStore) DeleteHold(ctx context.Context, id int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.holds[id]; !ok {
+		return ErrNotFound
+	}
+	delete(d.holds, id)
+	return nil
+}
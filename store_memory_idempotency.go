@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+func (s *memoryStore) ReserveIdempotencyKey(ctx context.Context, record IdempotencyRecord, now time.Time) (IdempotencyRecord, bool, error) {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if existing, ok := d.idempotencyKeys[idempotencyKeyOf(record)]; ok && existing.ExpiresAt.After(now) {
+		return existing, false, nil
+	}
+	d.idempotencyKeys[idempotencyKeyOf(record)] = record
+	return record, true, nil
+}
+
+func (s *memoryStore) CompleteIdempotencyKey(ctx context.Context, record IdempotencyRecord) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if existing, ok := d.idempotencyKeys[idempotencyKeyOf(record)]; ok {
+		existing.Status = record.Status
+		existing.ContentType = record.ContentType
+		existing.Body = record.Body
+		existing.ExpiresAt = record.ExpiresAt
+		d.idempotencyKeys[idempotencyKeyOf(record)] = existing
+	}
+	return nil
+}
+
+func (s *memoryStore) DeleteIdempotencyKey(ctx context.Context, caller, key string) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	delete(d.idempotencyKeys, idempotencyKey{caller: caller, key: key})
+	return nil
+}
+
+// idempotencyKey identifies a record in memory: keys are the caller's.
+type idempotencyKey struct {
+	caller, key string
+}
+
+func idempotencyKeyOf(record IdempotencyRecord) idempotencyKey {
+	return idempotencyKey{caller: record.Caller, key: record.Key}
+}
+
+func (s *memoryStore) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	defer s.lock()()
+
+	var n int64
+	for _, d := range s.state.data {
+		for key, record := range d.idempotencyKeys {
+			if record.ExpiresAt.Before(now) {
+				delete(d.idempotencyKeys, key)
+				n++
+			}
+		}
+	}
+	return n, nil
+}
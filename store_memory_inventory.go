@@ -0,0 +1,37 @@
+package main
+
+import "context"
+
+func (s *memoryStore) GetBookStock(ctx context.Context, bookId int) (int, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	if _, ok := d.books[bookId]; !ok {
+		return 0, ErrNotFound
+	}
+	return d.bookStock[bookId], nil
+}
+
+func (s *memoryStore) AddBookStock(ctx context.Context, bookId, quantity int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.books[bookId]; !ok {
+		return ErrNotFound
+	}
+	d.bookStock[bookId] += quantity
+	return nil
+}
+
+func (s *memoryStore) ListBookStock(ctx context.Context, bookIds []int) (map[int]int, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	stock := make(map[int]int, len(bookIds))
+	for _, id := range bookIds {
+		if _, ok := d.books[id]; ok {
+			stock[id] = d.bookStock[id]
+		}
+	}
+	return stock, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+func (s *memoryStore) ListMemberLoans(ctx context.Context, memberId int, after ChangedAfter) ([]Loan, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	loans := []Loan{}
+	for _, loan := range d.loans {
+		if loan.MemberId == memberId && after.keeps(loan) {
+			book := d.books[loan.BookId]
+			loan.Book = &book
+			loans = append(loans, loan)
+		}
+	}
+	sort.Slice(loans, func(i, j int) bool {
+		if !loans[i].BorrowedAt.Equal(loans[j].BorrowedAt) {
+			return loans[i].BorrowedAt.After(loans[j].BorrowedAt)
+		}
+		return loans[i].Id > loans[j].Id
+	})
+	return loans, nil
+}
+
+func (s *memoryStore) GetLoan(ctx context.Context, id int) (Loan, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	loan, ok := d.loans[id]
+	if !ok {
+		return Loan{}, ErrNotFound
+	}
+	return loan, nil
+}
+
+func (s *memoryStore) CreateLoan(ctx context.Context, loan *Loan) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	loan.Id = d.nextLoanId
+	d.nextLoanId++
+	stored := *loan
+	stored.Book = nil
+	d.loans[loan.Id] = stored
+	return nil
+}
+
+func (s *memoryStore) ReturnLoan(ctx context.Context, id int, returnedAt time.Time) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	loan, ok := d.loans[id]
+	if !ok {
+		return ErrNotFound
+	}
+	loan.ReturnedAt, loan.UpdatedAt = &returnedAt, returnedAt
+	d.loans[id] = loan
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+)
+
+func (s *memoryStore) LoyaltyBalance(ctx context.Context, userId int, now time.Time) (int, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	balance := 0
+	for _, entry := range d.loyaltyEntries {
+		if entry.UserId == userId && entry.Kind == PointsEarned && (entry.ExpiresAt == nil || entry.ExpiresAt.After(now)) {
+			balance += entry.Remaining
+		}
+	}
+	return balance, nil
+}
+
+func (s *memoryStore) ListLoyaltyEntries(ctx context.Context, userId, limit int, after ChangedAfter) ([]LoyaltyEntry, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	entries := []LoyaltyEntry{}
+	for _, entry := range d.loyaltyEntries {
+		if entry.UserId == userId && after.keeps(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	slices.SortFunc(entries, func(a, b LoyaltyEntry) int { return cmp.Compare(b.Id, a.Id) })
+	return entries[:min(limit, len(entries))], nil
+}
+
+func (s *memoryStore) AddLoyaltyPoints(ctx context.Context, entry *LoyaltyEntry) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	entry.Id = d.nextLoyaltyEntryId
+	d.nextLoyaltyEntryId++
+	d.loyaltyEntries[entry.Id] = *entry
+	return nil
+}
+
+func (s *memoryStore) RedeemLoyaltyPoints(ctx context.Context, userId, points, saleId int, now time.Time) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	var earned []LoyaltyEntry
+	available := 0
+	for _, entry := range d.loyaltyEntries {
+		if entry.UserId == userId && entry.Kind == PointsEarned && entry.Remaining > 0 && (entry.ExpiresAt == nil || entry.ExpiresAt.After(now)) {
+			earned = append(earned, entry)
+			available += entry.Remaining
+		}
+	}
+	if available < points {
+		return ErrNotEnoughPoints
+	}
+	slices.SortFunc(earned, func(a, b LoyaltyEntry) int { return cmp.Compare(a.Id, b.Id) })
+
+	left := points
+	for _, entry := range earned {
+		if left == 0 {
+			break
+		}
+		taken := min(entry.Remaining, left)
+		entry.Remaining -= taken
+		left -= taken
+		d.loyaltyEntries[entry.Id] = entry
+	}
+
+	id := d.nextLoyaltyEntryId
+	d.nextLoyaltyEntryId++
+	d.loyaltyEntries[id] = LoyaltyEntry{Id: id, UserId: userId, Kind: PointsRedeemed, Points: -points, SaleId: saleId, CreatedAt: now}
+	return nil
+}
+
+func (s *memoryStore) ExpireLoyaltyPoints(ctx context.Context, now time.Time) (int, error) {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	var expired []LoyaltyEntry
+	for _, entry := range d.loyaltyEntries {
+		if entry.Kind == PointsEarned && entry.Remaining > 0 && entry.ExpiresAt != nil && !entry.ExpiresAt.After(now) {
+			expired = append(expired, entry)
+		}
+	}
+	slices.SortFunc(expired, func(a, b LoyaltyEntry) int { return cmp.Compare(a.Id, b.Id) })
+
+	total := 0
+	for _, entry := range expired {
+		id := d.nextLoyaltyEntryId
+		d.nextLoyaltyEntryId++
+		d.loyaltyEntries[id] = LoyaltyEntry{Id: id, UserId: entry.UserId, Kind: PointsExpired, Points: -entry.Remaining, CreatedAt: now}
+		total += entry.Remaining
+		entry.Remaining = 0
+		d.loyaltyEntries[entry.Id] = entry
+	}
+	return total, nil
+}
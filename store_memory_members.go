@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"sort"
+)
+
+func (s *memoryStore) ListMembers(ctx context.Context, after ChangedAfter) ([]Member, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	members := make([]Member, 0, len(d.members))
+	for _, member := range d.members {
+		if after.keeps(member) {
+			members = append(members, member)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Name != members[j].Name {
+			return members[i].Name < members[j].Name
+		}
+		return members[i].Id < members[j].Id
+	})
+	return members, nil
+}
+
+func (s *memoryStore) GetMember(ctx context.Context, id int) (Member, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	member, ok := d.members[id]
+	if !ok {
+		return Member{}, ErrNotFound
+	}
+	return member, nil
+}
+
+func (s *memoryStore) GetMemberByCard(ctx context.Context, cardNumber string) (Member, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	for _, member := range d.members {
+		if member.CardNumber == cardNumber {
+			return member, nil
+		}
+	}
+	return Member{}, ErrNotFound
+}
+
+func (s *memoryStore) CreateMember(ctx context.Context, member *Member) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if d.cardNumberTaken(member.CardNumber, 0) {
+		return ErrDuplicateCardNumber
+	}
+	member.Id = d.nextMemberId
+	d.nextMemberId++
+	d.members[member.Id] = *member
+	return nil
+}
+
+func (s *memoryStore) UpdateMember(ctx context.Context, member Member) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	stored, ok := d.members[member.Id]
+	if !ok {
+		return ErrNotFound
+	}
+	if d.cardNumberTaken(member.CardNumber, member.Id) {
+		return ErrDuplicateCardNumber
+	}
+	member.CreatedAt = stored.CreatedAt
+	d.members[member.Id] = member
+	return nil
+}
+
+func (s *memoryStore) DeleteMember(ctx context.Context, id int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.members[id]; !ok {
+		return ErrNotFound
+	}
+	for _, loan := range d.loans {
+		if loan.MemberId == id && loan.ReturnedAt == nil {
+			return ErrMemberInUse
+		}
+	}
+	for _, fine := range d.fines {
+		if fine.MemberId == id && fine.SettledAt == nil {
+			return ErrMemberInUse
+		}
+	}
+
+	// Loans and fines keep the id of the member, who is no more.
+	delete(d.members, id)
+	for holdId, hold := range d.holds {
+		if hold.MemberId == id {
+			delete(d.holds, holdId)
+		}
+	}
+	return nil
+}
+
+// Members in memory are never encrypted, as they never reach a database.
+func (s *memoryStore) EncryptMembers(ctx context.Context) error {
+	return nil
+}
+
+// Report whether a member other than the one with id except has the card
+// number.
+func (d *memoryData) cardNumberTaken(cardNumber string, except int) bool {
+	for _, member := range d.members {
+		if member.CardNumber == cardNumber && member.Id != except {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+func (s *memoryStore) MergeBook(ctx context.Context, duplicateId, survivorId int, mergedAt time.Time) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	for _, id := range []int{duplicateId, survivorId} {
+		if _, ok := d.books[id]; !ok {
+			return ErrNotFound
+		}
+		if _, merged := d.bookMerges[id]; merged {
+			return ErrNotFound
+		}
+	}
+
+	for id, review := range d.reviews {
+		if review.BookId == duplicateId {
+			review.BookId = survivorId
+			d.reviews[id] = review
+		}
+	}
+	for id, loan := range d.loans {
+		if loan.BookId == duplicateId {
+			loan.BookId = survivorId
+			d.loans[id] = loan
+		}
+	}
+	for id, copy := range d.copies {
+		if copy.BookId == duplicateId {
+			copy.BookId = survivorId
+			d.copies[id] = copy
+		}
+	}
+	for id, hold := range d.holds {
+		if hold.BookId == duplicateId {
+			hold.BookId = survivorId
+			d.holds[id] = hold
+		}
+	}
+	for id, note := range d.notes {
+		if note.BookId == duplicateId {
+			note.BookId = survivorId
+			d.notes[id] = note
+		}
+	}
+	for id, quote := range d.quotes {
+		if quote.BookId == duplicateId {
+			quote.BookId = survivorId
+			d.quotes[id] = quote
+		}
+	}
+
+	for id, order := range d.purchaseOrders {
+		if slices.ContainsFunc(order.Lines, func(l OrderLine) bool { return l.BookId == duplicateId }) {
+			order.Lines = slices.Clone(order.Lines)
+			for i := range order.Lines {
+				if order.Lines[i].BookId == duplicateId {
+					order.Lines[i].BookId = survivorId
+				}
+			}
+			d.purchaseOrders[id] = order
+		}
+	}
+	for id, sale := range d.sales {
+		if slices.ContainsFunc(sale.Lines, func(l SaleLine) bool { return l.BookId == duplicateId }) {
+			sale.Lines = slices.Clone(sale.Lines)
+			for i := range sale.Lines {
+				if sale.Lines[i].BookId == duplicateId {
+					sale.Lines[i].BookId = survivorId
+				}
+			}
+			d.sales[id] = sale
+		}
+	}
+	for id, donation := range d.donations {
+		if donation.BookId != nil && *donation.BookId == duplicateId {
+			donation.BookId = &survivorId
+			d.donations[id] = donation
+		}
+	}
+	d.bookStock[survivorId] += d.bookStock[duplicateId]
+	delete(d.bookStock, duplicateId)
+	// The duplicate stays, archived, its ISBN free for the survivor.
+	duplicate := d.books[duplicateId]
+	archivedAt := mergedAt
+	duplicate.ISBN, duplicate.ArchivedAt = "", &archivedAt
+	d.books[duplicateId] = duplicate
+
+	// Readers who shelved or wished for both keep the survivor's entries.
+	for key, item := range d.shelfItems {
+		if key.bookId != duplicateId {
+			continue
+		}
+		moved := shelfKey{key.userId, survivorId}
+		if _, ok := d.shelfItems[moved]; !ok {
+			item.BookId = survivorId
+			d.shelfItems[moved] = item
+			progress := slices.Clone(d.readingProgress[key])
+			for i := range progress {
+				progress[i].BookId = survivorId
+			}
+			if len(progress) > 0 {
+				d.readingProgress[moved] = progress
+			}
+		}
+		delete(d.shelfItems, key)
+		delete(d.readingProgress, key)
+	}
+	for key, item := range d.wishlistItems {
+		if key.bookId != duplicateId {
+			continue
+		}
+		moved := shelfKey{key.userId, survivorId}
+		if _, ok := d.wishlistItems[moved]; !ok {
+			item.BookId = survivorId
+			d.wishlistItems[moved] = item
+		}
+		delete(d.wishlistItems, key)
+	}
+
+	// So do collections, suppliers and subscriptions that had both, and
+	// the survivor keeps its own series and work.
+	for key, price := range d.pushWatches {
+		if key.bookId != duplicateId {
+			continue
+		}
+		moved := pushWatch{key.subscriptionId, survivorId}
+		if _, ok := d.pushWatches[moved]; !ok {
+			d.pushWatches[moved] = price
+		}
+		delete(d.pushWatches, key)
+	}
+	for id, c := range d.collections {
+		i := slices.Index(c.bookIds, duplicateId)
+		if i < 0 {
+			continue
+		}
+		c.bookIds = slices.Clone(c.bookIds)
+		if slices.Contains(c.bookIds, survivorId) {
+			c.bookIds = slices.Delete(c.bookIds, i, i+1)
+		} else {
+			c.bookIds[i] = survivorId
+		}
+		d.collections[id] = c
+	}
+	for key, price := range d.supplierPrices {
+		if key.bookId != duplicateId {
+			continue
+		}
+		moved := supplierPriceKey{key.supplierId, survivorId}
+		if _, ok := d.supplierPrices[moved]; !ok {
+			price.BookId = survivorId
+			d.supplierPrices[moved] = price
+		}
+		delete(d.supplierPrices, key)
+	}
+	if subjects, ok := d.bookSubjects[duplicateId]; ok {
+		merged := d.bookSubjects[survivorId]
+		for _, name := range subjects.Categories {
+			if !slices.Contains(merged.Categories, name) {
+				merged.Categories = append(slices.Clone(merged.Categories), name)
+			}
+		}
+		for _, name := range subjects.Tags {
+			if !slices.Contains(merged.Tags, name) {
+				merged.Tags = append(slices.Clone(merged.Tags), name)
+			}
+		}
+		d.bookSubjects[survivorId] = merged
+		delete(d.bookSubjects, duplicateId)
+	}
+	if membership, ok := d.seriesBooks[duplicateId]; ok {
+		if _, ok := d.seriesBooks[survivorId]; !ok {
+			d.seriesBooks[survivorId] = membership
+		}
+		delete(d.seriesBooks, duplicateId)
+	}
+	if workId, ok := d.editions[duplicateId]; ok {
+		if _, ok := d.editions[survivorId]; !ok {
+			d.editions[survivorId] = workId
+		}
+		delete(d.editions, duplicateId)
+	}
+
+	for id, survivor := range d.bookMerges {
+		if survivor == duplicateId {
+			d.bookMerges[id] = survivorId
+		}
+	}
+	d.bookMerges[duplicateId] = survivorId
+	return nil
+}
+
+// Drop the books merged into others.
+func (d *memoryData) dropMerged(books []Book) []Book {
+	return slices.DeleteFunc(books, func(book Book) bool {
+		_, merged := d.bookMerges[book.Id]
+		return merged
+	})
+}
+
+func (s *memoryStore) MergedInto(ctx context.Context, id int) (int, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	survivorId, ok := d.bookMerges[id]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return survivorId, nil
+}
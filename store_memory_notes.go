@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+func (s *memoryStore) ListNotes(ctx context.Context, userId int, query NoteQuery) ([]Note, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	words := searchWords(query.Search)
+	notes := []Note{}
+	for _, note := range d.notes {
+		if note.UserId != userId || (query.BookId != 0 && note.BookId != query.BookId) || !query.keeps(note) {
+			continue
+		}
+		text := strings.ToLower(note.Text)
+		matches := true
+		for _, word := range words {
+			matches = matches && strings.Contains(text, word)
+		}
+		if matches {
+			book := d.books[note.BookId]
+			note.Book = &book
+			notes = append(notes, note)
+		}
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		if !notes[i].CreatedAt.Equal(notes[j].CreatedAt) {
+			return notes[i].CreatedAt.After(notes[j].CreatedAt)
+		}
+		return notes[i].Id > notes[j].Id
+	})
+	return notes, nil
+}
+
+func (s *memoryStore) GetNote(ctx context.Context, userId, id int) (Note, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	note, ok := d.notes[id]
+	if !ok || note.UserId != userId {
+		return Note{}, ErrNotFound
+	}
+	return note, nil
+}
+
+func (s *memoryStore) CreateNote(ctx context.Context, note *Note) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	note.Id = d.nextNoteId
+	d.nextNoteId++
+	d.notes[note.Id] = *note
+	return nil
+}
+
+func (s *memoryStore) UpdateNote(ctx context.Context, note Note) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	stored, ok := d.notes[note.Id]
+	if !ok || stored.UserId != note.UserId {
+		return ErrNotFound
+	}
+	stored.Kind, stored.Text, stored.Page, stored.UpdatedAt = note.Kind, note.Text, note.Page, note.UpdatedAt
+	d.notes[note.Id] = stored
+	return nil
+}
+
+func (s *memoryStore) DeleteNote(ctx context.Context, userId, id int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	note, ok := d.notes[id]
+	if !ok || note.UserId != userId {
+		return ErrNotFound
+	}
+	delete(d.notes, id)
+	return nil
+}
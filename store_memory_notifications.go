@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+)
+
+// notificationPreference keys memoryData.notificationPreferences.
+type notificationPreference struct {
+	recipient string
+	kind      string
+}
+
+func (s *memoryStore) EnqueueNotification(ctx context.Context, n *Notification) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	n.TenantId = tenantId(ctx)
+	n.Id = d.nextNotificationId
+	d.nextNotificationId++
+	d.notifications[n.Id] = *n
+	return nil
+}
+
+func (s *memoryStore) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Notification, error) {
+	defer s.lock()()
+
+	notifications := []Notification{}
+	for _, d := range s.state.data {
+		for _, n := range d.notifications {
+			if !n.NextAttemptAt.After(now) {
+				notifications = append(notifications, n)
+			}
+		}
+	}
+	sort.Slice(notifications, func(i, j int) bool {
+		if !notifications[i].NextAttemptAt.Equal(notifications[j].NextAttemptAt) {
+			return notifications[i].NextAttemptAt.Before(notifications[j].NextAttemptAt)
+		}
+		return notifications[i].Id < notifications[j].Id
+	})
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+	}
+
+	for i, n := range notifications {
+		notifications[i].NextAttemptAt = now.Add(lease)
+		s.state.data[n.TenantId].notifications[n.Id] = notifications[i]
+	}
+	return notifications, nil
+}
+
+func (s *memoryStore) UpdateNotification(ctx context.Context, n Notification) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.notifications[n.Id]; ok {
+		d.notifications[n.Id] = n
+	}
+	return nil
+}
+
+func (s *memoryStore) DeleteNotification(ctx context.Context, id int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	delete(d.notifications, id)
+	return nil
+}
+
+func (s *memoryStore) AddNotificationFailure(ctx context.Context, failure *NotificationFailure) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	failure.Id = d.nextNotificationFailureId
+	d.nextNotificationFailureId++
+	d.notificationFailures = append(d.notificationFailures, *failure)
+	return nil
+}
+
+func (s *memoryStore) ListNotificationFailures(ctx context.Context, limit int, after ChangedAfter) ([]NotificationFailure, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	failures := []NotificationFailure{}
+	for i := len(d.notificationFailures) - 1; i >= 0 && len(failures) < limit; i-- {
+		if after.keeps(d.notificationFailures[i]) {
+			failures = append(failures, d.notificationFailures[i])
+		}
+	}
+	return failures, nil
+}
+
+func (s *memoryStore) DeleteNotificationFailuresBefore(ctx context.Context, before time.Time) (int64, error) {
+	defer s.lock()()
+
+	var n int64
+	for _, d := range s.state.data {
+		kept := len(d.notificationFailures)
+		d.notificationFailures = slices.DeleteFunc(d.notificationFailures, func(failure NotificationFailure) bool { return failure.FailedAt.Before(before) })
+		n += int64(kept - len(d.notificationFailures))
+	}
+	return n, nil
+}
+
+func (s *memoryStore) NotificationPreferences(ctx context.Context, recipient string) (NotificationPreferences, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	preferences := NotificationPreferences{Recipient: recipient, Preferences: []NotificationPreference{}}
+	if phone, ok := d.notificationPhones[recipient]; ok {
+		preferences.Phone = &phone
+	}
+	for key, p := range d.notificationPreferences {
+		if key.recipient == recipient {
+			preferences.Preferences = append(preferences.Preferences, p)
+		}
+	}
+	sort.Slice(preferences.Preferences, func(i, j int) bool {
+		return preferences.Preferences[i].Kind < preferences.Preferences[j].Kind
+	})
+	return preferences, nil
+}
+
+func (s *memoryStore) SetNotificationPreference(ctx context.Context, recipient string, preference NotificationPreference) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	d.notificationPreferences[notificationPreference{recipient, preference.Kind}] = preference
+	return nil
+}
+
+func (s *memoryStore) SetNotificationPhone(ctx context.Context, recipient, phone string) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if phone == "" {
+		delete(d.notificationPhones, recipient)
+	} else {
+		d.notificationPhones[recipient] = phone
+	}
+	return nil
+}
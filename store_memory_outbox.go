@@ -0,0 +1,39 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"slices"
+)
+
+func (s *memoryStore) AddOutboxEvent(ctx context.Context, event *Event) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	event.Id, event.TenantId = s.state.nextOutboxId, tenantId(ctx)
+	s.state.nextOutboxId++
+	d.outbox = append(d.outbox, *event)
+	return nil
+}
+
+func (s *memoryStore) PendingOutboxEvents(ctx context.Context, limit int) ([]Event, error) {
+	defer s.rlock()()
+
+	var events []Event
+	for _, d := range s.state.data {
+		events = append(events, d.outbox...)
+	}
+	slices.SortFunc(events, func(a, b Event) int { return cmp.Compare(a.Id, b.Id) })
+	return events[:min(limit, len(events))], nil
+}
+
+func (s *memoryStore) DeleteOutboxEvents(ctx context.Context, ids []int64) error {
+	defer s.lock()()
+
+	for _, d := range s.state.data {
+		d.outbox = slices.DeleteFunc(d.outbox, func(event Event) bool {
+			return slices.Contains(ids, event.Id)
+		})
+	}
+	return nil
+}
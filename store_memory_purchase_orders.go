@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+)
+
+func (s *memoryStore) ListPurchaseOrders(ctx context.Context, query PurchaseOrderQuery) ([]PurchaseOrder, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	orders := []PurchaseOrder{}
+	for _, order := range d.purchaseOrders {
+		switch {
+		case query.Status != "" && order.Status != query.Status:
+		case query.SupplierId != 0 && order.SupplierId != query.SupplierId:
+		case !query.keeps(order):
+		default:
+			order.Lines = slices.Clone(order.Lines)
+			orders = append(orders, order)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].OrderedAt.Equal(orders[j].OrderedAt) {
+			return orders[i].OrderedAt.Before(orders[j].OrderedAt)
+		}
+		return orders[i].Id < orders[j].Id
+	})
+	return orders, nil
+}
+
+func (s *memoryStore) GetPurchaseOrder(ctx context.Context, id int) (PurchaseOrder, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	order, ok := d.purchaseOrders[id]
+	if !ok {
+		return PurchaseOrder{}, ErrNotFound
+	}
+	order.Lines = slices.Clone(order.Lines)
+	return order, nil
+}
+
+func (s *memoryStore) CreatePurchaseOrder(ctx context.Context, order *PurchaseOrder) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	order.Id = d.nextPurchaseOrderId
+	d.nextPurchaseOrderId++
+	for i := range order.Lines {
+		order.Lines[i].Id = d.nextOrderLineId
+		d.nextOrderLineId++
+	}
+	stored := *order
+	stored.Lines = slices.Clone(order.Lines)
+	d.purchaseOrders[order.Id] = stored
+	return nil
+}
+
+func (s *memoryStore) ReceiveOrderLine(ctx context.Context, lineId, quantity int, receivedAt time.Time) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	for id, order := range d.purchaseOrders {
+		i := slices.IndexFunc(order.Lines, func(l OrderLine) bool { return l.Id == lineId })
+		if i < 0 {
+			continue
+		}
+		// Orders share their lines with snapshots taken for transactions.
+		order.Lines = slices.Clone(order.Lines)
+		order.Lines[i].Received += quantity
+		order.UpdatedAt = receivedAt
+		d.purchaseOrders[id] = order
+		return nil
+	}
+	return ErrNotFound
+}
+
+func (s *memoryStore) ClosePurchaseOrder(ctx context.Context, id int, status string, closedAt time.Time) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	order, ok := d.purchaseOrders[id]
+	if !ok {
+		return ErrNotFound
+	}
+	order.Status, order.ClosedAt, order.UpdatedAt = status, &closedAt, closedAt
+	d.purchaseOrders[id] = order
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"sort"
+)
+
+// pushWatch keys memoryData.pushWatches.
+type pushWatch struct {
+	subscriptionId int
+	bookId         int
+}
+
+func (s *memoryStore) SavePushSubscription(ctx context.Context, sub *PushSubscription, prices map[int]float64) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	d.deletePushSubscription(sub.Endpoint)
+	sub.Id = d.nextPushSubscriptionId
+	d.nextPushSubscriptionId++
+	d.pushSubscriptions[sub.Id] = *sub
+	for bookId, price := range prices {
+		d.pushWatches[pushWatch{sub.Id, bookId}] = price
+	}
+	return nil
+}
+
+func (s *memoryStore) GetPushSubscription(ctx context.Context, endpoint string) (PushSubscription, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	for _, sub := range d.pushSubscriptions {
+		if sub.Endpoint == endpoint {
+			return sub, nil
+		}
+	}
+	return PushSubscription{}, ErrNotFound
+}
+
+func (s *memoryStore) ListPushSubscriptions(ctx context.Context, recipient string) ([]PushSubscription, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	subs := []PushSubscription{}
+	for _, sub := range d.pushSubscriptions {
+		if sub.Recipient == recipient {
+			subs = append(subs, sub)
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].Id < subs[j].Id })
+	return subs, nil
+}
+
+func (s *memoryStore) DeletePushSubscription(ctx context.Context, endpoint string) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if !d.deletePushSubscription(endpoint) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete the subscription with the endpoint and its watches, reporting
+// whether there was one. The caller must hold the write lock.
+func (d *memoryData) deletePushSubscription(endpoint string) bool {
+	for id, sub := range d.pushSubscriptions {
+		if sub.Endpoint != endpoint {
+			continue
+		}
+		delete(d.pushSubscriptions, id)
+		for key := range d.pushWatches {
+			if key.subscriptionId == id {
+				delete(d.pushWatches, key)
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (s *memoryStore) WatchPrice(ctx context.Context, bookId int, price float64) ([]PriceWatch, error) {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	var ids []int
+	for key, watched := range d.pushWatches {
+		if key.bookId != bookId {
+			continue
+		}
+		if watched > price {
+			ids = append(ids, key.subscriptionId)
+		}
+	}
+	sort.Ints(ids)
+
+	var watches []PriceWatch
+	for _, id := range ids {
+		key := pushWatch{id, bookId}
+		watches = append(watches, PriceWatch{Endpoint: d.pushSubscriptions[id].Endpoint, Price: d.pushWatches[key]})
+	}
+	for key := range d.pushWatches {
+		if key.bookId == bookId {
+			d.pushWatches[key] = price
+		}
+	}
+	return watches, nil
+}
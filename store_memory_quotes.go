@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"math/rand/v2"
+	"sort"
+)
+
+// The quotes keep, with their books, newest first.
+func (d *memoryData) quotesWhere(keep func(Quote) bool) []Quote {
+	quotes := []Quote{}
+	for _, quote := range d.quotes {
+		if keep(quote) {
+			book := d.books[quote.BookId]
+			quote.Book = &book
+			quotes = append(quotes, quote)
+		}
+	}
+	sort.Slice(quotes, func(i, j int) bool {
+		if !quotes[i].CreatedAt.Equal(quotes[j].CreatedAt) {
+			return quotes[i].CreatedAt.After(quotes[j].CreatedAt)
+		}
+		return quotes[i].Id > quotes[j].Id
+	})
+	return quotes
+}
+
+func (s *memoryStore) ListQuotes(ctx context.Context, userId int, after ChangedAfter) ([]Quote, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	return d.quotesWhere(func(q Quote) bool { return q.UserId == userId && after.keeps(q) }), nil
+}
+
+func (s *memoryStore) ListBookQuotes(ctx context.Context, bookId int, after ChangedAfter) ([]Quote, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	if _, ok := d.books[bookId]; !ok {
+		return nil, ErrNotFound
+	}
+	quotes := d.quotesWhere(func(q Quote) bool { return q.BookId == bookId && q.Public && after.keeps(q) })
+	for i := range quotes {
+		quotes[i].Book = nil
+	}
+	return quotes, nil
+}
+
+func (s *memoryStore) RandomQuote(ctx context.Context) (Quote, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	quotes := d.quotesWhere(func(q Quote) bool { return q.Public })
+	if len(quotes) == 0 {
+		return Quote{}, ErrNotFound
+	}
+	return quotes[rand.IntN(len(quotes))], nil
+}
+
+func (s *memoryStore) GetQuote(ctx context.Context, userId, id int) (Quote, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	quote, ok := d.quotes[id]
+	if !ok || quote.UserId != userId {
+		return Quote{}, ErrNotFound
+	}
+	return quote, nil
+}
+
+func (s *memoryStore) CreateQuote(ctx context.Context, quote *Quote) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	quote.Id = d.nextQuoteId
+	d.nextQuoteId++
+	stored := *quote
+	stored.Book = nil
+	d.quotes[quote.Id] = stored
+	return nil
+}
+
+func (s *memoryStore) UpdateQuote(ctx context.Context, quote Quote) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	stored, ok := d.quotes[quote.Id]
+	if !ok || stored.UserId != quote.UserId {
+		return ErrNotFound
+	}
+	stored.Text, stored.Author, stored.Public, stored.UpdatedAt = quote.Text, quote.Author, quote.Public, quote.UpdatedAt
+	d.quotes[quote.Id] = stored
+	return nil
+}
+
+func (s *memoryStore) DeleteQuote(ctx context.Context, userId, id int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	quote, ok := d.quotes[id]
+	if !ok || quote.UserId != userId {
+		return ErrNotFound
+	}
+	delete(d.quotes, id)
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+)
+
+func (s *memoryStore) ListReviews(ctx context.Context, bookId int) ([]Review, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	reviews := []Review{}
+	for _, review := range d.reviews {
+		if review.BookId == bookId {
+			reviews = append(reviews, review)
+		}
+	}
+	sort.Slice(reviews, func(i, j int) bool { return reviews[i].Id < reviews[j].Id })
+	return reviews, nil
+}
+
+func (s *memoryStore) ListUserReviews(ctx context.Context, userId int) ([]Review, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	reviews := []Review{}
+	for _, review := range d.reviews {
+		if review.UserId != nil && *review.UserId == userId {
+			reviews = append(reviews, review)
+		}
+	}
+	sort.Slice(reviews, func(i, j int) bool { return reviews[i].Id < reviews[j].Id })
+	return reviews, nil
+}
+
+func (s *memoryStore) ListReviewsForBooks(ctx context.Context, bookIds []int, limit int) ([]Review, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	reviews := []Review{}
+	for _, review := range d.reviews {
+		if slices.Contains(bookIds, review.BookId) {
+			reviews = append(reviews, review)
+		}
+	}
+	sort.Slice(reviews, func(i, j int) bool {
+		if reviews[i].BookId != reviews[j].BookId {
+			return reviews[i].BookId < reviews[j].BookId
+		}
+		return reviews[i].Id < reviews[j].Id
+	})
+	return reviews[:min(limit, len(reviews))], nil
+}
+
+func (s *memoryStore) CreateReview(ctx context.Context, review *Review) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.books[review.BookId]; !ok {
+		return ErrNotFound
+	}
+
+	createdAt := time.Now().UTC().Truncate(time.Microsecond)
+	review.Id, review.CreatedAt = d.nextReviewId, &createdAt
+	d.nextReviewId++
+	d.reviews[review.Id] = *review
+	return nil
+}
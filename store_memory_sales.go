@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+func (s *memoryStore) GetSale(ctx context.Context, id int) (Sale, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	sale, ok := d.sales[id]
+	if !ok {
+		return Sale{}, ErrNotFound
+	}
+	sale.Lines = slices.Clone(sale.Lines)
+	return sale, nil
+}
+
+func (s *memoryStore) ListUserSales(ctx context.Context, userId int, email string) ([]Sale, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	sales := []Sale{}
+	for _, sale := range d.sales {
+		if sale.UserId == userId || sale.CustomerEmail != "" && strings.EqualFold(sale.CustomerEmail, email) {
+			sale.Lines = slices.Clone(sale.Lines)
+			sales = append(sales, sale)
+		}
+	}
+	sort.Slice(sales, func(i, j int) bool { return sales[i].Id < sales[j].Id })
+	return sales, nil
+}
+
+func (s *memoryStore) CreateSale(ctx context.Context, sale *Sale) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	sale.Id = d.nextSaleId
+	d.nextSaleId++
+	d.lastInvoiceNumber++
+	sale.InvoiceNumber = d.lastInvoiceNumber
+	for i := range sale.Lines {
+		sale.Lines[i].Id = d.nextSaleLineId
+		d.nextSaleLineId++
+	}
+	stored := *sale
+	stored.Lines = slices.Clone(sale.Lines)
+	d.sales[sale.Id] = stored
+	return nil
+}
+
+func (s *memoryStore) ShipSale(ctx context.Context, id int, carrier, trackingNumber string, shippedAt time.Time) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	sale, ok := d.sales[id]
+	if !ok {
+		return ErrNotFound
+	}
+	sale.ShippedAt, sale.Carrier, sale.TrackingNumber, sale.UpdatedAt = &shippedAt, carrier, trackingNumber, shippedAt
+	d.sales[id] = sale
+	return nil
+}
+
+// Pass each line of the sales made from one time to before another to
+// add, with the day it was sold on.
+func (d *memoryData) eachSaleLine(from, to time.Time, add func(day time.Time, line SaleLine)) {
+	for _, sale := range d.sales {
+		day := sale.SoldAt.Truncate(24 * time.Hour)
+		if day.Before(from) || !day.Before(to) {
+			continue
+		}
+		for _, line := range sale.Lines {
+			add(day, line)
+		}
+	}
+}
+
+func (s *memoryStore) SalesByDay(ctx context.Context, from, to time.Time) ([]SalesTotal, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	byDay := map[time.Time]SalesTotal{}
+	d.eachSaleLine(from, to, func(day time.Time, line SaleLine) {
+		t := byDay[day]
+		t.Day, t.Units, t.Revenue = day, t.Units+line.Quantity, roundCents(t.Revenue+line.Amount)
+		byDay[day] = t
+	})
+	totals := make([]SalesTotal, 0, len(byDay))
+	for _, t := range byDay {
+		totals = append(totals, t)
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].Day.Before(totals[j].Day) })
+	return totals, nil
+}
+
+func (s *memoryStore) SalesByCategory(ctx context.Context, from, to time.Time) ([]SalesTotal, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	byCategory := map[string]SalesTotal{}
+	addTo := func(category string, line SaleLine) {
+		t := byCategory[category]
+		t.Category, t.Units, t.Revenue = category, t.Units+line.Quantity, roundCents(t.Revenue+line.Amount)
+		byCategory[category] = t
+	}
+	d.eachSaleLine(from, to, func(_ time.Time, line SaleLine) {
+		categories := d.bookSubjects[line.BookId].Categories
+		if len(categories) == 0 {
+			addTo("", line)
+		}
+		for _, category := range categories {
+			addTo(category, line)
+		}
+	})
+	totals := make([]SalesTotal, 0, len(byCategory))
+	for _, t := range byCategory {
+		totals = append(totals, t)
+	}
+	return totals, nil
+}
+
+func (s *memoryStore) TopSellingBooks(ctx context.Context, from, to time.Time, limit int) ([]BookSales, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	// Lines of a deleted book have no BookId, so they are told apart by
+	// title.
+	type key struct {
+		bookId int
+		title  string
+	}
+	byBook := map[key]BookSales{}
+	d.eachSaleLine(from, to, func(_ time.Time, line SaleLine) {
+		k := key{line.BookId, line.Title}
+		b := byBook[k]
+		b.BookId, b.Title, b.Units, b.Revenue = line.BookId, line.Title, b.Units+line.Quantity, roundCents(b.Revenue+line.Amount)
+		byBook[k] = b
+	})
+	top := make([]BookSales, 0, len(byBook))
+	for _, b := range byBook {
+		top = append(top, b)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		a, b := top[i], top[j]
+		switch {
+		case a.Units != b.Units:
+			return a.Units > b.Units
+		case a.Revenue != b.Revenue:
+			return a.Revenue > b.Revenue
+		}
+		return a.Title < b.Title
+	})
+	if len(top) > limit {
+		top = top[:limit]
+	}
+	return top, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+func (s *memoryStore) ListSavedSearches(ctx context.Context, userId int, after ChangedAfter) ([]SavedSearch, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	searches := []SavedSearch{}
+	for _, search := range d.savedSearches {
+		if search.UserId == userId && after.keeps(search) {
+			searches = append(searches, search)
+		}
+	}
+	sort.Slice(searches, func(i, j int) bool {
+		return searches[i].Id < searches[j].Id
+	})
+	return searches, nil
+}
+
+func (s *memoryStore) ListAllSavedSearches(ctx context.Context) ([]SavedSearch, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	searches := []SavedSearch{}
+	for _, search := range d.savedSearches {
+		searches = append(searches, search)
+	}
+	sort.Slice(searches, func(i, j int) bool {
+		return searches[i].Id < searches[j].Id
+	})
+	return searches, nil
+}
+
+func (s *memoryStore) CreateSavedSearch(ctx context.Context, search *SavedSearch) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	search.Id = d.nextSavedSearchId
+	d.nextSavedSearchId++
+	d.savedSearches[search.Id] = *search
+	return nil
+}
+
+func (s *memoryStore) DeleteSavedSearch(ctx context.Context, userId, id int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	search, ok := d.savedSearches[id]
+	if !ok || search.UserId != userId {
+		return ErrNotFound
+	}
+	delete(d.savedSearches, id)
+	return nil
+}
+
+func (s *memoryStore) SetSavedSearchSeen(ctx context.Context, id, seenBookId int, notifiedAt *time.Time) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	search, ok := d.savedSearches[id]
+	if !ok {
+		return ErrNotFound
+	}
+	search.SeenBookId = seenBookId
+	if notifiedAt != nil {
+		search.NotifiedAt, search.UpdatedAt = notifiedAt, *notifiedAt
+	}
+	d.savedSearches[id] = search
+	return nil
+}
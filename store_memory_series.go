@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// seriesMembership is where a book comes in its series, keyed in
+// memoryData by the book's id.
+type seriesMembership struct {
+	seriesId int
+	position float64
+}
+
+func (s *memoryStore) ListSeries(ctx context.Context, after ChangedAfter) ([]Series, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	series := make([]Series, 0, len(d.series))
+	for _, sr := range d.series {
+		if after.keeps(sr) {
+			series = append(series, sr)
+		}
+	}
+	sort.Slice(series, func(i, j int) bool {
+		if a, b := strings.ToLower(series[i].Name), strings.ToLower(series[j].Name); a != b {
+			return a < b
+		}
+		return series[i].Id < series[j].Id
+	})
+	return series, nil
+}
+
+func (s *memoryStore) GetSeries(ctx context.Context, id int) (Series, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	series, ok := d.series[id]
+	if !ok {
+		return Series{}, ErrNotFound
+	}
+	return series, nil
+}
+
+func (s *memoryStore) CreateSeries(ctx context.Context, series *Series) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	series.Id = d.nextSeriesId
+	d.nextSeriesId++
+	d.series[series.Id] = *series
+	return nil
+}
+
+func (s *memoryStore) UpdateSeries(ctx context.Context, series Series) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	stored, ok := d.series[series.Id]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Name, stored.Description, stored.UpdatedAt = series.Name, series.Description, series.UpdatedAt
+	d.series[series.Id] = stored
+	return nil
+}
+
+func (s *memoryStore) DeleteSeries(ctx context.Context, id int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.series[id]; !ok {
+		return ErrNotFound
+	}
+	delete(d.series, id)
+	for bookId, m := range d.seriesBooks {
+		if m.seriesId == id {
+			delete(d.seriesBooks, bookId)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) ListSeriesBooks(ctx context.Context, seriesId int, after ChangedAfter) ([]SeriesBook, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	if _, ok := d.series[seriesId]; !ok {
+		return nil, ErrNotFound
+	}
+	books := []SeriesBook{}
+	for bookId, m := range d.seriesBooks {
+		book := SeriesBook{Position: m.position, Book: d.books[bookId]}
+		if m.seriesId == seriesId && after.keeps(book) {
+			books = append(books, book)
+		}
+	}
+	sort.Slice(books, func(i, j int) bool {
+		if books[i].Position != books[j].Position {
+			return books[i].Position < books[j].Position
+		}
+		return books[i].Book.Id < books[j].Book.Id
+	})
+	return books, nil
+}
+
+func (s *memoryStore) SetBookSeries(ctx context.Context, bookId, seriesId int, position float64) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.books[bookId]; !ok {
+		return ErrNotFound
+	}
+	if _, ok := d.series[seriesId]; !ok {
+		return ErrNotFound
+	}
+	d.seriesBooks[bookId] = seriesMembership{seriesId: seriesId, position: position}
+	return nil
+}
+
+func (s *memoryStore) RemoveBookFromSeries(ctx context.Context, bookId, seriesId int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if m, ok := d.seriesBooks[bookId]; !ok || m.seriesId != seriesId {
+		return ErrNotFound
+	}
+	delete(d.seriesBooks, bookId)
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sort"
+)
+
+// shelfKey keys memoryData.shelfItems and memoryData.wishlistItems.
+type shelfKey struct {
+	userId int
+	bookId int
+}
+
+func (s *memoryStore) ListShelf(ctx context.Context, userId int, status string, after ChangedAfter) ([]ShelfItem, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	items := []ShelfItem{}
+	for key, item := range d.shelfItems {
+		if key.userId == userId && (status == "" || item.Status == status) && after.keeps(item) {
+			book := d.books[key.bookId]
+			item.Book = &book
+			item.StatusChanges = slices.Clone(item.StatusChanges)
+			if progress := d.readingProgress[key]; len(progress) > 0 {
+				last := progress[len(progress)-1]
+				item.Progress = &last
+			}
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].AddedAt.Equal(items[j].AddedAt) {
+			return items[i].AddedAt.Before(items[j].AddedAt)
+		}
+		return items[i].BookId < items[j].BookId
+	})
+	return items, nil
+}
+
+func (s *memoryStore) ShelveBook(ctx context.Context, userId int, item *ShelfItem) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	key := shelfKey{userId, item.BookId}
+	changedAt := item.AddedAt
+	existing, ok := d.shelfItems[key]
+	item.CreatedAt, item.UpdatedAt = item.AddedAt, changedAt
+	if ok {
+		item.AddedAt, item.CreatedAt = existing.AddedAt, existing.CreatedAt
+	}
+	item.StatusChanges = slices.Clip(existing.StatusChanges)
+	switch {
+	case item.Status == "" && ok:
+		item.Status = existing.Status
+	case item.Status == "":
+		item.Status = ReadingToRead
+	}
+	if item.Status != existing.Status {
+		item.StatusChanges = append(item.StatusChanges, StatusChange{item.Status, changedAt})
+	}
+	item.UserId = userId
+	stored := *item
+	stored.Book, stored.Progress = nil, nil
+	d.shelfItems[key] = stored
+	item.StatusChanges = slices.Clone(item.StatusChanges)
+	return nil
+}
+
+func (s *memoryStore) UnshelveBook(ctx context.Context, userId, bookId int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	key := shelfKey{userId, bookId}
+	if _, ok := d.shelfItems[key]; !ok {
+		return ErrNotFound
+	}
+	delete(d.shelfItems, key)
+	delete(d.readingProgress, key)
+	return nil
+}
+
+func (s *memoryStore) RecordProgress(ctx context.Context, userId int, progress *ReadingProgress) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	key := shelfKey{userId, progress.BookId}
+	item, ok := d.shelfItems[key]
+	if !ok {
+		return ErrNotFound
+	}
+	item.UpdatedAt = progress.RecordedAt
+	d.shelfItems[key] = item
+	// Clipped, so a clone's appends don't write into this slice.
+	d.readingProgress[key] = append(slices.Clip(d.readingProgress[key]), *progress)
+	return nil
+}
+
+func (s *memoryStore) ListProgress(ctx context.Context, userId int) ([]ReadingProgress, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	history := []ReadingProgress{}
+	for key, progress := range d.readingProgress {
+		if key.userId == userId {
+			history = append(history, progress...)
+		}
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].RecordedAt.Before(history[j].RecordedAt)
+	})
+	return history, nil
+}
+
+func (s *memoryStore) AllShelfItems(ctx context.Context) ([]ShelfItem, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	items := []ShelfItem{}
+	for _, item := range d.shelfItems {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].UserId != items[j].UserId {
+			return items[i].UserId < items[j].UserId
+		}
+		return items[i].AddedAt.Before(items[j].AddedAt)
+	})
+	return items, nil
+}
+
+func (s *memoryStore) ReplaceRecommendations(ctx context.Context, recommendations []Recommendation) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	d.recommendations = nil
+	for _, rec := range recommendations {
+		// Books deleted since the shelves were read can't be recommended,
+		// as the foreign key keeps them out of the SQL stores.
+		if _, ok := d.books[rec.BookId]; ok {
+			d.recommendations = append(d.recommendations, rec)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) ListRecommendations(ctx context.Context, userId, limit int) ([]Recommendation, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	recommendations := []Recommendation{}
+	for _, rec := range d.recommendations {
+		book, ok := d.books[rec.BookId]
+		if rec.UserId == userId && ok {
+			rec.Book = &book
+			recommendations = append(recommendations, rec)
+		}
+	}
+	sort.Slice(recommendations, func(i, j int) bool {
+		if recommendations[i].Score != recommendations[j].Score {
+			return recommendations[i].Score > recommendations[j].Score
+		}
+		return recommendations[i].BookId < recommendations[j].BookId
+	})
+	return recommendations[:min(len(recommendations), limit)], nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+func (s *memoryStore) CatalogStats(ctx context.Context, now time.Time, topAuthors int) (CatalogStats, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	day, week, month := recentWindows(now)
+	stats := CatalogStats{TotalBooks: len(d.books), Authors: []AuthorCount{}}
+	perAuthor := map[string]int{}
+	var total float64
+	first := true
+	for _, book := range d.books {
+		perAuthor[book.Author]++
+
+		total += book.Price
+		if first {
+			stats.Price.Min, stats.Price.Max = book.Price, book.Price
+			first = false
+		}
+		stats.Price.Min = min(stats.Price.Min, book.Price)
+		stats.Price.Max = max(stats.Price.Max, book.Price)
+
+		if book.CreatedAt != nil && !book.CreatedAt.Before(day) {
+			stats.RecentlyAdded.LastDay++
+		}
+		if book.CreatedAt != nil && !book.CreatedAt.Before(week) {
+			stats.RecentlyAdded.LastWeek++
+		}
+		if book.CreatedAt != nil && !book.CreatedAt.Before(month) {
+			stats.RecentlyAdded.LastMonth++
+		}
+	}
+	if stats.TotalBooks > 0 {
+		stats.Price.Average = total / float64(stats.TotalBooks)
+	}
+
+	stats.TotalAuthors = len(perAuthor)
+	for author, books := range perAuthor {
+		stats.Authors = append(stats.Authors, AuthorCount{Author: author, Books: books})
+	}
+	sort.Slice(stats.Authors, func(i, j int) bool {
+		if stats.Authors[i].Books != stats.Authors[j].Books {
+			return stats.Authors[i].Books > stats.Authors[j].Books
+		}
+		return stats.Authors[i].Author < stats.Authors[j].Author
+	})
+	stats.Authors = stats.Authors[:min(topAuthors, len(stats.Authors))]
+	return stats, nil
+}
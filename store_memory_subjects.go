@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+)
+
+func (s *memoryStore) GetBookSubjects(ctx context.Context, bookId int) (BookSubjects, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	if _, ok := d.books[bookId]; !ok {
+		return BookSubjects{}, ErrNotFound
+	}
+	subjects := d.bookSubjects[bookId]
+	subjects.Categories = append([]string{}, subjects.Categories...)
+	subjects.Tags = append([]string{}, subjects.Tags...)
+	sort.Strings(subjects.Categories)
+	sort.Strings(subjects.Tags)
+	return subjects, nil
+}
+
+func (s *memoryStore) SetBookSubjects(ctx context.Context, bookId int, subjects BookSubjects) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.books[bookId]; !ok {
+		return ErrNotFound
+	}
+	d.bookSubjects[bookId] = BookSubjects{
+		Categories: slices.Clone(subjects.Categories),
+		Tags:       slices.Clone(subjects.Tags),
+	}
+	return nil
+}
+
+func (s *memoryStore) BookCategories(ctx context.Context) (map[int][]string, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	categories := map[int][]string{}
+	for id, subjects := range d.bookSubjects {
+		if _, ok := d.books[id]; ok && len(subjects.Categories) > 0 {
+			categories[id] = slices.Clone(subjects.Categories)
+		}
+	}
+	return categories, nil
+}
+
+func (s *memoryStore) SimilarBooks(ctx context.Context, bookId, limit int) ([]SimilarBook, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	target, ok := d.books[bookId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	subjects := d.bookSubjects[bookId]
+
+	similar := []SimilarBook{}
+	for id, book := range d.books {
+		if id == bookId {
+			continue
+		}
+		score := 0
+		if strings.EqualFold(book.Author, target.Author) {
+			score += 3
+		}
+		other := d.bookSubjects[id]
+		score += 2 * sharedNames(subjects.Categories, other.Categories)
+		score += sharedNames(subjects.Tags, other.Tags)
+		if score > 0 {
+			similar = append(similar, SimilarBook{Score: score, Book: book})
+		}
+	}
+	sort.Slice(similar, func(i, j int) bool {
+		if similar[i].Score != similar[j].Score {
+			return similar[i].Score > similar[j].Score
+		}
+		return similar[i].Book.Id < similar[j].Book.Id
+	})
+	return similar[:min(len(similar), limit)], nil
+}
+
+// How many of names are also in others, ignoring case.
+func sharedNames(names, others []string) int {
+	n := 0
+	for _, name := range names {
+		if slices.ContainsFunc(others, func(other string) bool { return strings.EqualFold(name, other) }) {
+			n++
+		}
+	}
+	return n
+}
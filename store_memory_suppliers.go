@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"sort"
+)
+
+// supplierPriceKey keys memoryData.supplierPrices.
+type supplierPriceKey struct {
+	supplierId int
+	bookId     int
+}
+
+func (s *memoryStore) ListSuppliers(ctx context.Context, after ChangedAfter) ([]Supplier, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	suppliers := make([]Supplier, 0, len(d.suppliers))
+	for _, supplier := range d.suppliers {
+		if after.keeps(supplier) {
+			suppliers = append(suppliers, supplier)
+		}
+	}
+	sort.Slice(suppliers, func(i, j int) bool {
+		if suppliers[i].Name != suppliers[j].Name {
+			return suppliers[i].Name < suppliers[j].Name
+		}
+		return suppliers[i].Id < suppliers[j].Id
+	})
+	return suppliers, nil
+}
+
+func (s *memoryStore) GetSupplier(ctx context.Context, id int) (Supplier, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	supplier, ok := d.suppliers[id]
+	if !ok {
+		return Supplier{}, ErrNotFound
+	}
+	return supplier, nil
+}
+
+func (s *memoryStore) CreateSupplier(ctx context.Context, supplier *Supplier) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	supplier.Id = d.nextSupplierId
+	d.nextSupplierId++
+	d.suppliers[supplier.Id] = *supplier
+	return nil
+}
+
+func (s *memoryStore) UpdateSupplier(ctx context.Context, supplier Supplier) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.suppliers[supplier.Id]; !ok {
+		return ErrNotFound
+	}
+	d.suppliers[supplier.Id] = supplier
+	return nil
+}
+
+func (s *memoryStore) DeleteSupplier(ctx context.Context, id int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.suppliers[id]; !ok {
+		return ErrNotFound
+	}
+	delete(d.suppliers, id)
+	for key := range d.supplierPrices {
+		if key.supplierId == id {
+			delete(d.supplierPrices, key)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) ListSupplierPrices(ctx context.Context, query SupplierPriceQuery) ([]SupplierPrice, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	prices := []SupplierPrice{}
+	for key, price := range d.supplierPrices {
+		switch {
+		case query.SupplierId != 0 && key.supplierId != query.SupplierId:
+		case query.BookId != 0 && key.bookId != query.BookId:
+		case !query.keeps(price):
+		default:
+			price.SupplierName = d.suppliers[key.supplierId].Name
+			price.Title = d.books[key.bookId].Title
+			prices = append(prices, price)
+		}
+	}
+	sort.Slice(prices, func(i, j int) bool {
+		a, b := prices[i], prices[j]
+		switch {
+		case a.UnitCost != b.UnitCost:
+			return a.UnitCost < b.UnitCost
+		case a.LeadTimeDays != b.LeadTimeDays:
+			return a.LeadTimeDays < b.LeadTimeDays
+		case a.SupplierId != b.SupplierId:
+			return a.SupplierId < b.SupplierId
+		}
+		return a.BookId < b.BookId
+	})
+	return prices, nil
+}
+
+func (s *memoryStore) SetSupplierPrice(ctx context.Context, price *SupplierPrice) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	key := supplierPriceKey{price.SupplierId, price.BookId}
+	price.CreatedAt = price.UpdatedAt
+	if existing, ok := d.supplierPrices[key]; ok {
+		price.CreatedAt = existing.CreatedAt
+	}
+	d.supplierPrices[key] = *price
+	return nil
+}
+
+func (s *memoryStore) DeleteSupplierPrice(ctx context.Context, supplierId, bookId int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	key := supplierPriceKey{supplierId, bookId}
+	if _, ok := d.supplierPrices[key]; !ok {
+		return ErrNotFound
+	}
+	delete(d.supplierPrices, key)
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"sort"
+)
+
+func (s *memoryStore) CreateTenant(ctx context.Context, tenant *Tenant) error {
+	defer s.lock()()
+
+	for _, existing := range s.state.tenants {
+		if existing.Slug == tenant.Slug {
+			return ErrDuplicateTenant
+		}
+	}
+	tenant.Id = s.state.nextTenantId
+	s.state.nextTenantId++
+	s.state.tenants[tenant.Id] = *tenant
+	s.state.data[tenant.Id] = newMemoryData()
+	return nil
+}
+
+func (s *memoryStore) GetTenantBySlug(ctx context.Context, slug string) (Tenant, error) {
+	defer s.rlock()()
+
+	for _, tenant := range s.state.tenants {
+		if tenant.Slug == slug {
+			return tenant, nil
+		}
+	}
+	return Tenant{}, ErrNotFound
+}
+
+func (s *memoryStore) ListTenants(ctx context.Context, after ChangedAfter) ([]Tenant, error) {
+	defer s.rlock()()
+
+	tenants := make([]Tenant, 0, len(s.state.tenants))
+	for _, tenant := range s.state.tenants {
+		if after.keeps(tenant) {
+			tenants = append(tenants, tenant)
+		}
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].Id < tenants[j].Id })
+	return tenants, nil
+}
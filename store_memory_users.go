@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// memoryUser is a user and the hash of their token.
+type memoryUser struct {
+	User
+	tokenHash string
+}
+
+func (s *memoryStore) CreateUser(ctx context.Context, user *User, tokenHash string) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	for _, existing := range d.users {
+		if existing.Email == user.Email {
+			return ErrDuplicateEmail
+		}
+	}
+	user.Id = d.nextUserId
+	d.nextUserId++
+	d.users[user.Id] = memoryUser{User: *user, tokenHash: tokenHash}
+	return nil
+}
+
+func (s *memoryStore) GetUserByToken(ctx context.Context, tokenHash string) (User, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	for _, user := range d.users {
+		if user.tokenHash == tokenHash {
+			return user.User, nil
+		}
+	}
+	return User{}, ErrNotFound
+}
+
+func (s *memoryStore) GetUser(ctx context.Context, id int) (User, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	user, ok := d.users[id]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return user.User, nil
+}
+
+func (s *memoryStore) EraseUser(ctx context.Context, id int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	user, ok := d.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(d.users, id)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	for key := range d.shelfItems {
+		if key.userId == id {
+			delete(d.shelfItems, key)
+		}
+	}
+	for key := range d.wishlistItems {
+		if key.userId == id {
+			delete(d.wishlistItems, key)
+		}
+	}
+	for key := range d.readingProgress {
+		if key.userId == id {
+			delete(d.readingProgress, key)
+		}
+	}
+	for key := range d.goals {
+		if key.userId == id {
+			delete(d.goals, key)
+		}
+	}
+	maps.DeleteFunc(d.notes, func(_ int, note Note) bool { return note.UserId == id })
+	maps.DeleteFunc(d.quotes, func(_ int, quote Quote) bool { return quote.UserId == id })
+	maps.DeleteFunc(d.savedSearches, func(_ int, search SavedSearch) bool { return search.UserId == id })
+	maps.DeleteFunc(d.loyaltyEntries, func(_ int, entry LoyaltyEntry) bool { return entry.UserId == id })
+	maps.DeleteFunc(d.collections, func(_ int, c memoryCollection) bool { return c.UserId == id })
+	d.recommendations = slices.DeleteFunc(d.recommendations, func(r Recommendation) bool { return r.UserId == id })
+
+	for memberId, member := range d.members {
+		if member.UserId != nil && *member.UserId == id {
+			member.Name, member.Email, member.Phone, member.Address, member.UserId = erasedName, "", "", "", nil
+			member.UpdatedAt = now
+			d.members[memberId] = member
+		}
+	}
+	for saleId, sale := range d.sales {
+		if sale.UserId == id || sale.CustomerEmail != "" && strings.EqualFold(sale.CustomerEmail, user.Email) {
+			sale.CustomerName, sale.CustomerAddress, sale.CustomerEmail, sale.UserId = "", "", "", 0
+			sale.UpdatedAt = now
+			d.sales[saleId] = sale
+		}
+	}
+	for reviewId, review := range d.reviews {
+		if review.UserId != nil && *review.UserId == id {
+			review.Reviewer, review.UserId = erasedName, nil
+			d.reviews[reviewId] = review
+		}
+	}
+
+	for _, sub := range d.pushSubscriptions {
+		if sub.Recipient == user.Email {
+			d.deletePushSubscription(sub.Endpoint)
+		}
+	}
+	maps.DeleteFunc(d.notifications, func(_ int, n Notification) bool { return n.Recipient == user.Email })
+	d.notificationFailures = slices.DeleteFunc(d.notificationFailures, func(f NotificationFailure) bool { return f.Recipient == user.Email })
+	for key := range d.notificationPreferences {
+		if key.recipient == user.Email {
+			delete(d.notificationPreferences, key)
+		}
+	}
+	delete(d.notificationPhones, user.Email)
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+func (s *memoryStore) ListWebhooks(ctx context.Context, after ChangedAfter) ([]Webhook, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	hooks := make([]Webhook, 0, len(d.webhooks))
+	for _, hook := range d.webhooks {
+		if after.keeps(hook) {
+			hooks = append(hooks, hook)
+		}
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].Id < hooks[j].Id })
+	return hooks, nil
+}
+
+func (s *memoryStore) GetWebhook(ctx context.Context, id int) (Webhook, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	hook, ok := d.webhooks[id]
+	if !ok {
+		return Webhook{}, ErrNotFound
+	}
+	return hook, nil
+}
+
+func (s *memoryStore) CreateWebhook(ctx context.Context, hook *Webhook) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	hook.Id = d.nextWebhookId
+	hook.CreatedAt = time.Now().UTC()
+	hook.UpdatedAt = hook.CreatedAt
+	d.nextWebhookId++
+	d.webhooks[hook.Id] = *hook
+	return nil
+}
+
+func (s *memoryStore) DeleteWebhook(ctx context.Context, id int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.webhooks[id]; !ok {
+		return ErrNotFound
+	}
+	delete(d.webhooks, id)
+	for deliveryId, delivery := range d.webhookDeliveries {
+		if delivery.WebhookId == id {
+			delete(d.webhookDeliveries, deliveryId)
+		}
+	}
+	for jobId, job := range d.webhookJobs {
+		if job.WebhookId == id {
+			delete(d.webhookJobs, jobId)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) CreateWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.webhooks[delivery.WebhookId]; !ok {
+		return ErrNotFound
+	}
+	delivery.Id = d.nextWebhookDeliveryId
+	d.nextWebhookDeliveryId++
+	d.webhookDeliveries[delivery.Id] = *delivery
+	return nil
+}
+
+func (s *memoryStore) ListWebhookDeliveries(ctx context.Context, webhookId int, limit int, after ChangedAfter) ([]WebhookDelivery, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	deliveries := []WebhookDelivery{}
+	for _, delivery := range d.webhookDeliveries {
+		if delivery.WebhookId == webhookId && after.keeps(delivery) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].Id > deliveries[j].Id })
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+func (s *memoryStore) DeleteWebhookDeliveriesBefore(ctx context.Context, before time.Time) (int64, error) {
+	defer s.lock()()
+
+	var n int64
+	for _, d := range s.state.data {
+		for id, delivery := range d.webhookDeliveries {
+			if delivery.CreatedAt.Before(before) {
+				delete(d.webhookDeliveries, id)
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
+func (s *memoryStore) EnqueueWebhookJob(ctx context.Context, job *WebhookJob) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.webhooks[job.WebhookId]; !ok {
+		return ErrNotFound
+	}
+	job.Event.TenantId = tenantId(ctx)
+	job.Id = d.nextWebhookJobId
+	d.nextWebhookJobId++
+	d.webhookJobs[job.Id] = *job
+	return nil
+}
+
+func (s *memoryStore) ClaimWebhookJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]WebhookJob, error) {
+	defer s.lock()()
+
+	jobs := []WebhookJob{}
+	for _, d := range s.state.data {
+		for _, job := range d.webhookJobs {
+			if !job.NextAttemptAt.After(now) {
+				jobs = append(jobs, job)
+			}
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].NextAttemptAt.Equal(jobs[j].NextAttemptAt) {
+			return jobs[i].NextAttemptAt.Before(jobs[j].NextAttemptAt)
+		}
+		return jobs[i].Id < jobs[j].Id
+	})
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+
+	for i, job := range jobs {
+		jobs[i].NextAttemptAt = now.Add(lease)
+		s.state.data[job.Event.TenantId].webhookJobs[job.Id] = jobs[i]
+	}
+	return jobs, nil
+}
+
+func (s *memoryStore) UpdateWebhookJob(ctx context.Context, job WebhookJob) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.webhookJobs[job.Id]; ok {
+		d.webhookJobs[job.Id] = job
+	}
+	return nil
+}
+
+func (s *memoryStore) DeleteWebhookJob(ctx context.Context, id int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	delete(d.webhookJobs, id)
+	return nil
+}
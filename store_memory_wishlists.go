@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+)
+
+func (s *memoryStore) ListWishlist(ctx context.Context, userId int, after ChangedAfter) ([]WishlistItem, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	items := d.wishlistWhere(func(item WishlistItem) bool { return item.UserId == userId && after.keeps(item) })
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].AddedAt.Equal(items[j].AddedAt) {
+			return items[i].AddedAt.Before(items[j].AddedAt)
+		}
+		return items[i].BookId < items[j].BookId
+	})
+	return items, nil
+}
+
+func (s *memoryStore) ListPriceTargets(ctx context.Context) ([]WishlistItem, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	items := d.wishlistWhere(func(item WishlistItem) bool { return item.TargetPrice != nil })
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].UserId != items[j].UserId {
+			return items[i].UserId < items[j].UserId
+		}
+		return items[i].BookId < items[j].BookId
+	})
+	return items, nil
+}
+
+// The wishlist items keep accepts, with their books.
+func (d *memoryData) wishlistWhere(keep func(WishlistItem) bool) []WishlistItem {
+	items := []WishlistItem{}
+	for _, item := range d.wishlistItems {
+		if keep(item) {
+			book := d.books[item.BookId]
+			item.Book = &book
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (s *memoryStore) SaveWishlistItem(ctx context.Context, userId int, item *WishlistItem) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	key := shelfKey{userId, item.BookId}
+	if existing, ok := d.wishlistItems[key]; ok {
+		item.AddedAt, item.CreatedAt = existing.AddedAt, existing.CreatedAt
+	}
+	item.UserId = userId
+	stored := *item
+	stored.Book = nil
+	d.wishlistItems[key] = stored
+	return nil
+}
+
+func (s *memoryStore) DeleteWishlistItem(ctx context.Context, userId, bookId int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	key := shelfKey{userId, bookId}
+	if _, ok := d.wishlistItems[key]; !ok {
+		return ErrNotFound
+	}
+	delete(d.wishlistItems, key)
+	return nil
+}
+
+func (s *memoryStore) SetWishlistAlert(ctx context.Context, userId, bookId int, price *float64, alertedAt *time.Time, updatedAt time.Time) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	key := shelfKey{userId, bookId}
+	if item, ok := d.wishlistItems[key]; ok {
+		item.AlertedPrice, item.AlertedAt, item.UpdatedAt = price, alertedAt, updatedAt
+		d.wishlistItems[key] = item
+	}
+	return nil
+}
+
+func (s *memoryStore) RecordBookPrice(ctx context.Context, price BookPrice) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.books[price.BookId]; !ok {
+		return nil
+	}
+	prices := d.bookPrices[price.BookId]
+	if len(prices) > 0 && prices[len(prices)-1].Price == price.Price {
+		return nil
+	}
+	d.bookPrices[price.BookId] = append(slices.Clip(prices), price)
+	return nil
+}
+
+func (s *memoryStore) ListBookPrices(ctx context.Context, bookId int, after ChangedAfter) ([]BookPrice, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	if _, ok := d.books[bookId]; !ok {
+		return nil, ErrNotFound
+	}
+	return keepChangedAfter(append([]BookPrice{}, d.bookPrices[bookId]...), after), nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// The work with its editions loaded; the caller must hold the lock.
+func (d *memoryData) loadWork(work Work) Work {
+	work.Editions = []Book{}
+	for bookId, workId := range d.editions {
+		if workId == work.Id {
+			work.Editions = append(work.Editions, d.books[bookId])
+		}
+	}
+	sort.Slice(work.Editions, func(i, j int) bool { return work.Editions[i].Id < work.Editions[j].Id })
+	return work
+}
+
+func (s *memoryStore) ListWorks(ctx context.Context, after ChangedAfter) ([]Work, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	works := make([]Work, 0, len(d.works))
+	for _, work := range d.works {
+		if after.keeps(work) {
+			works = append(works, d.loadWork(work))
+		}
+	}
+	sort.Slice(works, func(i, j int) bool {
+		if a, b := strings.ToLower(works[i].Title), strings.ToLower(works[j].Title); a != b {
+			return a < b
+		}
+		return works[i].Id < works[j].Id
+	})
+	return works, nil
+}
+
+func (s *memoryStore) GetWork(ctx context.Context, id int) (Work, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	work, ok := d.works[id]
+	if !ok {
+		return Work{}, ErrNotFound
+	}
+	return d.loadWork(work), nil
+}
+
+func (s *memoryStore) SearchWorks(ctx context.Context, query string) ([]Work, error) {
+	defer s.rlock()()
+	d := s.data(ctx)
+
+	query = strings.ToLower(query)
+	matches := func(title, author string) bool {
+		return strings.Contains(strings.ToLower(title), query) || strings.Contains(strings.ToLower(author), query)
+	}
+	found := map[int]bool{}
+	for id, work := range d.works {
+		if matches(work.Title, work.Author) {
+			found[id] = true
+		}
+	}
+	for bookId, workId := range d.editions {
+		if book := d.books[bookId]; matches(book.Title, book.Author) {
+			found[workId] = true
+		}
+	}
+
+	works := []Work{}
+	for id := range found {
+		works = append(works, d.loadWork(d.works[id]))
+	}
+	sort.Slice(works, func(i, j int) bool { return works[i].Id < works[j].Id })
+	return works, nil
+}
+
+func (s *memoryStore) CreateWork(ctx context.Context, work *Work) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	work.Id = d.nextWorkId
+	d.nextWorkId++
+	stored := *work
+	stored.Editions = nil
+	d.works[work.Id] = stored
+	return nil
+}
+
+func (s *memoryStore) UpdateWork(ctx context.Context, work Work) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	stored, ok := d.works[work.Id]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Title, stored.Author, stored.UpdatedAt = work.Title, work.Author, work.UpdatedAt
+	d.works[work.Id] = stored
+	return nil
+}
+
+func (s *memoryStore) DeleteWork(ctx context.Context, id int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.works[id]; !ok {
+		return ErrNotFound
+	}
+	delete(d.works, id)
+	for bookId, workId := range d.editions {
+		if workId == id {
+			delete(d.editions, bookId)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) SetBookWork(ctx context.Context, bookId, workId int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if _, ok := d.books[bookId]; !ok {
+		return ErrNotFound
+	}
+	if _, ok := d.works[workId]; !ok {
+		return ErrNotFound
+	}
+	d.editions[bookId] = workId
+	return nil
+}
+
+func (s *memoryStore) RemoveBookFromWork(ctx context.Context, bookId, workId int) error {
+	defer s.lock()()
+	d := s.data(ctx)
+
+	if id, ok := d.editions[bookId]; !ok || id != workId {
+		return ErrNotFound
+	}
+	delete(d.editions, bookId)
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// replicaSet spreads reads over read-only replicas round-robin, skipping
+// any that failed their last health check.
+type replicaSet struct {
+	replicas []*replica
+	next     atomic.Uint64
+	stop     chan struct{}
+}
+
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// Open the replica pools and start checking their health every interval.
+// A replica that is down at startup is only marked unhealthy.
+func openReplicas(d dialect, dsns []string, interval time.Duration) *replicaSet {
+	set := &replicaSet{stop: make(chan struct{})}
+	for _, dsn := range dsns {
+		db, err := openDB(d, dsn)
+		if err != nil {
+			log.Printf("Replica open error: %v", err)
+			continue
+		}
+		set.replicas = append(set.replicas, &replica{db: db})
+	}
+
+	set.check()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				set.check()
+			case <-set.stop:
+				return
+			}
+		}
+	}()
+	return set
+}
+
+// Ping every replica and record whether it answered.
+func (set *replicaSet) check() {
+	for i, r := range set.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err := r.db.PingContext(ctx)
+		cancel()
+
+		healthy := err == nil
+		if r.healthy.Swap(healthy) != healthy {
+			if healthy {
+				log.Printf("Replica %d is healthy", i)
+			} else {
+				log.Printf("Replica %d is unhealthy: %v", i, err)
+			}
+		}
+	}
+}
+
+// The next healthy replica, or nil if none are healthy.
+func (set *replicaSet) pick() *sql.DB {
+	n := uint64(len(set.replicas))
+	start := set.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		r := set.replicas[(start+i)%n]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+	return nil
+}
+
+func (set *replicaSet) Close() error {
+	close(set.stop)
+	for _, r := range set.replicas {
+		r.db.Close()
+	}
+	return nil
+}
@@ -0,0 +1,783 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/mattn/go-sqlite3"
+)
+
+// dialect describes the differences between the SQL databases we support.
+type dialect struct {
+	// name is also the directory holding the dialect's migrations.
+	name   string
+	driver string
+	// numbered reports whether placeholders are $1, $2, ... instead of ?.
+	numbered bool
+	// returning reports whether inserts report the new id via RETURNING.
+	returning bool
+	// forUpdate reports whether reads inside a transaction can lock rows
+	// with SELECT ... FOR UPDATE.
+	forUpdate bool
+	// isolation is the level used for transactions started by WithTx.
+	isolation sql.IsolationLevel
+}
+
+var (
+	mysqlDialect    = dialect{name: "mysql", driver: "mysql", forUpdate: true, isolation: sql.LevelRepeatableRead}
+	postgresDialect = dialect{name: "postgres", driver: "pgx", numbered: true, returning: true, forUpdate: true, isolation: sql.LevelRepeatableRead}
+	// SQLite transactions are serializable; the DSN makes them take the
+	// write lock up front (BEGIN IMMEDIATE).
+	sqliteDialect = dialect{name: "sqlite", driver: "sqlite3"}
+)
+
+// Rewrite ? placeholders for drivers that use numbered parameters.
+func (d dialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Report whether err is the database refusing a row that would repeat a
+// value in the unique index, which SQLite names by its table.column
+// instead.
+func (d dialect) violatesUnique(err error, index, column string) bool {
+	var mysqlErr *mysql.MySQLError
+	var pgErr *pgconn.PgError
+	var sqliteErr sqlite3.Error
+	switch {
+	case errors.As(err, &mysqlErr):
+		// ER_DUP_ENTRY ends "for key '[table.]index'".
+		return mysqlErr.Number == 1062 && strings.HasSuffix(mysqlErr.Message, index+"'")
+	case errors.As(err, &pgErr):
+		return pgErr.Code == "23505" && pgErr.ConstraintName == index // unique_violation
+	case errors.As(err, &sqliteErr):
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique && strings.Contains(sqliteErr.Error(), column)
+	}
+	return false
+}
+
+// The store error for a write to books the database refused, if it is
+// one the callers handle.
+func (d dialect) bookWriteError(err error) error {
+	switch {
+	case d.violatesUnique(err, "books_isbn_idx", "books.isbn"):
+		return ErrDuplicateISBN
+	case d.violatesUnique(err, "books_slug_idx", "books.slug"):
+		return errSlugTaken
+	}
+	return err
+}
+
+// sqlStore implements BookStore on top of database/sql.
+type sqlStore struct {
+	db *sql.DB
+	// replicas serve reads that don't need to see the latest writes; nil
+	// when none are configured.
+	replicas *replicaSet
+	// tx is set on the store handed to WithTx callbacks.
+	tx      *sql.Tx
+	dialect dialect
+}
+
+// queryer is the part of *sql.DB and *sql.Tx the store needs.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// The open transaction if there is one, otherwise the primary.
+func (s *sqlStore) conn() queryer {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db
+}
+
+// Where read-only queries go: the open transaction, a healthy replica,
+// or the primary as a fallback.
+func (s *sqlStore) reader() queryer {
+	if s.tx == nil && s.replicas != nil {
+		if db := s.replicas.pick(); db != nil {
+			return db
+		}
+	}
+	return s.conn()
+}
+
+func (s *sqlStore) WithTx(ctx context.Context, fn func(tx BookStore) error) error {
+	// Nested calls join the transaction that is already open.
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: s.dialect.isolation})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&sqlStore{db: s.db, replicas: s.replicas, tx: tx, dialect: s.dialect}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Connect to the database and bring its schema up to date.
+func openSQLStore(d dialect, dsn string) (*sqlStore, error) {
+	db, err := openDB(d, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err := migrate(db, d); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := &sqlStore{db: db, dialect: d}
+	if err := s.fillSlugs(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := s.EncryptMembers(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+const bookColumns = "id, title, author, price, COALESCE(isbn, ''), COALESCE(slug, ''), COALESCE(publisher, ''), COALESCE(format, ''), COALESCE(description, ''), COALESCE(cover_url, ''), created_at, updated_at, archived_at"
+
+// bookColumns qualified with the table name, for queries joining books.
+const joinedBookColumns = "books.id, books.title, books.author, books.price, COALESCE(books.isbn, ''), COALESCE(books.slug, ''), COALESCE(books.publisher, ''), COALESCE(books.format, ''), COALESCE(books.description, ''), COALESCE(books.cover_url, ''), books.created_at, books.updated_at, books.archived_at"
+
+// The column expression for each of bookFields. Books without an ISBN
+// store NULL, so it stays unique among those that have one.
+var bookFieldColumns = map[string]string{
+	"id":          "id",
+	"title":       "title",
+	"author":      "author",
+	"price":       "price",
+	"isbn":        "COALESCE(isbn, '')",
+	"slug":        "COALESCE(slug, '')",
+	"publisher":   "COALESCE(publisher, '')",
+	"format":      "COALESCE(format, '')",
+	"description": "COALESCE(description, '')",
+	"cover_url":   "COALESCE(cover_url, '')",
+	"created_at":  "created_at",
+	"updated_at":  "updated_at",
+	"archived_at": "archived_at",
+}
+
+// NULL for an empty string, for optional columns.
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// NULL for a zero ID, for optional references.
+func nullInt(n int) any {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
+// scanner is satisfied by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// The time t holds, or nil if it is NULL.
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func scanBook(row scanner) (Book, error) {
+	var book Book
+	err := row.Scan(book.fieldPointers()...)
+	return book, err
+}
+
+// Scan a row of the given fields' columns into a partial book, or a
+// whole one if fields is nil.
+func scanBookFields(row scanner, fields []string) (Book, error) {
+	if fields == nil {
+		return scanBook(row)
+	}
+	book := Book{fields: fields}
+	dest := make([]any, len(fields))
+	for i, field := range fields {
+		dest[i] = book.field(field)
+	}
+	err := row.Scan(dest...)
+	return book, err
+}
+
+func (s *sqlStore) ListBooks(ctx context.Context) ([]Book, error) {
+	return s.queryBooks(ctx, "SELECT "+bookColumns+" FROM books WHERE tenant_id = ? ORDER BY id", tenantId(ctx))
+}
+
+func (s *sqlStore) LockBooks(ctx context.Context) ([]Book, error) {
+	return s.queryBooks(ctx, "SELECT "+bookColumns+" FROM books WHERE tenant_id = ? ORDER BY id"+s.lockClause(), tenantId(ctx))
+}
+
+func (s *sqlStore) RecentBooks(ctx context.Context, by string, limit int) ([]Book, error) {
+	// Both columns are indexed, and by is one of two known values.
+	column := "created_at"
+	if by == RecentUpdated {
+		column = "updated_at"
+	}
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind(
+		"SELECT "+bookColumns+" FROM books WHERE tenant_id = ? AND "+column+" IS NOT NULL ORDER BY "+column+" DESC, id DESC LIMIT ?"),
+		tenantId(ctx), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	books := []Book{}
+	for rows.Next() {
+		var b Book
+		if err := rows.Scan(b.fieldPointers()...); err != nil {
+			return nil, err
+		}
+		books = append(books, b)
+	}
+	return books, rows.Err()
+}
+
+func (s *sqlStore) SearchBooks(ctx context.Context, query string) ([]Book, error) {
+	pattern := "%" + escapeLike(strings.ToLower(query)) + "%"
+	return s.queryBooks(ctx, searchBooksQuery, tenantId(ctx), pattern, pattern)
+}
+
+const searchBooksQuery = "SELECT " + bookColumns + " FROM books" +
+	" WHERE tenant_id = ? AND (LOWER(title) LIKE ? ESCAPE '!' OR LOWER(author) LIKE ? ESCAPE '!') AND " + notMergedBook + " ORDER BY id"
+
+func (s *sqlStore) StreamBooks(ctx context.Context, query BookQuery, fn func(Book) error) error {
+	if err := validBookFields(query.Fields); err != nil {
+		return err
+	}
+
+	w := where("tenant_id = ?", tenantId(ctx)).and(notMergedBook)
+	if query.Search != "" {
+		pattern := "%" + escapeLike(strings.ToLower(query.Search)) + "%"
+		w.and("(LOWER(title) LIKE ? ESCAPE '!' OR LOWER(author) LIKE ? ESCAPE '!')", pattern, pattern)
+	}
+	if len(query.Formats) > 0 {
+		condition, args := sqlIn("format", query.Formats)
+		w.and(condition, args...)
+	}
+	if query.Filter != nil {
+		condition, args := query.Filter.sql()
+		w.and(condition, args...)
+	}
+	if !query.CreatedAfter.IsZero() {
+		w.and("created_at > ?", query.CreatedAfter)
+	}
+	if !query.UpdatedAfter.IsZero() {
+		w.and("updated_at > ?", query.UpdatedAfter)
+	}
+	if query.ExcludeArchived {
+		w.and("archived_at IS NULL")
+	}
+	clause, args := w.clause()
+	sql := "SELECT " + selectColumns(query.Fields) + " FROM books" + clause + " ORDER BY id"
+	if query.Limit > 0 {
+		sql += " LIMIT ? OFFSET ?"
+		args = append(args, query.Limit, query.Offset)
+	}
+	return s.eachBook(ctx, query.Fields, fn, sql, args...)
+}
+
+// The column list loading fields, all columns if nil. The fields must
+// have passed validBookFields.
+func selectColumns(fields []string) string {
+	if fields == nil {
+		return bookColumns
+	}
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = bookFieldColumns[field]
+	}
+	return strings.Join(columns, ", ")
+}
+
+// Escape LIKE wildcards in s so it matches literally. '!' is the escape
+// character because backslash handling differs between databases.
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// Run a read-only query returning book rows.
+func (s *sqlStore) queryBooks(ctx context.Context, query string, args ...any) ([]Book, error) {
+	var books []Book
+	err := s.eachBook(ctx, nil, func(book Book) error {
+		books = append(books, book)
+		return nil
+	}, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+// Run a read-only query returning the fields of book rows, all of them if
+// nil, and call fn with each row as it is scanned.
+func (s *sqlStore) eachBook(ctx context.Context, fields []string, fn func(Book) error, query string, args ...any) error {
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		book, err := scanBookFields(rows, fields)
+		if err != nil {
+			return err
+		}
+		if err := fn(book); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s *sqlStore) GetBook(ctx context.Context, id int) (Book, error) {
+	return s.getBook(ctx, s.reader(), id)
+}
+
+func (s *sqlStore) GetBookFields(ctx context.Context, id int, fields []string) (Book, error) {
+	if err := validBookFields(fields); err != nil {
+		return Book{}, err
+	}
+	return s.getBookFields(ctx, s.reader(), id, fields)
+}
+
+func (s *sqlStore) getBook(ctx context.Context, q queryer, id int) (Book, error) {
+	return s.getBookFields(ctx, q, id, nil)
+}
+
+func (s *sqlStore) getBookFields(ctx context.Context, q queryer, id int, fields []string) (Book, error) {
+	// Lock the row so a read-then-write in a transaction can't race.
+	query := "SELECT " + selectColumns(fields) + " FROM books WHERE id = ? AND tenant_id = ?" + s.lockClause()
+
+	book, err := scanBookFields(q.QueryRowContext(ctx, s.dialect.rebind(query), id, tenantId(ctx)), fields)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Book{}, ErrNotFound
+	}
+	return book, err
+}
+
+// The suffix that makes a SELECT lock its rows until the end of the
+// transaction, or "" outside one or where the database has no row locks.
+func (s *sqlStore) lockClause() string {
+	if s.tx != nil && s.dialect.forUpdate {
+		return " FOR UPDATE"
+	}
+	return ""
+}
+
+func (s *sqlStore) CreateBook(ctx context.Context, book *Book) error {
+	slug, err := s.freeSlug(ctx, slugify(book.Title), 0)
+	if err != nil {
+		return err
+	}
+	createdAt := time.Now().UTC().Truncate(time.Microsecond)
+	id, err := s.insert(ctx, "INSERT INTO books (tenant_id, title, author, price, isbn, slug, publisher, format, description, cover_url, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		tenantId(ctx), book.Title, book.Author, book.Price, nullString(book.ISBN), slug, nullString(book.Publisher), nullString(book.Format), nullString(book.Description), nullString(book.CoverURL), createdAt, createdAt)
+	if err != nil {
+		return s.dialect.bookWriteError(err)
+	}
+	book.Id, book.Slug, book.CreatedAt, book.UpdatedAt = id, slug, &createdAt, &createdAt
+	return nil
+}
+
+// The first unused slug starting with base, counting the one the book
+// with id except has as unused.
+func (s *sqlStore) freeSlug(ctx context.Context, base string, except int) (string, error) {
+	rows, err := s.conn().QueryContext(ctx, s.dialect.rebind("SELECT slug FROM books WHERE tenant_id = ? AND (slug = ? OR slug LIKE ?) AND id <> ?"),
+		tenantId(ctx), base, base+"-%", except)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	taken := map[string]bool{}
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return "", err
+		}
+		taken[slug] = true
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return firstFreeSlug(base, taken), nil
+}
+
+// Give the books from before slugs existed one, in every tenant.
+func (s *sqlStore) fillSlugs(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, title, tenant_id FROM books WHERE slug IS NULL ORDER BY id")
+	if err != nil {
+		return err
+	}
+	var books []Book
+	var tenants []int
+	for rows.Next() {
+		var book Book
+		var tenant int
+		if err := rows.Scan(&book.Id, &book.Title, &tenant); err != nil {
+			rows.Close()
+			return err
+		}
+		books = append(books, book)
+		tenants = append(tenants, tenant)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i, book := range books {
+		slug, err := s.freeSlug(withTenantId(ctx, tenants[i]), slugify(book.Title), book.Id)
+		if err != nil {
+			return err
+		}
+		if _, err := s.db.ExecContext(ctx, s.dialect.rebind("UPDATE books SET slug = ? WHERE id = ?"), slug, book.Id); err != nil {
+			return err
+		}
+	}
+	if len(books) > 0 {
+		log.Printf("Gave %d books a slug", len(books))
+	}
+	return nil
+}
+
+func (s *sqlStore) GetBookIdBySlug(ctx context.Context, slug string) (int, error) {
+	var id int
+	err := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT id FROM books WHERE tenant_id = ? AND slug = ?"), tenantId(ctx), slug).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return id, err
+}
+
+func (s *sqlStore) GetBookIdByISBN(ctx context.Context, isbn string) (int, error) {
+	var id int
+	err := s.reader().QueryRowContext(ctx, s.dialect.rebind(
+		"SELECT id FROM books WHERE tenant_id = ? AND REPLACE(REPLACE(UPPER(isbn), '-', ''), ' ', '') IN (?, ?) ORDER BY id LIMIT 1"),
+		tenantId(ctx), isbn, isbn10(isbn)).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return id, err
+}
+
+// Run an INSERT into a table with an id column and return the new id.
+func (s *sqlStore) insert(ctx context.Context, query string, args ...any) (int, error) {
+	if s.dialect.returning {
+		var id int
+		err := s.conn().QueryRowContext(ctx, s.dialect.rebind(query+" RETURNING id"), args...).Scan(&id)
+		return id, err
+	}
+
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+
+	lastId, err := result.LastInsertId()
+	return int(lastId), err
+}
+
+func (s *sqlStore) UpdateBook(ctx context.Context, id int, book Book) (Book, error) {
+	// Only update non-empty fields.
+	u := update("books")
+	if book.Title != "" {
+		slug, err := s.freeSlug(ctx, slugify(book.Title), id)
+		if err != nil {
+			return Book{}, err
+		}
+		u.set("title", book.Title).set("slug", slug)
+	}
+	if book.Author != "" {
+		u.set("author", book.Author)
+	}
+	if book.Price != 0 {
+		u.set("price", book.Price)
+	}
+	if book.ISBN != "" {
+		u.set("isbn", book.ISBN)
+	}
+	if book.Publisher != "" {
+		u.set("publisher", book.Publisher)
+	}
+	if book.Format != "" {
+		u.set("format", book.Format)
+	}
+	if book.Description != "" {
+		u.set("description", book.Description)
+	}
+	if book.CoverURL != "" {
+		u.set("cover_url", book.CoverURL)
+	}
+
+	if !u.empty() {
+		u.set("updated_at", time.Now().UTC().Truncate(time.Microsecond))
+		query, args := u.where(where("id = ?", id).and("tenant_id = ?", tenantId(ctx)))
+		if _, err := s.conn().ExecContext(ctx, s.dialect.rebind(query), args...); err != nil {
+			return Book{}, s.dialect.bookWriteError(err)
+		}
+	}
+
+	// Read back from the primary, a replica may not have the change yet.
+	return s.getBook(ctx, s.conn(), id)
+}
+
+func (s *sqlStore) SetPrices(ctx context.Context, prices map[int]float64) ([]Book, error) {
+	var books []Book
+	err := s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		updatedAt := time.Now().UTC().Truncate(time.Microsecond)
+		for _, id := range slices.Sorted(maps.Keys(prices)) {
+			if _, err := t.tx.ExecContext(ctx, t.dialect.rebind("UPDATE books SET price = ?, updated_at = ? WHERE id = ? AND tenant_id = ?"), prices[id], updatedAt, id, tenantId(ctx)); err != nil {
+				return err
+			}
+			book, err := t.getBook(ctx, t.tx, id)
+			if err != nil {
+				return err
+			}
+			books = append(books, book)
+		}
+		return nil
+	})
+	return books, err
+}
+
+func (s *sqlStore) DeleteBook(ctx context.Context, id int) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM books WHERE id = ? AND tenant_id = ?"), id, tenantId(ctx))
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore) DeleteAllBooks(ctx context.Context) (int64, error) {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM books WHERE tenant_id = ?"), tenantId(ctx))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// The tables, and their column naming a book, whose rows keep
+// RestoreCatalog from deleting the book. The rest only hold the book's own
+// history and go with it.
+var restoreBlockingTables = []struct{ table, column string }{
+	{"loans", "book_id"}, {"copies", "book_id"}, {"holds", "book_id"},
+	{"supplier_prices", "book_id"},
+	{"shelf_items", "book_id"}, {"reading_progress", "book_id"},
+	{"reading_status_changes", "book_id"}, {"wishlist_items", "book_id"},
+	{"push_watches", "book_id"}, {"notes", "book_id"}, {"quotes", "book_id"},
+	{"collection_items", "book_id"}, {"series_books", "book_id"},
+	{"work_editions", "book_id"}, {"book_merges", "survivor_id"},
+}
+
+func (s *sqlStore) RestoreCatalog(ctx context.Context, books []Book, reviews []Review) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		bookIds, err := t.tenantIds(ctx, "books")
+		if err != nil {
+			return err
+		}
+		reviewIds, err := t.tenantIds(ctx, "reviews")
+		if err != nil {
+			return err
+		}
+		userIds, err := t.tenantIds(ctx, "users")
+		if err != nil {
+			return err
+		}
+
+		// Books the backup doesn't have go, unless deleting them would
+		// take more than their own history with them.
+		deleted := make(map[int]bool, len(bookIds))
+		for id := range bookIds {
+			deleted[id] = true
+		}
+		for _, book := range books {
+			delete(deleted, book.Id)
+		}
+		if len(deleted) > 0 {
+			for _, blocking := range restoreBlockingTables {
+				condition, args := sqlIn(blocking.column, slices.Sorted(maps.Keys(deleted)))
+				var n int
+				if err := t.tx.QueryRowContext(ctx, t.dialect.rebind("SELECT COUNT(*) FROM "+blocking.table+" WHERE "+condition), args...).Scan(&n); err != nil {
+					return err
+				}
+				if n > 0 {
+					return ErrBooksInUse
+				}
+			}
+			condition, args := sqlIn("id", slices.Sorted(maps.Keys(deleted)))
+			if _, err := t.tx.ExecContext(ctx, t.dialect.rebind("DELETE FROM books WHERE tenant_id = ? AND "+condition), append([]any{tenantId(ctx)}, args...)...); err != nil {
+				return err
+			}
+		}
+		restoredReviews := make(map[int]bool, len(reviews))
+		for _, review := range reviews {
+			restoredReviews[review.Id] = true
+		}
+		var deletedReviews []int
+		for id := range reviewIds {
+			if !restoredReviews[id] {
+				deletedReviews = append(deletedReviews, id)
+			}
+		}
+		if len(deletedReviews) > 0 {
+			condition, args := sqlIn("id", deletedReviews)
+			if _, err := t.tx.ExecContext(ctx, t.dialect.rebind("DELETE FROM reviews WHERE tenant_id = ? AND "+condition), append([]any{tenantId(ctx)}, args...)...); err != nil {
+				return err
+			}
+		}
+
+		// The rest are updated in place, keeping what refers to them. Books
+		// may have swapped slugs or ISBNs since the backup, so theirs are
+		// cleared first, the slugs replaced by ones slugify never makes.
+		for _, book := range books {
+			if !bookIds[book.Id] {
+				continue
+			}
+			query, args := update("books").set("slug", fmt.Sprintf("-restoring-%d", book.Id)).set("isbn", nil).
+				where(where("tenant_id = ?", tenantId(ctx)).and("id = ?", book.Id))
+			if _, err := t.tx.ExecContext(ctx, t.dialect.rebind(query), args...); err != nil {
+				return err
+			}
+		}
+		for _, book := range books {
+			var err error
+			if bookIds[book.Id] {
+				query, args := update("books").
+					set("title", book.Title).set("author", book.Author).set("price", book.Price).
+					set("isbn", nullString(book.ISBN)).set("slug", book.Slug).set("publisher", nullString(book.Publisher)).
+					set("format", nullString(book.Format)).set("description", nullString(book.Description)).
+					set("cover_url", nullString(book.CoverURL)).set("created_at", book.CreatedAt).
+					set("updated_at", book.UpdatedAt).set("archived_at", book.ArchivedAt).
+					where(where("tenant_id = ?", tenantId(ctx)).and("id = ?", book.Id))
+				_, err = t.tx.ExecContext(ctx, t.dialect.rebind(query), args...)
+			} else {
+				_, err = t.tx.ExecContext(ctx, t.dialect.rebind("INSERT INTO books (id, tenant_id, title, author, price, isbn, slug, publisher, format, description, cover_url, created_at, updated_at, archived_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+					book.Id, tenantId(ctx), book.Title, book.Author, book.Price, nullString(book.ISBN), book.Slug, nullString(book.Publisher), nullString(book.Format), nullString(book.Description), nullString(book.CoverURL), book.CreatedAt, book.UpdatedAt, book.ArchivedAt)
+			}
+			if err != nil {
+				return t.dialect.bookWriteError(err)
+			}
+		}
+		for _, review := range reviews {
+			// Reviews stay tied to the accounts that still exist.
+			if review.UserId != nil && !userIds[*review.UserId] {
+				review.UserId = nil
+			}
+			var err error
+			if reviewIds[review.Id] {
+				query, args := update("reviews").
+					set("book_id", review.BookId).set("reviewer", review.Reviewer).set("rating", review.Rating).
+					set("body", review.Body).set("created_at", review.CreatedAt).set("user_id", review.UserId).
+					where(where("tenant_id = ?", tenantId(ctx)).and("id = ?", review.Id))
+				_, err = t.tx.ExecContext(ctx, t.dialect.rebind(query), args...)
+			} else {
+				_, err = t.tx.ExecContext(ctx, t.dialect.rebind("INSERT INTO reviews (id, tenant_id, book_id, reviewer, rating, body, created_at, user_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"),
+					review.Id, tenantId(ctx), review.BookId, review.Reviewer, review.Rating, review.Body, review.CreatedAt, review.UserId)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		// PostgreSQL sequences don't notice ids inserted explicitly.
+		if t.dialect == postgresDialect {
+			for _, table := range []string{"books", "reviews"} {
+				_, err := t.tx.ExecContext(ctx, "SELECT setval(pg_get_serial_sequence('"+table+"', 'id'), COALESCE((SELECT MAX(id) FROM "+table+"), 0) + 1, false)")
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// The ids of the rows of table the tenant ctx acts for has.
+func (s *sqlStore) tenantIds(ctx context.Context, table string) (map[int]bool, error) {
+	rows, err := s.conn().QueryContext(ctx, s.dialect.rebind("SELECT id FROM "+table+" WHERE tenant_id = ?"), tenantId(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+func (s *sqlStore) Close() error {
+	// The pool belongs to the outer store, not to a transaction.
+	if s.tx != nil {
+		return nil
+	}
+	if s.replicas != nil {
+		s.replicas.Close()
+	}
+	return s.db.Close()
+}
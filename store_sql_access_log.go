@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+)
+
+func (s *sqlStore) RecordMemberAccess(ctx context.Context, access *MemberAccess) error {
+	id, err := s.insert(ctx, "INSERT INTO member_access_log (tenant_id, member_id, actor, data, accessed_at) VALUES (?, ?, ?, ?, ?)",
+		tenantId(ctx), access.MemberId, access.Actor, access.Data, access.AccessedAt)
+	if err != nil {
+		return err
+	}
+	access.Id = id
+	return nil
+}
+
+func (s *sqlStore) ListMemberAccess(ctx context.Context, query MemberAccessQuery) ([]MemberAccess, error) {
+	w := where("tenant_id = ?", tenantId(ctx))
+	if query.MemberId != 0 {
+		w.and("(member_id = ? OR member_id IS NULL)", query.MemberId)
+	}
+	if query.Actor != "" {
+		w.and("actor = ?", query.Actor)
+	}
+	if !query.Since.IsZero() {
+		w.and("accessed_at >= ?", query.Since)
+	}
+	if !query.Until.IsZero() {
+		w.and("accessed_at < ?", query.Until)
+	}
+	w.changedAfter(query.ChangedAfter, "accessed_at", "accessed_at")
+	clause, args := w.clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT id, member_id, actor, data, accessed_at FROM member_access_log"+clause+" ORDER BY accessed_at DESC, id DESC LIMIT ?"),
+		append(args, query.Limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accesses := []MemberAccess{}
+	for rows.Next() {
+		var access MemberAccess
+		var memberId sql.NullInt64
+		if err := rows.Scan(&access.Id, &memberId, &access.Actor, &access.Data, &access.AccessedAt); err != nil {
+			return nil, err
+		}
+		if memberId.Valid {
+			id := int(memberId.Int64)
+			access.MemberId = &id
+		}
+		accesses = append(accesses, access)
+	}
+	return accesses, rows.Err()
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+func (s *sqlStore) AddBookActivity(ctx context.Context, activities []BookActivity) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		for _, a := range activities {
+			result, err := t.conn().ExecContext(ctx, t.dialect.rebind("UPDATE book_activity SET views = views + ?, loans = loans + ?, sales = sales + ? WHERE tenant_id = ? AND book_id = ? AND day = ?"),
+				a.Views, a.Loans, a.Sales, tenantId(ctx), a.BookId, a.Day)
+			if err != nil {
+				return err
+			}
+			if n, err := result.RowsAffected(); err != nil || n > 0 {
+				continue
+			}
+			// Selecting from books skips a book deleted since it was counted.
+			_, err = t.conn().ExecContext(ctx, t.dialect.rebind("INSERT INTO book_activity (tenant_id, book_id, day, views, loans, sales) SELECT tenant_id, id, ?, ?, ?, ? FROM books WHERE id = ? AND tenant_id = ?"),
+				a.Day, a.Views, a.Loans, a.Sales, a.BookId, tenantId(ctx))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqlStore) TrendingBooks(ctx context.Context, kind string, since time.Time, limit int) ([]TrendingBook, error) {
+	// kind is one of the column names, never client input.
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind(
+		"SELECT activity.views, activity.loans, activity.sales, "+joinedBookColumns+" FROM (SELECT book_id, SUM(views) AS views, SUM(loans) AS loans, SUM(sales) AS sales FROM book_activity WHERE tenant_id = ? AND day >= ? GROUP BY book_id) activity JOIN books ON books.id = activity.book_id WHERE activity."+kind+" > 0 AND "+notMergedBook+" ORDER BY activity."+kind+" DESC, books.id LIMIT ?"),
+		tenantId(ctx), since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trending := []TrendingBook{}
+	for rows.Next() {
+		var tb TrendingBook
+		b := &tb.Book
+		if err := rows.Scan(append([]any{&tb.Views, &tb.Loans, &tb.Sales}, b.fieldPointers()...)...); err != nil {
+			return nil, err
+		}
+		trending = append(trending, tb)
+	}
+	return trending, rows.Err()
+}
+
+func (s *sqlStore) ArchiveBooks(ctx context.Context, before, now time.Time) (archived, restored []int, err error) {
+	// A book is touched since before if it changed or has a day of
+	// activity from then on.
+	const touched = "(COALESCE(updated_at, created_at) >= ? OR EXISTS (SELECT 1 FROM book_activity WHERE book_activity.tenant_id = books.tenant_id AND book_activity.book_id = books.id AND book_activity.day >= ?))"
+	err = s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		var err error
+		// Merged books stay archived.
+		if restored, err = t.queryIds(ctx, "SELECT id FROM books WHERE tenant_id = ? AND archived_at IS NOT NULL AND "+touched+" AND "+notMergedBook+" ORDER BY id",
+			tenantId(ctx), before, before); err != nil {
+			return err
+		}
+		if archived, err = t.queryIds(ctx, "SELECT id FROM books WHERE tenant_id = ? AND archived_at IS NULL AND COALESCE(updated_at, created_at) IS NOT NULL AND NOT "+touched+" ORDER BY id",
+			tenantId(ctx), before, before); err != nil {
+			return err
+		}
+		for _, id := range restored {
+			if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("UPDATE books SET archived_at = NULL WHERE id = ? AND tenant_id = ?"), id, tenantId(ctx)); err != nil {
+				return err
+			}
+		}
+		for _, id := range archived {
+			if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("UPDATE books SET archived_at = ? WHERE id = ? AND tenant_id = ?"), now, id, tenantId(ctx)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return archived, restored, err
+}
+
+// Run a query returning a column of ids.
+func (s *sqlStore) queryIds(ctx context.Context, query string, args ...any) ([]int, error) {
+	rows, err := s.conn().QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// How many searches one INSERT stores, keeping under every database's
+// limit on parameters.
+const searchInsertBatch = 100
+
+func (s *sqlStore) RecordSearches(ctx context.Context, searches []SearchRecord) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		for batch := range slices.Chunk(searches, searchInsertBatch) {
+			args := make([]any, 0, 6*len(batch))
+			for _, search := range batch {
+				args = append(args, tenantId(ctx), search.Term, search.Visitor, search.Results, search.Latency.Microseconds(), search.CreatedAt)
+			}
+			values := strings.Repeat(", (?, ?, ?, ?, ?, ?)", len(batch))[2:]
+			if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("INSERT INTO search_queries (tenant_id, term, visitor, results, latency_us, created_at) VALUES "+values), args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Count the different visitors, leaving out searches from before they
+// were recorded.
+const distinctVisitors = "COUNT(DISTINCT CASE WHEN visitor <> '' THEN visitor END)"
+
+// The most searched terms since a time, with an extra condition that may
+// narrow them down.
+const searchTermsQuery = "SELECT term, COUNT(*) AS searches, " + distinctVisitors + ", AVG(results), AVG(latency_us) / 1000.0" +
+	" FROM search_queries WHERE tenant_id = ? AND created_at >= ?%s" +
+	" GROUP BY term ORDER BY searches DESC, term LIMIT ?"
+
+func (s *sqlStore) SearchAnalytics(ctx context.Context, since time.Time, limit int) (SearchAnalytics, error) {
+	q := s.reader()
+	analytics := SearchAnalytics{Since: since}
+
+	err := q.QueryRowContext(ctx, s.dialect.rebind("SELECT COUNT(*), COUNT(CASE WHEN results = 0 THEN 1 END), "+distinctVisitors+" FROM search_queries WHERE tenant_id = ? AND created_at >= ?"), tenantId(ctx), since).
+		Scan(&analytics.TotalSearches, &analytics.ZeroResultSearches, &analytics.Visitors)
+	if err != nil {
+		return SearchAnalytics{}, err
+	}
+
+	if analytics.TopTerms, err = s.searchTerms(ctx, q, "", since, limit); err != nil {
+		return SearchAnalytics{}, err
+	}
+	if analytics.ZeroResultTerms, err = s.searchTerms(ctx, q, " AND results = 0", since, limit); err != nil {
+		return SearchAnalytics{}, err
+	}
+	return analytics, nil
+}
+
+func (s *sqlStore) searchTerms(ctx context.Context, q queryer, condition string, since time.Time, limit int) ([]SearchTermStats, error) {
+	rows, err := q.QueryContext(ctx, s.dialect.rebind(fmt.Sprintf(searchTermsQuery, condition)), tenantId(ctx), since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	terms := []SearchTermStats{}
+	for rows.Next() {
+		var term SearchTermStats
+		if err := rows.Scan(&term.Term, &term.Searches, &term.Visitors, &term.AverageResults, &term.AverageLatencyMs); err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	return terms, rows.Err()
+}
+
+func (s *sqlStore) DeleteSearchesBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM search_queries WHERE created_at < ?"), before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
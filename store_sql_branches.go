@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const branchColumns = "id, name, address, latitude, longitude, created_at, updated_at"
+
+func scanBranch(row scanner) (Branch, error) {
+	var branch Branch
+	err := row.Scan(&branch.Id, &branch.Name, &branch.Address, &branch.Latitude, &branch.Longitude, &branch.CreatedAt, &branch.UpdatedAt)
+	return branch, err
+}
+
+func (s *sqlStore) ListBranches(ctx context.Context, after ChangedAfter) ([]Branch, error) {
+	clause, args := where("tenant_id = ?", tenantId(ctx)).changedAfter(after, "created_at", "updated_at").clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT "+branchColumns+" FROM branches"+clause+" ORDER BY name, id"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	branches := []Branch{}
+	for rows.Next() {
+		branch, err := scanBranch(rows)
+		if err != nil {
+			return nil, err
+		}
+		branches = append(branches, branch)
+	}
+	return branches, rows.Err()
+}
+
+func (s *sqlStore) GetBranch(ctx context.Context, id int) (Branch, error) {
+	row := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT "+branchColumns+" FROM branches WHERE tenant_id = ? AND id = ?"), tenantId(ctx), id)
+	branch, err := scanBranch(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Branch{}, ErrNotFound
+	}
+	return branch, err
+}
+
+func (s *sqlStore) CreateBranch(ctx context.Context, branch *Branch) error {
+	id, err := s.insert(ctx, "INSERT INTO branches (tenant_id, name, address, latitude, longitude, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		tenantId(ctx), branch.Name, branch.Address, branch.Latitude, branch.Longitude, branch.CreatedAt, branch.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	branch.Id = id
+	return nil
+}
+
+func (s *sqlStore) UpdateBranch(ctx context.Context, branch Branch) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE branches SET name = ?, address = ?, latitude = ?, longitude = ?, updated_at = ? WHERE tenant_id = ? AND id = ?"),
+		branch.Name, branch.Address, branch.Latitude, branch.Longitude, branch.UpdatedAt, tenantId(ctx), branch.Id)
+	if err != nil {
+		return err
+	}
+	// MySQL counts only rows that changed, so check for the branch when
+	// nothing did.
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		_, err := s.GetBranch(ctx, branch.Id)
+		return err
+	}
+	return nil
+}
+
+func (s *sqlStore) DeleteBranch(ctx context.Context, id int) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		// The copies would be left at none anyway, but not as updated.
+		_, err := t.conn().ExecContext(ctx, t.dialect.rebind("UPDATE copies SET branch_id = NULL, updated_at = ? WHERE tenant_id = ? AND branch_id = ?"),
+			time.Now().UTC().Truncate(time.Microsecond), tenantId(ctx), id)
+		if err != nil {
+			return err
+		}
+		result, err := t.conn().ExecContext(ctx, t.dialect.rebind("DELETE FROM branches WHERE tenant_id = ? AND id = ?"), tenantId(ctx), id)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
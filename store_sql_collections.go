@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// A user's collections are read from the primary so their own changes
+// show right away.
+const collectionColumns = "id, user_id, name, COALESCE(share_token, ''), created_at, updated_at"
+
+func scanCollection(row scanner) (Collection, error) {
+	collection := Collection{Books: []Book{}}
+	err := row.Scan(&collection.Id, &collection.UserId, &collection.Name, &collection.ShareToken, &collection.CreatedAt, &collection.UpdatedAt)
+	return collection, err
+}
+
+func (s *sqlStore) ListCollections(ctx context.Context, userId int, after ChangedAfter) ([]Collection, error) {
+	condition, args := where("collections.user_id = ?", userId).changedAfter(after, "collections.created_at", "collections.updated_at").sql()
+	rows, err := s.conn().QueryContext(ctx, s.dialect.rebind("SELECT "+collectionColumns+" FROM collections WHERE tenant_id = ? AND "+condition+" ORDER BY id"),
+		append([]any{tenantId(ctx)}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []Collection{}
+	for rows.Next() {
+		collection, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	err = s.loadCollectionBooks(ctx, s.conn(), collections, condition, args...)
+	return collections, err
+}
+
+func (s *sqlStore) GetCollection(ctx context.Context, userId, id int) (Collection, error) {
+	return s.getCollection(ctx, s.conn(), "user_id = ? AND id = ?"+s.lockClause(), userId, id)
+}
+
+func (s *sqlStore) GetSharedCollection(ctx context.Context, token string) (Collection, error) {
+	return s.getCollection(ctx, s.reader(), "share_token = ?", token)
+}
+
+// The tenant's collection matching condition, with its books.
+func (s *sqlStore) getCollection(ctx context.Context, q queryer, condition string, args ...any) (Collection, error) {
+	row := q.QueryRowContext(ctx, s.dialect.rebind("SELECT "+collectionColumns+" FROM collections WHERE tenant_id = ? AND "+condition),
+		append([]any{tenantId(ctx)}, args...)...)
+	collection, err := scanCollection(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Collection{}, ErrNotFound
+	} else if err != nil {
+		return Collection{}, err
+	}
+
+	collections := []Collection{collection}
+	err = s.loadCollectionBooks(ctx, q, collections, "collections.id = ?", collection.Id)
+	return collections[0], err
+}
+
+// Fill in the books of collections, all of them matching condition, in
+// their order.
+func (s *sqlStore) loadCollectionBooks(ctx context.Context, q queryer, collections []Collection, condition string, args ...any) error {
+	if len(collections) == 0 {
+		return nil
+	}
+	index := make(map[int]int, len(collections))
+	for i, collection := range collections {
+		index[collection.Id] = i
+	}
+
+	rows, err := q.QueryContext(ctx, s.dialect.rebind(
+		"SELECT collection_items.collection_id, "+joinedBookColumns+" FROM collection_items"+
+			" JOIN collections ON collections.id = collection_items.collection_id JOIN books ON books.id = collection_items.book_id"+
+			" WHERE collections.tenant_id = ? AND "+condition+" ORDER BY collection_items.collection_id, collection_items.position"),
+		append([]any{tenantId(ctx)}, args...)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var collectionId int
+		var b Book
+		if err := rows.Scan(append([]any{&collectionId}, b.fieldPointers()...)...); err != nil {
+			return err
+		}
+		if i, ok := index[collectionId]; ok {
+			collections[i].Books = append(collections[i].Books, b)
+		}
+	}
+	return rows.Err()
+}
+
+func (s *sqlStore) CreateCollection(ctx context.Context, collection *Collection) error {
+	id, err := s.insert(ctx, "INSERT INTO collections (tenant_id, user_id, name, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		tenantId(ctx), collection.UserId, collection.Name, collection.CreatedAt, collection.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	collection.Id = id
+	return nil
+}
+
+func (s *sqlStore) UpdateCollection(ctx context.Context, collection Collection) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		result, err := t.conn().ExecContext(ctx, t.dialect.rebind("UPDATE collections SET name = ?, share_token = ?, updated_at = ? WHERE tenant_id = ? AND user_id = ? AND id = ?"),
+			collection.Name, nullString(collection.ShareToken), collection.UpdatedAt, tenantId(ctx), collection.UserId, collection.Id)
+		if err != nil {
+			return err
+		}
+		// MySQL counts only rows that changed, so check for the
+		// collection when nothing did.
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			if _, err := t.getCollection(ctx, t.conn(), "user_id = ? AND id = ?", collection.UserId, collection.Id); err != nil {
+				return err
+			}
+		}
+
+		if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("DELETE FROM collection_items WHERE collection_id = ?"), collection.Id); err != nil {
+			return err
+		}
+		for i, book := range collection.Books {
+			_, err := t.conn().ExecContext(ctx, t.dialect.rebind("INSERT INTO collection_items (tenant_id, collection_id, book_id, position) VALUES (?, ?, ?, ?)"),
+				tenantId(ctx), collection.Id, book.Id, i)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqlStore) DeleteCollection(ctx context.Context, userId, id int) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM collections WHERE tenant_id = ? AND user_id = ? AND id = ?"),
+		tenantId(ctx), userId, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const copyColumns = "id, book_id, barcode, branch_id, created_at, updated_at"
+
+func scanCopy(row scanner) (Copy, error) {
+	var copy Copy
+	var branchId sql.NullInt64
+	err := row.Scan(&copy.Id, &copy.BookId, &copy.Barcode, &branchId, &copy.CreatedAt, &copy.UpdatedAt)
+	if branchId.Valid {
+		id := int(branchId.Int64)
+		copy.BranchId = &id
+	}
+	return copy, err
+}
+
+func (s *sqlStore) ListCopies(ctx context.Context, bookId int, after ChangedAfter) ([]Copy, error) {
+	if _, err := s.GetBookFields(ctx, bookId, []string{"id"}); err != nil {
+		return nil, err
+	}
+	loans, err := s.openCopyLoans(ctx, "book_id = ?", bookId)
+	if err != nil {
+		return nil, err
+	}
+
+	clause, args := where("tenant_id = ?", tenantId(ctx)).and("book_id = ?", bookId).changedAfter(after, "created_at", "updated_at").clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT "+copyColumns+" FROM copies"+clause+" ORDER BY id"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	copies := []Copy{}
+	for rows.Next() {
+		copy, err := scanCopy(rows)
+		if err != nil {
+			return nil, err
+		}
+		if loan, ok := loans[copy.Id]; ok {
+			copy.Loan = &loan
+		}
+		copies = append(copies, copy)
+	}
+	return copies, rows.Err()
+}
+
+func (s *sqlStore) GetCopy(ctx context.Context, id int) (Copy, error) {
+	return s.getCopy(ctx, "id = ?", id)
+}
+
+func (s *sqlStore) GetCopyByBarcode(ctx context.Context, barcode string) (Copy, error) {
+	return s.getCopy(ctx, "barcode = ?", barcode)
+}
+
+// Return the copy matching where, locked in a transaction, with its open
+// loan.
+func (s *sqlStore) getCopy(ctx context.Context, where string, args ...any) (Copy, error) {
+	row := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT "+copyColumns+" FROM copies WHERE tenant_id = ? AND "+where+s.lockClause()),
+		append([]any{tenantId(ctx)}, args...)...)
+	copy, err := scanCopy(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Copy{}, ErrNotFound
+	} else if err != nil {
+		return Copy{}, err
+	}
+
+	loans, err := s.openCopyLoans(ctx, "copy_id = ?", copy.Id)
+	if loan, ok := loans[copy.Id]; ok {
+		copy.Loan = &loan
+	}
+	return copy, err
+}
+
+// Return the open loans of the copies matching where, by copy id.
+func (s *sqlStore) openCopyLoans(ctx context.Context, where string, args ...any) (map[int]Loan, error) {
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT "+loanColumns+" FROM loans WHERE tenant_id = ? AND copy_id IS NOT NULL AND returned_at IS NULL AND "+where),
+		append([]any{tenantId(ctx)}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	loans := map[int]Loan{}
+	for rows.Next() {
+		loan, err := scanLoan(rows)
+		if err != nil {
+			return nil, err
+		}
+		loans[*loan.CopyId] = loan
+	}
+	return loans, rows.Err()
+}
+
+func (s *sqlStore) CreateCopy(ctx context.Context, copy *Copy) error {
+	id, err := s.insert(ctx, "INSERT INTO copies (tenant_id, book_id, barcode, branch_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
+		tenantId(ctx), copy.BookId, copy.Barcode, copy.BranchId, copy.CreatedAt, copy.UpdatedAt)
+	if s.dialect.violatesUnique(err, "copies_barcode_idx", "copies.barcode") {
+		return ErrDuplicateBarcode
+	} else if err != nil {
+		return err
+	}
+	copy.Id = id
+	return nil
+}
+
+func (s *sqlStore) DeleteCopy(ctx context.Context, id int) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM copies WHERE tenant_id = ? AND id = ?"), tenantId(ctx), id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore) SetCopyBranch(ctx context.Context, id int, branchId *int, updatedAt time.Time) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE copies SET branch_id = ?, updated_at = ? WHERE tenant_id = ? AND id = ?"), branchId, updatedAt, tenantId(ctx), id)
+	if err != nil {
+		return err
+	}
+	// MySQL counts only rows that changed, so check for the copy when
+	// nothing did.
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		_, err := s.GetCopy(ctx, id)
+		return err
+	}
+	return nil
+}
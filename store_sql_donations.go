@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+const donationColumns = "id, donor_name, donor_email, donor_address, title, author, isbn, book_condition, " +
+	"appraised_value, status, note, book_id, received_at, decided_at, created_at, updated_at"
+
+func scanDonation(row scanner) (Donation, error) {
+	var donation Donation
+	var bookId sql.NullInt64
+	var decidedAt sql.NullTime
+	err := row.Scan(&donation.Id, &donation.DonorName, &donation.DonorEmail, &donation.DonorAddress, &donation.Title, &donation.Author,
+		&donation.ISBN, &donation.Condition, &donation.AppraisedValue, &donation.Status, &donation.Note, &bookId, &donation.ReceivedAt, &decidedAt, &donation.CreatedAt, &donation.UpdatedAt)
+	if bookId.Valid {
+		id := int(bookId.Int64)
+		donation.BookId = &id
+	}
+	if decidedAt.Valid {
+		donation.DecidedAt = &decidedAt.Time
+	}
+	return donation, err
+}
+
+func (s *sqlStore) ListDonations(ctx context.Context, query DonationQuery) ([]Donation, error) {
+	w := where("tenant_id = ?", tenantId(ctx))
+	if query.Status != "" {
+		w.and("status = ?", query.Status)
+	}
+	if !query.ReceivedFrom.IsZero() {
+		w.and("received_at >= ?", query.ReceivedFrom)
+	}
+	if !query.ReceivedBefore.IsZero() {
+		w.and("received_at < ?", query.ReceivedBefore)
+	}
+	w.changedAfter(query.ChangedAfter, "created_at", "updated_at")
+	clause, args := w.clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT "+donationColumns+" FROM donations"+clause+" ORDER BY received_at, id"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	donations := []Donation{}
+	for rows.Next() {
+		donation, err := scanDonation(rows)
+		if err != nil {
+			return nil, err
+		}
+		donations = append(donations, donation)
+	}
+	return donations, rows.Err()
+}
+
+func (s *sqlStore) GetDonation(ctx context.Context, id int) (Donation, error) {
+	row := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT "+donationColumns+" FROM donations WHERE tenant_id = ? AND id = ?"+s.lockClause()), tenantId(ctx), id)
+	donation, err := scanDonation(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Donation{}, ErrNotFound
+	}
+	return donation, err
+}
+
+func (s *sqlStore) CreateDonation(ctx context.Context, donation *Donation) error {
+	id, err := s.insert(ctx, "INSERT INTO donations (tenant_id, donor_name, donor_email, donor_address, title, author, isbn, book_condition, "+
+		"appraised_value, status, note, received_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		tenantId(ctx), donation.DonorName, donation.DonorEmail, donation.DonorAddress, donation.Title, donation.Author, donation.ISBN,
+		donation.Condition, donation.AppraisedValue, donation.Status, donation.Note, donation.ReceivedAt, donation.CreatedAt, donation.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	donation.Id = id
+	return nil
+}
+
+func (s *sqlStore) DecideDonation(ctx context.Context, donation Donation) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE donations SET status = ?, note = ?, book_id = ?, decided_at = ?, updated_at = ? WHERE tenant_id = ? AND id = ?"),
+		donation.Status, donation.Note, donation.BookId, donation.DecidedAt, donation.UpdatedAt, tenantId(ctx), donation.Id)
+	if err != nil {
+		return err
+	}
+	// MySQL counts only rows that changed, so check for the donation when
+	// nothing did.
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		_, err := s.GetDonation(ctx, donation.Id)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const fineColumns = "fines.id, fines.member_id, fines.loan_id, fines.reason, fines.amount, " +
+	"COALESCE((SELECT SUM(fine_payments.amount) FROM fine_payments WHERE fine_payments.fine_id = fines.id), 0), fines.settled_at, fines.created_at, fines.updated_at"
+
+const finePaymentColumns = "id, fine_id, amount, method, reference, paid_at"
+
+func scanFine(row scanner) (Fine, error) {
+	var fine Fine
+	var loanId sql.NullInt64
+	var settledAt sql.NullTime
+	err := row.Scan(&fine.Id, &fine.MemberId, &loanId, &fine.Reason, &fine.Amount, &fine.Paid, &settledAt, &fine.CreatedAt, &fine.UpdatedAt)
+	if loanId.Valid {
+		id := int(loanId.Int64)
+		fine.LoanId = &id
+	}
+	if settledAt.Valid {
+		fine.SettledAt = &settledAt.Time
+	}
+	fine.Paid = roundCents(fine.Paid)
+	return fine, err
+}
+
+func (s *sqlStore) ListMemberFines(ctx context.Context, memberId int, after ChangedAfter) ([]Fine, error) {
+	if _, err := s.GetMember(ctx, memberId); err != nil {
+		return nil, err
+	}
+	condition, args := where("fines.member_id = ?", memberId).changedAfter(after, "fines.created_at", "fines.updated_at").sql()
+	return s.queryFines(ctx, condition+" ORDER BY fines.created_at DESC, fines.id DESC", args...)
+}
+
+func (s *sqlStore) ListUnsettledFines(ctx context.Context) ([]Fine, error) {
+	return s.queryFines(ctx, "fines.settled_at IS NULL ORDER BY fines.created_at, fines.id")
+}
+
+// Return the fines matching where, which may end in an ORDER BY.
+func (s *sqlStore) queryFines(ctx context.Context, where string, args ...any) ([]Fine, error) {
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT "+fineColumns+" FROM fines WHERE fines.tenant_id = ? AND "+where),
+		append([]any{tenantId(ctx)}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fines := []Fine{}
+	for rows.Next() {
+		fine, err := scanFine(rows)
+		if err != nil {
+			return nil, err
+		}
+		fines = append(fines, fine)
+	}
+	return fines, rows.Err()
+}
+
+func (s *sqlStore) GetFine(ctx context.Context, id int) (Fine, error) {
+	row := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT "+fineColumns+" FROM fines WHERE fines.tenant_id = ? AND fines.id = ?"+s.lockClause()), tenantId(ctx), id)
+	fine, err := scanFine(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Fine{}, ErrNotFound
+	}
+	return fine, err
+}
+
+func (s *sqlStore) CreateFine(ctx context.Context, fine *Fine) error {
+	id, err := s.insert(ctx, "INSERT INTO fines (tenant_id, member_id, loan_id, reason, amount, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		tenantId(ctx), fine.MemberId, fine.LoanId, fine.Reason, fine.Amount, fine.CreatedAt, fine.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	fine.Id = id
+	return nil
+}
+
+func (s *sqlStore) SettleFine(ctx context.Context, id int, settledAt time.Time) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE fines SET settled_at = ?, updated_at = ? WHERE tenant_id = ? AND id = ?"), settledAt, settledAt, tenantId(ctx), id)
+	if err != nil {
+		return err
+	}
+	// MySQL counts only rows that changed, so check for the fine when
+	// nothing did.
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		_, err := s.GetFine(ctx, id)
+		return err
+	}
+	return nil
+}
+
+func (s *sqlStore) GetFinePayment(ctx context.Context, id int) (FinePayment, error) {
+	var payment FinePayment
+	err := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT "+finePaymentColumns+" FROM fine_payments WHERE tenant_id = ? AND id = ?"), tenantId(ctx), id).
+		Scan(&payment.Id, &payment.FineId, &payment.Amount, &payment.Method, &payment.Reference, &payment.PaidAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return FinePayment{}, ErrNotFound
+	}
+	return payment, err
+}
+
+func (s *sqlStore) CreateFinePayment(ctx context.Context, payment *FinePayment) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		id, err := t.insert(ctx, "INSERT INTO fine_payments (tenant_id, fine_id, amount, method, reference, paid_at) VALUES (?, ?, ?, ?, ?, ?)",
+			tenantId(ctx), payment.FineId, payment.Amount, payment.Method, payment.Reference, payment.PaidAt)
+		if err != nil {
+			return err
+		}
+		_, err = t.conn().ExecContext(ctx, t.dialect.rebind("UPDATE fines SET updated_at = ? WHERE tenant_id = ? AND id = ?"), payment.PaidAt, tenantId(ctx), payment.FineId)
+		if err != nil {
+			return err
+		}
+		payment.Id = id
+		return nil
+	})
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+func (s *sqlStore) GetGiftCard(ctx context.Context, code string) (GiftCard, error) {
+	card := GiftCard{Code: code, Redemptions: []GiftCardRedemption{}}
+	var voidedAt sql.NullTime
+	err := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT id, amount, balance, note, issued_at, voided_at, created_at, updated_at FROM gift_cards WHERE tenant_id = ? AND code = ?"+s.lockClause()), tenantId(ctx), code).
+		Scan(&card.Id, &card.Amount, &card.Balance, &card.Note, &card.IssuedAt, &voidedAt, &card.CreatedAt, &card.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return GiftCard{}, ErrNotFound
+	} else if err != nil {
+		return GiftCard{}, err
+	}
+	if voidedAt.Valid {
+		card.VoidedAt = &voidedAt.Time
+	}
+
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT sale_id, amount, redeemed_at FROM gift_card_redemptions WHERE gift_card_id = ? ORDER BY id"), card.Id)
+	if err != nil {
+		return GiftCard{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		redemption := GiftCardRedemption{GiftCardId: card.Id}
+		if err := rows.Scan(&redemption.SaleId, &redemption.Amount, &redemption.RedeemedAt); err != nil {
+			return GiftCard{}, err
+		}
+		card.Redemptions = append(card.Redemptions, redemption)
+	}
+	return card, rows.Err()
+}
+
+func (s *sqlStore) CreateGiftCard(ctx context.Context, card *GiftCard) error {
+	id, err := s.insert(ctx, "INSERT INTO gift_cards (tenant_id, code, amount, balance, note, issued_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		tenantId(ctx), card.Code, card.Amount, card.Balance, card.Note, card.IssuedAt, card.CreatedAt, card.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	card.Id = id
+	return nil
+}
+
+func (s *sqlStore) VoidGiftCard(ctx context.Context, id int, voidedAt time.Time) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE gift_cards SET voided_at = ?, updated_at = ? WHERE tenant_id = ? AND id = ?"), voidedAt, voidedAt, tenantId(ctx), id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore) RedeemGiftCard(ctx context.Context, redemption GiftCardRedemption) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		result, err := t.conn().ExecContext(ctx, t.dialect.rebind("UPDATE gift_cards SET balance = balance - ?, updated_at = ? WHERE tenant_id = ? AND id = ?"),
+			redemption.Amount, redemption.RedeemedAt, tenantId(ctx), redemption.GiftCardId)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrNotFound
+		}
+		_, err = t.insert(ctx, "INSERT INTO gift_card_redemptions (tenant_id, gift_card_id, sale_id, amount, redeemed_at) VALUES (?, ?, ?, ?, ?)",
+			tenantId(ctx), redemption.GiftCardId, redemption.SaleId, redemption.Amount, redemption.RedeemedAt)
+		return err
+	})
+}
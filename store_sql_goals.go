@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+func (s *sqlStore) ListGoals(ctx context.Context, userId int, after ChangedAfter) ([]ReadingGoal, error) {
+	clause, args := where("tenant_id = ?", tenantId(ctx)).and("user_id = ?", userId).changedAfter(after, "created_at", "updated_at").clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT year, unit, target, created_at, updated_at FROM reading_goals"+clause+" ORDER BY year"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	goals := []ReadingGoal{}
+	for rows.Next() {
+		goal := ReadingGoal{UserId: userId}
+		var createdAt, updatedAt sql.NullTime
+		if err := rows.Scan(&goal.Year, &goal.Unit, &goal.Target, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		goal.CreatedAt, goal.UpdatedAt = nullTime(createdAt), nullTime(updatedAt)
+		goals = append(goals, goal)
+	}
+	return goals, rows.Err()
+}
+
+func (s *sqlStore) GetGoal(ctx context.Context, userId, year int) (ReadingGoal, error) {
+	goal := ReadingGoal{UserId: userId, Year: year}
+	var createdAt, updatedAt sql.NullTime
+	err := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT unit, target, created_at, updated_at FROM reading_goals WHERE tenant_id = ? AND user_id = ? AND year = ?"), tenantId(ctx), userId, year).
+		Scan(&goal.Unit, &goal.Target, &createdAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ReadingGoal{}, ErrNotFound
+	}
+	goal.CreatedAt, goal.UpdatedAt = nullTime(createdAt), nullTime(updatedAt)
+	return goal, err
+}
+
+func (s *sqlStore) SetGoal(ctx context.Context, goal *ReadingGoal) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		var year int
+		var createdAt sql.NullTime
+		err := t.conn().QueryRowContext(ctx, t.dialect.rebind("SELECT year, created_at FROM reading_goals WHERE tenant_id = ? AND user_id = ? AND year = ?"+t.lockClause()),
+			tenantId(ctx), goal.UserId, goal.Year).Scan(&year, &createdAt)
+		if err == nil {
+			goal.CreatedAt = nullTime(createdAt)
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("DELETE FROM reading_goals WHERE tenant_id = ? AND user_id = ? AND year = ?"), tenantId(ctx), goal.UserId, goal.Year); err != nil {
+			return err
+		}
+		_, err = t.conn().ExecContext(ctx, t.dialect.rebind("INSERT INTO reading_goals (tenant_id, user_id, year, unit, target, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)"),
+			tenantId(ctx), goal.UserId, goal.Year, goal.Unit, goal.Target, goal.CreatedAt, goal.UpdatedAt)
+		return err
+	})
+}
+
+func (s *sqlStore) DeleteGoal(ctx context.Context, userId, year int) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM reading_goals WHERE tenant_id = ? AND user_id = ? AND year = ?"), tenantId(ctx), userId, year)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
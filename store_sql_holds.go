@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const holdColumns = "id, member_id, book_id, placed_at, ready_at, created_at, updated_at"
+
+func scanHold(row scanner) (Hold, error) {
+	var hold Hold
+	var readyAt sql.NullTime
+	err := row.Scan(&hold.Id, &hold.MemberId, &hold.BookId, &hold.PlacedAt, &readyAt, &hold.CreatedAt, &hold.UpdatedAt)
+	if readyAt.Valid {
+		hold.ReadyAt = &readyAt.Time
+	}
+	return hold, err
+}
+
+func (s *sqlStore) ListHolds(ctx context.Context, bookId int, after ChangedAfter) ([]Hold, error) {
+	if _, err := s.GetBookFields(ctx, bookId, []string{"id"}); err != nil {
+		return nil, err
+	}
+	clause, args := where("tenant_id = ?", tenantId(ctx)).and("book_id = ?", bookId).changedAfter(after, "created_at", "updated_at").clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT "+holdColumns+" FROM holds"+clause+" ORDER BY placed_at, id"+s.lockClause()), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := []Hold{}
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, hold)
+	}
+	return holds, rows.Err()
+}
+
+func (s *sqlStore) CreateHold(ctx context.Context, hold *Hold) error {
+	if _, err := s.GetBookFields(ctx, hold.BookId, []string{"id"}); err != nil {
+		return err
+	}
+	id, err := s.insert(ctx, "INSERT INTO holds (tenant_id, member_id, book_id, placed_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
+		tenantId(ctx), hold.MemberId, hold.BookId, hold.PlacedAt, hold.CreatedAt, hold.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	hold.Id = id
+	return nil
+}
+
+func (s *sqlStore) SetHoldReady(ctx context.Context, id int, readyAt time.Time) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE holds SET ready_at = ?, updated_at = ? WHERE tenant_id = ? AND id = ?"), readyAt, readyAt, tenantId(ctx), id)
+	if err != nil {
+		return err
+	}
+	// MySQL counts only rows that changed, so check for the hold when
+	// nothing did.
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		var found int
+		err := s.conn().QueryRowContext(ctx, s.dialect.rebind("SELECT id FROM holds WHERE tenant_id = ? AND id = ?"), tenantId(ctx), id).Scan(&found)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *sqlStore) DeleteHold(ctx context.Context, id int) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM holds WHERE tenant_id = ? AND id = ?"), tenantId(ctx), id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const idempotencyColumns = "caller, idempotency_key, fingerprint, status, content_type, body, created_at, expires_at"
+
+func scanIdempotencyRecord(row scanner) (IdempotencyRecord, error) {
+	var record IdempotencyRecord
+	err := row.Scan(&record.Caller, &record.Key, &record.Fingerprint, &record.Status, &record.ContentType, &record.Body, &record.CreatedAt, &record.ExpiresAt)
+	return record, err
+}
+
+func (s *sqlStore) getIdempotencyRecord(ctx context.Context, caller, key string) (IdempotencyRecord, error) {
+	row := s.conn().QueryRowContext(ctx, s.dialect.rebind("SELECT "+idempotencyColumns+" FROM idempotency_keys WHERE tenant_id = ? AND caller = ? AND idempotency_key = ?"+s.lockClause()), tenantId(ctx), caller, key)
+	record, err := scanIdempotencyRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return IdempotencyRecord{}, ErrNotFound
+	}
+	return record, err
+}
+
+func (s *sqlStore) ReserveIdempotencyKey(ctx context.Context, record IdempotencyRecord, now time.Time) (IdempotencyRecord, bool, error) {
+	var existing IdempotencyRecord
+	reserved := false
+	err := s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		current, err := t.getIdempotencyRecord(ctx, record.Caller, record.Key)
+		if err == nil && current.ExpiresAt.After(now) {
+			existing = current
+			return nil
+		} else if err == nil {
+			_, err = t.conn().ExecContext(ctx, t.dialect.rebind("DELETE FROM idempotency_keys WHERE tenant_id = ? AND caller = ? AND idempotency_key = ?"), tenantId(ctx), record.Caller, record.Key)
+		} else if errors.Is(err, ErrNotFound) {
+			err = nil
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = t.conn().ExecContext(ctx, t.dialect.rebind("INSERT INTO idempotency_keys (tenant_id, "+idempotencyColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+			tenantId(ctx), record.Caller, record.Key, record.Fingerprint, record.Status, record.ContentType, record.Body, record.CreatedAt, record.ExpiresAt)
+		reserved = err == nil
+		return err
+	})
+	if err != nil {
+		// Another request may have inserted the key first.
+		if current, getErr := s.getIdempotencyRecord(ctx, record.Caller, record.Key); getErr == nil && current.ExpiresAt.After(now) {
+			return current, false, nil
+		}
+		return IdempotencyRecord{}, false, err
+	}
+	if !reserved {
+		return existing, false, nil
+	}
+	return record, true, nil
+}
+
+func (s *sqlStore) CompleteIdempotencyKey(ctx context.Context, record IdempotencyRecord) error {
+	_, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE idempotency_keys SET status = ?, content_type = ?, body = ?, expires_at = ? WHERE tenant_id = ? AND caller = ? AND idempotency_key = ?"),
+		record.Status, record.ContentType, record.Body, record.ExpiresAt, tenantId(ctx), record.Caller, record.Key)
+	return err
+}
+
+func (s *sqlStore) DeleteIdempotencyKey(ctx context.Context, caller, key string) error {
+	_, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM idempotency_keys WHERE tenant_id = ? AND caller = ? AND idempotency_key = ?"), tenantId(ctx), caller, key)
+	return err
+}
+
+func (s *sqlStore) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM idempotency_keys WHERE expires_at < ?"), now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
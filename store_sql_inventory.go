@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+func (s *sqlStore) GetBookStock(ctx context.Context, bookId int) (int, error) {
+	var stock int
+	err := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT stock FROM books WHERE tenant_id = ? AND id = ?"), tenantId(ctx), bookId).Scan(&stock)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return stock, err
+}
+
+func (s *sqlStore) AddBookStock(ctx context.Context, bookId, quantity int) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE books SET stock = stock + ? WHERE tenant_id = ? AND id = ?"), quantity, tenantId(ctx), bookId)
+	if err != nil {
+		return err
+	}
+	// MySQL counts only rows that changed, so check for the book when
+	// nothing did.
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		_, err := s.GetBookStock(ctx, bookId)
+		return err
+	}
+	return nil
+}
+
+func (s *sqlStore) ListBookStock(ctx context.Context, bookIds []int) (map[int]int, error) {
+	stock := make(map[int]int, len(bookIds))
+	if len(bookIds) == 0 {
+		return stock, nil
+	}
+	condition, ids := sqlIn("id", bookIds)
+	clause, args := where("tenant_id = ?", tenantId(ctx)).and(condition, ids...).clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT id, stock FROM books"+clause), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, quantity int
+		if err := rows.Scan(&id, &quantity); err != nil {
+			return nil, err
+		}
+		stock[id] = quantity
+	}
+	return stock, rows.Err()
+}
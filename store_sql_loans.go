@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const loanColumns = "loans.id, loans.member_id, loans.book_id, loans.copy_id, loans.borrowed_at, loans.due_at, loans.returned_at, loans.created_at, loans.updated_at"
+
+func scanLoan(row scanner, extra ...any) (Loan, error) {
+	var loan Loan
+	var copyId sql.NullInt64
+	var returnedAt sql.NullTime
+	err := row.Scan(append([]any{&loan.Id, &loan.MemberId, &loan.BookId, &copyId, &loan.BorrowedAt, &loan.DueAt, &returnedAt, &loan.CreatedAt, &loan.UpdatedAt}, extra...)...)
+	if copyId.Valid {
+		id := int(copyId.Int64)
+		loan.CopyId = &id
+	}
+	if returnedAt.Valid {
+		loan.ReturnedAt = &returnedAt.Time
+	}
+	return loan, err
+}
+
+func (s *sqlStore) ListMemberLoans(ctx context.Context, memberId int, after ChangedAfter) ([]Loan, error) {
+	clause, args := where("loans.tenant_id = ?", tenantId(ctx)).and("loans.member_id = ?", memberId).changedAfter(after, "loans.created_at", "loans.updated_at").clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT "+loanColumns+", "+joinedBookColumns+" FROM loans JOIN books ON books.id = loans.book_id"+clause+" ORDER BY loans.borrowed_at DESC, loans.id DESC"),
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	loans := []Loan{}
+	for rows.Next() {
+		book := &Book{}
+		loan, err := scanLoan(rows, book.fieldPointers()...)
+		if err != nil {
+			return nil, err
+		}
+		loan.Book = book
+		loans = append(loans, loan)
+	}
+	return loans, rows.Err()
+}
+
+func (s *sqlStore) GetLoan(ctx context.Context, id int) (Loan, error) {
+	row := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT "+loanColumns+" FROM loans WHERE tenant_id = ? AND id = ?"+s.lockClause()), tenantId(ctx), id)
+	loan, err := scanLoan(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Loan{}, ErrNotFound
+	}
+	return loan, err
+}
+
+func (s *sqlStore) CreateLoan(ctx context.Context, loan *Loan) error {
+	id, err := s.insert(ctx, "INSERT INTO loans (tenant_id, member_id, book_id, copy_id, borrowed_at, due_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		tenantId(ctx), loan.MemberId, loan.BookId, loan.CopyId, loan.BorrowedAt, loan.DueAt, loan.CreatedAt, loan.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	loan.Id = id
+	return nil
+}
+
+func (s *sqlStore) ReturnLoan(ctx context.Context, id int, returnedAt time.Time) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE loans SET returned_at = ?, updated_at = ? WHERE tenant_id = ? AND id = ?"), returnedAt, returnedAt, tenantId(ctx), id)
+	if err != nil {
+		return err
+	}
+	// MySQL counts only rows that changed, so check for the loan when
+	// nothing did.
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		_, err := s.GetLoan(ctx, id)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+func (s *sqlStore) LoyaltyBalance(ctx context.Context, userId int, now time.Time) (int, error) {
+	var balance int
+	err := s.reader().QueryRowContext(ctx, s.dialect.rebind(
+		"SELECT COALESCE(SUM(remaining), 0) FROM loyalty_points WHERE tenant_id = ? AND user_id = ? AND kind = ? AND (expires_at IS NULL OR expires_at > ?)"),
+		tenantId(ctx), userId, PointsEarned, now).Scan(&balance)
+	return balance, err
+}
+
+func (s *sqlStore) ListLoyaltyEntries(ctx context.Context, userId, limit int, after ChangedAfter) ([]LoyaltyEntry, error) {
+	clause, args := where("tenant_id = ?", tenantId(ctx)).and("user_id = ?", userId).changedAfter(after, "created_at", "created_at").clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind(
+		"SELECT id, kind, points, remaining, sale_id, expires_at, created_at FROM loyalty_points"+clause+" ORDER BY id DESC LIMIT ?"),
+		append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []LoyaltyEntry{}
+	for rows.Next() {
+		entry := LoyaltyEntry{UserId: userId}
+		var saleId sql.NullInt64
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&entry.Id, &entry.Kind, &entry.Points, &entry.Remaining, &saleId, &expiresAt, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entry.SaleId = int(saleId.Int64)
+		if expiresAt.Valid {
+			entry.ExpiresAt = &expiresAt.Time
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (s *sqlStore) AddLoyaltyPoints(ctx context.Context, entry *LoyaltyEntry) error {
+	id, err := s.insert(ctx, "INSERT INTO loyalty_points (tenant_id, user_id, kind, points, remaining, sale_id, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		tenantId(ctx), entry.UserId, entry.Kind, entry.Points, entry.Remaining, nullInt(entry.SaleId), entry.ExpiresAt, entry.CreatedAt)
+	if err != nil {
+		return err
+	}
+	entry.Id = id
+	return nil
+}
+
+// pointsLot is what is left of points earned at once.
+type pointsLot struct {
+	id, userId, remaining int
+}
+
+// The earned points matching where, oldest first, locked until the
+// transaction ends.
+func (s *sqlStore) pointsLots(ctx context.Context, where string, args ...any) ([]pointsLot, error) {
+	rows, err := s.conn().QueryContext(ctx, s.dialect.rebind(
+		"SELECT id, user_id, remaining FROM loyalty_points WHERE tenant_id = ? AND kind = ? AND remaining > 0 AND "+where+" ORDER BY id"+s.lockClause()),
+		append([]any{tenantId(ctx), PointsEarned}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lots []pointsLot
+	for rows.Next() {
+		var lot pointsLot
+		if err := rows.Scan(&lot.id, &lot.userId, &lot.remaining); err != nil {
+			return nil, err
+		}
+		lots = append(lots, lot)
+	}
+	return lots, rows.Err()
+}
+
+func (s *sqlStore) RedeemLoyaltyPoints(ctx context.Context, userId, points, saleId int, now time.Time) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		lots, err := t.pointsLots(ctx, "user_id = ? AND (expires_at IS NULL OR expires_at > ?)", userId, now)
+		if err != nil {
+			return err
+		}
+		available := 0
+		for _, lot := range lots {
+			available += lot.remaining
+		}
+		if available < points {
+			return ErrNotEnoughPoints
+		}
+
+		left := points
+		for _, lot := range lots {
+			if left == 0 {
+				break
+			}
+			taken := min(lot.remaining, left)
+			if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("UPDATE loyalty_points SET remaining = ? WHERE id = ?"), lot.remaining-taken, lot.id); err != nil {
+				return err
+			}
+			left -= taken
+		}
+		return t.AddLoyaltyPoints(ctx, &LoyaltyEntry{UserId: userId, Kind: PointsRedeemed, Points: -points, SaleId: saleId, CreatedAt: now})
+	})
+}
+
+func (s *sqlStore) ExpireLoyaltyPoints(ctx context.Context, now time.Time) (int, error) {
+	total := 0
+	err := s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		lots, err := t.pointsLots(ctx, "expires_at <= ?", now)
+		if err != nil {
+			return err
+		}
+		for _, lot := range lots {
+			if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("UPDATE loyalty_points SET remaining = 0 WHERE id = ?"), lot.id); err != nil {
+				return err
+			}
+			if err := t.AddLoyaltyPoints(ctx, &LoyaltyEntry{UserId: lot.userId, Kind: PointsExpired, Points: -lot.remaining, CreatedAt: now}); err != nil {
+				return err
+			}
+			total += lot.remaining
+		}
+		return nil
+	})
+	return total, err
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const memberColumns = "id, card_number, name, email, phone, address, tier, expires_at, user_id, created_at, updated_at"
+
+func scanMember(row scanner) (Member, error) {
+	var member Member
+	var expiresAt sql.NullTime
+	var userId sql.NullInt64
+	err := row.Scan(&member.Id, &member.CardNumber, &member.Name, &member.Email, &member.Phone, &member.Address, &member.Tier, &expiresAt, &userId, &member.CreatedAt, &member.UpdatedAt)
+	if err != nil {
+		return Member{}, err
+	}
+	if member.Email, member.Phone, member.Address, err = decryptMemberContacts(member.Email, member.Phone, member.Address); err != nil {
+		return Member{}, err
+	}
+	if expiresAt.Valid {
+		member.ExpiresAt = &expiresAt.Time
+	}
+	if userId.Valid {
+		id := int(userId.Int64)
+		member.UserId = &id
+	}
+	return member, nil
+}
+
+// The member's email, phone and address as the database keeps them,
+// encrypted if set up.
+func encryptMemberContacts(email, phone, address string) (string, string, string, error) {
+	var err error
+	if email, err = encryptPII("members.email", email); err != nil {
+		return "", "", "", err
+	}
+	if phone, err = encryptPII("members.phone", phone); err != nil {
+		return "", "", "", err
+	}
+	if address, err = encryptPII("members.address", address); err != nil {
+		return "", "", "", err
+	}
+	return email, phone, address, nil
+}
+
+// The member's email, phone and address as the database keeps them,
+// decrypted.
+func decryptMemberContacts(email, phone, address string) (string, string, string, error) {
+	var err error
+	if email, err = decryptPII("members.email", email); err != nil {
+		return "", "", "", err
+	}
+	if phone, err = decryptPII("members.phone", phone); err != nil {
+		return "", "", "", err
+	}
+	if address, err = decryptPII("members.address", address); err != nil {
+		return "", "", "", err
+	}
+	return email, phone, address, nil
+}
+
+// Encrypt the contact details of members from before encryption was set
+// up, and those encrypted with an earlier key, in every tenant.
+func (s *sqlStore) EncryptMembers(ctx context.Context) error {
+	if pii == nil {
+		return nil
+	}
+	current := pii.prefix() + "%"
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind("SELECT id, email, phone, address FROM members "+
+		"WHERE (email <> '' AND email NOT LIKE ?) OR (phone <> '' AND phone NOT LIKE ?) OR (address <> '' AND address NOT LIKE ?) ORDER BY id"),
+		current, current, current)
+	if err != nil {
+		return err
+	}
+	type contacts struct {
+		id                    int
+		email, phone, address string
+	}
+	var members []contacts
+	for rows.Next() {
+		var m contacts
+		if err := rows.Scan(&m.id, &m.email, &m.phone, &m.address); err != nil {
+			rows.Close()
+			return err
+		}
+		members = append(members, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, m := range members {
+		email, phone, address, err := decryptMemberContacts(m.email, m.phone, m.address)
+		if err != nil {
+			return err
+		}
+		if email, phone, address, err = encryptMemberContacts(email, phone, address); err != nil {
+			return err
+		}
+		// A member changed meanwhile was saved with the current key.
+		if _, err := s.db.ExecContext(ctx, s.dialect.rebind("UPDATE members SET email = ?, phone = ?, address = ? WHERE id = ? AND email = ? AND phone = ? AND address = ?"),
+			email, phone, address, m.id, m.email, m.phone, m.address); err != nil {
+			return err
+		}
+	}
+	if len(members) > 0 {
+		log.Printf("Encrypted the contact details of %d members", len(members))
+	}
+	return nil
+}
+
+// The store error for a write to members the database refused, if it is
+// one the callers handle.
+func (d dialect) memberWriteError(err error) error {
+	if d.violatesUnique(err, "members_card_number_idx", "members.card_number") {
+		return ErrDuplicateCardNumber
+	}
+	return err
+}
+
+func (s *sqlStore) ListMembers(ctx context.Context, after ChangedAfter) ([]Member, error) {
+	clause, args := where("tenant_id = ?", tenantId(ctx)).and("deleted_at IS NULL").changedAfter(after, "created_at", "updated_at").clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT "+memberColumns+" FROM members"+clause+" ORDER BY name, id"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []Member{}
+	for rows.Next() {
+		member, err := scanMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+func (s *sqlStore) GetMember(ctx context.Context, id int) (Member, error) {
+	row := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT "+memberColumns+" FROM members WHERE tenant_id = ? AND id = ? AND deleted_at IS NULL"+s.lockClause()), tenantId(ctx), id)
+	member, err := scanMember(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Member{}, ErrNotFound
+	}
+	return member, err
+}
+
+func (s *sqlStore) GetMemberByCard(ctx context.Context, cardNumber string) (Member, error) {
+	row := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT "+memberColumns+" FROM members WHERE tenant_id = ? AND card_number = ? AND deleted_at IS NULL"+s.lockClause()), tenantId(ctx), cardNumber)
+	member, err := scanMember(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Member{}, ErrNotFound
+	}
+	return member, err
+}
+
+func (s *sqlStore) CreateMember(ctx context.Context, member *Member) error {
+	email, phone, address, err := encryptMemberContacts(member.Email, member.Phone, member.Address)
+	if err != nil {
+		return err
+	}
+	id, err := s.insert(ctx, "INSERT INTO members (tenant_id, card_number, name, email, phone, address, tier, expires_at, user_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		tenantId(ctx), member.CardNumber, member.Name, email, phone, address, member.Tier, member.ExpiresAt, member.UserId, member.CreatedAt, member.UpdatedAt)
+	if err != nil {
+		return s.dialect.memberWriteError(err)
+	}
+	member.Id = id
+	return nil
+}
+
+func (s *sqlStore) UpdateMember(ctx context.Context, member Member) error {
+	email, phone, address, err := encryptMemberContacts(member.Email, member.Phone, member.Address)
+	if err != nil {
+		return err
+	}
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE members SET card_number = ?, name = ?, email = ?, phone = ?, address = ?, tier = ?, expires_at = ?, user_id = ?, updated_at = ? WHERE tenant_id = ? AND id = ? AND deleted_at IS NULL"),
+		member.CardNumber, member.Name, email, phone, address, member.Tier, member.ExpiresAt, member.UserId, member.UpdatedAt, tenantId(ctx), member.Id)
+	if err != nil {
+		return s.dialect.memberWriteError(err)
+	}
+	// MySQL counts only rows that changed, so check for the member when
+	// nothing did.
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		_, err := s.GetMember(ctx, member.Id)
+		return err
+	}
+	return nil
+}
+
+// The card number a deleted member is left with, which no card can have,
+// freeing theirs.
+func deletedCardNumber(id int) string {
+	return fmt.Sprintf("deleted-%d", id)
+}
+
+func (s *sqlStore) DeleteMember(ctx context.Context, id int) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		if _, err := t.GetMember(ctx, id); err != nil {
+			return err
+		}
+		var open int
+		err := t.conn().QueryRowContext(ctx, t.dialect.rebind("SELECT (SELECT COUNT(*) FROM loans WHERE tenant_id = ? AND member_id = ? AND returned_at IS NULL) + (SELECT COUNT(*) FROM fines WHERE tenant_id = ? AND member_id = ? AND settled_at IS NULL)"),
+			tenantId(ctx), id, tenantId(ctx), id).Scan(&open)
+		if err != nil {
+			return err
+		}
+		if open > 0 {
+			return ErrMemberInUse
+		}
+
+		if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("DELETE FROM holds WHERE tenant_id = ? AND member_id = ?"), tenantId(ctx), id); err != nil {
+			return err
+		}
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		_, err = t.conn().ExecContext(ctx, t.dialect.rebind("UPDATE members SET card_number = ?, name = ?, email = '', phone = '', address = '', user_id = NULL, updated_at = ?, deleted_at = ? WHERE tenant_id = ? AND id = ?"),
+			deletedCardNumber(id), erasedName, now, now, tenantId(ctx), id)
+		return err
+	})
+}
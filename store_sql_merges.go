@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// The tables whose rows move to the survivor when books are merged: all
+// of those that refer to books but the duplicate's views, price history
+// and recommendations, which it keeps.
+var mergedBookTables = []string{
+	"reviews", "loans", "copies", "holds", "notes", "quotes",
+	"purchase_order_lines", "sale_lines", "donations",
+}
+
+// The tables with a row per book and something else, the key column:
+// the duplicate's rows only move for what the survivor has no row for, or
+// no row in decidedBy for, and go with the duplicate otherwise. A book is
+// in one series and of one work at most, which the survivor keeps.
+var mergedKeyedTables = []struct {
+	table, key, decidedBy string
+}{
+	// shelf_items decides for the shelf's other tables, so it moves after
+	// them.
+	{"reading_status_changes", "user_id", "shelf_items"},
+	{"reading_progress", "user_id", "shelf_items"},
+	{"shelf_items", "user_id", ""},
+	{"wishlist_items", "user_id", ""},
+	{"push_watches", "subscription_id", ""},
+	{"collection_items", "collection_id", ""},
+	{"supplier_prices", "supplier_id", ""},
+	{"book_categories", "name", ""},
+	{"book_tags", "name", ""},
+	{"series_books", "tenant_id", ""},
+	{"work_editions", "tenant_id", ""},
+}
+
+func (s *sqlStore) MergeBook(ctx context.Context, duplicateId, survivorId int, mergedAt time.Time) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		for _, id := range []int{duplicateId, survivorId} {
+			if _, err := t.getBookFields(ctx, t.conn(), id, []string{"id"}); err != nil {
+				return err
+			}
+			if _, err := t.MergedInto(ctx, id); err == nil {
+				return ErrNotFound
+			} else if !errors.Is(err, ErrNotFound) {
+				return err
+			}
+		}
+
+		for _, table := range mergedBookTables {
+			if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("UPDATE "+table+" SET book_id = ? WHERE tenant_id = ? AND book_id = ?"),
+				survivorId, tenantId(ctx), duplicateId); err != nil {
+				return err
+			}
+		}
+		for _, keyed := range mergedKeyedTables {
+			decidedBy := keyed.table
+			if keyed.decidedBy != "" {
+				decidedBy = keyed.decidedBy
+			}
+			// MySQL can't update a table it selects from, hence the
+			// derived table.
+			_, err := t.conn().ExecContext(ctx, t.dialect.rebind(
+				"UPDATE "+keyed.table+" SET book_id = ? WHERE tenant_id = ? AND book_id = ? AND "+keyed.key+" NOT IN (SELECT "+keyed.key+" FROM (SELECT "+keyed.key+" FROM "+decidedBy+" WHERE tenant_id = ? AND book_id = ?) AS survivors)"),
+				survivorId, tenantId(ctx), duplicateId, tenantId(ctx), survivorId)
+			if err != nil {
+				return err
+			}
+		}
+		// The copies in stock are the survivor's now.
+		var stock int
+		if err := t.conn().QueryRowContext(ctx, t.dialect.rebind("SELECT stock FROM books WHERE tenant_id = ? AND id = ?"), tenantId(ctx), duplicateId).Scan(&stock); err != nil {
+			return err
+		}
+		if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("UPDATE books SET stock = stock + ? WHERE tenant_id = ? AND id = ?"), stock, tenantId(ctx), survivorId); err != nil {
+			return err
+		}
+		// The duplicate stays, archived, its ISBN free for the survivor.
+		if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("UPDATE books SET stock = 0, isbn = NULL, archived_at = ? WHERE tenant_id = ? AND id = ?"), mergedAt, tenantId(ctx), duplicateId); err != nil {
+			return err
+		}
+
+		if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("UPDATE book_merges SET survivor_id = ? WHERE tenant_id = ? AND survivor_id = ?"),
+			survivorId, tenantId(ctx), duplicateId); err != nil {
+			return err
+		}
+		_, err := t.conn().ExecContext(ctx, t.dialect.rebind("INSERT INTO book_merges (tenant_id, book_id, survivor_id, merged_at) VALUES (?, ?, ?, ?)"),
+			tenantId(ctx), duplicateId, survivorId, mergedAt)
+		return err
+	})
+}
+
+// The condition leaving out of a query on books those merged into others.
+const notMergedBook = "NOT EXISTS (SELECT 1 FROM book_merges WHERE book_merges.tenant_id = books.tenant_id AND book_merges.book_id = books.id)"
+
+func (s *sqlStore) MergedInto(ctx context.Context, id int) (int, error) {
+	var survivorId int
+	err := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT survivor_id FROM book_merges WHERE tenant_id = ? AND book_id = ?"), tenantId(ctx), id).Scan(&survivorId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return survivorId, err
+}
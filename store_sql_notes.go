@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+const noteColumns = "notes.id, notes.user_id, notes.book_id, notes.kind, notes.body, notes.page, notes.created_at, notes.updated_at"
+
+func scanNote(row scanner, extra ...any) (Note, error) {
+	var note Note
+	var page sql.NullInt64
+	err := row.Scan(append([]any{&note.Id, &note.UserId, &note.BookId, &note.Kind, &note.Text, &page, &note.CreatedAt, &note.UpdatedAt}, extra...)...)
+	if page.Valid {
+		n := int(page.Int64)
+		note.Page = &n
+	}
+	return note, err
+}
+
+func (s *sqlStore) ListNotes(ctx context.Context, userId int, query NoteQuery) ([]Note, error) {
+	w := where("notes.tenant_id = ?", tenantId(ctx)).and("notes.user_id = ?", userId)
+	if query.BookId != 0 {
+		w.and("notes.book_id = ?", query.BookId)
+	}
+	for _, word := range searchWords(query.Search) {
+		w.and("LOWER(notes.body) LIKE ? ESCAPE '!'", "%"+escapeLike(word)+"%")
+	}
+	w.changedAfter(query.ChangedAfter, "notes.created_at", "notes.updated_at")
+	clause, args := w.clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT "+noteColumns+", "+joinedBookColumns+" FROM notes JOIN books ON books.id = notes.book_id"+clause+" ORDER BY notes.created_at DESC, notes.id DESC"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []Note{}
+	for rows.Next() {
+		book := &Book{}
+		note, err := scanNote(rows, book.fieldPointers()...)
+		if err != nil {
+			return nil, err
+		}
+		note.Book = book
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+func (s *sqlStore) GetNote(ctx context.Context, userId, id int) (Note, error) {
+	row := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT "+noteColumns+" FROM notes WHERE tenant_id = ? AND user_id = ? AND id = ?"+s.lockClause()), tenantId(ctx), userId, id)
+	note, err := scanNote(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Note{}, ErrNotFound
+	}
+	return note, err
+}
+
+func (s *sqlStore) CreateNote(ctx context.Context, note *Note) error {
+	id, err := s.insert(ctx, "INSERT INTO notes (tenant_id, user_id, book_id, kind, body, page, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		tenantId(ctx), note.UserId, note.BookId, note.Kind, note.Text, note.Page, note.CreatedAt, note.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	note.Id = id
+	return nil
+}
+
+func (s *sqlStore) UpdateNote(ctx context.Context, note Note) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE notes SET kind = ?, body = ?, page = ?, updated_at = ? WHERE tenant_id = ? AND user_id = ? AND id = ?"),
+		note.Kind, note.Text, note.Page, note.UpdatedAt, tenantId(ctx), note.UserId, note.Id)
+	if err != nil {
+		return err
+	}
+	// MySQL counts only rows that changed, so check for the note when
+	// nothing did.
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		_, err := s.GetNote(ctx, note.UserId, note.Id)
+		return err
+	}
+	return nil
+}
+
+func (s *sqlStore) DeleteNote(ctx context.Context, userId, id int) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM notes WHERE tenant_id = ? AND user_id = ? AND id = ?"), tenantId(ctx), userId, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
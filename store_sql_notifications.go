@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+func (s *sqlStore) EnqueueNotification(ctx context.Context, n *Notification) error {
+	n.TenantId = tenantId(ctx)
+	id, err := s.insert(ctx, "INSERT INTO notifications (tenant_id, channel, recipient, kind, subject, body, html, attempt, next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		n.TenantId, n.Channel, n.Recipient, n.Kind, n.Subject, n.Body, n.HTML, n.Attempt, n.NextAttemptAt, n.CreatedAt)
+	if err != nil {
+		return err
+	}
+	n.Id = id
+	return nil
+}
+
+func (s *sqlStore) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Notification, error) {
+	var notifications []Notification
+	err := s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		rows, err := t.conn().QueryContext(ctx, t.dialect.rebind(
+			"SELECT id, tenant_id, channel, recipient, kind, subject, body, html, attempt, created_at FROM notifications WHERE next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?"+t.lockClause()),
+			now, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var n Notification
+			if err := rows.Scan(&n.Id, &n.TenantId, &n.Channel, &n.Recipient, &n.Kind, &n.Subject, &n.Body, &n.HTML, &n.Attempt, &n.CreatedAt); err != nil {
+				return err
+			}
+			n.NextAttemptAt = now.Add(lease)
+			notifications = append(notifications, n)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+
+		for _, n := range notifications {
+			if err := t.UpdateNotification(withTenantId(ctx, n.TenantId), n); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return notifications, err
+}
+
+func (s *sqlStore) UpdateNotification(ctx context.Context, n Notification) error {
+	_, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE notifications SET attempt = ?, next_attempt_at = ? WHERE id = ? AND tenant_id = ?"),
+		n.Attempt, n.NextAttemptAt, n.Id, tenantId(ctx))
+	return err
+}
+
+func (s *sqlStore) DeleteNotification(ctx context.Context, id int) error {
+	_, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM notifications WHERE id = ? AND tenant_id = ?"), id, tenantId(ctx))
+	return err
+}
+
+func (s *sqlStore) AddNotificationFailure(ctx context.Context, failure *NotificationFailure) error {
+	id, err := s.insert(ctx, "INSERT INTO notification_failures (tenant_id, channel, recipient, kind, subject, body, attempts, error, created_at, failed_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		tenantId(ctx), failure.Channel, failure.Recipient, failure.Kind, failure.Subject, failure.Body, failure.Attempts, failure.Error, failure.CreatedAt, failure.FailedAt)
+	if err != nil {
+		return err
+	}
+	failure.Id = id
+	return nil
+}
+
+func (s *sqlStore) ListNotificationFailures(ctx context.Context, limit int, after ChangedAfter) ([]NotificationFailure, error) {
+	clause, args := where("tenant_id = ?", tenantId(ctx)).changedAfter(after, "created_at", "failed_at").clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind(
+		"SELECT id, channel, recipient, kind, subject, body, attempts, error, created_at, failed_at FROM notification_failures"+clause+" ORDER BY id DESC LIMIT ?"),
+		append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	failures := []NotificationFailure{}
+	for rows.Next() {
+		var f NotificationFailure
+		if err := rows.Scan(&f.Id, &f.Channel, &f.Recipient, &f.Kind, &f.Subject, &f.Body, &f.Attempts, &f.Error, &f.CreatedAt, &f.FailedAt); err != nil {
+			return nil, err
+		}
+		failures = append(failures, f)
+	}
+	return failures, rows.Err()
+}
+
+func (s *sqlStore) NotificationPreferences(ctx context.Context, recipient string) (NotificationPreferences, error) {
+	q := s.reader()
+	preferences := NotificationPreferences{Recipient: recipient, Preferences: []NotificationPreference{}}
+
+	var phone string
+	err := q.QueryRowContext(ctx, s.dialect.rebind("SELECT phone FROM notification_contacts WHERE tenant_id = ? AND recipient = ?"), tenantId(ctx), recipient).Scan(&phone)
+	if err == nil {
+		preferences.Phone = &phone
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return NotificationPreferences{}, err
+	}
+
+	rows, err := q.QueryContext(ctx, s.dialect.rebind("SELECT kind, enabled, channel FROM notification_preferences WHERE tenant_id = ? AND recipient = ? ORDER BY kind"), tenantId(ctx), recipient)
+	if err != nil {
+		return NotificationPreferences{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p NotificationPreference
+		if err := rows.Scan(&p.Kind, &p.Enabled, &p.Channel); err != nil {
+			return NotificationPreferences{}, err
+		}
+		preferences.Preferences = append(preferences.Preferences, p)
+	}
+	return preferences, rows.Err()
+}
+
+func (s *sqlStore) SetNotificationPreference(ctx context.Context, recipient string, preference NotificationPreference) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("DELETE FROM notification_preferences WHERE tenant_id = ? AND recipient = ? AND kind = ?"), tenantId(ctx), recipient, preference.Kind); err != nil {
+			return err
+		}
+		_, err := t.conn().ExecContext(ctx, t.dialect.rebind("INSERT INTO notification_preferences (tenant_id, recipient, kind, enabled, channel) VALUES (?, ?, ?, ?, ?)"),
+			tenantId(ctx), recipient, preference.Kind, preference.Enabled, preference.Channel)
+		return err
+	})
+}
+
+func (s *sqlStore) SetNotificationPhone(ctx context.Context, recipient, phone string) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("DELETE FROM notification_contacts WHERE tenant_id = ? AND recipient = ?"), tenantId(ctx), recipient); err != nil {
+			return err
+		}
+		if phone == "" {
+			return nil
+		}
+		_, err := t.conn().ExecContext(ctx, t.dialect.rebind("INSERT INTO notification_contacts (tenant_id, recipient, phone) VALUES (?, ?, ?)"), tenantId(ctx), recipient, phone)
+		return err
+	})
+}
+
+func (s *sqlStore) DeleteNotificationFailuresBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM notification_failures WHERE failed_at < ?"), before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
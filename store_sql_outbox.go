@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+)
+
+func (s *sqlStore) AddOutboxEvent(ctx context.Context, event *Event) error {
+	payload, err := json.Marshal(event.Book)
+	if err != nil {
+		return err
+	}
+
+	event.TenantId = tenantId(ctx)
+	id, err := s.insert(ctx, "INSERT INTO outbox (tenant_id, event_type, payload, created_at) VALUES (?, ?, ?, ?)",
+		event.TenantId, event.Type, string(payload), event.Time)
+	if err != nil {
+		return err
+	}
+	event.Id = int64(id)
+	return nil
+}
+
+func (s *sqlStore) PendingOutboxEvents(ctx context.Context, limit int) ([]Event, error) {
+	rows, err := s.conn().QueryContext(ctx, s.dialect.rebind("SELECT id, tenant_id, event_type, payload, created_at FROM outbox ORDER BY id LIMIT ?"+s.lockClause()), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var event Event
+		var payload string
+		if err := rows.Scan(&event.Id, &event.TenantId, &event.Type, &payload, &event.Time); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(payload), &event.Book); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (s *sqlStore) DeleteOutboxEvents(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	condition, args := sqlIn("id", ids)
+	clause, args := where(condition, args...).clause()
+	_, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM outbox"+clause), args...)
+	return err
+}
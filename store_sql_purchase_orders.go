@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const purchaseOrderColumns = "id, supplier_id, reference, status, ordered_at, closed_at, created_at, updated_at"
+
+const orderLineColumns = "id, order_id, book_id, title, quantity, unit_cost, received"
+
+func scanPurchaseOrder(row scanner) (PurchaseOrder, error) {
+	var order PurchaseOrder
+	var closedAt sql.NullTime
+	err := row.Scan(&order.Id, &order.SupplierId, &order.Reference, &order.Status, &order.OrderedAt, &closedAt, &order.CreatedAt, &order.UpdatedAt)
+	if closedAt.Valid {
+		order.ClosedAt = &closedAt.Time
+	}
+	order.Lines = []OrderLine{}
+	return order, err
+}
+
+func (s *sqlStore) ListPurchaseOrders(ctx context.Context, query PurchaseOrderQuery) ([]PurchaseOrder, error) {
+	w := where("tenant_id = ?", tenantId(ctx))
+	if query.Status != "" {
+		w.and("status = ?", query.Status)
+	}
+	if query.SupplierId != 0 {
+		w.and("supplier_id = ?", query.SupplierId)
+	}
+	w.changedAfter(query.ChangedAfter, "created_at", "updated_at")
+	condition, args := w.sql()
+	orders, err := s.queryPurchaseOrders(ctx, condition+" ORDER BY ordered_at, id", args...)
+	if err != nil || len(orders) == 0 {
+		return orders, err
+	}
+
+	byId := make(map[int]*PurchaseOrder, len(orders))
+	for i := range orders {
+		byId[orders[i].Id] = &orders[i]
+	}
+	err = s.queryOrderLines(ctx, "order_id IN (SELECT id FROM purchase_orders WHERE "+condition+")", args, func(orderId int, line OrderLine) {
+		if order, ok := byId[orderId]; ok {
+			order.Lines = append(order.Lines, line)
+		}
+	})
+	return orders, err
+}
+
+// Return the purchase orders matching where, which may end in an ORDER BY,
+// without their lines.
+func (s *sqlStore) queryPurchaseOrders(ctx context.Context, where string, args ...any) ([]PurchaseOrder, error) {
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT "+purchaseOrderColumns+" FROM purchase_orders WHERE "+where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []PurchaseOrder{}
+	for rows.Next() {
+		order, err := scanPurchaseOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+// Pass the lines matching where to add, with the ids of their orders, in
+// the order they were added.
+func (s *sqlStore) queryOrderLines(ctx context.Context, where string, args []any, add func(orderId int, line OrderLine)) error {
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT "+orderLineColumns+" FROM purchase_order_lines WHERE "+where+" ORDER BY id"), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderId int
+		var line OrderLine
+		var bookId sql.NullInt64
+		if err := rows.Scan(&line.Id, &orderId, &bookId, &line.Title, &line.Quantity, &line.UnitCost, &line.Received); err != nil {
+			return err
+		}
+		line.BookId = int(bookId.Int64)
+		add(orderId, line)
+	}
+	return rows.Err()
+}
+
+func (s *sqlStore) GetPurchaseOrder(ctx context.Context, id int) (PurchaseOrder, error) {
+	row := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT "+purchaseOrderColumns+" FROM purchase_orders WHERE tenant_id = ? AND id = ?"+s.lockClause()), tenantId(ctx), id)
+	order, err := scanPurchaseOrder(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return PurchaseOrder{}, ErrNotFound
+	} else if err != nil {
+		return PurchaseOrder{}, err
+	}
+
+	err = s.queryOrderLines(ctx, "order_id = ?", []any{id}, func(_ int, line OrderLine) {
+		order.Lines = append(order.Lines, line)
+	})
+	return order, err
+}
+
+func (s *sqlStore) CreatePurchaseOrder(ctx context.Context, order *PurchaseOrder) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		id, err := t.insert(ctx, "INSERT INTO purchase_orders (tenant_id, supplier_id, reference, status, ordered_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			tenantId(ctx), order.SupplierId, order.Reference, order.Status, order.OrderedAt, order.CreatedAt, order.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		order.Id = id
+
+		for i, line := range order.Lines {
+			id, err := t.insert(ctx, "INSERT INTO purchase_order_lines (tenant_id, order_id, book_id, title, quantity, unit_cost, received) VALUES (?, ?, ?, ?, ?, ?, ?)",
+				tenantId(ctx), order.Id, line.BookId, line.Title, line.Quantity, line.UnitCost, line.Received)
+			if err != nil {
+				return err
+			}
+			order.Lines[i].Id = id
+		}
+		return nil
+	})
+}
+
+func (s *sqlStore) ReceiveOrderLine(ctx context.Context, lineId, quantity int, receivedAt time.Time) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		result, err := t.conn().ExecContext(ctx, t.dialect.rebind("UPDATE purchase_order_lines SET received = received + ? WHERE tenant_id = ? AND id = ?"),
+			quantity, tenantId(ctx), lineId)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrNotFound
+		}
+		_, err = t.conn().ExecContext(ctx, t.dialect.rebind(
+			"UPDATE purchase_orders SET updated_at = ? WHERE tenant_id = ? AND id = (SELECT order_id FROM purchase_order_lines WHERE tenant_id = ? AND id = ?)"),
+			receivedAt, tenantId(ctx), tenantId(ctx), lineId)
+		return err
+	})
+}
+
+func (s *sqlStore) ClosePurchaseOrder(ctx context.Context, id int, status string, closedAt time.Time) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE purchase_orders SET status = ?, closed_at = ?, updated_at = ? WHERE tenant_id = ? AND id = ?"),
+		status, closedAt, closedAt, tenantId(ctx), id)
+	if err != nil {
+		return err
+	}
+	// MySQL counts only rows that changed, so check for the order when
+	// nothing did.
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		_, err := s.GetPurchaseOrder(ctx, id)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+func (s *sqlStore) SavePushSubscription(ctx context.Context, sub *PushSubscription, prices map[int]float64) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		if err := t.DeletePushSubscription(ctx, sub.Endpoint); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+
+		id, err := t.insert(ctx, "INSERT INTO push_subscriptions (tenant_id, endpoint, p256dh, auth, recipient, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			tenantId(ctx), sub.Endpoint, sub.Keys.P256dh, sub.Keys.Auth, sub.Recipient, sub.CreatedAt, sub.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		for bookId, price := range prices {
+			_, err := t.conn().ExecContext(ctx, t.dialect.rebind("INSERT INTO push_watches (tenant_id, subscription_id, book_id, price) VALUES (?, ?, ?, ?)"), tenantId(ctx), id, bookId, price)
+			if err != nil {
+				return err
+			}
+		}
+		sub.Id = id
+		return nil
+	})
+}
+
+const pushSubscriptionColumns = "id, endpoint, p256dh, auth, recipient, created_at, updated_at"
+
+func scanPushSubscription(row interface{ Scan(...any) error }) (PushSubscription, error) {
+	var sub PushSubscription
+	err := row.Scan(&sub.Id, &sub.Endpoint, &sub.Keys.P256dh, &sub.Keys.Auth, &sub.Recipient, &sub.CreatedAt, &sub.UpdatedAt)
+	return sub, err
+}
+
+func (s *sqlStore) GetPushSubscription(ctx context.Context, endpoint string) (PushSubscription, error) {
+	row := s.conn().QueryRowContext(ctx, s.dialect.rebind("SELECT "+pushSubscriptionColumns+" FROM push_subscriptions WHERE tenant_id = ? AND endpoint = ?"), tenantId(ctx), endpoint)
+	sub, err := scanPushSubscription(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return PushSubscription{}, ErrNotFound
+	}
+	return sub, err
+}
+
+func (s *sqlStore) ListPushSubscriptions(ctx context.Context, recipient string) ([]PushSubscription, error) {
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT "+pushSubscriptionColumns+" FROM push_subscriptions WHERE tenant_id = ? AND recipient = ? ORDER BY id"), tenantId(ctx), recipient)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []PushSubscription{}
+	for rows.Next() {
+		sub, err := scanPushSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+func (s *sqlStore) DeletePushSubscription(ctx context.Context, endpoint string) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		var id int
+		err := t.conn().QueryRowContext(ctx, t.dialect.rebind("SELECT id FROM push_subscriptions WHERE tenant_id = ? AND endpoint = ?"+t.lockClause()), tenantId(ctx), endpoint).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		} else if err != nil {
+			return err
+		}
+
+		if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("DELETE FROM push_watches WHERE subscription_id = ?"), id); err != nil {
+			return err
+		}
+		_, err = t.conn().ExecContext(ctx, t.dialect.rebind("DELETE FROM push_subscriptions WHERE id = ?"), id)
+		return err
+	})
+}
+
+func (s *sqlStore) WatchPrice(ctx context.Context, bookId int, price float64) ([]PriceWatch, error) {
+	var watches []PriceWatch
+	err := s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		rows, err := t.conn().QueryContext(ctx, t.dialect.rebind(
+			"SELECT s.endpoint, w.price FROM push_watches w JOIN push_subscriptions s ON s.id = w.subscription_id WHERE w.tenant_id = ? AND w.book_id = ? AND w.price > ? ORDER BY s.id"),
+			tenantId(ctx), bookId, price)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var w PriceWatch
+			if err := rows.Scan(&w.Endpoint, &w.Price); err != nil {
+				return err
+			}
+			watches = append(watches, w)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+
+		_, err = t.conn().ExecContext(ctx, t.dialect.rebind("UPDATE push_watches SET price = ? WHERE tenant_id = ? AND book_id = ?"), price, tenantId(ctx), bookId)
+		return err
+	})
+	return watches, err
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+)
+
+const quoteColumns = "quotes.id, quotes.user_id, quotes.book_id, quotes.body, quotes.author, quotes.public, quotes.created_at, quotes.updated_at"
+
+func scanQuote(row scanner, extra ...any) (Quote, error) {
+	var quote Quote
+	err := row.Scan(append([]any{&quote.Id, &quote.UserId, &quote.BookId, &quote.Text, &quote.Author, &quote.Public, &quote.CreatedAt, &quote.UpdatedAt}, extra...)...)
+	return quote, err
+}
+
+// Load the quotes matching condition, with args, and their books, newest
+// first.
+func (s *sqlStore) queryQuotes(ctx context.Context, condition string, args ...any) ([]Quote, error) {
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT "+quoteColumns+", "+joinedBookColumns+" FROM quotes JOIN books ON books.id = quotes.book_id WHERE quotes.tenant_id = ? AND "+condition+" ORDER BY quotes.created_at DESC, quotes.id DESC"),
+		append([]any{tenantId(ctx)}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	quotes := []Quote{}
+	for rows.Next() {
+		book := &Book{}
+		quote, err := scanQuote(rows, book.fieldPointers()...)
+		if err != nil {
+			return nil, err
+		}
+		quote.Book = book
+		quotes = append(quotes, quote)
+	}
+	return quotes, rows.Err()
+}
+
+func (s *sqlStore) ListQuotes(ctx context.Context, userId int, after ChangedAfter) ([]Quote, error) {
+	condition, args := where("quotes.user_id = ?", userId).changedAfter(after, "quotes.created_at", "quotes.updated_at").sql()
+	return s.queryQuotes(ctx, condition, args...)
+}
+
+func (s *sqlStore) ListBookQuotes(ctx context.Context, bookId int, after ChangedAfter) ([]Quote, error) {
+	if _, err := s.GetBookFields(ctx, bookId, []string{"id"}); err != nil {
+		return nil, err
+	}
+	condition, args := where("quotes.book_id = ?", bookId).and("quotes.public = ?", true).changedAfter(after, "quotes.created_at", "quotes.updated_at").sql()
+	quotes, err := s.queryQuotes(ctx, condition, args...)
+	for i := range quotes {
+		quotes[i].Book = nil
+	}
+	return quotes, err
+}
+
+func (s *sqlStore) RandomQuote(ctx context.Context) (Quote, error) {
+	// Counting and skipping to a random offset picks fairly the same way
+	// in every dialect.
+	var count int
+	err := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT COUNT(*) FROM quotes WHERE tenant_id = ? AND public = ?"), tenantId(ctx), true).Scan(&count)
+	if err != nil {
+		return Quote{}, err
+	}
+	if count == 0 {
+		return Quote{}, ErrNotFound
+	}
+	book := &Book{}
+	row := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT "+quoteColumns+", "+joinedBookColumns+" FROM quotes JOIN books ON books.id = quotes.book_id WHERE quotes.tenant_id = ? AND quotes.public = ? ORDER BY quotes.id LIMIT 1 OFFSET ?"),
+		tenantId(ctx), true, rand.IntN(count))
+	quote, err := scanQuote(row, book.fieldPointers()...)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Unpublished since it was counted.
+		return Quote{}, ErrNotFound
+	}
+	quote.Book = book
+	return quote, err
+}
+
+func (s *sqlStore) GetQuote(ctx context.Context, userId, id int) (Quote, error) {
+	row := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT "+quoteColumns+" FROM quotes WHERE tenant_id = ? AND user_id = ? AND id = ?"+s.lockClause()), tenantId(ctx), userId, id)
+	quote, err := scanQuote(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Quote{}, ErrNotFound
+	}
+	return quote, err
+}
+
+func (s *sqlStore) CreateQuote(ctx context.Context, quote *Quote) error {
+	id, err := s.insert(ctx, "INSERT INTO quotes (tenant_id, user_id, book_id, body, author, public, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		tenantId(ctx), quote.UserId, quote.BookId, quote.Text, quote.Author, quote.Public, quote.CreatedAt, quote.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	quote.Id = id
+	return nil
+}
+
+func (s *sqlStore) UpdateQuote(ctx context.Context, quote Quote) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE quotes SET body = ?, author = ?, public = ?, updated_at = ? WHERE tenant_id = ? AND user_id = ? AND id = ?"),
+		quote.Text, quote.Author, quote.Public, quote.UpdatedAt, tenantId(ctx), quote.UserId, quote.Id)
+	if err != nil {
+		return err
+	}
+	// MySQL counts only rows that changed, so check for the quote when
+	// nothing did.
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		_, err := s.GetQuote(ctx, quote.UserId, quote.Id)
+		return err
+	}
+	return nil
+}
+
+func (s *sqlStore) DeleteQuote(ctx context.Context, userId, id int) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM quotes WHERE tenant_id = ? AND user_id = ? AND id = ?"), tenantId(ctx), userId, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
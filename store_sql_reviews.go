@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+const reviewColumns = "id, book_id, reviewer, rating, body, created_at, user_id"
+
+func scanReview(row scanner) (Review, error) {
+	var review Review
+	err := row.Scan(&review.Id, &review.BookId, &review.Reviewer, &review.Rating, &review.Body, &review.CreatedAt, &review.UserId)
+	return review, err
+}
+
+func (s *sqlStore) ListReviews(ctx context.Context, bookId int) ([]Review, error) {
+	return s.queryReviews(ctx, "SELECT "+reviewColumns+" FROM reviews WHERE tenant_id = ? AND book_id = ? ORDER BY id", tenantId(ctx), bookId)
+}
+
+func (s *sqlStore) ListReviewsForBooks(ctx context.Context, bookIds []int, limit int) ([]Review, error) {
+	if len(bookIds) == 0 {
+		return []Review{}, nil
+	}
+
+	condition, ids := sqlIn("book_id", bookIds)
+	clause, args := where("tenant_id = ?", tenantId(ctx)).and(condition, ids...).clause()
+	return s.queryReviews(ctx, "SELECT "+reviewColumns+" FROM reviews"+clause+" ORDER BY book_id, id LIMIT ?", append(args, limit)...)
+}
+
+func (s *sqlStore) ListUserReviews(ctx context.Context, userId int) ([]Review, error) {
+	return s.queryReviews(ctx, "SELECT "+reviewColumns+" FROM reviews WHERE tenant_id = ? AND user_id = ? ORDER BY id", tenantId(ctx), userId)
+}
+
+func (s *sqlStore) queryReviews(ctx context.Context, query string, args ...any) ([]Review, error) {
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []Review{}
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, rows.Err()
+}
+
+func (s *sqlStore) CreateReview(ctx context.Context, review *Review) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		if _, err := tx.GetBook(ctx, review.BookId); err != nil {
+			return err
+		}
+
+		createdAt := time.Now().UTC().Truncate(time.Microsecond)
+		id, err := tx.(*sqlStore).insert(ctx, "INSERT INTO reviews (tenant_id, book_id, reviewer, rating, body, created_at, user_id) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			tenantId(ctx), review.BookId, review.Reviewer, review.Rating, review.Body, createdAt, review.UserId)
+		if err != nil {
+			return err
+		}
+		review.Id, review.CreatedAt = id, &createdAt
+		return nil
+	})
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+func (s *sqlStore) ListUserSales(ctx context.Context, userId int, email string) ([]Sale, error) {
+	ids, err := s.queryIds(ctx, "SELECT id FROM sales WHERE tenant_id = ? AND (user_id = ? OR (customer_email <> '' AND LOWER(customer_email) = ?)) ORDER BY id",
+		tenantId(ctx), userId, strings.ToLower(email))
+	if err != nil {
+		return nil, err
+	}
+	sales := []Sale{}
+	for _, id := range ids {
+		sale, err := s.GetSale(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		sales = append(sales, sale)
+	}
+	return sales, nil
+}
+
+func (s *sqlStore) GetSale(ctx context.Context, id int) (Sale, error) {
+	sale := Sale{Id: id, Lines: []SaleLine{}}
+	var shippedAt sql.NullTime
+	var userId sql.NullInt64
+	err := s.reader().QueryRowContext(ctx, s.dialect.rebind(
+		"SELECT invoice_number, customer_name, customer_address, customer_email, region, tax_included, total, user_id, points_redeemed, points_discount, points_earned, gift_card_amount, sold_at, shipped_at, carrier, tracking_number, created_at, updated_at FROM sales WHERE tenant_id = ? AND id = ?"+s.lockClause()), tenantId(ctx), id).
+		Scan(&sale.InvoiceNumber, &sale.CustomerName, &sale.CustomerAddress, &sale.CustomerEmail, &sale.Region, &sale.TaxIncluded, &sale.Total, &userId, &sale.PointsRedeemed, &sale.PointsDiscount, &sale.PointsEarned, &sale.GiftCardAmount, &sale.SoldAt, &shippedAt, &sale.Carrier, &sale.TrackingNumber, &sale.CreatedAt, &sale.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Sale{}, ErrNotFound
+	} else if err != nil {
+		return Sale{}, err
+	}
+	sale.UserId = int(userId.Int64)
+	if shippedAt.Valid {
+		sale.ShippedAt = &shippedAt.Time
+	}
+
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT id, book_id, title, quantity, unit_price, amount, tax_name, tax_rate, tax FROM sale_lines WHERE sale_id = ? ORDER BY id"), id)
+	if err != nil {
+		return Sale{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line SaleLine
+		var bookId sql.NullInt64
+		if err := rows.Scan(&line.Id, &bookId, &line.Title, &line.Quantity, &line.UnitPrice, &line.Amount, &line.TaxName, &line.TaxRate, &line.Tax); err != nil {
+			return Sale{}, err
+		}
+		line.BookId = int(bookId.Int64)
+		sale.Lines = append(sale.Lines, line)
+	}
+	return sale, rows.Err()
+}
+
+func (s *sqlStore) CreateSale(ctx context.Context, sale *Sale) error {
+	day := sale.SoldAt.Truncate(24 * time.Hour)
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		number, err := t.nextInvoiceNumber(ctx)
+		if err != nil {
+			return err
+		}
+		id, err := t.insert(ctx, "INSERT INTO sales (tenant_id, invoice_number, customer_name, customer_address, customer_email, region, tax_included, total, user_id, points_redeemed, points_discount, points_earned, gift_card_amount, sold_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			tenantId(ctx), number, sale.CustomerName, sale.CustomerAddress, sale.CustomerEmail, sale.Region, sale.TaxIncluded, sale.Total, nullInt(sale.UserId), sale.PointsRedeemed, sale.PointsDiscount, sale.PointsEarned, sale.GiftCardAmount, sale.SoldAt, sale.CreatedAt, sale.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		sale.Id, sale.InvoiceNumber = id, number
+
+		for i, line := range sale.Lines {
+			id, err := t.insert(ctx, "INSERT INTO sale_lines (tenant_id, sale_id, book_id, title, quantity, unit_price, amount, tax_name, tax_rate, tax, day) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+				tenantId(ctx), sale.Id, line.BookId, line.Title, line.Quantity, line.UnitPrice, line.Amount, line.TaxName, line.TaxRate, line.Tax, day)
+			if err != nil {
+				return err
+			}
+			sale.Lines[i].Id = id
+		}
+		return nil
+	})
+}
+
+func (s *sqlStore) ShipSale(ctx context.Context, id int, carrier, trackingNumber string, shippedAt time.Time) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE sales SET shipped_at = ?, carrier = ?, tracking_number = ?, updated_at = ? WHERE tenant_id = ? AND id = ?"),
+		shippedAt, carrier, trackingNumber, shippedAt, tenantId(ctx), id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Take the tenant's next invoice number. Its counter stays locked until
+// the transaction ends, so sales made at once can't share a number or
+// leave a gap when one rolls back.
+func (s *sqlStore) nextInvoiceNumber(ctx context.Context) (int, error) {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE invoice_numbers SET last_number = last_number + 1 WHERE tenant_id = ?"), tenantId(ctx))
+	if err != nil {
+		return 0, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		_, err := s.conn().ExecContext(ctx, s.dialect.rebind("INSERT INTO invoice_numbers (tenant_id, last_number) VALUES (?, 1)"), tenantId(ctx))
+		return 1, err
+	}
+
+	var number int
+	err = s.conn().QueryRowContext(ctx, s.dialect.rebind("SELECT last_number FROM invoice_numbers WHERE tenant_id = ?"), tenantId(ctx)).Scan(&number)
+	return number, err
+}
+
+func (s *sqlStore) SalesByDay(ctx context.Context, from, to time.Time) ([]SalesTotal, error) {
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT day, SUM(quantity), SUM(amount) FROM sale_lines WHERE tenant_id = ? AND day >= ? AND day < ? GROUP BY day ORDER BY day"),
+		tenantId(ctx), from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := []SalesTotal{}
+	for rows.Next() {
+		var t SalesTotal
+		if err := rows.Scan(&t.Day, &t.Units, &t.Revenue); err != nil {
+			return nil, err
+		}
+		t.Day, t.Revenue = t.Day.UTC(), roundCents(t.Revenue)
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
+
+func (s *sqlStore) SalesByCategory(ctx context.Context, from, to time.Time) ([]SalesTotal, error) {
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind(
+		"SELECT book_categories.name, SUM(sale_lines.quantity), SUM(sale_lines.amount) FROM sale_lines LEFT JOIN book_categories ON book_categories.book_id = sale_lines.book_id WHERE sale_lines.tenant_id = ? AND sale_lines.day >= ? AND sale_lines.day < ? GROUP BY book_categories.name"),
+		tenantId(ctx), from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := []SalesTotal{}
+	for rows.Next() {
+		var t SalesTotal
+		var category sql.NullString
+		if err := rows.Scan(&category, &t.Units, &t.Revenue); err != nil {
+			return nil, err
+		}
+		t.Category, t.Revenue = category.String, roundCents(t.Revenue)
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
+
+func (s *sqlStore) TopSellingBooks(ctx context.Context, from, to time.Time, limit int) ([]BookSales, error) {
+	// Lines of a deleted book have no book_id, so they are told apart by
+	// title.
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind(
+		"SELECT book_id, title, SUM(quantity) AS units, SUM(amount) AS revenue FROM sale_lines WHERE tenant_id = ? AND day >= ? AND day < ? GROUP BY book_id, title ORDER BY units DESC, revenue DESC, title LIMIT ?"),
+		tenantId(ctx), from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	top := []BookSales{}
+	for rows.Next() {
+		var b BookSales
+		var bookId sql.NullInt64
+		if err := rows.Scan(&bookId, &b.Title, &b.Units, &b.Revenue); err != nil {
+			return nil, err
+		}
+		b.BookId, b.Revenue = int(bookId.Int64), roundCents(b.Revenue)
+		top = append(top, b)
+	}
+	return top, rows.Err()
+}
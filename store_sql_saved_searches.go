@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const savedSearchColumns = "id, user_id, name, search_query, category, format, max_price, seen_book_id, created_at, notified_at, updated_at"
+
+// Load the tenant's saved searches matching condition, with args, by id.
+func (s *sqlStore) querySavedSearches(ctx context.Context, condition string, args ...any) ([]SavedSearch, error) {
+	clause, args := where("tenant_id = ?", tenantId(ctx)).and(condition, args...).clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT "+savedSearchColumns+" FROM saved_searches"+clause+" ORDER BY id"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := []SavedSearch{}
+	for rows.Next() {
+		var search SavedSearch
+		var maxPrice sql.NullFloat64
+		var notifiedAt sql.NullTime
+		err := rows.Scan(&search.Id, &search.UserId, &search.Name, &search.Query, &search.Category, &search.Format, &maxPrice, &search.SeenBookId, &search.CreatedAt, &notifiedAt, &search.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if maxPrice.Valid {
+			search.MaxPrice = &maxPrice.Float64
+		}
+		if notifiedAt.Valid {
+			search.NotifiedAt = &notifiedAt.Time
+		}
+		searches = append(searches, search)
+	}
+	return searches, rows.Err()
+}
+
+func (s *sqlStore) ListSavedSearches(ctx context.Context, userId int, after ChangedAfter) ([]SavedSearch, error) {
+	condition, args := where("user_id = ?", userId).changedAfter(after, "created_at", "updated_at").sql()
+	return s.querySavedSearches(ctx, condition, args...)
+}
+
+func (s *sqlStore) ListAllSavedSearches(ctx context.Context) ([]SavedSearch, error) {
+	return s.querySavedSearches(ctx, "")
+}
+
+func (s *sqlStore) CreateSavedSearch(ctx context.Context, search *SavedSearch) error {
+	id, err := s.insert(ctx, "INSERT INTO saved_searches (tenant_id, user_id, name, search_query, category, format, max_price, seen_book_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		tenantId(ctx), search.UserId, search.Name, search.Query, search.Category, search.Format, search.MaxPrice, search.SeenBookId, search.CreatedAt, search.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	search.Id = id
+	return nil
+}
+
+func (s *sqlStore) DeleteSavedSearch(ctx context.Context, userId, id int) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM saved_searches WHERE tenant_id = ? AND user_id = ? AND id = ?"), tenantId(ctx), userId, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore) SetSavedSearchSeen(ctx context.Context, id, seenBookId int, notifiedAt *time.Time) error {
+	u := update("saved_searches").set("seen_book_id", seenBookId)
+	if notifiedAt != nil {
+		u.set("notified_at", *notifiedAt).set("updated_at", *notifiedAt)
+	}
+	query, args := u.where(where("tenant_id = ?", tenantId(ctx)).and("id = ?", id))
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	// MySQL counts only rows that changed, so check for the search when
+	// nothing did.
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		var found int
+		err := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT id FROM saved_searches WHERE tenant_id = ? AND id = ?"), tenantId(ctx), id).Scan(&found)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+const seriesColumns = "id, name, description, created_at, updated_at"
+
+func scanSeries(row scanner) (Series, error) {
+	var series Series
+	err := row.Scan(&series.Id, &series.Name, &series.Description, &series.CreatedAt, &series.UpdatedAt)
+	return series, err
+}
+
+func (s *sqlStore) ListSeries(ctx context.Context, after ChangedAfter) ([]Series, error) {
+	clause, args := where("tenant_id = ?", tenantId(ctx)).changedAfter(after, "created_at", "updated_at").clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT "+seriesColumns+" FROM series"+clause+" ORDER BY LOWER(name), id"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	series := []Series{}
+	for rows.Next() {
+		sr, err := scanSeries(rows)
+		if err != nil {
+			return nil, err
+		}
+		series = append(series, sr)
+	}
+	return series, rows.Err()
+}
+
+func (s *sqlStore) GetSeries(ctx context.Context, id int) (Series, error) {
+	row := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT "+seriesColumns+" FROM series WHERE tenant_id = ? AND id = ?"+s.lockClause()), tenantId(ctx), id)
+	series, err := scanSeries(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Series{}, ErrNotFound
+	}
+	return series, err
+}
+
+func (s *sqlStore) CreateSeries(ctx context.Context, series *Series) error {
+	id, err := s.insert(ctx, "INSERT INTO series (tenant_id, name, description, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		tenantId(ctx), series.Name, series.Description, series.CreatedAt, series.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	series.Id = id
+	return nil
+}
+
+func (s *sqlStore) UpdateSeries(ctx context.Context, series Series) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE series SET name = ?, description = ?, updated_at = ? WHERE tenant_id = ? AND id = ?"),
+		series.Name, series.Description, series.UpdatedAt, tenantId(ctx), series.Id)
+	if err != nil {
+		return err
+	}
+	// MySQL counts only rows that changed, so check for the series when
+	// nothing did.
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		_, err := s.GetSeries(ctx, series.Id)
+		return err
+	}
+	return nil
+}
+
+func (s *sqlStore) DeleteSeries(ctx context.Context, id int) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM series WHERE tenant_id = ? AND id = ?"), tenantId(ctx), id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore) ListSeriesBooks(ctx context.Context, seriesId int, after ChangedAfter) ([]SeriesBook, error) {
+	if _, err := s.GetSeries(ctx, seriesId); err != nil {
+		return nil, err
+	}
+
+	clause, args := where("series_books.tenant_id = ?", tenantId(ctx)).and("series_books.series_id = ?", seriesId).changedAfter(after, "books.created_at", "books.updated_at").clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind(
+		"SELECT series_books.position, "+joinedBookColumns+" FROM series_books JOIN books ON books.id = series_books.book_id"+
+			clause+" ORDER BY series_books.position, series_books.book_id"),
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	books := []SeriesBook{}
+	for rows.Next() {
+		var sb SeriesBook
+		b := &sb.Book
+		if err := rows.Scan(append([]any{&sb.Position}, b.fieldPointers()...)...); err != nil {
+			return nil, err
+		}
+		books = append(books, sb)
+	}
+	return books, rows.Err()
+}
+
+func (s *sqlStore) SetBookSeries(ctx context.Context, bookId, seriesId int, position float64) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		if _, err := t.getBookFields(ctx, t.conn(), bookId, []string{"id"}); err != nil {
+			return err
+		}
+		if _, err := t.GetSeries(ctx, seriesId); err != nil {
+			return err
+		}
+		if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("DELETE FROM series_books WHERE tenant_id = ? AND book_id = ?"), tenantId(ctx), bookId); err != nil {
+			return err
+		}
+		_, err := t.conn().ExecContext(ctx, t.dialect.rebind("INSERT INTO series_books (tenant_id, book_id, series_id, position) VALUES (?, ?, ?, ?)"),
+			tenantId(ctx), bookId, seriesId, position)
+		return err
+	})
+}
+
+func (s *sqlStore) RemoveBookFromSeries(ctx context.Context, bookId, seriesId int) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM series_books WHERE tenant_id = ? AND book_id = ? AND series_id = ?"),
+		tenantId(ctx), bookId, seriesId)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+func (s *sqlStore) ListShelf(ctx context.Context, userId int, status string, after ChangedAfter) ([]ShelfItem, error) {
+	w := where("shelf_items.tenant_id = ?", tenantId(ctx)).and("shelf_items.user_id = ?", userId)
+	if status != "" {
+		w.and("shelf_items.status = ?", status)
+	}
+	w.changedAfter(after, "shelf_items.created_at", "shelf_items.updated_at")
+	clause, args := w.clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT shelf_items.rating, shelf_items.status, shelf_items.added_at, shelf_items.created_at, shelf_items.updated_at, "+joinedBookColumns+" FROM shelf_items JOIN books ON books.id = shelf_items.book_id"+clause+" ORDER BY shelf_items.added_at, shelf_items.book_id"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []ShelfItem{}
+	for rows.Next() {
+		item := ShelfItem{UserId: userId, Book: &Book{}, StatusChanges: []StatusChange{}}
+		var rating sql.NullInt64
+		b := item.Book
+		if err := rows.Scan(append([]any{&rating, &item.Status, &item.AddedAt, &item.CreatedAt, &item.UpdatedAt}, b.fieldPointers()...)...); err != nil {
+			return nil, err
+		}
+		item.BookId = b.Id
+		if rating.Valid {
+			r := int(rating.Int64)
+			item.Rating = &r
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	changes, err := s.statusChanges(ctx, s.reader(), userId, 0)
+	if err != nil {
+		return nil, err
+	}
+	last, err := s.queryProgress(ctx, userId, "id IN (SELECT MAX(id) FROM reading_progress WHERE tenant_id = ? AND user_id = ? GROUP BY book_id)", tenantId(ctx), userId)
+	if err != nil {
+		return nil, err
+	}
+	progress := map[int]*ReadingProgress{}
+	for i := range last {
+		progress[last[i].BookId] = &last[i]
+	}
+	for i := range items {
+		items[i].StatusChanges = append(items[i].StatusChanges, changes[items[i].BookId]...)
+		items[i].Progress = progress[items[i].BookId]
+	}
+	return items, nil
+}
+
+// Load the status changes of the books on the user's shelf, or of just the
+// book with bookId if it isn't 0, by book id.
+func (s *sqlStore) statusChanges(ctx context.Context, q queryer, userId, bookId int) (map[int][]StatusChange, error) {
+	w := where("tenant_id = ?", tenantId(ctx)).and("user_id = ?", userId)
+	if bookId != 0 {
+		w.and("book_id = ?", bookId)
+	}
+	clause, args := w.clause()
+	rows, err := q.QueryContext(ctx, s.dialect.rebind("SELECT book_id, status, changed_at FROM reading_status_changes"+clause+" ORDER BY id"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := map[int][]StatusChange{}
+	for rows.Next() {
+		var id int
+		var change StatusChange
+		if err := rows.Scan(&id, &change.Status, &change.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes[id] = append(changes[id], change)
+	}
+	return changes, rows.Err()
+}
+
+func (s *sqlStore) ShelveBook(ctx context.Context, userId int, item *ShelfItem) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		var addedAt, createdAt sql.NullTime
+		var status string
+		changedAt := item.AddedAt
+		err := t.conn().QueryRowContext(ctx, t.dialect.rebind("SELECT added_at, created_at, status FROM shelf_items WHERE tenant_id = ? AND user_id = ? AND book_id = ?"+t.lockClause()), tenantId(ctx), userId, item.BookId).Scan(&addedAt, &createdAt, &status)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if addedAt.Valid {
+			item.AddedAt = addedAt.Time
+		}
+		item.CreatedAt, item.UpdatedAt = item.AddedAt, changedAt
+		if createdAt.Valid {
+			item.CreatedAt = createdAt.Time
+		}
+		switch {
+		case item.Status == "" && addedAt.Valid:
+			item.Status = status
+		case item.Status == "":
+			item.Status = ReadingToRead
+		}
+		if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("DELETE FROM shelf_items WHERE tenant_id = ? AND user_id = ? AND book_id = ?"), tenantId(ctx), userId, item.BookId); err != nil {
+			return err
+		}
+		_, err = t.conn().ExecContext(ctx, t.dialect.rebind("INSERT INTO shelf_items (tenant_id, user_id, book_id, rating, status, added_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"),
+			tenantId(ctx), userId, item.BookId, item.Rating, item.Status, item.AddedAt, item.CreatedAt, item.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		if item.Status != status {
+			_, err = t.conn().ExecContext(ctx, t.dialect.rebind("INSERT INTO reading_status_changes (tenant_id, user_id, book_id, status, changed_at) VALUES (?, ?, ?, ?, ?)"),
+				tenantId(ctx), userId, item.BookId, item.Status, changedAt)
+			if err != nil {
+				return err
+			}
+		}
+		changes, err := t.statusChanges(ctx, t.conn(), userId, item.BookId)
+		if err != nil {
+			return err
+		}
+		item.UserId = userId
+		item.StatusChanges = append([]StatusChange{}, changes[item.BookId]...)
+		return nil
+	})
+}
+
+func (s *sqlStore) UnshelveBook(ctx context.Context, userId, bookId int) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		result, err := t.conn().ExecContext(ctx, t.dialect.rebind("DELETE FROM shelf_items WHERE tenant_id = ? AND user_id = ? AND book_id = ?"), tenantId(ctx), userId, bookId)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+		if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("DELETE FROM reading_status_changes WHERE tenant_id = ? AND user_id = ? AND book_id = ?"), tenantId(ctx), userId, bookId); err != nil {
+			return err
+		}
+		_, err = t.conn().ExecContext(ctx, t.dialect.rebind("DELETE FROM reading_progress WHERE tenant_id = ? AND user_id = ? AND book_id = ?"), tenantId(ctx), userId, bookId)
+		return err
+	})
+}
+
+func (s *sqlStore) RecordProgress(ctx context.Context, userId int, progress *ReadingProgress) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		var found int
+		err := t.conn().QueryRowContext(ctx, t.dialect.rebind("SELECT 1 FROM shelf_items WHERE tenant_id = ? AND user_id = ? AND book_id = ?"), tenantId(ctx), userId, progress.BookId).Scan(&found)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		} else if err != nil {
+			return err
+		}
+		_, err = t.conn().ExecContext(ctx, t.dialect.rebind("UPDATE shelf_items SET updated_at = ? WHERE tenant_id = ? AND user_id = ? AND book_id = ?"),
+			progress.RecordedAt, tenantId(ctx), userId, progress.BookId)
+		if err != nil {
+			return err
+		}
+		_, err = t.conn().ExecContext(ctx, t.dialect.rebind("INSERT INTO reading_progress (tenant_id, user_id, book_id, page, pages, percent, recorded_at) VALUES (?, ?, ?, ?, ?, ?, ?)"),
+			tenantId(ctx), userId, progress.BookId, progress.Page, progress.Pages, progress.Percent, progress.RecordedAt)
+		return err
+	})
+}
+
+func (s *sqlStore) ListProgress(ctx context.Context, userId int) ([]ReadingProgress, error) {
+	return s.queryProgress(ctx, userId, "")
+}
+
+// Load the user's progress matching condition, with args, oldest first.
+func (s *sqlStore) queryProgress(ctx context.Context, userId int, condition string, args ...any) ([]ReadingProgress, error) {
+	clause, args := where("tenant_id = ?", tenantId(ctx)).and("user_id = ?", userId).and(condition, args...).clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT book_id, page, pages, percent, recorded_at FROM reading_progress"+clause+" ORDER BY recorded_at, id"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []ReadingProgress{}
+	for rows.Next() {
+		var p ReadingProgress
+		var page, pages sql.NullInt64
+		var percent sql.NullFloat64
+		if err := rows.Scan(&p.BookId, &page, &pages, &percent, &p.RecordedAt); err != nil {
+			return nil, err
+		}
+		if page.Valid {
+			n := int(page.Int64)
+			p.Page = &n
+		}
+		if pages.Valid {
+			n := int(pages.Int64)
+			p.Pages = &n
+		}
+		if percent.Valid {
+			p.Percent = &percent.Float64
+		}
+		history = append(history, p)
+	}
+	return history, rows.Err()
+}
+
+func (s *sqlStore) AllShelfItems(ctx context.Context) ([]ShelfItem, error) {
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT user_id, book_id, rating, added_at FROM shelf_items WHERE tenant_id = ? ORDER BY user_id, added_at"), tenantId(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []ShelfItem{}
+	for rows.Next() {
+		var item ShelfItem
+		var rating sql.NullInt64
+		if err := rows.Scan(&item.UserId, &item.BookId, &rating, &item.AddedAt); err != nil {
+			return nil, err
+		}
+		if rating.Valid {
+			r := int(rating.Int64)
+			item.Rating = &r
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (s *sqlStore) ReplaceRecommendations(ctx context.Context, recommendations []Recommendation) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("DELETE FROM recommendations WHERE tenant_id = ?"), tenantId(ctx)); err != nil {
+			return err
+		}
+		for _, rec := range recommendations {
+			_, err := t.conn().ExecContext(ctx, t.dialect.rebind("INSERT INTO recommendations (tenant_id, user_id, book_id, score, because_book_id) VALUES (?, ?, ?, ?, ?)"),
+				tenantId(ctx), rec.UserId, rec.BookId, rec.Score, rec.BecauseBookId)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqlStore) ListRecommendations(ctx context.Context, userId, limit int) ([]Recommendation, error) {
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind(
+		"SELECT recommendations.score, recommendations.because_book_id, "+joinedBookColumns+" FROM recommendations JOIN books ON books.id = recommendations.book_id WHERE recommendations.tenant_id = ? AND recommendations.user_id = ? ORDER BY recommendations.score DESC, recommendations.book_id LIMIT ?"),
+		tenantId(ctx), userId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recommendations := []Recommendation{}
+	for rows.Next() {
+		rec := Recommendation{UserId: userId, Book: &Book{}}
+		b := rec.Book
+		if err := rows.Scan(append([]any{&rec.Score, &rec.BecauseBookId}, b.fieldPointers()...)...); err != nil {
+			return nil, err
+		}
+		rec.BookId = b.Id
+		recommendations = append(recommendations, rec)
+	}
+	return recommendations, rows.Err()
+}
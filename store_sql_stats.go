@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// The aggregates are computed by the database, so the rows never leave it.
+const catalogStatsQuery = "SELECT COUNT(*), COUNT(DISTINCT author)," +
+	" COALESCE(AVG(price), 0), COALESCE(MIN(price), 0), COALESCE(MAX(price), 0)," +
+	" COUNT(CASE WHEN created_at >= ? THEN 1 END)," +
+	" COUNT(CASE WHEN created_at >= ? THEN 1 END)," +
+	" COUNT(CASE WHEN created_at >= ? THEN 1 END)" +
+	" FROM books WHERE tenant_id = ?"
+
+const topAuthorsQuery = "SELECT author, COUNT(*) AS books FROM books" +
+	" WHERE tenant_id = ? GROUP BY author ORDER BY books DESC, author LIMIT ?"
+
+func (s *sqlStore) CatalogStats(ctx context.Context, now time.Time, topAuthors int) (CatalogStats, error) {
+	q := s.reader()
+	day, week, month := recentWindows(now)
+
+	var stats CatalogStats
+	err := q.QueryRowContext(ctx, s.dialect.rebind(catalogStatsQuery), day, week, month, tenantId(ctx)).Scan(
+		&stats.TotalBooks, &stats.TotalAuthors,
+		&stats.Price.Average, &stats.Price.Min, &stats.Price.Max,
+		&stats.RecentlyAdded.LastDay, &stats.RecentlyAdded.LastWeek, &stats.RecentlyAdded.LastMonth,
+	)
+	if err != nil {
+		return CatalogStats{}, err
+	}
+
+	rows, err := q.QueryContext(ctx, s.dialect.rebind(topAuthorsQuery), tenantId(ctx), topAuthors)
+	if err != nil {
+		return CatalogStats{}, err
+	}
+	defer rows.Close()
+
+	stats.Authors = []AuthorCount{}
+	for rows.Next() {
+		var count AuthorCount
+		if err := rows.Scan(&count.Author, &count.Books); err != nil {
+			return CatalogStats{}, err
+		}
+		stats.Authors = append(stats.Authors, count)
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+)
+
+func (s *sqlStore) GetBookSubjects(ctx context.Context, bookId int) (BookSubjects, error) {
+	q := s.reader()
+	if _, err := s.getBookFields(ctx, q, bookId, []string{"id"}); err != nil {
+		return BookSubjects{}, err
+	}
+
+	var subjects BookSubjects
+	for _, list := range []struct {
+		table string
+		names *[]string
+	}{{"book_categories", &subjects.Categories}, {"book_tags", &subjects.Tags}} {
+		rows, err := q.QueryContext(ctx, s.dialect.rebind("SELECT name FROM "+list.table+" WHERE tenant_id = ? AND book_id = ? ORDER BY name"), tenantId(ctx), bookId)
+		if err != nil {
+			return BookSubjects{}, err
+		}
+		*list.names = []string{}
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return BookSubjects{}, err
+			}
+			*list.names = append(*list.names, name)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return BookSubjects{}, err
+		}
+	}
+	return subjects, nil
+}
+
+func (s *sqlStore) SetBookSubjects(ctx context.Context, bookId int, subjects BookSubjects) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		if _, err := t.getBookFields(ctx, t.conn(), bookId, []string{"id"}); err != nil {
+			return err
+		}
+		for table, names := range map[string][]string{"book_categories": subjects.Categories, "book_tags": subjects.Tags} {
+			if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("DELETE FROM "+table+" WHERE tenant_id = ? AND book_id = ?"), tenantId(ctx), bookId); err != nil {
+				return err
+			}
+			for _, name := range names {
+				if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("INSERT INTO "+table+" (tenant_id, book_id, name) VALUES (?, ?, ?)"), tenantId(ctx), bookId, name); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqlStore) BookCategories(ctx context.Context) (map[int][]string, error) {
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT book_id, name FROM book_categories WHERE tenant_id = ?"), tenantId(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := map[int][]string{}
+	for rows.Next() {
+		var bookId int
+		var name string
+		if err := rows.Scan(&bookId, &name); err != nil {
+			return nil, err
+		}
+		categories[bookId] = append(categories[bookId], name)
+	}
+	return categories, rows.Err()
+}
+
+// similarBooksQuery scores every other book of the target's tenant against
+// the target, the first parameter, in a derived table, then keeps up to
+// the second parameter of the best.
+const similarBooksQuery = `SELECT scored.score, ` + joinedBookColumns + ` FROM (
+	SELECT books.id,
+		CASE WHEN LOWER(books.author) = LOWER(target.author) THEN 3 ELSE 0 END
+		+ 2 * (SELECT COUNT(*) FROM book_categories c JOIN book_categories o ON LOWER(o.name) = LOWER(c.name) WHERE c.book_id = books.id AND o.book_id = target.id)
+		+ (SELECT COUNT(*) FROM book_tags t JOIN book_tags o ON o.name = t.name WHERE t.book_id = books.id AND o.book_id = target.id) AS score
+	FROM books JOIN books target ON target.id = ?
+	WHERE books.tenant_id = target.tenant_id AND books.id <> target.id
+) scored JOIN books ON books.id = scored.id
+WHERE scored.score > 0
+ORDER BY scored.score DESC, books.id
+LIMIT ?`
+
+func (s *sqlStore) SimilarBooks(ctx context.Context, bookId, limit int) ([]SimilarBook, error) {
+	q := s.reader()
+	if _, err := s.getBookFields(ctx, q, bookId, []string{"id"}); err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, s.dialect.rebind(similarBooksQuery), bookId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	similar := []SimilarBook{}
+	for rows.Next() {
+		var sb SimilarBook
+		b := &sb.Book
+		if err := rows.Scan(append([]any{&sb.Score}, b.fieldPointers()...)...); err != nil {
+			return nil, err
+		}
+		similar = append(similar, sb)
+	}
+	return similar, rows.Err()
+}
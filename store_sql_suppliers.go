@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+const supplierColumns = "id, name, email, phone, created_at, updated_at"
+
+func scanSupplier(row scanner) (Supplier, error) {
+	var supplier Supplier
+	err := row.Scan(&supplier.Id, &supplier.Name, &supplier.Email, &supplier.Phone, &supplier.CreatedAt, &supplier.UpdatedAt)
+	return supplier, err
+}
+
+func (s *sqlStore) ListSuppliers(ctx context.Context, after ChangedAfter) ([]Supplier, error) {
+	clause, args := where("tenant_id = ?", tenantId(ctx)).changedAfter(after, "created_at", "updated_at").clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT "+supplierColumns+" FROM suppliers"+clause+" ORDER BY name, id"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppliers := []Supplier{}
+	for rows.Next() {
+		supplier, err := scanSupplier(rows)
+		if err != nil {
+			return nil, err
+		}
+		suppliers = append(suppliers, supplier)
+	}
+	return suppliers, rows.Err()
+}
+
+func (s *sqlStore) GetSupplier(ctx context.Context, id int) (Supplier, error) {
+	row := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT "+supplierColumns+" FROM suppliers WHERE tenant_id = ? AND id = ?"), tenantId(ctx), id)
+	supplier, err := scanSupplier(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Supplier{}, ErrNotFound
+	}
+	return supplier, err
+}
+
+func (s *sqlStore) CreateSupplier(ctx context.Context, supplier *Supplier) error {
+	id, err := s.insert(ctx, "INSERT INTO suppliers (tenant_id, name, email, phone, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
+		tenantId(ctx), supplier.Name, supplier.Email, supplier.Phone, supplier.CreatedAt, supplier.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	supplier.Id = id
+	return nil
+}
+
+func (s *sqlStore) UpdateSupplier(ctx context.Context, supplier Supplier) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE suppliers SET name = ?, email = ?, phone = ?, updated_at = ? WHERE tenant_id = ? AND id = ?"),
+		supplier.Name, supplier.Email, supplier.Phone, supplier.UpdatedAt, tenantId(ctx), supplier.Id)
+	if err != nil {
+		return err
+	}
+	// MySQL counts only rows that changed, so check for the supplier when
+	// nothing did.
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		_, err := s.GetSupplier(ctx, supplier.Id)
+		return err
+	}
+	return nil
+}
+
+func (s *sqlStore) DeleteSupplier(ctx context.Context, id int) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM suppliers WHERE tenant_id = ? AND id = ?"), tenantId(ctx), id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore) ListSupplierPrices(ctx context.Context, query SupplierPriceQuery) ([]SupplierPrice, error) {
+	w := where("supplier_prices.tenant_id = ?", tenantId(ctx))
+	if query.SupplierId != 0 {
+		w.and("supplier_prices.supplier_id = ?", query.SupplierId)
+	}
+	if query.BookId != 0 {
+		w.and("supplier_prices.book_id = ?", query.BookId)
+	}
+	w.changedAfter(query.ChangedAfter, "supplier_prices.created_at", "supplier_prices.updated_at")
+	clause, args := w.clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind(
+		"SELECT supplier_prices.supplier_id, suppliers.name, supplier_prices.book_id, books.title, supplier_prices.unit_cost, supplier_prices.lead_time_days, supplier_prices.created_at, supplier_prices.updated_at "+
+			"FROM supplier_prices JOIN suppliers ON suppliers.id = supplier_prices.supplier_id JOIN books ON books.id = supplier_prices.book_id"+
+			clause+" ORDER BY supplier_prices.unit_cost, supplier_prices.lead_time_days, supplier_prices.supplier_id, supplier_prices.book_id"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := []SupplierPrice{}
+	for rows.Next() {
+		var price SupplierPrice
+		if err := rows.Scan(&price.SupplierId, &price.SupplierName, &price.BookId, &price.Title, &price.UnitCost, &price.LeadTimeDays, &price.CreatedAt, &price.UpdatedAt); err != nil {
+			return nil, err
+		}
+		prices = append(prices, price)
+	}
+	return prices, rows.Err()
+}
+
+func (s *sqlStore) SetSupplierPrice(ctx context.Context, price *SupplierPrice) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		price.CreatedAt = price.UpdatedAt
+		err := t.conn().QueryRowContext(ctx, t.dialect.rebind("SELECT created_at FROM supplier_prices WHERE tenant_id = ? AND supplier_id = ? AND book_id = ?"+t.lockClause()),
+			tenantId(ctx), price.SupplierId, price.BookId).Scan(&price.CreatedAt)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("DELETE FROM supplier_prices WHERE tenant_id = ? AND supplier_id = ? AND book_id = ?"),
+			tenantId(ctx), price.SupplierId, price.BookId); err != nil {
+			return err
+		}
+		_, err = t.conn().ExecContext(ctx, t.dialect.rebind("INSERT INTO supplier_prices (tenant_id, supplier_id, book_id, unit_cost, lead_time_days, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)"),
+			tenantId(ctx), price.SupplierId, price.BookId, price.UnitCost, price.LeadTimeDays, price.CreatedAt, price.UpdatedAt)
+		return err
+	})
+}
+
+func (s *sqlStore) DeleteSupplierPrice(ctx context.Context, supplierId, bookId int) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM supplier_prices WHERE tenant_id = ? AND supplier_id = ? AND book_id = ?"),
+		tenantId(ctx), supplierId, bookId)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
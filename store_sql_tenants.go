@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Tenants are read from the primary so a new one can be used right away.
+const tenantColumns = "id, slug, name, created_at, updated_at"
+
+func scanTenant(row scanner) (Tenant, error) {
+	var tenant Tenant
+	err := row.Scan(&tenant.Id, &tenant.Slug, &tenant.Name, &tenant.CreatedAt, &tenant.UpdatedAt)
+	return tenant, err
+}
+
+func (s *sqlStore) CreateTenant(ctx context.Context, tenant *Tenant) error {
+	id, err := s.insert(ctx, "INSERT INTO tenants (slug, name, created_at, updated_at) VALUES (?, ?, ?, ?)",
+		tenant.Slug, tenant.Name, tenant.CreatedAt, tenant.UpdatedAt)
+	if s.dialect.violatesUnique(err, "tenants_slug_idx", "tenants.slug") {
+		return ErrDuplicateTenant
+	} else if err != nil {
+		return err
+	}
+	tenant.Id = id
+	return nil
+}
+
+func (s *sqlStore) GetTenantBySlug(ctx context.Context, slug string) (Tenant, error) {
+	row := s.conn().QueryRowContext(ctx, s.dialect.rebind("SELECT "+tenantColumns+" FROM tenants WHERE slug = ?"), slug)
+	tenant, err := scanTenant(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Tenant{}, ErrNotFound
+	}
+	return tenant, err
+}
+
+func (s *sqlStore) ListTenants(ctx context.Context, after ChangedAfter) ([]Tenant, error) {
+	clause, args := (&sqlWhere{}).changedAfter(after, "created_at", "updated_at").clause()
+	rows, err := s.conn().QueryContext(ctx, s.dialect.rebind("SELECT "+tenantColumns+" FROM tenants"+clause+" ORDER BY id"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tenants := []Tenant{}
+	for rows.Next() {
+		tenant, err := scanTenant(rows)
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, rows.Err()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+func (s *sqlStore) CreateUser(ctx context.Context, user *User, tokenHash string) error {
+	id, err := s.insert(ctx, "INSERT INTO users (tenant_id, name, email, admin, token_hash, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		tenantId(ctx), user.Name, user.Email, user.Admin, tokenHash, user.CreatedAt, user.UpdatedAt)
+	if s.dialect.violatesUnique(err, "users_email_idx", "users.email") {
+		return ErrDuplicateEmail
+	} else if err != nil {
+		return err
+	}
+	user.Id = id
+	return nil
+}
+
+func (s *sqlStore) GetUserByToken(ctx context.Context, tokenHash string) (User, error) {
+	var user User
+	err := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT id, name, email, admin, created_at, updated_at FROM users WHERE tenant_id = ? AND token_hash = ?"), tenantId(ctx), tokenHash).
+		Scan(&user.Id, &user.Name, &user.Email, &user.Admin, &user.CreatedAt, &user.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrNotFound
+	}
+	return user, err
+}
+
+func (s *sqlStore) GetUser(ctx context.Context, id int) (User, error) {
+	var user User
+	err := s.reader().QueryRowContext(ctx, s.dialect.rebind("SELECT id, name, email, admin, created_at, updated_at FROM users WHERE tenant_id = ? AND id = ?"), tenantId(ctx), id).
+		Scan(&user.Id, &user.Name, &user.Email, &user.Admin, &user.CreatedAt, &user.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrNotFound
+	}
+	return user, err
+}
+
+func (s *sqlStore) EraseUser(ctx context.Context, id int) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		user, err := t.GetUser(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		tenant := tenantId(ctx)
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		statements := []struct {
+			query string
+			args  []any
+		}{
+			{"UPDATE sales SET customer_name = '', customer_address = '', customer_email = '', user_id = NULL, updated_at = ? WHERE tenant_id = ? AND (user_id = ? OR (customer_email <> '' AND LOWER(customer_email) = ?))", []any{now, tenant, id, strings.ToLower(user.Email)}},
+			{"UPDATE members SET name = ?, email = '', phone = '', address = '', user_id = NULL, updated_at = ? WHERE tenant_id = ? AND user_id = ?", []any{erasedName, now, tenant, id}},
+			{"UPDATE reviews SET reviewer = ?, user_id = NULL WHERE tenant_id = ? AND user_id = ?", []any{erasedName, tenant, id}},
+			{"DELETE FROM push_watches WHERE subscription_id IN (SELECT id FROM push_subscriptions WHERE tenant_id = ? AND recipient = ?)", []any{tenant, user.Email}},
+			{"DELETE FROM push_subscriptions WHERE tenant_id = ? AND recipient = ?", []any{tenant, user.Email}},
+			{"DELETE FROM notifications WHERE tenant_id = ? AND recipient = ?", []any{tenant, user.Email}},
+			{"DELETE FROM notification_failures WHERE tenant_id = ? AND recipient = ?", []any{tenant, user.Email}},
+			{"DELETE FROM notification_preferences WHERE tenant_id = ? AND recipient = ?", []any{tenant, user.Email}},
+			{"DELETE FROM notification_contacts WHERE tenant_id = ? AND recipient = ?", []any{tenant, user.Email}},
+			// The rest of what is theirs goes with them by cascade.
+			{"DELETE FROM users WHERE tenant_id = ? AND id = ?", []any{tenant, id}},
+		}
+		for _, statement := range statements {
+			if _, err := t.conn().ExecContext(ctx, t.dialect.rebind(statement.query), statement.args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Webhooks are read from the primary so a newly registered one is used
+// right away.
+const webhookColumns = "id, url, secret, events, created_at, updated_at"
+
+func scanWebhook(row scanner) (Webhook, error) {
+	hook := Webhook{Events: []string{}}
+	var events string
+	err := row.Scan(&hook.Id, &hook.URL, &hook.Secret, &events, &hook.CreatedAt, &hook.UpdatedAt)
+	if events != "" {
+		hook.Events = strings.Split(events, ",")
+	}
+	return hook, err
+}
+
+func (s *sqlStore) ListWebhooks(ctx context.Context, after ChangedAfter) ([]Webhook, error) {
+	clause, args := where("tenant_id = ?", tenantId(ctx)).changedAfter(after, "created_at", "updated_at").clause()
+	rows, err := s.conn().QueryContext(ctx, s.dialect.rebind("SELECT "+webhookColumns+" FROM webhooks"+clause+" ORDER BY id"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+func (s *sqlStore) GetWebhook(ctx context.Context, id int) (Webhook, error) {
+	row := s.conn().QueryRowContext(ctx, s.dialect.rebind("SELECT "+webhookColumns+" FROM webhooks WHERE id = ? AND tenant_id = ?"), id, tenantId(ctx))
+	hook, err := scanWebhook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Webhook{}, ErrNotFound
+	}
+	return hook, err
+}
+
+func (s *sqlStore) CreateWebhook(ctx context.Context, hook *Webhook) error {
+	hook.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	hook.UpdatedAt = hook.CreatedAt
+	id, err := s.insert(ctx, "INSERT INTO webhooks (tenant_id, url, secret, events, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
+		tenantId(ctx), hook.URL, hook.Secret, strings.Join(hook.Events, ","), hook.CreatedAt, hook.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	hook.Id = id
+	return nil
+}
+
+func (s *sqlStore) DeleteWebhook(ctx context.Context, id int) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM webhooks WHERE id = ? AND tenant_id = ?"), id, tenantId(ctx))
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore) CreateWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	id, err := s.insert(ctx, "INSERT INTO webhook_deliveries (tenant_id, webhook_id, event_id, event_type, attempt, status_code, error, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		tenantId(ctx), delivery.WebhookId, delivery.EventId, delivery.EventType, delivery.Attempt, delivery.StatusCode, delivery.Error, delivery.CreatedAt)
+	if err != nil {
+		return err
+	}
+	delivery.Id = id
+	return nil
+}
+
+func (s *sqlStore) ListWebhookDeliveries(ctx context.Context, webhookId int, limit int, after ChangedAfter) ([]WebhookDelivery, error) {
+	clause, args := where("tenant_id = ?", tenantId(ctx)).and("webhook_id = ?", webhookId).changedAfter(after, "created_at", "created_at").clause()
+	rows, err := s.conn().QueryContext(ctx, s.dialect.rebind(
+		"SELECT id, webhook_id, event_id, event_type, attempt, status_code, error, created_at FROM webhook_deliveries"+clause+" ORDER BY id DESC LIMIT ?"),
+		append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var d WebhookDelivery
+		if err := rows.Scan(&d.Id, &d.WebhookId, &d.EventId, &d.EventType, &d.Attempt, &d.StatusCode, &d.Error, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *sqlStore) EnqueueWebhookJob(ctx context.Context, job *WebhookJob) error {
+	event, err := json.Marshal(job.Event)
+	if err != nil {
+		return err
+	}
+
+	id, err := s.insert(ctx, "INSERT INTO webhook_jobs (tenant_id, webhook_id, event, attempt, next_attempt_at) VALUES (?, ?, ?, ?, ?)",
+		tenantId(ctx), job.WebhookId, string(event), job.Attempt, job.NextAttemptAt)
+	if err != nil {
+		return err
+	}
+	job.Id = id
+	return nil
+}
+
+func (s *sqlStore) ClaimWebhookJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]WebhookJob, error) {
+	var jobs []WebhookJob
+	err := s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		rows, err := t.conn().QueryContext(ctx, t.dialect.rebind(
+			"SELECT id, tenant_id, webhook_id, event, attempt FROM webhook_jobs WHERE next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?"+t.lockClause()),
+			now, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var job WebhookJob
+			var event string
+			var tenant int
+			if err := rows.Scan(&job.Id, &tenant, &job.WebhookId, &event, &job.Attempt); err != nil {
+				return err
+			}
+			if err := json.Unmarshal([]byte(event), &job.Event); err != nil {
+				return err
+			}
+			job.Event.TenantId = tenant
+			job.NextAttemptAt = now.Add(lease)
+			jobs = append(jobs, job)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+
+		for _, job := range jobs {
+			if err := t.UpdateWebhookJob(withTenantId(ctx, job.Event.TenantId), job); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return jobs, err
+}
+
+func (s *sqlStore) UpdateWebhookJob(ctx context.Context, job WebhookJob) error {
+	_, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE webhook_jobs SET attempt = ?, next_attempt_at = ? WHERE id = ? AND tenant_id = ?"),
+		job.Attempt, job.NextAttemptAt, job.Id, tenantId(ctx))
+	return err
+}
+
+func (s *sqlStore) DeleteWebhookJob(ctx context.Context, id int) error {
+	_, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM webhook_jobs WHERE id = ? AND tenant_id = ?"), id, tenantId(ctx))
+	return err
+}
+
+func (s *sqlStore) DeleteWebhookDeliveriesBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM webhook_deliveries WHERE created_at < ?"), before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+func (s *sqlStore) ListWishlist(ctx context.Context, userId int, after ChangedAfter) ([]WishlistItem, error) {
+	condition, args := where("wishlist_items.user_id = ?", userId).changedAfter(after, "wishlist_items.created_at", "wishlist_items.updated_at").sql()
+	return s.queryWishlist(ctx, condition+" ORDER BY wishlist_items.added_at, wishlist_items.book_id", args...)
+}
+
+func (s *sqlStore) ListPriceTargets(ctx context.Context) ([]WishlistItem, error) {
+	return s.queryWishlist(ctx, "wishlist_items.target_price IS NOT NULL ORDER BY wishlist_items.user_id, wishlist_items.book_id")
+}
+
+// Query the tenant's wishlist items matching where, with their books.
+func (s *sqlStore) queryWishlist(ctx context.Context, where string, args ...any) ([]WishlistItem, error) {
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind(
+		"SELECT wishlist_items.user_id, wishlist_items.target_price, wishlist_items.added_at, wishlist_items.alerted_price, wishlist_items.alerted_at, wishlist_items.created_at, wishlist_items.updated_at, "+joinedBookColumns+
+			" FROM wishlist_items JOIN books ON books.id = wishlist_items.book_id WHERE wishlist_items.tenant_id = ? AND "+where),
+		append([]any{tenantId(ctx)}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []WishlistItem{}
+	for rows.Next() {
+		item := WishlistItem{Book: &Book{}}
+		var targetPrice, alertedPrice sql.NullFloat64
+		var alertedAt sql.NullTime
+		b := item.Book
+		if err := rows.Scan(append([]any{&item.UserId, &targetPrice, &item.AddedAt, &alertedPrice, &alertedAt, &item.CreatedAt, &item.UpdatedAt}, b.fieldPointers()...)...); err != nil {
+			return nil, err
+		}
+		item.BookId = b.Id
+		if targetPrice.Valid {
+			item.TargetPrice = &targetPrice.Float64
+		}
+		if alertedPrice.Valid {
+			item.AlertedPrice = &alertedPrice.Float64
+		}
+		if alertedAt.Valid {
+			item.AlertedAt = &alertedAt.Time
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (s *sqlStore) SaveWishlistItem(ctx context.Context, userId int, item *WishlistItem) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		var addedAt time.Time
+		var createdAt sql.NullTime
+		err := t.conn().QueryRowContext(ctx, t.dialect.rebind("SELECT added_at, created_at FROM wishlist_items WHERE tenant_id = ? AND user_id = ? AND book_id = ?"+t.lockClause()),
+			tenantId(ctx), userId, item.BookId).Scan(&addedAt, &createdAt)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			_, err = t.conn().ExecContext(ctx, t.dialect.rebind("INSERT INTO wishlist_items (tenant_id, user_id, book_id, target_price, added_at, alerted_price, alerted_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+				tenantId(ctx), userId, item.BookId, item.TargetPrice, item.AddedAt, item.AlertedPrice, item.AlertedAt, item.CreatedAt, item.UpdatedAt)
+			return err
+		case err != nil:
+			return err
+		}
+		item.AddedAt, item.CreatedAt = addedAt, addedAt
+		if createdAt.Valid {
+			item.CreatedAt = createdAt.Time
+		}
+		_, err = t.conn().ExecContext(ctx, t.dialect.rebind("UPDATE wishlist_items SET target_price = ?, alerted_price = ?, alerted_at = ?, updated_at = ? WHERE tenant_id = ? AND user_id = ? AND book_id = ?"),
+			item.TargetPrice, item.AlertedPrice, item.AlertedAt, item.UpdatedAt, tenantId(ctx), userId, item.BookId)
+		return err
+	})
+}
+
+func (s *sqlStore) DeleteWishlistItem(ctx context.Context, userId, bookId int) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM wishlist_items WHERE tenant_id = ? AND user_id = ? AND book_id = ?"), tenantId(ctx), userId, bookId)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore) SetWishlistAlert(ctx context.Context, userId, bookId int, price *float64, alertedAt *time.Time, updatedAt time.Time) error {
+	_, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE wishlist_items SET alerted_price = ?, alerted_at = ?, updated_at = ? WHERE tenant_id = ? AND user_id = ? AND book_id = ?"),
+		price, alertedAt, updatedAt, tenantId(ctx), userId, bookId)
+	return err
+}
+
+func (s *sqlStore) RecordBookPrice(ctx context.Context, price BookPrice) error {
+	var last float64
+	err := s.conn().QueryRowContext(ctx, s.dialect.rebind("SELECT price FROM book_prices WHERE tenant_id = ? AND book_id = ? ORDER BY id DESC LIMIT 1"),
+		tenantId(ctx), price.BookId).Scan(&last)
+	if err == nil && last == price.Price {
+		return nil
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	// The book may have been deleted since the event.
+	if _, err := s.GetBookFields(ctx, price.BookId, []string{"id"}); errors.Is(err, ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	_, err = s.insert(ctx, "INSERT INTO book_prices (tenant_id, book_id, price, changed_at) VALUES (?, ?, ?, ?)",
+		tenantId(ctx), price.BookId, price.Price, price.ChangedAt)
+	return err
+}
+
+func (s *sqlStore) ListBookPrices(ctx context.Context, bookId int, after ChangedAfter) ([]BookPrice, error) {
+	if _, err := s.GetBookFields(ctx, bookId, []string{"id"}); err != nil {
+		return nil, err
+	}
+	clause, args := where("tenant_id = ?", tenantId(ctx)).and("book_id = ?", bookId).changedAfter(after, "changed_at", "changed_at").clause()
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind("SELECT price, changed_at FROM book_prices"+clause+" ORDER BY id"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := []BookPrice{}
+	for rows.Next() {
+		price := BookPrice{BookId: bookId}
+		if err := rows.Scan(&price.Price, &price.ChangedAt); err != nil {
+			return nil, err
+		}
+		prices = append(prices, price)
+	}
+	return prices, rows.Err()
+}
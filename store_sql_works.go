@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+const workColumns = "id, title, author, created_at, updated_at"
+
+func scanWork(row scanner) (Work, error) {
+	work := Work{Editions: []Book{}}
+	var createdAt, updatedAt sql.NullTime
+	err := row.Scan(&work.Id, &work.Title, &work.Author, &createdAt, &updatedAt)
+	work.CreatedAt, work.UpdatedAt = nullTime(createdAt), nullTime(updatedAt)
+	return work, err
+}
+
+func (s *sqlStore) ListWorks(ctx context.Context, after ChangedAfter) ([]Work, error) {
+	clause, args := where("tenant_id = ?", tenantId(ctx)).changedAfter(after, "created_at", "updated_at").clause()
+	works, err := s.queryWorks(ctx, "SELECT "+workColumns+" FROM works"+clause+" ORDER BY LOWER(title), id", args...)
+	if err != nil {
+		return nil, err
+	}
+	err = s.loadEditions(ctx, s.reader(), works, "")
+	return works, err
+}
+
+func (s *sqlStore) GetWork(ctx context.Context, id int) (Work, error) {
+	q := s.reader()
+	row := q.QueryRowContext(ctx, s.dialect.rebind("SELECT "+workColumns+" FROM works WHERE tenant_id = ? AND id = ?"+s.lockClause()), tenantId(ctx), id)
+	work, err := scanWork(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Work{}, ErrNotFound
+	} else if err != nil {
+		return Work{}, err
+	}
+
+	works := []Work{work}
+	err = s.loadEditions(ctx, q, works, "work_editions.work_id = ?", id)
+	return works[0], err
+}
+
+// searchWorksQuery selects the works matching the pattern, given four
+// times, on their own title and author or on an edition's.
+const searchWorksQuery = "SELECT " + workColumns + " FROM works WHERE tenant_id = ?" +
+	" AND (LOWER(title) LIKE ? ESCAPE '!' OR LOWER(author) LIKE ? ESCAPE '!' OR id IN (" +
+	"SELECT work_editions.work_id FROM work_editions JOIN books ON books.id = work_editions.book_id" +
+	" WHERE LOWER(books.title) LIKE ? ESCAPE '!' OR LOWER(books.author) LIKE ? ESCAPE '!'))" +
+	" ORDER BY id"
+
+func (s *sqlStore) SearchWorks(ctx context.Context, query string) ([]Work, error) {
+	pattern := "%" + escapeLike(strings.ToLower(query)) + "%"
+	works, err := s.queryWorks(ctx, searchWorksQuery, tenantId(ctx), pattern, pattern, pattern, pattern)
+	if err != nil || len(works) == 0 {
+		return works, err
+	}
+
+	ids := make([]int, len(works))
+	for i, work := range works {
+		ids[i] = work.Id
+	}
+	condition, args := sqlIn("work_editions.work_id", ids)
+	err = s.loadEditions(ctx, s.reader(), works, condition, args...)
+	return works, err
+}
+
+// Run a read-only query returning work rows, without their editions.
+func (s *sqlStore) queryWorks(ctx context.Context, query string, args ...any) ([]Work, error) {
+	rows, err := s.reader().QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	works := []Work{}
+	for rows.Next() {
+		work, err := scanWork(rows)
+		if err != nil {
+			return nil, err
+		}
+		works = append(works, work)
+	}
+	return works, rows.Err()
+}
+
+// Fill in the editions of works, all of them matching condition, or all of
+// the tenant's editions if it is empty.
+func (s *sqlStore) loadEditions(ctx context.Context, q queryer, works []Work, condition string, args ...any) error {
+	if len(works) == 0 {
+		return nil
+	}
+	index := make(map[int]int, len(works))
+	for i, work := range works {
+		index[work.Id] = i
+	}
+
+	clause, args := where("work_editions.tenant_id = ?", tenantId(ctx)).and(condition, args...).clause()
+	rows, err := q.QueryContext(ctx, s.dialect.rebind("SELECT work_editions.work_id, "+joinedBookColumns+" FROM work_editions JOIN books ON books.id = work_editions.book_id"+clause+" ORDER BY work_editions.work_id, books.id"), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var workId int
+		var b Book
+		if err := rows.Scan(append([]any{&workId}, b.fieldPointers()...)...); err != nil {
+			return err
+		}
+		if i, ok := index[workId]; ok {
+			works[i].Editions = append(works[i].Editions, b)
+		}
+	}
+	return rows.Err()
+}
+
+func (s *sqlStore) CreateWork(ctx context.Context, work *Work) error {
+	id, err := s.insert(ctx, "INSERT INTO works (tenant_id, title, author, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		tenantId(ctx), work.Title, work.Author, work.CreatedAt, work.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	work.Id = id
+	return nil
+}
+
+func (s *sqlStore) UpdateWork(ctx context.Context, work Work) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("UPDATE works SET title = ?, author = ?, updated_at = ? WHERE tenant_id = ? AND id = ?"),
+		work.Title, work.Author, work.UpdatedAt, tenantId(ctx), work.Id)
+	if err != nil {
+		return err
+	}
+	// MySQL counts only rows that changed, so check for the work when
+	// nothing did.
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		_, err := s.GetWork(ctx, work.Id)
+		return err
+	}
+	return nil
+}
+
+func (s *sqlStore) DeleteWork(ctx context.Context, id int) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM works WHERE tenant_id = ? AND id = ?"), tenantId(ctx), id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore) SetBookWork(ctx context.Context, bookId, workId int) error {
+	return s.WithTx(ctx, func(tx BookStore) error {
+		t := tx.(*sqlStore)
+		if _, err := t.getBookFields(ctx, t.conn(), bookId, []string{"id"}); err != nil {
+			return err
+		}
+		var id int
+		err := t.conn().QueryRowContext(ctx, t.dialect.rebind("SELECT id FROM works WHERE tenant_id = ? AND id = ?"+t.lockClause()), tenantId(ctx), workId).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		} else if err != nil {
+			return err
+		}
+		if _, err := t.conn().ExecContext(ctx, t.dialect.rebind("DELETE FROM work_editions WHERE tenant_id = ? AND book_id = ?"), tenantId(ctx), bookId); err != nil {
+			return err
+		}
+		_, err = t.conn().ExecContext(ctx, t.dialect.rebind("INSERT INTO work_editions (tenant_id, book_id, work_id) VALUES (?, ?, ?)"), tenantId(ctx), bookId, workId)
+		return err
+	})
+}
+
+func (s *sqlStore) RemoveBookFromWork(ctx context.Context, bookId, workId int) error {
+	result, err := s.conn().ExecContext(ctx, s.dialect.rebind("DELETE FROM work_editions WHERE tenant_id = ? AND book_id = ? AND work_id = ?"),
+		tenantId(ctx), bookId, workId)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
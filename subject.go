@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// BookSubjects is what a book is about: the categories it is shelved
+// under, such as "Science Fiction", and free-form tags, such as "space
+// opera". Tags are lower case; both are compared ignoring case.
+type BookSubjects struct {
+	Categories []string `json:"categories" xml:"categories>category" validate:"max=20,dive,required,max=100"`
+	Tags       []string `json:"tags" xml:"tags>tag" validate:"max=50,dive,required,max=100"`
+}
+
+type BookSubjectsResponse struct {
+	Status  string       `json:"status" xml:"status"`
+	Message string       `json:"message" xml:"message"`
+	Data    BookSubjects `json:"data" xml:"data"`
+}
+
+// SimilarBook is a book sharing an author or subjects with another, and
+// how much they share: 3 for the same author, 2 for each category and 1
+// for each tag in common.
+type SimilarBook struct {
+	Score int  `json:"score" xml:"score"`
+	Book  Book `json:"book" xml:"book"`
+}
+
+type SimilarBooksResponse struct {
+	Status  string        `json:"status" xml:"status"`
+	Message string        `json:"message" xml:"message"`
+	Data    []SimilarBook `json:"data" xml:"data>similar"`
+}
+
+const (
+	defaultSimilarBooks = 10
+	maxSimilarBooks     = 50
+)
+
+// Clean up the names as entered, dropping repeats, and lower case the
+// tags.
+func (s *BookSubjects) normalize() {
+	s.Categories = uniqueNames(s.Categories, false)
+	s.Tags = uniqueNames(s.Tags, true)
+}
+
+func uniqueNames(names []string, lower bool) []string {
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = normalizeText(name)
+		if lower {
+			name = strings.ToLower(name)
+		}
+		if key := strings.ToLower(name); name != "" && !seen[key] {
+			seen[key] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+func getBookSubjectsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+
+	subjects, err := store.GetBookSubjects(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching book subjects")
+		log.Printf("Subject query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, BookSubjectsResponse{
+		Status:  "success",
+		Message: "Book subjects retrieved successfully",
+		Data:    subjects,
+	})
+}
+
+// Replace a book's categories and tags.
+func updateBookSubjectsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+	var subjects BookSubjects
+	if err := decodeRequest(r, &subjects); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	if errs := validateRequest(subjects); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	subjects.normalize()
+
+	err = store.SetBookSubjects(r.Context(), id, subjects)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error updating book subjects")
+		log.Printf("Subject update error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, BookSubjectsResponse{
+		Status:  "success",
+		Message: "Book subjects updated successfully",
+		Data:    subjects,
+	})
+}
+
+// List the books most like a book, by author and subjects, best first.
+func similarBooksHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+	limit, ok := intParameter(r, "limit", defaultSimilarBooks, maxSimilarBooks)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(maxSimilarBooks))
+		return
+	}
+
+	similar, err := store.SimilarBooks(r.Context(), id, limit)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error finding similar books")
+		log.Printf("Similar books query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, SimilarBooksResponse{
+		Status:  "success",
+		Message: "Similar books retrieved successfully",
+		Data:    similar,
+	})
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	defaultSuggestions = 8
+	maxSuggestions     = 20
+	// How long a tenant's suggestion index is used before it is rebuilt,
+	// to pick up writes relayed by other instances; this instance's own
+	// writes drop it at once.
+	suggestIndexMaxAge = time.Minute
+)
+
+// Suggestion is a title or author completing what a reader is typing.
+type Suggestion struct {
+	Text string `json:"text" xml:"text"`
+	// Field is "title" or "author".
+	Field string `json:"field" xml:"field"`
+	// Books is how many books have the title or author.
+	Books int `json:"books" xml:"books"`
+}
+
+type SuggestionsResponse struct {
+	Status  string       `json:"status" xml:"status"`
+	Message string       `json:"message" xml:"message"`
+	Data    []Suggestion `json:"data" xml:"data>suggestion"`
+}
+
+// suggestEntry is a title or author under one of its keys: its text,
+// lower-cased, from the start of one of its words.
+type suggestEntry struct {
+	key string
+	Suggestion
+	// whole is set if key starts at the start of the text.
+	whole bool
+}
+
+// suggestIndex is a tenant's titles and authors sorted by key, so those
+// with a word starting with a prefix are next to each other.
+type suggestIndex struct {
+	entries []suggestEntry
+	built   time.Time
+}
+
+// bookSuggester keeps the suggestion index of each tenant, built on the
+// first suggestion asked for after a write to its catalog.
+type bookSuggester struct {
+	mu      sync.Mutex
+	indexes map[int]*suggestIndex
+	// changed is when each tenant's index was last dropped, so an index
+	// whose build started before that isn't kept.
+	changed map[int]time.Time
+}
+
+var suggestions = &bookSuggester{indexes: map[int]*suggestIndex{}, changed: map[int]time.Time{}}
+
+// The suggestion index of the tenant in ctx, building it if there is
+// none or it is too old.
+func (s *bookSuggester) index(ctx context.Context) (*suggestIndex, error) {
+	s.mu.Lock()
+	index, ok := s.indexes[tenantId(ctx)]
+	s.mu.Unlock()
+	if ok && time.Since(index.built) < suggestIndexMaxAge {
+		return index, nil
+	}
+
+	built := time.Now()
+	books, err := store.ListBooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index = newSuggestIndex(books)
+	index.built = built
+
+	s.mu.Lock()
+	if built.After(s.changed[tenantId(ctx)]) {
+		s.indexes[tenantId(ctx)] = index
+	}
+	s.mu.Unlock()
+	return index, nil
+}
+
+// Drop the indexes of the tenants whose catalogs events changed; the
+// outbox relay calls it with each batch.
+func (s *bookSuggester) relayed(events []Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, event := range events {
+		delete(s.indexes, event.TenantId)
+		s.changed[event.TenantId] = now
+	}
+}
+
+func newSuggestIndex(books []Book) *suggestIndex {
+	counts := map[Suggestion]int{}
+	for _, book := range books {
+		counts[Suggestion{Text: book.Title, Field: "title"}]++
+		counts[Suggestion{Text: book.Author, Field: "author"}]++
+	}
+
+	index := &suggestIndex{}
+	for suggestion, n := range counts {
+		suggestion.Books = n
+		text := strings.ToLower(suggestion.Text)
+		for i, word := range wordStarts(text) {
+			index.entries = append(index.entries, suggestEntry{key: text[word:], Suggestion: suggestion, whole: i == 0})
+		}
+	}
+	sort.Slice(index.entries, func(i, j int) bool {
+		return index.entries[i].key < index.entries[j].key
+	})
+	return index
+}
+
+// The byte offsets of the words of s, letters and digits after anything
+// else.
+func wordStarts(s string) []int {
+	var starts []int
+	inWord := false
+	for i, r := range s {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && !inWord {
+			starts = append(starts, i)
+		}
+		inWord = isWord
+	}
+	return starts
+}
+
+// Up to limit titles and authors with a word starting with prefix,
+// lower-cased: those starting with it first, then those with the most
+// books, then alphabetically.
+func (index *suggestIndex) suggest(prefix string, limit int) []Suggestion {
+	first := sort.Search(len(index.entries), func(i int) bool {
+		return index.entries[i].key >= prefix
+	})
+	// A text with several words starting with prefix is listed once,
+	// as whole if it starts with it.
+	whole := map[Suggestion]bool{}
+	for _, entry := range index.entries[first:] {
+		if !strings.HasPrefix(entry.key, prefix) {
+			break
+		}
+		whole[entry.Suggestion] = whole[entry.Suggestion] || entry.whole
+	}
+
+	found := make([]Suggestion, 0, len(whole))
+	for suggestion := range whole {
+		found = append(found, suggestion)
+	}
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if whole[a] != whole[b] {
+			return whole[a]
+		}
+		if a.Books != b.Books {
+			return a.Books > b.Books
+		}
+		if a.Text != b.Text {
+			return a.Text < b.Text
+		}
+		return a.Field > b.Field
+	})
+	return found[:min(limit, len(found))]
+}
+
+// Complete what a reader is typing into a search box with titles and
+// authors from the catalog, for typeahead; answered from memory.
+func suggestBooksHandler(w http.ResponseWriter, r *http.Request) {
+	prefix := strings.ToLower(normalizeText(r.URL.Query().Get("q")))
+	if prefix == "" {
+		writeProblem(w, r, codeInvalidParameter, "Search query is required")
+		return
+	}
+	limit, ok := intParameter(r, "limit", defaultSuggestions, maxSuggestions)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(maxSuggestions))
+		return
+	}
+
+	index, err := suggestions.index(r.Context())
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching suggestions")
+		log.Printf("Suggestion index error: %v", err)
+		return
+	}
+	writeResponse(w, r, http.StatusOK, SuggestionsResponse{
+		Status:  "success",
+		Message: "Suggestions retrieved successfully",
+		Data:    index.suggest(prefix, limit),
+	})
+}
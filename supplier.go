@@ -0,0 +1,412 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Supplier is a publisher, wholesaler or shop the library orders books
+// from.
+type Supplier struct {
+	Id    int    `json:"id" xml:"id"`
+	Name  string `json:"name" xml:"name" validate:"required,max=255"`
+	Email string `json:"email,omitempty" xml:"email,omitempty" validate:"omitempty,email,max=255"`
+	// Phone is in E.164 format.
+	Phone     string    `json:"phone,omitempty" xml:"phone,omitempty" validate:"omitempty,e164"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+}
+
+func (s Supplier) timestamps() (created, updated time.Time) {
+	return s.CreatedAt, s.UpdatedAt
+}
+
+type SupplierResponse struct {
+	Status  string   `json:"status" xml:"status"`
+	Message string   `json:"message" xml:"message"`
+	Data    Supplier `json:"data" xml:"data"`
+}
+
+type SuppliersResponse struct {
+	Status  string     `json:"status" xml:"status"`
+	Message string     `json:"message" xml:"message"`
+	Data    []Supplier `json:"data" xml:"data>supplier"`
+}
+
+// SupplierPrice is what a supplier charges for a book, in the catalog's
+// currency, and how long it takes to deliver. It was created when the
+// supplier first had a price for the book.
+type SupplierPrice struct {
+	SupplierId   int       `json:"supplier_id" xml:"supplier_id"`
+	SupplierName string    `json:"supplier_name" xml:"supplier_name"`
+	BookId       int       `json:"book_id" xml:"book_id"`
+	Title        string    `json:"title" xml:"title"`
+	UnitCost     float64   `json:"unit_cost" xml:"unit_cost" validate:"gte=0,lte=99999999.99"`
+	LeadTimeDays int       `json:"lead_time_days" xml:"lead_time_days" validate:"gte=0,lte=365"`
+	CreatedAt    time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" xml:"updated_at"`
+}
+
+func (p SupplierPrice) timestamps() (created, updated time.Time) {
+	return p.CreatedAt, p.UpdatedAt
+}
+
+// SupplierPriceQuery selects prices for ListSupplierPrices.
+type SupplierPriceQuery struct {
+	// SupplierId and BookId keep only the prices of the supplier and of
+	// the book if they aren't zero.
+	SupplierId, BookId int
+	ChangedAfter
+}
+
+type SupplierPriceResponse struct {
+	Status  string        `json:"status" xml:"status"`
+	Message string        `json:"message" xml:"message"`
+	Data    SupplierPrice `json:"data" xml:"data"`
+}
+
+type SupplierPricesResponse struct {
+	Status  string          `json:"status" xml:"status"`
+	Message string          `json:"message" xml:"message"`
+	Data    []SupplierPrice `json:"data" xml:"data>price"`
+}
+
+var errSupplierHasOrders = errors.New("supplier has purchase orders")
+
+// Tidy the fields as typed.
+func (s *Supplier) normalize() {
+	s.Name = normalizeText(s.Name)
+	s.Email = strings.TrimSpace(s.Email)
+	s.Phone = strings.TrimSpace(s.Phone)
+}
+
+// List the suppliers by name.
+func listSuppliersHandler(w http.ResponseWriter, r *http.Request) {
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+	suppliers, err := store.ListSuppliers(r.Context(), after)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching suppliers")
+		log.Printf("Supplier query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, SuppliersResponse{
+		Status:  "success",
+		Message: "Suppliers retrieved successfully",
+		Data:    suppliers,
+	})
+}
+
+func createSupplierHandler(w http.ResponseWriter, r *http.Request) {
+	var supplier Supplier
+	if err := decodeRequest(r, &supplier); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	supplier.normalize()
+	if errs := validateRequest(supplier); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	supplier.Id = 0
+	supplier.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	supplier.UpdatedAt = supplier.CreatedAt
+
+	if err := store.CreateSupplier(r.Context(), &supplier); err != nil {
+		writeProblem(w, r, codeInternal, "Error creating supplier")
+		log.Printf("Supplier creation error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, SupplierResponse{
+		Status:  "success",
+		Message: "Supplier created successfully",
+		Data:    supplier,
+	})
+}
+
+func getSupplierHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid supplier ID")
+		return
+	}
+
+	supplier, err := store.GetSupplier(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeSupplierNotFound, "Supplier not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching supplier")
+		log.Printf("Supplier query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, SupplierResponse{
+		Status:  "success",
+		Message: "Supplier retrieved successfully",
+		Data:    supplier,
+	})
+}
+
+// Replace a supplier's details.
+func updateSupplierHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid supplier ID")
+		return
+	}
+	var req Supplier
+	if err := decodeRequest(r, &req); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	req.normalize()
+	if errs := validateRequest(req); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	var supplier Supplier
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		var err error
+		if supplier, err = tx.GetSupplier(r.Context(), id); err != nil {
+			return err
+		}
+		supplier.Name, supplier.Email, supplier.Phone = req.Name, req.Email, req.Phone
+		supplier.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		return tx.UpdateSupplier(r.Context(), supplier)
+	})
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeSupplierNotFound, "Supplier not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error updating supplier")
+		log.Printf("Supplier update error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, SupplierResponse{
+		Status:  "success",
+		Message: "Supplier updated successfully",
+		Data:    supplier,
+	})
+}
+
+// Delete a supplier and its prices, unless books were ordered from it.
+func deleteSupplierHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid supplier ID")
+		return
+	}
+
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		if _, err := tx.GetSupplier(r.Context(), id); err != nil {
+			return err
+		}
+		orders, err := tx.ListPurchaseOrders(r.Context(), PurchaseOrderQuery{SupplierId: id})
+		if err != nil {
+			return err
+		}
+		if len(orders) > 0 {
+			return errSupplierHasOrders
+		}
+		return tx.DeleteSupplier(r.Context(), id)
+	})
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeSupplierNotFound, "Supplier not found")
+		return
+	case errors.Is(err, errSupplierHasOrders):
+		writeProblem(w, r, codeSupplierHasOrders, "Books were ordered from the supplier")
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error deleting supplier")
+		log.Printf("Supplier deletion error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Supplier deleted successfully",
+	})
+}
+
+// List what a supplier charges for books, cheapest first.
+func listSupplierPricesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid supplier ID")
+		return
+	}
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+
+	_, err = store.GetSupplier(r.Context(), id)
+	var prices []SupplierPrice
+	if err == nil {
+		prices, err = store.ListSupplierPrices(r.Context(), SupplierPriceQuery{SupplierId: id, ChangedAfter: after})
+	}
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeSupplierNotFound, "Supplier not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching supplier prices")
+		log.Printf("Supplier price query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, SupplierPricesResponse{
+		Status:  "success",
+		Message: "Supplier prices retrieved successfully",
+		Data:    prices,
+	})
+}
+
+// Set what a supplier charges for a book and how long it takes to deliver.
+func setSupplierPriceHandler(w http.ResponseWriter, r *http.Request) {
+	supplierId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid supplier ID")
+		return
+	}
+	bookId, err := strconv.Atoi(mux.Vars(r)["book_id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+	var price SupplierPrice
+	if err := decodeRequest(r, &price); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	if errs := validateRequest(price); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	price = SupplierPrice{
+		SupplierId:   supplierId,
+		BookId:       bookId,
+		UnitCost:     roundCents(price.UnitCost),
+		LeadTimeDays: price.LeadTimeDays,
+		UpdatedAt:    time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		supplier, err := tx.GetSupplier(r.Context(), supplierId)
+		if errors.Is(err, ErrNotFound) {
+			return errSupplierMissing
+		} else if err != nil {
+			return err
+		}
+		book, err := tx.GetBookFields(r.Context(), bookId, []string{"id", "title"})
+		if err != nil {
+			return err
+		}
+		price.SupplierName, price.Title = supplier.Name, book.Title
+		return tx.SetSupplierPrice(r.Context(), &price)
+	})
+	switch {
+	case errors.Is(err, errSupplierMissing):
+		writeProblem(w, r, codeSupplierNotFound, "Supplier not found")
+		return
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error setting supplier price")
+		log.Printf("Supplier price update error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, SupplierPriceResponse{
+		Status:  "success",
+		Message: "Supplier price set successfully",
+		Data:    price,
+	})
+}
+
+func deleteSupplierPriceHandler(w http.ResponseWriter, r *http.Request) {
+	supplierId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid supplier ID")
+		return
+	}
+	bookId, err := strconv.Atoi(mux.Vars(r)["book_id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+
+	err = store.DeleteSupplierPrice(r.Context(), supplierId, bookId)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeSupplierPriceNotFound, "The supplier has no price for this book")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error deleting supplier price")
+		log.Printf("Supplier price deletion error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Supplier price deleted successfully",
+	})
+}
+
+// List what each supplier charges for a book, cheapest first and then
+// quickest to deliver, so the first is where to order it.
+func listBookSuppliersHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+
+	_, err = store.GetBookFields(r.Context(), id, []string{"id"})
+	var prices []SupplierPrice
+	if err == nil {
+		prices, err = store.ListSupplierPrices(r.Context(), SupplierPriceQuery{BookId: id, ChangedAfter: after})
+	}
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching supplier prices")
+		log.Printf("Supplier price query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, SupplierPricesResponse{
+		Status:  "success",
+		Message: "Supplier prices retrieved successfully",
+		Data:    prices,
+	})
+}
+
+// The cheapest of each book's prices, by book id, breaking ties by the
+// quickest delivery. prices must be in the order ListSupplierPrices
+// returns them.
+func cheapestSuppliers(prices []SupplierPrice) map[int]SupplierPrice {
+	cheapest := map[int]SupplierPrice{}
+	for _, price := range prices {
+		if _, ok := cheapest[price.BookId]; !ok {
+			cheapest[price.BookId] = price
+		}
+	}
+	return cheapest
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// TaxRule is a sales tax rate for books sold in a region, in a category,
+// or both; leaving either empty matches any. The first rule matching a
+// line of a sale applies to it, so more specific rules go first.
+type TaxRule struct {
+	// Name labels the tax on sales, like "VAT (reduced)".
+	Name     string `json:"name"`
+	Region   string `json:"region"`
+	Category string `json:"category"`
+	// Rate is a fraction, 0.07 for 7%.
+	Rate float64 `json:"rate"`
+}
+
+// taxPolicy is how sales are taxed.
+type taxPolicy struct {
+	rules []TaxRule
+	// included is whether book prices include tax rather than having it
+	// added to them.
+	included bool
+	// region is where sales are taxed unless they say otherwise.
+	region string
+}
+
+var taxes = &taxPolicy{}
+
+// Set up the tax rules as configured.
+func initTaxes(cfg Config) error {
+	for i, rule := range cfg.TaxRules {
+		if rule.Name == "" || len(rule.Name) > 64 {
+			return fmt.Errorf("tax_rules[%d]: name must be 1 to 64 characters", i)
+		}
+		if rule.Rate < 0 || rule.Rate >= 1 {
+			return fmt.Errorf("tax_rules[%d]: rate must be a fraction from 0 to less than 1", i)
+		}
+	}
+	taxes = &taxPolicy{rules: cfg.TaxRules, included: cfg.TaxIncluded, region: cfg.TaxRegion}
+	return nil
+}
+
+// The rule for a book in categories sold in region, if any matches.
+// Regions and categories match ignoring case.
+func (p *taxPolicy) rule(region string, categories []string) (TaxRule, bool) {
+	for _, rule := range p.rules {
+		if rule.Region != "" && !strings.EqualFold(rule.Region, region) {
+			continue
+		}
+		if rule.Category != "" && !slices.ContainsFunc(categories, func(c string) bool { return strings.EqualFold(c, rule.Category) }) {
+			continue
+		}
+		return rule, true
+	}
+	return TaxRule{}, false
+}
+
+// Charge the tax on a line of a sale whose amount, before tax unless
+// included, is already worked out. Included tax comes out of the amount.
+func (p *taxPolicy) apply(line *SaleLine, rule TaxRule) {
+	line.TaxName, line.TaxRate = rule.Name, rule.Rate
+	if p.included {
+		line.Tax = roundCents(line.Amount - line.Amount/(1+rule.Rate))
+		line.Amount = roundCents(line.Amount - line.Tax)
+	} else {
+		line.Tax = roundCents(line.Amount * rule.Rate)
+	}
+}
+
+// Itemize the tax on the sale's lines by rule, in the order the rules
+// first apply, and work out its totals and what is left to pay after its
+// points discount and gift card.
+func (s *Sale) totalTaxes() {
+	s.Subtotal, s.Tax, s.Taxes = 0, 0, []SaleTax{}
+	for _, line := range s.Lines {
+		s.Subtotal = roundCents(s.Subtotal + line.Amount)
+		if line.TaxName == "" {
+			continue
+		}
+		i := slices.IndexFunc(s.Taxes, func(t SaleTax) bool { return t.Name == line.TaxName && t.Rate == line.TaxRate })
+		if i < 0 {
+			i = len(s.Taxes)
+			s.Taxes = append(s.Taxes, SaleTax{Name: line.TaxName, Rate: line.TaxRate})
+		}
+		s.Taxes[i].Taxable = roundCents(s.Taxes[i].Taxable + line.Amount)
+		s.Taxes[i].Amount = roundCents(s.Taxes[i].Amount + line.Tax)
+		s.Tax = roundCents(s.Tax + line.Tax)
+	}
+	s.Total = roundCents(s.Subtotal + s.Tax)
+	s.AmountDue = roundCents(s.Total - s.PointsDiscount - s.GiftCardAmount)
+}
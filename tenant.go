@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tenant is one bookstore or library sharing the deployment. Every book,
+// user, webhook and the rest belongs to one tenant and is only seen by
+// requests made for it.
+type Tenant struct {
+	Id int `json:"id" xml:"id"`
+	// Slug names the tenant in the X-Tenant header and as the subdomain
+	// of tenant_domain.
+	Slug      string    `json:"slug" xml:"slug"`
+	Name      string    `json:"name" xml:"name"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+}
+
+func (t Tenant) timestamps() (created, updated time.Time) {
+	return t.CreatedAt, t.UpdatedAt
+}
+
+// The tenant everything from before tenants belongs to, which requests
+// naming no tenant are made for.
+var defaultTenant = Tenant{Id: 1, Slug: "default", Name: "Default"}
+
+var tenantSlugPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// CreateTenantRequest is a new tenant and its first admin user.
+type CreateTenantRequest struct {
+	Slug  string `json:"slug" xml:"slug" validate:"required,max=63,tenant_slug"`
+	Name  string `json:"name" xml:"name" validate:"required,max=255"`
+	Admin User   `json:"admin" xml:"admin"`
+}
+
+// TenantAdmin is a new tenant and the token of its first admin user.
+type TenantAdmin struct {
+	Tenant Tenant    `json:"tenant" xml:"tenant"`
+	Admin  UserToken `json:"admin" xml:"admin"`
+}
+
+type TenantAdminResponse struct {
+	Status  string      `json:"status" xml:"status"`
+	Message string      `json:"message" xml:"message"`
+	Data    TenantAdmin `json:"data" xml:"data"`
+}
+
+type TenantsResponse struct {
+	Status  string   `json:"status" xml:"status"`
+	Message string   `json:"message" xml:"message"`
+	Data    []Tenant `json:"data" xml:"data>tenant"`
+}
+
+type tenantContextKey struct{}
+
+// Make ctx act for the tenant: stores read and write only its data.
+func withTenant(ctx context.Context, tenant Tenant) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// Make ctx act for the tenant with the id, for workers handling records
+// that carry only their tenant's id.
+func withTenantId(ctx context.Context, id int) context.Context {
+	return withTenant(ctx, Tenant{Id: id})
+}
+
+// The tenant ctx acts for, the default tenant unless withTenant set one.
+func currentTenant(ctx context.Context) Tenant {
+	if tenant, ok := ctx.Value(tenantContextKey{}).(Tenant); ok {
+		return tenant
+	}
+	return defaultTenant
+}
+
+// The id of the tenant ctx acts for, which stores scope their queries to.
+func tenantId(ctx context.Context) int {
+	return currentTenant(ctx).Id
+}
+
+// Tenants are never renamed or removed, so they are looked up by slug
+// once.
+var tenantsBySlug sync.Map
+
+func lookupTenant(ctx context.Context, slug string) (Tenant, error) {
+	if tenant, ok := tenantsBySlug.Load(slug); ok {
+		return tenant.(Tenant), nil
+	}
+	tenant, err := store.GetTenantBySlug(ctx, slug)
+	if err != nil {
+		return Tenant{}, err
+	}
+	tenantsBySlug.Store(slug, tenant)
+	return tenant, nil
+}
+
+// Make each request act for the tenant it names: in the X-Tenant header,
+// or as the subdomain of domain when it is set. Requests naming neither
+// are for the default tenant, and ones naming a tenant that doesn't exist
+// are refused.
+func resolveTenant(domain string) func(http.Handler) http.Handler {
+	domain = strings.ToLower(domain)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			slug := r.Header.Get("X-Tenant")
+			if slug == "" && domain != "" {
+				host, _, err := net.SplitHostPort(r.Host)
+				if err != nil {
+					host = r.Host
+				}
+				if sub, ok := strings.CutSuffix(strings.ToLower(host), "."+domain); ok && !strings.Contains(sub, ".") {
+					slug = sub
+				}
+			}
+			if slug == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			tenant, err := lookupTenant(r.Context(), strings.ToLower(slug))
+			if errors.Is(err, ErrNotFound) {
+				writeProblem(w, r, codeTenantNotFound, "Tenant not found")
+				return
+			} else if err != nil {
+				writeProblem(w, r, codeInternal, "Error fetching tenant")
+				log.Printf("Tenant query error: %v", err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(withTenant(r.Context(), tenant)))
+		})
+	}
+}
+
+// Run fn once for every tenant, with a context acting for it, stopping at
+// the first error; for jobs that work on each tenant's data.
+func forEachTenant(ctx context.Context, fn func(ctx context.Context) error) error {
+	tenants, err := store.ListTenants(ctx, ChangedAfter{})
+	if err != nil {
+		return err
+	}
+	for _, tenant := range tenants {
+		if err := fn(withTenant(ctx, tenant)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create a tenant with its first admin user, who can then manage it and
+// make more admins.
+func createTenantHandler(w http.ResponseWriter, r *http.Request) {
+	var req CreateTenantRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	if errs := validateRequest(req); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	tenant := Tenant{Slug: req.Slug, Name: req.Name, CreatedAt: time.Now().UTC().Truncate(time.Microsecond)}
+	tenant.UpdatedAt = tenant.CreatedAt
+	admin := req.Admin
+	admin.Admin = true
+
+	var token string
+	err := store.WithTx(r.Context(), func(tx BookStore) error {
+		if err := tx.CreateTenant(r.Context(), &tenant); err != nil {
+			return err
+		}
+		var err error
+		token, err = createUserWithToken(withTenant(r.Context(), tenant), tx, &admin)
+		return err
+	})
+	if errors.Is(err, ErrDuplicateTenant) {
+		writeProblem(w, r, codeDuplicateTenant, "Another tenant has this slug")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error creating tenant")
+		log.Printf("Tenant creation error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, TenantAdminResponse{
+		Status:  "success",
+		Message: "Tenant created successfully",
+		Data:    TenantAdmin{Tenant: tenant, Admin: UserToken{User: admin, Token: token}},
+	})
+}
+
+func listTenantsHandler(w http.ResponseWriter, r *http.Request) {
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+	tenants, err := store.ListTenants(r.Context(), after)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching tenants")
+		log.Printf("Tenant query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, TenantsResponse{
+		Status:  "success",
+		Message: "Tenants retrieved successfully",
+		Data:    tenants,
+	})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"time"
+)
+
+// timestamped is a record with the times its store added it and last
+// changed it, zero if it has none from before they were kept. Records
+// that never change were last changed when they were added.
+type timestamped interface {
+	timestamps() (created, updated time.Time)
+}
+
+// ChangedAfter keeps only the records of a list added after Created and
+// changed after Updated, as far as those aren't zero: the
+// ?created_after= and ?updated_after= of the list.
+type ChangedAfter struct {
+	Created, Updated time.Time
+}
+
+// The ?created_after= and ?updated_after= of a list, answering with a
+// problem and returning false if either is malformed.
+func requestedChangedAfterFilter(w http.ResponseWriter, r *http.Request) (ChangedAfter, bool) {
+	created, updated, err := requestedChangedAfter(r)
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, err.Error())
+		return ChangedAfter{}, false
+	}
+	return ChangedAfter{Created: created, Updated: updated}, true
+}
+
+// Whether record was added and changed late enough to keep.
+func (after ChangedAfter) keeps(record timestamped) bool {
+	created, updated := record.timestamps()
+	return (after.Created.IsZero() || created.After(after.Created)) &&
+		(after.Updated.IsZero() || updated.After(after.Updated))
+}
+
+// The records of list added after after.Created and changed after
+// after.Updated, as far as those are given.
+func keepChangedAfter[T timestamped](list []T, after ChangedAfter) []T {
+	if after == (ChangedAfter{}) {
+		return list
+	}
+	return slices.DeleteFunc(list, func(record T) bool { return !after.keeps(record) })
+}
+
+// The time t points to, or zero.
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
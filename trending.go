@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// BookActivity counts what happened to a book on one day (in UTC).
+type BookActivity struct {
+	BookId int
+	Day    time.Time
+	Views  int
+	Loans  int
+	Sales  int
+}
+
+// TrendingBook is a book and its activity over a window.
+type TrendingBook struct {
+	Views int  `json:"views" xml:"views"`
+	Loans int  `json:"loans" xml:"loans"`
+	Sales int  `json:"sales" xml:"sales"`
+	Book  Book `json:"book" xml:"book"`
+}
+
+type TrendingBooksResponse struct {
+	Status  string         `json:"status" xml:"status"`
+	Message string         `json:"message" xml:"message"`
+	Data    []TrendingBook `json:"data" xml:"data>trending"`
+}
+
+// The kinds of activity counted, which are also the book_activity columns
+// trending books can be ranked by.
+const (
+	ActivityView = "views"
+	ActivityLoan = "loans"
+	ActivitySale = "sales"
+)
+
+// The windows trending books are counted over, as how many days they
+// cover, ending today.
+var trendingWindows = map[string]int{"day": 1, "week": 7, "month": 30}
+
+const (
+	defaultTrendingBooks = 20
+	maxTrendingBooks     = 100
+)
+
+// activityCounter adds up activity in memory until the activity_flush job
+// writes it to the store, so counting a view costs no query.
+type activityCounter struct {
+	mu     sync.Mutex
+	counts map[activityKey]BookActivity
+}
+
+type activityKey struct {
+	tenantId int
+	bookId   int
+	day      time.Time
+}
+
+var activity = &activityCounter{counts: make(map[activityKey]BookActivity)}
+
+// Count one of kind today for the book of the tenant ctx acts for.
+func (c *activityCounter) count(ctx context.Context, bookId int, kind string) {
+	c.add(ctx, bookId, kind, 1)
+}
+
+// Count n of kind today for the book, like copies sold together.
+func (c *activityCounter) add(ctx context.Context, bookId int, kind string, n int) {
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := activityKey{tenantId(ctx), bookId, day}
+	a := c.counts[key]
+	a.BookId, a.Day = bookId, day
+	switch kind {
+	case ActivityView:
+		a.Views += n
+	case ActivityLoan:
+		a.Loans += n
+	case ActivitySale:
+		a.Sales += n
+	}
+	c.counts[key] = a
+}
+
+// Add the counts so far to the store; the activity_flush job. Counts the
+// store couldn't take are kept for the next flush.
+func (c *activityCounter) flush(ctx context.Context) error {
+	c.mu.Lock()
+	counts := c.counts
+	c.counts = make(map[activityKey]BookActivity)
+	c.mu.Unlock()
+	if len(counts) == 0 {
+		return nil
+	}
+
+	byTenant := map[int][]BookActivity{}
+	for key, a := range counts {
+		byTenant[key.tenantId] = append(byTenant[key.tenantId], a)
+	}
+	var errs []error
+	for tenant, activities := range byTenant {
+		err := store.AddBookActivity(withTenantId(ctx, tenant), activities)
+		if err == nil {
+			continue
+		}
+		errs = append(errs, err)
+		c.mu.Lock()
+		for _, a := range activities {
+			key := activityKey{tenant, a.BookId, a.Day}
+			b := c.counts[key]
+			a.Views, a.Loans, a.Sales = a.Views+b.Views, a.Loans+b.Loans, a.Sales+b.Sales
+			c.counts[key] = a
+		}
+		c.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// List the books with the most activity of a kind over a window, most
+// first.
+func trendingBooksHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	window := query.Get("window")
+	if window == "" {
+		window = "week"
+	}
+	days, ok := trendingWindows[window]
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "window must be day, week or month")
+		return
+	}
+	by := query.Get("by")
+	switch by {
+	case "":
+		by = ActivityView
+	case ActivityView, ActivityLoan, ActivitySale:
+	default:
+		writeProblem(w, r, codeInvalidParameter, "by must be views, loans or sales")
+		return
+	}
+	limit, ok := intParameter(r, "limit", defaultTrendingBooks, maxTrendingBooks)
+	if !ok {
+		writeProblem(w, r, codeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(maxTrendingBooks))
+		return
+	}
+
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+	trending, err := store.TrendingBooks(r.Context(), by, since, limit)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching trending books")
+		log.Printf("Trending books query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, TrendingBooksResponse{
+		Status:  "success",
+		Message: "Trending books retrieved successfully",
+		Data:    trending,
+	})
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// User is a reader with an account, who keeps a shelf of books under
+// /me.
+type User struct {
+	Id    int    `json:"id" xml:"id"`
+	Name  string `json:"name" xml:"name" validate:"required,max=255"`
+	Email string `json:"email" xml:"email" validate:"required,email,max=255"`
+	// Admin users can use the admin endpoints of their tenant. Only
+	// admins make admins.
+	Admin     bool      `json:"admin" xml:"admin"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+}
+
+// UserToken is a new user and the token they authenticate with, which is
+// only ever shown here; the store keeps its hash.
+type UserToken struct {
+	User  User   `json:"user" xml:"user"`
+	Token string `json:"token" xml:"token"`
+}
+
+type UserTokenResponse struct {
+	Status  string    `json:"status" xml:"status"`
+	Message string    `json:"message" xml:"message"`
+	Data    UserToken `json:"data" xml:"data"`
+}
+
+type UserResponse struct {
+	Status  string `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
+	Data    User   `json:"data" xml:"data"`
+}
+
+// The SHA-256 of a user token, as the store keeps it. Tokens are random,
+// so a fast hash is enough.
+func hashUserToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Sign up: create a user and hand out their token.
+func createUserHandler(w http.ResponseWriter, r *http.Request) {
+	createUser(w, r, false)
+}
+
+// Create an admin user of the tenant and hand out their token.
+func createAdminUserHandler(w http.ResponseWriter, r *http.Request) {
+	createUser(w, r, true)
+}
+
+func createUser(w http.ResponseWriter, r *http.Request, admin bool) {
+	var user User
+	if err := decodeRequest(r, &user); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	if errs := validateRequest(user); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	user.Admin = admin
+
+	token, err := createUserWithToken(r.Context(), store, &user)
+	if errors.Is(err, ErrDuplicateEmail) {
+		writeProblem(w, r, codeDuplicateEmail, "Another user has this email address")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error creating user")
+		log.Printf("User creation error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, UserTokenResponse{
+		Status:  "success",
+		Message: "User created successfully",
+		Data:    UserToken{User: user, Token: token},
+	})
+}
+
+// Store a validated user in s with a new token, and return the token.
+func createUserWithToken(ctx context.Context, s BookStore, user *User) (string, error) {
+	user.Email = strings.ToLower(user.Email)
+	user.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	user.UpdatedAt = user.CreatedAt
+
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	token := hex.EncodeToString(secret)
+	return token, s.CreateUser(ctx, user, hashUserToken(token))
+}
+
+type userContextKey struct{}
+
+// Allow only requests bearing a user's token, and make the user
+// available to handlers with currentUser.
+func requireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="bookshelf"`)
+			writeProblem(w, r, codeUnauthorized, "User token required")
+			return
+		}
+		user, err := store.GetUserByToken(r.Context(), hashUserToken(token))
+		if errors.Is(err, ErrNotFound) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="bookshelf", error="invalid_token"`)
+			writeProblem(w, r, codeUnauthorized, "User token required")
+			return
+		} else if err != nil {
+			writeProblem(w, r, codeInternal, "Error fetching user")
+			log.Printf("User query error: %v", err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey{}, user)))
+	})
+}
+
+// The user requireUser authenticated.
+func currentUser(r *http.Request) User {
+	return r.Context().Value(userContextKey{}).(User)
+}
+
+func getMeHandler(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, r, http.StatusOK, UserResponse{
+		Status:  "success",
+		Message: "User retrieved successfully",
+		Data:    currentUser(r),
+	})
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Request types declare their rules in validate struct tags; see
+// https://pkg.go.dev/github.com/go-playground/validator/v10 for the
+// syntax. Rules report fields by their JSON names.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	// Replaces the validator's own, which allows 13 digit ISBNs without a
+	// 978 or 979 prefix.
+	v.RegisterValidation("isbn", func(fl validator.FieldLevel) bool {
+		_, ok := normalizeISBN(fl.Field().String())
+		return ok
+	})
+	v.RegisterValidation("event_type", func(fl validator.FieldLevel) bool {
+		return slices.Contains(webhookEventTypes, fl.Field().String())
+	})
+	v.RegisterValidation("notification_kind", func(fl validator.FieldLevel) bool {
+		return slices.Contains(notificationKinds, fl.Field().String())
+	})
+	v.RegisterValidation("notification_channel", func(fl validator.FieldLevel) bool {
+		return slices.Contains(notificationChannelNames, fl.Field().String())
+	})
+	v.RegisterValidation("book_format", func(fl validator.FieldLevel) bool {
+		return slices.Contains(bookFormats, fl.Field().String())
+	})
+	v.RegisterValidation("reading_status", func(fl validator.FieldLevel) bool {
+		return slices.Contains(readingStatuses, fl.Field().String())
+	})
+	v.RegisterValidation("goal_unit", func(fl validator.FieldLevel) bool {
+		return slices.Contains(goalUnits, fl.Field().String())
+	})
+	v.RegisterValidation("note_kind", func(fl validator.FieldLevel) bool {
+		return slices.Contains(noteKinds, fl.Field().String())
+	})
+	v.RegisterValidation("tenant_slug", func(fl validator.FieldLevel) bool {
+		return tenantSlugPattern.MatchString(fl.Field().String())
+	})
+	v.RegisterValidation("member_tier", func(fl validator.FieldLevel) bool {
+		return slices.Contains(memberTiers, fl.Field().String())
+	})
+	v.RegisterValidation("card_number", func(fl validator.FieldLevel) bool {
+		return cardNumberPattern.MatchString(fl.Field().String())
+	})
+	v.RegisterValidation("payment_method", func(fl validator.FieldLevel) bool {
+		return slices.Contains(paymentMethods, fl.Field().String())
+	})
+	v.RegisterValidation("book_condition", func(fl validator.FieldLevel) bool {
+		return slices.Contains(bookConditions, fl.Field().String())
+	})
+	v.RegisterValidation("sale_email_kind", func(fl validator.FieldLevel) bool {
+		return slices.Contains(saleEmailKinds, fl.Field().String())
+	})
+	return v
+}
+
+// FieldError is one rule a field of a request breaks.
+type FieldError struct {
+	Field   string `json:"field" xml:"field"`
+	Rule    string `json:"rule" xml:"rule"`
+	Message string `json:"message" xml:"message"`
+	// format and args make up the message after the field name.
+	format string
+	args   []any
+}
+
+// Check v against the rules of its type and return every violation, or
+// nil if there are none.
+func validateRequest(v any) []FieldError {
+	return fieldErrors(validate.Struct(v))
+}
+
+// Check only the named fields of v, for updates that leave the others
+// as they are.
+func validateFields(v any, fields ...string) []FieldError {
+	return fieldErrors(validate.StructPartial(v, fields...))
+}
+
+// The Go names of the fields of a book update that change the book.
+func (b Book) updatedFields() []string {
+	var fields []string
+	if b.Title != "" {
+		fields = append(fields, "Title")
+	}
+	if b.Author != "" {
+		fields = append(fields, "Author")
+	}
+	if b.Price != 0 {
+		fields = append(fields, "Price")
+	}
+	if b.ISBN != "" {
+		fields = append(fields, "ISBN")
+	}
+	if b.Publisher != "" {
+		fields = append(fields, "Publisher")
+	}
+	if b.Format != "" {
+		fields = append(fields, "Format")
+	}
+	if b.Description != "" {
+		fields = append(fields, "Description")
+	}
+	if b.CoverURL != "" {
+		fields = append(fields, "CoverURL")
+	}
+	return fields
+}
+
+func fieldErrors(err error) []FieldError {
+	var violations validator.ValidationErrors
+	if !errors.As(err, &violations) {
+		return nil
+	}
+
+	errs := make([]FieldError, len(violations))
+	for i, violation := range violations {
+		// The namespace starts with the type name.
+		_, field, _ := strings.Cut(violation.Namespace(), ".")
+		format, args := ruleMessage(violation)
+		errs[i] = newFieldError(field, violation.Tag(), format, args...)
+	}
+	return errs
+}
+
+// A violation of rule, with the message format for the field's name to
+// lead. Rules that tags can't express are checked by hand and reported
+// with this too.
+func newFieldError(field, rule, format string, args ...any) FieldError {
+	return FieldError{
+		Field:   field,
+		Rule:    rule,
+		Message: field + " " + fmt.Sprintf(format, args...),
+		format:  format,
+		args:    args,
+	}
+}
+
+// The message for a broken rule, as a format for its parameters so it can
+// be translated; see locale.go.
+func ruleMessage(violation validator.FieldError) (string, []any) {
+	param := violation.Param()
+	text := violation.Kind() == reflect.String
+
+	switch violation.Tag() {
+	case "required", "required_if":
+		return "is required", nil
+	case "gt":
+		return "must be greater than %s", []any{param}
+	case "max", "lte":
+		if text {
+			return "must be at most %s characters", []any{param}
+		}
+		return "must be at most %s", []any{param}
+	case "min", "gte":
+		if text {
+			return "must be at least %s characters", []any{param}
+		}
+		return "must be at least %s", []any{param}
+	case "isbn":
+		return "must be a valid ISBN-10 or ISBN-13", nil
+	case "email":
+		return "must be an email address", nil
+	case "http_url":
+		return "must be an absolute http or https URL", nil
+	case "https_url":
+		return "must be an absolute https URL", nil
+	case "event_type":
+		return "must be one of %s", []any{strings.Join(webhookEventTypes, ", ")}
+	case "notification_kind":
+		return "must be one of %s", []any{strings.Join(notificationKinds, ", ")}
+	case "notification_channel":
+		return "must be one of %s", []any{strings.Join(notificationChannelNames, ", ")}
+	case "book_format":
+		return "must be one of %s", []any{strings.Join(bookFormats, ", ")}
+	case "reading_status":
+		return "must be one of %s", []any{strings.Join(readingStatuses, ", ")}
+	case "goal_unit":
+		return "must be one of %s", []any{strings.Join(goalUnits, ", ")}
+	case "note_kind":
+		return "must be one of %s", []any{strings.Join(noteKinds, ", ")}
+	case "tenant_slug":
+		return "must be lower case letters, digits and hyphens", nil
+	case "member_tier":
+		return "must be one of %s", []any{strings.Join(memberTiers, ", ")}
+	case "card_number":
+		return "must be 4 to 32 letters and digits", nil
+	case "payment_method":
+		return "must be one of %s", []any{strings.Join(paymentMethods, ", ")}
+	case "book_condition":
+		return "must be one of %s", []any{strings.Join(bookConditions, ", ")}
+	case "sale_email_kind":
+		return "must be one of %s", []any{strings.Join(saleEmailKinds, ", ")}
+	case "e164":
+		return "must be a phone number in E.164 format, such as +14155550100", nil
+	}
+	return "is invalid", nil
+}
+
+// Answer a request that broke the rules in errs with a problem listing
+// all of them.
+func writeValidationErrors(w http.ResponseWriter, r *http.Request, errs []FieldError) {
+	problem := newProblem(r, codeValidationFailed, validationMessage(errs))
+	problem.Errors = errs
+	writeResponse(w, r, problem.Status, problem)
+}
+
+// The violations in errs as one message, for APIs without structured
+// errors.
+func validationMessage(errs []FieldError) string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Message
+	}
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Webhook is a URL that receives a POST for every catalog change event.
+type Webhook struct {
+	Id  int    `json:"id" xml:"id"`
+	URL string `json:"url" xml:"url" validate:"required,http_url,max=2048"`
+	// Secret signs the payloads. It is only returned when the webhook is
+	// created.
+	Secret string `json:"secret,omitempty" xml:"secret,omitempty" validate:"max=255"`
+	// Events limits which event types are sent; empty means all of them.
+	Events    []string  `json:"events" xml:"events>event" validate:"dive,event_type"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+}
+
+func (h Webhook) timestamps() (created, updated time.Time) {
+	return h.CreatedAt, h.UpdatedAt
+}
+
+// WebhookDelivery is one attempt to deliver an event to a webhook.
+type WebhookDelivery struct {
+	Id        int    `json:"id" xml:"id"`
+	WebhookId int    `json:"webhook_id" xml:"webhook_id"`
+	EventId   int64  `json:"event_id" xml:"event_id"`
+	EventType string `json:"event_type" xml:"event_type"`
+	// Attempt counts from 1 for each event.
+	Attempt int `json:"attempt" xml:"attempt"`
+	// StatusCode is the receiver's response status, 0 if there was none.
+	StatusCode int       `json:"status_code" xml:"status_code"`
+	Error      string    `json:"error" xml:"error"`
+	CreatedAt  time.Time `json:"created_at" xml:"created_at"`
+}
+
+// Deliveries never change once made.
+func (d WebhookDelivery) timestamps() (created, updated time.Time) {
+	return d.CreatedAt, d.CreatedAt
+}
+
+// WebhookJob is an event waiting to be delivered to a webhook.
+type WebhookJob struct {
+	Id        int
+	WebhookId int
+	Event     Event
+	// Attempt is the number of attempts made so far.
+	Attempt       int
+	NextAttemptAt time.Time
+}
+
+type WebhookResponse struct {
+	Status  string  `json:"status" xml:"status"`
+	Message string  `json:"message" xml:"message"`
+	Data    Webhook `json:"data,omitempty" xml:"data"`
+}
+
+type WebhooksResponse struct {
+	Status  string    `json:"status" xml:"status"`
+	Message string    `json:"message" xml:"message"`
+	Data    []Webhook `json:"data,omitempty" xml:"data>webhook,omitempty"`
+}
+
+type WebhookDeliveriesResponse struct {
+	Status  string            `json:"status" xml:"status"`
+	Message string            `json:"message" xml:"message"`
+	Data    []WebhookDelivery `json:"data,omitempty" xml:"data>delivery,omitempty"`
+}
+
+// How many delivery attempts GET /webhooks/{id}/deliveries returns.
+const webhookDeliveryLogLimit = 100
+
+var webhookEventTypes = []string{EventBookCreated, EventBookUpdated, EventBookDeleted}
+
+func createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var hook Webhook
+	if err := decodeRequest(r, &hook); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	if errs := validateRequest(hook); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+
+	// Generate a secret unless the caller brought their own.
+	if hook.Secret == "" {
+		secret := make([]byte, 32)
+		rand.Read(secret)
+		hook.Secret = hex.EncodeToString(secret)
+	}
+
+	if err := store.CreateWebhook(r.Context(), &hook); err != nil {
+		writeProblem(w, r, codeInternal, "Error creating webhook")
+		log.Printf("Webhook creation error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, WebhookResponse{
+		Status:  "success",
+		Message: "Webhook created successfully",
+		Data:    hook,
+	})
+}
+
+func listWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+	hooks, err := store.ListWebhooks(r.Context(), after)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching webhooks")
+		log.Printf("Webhook query error: %v", err)
+		return
+	}
+
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+
+	writeResponse(w, r, http.StatusOK, WebhooksResponse{
+		Status:  "success",
+		Message: "Webhooks retrieved successfully",
+		Data:    hooks,
+	})
+}
+
+func deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid webhook ID")
+		return
+	}
+
+	err = store.DeleteWebhook(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeWebhookNotFound, "Webhook not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error deleting webhook")
+		log.Printf("Webhook deletion error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Webhook deleted successfully",
+	})
+}
+
+func listWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid webhook ID")
+		return
+	}
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+
+	_, err = store.GetWebhook(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeWebhookNotFound, "Webhook not found")
+		return
+	}
+
+	var deliveries []WebhookDelivery
+	if err == nil {
+		deliveries, err = store.ListWebhookDeliveries(r.Context(), id, webhookDeliveryLogLimit, after)
+	}
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching deliveries")
+		log.Printf("Webhook delivery query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, WebhookDeliveriesResponse{
+		Status:  "success",
+		Message: "Deliveries retrieved successfully",
+		Data:    deliveries,
+	})
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Waits before each retry of a failed delivery; one more attempt than
+// there are delays is made in total.
+var webhookRetryDelays = []time.Duration{10 * time.Second, time.Minute, 5 * time.Minute, 30 * time.Minute}
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+const (
+	// How long a claimed job is reserved for the worker attempting it.
+	// Must be well above webhookClient's timeout.
+	webhookJobLease     = time.Minute
+	webhookPollInterval = time.Second
+	webhookClaimBatch   = 20
+)
+
+// Deliver queued webhook jobs until the process exits. Jobs live in the
+// store, so retries survive restarts.
+func dispatchWebhooks() {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			jobs, err := store.ClaimWebhookJobs(context.Background(), time.Now().UTC(), webhookJobLease, webhookClaimBatch)
+			if err != nil {
+				log.Printf("Webhook job query error: %v", err)
+				break
+			}
+			for _, job := range jobs {
+				go runWebhookJob(job)
+			}
+			if len(jobs) < webhookClaimBatch {
+				break
+			}
+		}
+
+		select {
+		case <-webhookWake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Make one delivery attempt for job, record it in the delivery log, and
+// either finish the job or schedule its retry.
+func runWebhookJob(job WebhookJob) {
+	ctx := withTenantId(context.Background(), job.Event.TenantId)
+
+	hook, err := store.GetWebhook(ctx, job.WebhookId)
+	if err != nil {
+		// A deleted webhook takes its jobs with it.
+		if !errors.Is(err, ErrNotFound) {
+			log.Printf("Webhook query error: %v", err)
+		}
+		return
+	}
+
+	job.Attempt++
+	delivery := WebhookDelivery{
+		WebhookId: hook.Id,
+		EventId:   job.Event.Id,
+		EventType: job.Event.Type,
+		Attempt:   job.Attempt,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	status, postErr := postWebhook(hook, job.Event)
+	delivery.StatusCode = status
+	if postErr != nil {
+		delivery.Error = postErr.Error()
+	}
+
+	if err := store.CreateWebhookDelivery(ctx, &delivery); err != nil {
+		log.Printf("Webhook delivery log error: %v", err)
+	}
+
+	if postErr == nil || job.Attempt > len(webhookRetryDelays) {
+		err = store.DeleteWebhookJob(ctx, job.Id)
+	} else {
+		job.NextAttemptAt = time.Now().UTC().Add(webhookRetryDelays[job.Attempt-1])
+		err = store.UpdateWebhookJob(ctx, job)
+	}
+	if err != nil {
+		// The lease runs out and the attempt is repeated.
+		log.Printf("Webhook job update error: %v", err)
+	}
+}
+
+// Send one signed POST and return the response status. Anything but a 2xx
+// is an error.
+func postWebhook(hook Webhook, event Event) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bookshelf-webhooks")
+	req.Header.Set("X-Bookshelf-Event", event.Type)
+	req.Header.Set("X-Bookshelf-Delivery", strconv.FormatInt(event.Id, 10))
+	req.Header.Set("X-Bookshelf-Signature", "sha256="+signWebhook(hook.Secret, body))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Hex HMAC-SHA256 of body keyed with secret, which receivers recompute to
+// verify a payload came from us.
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
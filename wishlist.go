@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// WishlistItem is a book a user wants, and the price they would buy it
+// at.
+type WishlistItem struct {
+	UserId int `json:"-" xml:"-"`
+	BookId int `json:"book_id" xml:"book_id"`
+	// TargetPrice is the price, in the catalog's currency, at or below
+	// which the user is told the book got cheaper; without one they
+	// aren't.
+	TargetPrice *float64  `json:"target_price,omitempty" xml:"target_price,omitempty" validate:"omitempty,gt=0"`
+	AddedAt     time.Time `json:"added_at" xml:"added_at"`
+	// AlertedPrice is the price the user was last told of, until the book
+	// costs more than the target again; they are only told again if it
+	// drops further.
+	AlertedPrice *float64   `json:"alerted_price,omitempty" xml:"alerted_price,omitempty"`
+	AlertedAt    *time.Time `json:"alerted_at,omitempty" xml:"alerted_at,omitempty"`
+	// Book is loaded by ListWishlist and ListPriceTargets.
+	Book *Book `json:"book,omitempty" xml:"book,omitempty"`
+	// CreatedAt is when the book was first added, and UpdatedAt when its
+	// target or alert last changed.
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+}
+
+func (i WishlistItem) timestamps() (created, updated time.Time) {
+	return i.CreatedAt, i.UpdatedAt
+}
+
+type WishlistResponse struct {
+	Status  string         `json:"status" xml:"status"`
+	Message string         `json:"message" xml:"message"`
+	Data    []WishlistItem `json:"data" xml:"data>item"`
+}
+
+type WishlistItemResponse struct {
+	Status  string       `json:"status" xml:"status"`
+	Message string       `json:"message" xml:"message"`
+	Data    WishlistItem `json:"data" xml:"data"`
+}
+
+// BookPrice is a price a book had from ChangedAt until its next one.
+type BookPrice struct {
+	BookId    int       `json:"-" xml:"-"`
+	Price     float64   `json:"price" xml:"price"`
+	ChangedAt time.Time `json:"changed_at" xml:"changed_at"`
+}
+
+// Prices in the history never change once recorded.
+func (p BookPrice) timestamps() (created, updated time.Time) {
+	return p.ChangedAt, p.ChangedAt
+}
+
+type BookPricesResponse struct {
+	Status  string      `json:"status" xml:"status"`
+	Message string      `json:"message" xml:"message"`
+	Data    []BookPrice `json:"data" xml:"data>price"`
+}
+
+// Add a price the outbox relay saw a created or updated book at to its
+// history, in the relay's transaction.
+func recordBookPrice(ctx context.Context, tx BookStore, event Event) error {
+	if event.Type != EventBookCreated && event.Type != EventBookUpdated {
+		return nil
+	}
+	return tx.RecordBookPrice(ctx, BookPrice{BookId: event.Book.Id, Price: event.Book.Price, ChangedAt: event.Time})
+}
+
+// Tell users of the books on their wishlists that dropped to their target
+// price; the wishlist_price_alerts job.
+func alertWishlistPrices(ctx context.Context) error {
+	items, err := store.ListPriceTargets(ctx)
+	if err != nil {
+		return err
+	}
+
+	users := map[int]User{}
+	for _, item := range items {
+		price := item.Book.Price
+		if price > *item.TargetPrice {
+			// Above the target again, so the next drop to it is news.
+			if item.AlertedPrice != nil {
+				now := time.Now().UTC().Truncate(time.Microsecond)
+				if err := store.SetWishlistAlert(ctx, item.UserId, item.BookId, nil, nil, now); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if item.AlertedPrice != nil && price >= *item.AlertedPrice {
+			continue
+		}
+
+		user, ok := users[item.UserId]
+		if !ok {
+			if user, err = store.GetUser(ctx, item.UserId); err != nil {
+				return err
+			}
+			users[item.UserId] = user
+		}
+		data, err := wishlistPriceDropData(ctx, item)
+		if err != nil {
+			return err
+		}
+		_, err = notify(ctx, "", user.Email, NotificationWishlistPriceDrop, data)
+		switch {
+		case errors.Is(err, errChannelUnavailable), errors.Is(err, errNoPhone), errors.Is(err, errNoPushSubscription):
+			// Not something another try would fix, so the drop counts as
+			// told.
+			log.Printf("Wishlist price drop for user %d not sent: %v", item.UserId, err)
+		case err != nil:
+			return err
+		}
+
+		alertedAt := time.Now().UTC().Truncate(time.Microsecond)
+		if err := store.SetWishlistAlert(ctx, item.UserId, item.BookId, &price, &alertedAt, alertedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The template data for a wishlist price drop: the book, its price and
+// the user's target, the price it dropped from, and whether it was ever
+// cheaper, from its price history.
+func wishlistPriceDropData(ctx context.Context, item WishlistItem) (map[string]any, error) {
+	prices, err := store.ListBookPrices(ctx, item.BookId, ChangedAfter{})
+	if err != nil {
+		return nil, err
+	}
+	price := item.Book.Price
+	var oldPrice string
+	lowest := true
+	for i := len(prices) - 1; i >= 0; i-- {
+		if prices[i].Price != price && oldPrice == "" {
+			oldPrice = fmt.Sprintf("%.2f", prices[i].Price)
+		}
+		if prices[i].Price < price {
+			lowest = false
+		}
+	}
+	return map[string]any{
+		"title":        item.Book.Title,
+		"author":       item.Book.Author,
+		"price":        fmt.Sprintf("%.2f", price),
+		"old_price":    oldPrice,
+		"target_price": fmt.Sprintf("%.2f", *item.TargetPrice),
+		"lowest":       lowest,
+		"currency":     currencies.base,
+	}, nil
+}
+
+// List the books on the user's wishlist, in the order they were added.
+func listWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+	items, err := store.ListWishlist(r.Context(), currentUser(r).Id, after)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching wishlist")
+		log.Printf("Wishlist query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, WishlistResponse{
+		Status:  "success",
+		Message: "Wishlist retrieved successfully",
+		Data:    items,
+	})
+}
+
+// Put a book on the user's wishlist, or change its target price. A book
+// already at or below the new target doesn't alert the user until it
+// drops further.
+func wishBookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+	var item WishlistItem
+	if err := decodeRequest(r, &item); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	if errs := validateRequest(item); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	if item.TargetPrice != nil {
+		*item.TargetPrice = roundCents(*item.TargetPrice)
+	}
+	item.BookId = id
+	item.AddedAt = time.Now().UTC().Truncate(time.Microsecond)
+	item.CreatedAt, item.UpdatedAt = item.AddedAt, item.AddedAt
+	item.AlertedPrice, item.AlertedAt = nil, nil
+
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		book, err := tx.GetBook(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		item.Book = &book
+		if item.TargetPrice != nil && book.Price <= *item.TargetPrice {
+			item.AlertedPrice, item.AlertedAt = &book.Price, &item.AddedAt
+		}
+		return tx.SaveWishlistItem(r.Context(), currentUser(r).Id, &item)
+	})
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error updating wishlist")
+		log.Printf("Wishlist update error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, WishlistItemResponse{
+		Status:  "success",
+		Message: "Book added to wishlist successfully",
+		Data:    item,
+	})
+}
+
+func unwishBookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+
+	err = store.DeleteWishlistItem(r.Context(), currentUser(r).Id, id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book isn't on the wishlist")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error updating wishlist")
+		log.Printf("Wishlist update error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Book removed from wishlist successfully",
+	})
+}
+
+// A book's price history, oldest first.
+func bookPricesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+
+	prices, err := store.ListBookPrices(r.Context(), id, after)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching price history")
+		log.Printf("Price history query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, BookPricesResponse{
+		Status:  "success",
+		Message: "Price history retrieved successfully",
+		Data:    prices,
+	})
+}
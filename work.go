@@ -0,0 +1,316 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Work is a book as written, such as "Pride and Prejudice", whatever the
+// edition. Its editions are the books in the catalog that print it, each
+// with its own ISBN, publisher and price; a book is an edition of at most
+// one work.
+type Work struct {
+	// Id is 0 for a book not yet grouped under a work, which searches
+	// show as a work of its own.
+	Id     int    `json:"id,omitempty" xml:"id,omitempty"`
+	Title  string `json:"title" xml:"title" validate:"required,max=255"`
+	Author string `json:"author" xml:"author" validate:"required,max=255"`
+	// Editions are ordered by id.
+	Editions []Book `json:"editions" xml:"editions>book"`
+	// CreatedAt and UpdatedAt are absent for works from before they were
+	// kept and for books shown as works of their own. Adding or removing
+	// an edition updates the work.
+	CreatedAt *time.Time `json:"created_at,omitempty" xml:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty" xml:"updated_at,omitempty"`
+}
+
+func (w Work) timestamps() (created, updated time.Time) {
+	return timeOrZero(w.CreatedAt), timeOrZero(w.UpdatedAt)
+}
+
+type WorkResponse struct {
+	Status  string `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
+	Data    Work   `json:"data" xml:"data"`
+}
+
+type WorksResponse struct {
+	Status  string `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
+	Data    []Work `json:"data" xml:"data>work"`
+}
+
+func (w *Work) normalize() {
+	w.Title = normalizeText(w.Title)
+	w.Author = normalizeText(w.Author)
+}
+
+func listWorksHandler(w http.ResponseWriter, r *http.Request) {
+	after, ok := requestedChangedAfterFilter(w, r)
+	if !ok {
+		return
+	}
+	works, err := store.ListWorks(r.Context(), after)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching works")
+		log.Printf("Work query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, WorksResponse{
+		Status:  "success",
+		Message: "Works retrieved successfully",
+		Data:    works,
+	})
+}
+
+func createWorkHandler(w http.ResponseWriter, r *http.Request) {
+	var work Work
+	if err := decodeRequest(r, &work); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	work.normalize()
+	if errs := validateRequest(work); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	work = Work{Title: work.Title, Author: work.Author, Editions: []Book{}, CreatedAt: &now, UpdatedAt: &now}
+
+	if err := store.CreateWork(r.Context(), &work); err != nil {
+		writeProblem(w, r, codeInternal, "Error creating work")
+		log.Printf("Work creation error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, WorkResponse{
+		Status:  "success",
+		Message: "Work created successfully",
+		Data:    work,
+	})
+}
+
+func getWorkHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid work ID")
+		return
+	}
+
+	work, err := store.GetWork(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeWorkNotFound, "Work not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error fetching work")
+		log.Printf("Work query error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, WorkResponse{
+		Status:  "success",
+		Message: "Work retrieved successfully",
+		Data:    work,
+	})
+}
+
+// Replace the title and author of a work; its editions keep theirs.
+func updateWorkHandler(w http.ResponseWriter, r *http.Request) {
+	var req Work
+	if err := decodeRequest(r, &req); err != nil {
+		writeProblem(w, r, codeInvalidRequest, "Invalid request body")
+		return
+	}
+	req.normalize()
+	if errs := validateRequest(req); errs != nil {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	changeWork(w, r, "Work updated successfully", func(tx BookStore, work *Work) error {
+		work.Title, work.Author = req.Title, req.Author
+		return tx.UpdateWork(r.Context(), *work)
+	})
+}
+
+// Delete a work; its editions stay in the catalog, ungrouped.
+func deleteWorkHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid work ID")
+		return
+	}
+
+	err = store.DeleteWork(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		writeProblem(w, r, codeWorkNotFound, "Work not found")
+		return
+	} else if err != nil {
+		writeProblem(w, r, codeInternal, "Error deleting work")
+		log.Printf("Work deletion error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, Response{
+		Message: "Work deleted successfully",
+	})
+}
+
+var (
+	errEditionBookMissing = errors.New("book not found")
+	errNotAnEdition       = errors.New("book not an edition of the work")
+)
+
+// Make a book an edition of a work, moving it from any other work.
+func addEditionHandler(w http.ResponseWriter, r *http.Request) {
+	bookId, err := strconv.Atoi(mux.Vars(r)["book_id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+
+	changeWork(w, r, "Edition added successfully", func(tx BookStore, work *Work) error {
+		err := tx.SetBookWork(r.Context(), bookId, work.Id)
+		if errors.Is(err, ErrNotFound) {
+			return errEditionBookMissing
+		} else if err != nil {
+			return err
+		}
+		return tx.UpdateWork(r.Context(), *work)
+	})
+}
+
+func removeEditionHandler(w http.ResponseWriter, r *http.Request) {
+	bookId, err := strconv.Atoi(mux.Vars(r)["book_id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid book ID")
+		return
+	}
+
+	changeWork(w, r, "Edition removed successfully", func(tx BookStore, work *Work) error {
+		err := tx.RemoveBookFromWork(r.Context(), bookId, work.Id)
+		if errors.Is(err, ErrNotFound) {
+			return errNotAnEdition
+		} else if err != nil {
+			return err
+		}
+		return tx.UpdateWork(r.Context(), *work)
+	})
+}
+
+// Change the work named in the URL in one transaction with change, which
+// saves what it changes, with the work's UpdatedAt set to now, or returns
+// one of the errors above, and answer with the work as it then is.
+func changeWork(w http.ResponseWriter, r *http.Request, message string, change func(tx BookStore, work *Work) error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, codeInvalidParameter, "Invalid work ID")
+		return
+	}
+
+	var work Work
+	err = store.WithTx(r.Context(), func(tx BookStore) error {
+		var err error
+		if work, err = tx.GetWork(r.Context(), id); err != nil {
+			return err
+		}
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		work.UpdatedAt = &now
+		if err := change(tx, &work); err != nil {
+			return err
+		}
+		work, err = tx.GetWork(r.Context(), id)
+		return err
+	})
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeProblem(w, r, codeWorkNotFound, "Work not found")
+		return
+	case errors.Is(err, errEditionBookMissing):
+		writeProblem(w, r, codeBookNotFound, "Book not found")
+		return
+	case errors.Is(err, errNotAnEdition):
+		writeProblem(w, r, codeBookNotFound, "Book isn't an edition of the work")
+		return
+	case err != nil:
+		writeProblem(w, r, codeInternal, "Error updating work")
+		log.Printf("Work update error: %v", err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, WorkResponse{
+		Status:  "success",
+		Message: message,
+		Data:    work,
+	})
+}
+
+// Search the catalog as /books/search does, but answer with one result per
+// work, holding all its editions, instead of one per edition. Works match
+// on their own title and author as well as their editions'; matching books
+// not grouped under a work come back as works of their own, without an id.
+// Results are ordered by their first edition.
+func searchWorksHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		writeProblem(w, r, codeInvalidParameter, "Search query is required")
+		return
+	}
+
+	started := time.Now()
+	works, err := store.SearchWorks(r.Context(), query)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error searching works")
+		log.Printf("Work search error: %v", err)
+		return
+	}
+	books, err := store.SearchBooks(r.Context(), query)
+	if err != nil {
+		writeProblem(w, r, codeInternal, "Error searching works")
+		log.Printf("Database search error: %v", err)
+		return
+	}
+
+	grouped := map[int]bool{}
+	for _, work := range works {
+		for _, edition := range work.Editions {
+			grouped[edition.Id] = true
+		}
+	}
+	for _, book := range books {
+		if !grouped[book.Id] {
+			works = append(works, Work{Title: book.Title, Author: book.Author, Editions: []Book{book}})
+		}
+	}
+	sort.SliceStable(works, func(i, j int) bool {
+		return firstEdition(works[i]) < firstEdition(works[j])
+	})
+	recordSearch(r.Context(), query, len(works), started)
+
+	message := "Works retrieved successfully"
+	if len(works) == 0 {
+		message = "No works found"
+	}
+	writeResponse(w, r, http.StatusOK, WorksResponse{
+		Status:  "success",
+		Message: message,
+		Data:    works,
+	})
+}
+
+// The id of a work's first edition, for ordering search results; works
+// without editions come last.
+func firstEdition(work Work) int {
+	if len(work.Editions) == 0 {
+		return math.MaxInt
+	}
+	return work.Editions[0].Id
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Time allowed to write a message to the client.
+	wsWriteWait = 10 * time.Second
+	// A client that hasn't answered a ping within this time is gone.
+	wsPongWait = 60 * time.Second
+	// Must be less than wsPongWait.
+	wsPingPeriod = wsPongWait * 9 / 10
+)
+
+// Same-origin only (the default check); the dashboard is served by us.
+var wsUpgrader = websocket.Upgrader{}
+
+// Stream catalog change events to a WebSocket client as JSON messages. The
+// optional types query parameter (e.g. ?types=book.created,book.deleted)
+// limits which events are sent.
+func wsHandler(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already answered the client.
+		log.Printf("WebSocket upgrade error: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	wanted := eventTypeFilter(r.URL.Query().Get("types"))
+
+	sub, unsubscribe := events.Subscribe(tenantId(r.Context()))
+	defer unsubscribe()
+
+	// The client doesn't send us anything, but reading is how control
+	// frames (pong, close) get processed.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		})
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub:
+			if !ok {
+				// Dropped for falling behind; the client should reconnect and refetch.
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"), time.Now().Add(wsWriteWait))
+				return
+			}
+			if wanted != nil && !wanted[event.Type] {
+				continue
+			}
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// Parse a comma-separated list of event types; nil means all of them.
+func eventTypeFilter(list string) map[string]bool {
+	if list == "" {
+		return nil
+	}
+	wanted := map[string]bool{}
+	for _, t := range strings.Split(list, ",") {
+		wanted[strings.TrimSpace(t)] = true
+	}
+	return wanted
+}